@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -9,16 +10,20 @@ import (
 	"os/exec"
 	"os/signal"
 	"runtime/pprof"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
 
 	"github.com/spf13/cobra"
+	"opperator/config"
 	"opperator/internal/cli"
 	"opperator/internal/credentials"
 	"opperator/internal/daemon"
 	"opperator/internal/deployment"
+	"opperator/internal/ipc"
 	"opperator/internal/onboarding"
+	"opperator/pkg/tracing"
 	"opperator/updater"
 	"opperator/version"
 	"tui"
@@ -26,11 +31,34 @@ import (
 
 var (
 	tuiCPUProfilePath string
+	shutdownTracing   = func(context.Context) error { return nil }
 )
 
+// flushTracing exports spans still buffered; call it before os.Exit.
+func flushTracing() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdownTracing(ctx); err != nil {
+		log.Printf("Failed to flush traces: %v", err)
+	}
+}
+
 var rootCmd = &cobra.Command{
 	Use:   "op",
 	Short: "Opperator",
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		if instance, _ := cmd.Flags().GetString("instance"); instance != "" {
+			if err := config.SetInstance(instance); err != nil {
+				cli.PrintError(err)
+				os.Exit(1)
+			}
+		}
+		service := "opperator-cli"
+		if cmd == daemonStartCmd {
+			service = "opperator-daemon"
+		}
+		shutdownTracing = tracing.Init(service)
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 		// Check if this is the first run
 		if onboarding.IsFirstRun() {
@@ -55,6 +83,16 @@ var rootCmd = &cobra.Command{
 			time.Sleep(2 * time.Second)
 		}
 
+		// Refuse to open the TUI against a daemon it cannot talk to. Mere
+		// version skew is reported inside the TUI, where stderr is hidden.
+		ipc.VersionWarning = func(string) {}
+		if client, err := ipc.NewClientFromRegistry("local"); err == nil {
+			client.Close()
+		} else if ipc.IsCode(err, ipc.ErrCodeIncompatible) {
+			cli.PrintError(err)
+			os.Exit(1)
+		}
+
 		var stopProfile func()
 		if tuiCPUProfilePath != "" {
 			cleanup, err := startTUICPUProfile(tuiCPUProfilePath)
@@ -69,12 +107,16 @@ var rootCmd = &cobra.Command{
 			}()
 		}
 
-		hasKey, err := credentials.HasAPIKey()
+		var hasKey bool
+		err := cli.RetryKeyring(func() (err error) {
+			hasKey, err = credentials.HasAPIKey()
+			return err
+		})
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error reading secrets: %v\n", err)
 			os.Exit(1)
 		}
-		if !hasKey {
+		if llm, _ := config.LoadLLMConfig(); !hasKey && llm == nil {
 			fmt.Fprintf(os.Stderr, "Opper API key is not configured. Run `op secret create %s` to add one.\n", credentials.OpperAPIKeyName)
 			os.Exit(1)
 		}
@@ -174,6 +216,7 @@ var daemonStartCmd = &cobra.Command{
 			log.Printf("Received signal %v, initiating graceful shutdown...", sig)
 			fmt.Println("\nShutting down daemon...")
 			server.Stop()
+			flushTracing()
 			log.Printf("Graceful shutdown complete")
 			os.Exit(0)
 		}()
@@ -255,7 +298,7 @@ Examples:
 		enabled, _ := cmd.Flags().GetBool("enabled")
 
 		if err := cli.AddDaemon(name, address, token, enabled); err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			cli.PrintError(err)
 			os.Exit(1)
 		}
 	},
@@ -266,7 +309,7 @@ var daemonListCmd = &cobra.Command{
 	Short: "List all configured daemons",
 	Run: func(cmd *cobra.Command, args []string) {
 		if err := cli.ListDaemons(); err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			cli.PrintError(err)
 			os.Exit(1)
 		}
 	},
@@ -279,7 +322,7 @@ var daemonRemoveCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		force, _ := cmd.Flags().GetBool("force")
 		if err := cli.RemoveDaemon(args[0], force); err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			cli.PrintError(err)
 			os.Exit(1)
 		}
 	},
@@ -291,7 +334,7 @@ var daemonTestCmd = &cobra.Command{
 	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		if err := cli.TestDaemon(args[0]); err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			cli.PrintError(err)
 			os.Exit(1)
 		}
 	},
@@ -303,7 +346,7 @@ var daemonEnableCmd = &cobra.Command{
 	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		if err := cli.SetDaemonEnabled(args[0], true); err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			cli.PrintError(err)
 			os.Exit(1)
 		}
 	},
@@ -315,7 +358,73 @@ var daemonDisableCmd = &cobra.Command{
 	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		if err := cli.SetDaemonEnabled(args[0], false); err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			cli.PrintError(err)
+			os.Exit(1)
+		}
+	},
+}
+
+var daemonTopCmd = &cobra.Command{
+	Use:   "top [name]",
+	Short: "Show live CPU, memory, file descriptor and child process usage of a daemon and its agents",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := "local"
+		if len(args) > 0 {
+			name = args[0]
+		}
+		interval, _ := cmd.Flags().GetDuration("interval")
+		once, _ := cmd.Flags().GetBool("once")
+		if err := cli.DaemonTop(name, interval, once); err != nil {
+			cli.PrintError(err)
+			os.Exit(1)
+		}
+	},
+}
+
+var daemonTokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "Manage auth tokens accepted by a daemon's TCP listener",
+	Long: `Create, list and revoke named auth tokens on a daemon. Only a hash of each
+token is stored. To rotate a token, create a new one, update your clients, then
+revoke the old one once 'list' shows it is no longer used.`,
+}
+
+var daemonTokenCreateCmd = &cobra.Command{
+	Use:   "create [name]",
+	Short: "Create a named auth token",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		daemonName, _ := cmd.Flags().GetString("daemon")
+		role, _ := cmd.Flags().GetString("role")
+		user, _ := cmd.Flags().GetString("user")
+		if err := cli.CreateAuthToken(daemonName, args[0], role, user); err != nil {
+			cli.PrintError(err)
+			os.Exit(1)
+		}
+	},
+}
+
+var daemonTokenRevokeCmd = &cobra.Command{
+	Use:   "revoke [name]",
+	Short: "Revoke a named auth token",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		daemonName, _ := cmd.Flags().GetString("daemon")
+		if err := cli.RevokeAuthToken(daemonName, args[0]); err != nil {
+			cli.PrintError(err)
+			os.Exit(1)
+		}
+	},
+}
+
+var daemonTokenListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List auth tokens and when they were last used",
+	Run: func(cmd *cobra.Command, args []string) {
+		daemonName, _ := cmd.Flags().GetString("daemon")
+		if err := cli.ListAuthTokens(daemonName); err != nil {
+			cli.PrintError(err)
 			os.Exit(1)
 		}
 	},
@@ -337,14 +446,31 @@ This will:
  2. Install and configure Opperator
  3. Register the daemon in your local config
 
-The wizard will guide you through the process.`,
+The wizard will guide you through the process. The hardening flags preselect
+the wizard's server hardening choices: limiting the daemon port to one address
+(with ufw enabled and only SSH left open), automatic security updates,
+fail2ban for SSH, key-only SSH login and a swap file.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		if err := deployment.Deploy(); err != nil {
+		restrict, _ := cmd.Flags().GetBool("restrict-to-my-ip")
+		allowFrom, _ := cmd.Flags().GetString("allow-from")
+		unattended, _ := cmd.Flags().GetBool("unattended-upgrades")
+		fail2ban, _ := cmd.Flags().GetBool("fail2ban")
+		keyOnly, _ := cmd.Flags().GetBool("ssh-key-only")
+		swap, _ := cmd.Flags().GetInt("swap")
+		hardening := deployment.HardeningOptions{
+			AllowFrom:          allowFrom,
+			RestrictToMyIP:     restrict,
+			UnattendedUpgrades: unattended,
+			Fail2ban:           fail2ban,
+			SSHKeyOnly:         keyOnly,
+			SwapGB:             swap,
+		}
+		if err := deployment.Deploy(hardening); err != nil {
 			if err.Error() == "cancelled" {
 				fmt.Println("\nDeployment cancelled.")
 				return
 			}
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			cli.PrintError(err)
 			os.Exit(1)
 		}
 	},
@@ -363,7 +489,7 @@ Use --force to skip the confirmation prompt.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		force, _ := cmd.Flags().GetBool("force")
 		if err := deployment.Destroy(args[0], force); err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			cli.PrintError(err)
 			os.Exit(1)
 		}
 	},
@@ -375,7 +501,81 @@ var cloudListCmd = &cobra.Command{
 	Long:  `List all Opperator daemons deployed to cloud providers.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		if err := cli.ListCloudDaemons(); err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			cli.PrintError(err)
+			os.Exit(1)
+		}
+	},
+}
+
+var cloudCostsCmd = &cobra.Command{
+	Use:   "costs",
+	Short: "Estimate spend on cloud deployments this month",
+	Long: `Estimate month-to-date and projected spend for this calendar month for every
+cloud deployment. Prices come from the provider API when an API key is stored
+and otherwise from the prices recorded when the daemon was deployed. Hetzner
+bills every started hour up to the server type's monthly price.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		jsonOutput, _ := cmd.Flags().GetBool("json")
+		if err := deployment.Costs(jsonOutput); err != nil {
+			cli.PrintError(err)
+			os.Exit(1)
+		}
+	},
+}
+
+var cloudRecoverCmd = &cobra.Command{
+	Use:   "recover <name>",
+	Short: "Rebuild a cloud daemon from its backups",
+	Long: `Rebuild a cloud-deployed daemon after its server or data was lost.
+
+This will:
+ 1. Create a new VPS with the lost server's type and location
+ 2. Install Opperator with the daemon's existing auth token
+ 3. Restore a snapshot from the daemon's backup bucket
+ 4. Point the daemon's registry entry at the new server
+ 5. Ask for the secrets the restored daemon uses, offering copies from
+    your local keyring, since backups do not contain secret values
+ 6. Start the agents that were running when the snapshot was taken
+
+--bucket names the bucket the daemon backed up to; the other bucket flags
+follow its daemon.yaml. The AWS_* credentials in your environment are copied
+to the new server, where the restored daemon keeps using them for its own
+backups. The old server is kept unless --delete-old is set.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		backupCfg := backupOverride(cmd)
+		if err := backupCfg.Validate(); err != nil {
+			cli.PrintError(err)
+			os.Exit(1)
+		}
+		snapshot, _ := cmd.Flags().GetString("snapshot")
+		serverType, _ := cmd.Flags().GetString("server-type")
+		location, _ := cmd.Flags().GetString("location")
+		deleteOld, _ := cmd.Flags().GetBool("delete-old")
+		restrict, _ := cmd.Flags().GetBool("restrict-to-my-ip")
+		allowFrom, _ := cmd.Flags().GetString("allow-from")
+		swap, _ := cmd.Flags().GetInt("swap")
+		opts := deployment.RecoverOptions{
+			Backup:     backupCfg,
+			Snapshot:   snapshot,
+			ServerType: serverType,
+			Location:   location,
+			DeleteOld:  deleteOld,
+			Hardening: deployment.HardeningOptions{
+				AllowFrom:          allowFrom,
+				RestrictToMyIP:     restrict,
+				UnattendedUpgrades: true,
+				Fail2ban:           true,
+				SSHKeyOnly:         true,
+				SwapGB:             swap,
+			},
+		}
+		if err := deployment.Recover(args[0], opts); err != nil {
+			if err.Error() == "cancelled" {
+				fmt.Println("\nRecovery cancelled.")
+				return
+			}
+			cli.PrintError(err)
 			os.Exit(1)
 		}
 	},
@@ -389,12 +589,23 @@ var cloudUpdateCmd = &cobra.Command{
  2. Transferring it to the remote server via SSH
  3. Gracefully restarting the daemon
 
-This is useful after you've made local changes and want to deploy them to production.`,
+This is useful after you've made local changes and want to deploy them to production.
+
+With --remote-pull the daemon instead downloads the latest GitHub release of
+its configured channel itself, verifies its checksum, installs it and restarts
+through systemd. No SSH access is needed. Set updates.auto in the daemon's
+daemon.yaml to have it do this on a schedule.`,
 	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		preRelease, _ := cmd.Flags().GetBool("pre-release")
-		if err := deployment.Update(args[0], preRelease); err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		remotePull, _ := cmd.Flags().GetBool("remote-pull")
+		update := deployment.Update
+		if remotePull {
+			update = deployment.PullUpdate
+		}
+		if err := update(args[0], preRelease); err != nil {
+			cli.ReportUpdateFailure(args[0], err)
+			cli.PrintError(err)
 			os.Exit(1)
 		}
 	},
@@ -407,17 +618,21 @@ var asyncCmd = &cobra.Command{
 
 var asyncListCmd = &cobra.Command{
 	Use:   "list",
-	Short: "List async tasks managed by the daemon",
+	Short: "List async tasks across all enabled daemons",
 	RunE: func(cmd *cobra.Command, args []string) error {
 		status, _ := cmd.Flags().GetString("status")
 		origin, _ := cmd.Flags().GetString("origin")
 		session, _ := cmd.Flags().GetString("session")
 		client, _ := cmd.Flags().GetString("client")
+		daemonName, _ := cmd.Flags().GetString("daemon")
+		allUsers, _ := cmd.Flags().GetBool("all-users")
 		return cli.ListAsyncTasks(cli.AsyncListOptions{
-			Status:  status,
-			Origin:  origin,
-			Session: session,
-			Client:  client,
+			Status:   status,
+			Origin:   origin,
+			Session:  session,
+			Client:   client,
+			Daemon:   daemonName,
+			AllUsers: allUsers,
 		})
 	},
 }
@@ -440,6 +655,260 @@ var asyncDeleteCmd = &cobra.Command{
 	},
 }
 
+var workflowCmd = &cobra.Command{
+	Use:   "workflow",
+	Short: "Run multi-step agent workflows",
+}
+
+var workflowListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List workflow definitions",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return cli.ListWorkflows()
+	},
+}
+
+var workflowRunCmd = &cobra.Command{
+	Use:   "run [name]",
+	Short: "Run a workflow as a single async task",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		inputs, _ := cmd.Flags().GetStringArray("input")
+		wait, _ := cmd.Flags().GetBool("wait")
+		return cli.RunWorkflow(args[0], inputs, wait)
+	},
+}
+
+var workflowStatusCmd = &cobra.Command{
+	Use:   "status [task_id]",
+	Short: "Show the step states of a workflow run",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return cli.ShowWorkflowStatus(args[0])
+	},
+}
+
+var triggerCmd = &cobra.Command{
+	Use:   "trigger",
+	Short: "Inspect event triggers",
+}
+
+var triggerListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List triggers and how often they fired",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		allWorkspaces, _ := cmd.Flags().GetBool("all-workspaces")
+		return cli.ListTriggers(allWorkspaces)
+	},
+}
+
+var channelCmd = &cobra.Command{
+	Use:   "channel",
+	Short: "Manage chat channels (Slack, Telegram, email) that talk to agents",
+}
+
+var channelListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List chat channels and their connection state",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return cli.ListChannels()
+	},
+}
+
+var channelAddCmd = &cobra.Command{
+	Use:   "add [name]",
+	Short: "Add a chat channel and start it on the daemon",
+	Long: `Add a chat channel and start it on the daemon. Channels are kept in
+channels.yaml and started again whenever the daemon starts.
+
+Examples:
+  op channel add support --type slack --agent helper --app-token '${SLACK_APP_TOKEN}' --bot-token '${SLACK_BOT_TOKEN}'
+  op channel add phone --type telegram --agent helper --allowed-user 123456789
+  op channel add inbox --type email --agent helper --allowed-sender '*@example.com' --webhook-listen :8025 --webhook-secret s3cret`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ch, err := channelFromFlags(cmd, args[0])
+		if err != nil {
+			return err
+		}
+		return cli.AddChannel(ch)
+	},
+}
+
+var channelRemoveCmd = &cobra.Command{
+	Use:   "remove [name]",
+	Short: "Stop a channel added with 'op channel add' and forget it",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return cli.RemoveChannel(args[0])
+	},
+}
+
+var kbCmd = &cobra.Command{
+	Use:   "kb",
+	Short: "Manage the knowledge base agents search with search_knowledge",
+}
+
+var kbAddCmd = &cobra.Command{
+	Use:   "add [path|url]...",
+	Short: "Add documents to the knowledge base",
+	Long: `Add documents to the knowledge base. Each path may be a file or a
+directory, which is searched for markdown, text, HTML and PDF files; URLs
+are fetched. Documents are split into chunks and embedded; adding a source
+again replaces it. PDFs need pdftotext (poppler-utils) on PATH.
+
+Examples:
+  op kb add ./docs
+  op kb add runbook.pdf https://example.com/guide.html`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return cli.KBAdd(args)
+	},
+}
+
+var kbListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List documents in the knowledge base",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return cli.KBList()
+	},
+}
+
+var kbRemoveCmd = &cobra.Command{
+	Use:   "remove [id|path|url]",
+	Short: "Remove a document, or every document added from a directory",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return cli.KBRemove(args[0])
+	},
+}
+
+var kbSearchCmd = &cobra.Command{
+	Use:   "search [query]",
+	Short: "Search the knowledge base like the search_knowledge tool does",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		limit, _ := cmd.Flags().GetInt("limit")
+		return cli.KBSearch(strings.Join(args, " "), limit)
+	},
+}
+
+var evalCmd = &cobra.Command{
+	Use:   "eval",
+	Short: "Regression-test agents against suites of prompts",
+}
+
+var evalRunCmd = &cobra.Command{
+	Use:   "run [suite.yaml]",
+	Short: "Run an eval suite and report the pass rate",
+	Long: `Run every case of an eval suite against an agent or core agent and
+report which passed. A case sends a prompt, several turns, or the user
+messages of a recorded conversation, and checks the final reply with
+equals, contains, regex or an LLM judge. Conversation cases without checks
+are judged against the conversation's recorded reply.
+
+Example suite:
+  name: support
+  agent: support-bot
+  cases:
+    - name: refund policy
+      prompt: How long do I have to return an item?
+      expect:
+        contains: ["30 days"]
+    - name: escalation
+      conversation: 7f3c2a10-...
+    - name: tone
+      prompt: This is the third time my order is late!
+      expect:
+        judge: Apologizes and offers a concrete next step
+
+Examples:
+  op eval run evals/support.yaml
+  op eval run evals/support.yaml --agent support-bot-v2 --min-pass-rate 0.9`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		agentName, _ := cmd.Flags().GetString("agent")
+		judgeModel, _ := cmd.Flags().GetString("judge-model")
+		keep, _ := cmd.Flags().GetBool("keep")
+		jsonOutput, _ := cmd.Flags().GetBool("json")
+		minPassRate, _ := cmd.Flags().GetFloat64("min-pass-rate")
+		return cli.EvalRun(args[0], cli.EvalOptions{
+			Agent:       agentName,
+			JudgeModel:  judgeModel,
+			Keep:        keep,
+			JSON:        jsonOutput,
+			MinPassRate: minPassRate,
+		})
+	},
+}
+
+// channelFromFlags builds a channel of the --type given to 'op channel add'.
+func channelFromFlags(cmd *cobra.Command, name string) (config.ChannelConfig, error) {
+	flags := cmd.Flags()
+	channelType, _ := flags.GetString("type")
+	agent, _ := flags.GetString("agent")
+	timeout, _ := flags.GetString("timeout")
+	allowedUsers, _ := flags.GetStringArray("allowed-user")
+
+	ch := config.ChannelConfig{Name: name, Type: strings.ToLower(strings.TrimSpace(channelType))}
+	switch ch.Type {
+	case config.ChannelSlack:
+		appToken, _ := flags.GetString("app-token")
+		botToken, _ := flags.GetString("bot-token")
+		routes, _ := flags.GetStringArray("route")
+		ch.Slack = &config.SlackConfig{
+			AppToken:     appToken,
+			BotToken:     botToken,
+			Agent:        agent,
+			AllowedUsers: allowedUsers,
+			Timeout:      timeout,
+		}
+		for _, route := range routes {
+			slackChannel, routeAgent, ok := strings.Cut(route, "=")
+			if !ok || strings.TrimSpace(slackChannel) == "" || strings.TrimSpace(routeAgent) == "" {
+				return ch, fmt.Errorf("invalid --route %q (expected CHANNEL_ID=agent)", route)
+			}
+			if ch.Slack.Channels == nil {
+				ch.Slack.Channels = make(map[string]string)
+			}
+			ch.Slack.Channels[strings.TrimSpace(slackChannel)] = strings.TrimSpace(routeAgent)
+		}
+	case config.ChannelTelegram:
+		tokenSecret, _ := flags.GetString("token-secret")
+		ch.Telegram = &config.TelegramConfig{TokenSecret: tokenSecret, Agent: agent, Timeout: timeout}
+		for _, user := range allowedUsers {
+			id, err := strconv.ParseInt(strings.TrimSpace(user), 10, 64)
+			if err != nil {
+				return ch, fmt.Errorf("invalid --allowed-user %q: telegram user IDs are numeric", user)
+			}
+			ch.Telegram.AllowedUsers = append(ch.Telegram.AllowedUsers, id)
+		}
+	case config.ChannelEmail:
+		senders, _ := flags.GetStringArray("allowed-sender")
+		ch.Email = &config.EmailChannel{Agent: agent, AllowedSenders: senders, Timeout: timeout}
+		if address, _ := flags.GetString("imap-address"); address != "" {
+			username, _ := flags.GetString("imap-username")
+			password, _ := flags.GetString("imap-password")
+			mailbox, _ := flags.GetString("imap-mailbox")
+			interval, _ := flags.GetString("imap-interval")
+			ch.Email.IMAP = &config.EmailIMAP{Address: address, Username: username, Password: password, Mailbox: mailbox, Interval: interval}
+		}
+		if listen, _ := flags.GetString("webhook-listen"); listen != "" {
+			secret, _ := flags.GetString("webhook-secret")
+			ch.Email.Webhook = &config.EmailWebhook{Listen: listen, Secret: secret}
+		}
+		if address, _ := flags.GetString("smtp-address"); address != "" {
+			username, _ := flags.GetString("smtp-username")
+			password, _ := flags.GetString("smtp-password")
+			from, _ := flags.GetString("smtp-from")
+			ch.Email.SMTP = &config.EmailSMTP{Address: address, Username: username, Password: password, From: from}
+		}
+	default:
+		return ch, fmt.Errorf("--type must be slack, telegram or email")
+	}
+	return ch, nil
+}
+
 var setupCmd = &cobra.Command{
 	Use:   "setup",
 	Short: "Run the setup wizard",
@@ -450,13 +919,103 @@ var setupCmd = &cobra.Command{
 	},
 }
 
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show a health overview of all enabled daemons",
+	Long:  "Show agent counts by status, queued and running tasks, tasks completed and failed in the last 24 hours, and the version of every enabled daemon.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		jsonOutput, _ := cmd.Flags().GetBool("json")
+		if err := cli.FleetStats(jsonOutput); err != nil {
+			cli.PrintError(err)
+			os.Exit(1)
+		}
+	},
+}
+
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Back up and restore daemon state",
+	Long: `Snapshot the config directory (agents.yaml, the database and agent
+directories) to S3-compatible storage, and restore it onto a fresh server.
+Secrets in the keyring, logs and virtualenvs are not included.
+
+Configure the bucket in the backup section of daemon.yaml; credentials come
+from AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY. Set backup.interval to have
+the daemon take snapshots on its own.`,
+}
+
+var backupNowCmd = &cobra.Command{
+	Use:   "now",
+	Short: "Take a snapshot now",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		daemonName, _ := cmd.Flags().GetString("daemon")
+		if err := cli.BackupNow(daemonName); err != nil {
+			cli.PrintError(err)
+			os.Exit(1)
+		}
+	},
+}
+
+var backupListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List snapshots in the backup bucket",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		jsonOutput, _ := cmd.Flags().GetBool("json")
+		cfg, err := cli.BackupSettings(backupOverride(cmd))
+		if err == nil {
+			err = cli.ListBackups(cfg, jsonOutput)
+		}
+		if err != nil {
+			cli.PrintError(err)
+			os.Exit(1)
+		}
+	},
+}
+
+var backupRestoreCmd = &cobra.Command{
+	Use:   "restore <snapshot>",
+	Short: "Restore a snapshot into the config directory",
+	Long: `Restore a snapshot, named by its timestamp as shown by 'op backup list' or
+"latest", over the local config directory. Stop the daemon first. On a new
+server, pass --bucket (and --prefix, --region or --endpoint as needed) when
+daemon.yaml has not been restored yet.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if daemon.IsRunning() {
+			cli.PrintError(fmt.Errorf("the daemon is running; stop it with 'op daemon stop' before restoring"))
+			os.Exit(1)
+		}
+		cfg, err := cli.BackupSettings(backupOverride(cmd))
+		if err == nil {
+			err = cli.RestoreBackup(cfg, args[0])
+		}
+		if err != nil {
+			cli.PrintError(err)
+			os.Exit(1)
+		}
+	},
+}
+
+// backupOverride reads the bucket flags shared by backup list and restore.
+func backupOverride(cmd *cobra.Command) config.BackupConfig {
+	var cfg config.BackupConfig
+	cfg.Bucket, _ = cmd.Flags().GetString("bucket")
+	cfg.Region, _ = cmd.Flags().GetString("region")
+	cfg.Prefix, _ = cmd.Flags().GetString("prefix")
+	cfg.Endpoint, _ = cmd.Flags().GetString("endpoint")
+	return cfg
+}
+
 var doctorCmd = &cobra.Command{
 	Use:   "doctor",
 	Short: "Check installation and runtime health",
 	Run: func(cmd *cobra.Command, args []string) {
 		exitCode, err := cli.Doctor(cmd.OutOrStdout())
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			cli.PrintError(err)
 			os.Exit(1)
 		}
 		if exitCode != 0 {
@@ -465,6 +1024,38 @@ var doctorCmd = &cobra.Command{
 	},
 }
 
+var dirsCmd = &cobra.Command{
+	Use:   "dirs",
+	Short: "Show where config, data and logs are stored",
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := cli.ShowDirs(); err != nil {
+			cli.PrintError(err)
+			os.Exit(1)
+		}
+	},
+}
+
+var dirsMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Move the database and logs to the XDG data and state directories",
+	Long: `Move opperator.db and agent_data.json to $XDG_DATA_HOME/opperator
+(~/.local/share/opperator) and logs/ to $XDG_STATE_HOME/opperator
+(~/.local/state/opperator), or to OPPERATOR_DATA_DIR when set. Stop the
+daemon first.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		if !dryRun && daemon.IsRunning() {
+			cli.PrintError(fmt.Errorf("the daemon is running; stop it with 'op daemon stop' before migrating"))
+			os.Exit(1)
+		}
+		if err := cli.MigrateDirs(dryRun); err != nil {
+			cli.PrintError(err)
+			os.Exit(1)
+		}
+	},
+}
+
 var agentCmd = &cobra.Command{
 	Use:   "agent",
 	Short: "Manage agents",
@@ -478,6 +1069,7 @@ var listCmd = &cobra.Command{
 		stoppedOnly, _ := cmd.Flags().GetBool("stopped")
 		crashedOnly, _ := cmd.Flags().GetBool("crashed")
 		daemonFilter, _ := cmd.Flags().GetString("daemon")
+		allWorkspaces, _ := cmd.Flags().GetBool("all-workspaces")
 
 		// Ensure only one filter is used at a time
 		filters := 0
@@ -494,8 +1086,8 @@ var listCmd = &cobra.Command{
 			fmt.Fprintln(os.Stderr, "Use at most one of --running, --stopped, --crashed")
 			os.Exit(1)
 		}
-		if err := cli.ListAgents(runningOnly, stoppedOnly, crashedOnly, daemonFilter); err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		if err := cli.ListAgents(runningOnly, stoppedOnly, crashedOnly, daemonFilter, allWorkspaces); err != nil {
+			cli.PrintError(err)
 			os.Exit(1)
 		}
 	},
@@ -508,7 +1100,7 @@ var startCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		daemon, _ := cmd.Flags().GetString("daemon")
 		if err := cli.StartAgent(args[0], daemon); err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			cli.PrintError(err)
 			os.Exit(1)
 		}
 	},
@@ -521,15 +1113,17 @@ var stopCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		stopAll, _ := cmd.Flags().GetBool("all")
 		daemon, _ := cmd.Flags().GetString("daemon")
+		force, _ := cmd.Flags().GetBool("force")
+		grace, _ := cmd.Flags().GetDuration("grace-period")
 
 		if stopAll {
 			if err := cli.StopAllAgents(); err != nil {
-				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				cli.PrintError(err)
 				os.Exit(1)
 			}
 		} else if len(args) == 1 {
-			if err := cli.StopAgent(args[0], daemon); err != nil {
-				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			if err := cli.StopAgent(args[0], daemon, force, grace); err != nil {
+				cli.PrintError(err)
 				os.Exit(1)
 			}
 		} else {
@@ -546,7 +1140,34 @@ var restartCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		daemon, _ := cmd.Flags().GetString("daemon")
 		if err := cli.RestartAgent(args[0], daemon); err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			cli.PrintError(err)
+			os.Exit(1)
+		}
+	},
+}
+
+var pauseCmd = &cobra.Command{
+	Use:   "pause [name]",
+	Short: "Keep an agent running but reject its commands and triggers until resumed",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		daemon, _ := cmd.Flags().GetString("daemon")
+		reason, _ := cmd.Flags().GetString("reason")
+		if err := cli.PauseAgent(args[0], daemon, reason); err != nil {
+			cli.PrintError(err)
+			os.Exit(1)
+		}
+	},
+}
+
+var resumeCmd = &cobra.Command{
+	Use:   "resume [name]",
+	Short: "Let a paused agent take commands again",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		daemon, _ := cmd.Flags().GetString("daemon")
+		if err := cli.ResumeAgent(args[0], daemon); err != nil {
+			cli.PrintError(err)
 			os.Exit(1)
 		}
 	},
@@ -560,7 +1181,7 @@ var bootstrapCmd = &cobra.Command{
 		description, _ := cmd.Flags().GetString("description")
 		noStart, _ := cmd.Flags().GetBool("no-start")
 		if err := cli.BootstrapAgent(args[0], description, noStart); err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			cli.PrintError(err)
 			os.Exit(1)
 		}
 	},
@@ -574,7 +1195,7 @@ var deleteCmd = &cobra.Command{
 		force, _ := cmd.Flags().GetBool("force")
 		daemonName, _ := cmd.Flags().GetString("daemon")
 		if err := cli.DeleteAgent(args[0], force, daemonName); err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			cli.PrintError(err)
 			os.Exit(1)
 		}
 	},
@@ -595,7 +1216,7 @@ var moveCmd = &cobra.Command{
 		}
 
 		if err := cli.MoveAgent(args[0], toDaemon, force, noStart); err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			cli.PrintError(err)
 			os.Exit(1)
 		}
 	},
@@ -607,7 +1228,7 @@ var whereCmd = &cobra.Command{
 	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		if err := cli.WhereIsAgent(args[0]); err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			cli.PrintError(err)
 			os.Exit(1)
 		}
 	},
@@ -618,8 +1239,9 @@ var reloadCmd = &cobra.Command{
 	Short: "Reload configuration (use --daemon to specify which daemon)",
 	Run: func(cmd *cobra.Command, args []string) {
 		daemon, _ := cmd.Flags().GetString("daemon")
-		if err := cli.ReloadConfig(daemon); err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		fromGit, _ := cmd.Flags().GetBool("from-git")
+		if err := cli.ReloadConfig(daemon, fromGit); err != nil {
+			cli.PrintError(err)
 			os.Exit(1)
 		}
 	},
@@ -633,36 +1255,442 @@ var logsCmd = &cobra.Command{
 		follow, _ := cmd.Flags().GetBool("follow")
 		lines, _ := cmd.Flags().GetInt("lines")
 		daemon, _ := cmd.Flags().GetString("daemon")
+		crash, _ := cmd.Flags().GetBool("crash")
+
+		if crash {
+			if err := cli.ShowCrashReport(args[0], daemon); err != nil {
+				cli.PrintError(err)
+				os.Exit(1)
+			}
+			return
+		}
 
 		if err := cli.GetLogs(args[0], follow, lines, daemon); err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			cli.PrintError(err)
 			os.Exit(1)
 		}
 	},
 }
 
-var commandCmd = &cobra.Command{
-	Use:   "command [name] [command] [args...]",
-	Short: "Send a command to a managed agent (auto-detects daemon or use --daemon)",
-	Long: `Send a command to a managed agent with either natural language arguments or JSON.
+var logsRootCmd = &cobra.Command{
+	Use:   "logs",
+	Short: "Work with the logs of every agent",
+}
 
-Examples:
-  # Natural language arguments (LLM-parsed)
-  op agent command weather-agent get_forecast "from 2nd march to 10th march in London"
+var logsSearchCmd = &cobra.Command{
+	Use:   "search [pattern]",
+	Short: "Search agent logs with a regular expression",
+	Long: `Search the logs the daemons keep for their agents (the last 10,000 lines
+of each) with a regular expression. Every enabled daemon is searched unless
+--daemon names one; daemons that cannot be reached are reported and the
+results marked partial.
 
-  # JSON arguments (precise control)
-  op agent command weather-agent get_forecast --args '{"start":"2024-03-02","end":"2024-03-10","city":"London"}'
+Examples:
+  op logs search "timeout|connection refused" --since 2h
+  op logs search -i error --agent my-agent -C 3`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		agentName, _ := cmd.Flags().GetString("agent")
+		since, _ := cmd.Flags().GetString("since")
+		daemonName, _ := cmd.Flags().GetString("daemon")
+		context, _ := cmd.Flags().GetInt("context")
+		ignoreCase, _ := cmd.Flags().GetBool("ignore-case")
+		limit, _ := cmd.Flags().GetInt("limit")
+		return cli.SearchLogs(args[0], cli.LogSearchOptions{
+			Agent:      agentName,
+			Since:      since,
+			Daemon:     daemonName,
+			Context:    context,
+			IgnoreCase: ignoreCase,
+			Limit:      limit,
+		})
+	},
+}
 
-  # No arguments
-  op agent command my-agent refresh`,
-	Args: cobra.MinimumNArgs(2),
+var agentDevCmd = &cobra.Command{
+	Use:   "dev [name]",
+	Short: "Run a local agent in the foreground, restarting it when its source changes",
+	Long: `Start the agent on the local daemon and watch its source directory. Every
+change restarts the agent. Its logs and the protocol messages exchanged with
+the daemon are printed until Ctrl+C, which stops the agent again if this
+command started it.
+
+Protocol messages are also written in full, one JSON object per line, to
+--frames-file (default: <logs dir>/<name>.frames.jsonl) for debugging the
+SDK integration.`,
+	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		agentName := args[0]
-		commandName := args[1]
+		framesFile, _ := cmd.Flags().GetString("frames-file")
+		quiet, _ := cmd.Flags().GetBool("quiet")
+		if err := cli.AgentDev(args[0], framesFile, quiet); err != nil {
+			cli.PrintError(err)
+			os.Exit(1)
+		}
+	},
+}
 
-		argsJSON, _ := cmd.Flags().GetString("args")
+var agentTraceCmd = &cobra.Command{
+	Use:   "trace [name]",
+	Short: "Show the protocol messages exchanged between the daemon and an agent",
+	Long: `Pretty-print the JSON protocol messages recently exchanged between the
+daemon and an agent (commands, responses, progress, lifecycle events, logs,
+metadata updates), oldest first, with their time and direction: ← from the
+agent, → to it. The daemon keeps the last 500 per agent.
+
+Examples:
+  op agent trace my-agent -f --type command,response
+  op agent trace my-agent --save trace.jsonl
+  op agent trace --file trace.jsonl`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var opts cli.TraceOptions
+		opts.Daemon, _ = cmd.Flags().GetString("daemon")
+		opts.Types, _ = cmd.Flags().GetStringSlice("type")
+		opts.Follow, _ = cmd.Flags().GetBool("follow")
+		opts.Save, _ = cmd.Flags().GetString("save")
+		opts.File, _ = cmd.Flags().GetString("file")
+		var name string
+		if len(args) > 0 {
+			name = args[0]
+		}
+		if name == "" && opts.File == "" {
+			cli.PrintError(fmt.Errorf("name an agent, or a saved trace with --file"))
+			os.Exit(1)
+		}
+		if err := cli.AgentTrace(name, opts); err != nil {
+			cli.PrintError(err)
+			os.Exit(1)
+		}
+	},
+}
+
+var agentStatusCmd = &cobra.Command{
+	Use:   "status [name]",
+	Short: "Show an agent's status, commands and sidebar sections (auto-detects daemon or use --daemon)",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		daemon, _ := cmd.Flags().GetString("daemon")
+		jsonOutput, _ := cmd.Flags().GetBool("json")
+		if err := cli.AgentStatus(args[0], daemon, jsonOutput); err != nil {
+			cli.PrintError(err)
+			os.Exit(1)
+		}
+	},
+}
+
+var budgetCmd = &cobra.Command{
+	Use:   "budget [name]",
+	Short: "Show today's model calls and estimated cost of agents against their budgets",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var name string
+		if len(args) > 0 {
+			name = args[0]
+		}
+		daemon, _ := cmd.Flags().GetString("daemon")
+		jsonOutput, _ := cmd.Flags().GetBool("json")
+		if err := cli.BudgetStatus(name, daemon, jsonOutput); err != nil {
+			cli.PrintError(err)
+			os.Exit(1)
+		}
+	},
+}
+
+var depsCmd = &cobra.Command{
+	Use:   "deps [name]",
+	Short: "Audit an agent's dependencies for outdated or vulnerable packages",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		update, _ := cmd.Flags().GetBool("update")
+		jsonOutput, _ := cmd.Flags().GetBool("json")
+		if err := cli.AuditAgentDependencies(args[0], update, jsonOutput); err != nil {
+			cli.PrintError(err)
+			os.Exit(1)
+		}
+	},
+}
+
+var agentTestCmd = &cobra.Command{
+	Use:   "test [name] [tests.yaml]",
+	Short: "Run an agent under an isolated daemon and check its commands against a tests file",
+	Long: `Start an agent from agents.yaml under a separate daemon with its own
+temporary config dir, socket and database, then invoke its commands as
+listed in the tests file and check their results and logs:
+
+  tests:
+    - name: forecast for Paris
+      command: get_forecast
+      args: {city: Paris}
+      expect:
+        contains: ["Paris"]
+        log: fetched forecast
+
+Without a tests file only starting the agent is checked. Go tests can do the
+same with the opperator/pkg/agenttest package.`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
 		timeout, _ := cmd.Flags().GetDuration("timeout")
+		testsPath := ""
+		if len(args) > 1 {
+			testsPath = args[1]
+		}
+		if err := cli.TestAgent(args[0], testsPath, timeout); err != nil {
+			cli.PrintError(err)
+			os.Exit(1)
+		}
+	},
+}
+
+var kvCmd = &cobra.Command{
+	Use:   "kv",
+	Short: "Inspect and edit the key-value state the daemon keeps for an agent",
+}
+
+var kvGetCmd = &cobra.Command{
+	Use:   "get [name] [key]",
+	Short: "Print an agent's value for a key as JSON",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		daemon, _ := cmd.Flags().GetString("daemon")
+		if err := cli.KVGet(args[0], args[1], daemon); err != nil {
+			cli.PrintError(err)
+			os.Exit(1)
+		}
+	},
+}
+
+var kvSetCmd = &cobra.Command{
+	Use:   "set [name] [key] [value]",
+	Short: "Set an agent's value for a key (JSON, or else stored as a string)",
+	Args:  cobra.ExactArgs(3),
+	Run: func(cmd *cobra.Command, args []string) {
 		daemon, _ := cmd.Flags().GetString("daemon")
+		if err := cli.KVSet(args[0], args[1], args[2], daemon); err != nil {
+			cli.PrintError(err)
+			os.Exit(1)
+		}
+	},
+}
+
+var kvDeleteCmd = &cobra.Command{
+	Use:   "delete [name] [key]",
+	Short: "Delete an agent's value for a key",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		daemon, _ := cmd.Flags().GetString("daemon")
+		if err := cli.KVDelete(args[0], args[1], daemon); err != nil {
+			cli.PrintError(err)
+			os.Exit(1)
+		}
+	},
+}
+
+var kvListCmd = &cobra.Command{
+	Use:   "list [name] [prefix]",
+	Short: "List an agent's keys, or those starting with a prefix, and their values",
+	Args:  cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		daemon, _ := cmd.Flags().GetString("daemon")
+		jsonOutput, _ := cmd.Flags().GetBool("json")
+		prefix := ""
+		if len(args) > 1 {
+			prefix = args[1]
+		}
+		if err := cli.KVList(args[0], prefix, daemon, jsonOutput); err != nil {
+			cli.PrintError(err)
+			os.Exit(1)
+		}
+	},
+}
+
+var blackboardCmd = &cobra.Command{
+	Use:   "blackboard",
+	Short: "Inspect and edit the key-value namespaces agents share",
+	Long: `Blackboards are key-value namespaces shared between agents, declared with
+the agents that may read and write them under blackboards in daemon.yaml.
+Operators can read and write every blackboard.`,
+}
+
+var blackboardGetCmd = &cobra.Command{
+	Use:   "get [namespace] [key]",
+	Short: "Print the value of a key on a blackboard as JSON",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		daemon, _ := cmd.Flags().GetString("daemon")
+		if err := cli.BlackboardGet(args[0], args[1], daemon); err != nil {
+			cli.PrintError(err)
+			os.Exit(1)
+		}
+	},
+}
+
+var blackboardSetCmd = &cobra.Command{
+	Use:   "set [namespace] [key] [value]",
+	Short: "Set a key on a blackboard (JSON, or else stored as a string)",
+	Args:  cobra.ExactArgs(3),
+	Run: func(cmd *cobra.Command, args []string) {
+		daemon, _ := cmd.Flags().GetString("daemon")
+		if err := cli.BlackboardSet(args[0], args[1], args[2], daemon); err != nil {
+			cli.PrintError(err)
+			os.Exit(1)
+		}
+	},
+}
+
+var blackboardDeleteCmd = &cobra.Command{
+	Use:   "delete [namespace] [key]",
+	Short: "Delete a key from a blackboard",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		daemon, _ := cmd.Flags().GetString("daemon")
+		if err := cli.BlackboardDelete(args[0], args[1], daemon); err != nil {
+			cli.PrintError(err)
+			os.Exit(1)
+		}
+	},
+}
+
+var blackboardListCmd = &cobra.Command{
+	Use:   "list [namespace] [prefix]",
+	Short: "List a blackboard's keys, or those starting with a prefix, and who wrote them",
+	Args:  cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		daemon, _ := cmd.Flags().GetString("daemon")
+		jsonOutput, _ := cmd.Flags().GetBool("json")
+		prefix := ""
+		if len(args) > 1 {
+			prefix = args[1]
+		}
+		if err := cli.BlackboardList(args[0], prefix, daemon, jsonOutput); err != nil {
+			cli.PrintError(err)
+			os.Exit(1)
+		}
+	},
+}
+
+var promptCmd = &cobra.Command{
+	Use:   "prompt",
+	Short: "Inspect and revert the history of an agent's system prompt",
+}
+
+var promptHistoryCmd = &cobra.Command{
+	Use:   "history [name]",
+	Short: "List recorded versions of an agent's system prompt and description",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		daemon, _ := cmd.Flags().GetString("daemon")
+		if err := cli.ShowPromptHistory(args[0], daemon); err != nil {
+			cli.PrintError(err)
+			os.Exit(1)
+		}
+	},
+}
+
+var promptDiffCmd = &cobra.Command{
+	Use:   "diff [name] [id] [id]",
+	Short: "Show what changed between two prompt versions (or a version and the one before it)",
+	Args:  cobra.RangeArgs(2, 3),
+	Run: func(cmd *cobra.Command, args []string) {
+		daemon, _ := cmd.Flags().GetString("daemon")
+		ids, err := parsePromptVersionIDs(args[1:])
+		if err != nil {
+			cli.PrintError(err)
+			os.Exit(1)
+		}
+		to := int64(0)
+		if len(ids) > 1 {
+			to = ids[1]
+		}
+		if err := cli.DiffPrompts(args[0], ids[0], to, daemon); err != nil {
+			cli.PrintError(err)
+			os.Exit(1)
+		}
+	},
+}
+
+var promptRevertCmd = &cobra.Command{
+	Use:   "revert [name] [id]",
+	Short: "Restore an agent's system prompt and description from its history",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		daemon, _ := cmd.Flags().GetString("daemon")
+		ids, err := parsePromptVersionIDs(args[1:])
+		if err != nil {
+			cli.PrintError(err)
+			os.Exit(1)
+		}
+		if err := cli.RevertPrompt(args[0], ids[0], daemon); err != nil {
+			cli.PrintError(err)
+			os.Exit(1)
+		}
+	},
+}
+
+func parsePromptVersionIDs(args []string) ([]int64, error) {
+	ids := make([]int64, 0, len(args))
+	for _, arg := range args {
+		id, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("invalid prompt version %q; see 'op agent prompt history'", arg)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+var commandCmd = &cobra.Command{
+	Use:   "command [name] [command] [args...]",
+	Short: "Send a command to a managed agent (auto-detects daemon or use --daemon)",
+	Long: `Send a command to a managed agent with either natural language arguments or JSON.
+
+Examples:
+  # Natural language arguments (LLM-parsed)
+  op agent command weather-agent get_forecast "from 2nd march to 10th march in London"
+
+  # JSON arguments (precise control)
+  op agent command weather-agent get_forecast --args '{"start":"2024-03-02","end":"2024-03-10","city":"London"}'
+
+  # No arguments
+  op agent command my-agent refresh
+
+  # Pass a large file: the agent receives the path of an uploaded copy
+  op agent command importer load --args '{"file":"@data/export.csv"}'
+
+  # Save the files the command outputs
+  op agent command reporter weekly_report --output-dir ./reports
+
+  # Run in another directory instead of the current one
+  op agent command my-agent lint --cwd ~/src/project
+
+  # Run a past invocation again with the same arguments
+  op agent command --replay 0f8c2e7a-...`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if replay, _ := cmd.Flags().GetString("replay"); replay != "" {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.MinimumNArgs(2)(cmd, args)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if replay, _ := cmd.Flags().GetString("replay"); replay != "" {
+			timeout, _ := cmd.Flags().GetDuration("timeout")
+			daemon, _ := cmd.Flags().GetString("daemon")
+			if err := cli.ReplayCommand(replay, timeout, daemon); err != nil {
+				cli.PrintError(err)
+				flushTracing()
+				os.Exit(1)
+			}
+			return
+		}
+
+		agentName := args[0]
+		commandName := args[1]
+
+		argsJSON, _ := cmd.Flags().GetString("args")
+		var opts cli.CommandOptions
+		opts.Timeout, _ = cmd.Flags().GetDuration("timeout")
+		opts.Daemon, _ = cmd.Flags().GetString("daemon")
+		opts.Dir, _ = cmd.Flags().GetString("cwd")
+		opts.OutputDir, _ = cmd.Flags().GetString("output-dir")
 
 		// Check if raw text args provided (everything after command name)
 		if len(args) > 2 && argsJSON == "" {
@@ -670,8 +1698,9 @@ Examples:
 			rawInput := strings.Join(args[2:], " ")
 
 			// Parse using LLM
-			if err := cli.InvokeCommandWithParsing(agentName, commandName, rawInput, timeout, daemon); err != nil {
-				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			if err := cli.InvokeCommandWithParsing(agentName, commandName, rawInput, opts); err != nil {
+				cli.PrintError(err)
+				flushTracing()
 				os.Exit(1)
 			}
 			return
@@ -686,8 +1715,9 @@ Examples:
 			}
 		}
 
-		if err := cli.InvokeCommand(agentName, commandName, payload, timeout, daemon); err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		if err := cli.InvokeCommand(agentName, commandName, payload, opts); err != nil {
+			cli.PrintError(err)
+			flushTracing()
 			os.Exit(1)
 		}
 	},
@@ -700,7 +1730,7 @@ var listCommandsCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		daemon, _ := cmd.Flags().GetString("daemon")
 		if err := cli.ListAgentCommands(args[0], daemon); err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			cli.PrintError(err)
 			os.Exit(1)
 		}
 	},
@@ -721,8 +1751,9 @@ var secretCreateCmd = &cobra.Command{
 		if len(args) > 1 {
 			value = args[1]
 		}
-		if err := cli.CreateSecret(name, value); err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		daemon, _ := cmd.Flags().GetString("daemon")
+		if err := cli.CreateSecret(name, value, daemon); err != nil {
+			cli.PrintError(err)
 			os.Exit(1)
 		}
 	},
@@ -738,21 +1769,83 @@ var secretUpdateCmd = &cobra.Command{
 		if len(args) > 1 {
 			value = args[1]
 		}
-		if err := cli.UpdateSecret(name, value); err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		daemon, _ := cmd.Flags().GetString("daemon")
+		if err := cli.UpdateSecret(name, value, daemon); err != nil {
+			cli.PrintError(err)
+			os.Exit(1)
+		}
+	},
+}
+
+var secretRotateCmd = &cobra.Command{
+	Use:   "rotate [name] [value]",
+	Short: "Replace a secret everywhere it is used and tell the agents using it",
+	Long: `Give an existing secret a new value, typed, passed as an argument or
+generated with --generate. The new value also replaces the secret on every
+remote daemon that registered it, and agents that use the secret receive a
+secret_rotated lifecycle event so they can refresh their connections.
+
+Examples:
+  op secret rotate CRM_TOKEN --generate 32
+  op secret rotate OPPER_API_KEY --daemon prod`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		value := ""
+		if len(args) > 1 {
+			value = args[1]
+		}
+		generate, _ := cmd.Flags().GetInt("generate")
+		daemon, _ := cmd.Flags().GetString("daemon")
+		if err := cli.RotateSecret(args[0], value, generate, daemon); err != nil {
+			cli.PrintError(err)
 			os.Exit(1)
 		}
 	},
 }
 
+var secretEnvCmd = &cobra.Command{
+	Use:   "env <agent> [-- command [args...]]",
+	Short: "Print or apply an agent's environment, secrets included, for local development",
+	Long: `Build the environment the daemon gives an agent: its env entries, the
+secrets mapped in its secrets section, its opper_api_key and the variables
+the SDK reads. Values come from the local agents.yaml and keyring.
+
+Without a command, print export statements to eval in a shell. After --,
+run the command with the environment added instead.
+
+Examples:
+  eval "$(op secret env my-agent)"
+  op secret env my-agent -- python main.py`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var command []string
+		if dash := cmd.ArgsLenAtDash(); dash >= 0 {
+			if dash != 1 {
+				cli.PrintError(fmt.Errorf("expected exactly one agent name before --"))
+				os.Exit(1)
+			}
+			command = args[dash:]
+		} else if len(args) > 1 {
+			cli.PrintError(fmt.Errorf("put the command to run after --, e.g. op secret env %s -- %s", args[0], strings.Join(args[1:], " ")))
+			os.Exit(1)
+		}
+		code, err := cli.SecretEnv(args[0], command)
+		if err != nil {
+			cli.PrintError(err)
+		}
+		os.Exit(code)
+	},
+}
+
 var secretDeleteCmd = &cobra.Command{
 	Use:   "delete [name]",
 	Short: "Remove a stored secret",
 	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		name := args[0]
-		if err := cli.DeleteSecret(name); err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		daemon, _ := cmd.Flags().GetString("daemon")
+		if err := cli.DeleteSecret(name, daemon); err != nil {
+			cli.PrintError(err)
 			os.Exit(1)
 		}
 	},
@@ -766,19 +1859,38 @@ var secretReadCmd = &cobra.Command{
 		name := args[0]
 		value, err := cli.ReadSecret(name)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			cli.PrintError(err)
 			os.Exit(1)
 		}
 		fmt.Println(value)
 	},
 }
 
+var secretEncryptCmd = &cobra.Command{
+	Use:   "encrypt [value]",
+	Short: "Encrypt a value for agents.yaml with the keyring's config key",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		value := ""
+		if len(args) > 0 {
+			value = args[0]
+		}
+		encrypted, err := cli.EncryptValue(value)
+		if err != nil {
+			cli.PrintError(err)
+			os.Exit(1)
+		}
+		fmt.Println(encrypted)
+	},
+}
+
 var secretListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List secrets registered with opperator",
 	Run: func(cmd *cobra.Command, args []string) {
-		if err := cli.ListSecrets(); err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		allWorkspaces, _ := cmd.Flags().GetBool("all-workspaces")
+		if err := cli.ListSecrets(allWorkspaces); err != nil {
+			cli.PrintError(err)
 			os.Exit(1)
 		}
 	},
@@ -791,7 +1903,7 @@ var secretStatusCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		name := args[0]
 		if err := cli.SecretStatus(name); err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			cli.PrintError(err)
 			os.Exit(1)
 		}
 	},
@@ -819,7 +1931,7 @@ var versionCheckCmd = &cobra.Command{
 		info, err := updater.CheckForUpdates(includePrerelease)
 		if err != nil {
 			// Handle "no releases" gracefully
-			if strings.Contains(err.Error(), "no releases found") {
+			if errors.Is(err, updater.ErrNoReleases) {
 				fmt.Printf("Current version: %s\n", version.Get())
 				fmt.Println("\n✓ No releases published yet on GitHub")
 				fmt.Println("Once you publish a release, the updater will be able to check for updates")
@@ -844,6 +1956,13 @@ var versionCheckCmd = &cobra.Command{
 var versionUpdateCmd = &cobra.Command{
 	Use:   "update",
 	Short: "Update to the latest version",
+	Long: `Update this installation to the latest release, then every enabled cloud daemon.
+
+With --rollout canary one cloud daemon is updated first and watched for the
+--soak period. The others are then updated one at a time, each once the one
+before it answers again. If a daemon fails to update, does not come back or
+its agents start crash-looping, the rollout stops and every daemon updated so
+far is restored to its previous binary.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		includePrerelease, _ := cmd.Flags().GetBool("pre-release")
 		fmt.Println("Checking for updates...")
@@ -863,6 +1982,7 @@ var versionUpdateCmd = &cobra.Command{
 		fmt.Println("Downloading and installing update...")
 
 		if err := updater.DownloadAndInstall(info); err != nil {
+			cli.ReportUpdateFailure("local", err)
 			fmt.Fprintf(os.Stderr, "Error installing update: %v\n", err)
 			os.Exit(1)
 		}
@@ -889,7 +2009,11 @@ var versionUpdateCmd = &cobra.Command{
 		}
 
 		// Update all cloud daemons
-		if err := deployment.UpdateAllCloudDaemons(includePrerelease); err != nil {
+		strategy, _ := cmd.Flags().GetString("rollout")
+		canary, _ := cmd.Flags().GetString("canary")
+		soak, _ := cmd.Flags().GetDuration("soak")
+		rollout := deployment.RolloutOptions{Strategy: strategy, Canary: canary, Soak: soak}
+		if err := deployment.UpdateAllCloudDaemons(includePrerelease, rollout); err != nil {
 			fmt.Printf("Warning: Some cloud daemon updates may have failed: %v\n", err)
 		}
 
@@ -904,26 +2028,378 @@ var execCmd = &cobra.Command{
 
 Activity is streamed to stderr, while the final assistant response is written to stdout.
 This allows piping the output to other commands while still seeing progress.
+When stderr is a terminal, the streamed response is rendered as markdown
+(headings, lists, highlighted code blocks) block by block; --plain streams the
+raw text instead.
+
+With --interactive (-i), messages are read from stdin line by line and sent
+to the same conversation, with line editing and history in a terminal. A line
+"<<EOF" starts a multi-line message that ends at a line reading "EOF". This
+is a lighter alternative to the TUI for limited terminals.
+
+With --detach, the message is queued on the local daemon, which answers it in
+the background. The handle printed on stdout can be passed to
+'op exec attach' to follow progress and print the response.
+
+With --listen, a local web chat UI is served instead, backed by the same
+conversation loop and daemon connections. Conversations started there can be
+resumed from the TUI.
 
 Examples:
   op exec "What is the weather today?" --agent weather-bot
   op exec "Continue our discussion" --resume 1234567890
-  op exec "Hello" --agent assistant | jq -r .`,
-	Args: cobra.ExactArgs(1),
+  op exec "Hello" --agent assistant | jq -r .
+  op exec "What's the forecast?" --agent weather-bot --mock-tools fixtures.yaml
+  op exec "Summarise today's logs" --agent ops --detach
+  op exec -i --agent assistant
+  op exec --listen 127.0.0.1:7777 --agent assistant`,
+	Args: cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		message := args[0]
 		agentName, _ := cmd.Flags().GetString("agent")
+		if listen, _ := cmd.Flags().GetString("listen"); listen != "" {
+			token, _ := cmd.Flags().GetString("token")
+			if err := cli.ServeWebChat(listen, token, agentName); err != nil {
+				cli.PrintError(err)
+				flushTracing()
+				os.Exit(1)
+			}
+			return
+		}
 		conversationID, _ := cmd.Flags().GetString("resume")
+		plain, _ := cmd.Flags().GetBool("plain")
+		var limits config.Limits
+		limits.MaxRounds, _ = cmd.Flags().GetInt("max-rounds")
+		limits.TurnTimeout, _ = cmd.Flags().GetDuration("turn-timeout")
+		limits.Timeout, _ = cmd.Flags().GetDuration("timeout")
+		if interactive, _ := cmd.Flags().GetBool("interactive"); interactive {
+			if len(args) > 0 {
+				cli.PrintError(fmt.Errorf("--interactive reads messages from stdin and takes no message argument"))
+				os.Exit(1)
+			}
+			if err := cli.ExecInteractive(agentName, conversationID, plain, limits); err != nil {
+				cli.PrintError(err)
+				flushTracing()
+				os.Exit(1)
+			}
+			return
+		}
+		if len(args) == 0 {
+			cli.PrintError(fmt.Errorf("a message is required unless --listen or --interactive is set"))
+			os.Exit(1)
+		}
+		message := args[0]
+		if detach, _ := cmd.Flags().GetBool("detach"); detach {
+			if err := cli.ExecDetached(message, agentName, conversationID); err != nil {
+				cli.PrintError(err)
+				os.Exit(1)
+			}
+			return
+		}
 		jsonMode, _ := cmd.Flags().GetBool("json")
 		noSave, _ := cmd.Flags().GetBool("no-save")
+		record, _ := cmd.Flags().GetBool("record")
+		mockTools, _ := cmd.Flags().GetString("mock-tools")
+
+		if err := cli.ExecMessage(message, agentName, conversationID, jsonMode, plain, noSave, record, mockTools, limits); err != nil {
+			cli.PrintError(err)
+			flushTracing()
+			os.Exit(1)
+		}
+	},
+}
+
+var execAttachCmd = &cobra.Command{
+	Use:   "attach <handle>",
+	Short: "Follow a message sent with 'op exec --detach'",
+	Long: `Follow a detached exec until it finishes. Progress is printed to stderr
+and the response to stdout. A unique prefix of the handle is enough.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := cli.AttachExec(args[0]); err != nil {
+			cli.PrintError(err)
+			os.Exit(1)
+		}
+	},
+}
+
+var conversationCmd = &cobra.Command{
+	Use:   "conversation",
+	Short: "Work with saved conversations",
+}
+
+var conversationShareCmd = &cobra.Command{
+	Use:   "share [id]",
+	Short: "Export a conversation with secrets redacted, optionally uploading it",
+	Long: `Render a conversation as markdown or HTML for sharing. Stored secrets,
+credential-looking tokens and password/token assignments are replaced with
+[REDACTED] before anything leaves the machine.
+
+With --upload, the rendering is sent to the paste service or S3 bucket
+configured in share.yaml and the shareable URL is printed.
+
+Examples:
+  op conversation share 1234567890 > chat.md
+  op conversation share 1234567890 --format html --strip-tools --output chat.html
+  op conversation share 1234567890 --upload --redact internal.example.com`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, _ := cmd.Flags().GetString("format")
+		stripTools, _ := cmd.Flags().GetBool("strip-tools")
+		output, _ := cmd.Flags().GetString("output")
+		upload, _ := cmd.Flags().GetBool("upload")
+		redact, _ := cmd.Flags().GetStringArray("redact")
+		return cli.ShareConversation(args[0], cli.ShareOptions{
+			Format:     format,
+			StripTools: stripTools,
+			Output:     output,
+			Upload:     upload,
+			Redact:     redact,
+		})
+	},
+}
+
+var workspaceCmd = &cobra.Command{
+	Use:   "workspace",
+	Short: "Group agents, conversations, triggers and secrets into workspaces",
+	Long: `Workspaces group agents, secrets and conversations under a name, such as
+"client-a" and "personal". While a workspace is active, 'op agent list',
+'op secret list' and 'op trigger list' only show its members (triggers follow
+the agent they invoke), the TUI session list shows its conversations, new
+agents, secrets and conversations join it, and its default daemon and model
+are used.
+
+OPPERATOR_WORKSPACE selects a workspace for one shell, overriding
+'op workspace use'.`,
+}
+
+var workspaceCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create a workspace",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		description, _ := cmd.Flags().GetString("description")
+		daemonName, _ := cmd.Flags().GetString("daemon")
+		model, _ := cmd.Flags().GetString("model")
+		use, _ := cmd.Flags().GetBool("use")
+		ws := config.Workspace{Name: args[0], Description: description, Daemon: daemonName, Model: model}
+		if err := cli.CreateWorkspace(ws, use); err != nil {
+			cli.PrintError(err)
+			os.Exit(1)
+		}
+	},
+}
+
+var workspaceListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List workspaces",
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := cli.ListWorkspaces(); err != nil {
+			cli.PrintError(err)
+			os.Exit(1)
+		}
+	},
+}
+
+var workspaceUseCmd = &cobra.Command{
+	Use:   "use [name]",
+	Short: "Switch the active workspace",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		clear, _ := cmd.Flags().GetBool("clear")
+		if clear == (len(args) == 1) {
+			cli.PrintError(fmt.Errorf("pass a workspace name or --clear"))
+			os.Exit(1)
+		}
+		name := ""
+		if len(args) == 1 {
+			name = args[0]
+		}
+		if err := cli.UseWorkspace(name); err != nil {
+			cli.PrintError(err)
+			os.Exit(1)
+		}
+	},
+}
+
+var workspaceShowCmd = &cobra.Command{
+	Use:   "show [name]",
+	Short: "Show a workspace's defaults and members (default: the active one)",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := ""
+		if len(args) == 1 {
+			name = args[0]
+		}
+		if err := cli.ShowWorkspace(name); err != nil {
+			cli.PrintError(err)
+			os.Exit(1)
+		}
+	},
+}
 
-		if err := cli.ExecMessage(message, agentName, conversationID, jsonMode, noSave); err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+var workspaceSetCmd = &cobra.Command{
+	Use:   "set <name>",
+	Short: "Change a workspace's description, default daemon or model",
+	Long: `Change a workspace's settings. Only the flags given are changed; pass an
+empty value, e.g. --model "", to clear a default.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		var changes cli.WorkspaceChanges
+		for flag, field := range map[string]**string{
+			"description": &changes.Description,
+			"daemon":      &changes.Daemon,
+			"model":       &changes.Model,
+		} {
+			if cmd.Flags().Changed(flag) {
+				value, _ := cmd.Flags().GetString(flag)
+				*field = &value
+			}
+		}
+		if err := cli.UpdateWorkspace(args[0], changes); err != nil {
+			cli.PrintError(err)
 			os.Exit(1)
 		}
 	},
 }
 
+var workspaceAddCmd = &cobra.Command{
+	Use:   "add <name>",
+	Short: "Add agents or secrets to a workspace",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		agents, _ := cmd.Flags().GetStringSlice("agent")
+		secrets, _ := cmd.Flags().GetStringSlice("secret")
+		if err := cli.AssignToWorkspace(args[0], agents, secrets, false); err != nil {
+			cli.PrintError(err)
+			os.Exit(1)
+		}
+	},
+}
+
+var workspaceRemoveCmd = &cobra.Command{
+	Use:   "remove <name>",
+	Short: "Remove agents or secrets from a workspace",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		agents, _ := cmd.Flags().GetStringSlice("agent")
+		secrets, _ := cmd.Flags().GetStringSlice("secret")
+		if err := cli.AssignToWorkspace(args[0], agents, secrets, true); err != nil {
+			cli.PrintError(err)
+			os.Exit(1)
+		}
+	},
+}
+
+var workspaceDeleteCmd = &cobra.Command{
+	Use:   "delete <name>",
+	Short: "Delete a workspace, keeping its agents, secrets and conversations",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := cli.DeleteWorkspace(args[0]); err != nil {
+			cli.PrintError(err)
+			os.Exit(1)
+		}
+	},
+}
+
+var conversationListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List saved conversations",
+	Long: `List saved conversations, pinned ones first. Archived conversations are
+left out unless --archived or --all is given.
+
+Listing first archives the unpinned conversations idle for longer than
+archive_after_days in conversation.yaml, when set.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		pinned, _ := cmd.Flags().GetBool("pinned")
+		archived, _ := cmd.Flags().GetBool("archived")
+		all, _ := cmd.Flags().GetBool("all")
+		return cli.ListConversations(cli.ConversationFilter{Pinned: pinned, Archived: archived, All: all})
+	},
+}
+
+var conversationPinCmd = &cobra.Command{
+	Use:   "pin [id]",
+	Short: "Pin a conversation to the top of the session list",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return cli.PinConversation(args[0], true)
+	},
+}
+
+var conversationUnpinCmd = &cobra.Command{
+	Use:   "unpin [id]",
+	Short: "Unpin a conversation",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return cli.PinConversation(args[0], false)
+	},
+}
+
+var conversationArchiveCmd = &cobra.Command{
+	Use:   "archive [id]",
+	Short: "Archive a conversation, ending its session agents",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return cli.ArchiveConversation(args[0], true)
+	},
+}
+
+var conversationUnarchiveCmd = &cobra.Command{
+	Use:   "unarchive [id]",
+	Short: "Restore an archived conversation",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return cli.ArchiveConversation(args[0], false)
+	},
+}
+
+var conversationRenameCmd = &cobra.Command{
+	Use:   "rename [id] [title]",
+	Short: "Rename a conversation, or have the model title it again",
+	Long: `Set the title of a saved conversation. Conversations are titled by a
+small model after their first exchange and retitled when their topic
+shifts; a renamed conversation keeps its title.
+
+Without a title, the model titles the conversation again from its messages
+and keeps the title up to date from then on.
+
+Examples:
+  op conversation rename 1234567890 "Nginx log rotation"
+  op conversation rename 1234567890`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		title := ""
+		if len(args) > 1 {
+			title = args[1]
+		}
+		return cli.RenameConversation(args[0], title)
+	},
+}
+
+var conversationReplayCmd = &cobra.Command{
+	Use:   "replay [id]",
+	Short: "Re-run a recorded conversation's loop from its model recording",
+	Long: `Re-run the conversation loop for every recorded message of a
+conversation, answering model calls from the recording made with
+'op exec --record' (or OPPERATOR_RECORD=1). Tools run for real, so
+tool-execution bugs reproduce deterministically. Nothing is saved.
+
+With --offline no model calls are made: a request that differs from the
+recording still gets the recorded response. Without it, the loop continues
+against the model from the first request that differs.
+
+Examples:
+  op exec "Clean up the logs" --agent ops --record
+  op conversation replay 1234567890 --offline`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		offline, _ := cmd.Flags().GetBool("offline")
+		return cli.ReplayConversation(args[0], offline)
+	},
+}
+
 func startTUICPUProfile(path string) (func(), error) {
 	file, err := os.Create(path)
 	if err != nil {
@@ -948,24 +2424,70 @@ func init() {
 	// Disable the default completion command
 	rootCmd.CompletionOptions.DisableDefaultCmd = true
 
+	rootCmd.PersistentFlags().String("instance", "", "Use a separate local daemon, config directory and database (also OPPERATOR_INSTANCE)")
 	rootCmd.Flags().StringVar(&tuiCPUProfilePath, "tui-cpuprofile", "", "Write TUI CPU profile to file")
 	stopCmd.Flags().BoolP("all", "a", false, "Stop all agents")
 	stopCmd.Flags().String("daemon", "", "Specify daemon (auto-detects if not provided)")
+	stopCmd.Flags().Bool("force", false, "Kill the agent right away instead of asking it to shut down")
+	stopCmd.Flags().Duration("grace-period", 0, "How long the agent has to exit after the shutdown event before SIGTERM (default: the agent's stop_grace_period or 10s)")
 	logsCmd.Flags().BoolP("follow", "f", false, "Follow log output (stream mode)")
 	logsCmd.Flags().IntP("lines", "n", 0, "Show last N lines (0 = all lines)")
 	logsCmd.Flags().String("daemon", "", "Specify daemon (auto-detects if not provided)")
+	logsCmd.Flags().Bool("crash", false, "Show exit status and stderr from the last crash")
+	agentStatusCmd.Flags().String("daemon", "", "Specify daemon (auto-detects if not provided)")
+	agentStatusCmd.Flags().Bool("json", false, "Output the status as JSON")
+	agentDevCmd.Flags().String("frames-file", "", "Write protocol messages to this file as JSON lines")
+	agentDevCmd.Flags().Bool("quiet", false, "Print only logs, not protocol messages")
+	agentTraceCmd.Flags().String("daemon", "", "Specify daemon (auto-detects if not provided)")
+	agentTraceCmd.Flags().StringSlice("type", nil, "Only show messages of these types (e.g. command,response,lifecycle_event)")
+	agentTraceCmd.Flags().BoolP("follow", "f", false, "Keep printing new messages")
+	agentTraceCmd.Flags().String("save", "", "Append the messages shown to this file as JSON lines")
+	agentTraceCmd.Flags().String("file", "", "Show a saved trace instead of asking the daemon")
+	statsCmd.Flags().Bool("json", false, "Output the stats as JSON")
+	budgetCmd.Flags().String("daemon", "", "Specify daemon (auto-detects from the agent, or local)")
+	budgetCmd.Flags().Bool("json", false, "Output the usage as JSON")
+
+	depsCmd.Flags().Bool("update", false, "Upgrade outdated and vulnerable packages")
+	depsCmd.Flags().Bool("json", false, "Output the report as JSON")
+	agentTestCmd.Flags().Duration("timeout", 0, "Timeout for starting the agent and for each command (default 1m to start, 5m per command)")
+	daemonTopCmd.Flags().Duration("interval", 2*time.Second, "Refresh interval")
+	daemonTopCmd.Flags().Bool("once", false, "Print a single sample and exit")
 	startCmd.Flags().String("daemon", "", "Specify daemon (auto-detects if not provided)")
 	restartCmd.Flags().String("daemon", "", "Specify daemon (auto-detects if not provided)")
+	pauseCmd.Flags().String("daemon", "", "Specify daemon (auto-detects if not provided)")
+	pauseCmd.Flags().String("reason", "", "Reason shown in the errors of rejected commands")
+	resumeCmd.Flags().String("daemon", "", "Specify daemon (auto-detects if not provided)")
 	reloadCmd.Flags().String("daemon", "", "Specify daemon to reload (defaults to local)")
+	reloadCmd.Flags().Bool("from-git", false, "Pull the config directory from git before reloading")
 	commandCmd.Flags().String("args", "", "JSON object to pass as command arguments")
 	commandCmd.Flags().Duration("timeout", 10*time.Second, "How long to wait for the command response")
 	commandCmd.Flags().String("daemon", "", "Specify daemon (auto-detects if not provided)")
+	commandCmd.Flags().String("replay", "", "Run the command recorded as this task ID again with the same arguments")
+	commandCmd.Flags().String("cwd", "", "Directory to run the command in (must be within the agent's allowed_dirs)")
+	commandCmd.Flags().String("output-dir", "", "Save files the command outputs, such as reports or images, to this directory")
 	listCommandsCmd.Flags().String("daemon", "", "Specify daemon (auto-detects if not provided)")
+	promptHistoryCmd.Flags().String("daemon", "", "Specify daemon (auto-detects if not provided)")
+	promptDiffCmd.Flags().String("daemon", "", "Specify daemon (auto-detects if not provided)")
+	promptRevertCmd.Flags().String("daemon", "", "Specify daemon (auto-detects if not provided)")
+	promptCmd.AddCommand(promptHistoryCmd)
+	promptCmd.AddCommand(promptDiffCmd)
+	promptCmd.AddCommand(promptRevertCmd)
+	for _, c := range []*cobra.Command{kvGetCmd, kvSetCmd, kvDeleteCmd, kvListCmd} {
+		c.Flags().String("daemon", "", "Specify daemon (auto-detects if not provided)")
+		kvCmd.AddCommand(c)
+	}
+	kvListCmd.Flags().Bool("json", false, "Output the entries as JSON")
+	for _, c := range []*cobra.Command{blackboardGetCmd, blackboardSetCmd, blackboardDeleteCmd, blackboardListCmd} {
+		c.Flags().String("daemon", "local", "Daemon whose blackboard to use")
+		blackboardCmd.AddCommand(c)
+	}
+	blackboardListCmd.Flags().Bool("json", false, "Output the entries as JSON")
 
 	listCmd.Flags().Bool("running", false, "Only show running agents")
 	listCmd.Flags().Bool("stopped", false, "Only show stopped agents")
-	listCmd.Flags().Bool("crashed", false, "Only show crashed agents")
+	listCmd.Flags().Bool("crashed", false, "Only show crashed or crashlooping agents")
 	listCmd.Flags().String("daemon", "", "Filter agents by daemon name")
+	listCmd.Flags().Bool("all-workspaces", false, "List agents of every workspace, not only the active one")
 	bootstrapCmd.Flags().StringP("description", "d", "", "Agent description")
 	bootstrapCmd.Flags().Bool("no-start", false, "Skip auto-starting the agent after bootstrap")
 	deleteCmd.Flags().BoolP("force", "f", false, "Skip confirmation prompt")
@@ -977,20 +2499,39 @@ func init() {
 	agentCmd.AddCommand(startCmd)
 	agentCmd.AddCommand(stopCmd)
 	agentCmd.AddCommand(restartCmd)
+	agentCmd.AddCommand(pauseCmd)
+	agentCmd.AddCommand(resumeCmd)
 	agentCmd.AddCommand(bootstrapCmd)
 	agentCmd.AddCommand(deleteCmd)
 	agentCmd.AddCommand(moveCmd)
 	agentCmd.AddCommand(whereCmd)
 	agentCmd.AddCommand(reloadCmd)
 	agentCmd.AddCommand(logsCmd)
+	agentCmd.AddCommand(agentStatusCmd)
+	agentCmd.AddCommand(agentDevCmd)
+	agentCmd.AddCommand(agentTraceCmd)
+	agentCmd.AddCommand(promptCmd)
+	agentCmd.AddCommand(kvCmd)
+	agentCmd.AddCommand(budgetCmd)
+	agentCmd.AddCommand(depsCmd)
+	agentCmd.AddCommand(agentTestCmd)
 	agentCmd.AddCommand(commandCmd)
 	agentCmd.AddCommand(listCommandsCmd)
 	secretCmd.AddCommand(secretCreateCmd)
 	secretCmd.AddCommand(secretUpdateCmd)
+	secretCmd.AddCommand(secretRotateCmd)
+	secretCmd.AddCommand(secretEnvCmd)
 	secretCmd.AddCommand(secretDeleteCmd)
 	secretCmd.AddCommand(secretReadCmd)
+	secretCmd.AddCommand(secretEncryptCmd)
 	secretCmd.AddCommand(secretListCmd)
 	secretCmd.AddCommand(secretStatusCmd)
+	secretCreateCmd.Flags().String("daemon", "", "Store the secret in this daemon's keyring instead of the local one")
+	secretUpdateCmd.Flags().String("daemon", "", "Update the secret in this daemon's keyring instead of the local one")
+	secretRotateCmd.Flags().Int("generate", 0, "Generate a random value of this many characters")
+	secretRotateCmd.Flags().String("daemon", "", "Rotate the secret in this daemon's keyring only")
+	secretDeleteCmd.Flags().String("daemon", "", "Delete the secret from this daemon's keyring instead of the local one")
+	secretListCmd.Flags().Bool("all-workspaces", false, "List secrets of every workspace, not only the active one")
 
 	// Daemon start flags
 	daemonStartCmd.Flags().Bool("foreground", false, "Run daemon in foreground (blocks terminal)")
@@ -1004,15 +2545,53 @@ func init() {
 	daemonCmd.AddCommand(daemonTestCmd)
 	daemonCmd.AddCommand(daemonEnableCmd)
 	daemonCmd.AddCommand(daemonDisableCmd)
+	daemonCmd.AddCommand(daemonTopCmd)
+	daemonCmd.AddCommand(daemonTokenCmd)
+
+	// Daemon token commands
+	daemonTokenCmd.PersistentFlags().String("daemon", "local", "Daemon to manage tokens on")
+	daemonTokenCreateCmd.Flags().String("role", "viewer", "Token role (viewer, operator or admin)")
+	daemonTokenCreateCmd.Flags().String("user", "", "User the token acts as; tasks are private to each user (default: the token name)")
+	daemonTokenCmd.AddCommand(daemonTokenCreateCmd)
+	daemonTokenCmd.AddCommand(daemonTokenRevokeCmd)
+	daemonTokenCmd.AddCommand(daemonTokenListCmd)
 
 	// Cloud command
 	cloudCmd.AddCommand(cloudDeployCmd)
 	cloudCmd.AddCommand(cloudDestroyCmd)
 	cloudCmd.AddCommand(cloudListCmd)
 	cloudCmd.AddCommand(cloudUpdateCmd)
+	cloudCmd.AddCommand(cloudCostsCmd)
+	cloudCmd.AddCommand(cloudRecoverCmd)
+
+	// Cloud deploy flags
+	cloudDeployCmd.Flags().Bool("restrict-to-my-ip", false, "Only accept daemon connections from this machine's public IP")
+	cloudDeployCmd.Flags().String("allow-from", "", "Only accept daemon connections from this IP address or CIDR range")
+	cloudDeployCmd.Flags().Bool("unattended-upgrades", true, "Install security updates automatically")
+	cloudDeployCmd.Flags().Bool("fail2ban", true, "Ban addresses with repeated failed SSH logins")
+	cloudDeployCmd.Flags().Bool("ssh-key-only", true, "Disable SSH password login")
+	cloudDeployCmd.Flags().Int("swap", 0, "Swap file size in GB (0 for none)")
+
+	// Cloud recover flags
+	cloudRecoverCmd.Flags().String("bucket", "", "Bucket the daemon backed up to")
+	cloudRecoverCmd.Flags().String("region", "", "Bucket region")
+	cloudRecoverCmd.Flags().String("prefix", "", "Key prefix of the snapshots")
+	cloudRecoverCmd.Flags().String("endpoint", "", "Endpoint of an S3-compatible service")
+	cloudRecoverCmd.Flags().String("snapshot", "latest", "Snapshot to restore, as shown by 'op backup list'")
+	cloudRecoverCmd.Flags().String("server-type", "", "Server type for the new VPS (default: the lost server's)")
+	cloudRecoverCmd.Flags().String("location", "", "Location for the new VPS (default: the lost server's)")
+	cloudRecoverCmd.Flags().Bool("delete-old", false, "Delete the old server once the new one has taken over")
+	cloudRecoverCmd.Flags().Bool("restrict-to-my-ip", false, "Only accept daemon connections from this machine's public IP")
+	cloudRecoverCmd.Flags().String("allow-from", "", "Only accept daemon connections from this IP address or CIDR range")
+	cloudRecoverCmd.Flags().Int("swap", 0, "Swap file size in GB (0 for none)")
+	cloudRecoverCmd.MarkFlagRequired("bucket")
+
+	// Cloud costs flags
+	cloudCostsCmd.Flags().Bool("json", false, "Output as JSON")
 
 	// Cloud update flags
 	cloudUpdateCmd.Flags().Bool("pre-release", false, "Update to the latest pre-release version instead of stable")
+	cloudUpdateCmd.Flags().Bool("remote-pull", false, "Have the daemon download and install the release itself instead of pushing it over SSH")
 
 	// Daemon add flags
 	daemonAddCmd.Flags().String("token", "", "Authentication token (can use env var: --token=$MY_TOKEN)")
@@ -1024,17 +2603,76 @@ func init() {
 	// Cloud destroy flags
 	cloudDestroyCmd.Flags().BoolP("force", "f", false, "Skip confirmation prompt")
 
-	asyncListCmd.Flags().String("status", "", "Filter tasks by status (pending|complete|failed)")
+	asyncListCmd.Flags().String("status", "", "Filter tasks by status (loading|pending|complete|failed|skipped)")
 	asyncListCmd.Flags().String("origin", "", "Filter tasks by origin identifier")
 	asyncListCmd.Flags().String("session", "", "Filter tasks by session identifier")
 	asyncListCmd.Flags().String("client", "", "Filter tasks by client identifier")
+	asyncListCmd.Flags().String("daemon", "", "Only list tasks of this daemon (default: every enabled daemon)")
+	asyncListCmd.Flags().Bool("all-users", false, "Include other users' tasks on a shared daemon (admin tokens only)")
+	logsSearchCmd.Flags().String("agent", "", "Only search the logs of this agent")
+	logsSearchCmd.Flags().String("since", "", "Only search lines newer than this: a duration (2h, 7d), a date or an RFC 3339 time")
+	logsSearchCmd.Flags().String("daemon", "", "Only search this daemon (default: every enabled daemon)")
+	logsSearchCmd.Flags().IntP("context", "C", 0, "Lines of context to show around each match")
+	logsSearchCmd.Flags().BoolP("ignore-case", "i", false, "Match case-insensitively")
+	logsSearchCmd.Flags().Int("limit", 0, "Maximum matches per daemon (default 500)")
+	logsRootCmd.AddCommand(logsSearchCmd)
+
 	asyncCmd.AddCommand(asyncListCmd)
 	asyncCmd.AddCommand(asyncGetCmd)
 	asyncCmd.AddCommand(asyncDeleteCmd)
 
+	workflowRunCmd.Flags().StringArrayP("input", "i", nil, "Workflow input as key=value (repeatable)")
+	workflowRunCmd.Flags().Bool("wait", false, "Follow the run until it finishes")
+	workflowCmd.AddCommand(workflowListCmd)
+	workflowCmd.AddCommand(workflowRunCmd)
+	workflowCmd.AddCommand(workflowStatusCmd)
+
+	triggerCmd.AddCommand(triggerListCmd)
+	triggerListCmd.Flags().Bool("all-workspaces", false, "List triggers of every workspace, not only the active one")
+
+	channelAddCmd.Flags().String("type", "", "Channel type: slack, telegram or email")
+	channelAddCmd.Flags().String("agent", "", "Agent that answers messages")
+	channelAddCmd.Flags().String("timeout", "", "Maximum duration of one agent exchange (default 10m)")
+	channelAddCmd.Flags().StringArray("allowed-user", nil, "Slack user ID or numeric Telegram user ID allowed to chat (repeatable)")
+	channelAddCmd.Flags().String("app-token", "", "Slack app-level token (xapp-...) or ${ENV_VAR}")
+	channelAddCmd.Flags().String("bot-token", "", "Slack bot token (xoxb-...) or ${ENV_VAR}")
+	channelAddCmd.Flags().StringArray("route", nil, "Route a Slack channel to another agent as CHANNEL_ID=agent (repeatable)")
+	channelAddCmd.Flags().String("token-secret", "", "Secret holding the Telegram bot token (default TELEGRAM_BOT_TOKEN)")
+	channelAddCmd.Flags().StringArray("allowed-sender", nil, "Email address or glob whose mail is handled (repeatable)")
+	channelAddCmd.Flags().String("imap-address", "", "IMAP server host:port to poll")
+	channelAddCmd.Flags().String("imap-username", "", "IMAP username")
+	channelAddCmd.Flags().String("imap-password", "", "IMAP password or ${ENV_VAR}")
+	channelAddCmd.Flags().String("imap-mailbox", "", "IMAP mailbox (default INBOX)")
+	channelAddCmd.Flags().String("imap-interval", "", "IMAP poll interval (default 1m)")
+	channelAddCmd.Flags().String("webhook-listen", "", "Address for the inbound mail webhook, e.g. :8025")
+	channelAddCmd.Flags().String("webhook-secret", "", "Token the mail provider must send to the webhook")
+	channelAddCmd.Flags().String("smtp-address", "", "SMTP server host:port for replies")
+	channelAddCmd.Flags().String("smtp-username", "", "SMTP username")
+	channelAddCmd.Flags().String("smtp-password", "", "SMTP password or ${ENV_VAR}")
+	channelAddCmd.Flags().String("smtp-from", "", "From address of replies")
+	channelCmd.AddCommand(channelListCmd)
+	channelCmd.AddCommand(channelAddCmd)
+	channelCmd.AddCommand(channelRemoveCmd)
+
+	kbSearchCmd.Flags().Int("limit", 5, "Number of passages to show")
+	kbCmd.AddCommand(kbAddCmd)
+	kbCmd.AddCommand(kbListCmd)
+	kbCmd.AddCommand(kbRemoveCmd)
+	kbCmd.AddCommand(kbSearchCmd)
+
+	evalRunCmd.Flags().String("agent", "", "Run the cases against this agent instead of the suite's")
+	evalRunCmd.Flags().String("judge-model", "", "Model for LLM judge checks (default: the exec model)")
+	evalRunCmd.Flags().Bool("keep", false, "Keep the conversations the cases ran in")
+	evalRunCmd.Flags().Bool("json", false, "Print the report as JSON")
+	evalRunCmd.Flags().Float64("min-pass-rate", 1, "Exit with an error below this pass rate (0 to 1)")
+	evalCmd.AddCommand(evalRunCmd)
+
 	// Add version subcommands
 	versionCheckCmd.Flags().Bool("pre-release", false, "Include pre-release versions")
 	versionUpdateCmd.Flags().Bool("pre-release", false, "Include pre-release versions")
+	versionUpdateCmd.Flags().String("rollout", deployment.RolloutAll, "How to roll the update out to cloud daemons: all or canary")
+	versionUpdateCmd.Flags().String("canary", "", "Cloud daemon to update first with --rollout canary (default: first in the registry)")
+	versionUpdateCmd.Flags().Duration("soak", 10*time.Minute, "How long the canary must stay healthy before the other cloud daemons are updated")
 	versionCmd.AddCommand(versionShowCmd)
 	versionCmd.AddCommand(versionCheckCmd)
 	versionCmd.AddCommand(versionUpdateCmd)
@@ -1043,22 +2681,146 @@ func init() {
 	execCmd.Flags().String("agent", "", "Name of the agent to send the message to")
 	execCmd.Flags().String("resume", "", "Resume an existing conversation by ID")
 	execCmd.Flags().Bool("json", false, "Output events as JSON Lines (JSONL) instead of pretty-printing")
+	execCmd.Flags().BoolP("interactive", "i", false, "Chat line by line on stdin, continuing one conversation")
+	execCmd.Flags().Bool("detach", false, "Queue the message on the daemon and print a handle for 'op exec attach' instead of waiting")
+	execCmd.Flags().Bool("plain", false, "Stream the response as raw text instead of rendering markdown")
 	execCmd.Flags().Bool("no-save", false, "Don't save conversation to database")
+	execCmd.Flags().Bool("record", false, "Record model requests and responses for 'op conversation replay'")
+	execCmd.Flags().String("mock-tools", "", "Resolve tool calls from this fixtures file instead of running them")
+	execCmd.Flags().Int("max-rounds", 0, "Maximum model rounds for the message (default from conversation.yaml, or 60)")
+	execCmd.Flags().Duration("turn-timeout", 0, "Fail when one model round and its tool calls take longer than this")
+	execCmd.Flags().Duration("timeout", 0, "Fail when answering the message takes longer than this")
+	execCmd.Flags().String("listen", "", "Serve a web chat UI on this address (e.g. 127.0.0.1:7777) instead of sending one message")
+	execCmd.Flags().String("token", "", "Access token for the web chat (default: randomly generated)")
+
+	// Add conversation subcommands
+	conversationShareCmd.Flags().String("format", "markdown", "Output format: markdown or html")
+	conversationShareCmd.Flags().Bool("strip-tools", false, "Leave out tool arguments and results")
+	conversationShareCmd.Flags().String("output", "", "Write to this file instead of stdout")
+	conversationShareCmd.Flags().Bool("upload", false, "Upload to the target configured in share.yaml and print the URL")
+	conversationShareCmd.Flags().StringArray("redact", nil, "Extra text to redact (repeatable)")
+	conversationCmd.AddCommand(conversationShareCmd)
+	conversationReplayCmd.Flags().Bool("offline", false, "Never call the model; serve every response from the recording")
+	conversationCmd.AddCommand(conversationReplayCmd)
+	conversationCmd.AddCommand(conversationRenameCmd)
+	conversationListCmd.Flags().Bool("pinned", false, "Only list pinned conversations")
+	conversationListCmd.Flags().Bool("archived", false, "Only list archived conversations")
+	conversationListCmd.Flags().Bool("all", false, "Include archived conversations")
+	conversationCmd.AddCommand(conversationListCmd)
+	conversationCmd.AddCommand(conversationPinCmd)
+	conversationCmd.AddCommand(conversationUnpinCmd)
+	conversationCmd.AddCommand(conversationArchiveCmd)
+	conversationCmd.AddCommand(conversationUnarchiveCmd)
 
 	rootCmd.AddCommand(agentCmd)
 	rootCmd.AddCommand(setupCmd)
 	rootCmd.AddCommand(doctorCmd)
+	dirsMigrateCmd.Flags().Bool("dry-run", false, "Only print what would be moved")
+	dirsCmd.AddCommand(dirsMigrateCmd)
+	rootCmd.AddCommand(dirsCmd)
+	rootCmd.AddCommand(statsCmd)
+	rootCmd.AddCommand(backupCmd)
+	backupCmd.AddCommand(backupNowCmd)
+	backupCmd.AddCommand(backupListCmd)
+	backupCmd.AddCommand(backupRestoreCmd)
+	backupNowCmd.Flags().String("daemon", "local", "Daemon to back up")
+	backupListCmd.Flags().Bool("json", false, "Output as JSON")
+	for _, c := range []*cobra.Command{backupListCmd, backupRestoreCmd} {
+		c.Flags().String("bucket", "", "Backup bucket (default: backup.bucket in daemon.yaml)")
+		c.Flags().String("region", "", "Bucket region")
+		c.Flags().String("prefix", "", "Key prefix of the snapshots")
+		c.Flags().String("endpoint", "", "Endpoint of an S3-compatible service")
+	}
 	rootCmd.AddCommand(secretCmd)
 	rootCmd.AddCommand(asyncCmd)
+	rootCmd.AddCommand(logsRootCmd)
+	rootCmd.AddCommand(workflowCmd)
+	rootCmd.AddCommand(triggerCmd)
+	rootCmd.AddCommand(channelCmd)
+	rootCmd.AddCommand(kbCmd)
+	rootCmd.AddCommand(blackboardCmd)
+	pluginCmd.AddCommand(pluginListCmd)
+	rootCmd.AddCommand(pluginCmd)
+	rootCmd.AddCommand(evalCmd)
 	rootCmd.AddCommand(versionCmd)
 	rootCmd.AddCommand(cloudCmd)
+	execCmd.AddCommand(execAttachCmd)
 	rootCmd.AddCommand(execCmd)
+	rootCmd.AddCommand(conversationCmd)
+	rootCmd.AddCommand(workspaceCmd)
+	workspaceCmd.AddCommand(workspaceCreateCmd)
+	workspaceCmd.AddCommand(workspaceListCmd)
+	workspaceCmd.AddCommand(workspaceUseCmd)
+	workspaceCmd.AddCommand(workspaceShowCmd)
+	workspaceCmd.AddCommand(workspaceSetCmd)
+	workspaceCmd.AddCommand(workspaceAddCmd)
+	workspaceCmd.AddCommand(workspaceRemoveCmd)
+	workspaceCmd.AddCommand(workspaceDeleteCmd)
+	for _, c := range []*cobra.Command{workspaceCreateCmd, workspaceSetCmd} {
+		c.Flags().String("description", "", "What the workspace is for")
+		c.Flags().String("daemon", "", "Daemon new agents are bootstrapped on")
+		c.Flags().String("model", "", "Model for conversations in this workspace")
+	}
+	workspaceCreateCmd.Flags().Bool("use", false, "Switch to the new workspace")
+	workspaceUseCmd.Flags().Bool("clear", false, "Deactivate the current workspace")
+	for _, c := range []*cobra.Command{workspaceAddCmd, workspaceRemoveCmd} {
+		c.Flags().StringSlice("agent", nil, "Agent name (repeatable)")
+		c.Flags().StringSlice("secret", nil, "Secret name (repeatable)")
+	}
 	// Add hidden commands (needed internally but not shown to users)
 	rootCmd.AddCommand(daemonCmd)
 }
 
+var pluginCmd = &cobra.Command{
+	Use:   "plugin",
+	Short: "Manage CLI plugins",
+	Long: `Plugins add commands to op without changing it: any executable named
+op-<name> on PATH runs as 'op <name>', with the remaining arguments and
+OPPERATOR_PLUGIN set to its name. Plugins reach daemons and agents by
+running op themselves.`,
+}
+
+var pluginListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the plugins found on PATH",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := cli.ListPlugins(isBuiltinCommand); err != nil {
+			cli.PrintError(err)
+			os.Exit(1)
+		}
+	},
+}
+
+// isBuiltinCommand reports whether name is one of op's own commands, which
+// plugins cannot replace.
+func isBuiltinCommand(name string) bool {
+	switch name {
+	case "help", "completion", cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd:
+		return true
+	}
+	for _, c := range rootCmd.Commands() {
+		if c.Name() == name || c.HasAlias(name) {
+			return true
+		}
+	}
+	return false
+}
+
 func main() {
-	if err := rootCmd.Execute(); err != nil {
+	// 'op <name>' runs the op-<name> plugin unless name is a built-in command
+	if len(os.Args) > 1 && !isBuiltinCommand(os.Args[1]) {
+		if path, ok := cli.LookupPlugin(os.Args[1]); ok {
+			if err := cli.RunPlugin(os.Args[1], path, os.Args[2:]); err != nil {
+				cli.PrintError(err)
+				os.Exit(1)
+			}
+		}
+	}
+
+	err := rootCmd.Execute()
+	flushTracing()
+	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
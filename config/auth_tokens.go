@@ -0,0 +1,88 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// authTokenHashPrefix marks the hash algorithm so it can change later.
+const authTokenHashPrefix = "sha256:"
+
+// ManagedToken is an auth token created with 'op daemon token create'. Only a
+// hash of the secret is stored; the token itself is shown once at creation.
+type ManagedToken struct {
+	Name       string     `yaml:"name"`
+	Hash       string     `yaml:"hash"`
+	Role       string     `yaml:"role"`
+	User       string     `yaml:"user,omitempty"`
+	CreatedAt  time.Time  `yaml:"created_at"`
+	LastUsedAt *time.Time `yaml:"last_used_at,omitempty"`
+}
+
+type managedTokenFile struct {
+	Tokens []ManagedToken `yaml:"tokens"`
+}
+
+// HashAuthToken returns the stored form of an auth token.
+func HashAuthToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return authTokenHashPrefix + hex.EncodeToString(sum[:])
+}
+
+// GetAuthTokensPath returns the path to the auth_tokens.yaml file
+func GetAuthTokensPath() (string, error) {
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "auth_tokens.yaml"), nil
+}
+
+// LoadManagedTokens reads the tokens managed by the daemon. A missing file
+// yields no tokens.
+func LoadManagedTokens() ([]ManagedToken, error) {
+	path, err := GetAuthTokensPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read auth tokens: %w", err)
+	}
+	var file managedTokenFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse auth tokens: %w", err)
+	}
+	return file.Tokens, nil
+}
+
+// SaveManagedTokens replaces the managed token file. The file is written
+// atomically and readable by the owner only.
+func SaveManagedTokens(tokens []ManagedToken) error {
+	path, err := GetAuthTokensPath()
+	if err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(managedTokenFile{Tokens: tokens})
+	if err != nil {
+		return fmt.Errorf("failed to marshal auth tokens: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write auth tokens: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write auth tokens: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// BlackboardConfig lists the agents that may use a blackboard, a key-value
+// namespace shared between agents. Agents in Write may also read; "*" stands
+// for every agent. Agents are identified by their protocol pipe; operators
+// using 'op blackboard' are not restricted.
+//
+//	blackboards:
+//	  research:
+//	    read: ["*"]
+//	    write: [crawler, summarizer]
+type BlackboardConfig struct {
+	Read  []string `yaml:"read,omitempty"`
+	Write []string `yaml:"write,omitempty"`
+}
+
+// CanRead reports whether agent may read the blackboard.
+func (c BlackboardConfig) CanRead(agent string) bool {
+	return matchesAgent(c.Read, agent) || c.CanWrite(agent)
+}
+
+// CanWrite reports whether agent may write to the blackboard.
+func (c BlackboardConfig) CanWrite(agent string) bool {
+	return matchesAgent(c.Write, agent)
+}
+
+func matchesAgent(agents []string, agent string) bool {
+	return slices.Contains(agents, "*") || slices.Contains(agents, agent)
+}
+
+func validateBlackboards(boards map[string]BlackboardConfig) error {
+	for name, b := range boards {
+		if strings.TrimSpace(name) == "" || strings.ContainsAny(name, " \t\n") {
+			return fmt.Errorf("blackboards: invalid namespace %q", name)
+		}
+		for _, agent := range append(slices.Clone(b.Read), b.Write...) {
+			if strings.TrimSpace(agent) == "" {
+				return fmt.Errorf("blackboards.%s: agent names cannot be empty", name)
+			}
+		}
+	}
+	return nil
+}
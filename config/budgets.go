@@ -0,0 +1,74 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// BudgetsConfig sets an advisory daily allowance of model calls for each
+// agent, counted by the daemon that hosts the agent. Agents maps agent names
+// to their limits; Default applies to agents without an entry, and core
+// agents are named "core:<id>". Prices, in USD per million tokens keyed by model ("*" for any
+// other model), turn estimated token counts into the cost MaxCost is checked
+// against.
+type BudgetsConfig struct {
+	Default *BudgetLimit           `yaml:"default,omitempty"`
+	Agents  map[string]BudgetLimit `yaml:"agents,omitempty"`
+	Prices  map[string]ModelPrice  `yaml:"prices,omitempty"`
+}
+
+// BudgetLimit is a daily allowance; zero means no limit.
+type BudgetLimit struct {
+	MaxCalls int     `yaml:"max_calls,omitempty"`
+	MaxCost  float64 `yaml:"max_cost,omitempty"`
+}
+
+// ModelPrice is what a model costs in USD per million tokens.
+type ModelPrice struct {
+	Input  float64 `yaml:"input"`
+	Output float64 `yaml:"output"`
+}
+
+// Limit returns the daily allowance of agent, if it has one.
+func (c BudgetsConfig) Limit(agent string) (BudgetLimit, bool) {
+	if l, ok := c.Agents[agent]; ok {
+		return l, l.MaxCalls > 0 || l.MaxCost > 0
+	}
+	if c.Default != nil {
+		return *c.Default, c.Default.MaxCalls > 0 || c.Default.MaxCost > 0
+	}
+	return BudgetLimit{}, false
+}
+
+// Cost estimates what a call to model with the given token counts costs.
+func (c BudgetsConfig) Cost(model string, inputTokens, outputTokens int) float64 {
+	price, ok := c.Prices[model]
+	if !ok {
+		price = c.Prices["*"]
+	}
+	return (float64(inputTokens)*price.Input + float64(outputTokens)*price.Output) / 1e6
+}
+
+func (c *BudgetsConfig) validate() error {
+	limits := map[string]BudgetLimit{}
+	if c.Default != nil {
+		limits["default"] = *c.Default
+	}
+	for name, l := range c.Agents {
+		limits["agents."+name] = l
+	}
+	for name, l := range limits {
+		if l.MaxCalls < 0 || l.MaxCost < 0 {
+			return fmt.Errorf("budgets.%s: limits cannot be negative", name)
+		}
+		if l.MaxCost > 0 && len(c.Prices) == 0 {
+			return fmt.Errorf("budgets.%s: max_cost needs budgets.prices to estimate cost", name)
+		}
+	}
+	for model, p := range c.Prices {
+		if strings.TrimSpace(model) == "" || p.Input < 0 || p.Output < 0 {
+			return fmt.Errorf("budgets.prices: invalid price for model %q", model)
+		}
+	}
+	return nil
+}
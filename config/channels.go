@@ -0,0 +1,361 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Chat channel types.
+const (
+	ChannelEmail    = "email"
+	ChannelSlack    = "slack"
+	ChannelTelegram = "telegram"
+)
+
+// ChannelConfig is a chat channel: a transport through which people talk to
+// an agent. Only the section matching Type is set. Channels come from the
+// email, slack and telegram sections of daemon.yaml or are registered with
+// 'op channel add' and kept in channels.yaml.
+type ChannelConfig struct {
+	Name     string          `yaml:"name" json:"name"`
+	Type     string          `yaml:"type" json:"type"`
+	Email    *EmailChannel   `yaml:"email,omitempty" json:"email,omitempty"`
+	Slack    *SlackConfig    `yaml:"slack,omitempty" json:"slack,omitempty"`
+	Telegram *TelegramConfig `yaml:"telegram,omitempty" json:"telegram,omitempty"`
+}
+
+type channelFile struct {
+	Channels []ChannelConfig `yaml:"channels"`
+}
+
+// EmailChannel routes inbound mail to an agent as an exec conversation and
+// optionally mails the agent's answer back. Mail arrives by polling IMAP,
+// through a webhook posted by a mail provider, or both. String values may
+// reference environment variables as ${NAME}.
+type EmailChannel struct {
+	Name  string `yaml:"name"`
+	Agent string `yaml:"agent"`
+	// AllowedSenders lists addresses or globs such as *@example.com whose
+	// mail is handled; everything else is ignored. Use "*" to accept anyone.
+	AllowedSenders []string `yaml:"allowed_senders"`
+	// Timeout bounds one agent exchange as a Go duration (default 10m).
+	Timeout string `yaml:"timeout,omitempty"`
+
+	IMAP    *EmailIMAP    `yaml:"imap,omitempty"`
+	Webhook *EmailWebhook `yaml:"webhook,omitempty"`
+	SMTP    *EmailSMTP    `yaml:"smtp,omitempty"`
+}
+
+// EmailIMAP polls a mailbox over TLS for unseen messages.
+type EmailIMAP struct {
+	Address  string `yaml:"address"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	Mailbox  string `yaml:"mailbox,omitempty"`
+	Interval string `yaml:"interval,omitempty"`
+}
+
+// EmailWebhook accepts messages POSTed by a mail provider on Listen. Requests
+// must carry Secret as a bearer token or a token query parameter.
+type EmailWebhook struct {
+	Listen string `yaml:"listen"`
+	Secret string `yaml:"secret"`
+}
+
+// EmailSMTP sends replies. Port 465 uses implicit TLS; other ports upgrade
+// with STARTTLS when the server offers it.
+type EmailSMTP struct {
+	Address  string `yaml:"address"`
+	Username string `yaml:"username,omitempty"`
+	Password string `yaml:"password,omitempty"`
+	From     string `yaml:"from"`
+}
+
+// DefaultTelegramTokenSecret is the secret holding the Telegram bot token
+// when TelegramConfig.TokenSecret is unset.
+const DefaultTelegramTokenSecret = "TELEGRAM_BOT_TOKEN"
+
+// TelegramConfig runs a Telegram bot so agents can be chatted with from a
+// phone. Each chat keeps its own conversation.
+type TelegramConfig struct {
+	// TokenSecret names the secret (see 'op secret create') holding the bot
+	// token from @BotFather.
+	TokenSecret string `yaml:"token_secret,omitempty"`
+	Agent       string `yaml:"agent"`
+	// AllowedUsers lists the numeric Telegram user IDs the bot answers.
+	AllowedUsers []int64 `yaml:"allowed_users"`
+	// Timeout bounds one agent exchange as a Go duration (default 10m).
+	Timeout string `yaml:"timeout,omitempty"`
+}
+
+// SlackConfig connects the daemon to a Slack app over Socket Mode, so agents
+// can be chatted with from Slack without exposing the daemon. Tokens may
+// reference environment variables as ${NAME}.
+type SlackConfig struct {
+	// AppToken is the app-level token (xapp-...) with connections:write.
+	AppToken string `yaml:"app_token"`
+	// BotToken is the bot token (xoxb-...) used to post replies.
+	BotToken string `yaml:"bot_token"`
+	// Agent answers messages unless Channels names another agent for the
+	// Slack channel ID the message was posted in.
+	Agent    string            `yaml:"agent"`
+	Channels map[string]string `yaml:"channels,omitempty"`
+	// AllowedUsers limits who may talk to agents to these Slack user IDs.
+	// Empty allows every member of channels the app was added to.
+	AllowedUsers []string `yaml:"allowed_users,omitempty"`
+	// Timeout bounds one agent exchange as a Go duration (default 10m).
+	Timeout string `yaml:"timeout,omitempty"`
+}
+
+// Agent returns the agent that answers the channel by default.
+func (c ChannelConfig) Agent() string {
+	switch {
+	case c.Email != nil:
+		return c.Email.Agent
+	case c.Slack != nil:
+		return c.Slack.Agent
+	case c.Telegram != nil:
+		return c.Telegram.Agent
+	}
+	return ""
+}
+
+// Timeout bounds one agent exchange on the channel; zero means the default.
+func (c ChannelConfig) Timeout() time.Duration {
+	var value string
+	switch {
+	case c.Email != nil:
+		value = c.Email.Timeout
+	case c.Slack != nil:
+		value = c.Slack.Timeout
+	case c.Telegram != nil:
+		value = c.Telegram.Timeout
+	}
+	d, _ := time.ParseDuration(value)
+	return d
+}
+
+// Validate normalises the channel and checks the section for its type.
+func (c *ChannelConfig) Validate() error {
+	c.Name = strings.TrimSpace(c.Name)
+	c.Type = strings.ToLower(strings.TrimSpace(c.Type))
+	if c.Name == "" {
+		return fmt.Errorf("channel name is required")
+	}
+	switch c.Type {
+	case ChannelEmail:
+		if c.Email == nil {
+			return fmt.Errorf("channel %q: email settings are required", c.Name)
+		}
+		c.Email.Name = c.Name
+		return c.Email.validate()
+	case ChannelSlack:
+		if c.Slack == nil {
+			return fmt.Errorf("channel %q: slack settings are required", c.Name)
+		}
+		return c.Slack.validate(c.Name)
+	case ChannelTelegram:
+		if c.Telegram == nil {
+			return fmt.Errorf("channel %q: telegram settings are required", c.Name)
+		}
+		return c.Telegram.validate(c.Name)
+	default:
+		return fmt.Errorf("channel %q: unknown type %q (expected email, slack or telegram)", c.Name, c.Type)
+	}
+}
+
+// Expanded returns a copy with environment variable references resolved, so
+// the stored form keeps the ${NAME} placeholders.
+func (c ChannelConfig) Expanded() ChannelConfig {
+	if c.Email != nil {
+		email := *c.Email
+		email.expandEnv()
+		c.Email = &email
+	}
+	if c.Slack != nil {
+		slack := *c.Slack
+		slack.expandEnv()
+		c.Slack = &slack
+	}
+	return c
+}
+
+// Channels returns the chat channels declared in daemon.yaml. The slack and
+// telegram sections are named after their type.
+func (s *DaemonSettings) Channels() []ChannelConfig {
+	var channels []ChannelConfig
+	for i := range s.Email {
+		channels = append(channels, ChannelConfig{Name: s.Email[i].Name, Type: ChannelEmail, Email: &s.Email[i]})
+	}
+	if s.Slack != nil {
+		channels = append(channels, ChannelConfig{Name: ChannelSlack, Type: ChannelSlack, Slack: s.Slack})
+	}
+	if s.Telegram != nil {
+		channels = append(channels, ChannelConfig{Name: ChannelTelegram, Type: ChannelTelegram, Telegram: s.Telegram})
+	}
+	return channels
+}
+
+// GetChannelsPath returns the path to the channels.yaml file
+func GetChannelsPath() (string, error) {
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "channels.yaml"), nil
+}
+
+// LoadRegisteredChannels reads the channels added with 'op channel add'. A
+// missing file yields no channels.
+func LoadRegisteredChannels() ([]ChannelConfig, error) {
+	path, err := GetChannelsPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read channels: %w", err)
+	}
+	var file channelFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse channels: %w", err)
+	}
+	return file.Channels, nil
+}
+
+// SaveRegisteredChannels replaces channels.yaml. The file may hold
+// credentials, so it is written atomically and readable by the owner only.
+func SaveRegisteredChannels(channels []ChannelConfig) error {
+	path, err := GetChannelsPath()
+	if err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(channelFile{Channels: channels})
+	if err != nil {
+		return fmt.Errorf("failed to marshal channels: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write channels: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write channels: %w", err)
+	}
+	return nil
+}
+
+func (c *EmailChannel) expandEnv() {
+	if c.IMAP != nil {
+		imap := *c.IMAP
+		imap.Address = expandEnvVars(imap.Address)
+		imap.Username = expandEnvVars(imap.Username)
+		imap.Password = expandEnvVars(imap.Password)
+		c.IMAP = &imap
+	}
+	if c.Webhook != nil {
+		webhook := *c.Webhook
+		webhook.Listen = expandEnvVars(webhook.Listen)
+		webhook.Secret = expandEnvVars(webhook.Secret)
+		c.Webhook = &webhook
+	}
+	if c.SMTP != nil {
+		smtp := *c.SMTP
+		smtp.Address = expandEnvVars(smtp.Address)
+		smtp.Username = expandEnvVars(smtp.Username)
+		smtp.Password = expandEnvVars(smtp.Password)
+		smtp.From = expandEnvVars(smtp.From)
+		c.SMTP = &smtp
+	}
+}
+
+func (c *SlackConfig) expandEnv() {
+	c.AppToken = expandEnvVars(c.AppToken)
+	c.BotToken = expandEnvVars(c.BotToken)
+}
+
+func (c *EmailChannel) validate() error {
+	c.Agent = strings.TrimSpace(c.Agent)
+	if c.Agent == "" {
+		return fmt.Errorf("email channel %q: agent is required", c.Name)
+	}
+	if len(c.AllowedSenders) == 0 {
+		return fmt.Errorf("email channel %q: allowed_senders is required (use \"*\" to accept anyone)", c.Name)
+	}
+	if c.Timeout != "" {
+		if d, err := time.ParseDuration(c.Timeout); err != nil || d <= 0 {
+			return fmt.Errorf("email channel %q: invalid timeout %q", c.Name, c.Timeout)
+		}
+	}
+	if c.IMAP == nil && c.Webhook == nil {
+		return fmt.Errorf("email channel %q: configure imap, webhook or both", c.Name)
+	}
+	if c.IMAP != nil {
+		if c.IMAP.Address == "" || c.IMAP.Username == "" || c.IMAP.Password == "" {
+			return fmt.Errorf("email channel %q: imap address, username and password are required", c.Name)
+		}
+		if c.IMAP.Interval != "" {
+			if d, err := time.ParseDuration(c.IMAP.Interval); err != nil || d <= 0 {
+				return fmt.Errorf("email channel %q: invalid imap interval %q", c.Name, c.IMAP.Interval)
+			}
+		}
+	}
+	if c.Webhook != nil && (c.Webhook.Listen == "" || c.Webhook.Secret == "") {
+		return fmt.Errorf("email channel %q: webhook listen and secret are required", c.Name)
+	}
+	if c.SMTP != nil && (c.SMTP.Address == "" || c.SMTP.From == "") {
+		return fmt.Errorf("email channel %q: smtp address and from are required", c.Name)
+	}
+	return nil
+}
+
+func (c *SlackConfig) validate(name string) error {
+	c.Agent = strings.TrimSpace(c.Agent)
+	// Tokens may still be ${NAME} references in channels.yaml; check the
+	// prefixes on the expanded values.
+	expanded := *c
+	expanded.expandEnv()
+	if !strings.HasPrefix(strings.TrimSpace(expanded.AppToken), "xapp-") {
+		return fmt.Errorf("slack channel %q: app_token must be an app-level token (xapp-...)", name)
+	}
+	if !strings.HasPrefix(strings.TrimSpace(expanded.BotToken), "xoxb-") {
+		return fmt.Errorf("slack channel %q: bot_token must be a bot token (xoxb-...)", name)
+	}
+	if c.Agent == "" {
+		return fmt.Errorf("slack channel %q: agent is required", name)
+	}
+	if c.Timeout != "" {
+		if d, err := time.ParseDuration(c.Timeout); err != nil || d <= 0 {
+			return fmt.Errorf("slack channel %q: invalid timeout %q", name, c.Timeout)
+		}
+	}
+	return nil
+}
+
+func (c *TelegramConfig) validate(name string) error {
+	c.TokenSecret = strings.TrimSpace(c.TokenSecret)
+	c.Agent = strings.TrimSpace(c.Agent)
+	if c.TokenSecret == "" {
+		c.TokenSecret = DefaultTelegramTokenSecret
+	}
+	if c.Agent == "" {
+		return fmt.Errorf("telegram channel %q: agent is required", name)
+	}
+	if len(c.AllowedUsers) == 0 {
+		return fmt.Errorf("telegram channel %q: allowed_users is required", name)
+	}
+	if c.Timeout != "" {
+		if d, err := time.ParseDuration(c.Timeout); err != nil || d <= 0 {
+			return fmt.Errorf("telegram channel %q: invalid timeout %q", name, c.Timeout)
+		}
+	}
+	return nil
+}
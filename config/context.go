@@ -0,0 +1,100 @@
+package config
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ProjectContextFile is looked up from the working directory upwards and
+// added to the core agents' instructions.
+const ProjectContextFile = "OPPERATOR.md"
+
+// maxContextFileSize bounds how much of a context file is read.
+const maxContextFileSize = 64 << 10
+
+// ContextFile is a file added to the core agents' instructions.
+type ContextFile struct {
+	Path    string
+	Content string
+}
+
+// GetGlobalContextPath returns the path to the context.md file added to the
+// core agents' instructions in every project
+func GetGlobalContextPath() (string, error) {
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "context.md"), nil
+}
+
+// LoadContextFiles returns the global context.md followed by the nearest
+// OPPERATOR.md in workingDir or a parent directory. Missing and empty files
+// are left out.
+func LoadContextFiles(workingDir string) []ContextFile {
+	var files []ContextFile
+	if path, err := GetGlobalContextPath(); err == nil {
+		if content := readContextFile(path); content != "" {
+			files = append(files, ContextFile{Path: path, Content: content})
+		}
+	}
+	if path := findProjectContext(workingDir); path != "" {
+		if content := readContextFile(path); content != "" {
+			files = append(files, ContextFile{Path: path, Content: content})
+		}
+	}
+	return files
+}
+
+// FormatContextFiles renders context files as an instructions section, or
+// "" when there are none.
+func FormatContextFiles(files []ContextFile) string {
+	if len(files) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("## Context\n\nThe user provided the following context. Follow it unless it conflicts with the task at hand.")
+	for _, f := range files {
+		b.WriteString("\n\n### ")
+		b.WriteString(f.Path)
+		b.WriteString("\n\n")
+		b.WriteString(f.Content)
+	}
+	return b.String()
+}
+
+func findProjectContext(dir string) string {
+	if strings.TrimSpace(dir) == "" {
+		return ""
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for {
+		path := filepath.Join(dir, ProjectContextFile)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+func readContextFile(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, maxContextFileSize))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
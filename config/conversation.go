@@ -0,0 +1,174 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultMaxRounds is how many model rounds one turn may take when nothing
+// else is configured.
+const DefaultMaxRounds = 60
+
+// ConversationConfig bounds the conversation loops of the TUI and op exec.
+// The top-level limits apply to every agent; Agents overrides them per agent,
+// with core agents named by their ID (e.g. "opperator"). ArchiveAfterDays
+// archives unpinned conversations idle for that many days; zero never does.
+type ConversationConfig struct {
+	ConversationLimits `yaml:",inline"`
+	Agents             map[string]ConversationLimits `yaml:"agents,omitempty"`
+	ArchiveAfterDays   int                           `yaml:"archive_after_days,omitempty"`
+}
+
+// ConversationLimits are the limits of one turn, that is one user message and
+// everything the model does to answer it. MaxRounds caps its model calls,
+// TurnTimeout each model call together with the tools it calls, and Timeout
+// the whole turn. Durations use Go syntax ("90s", "10m"); empty or zero
+// values mean no limit, and MaxRounds defaults to DefaultMaxRounds.
+// ToolOutputTokens caps how much of one tool output the model sees, the
+// rest being available through the read_more tool; zero keeps the default.
+// RecentToolOutputs is how many of the latest tool outputs are sent whole,
+// older ones being compacted to a summary; zero keeps the default.
+// LoopRepeats is how many identical tool calls or texts stop the turn as a
+// loop; zero keeps the default.
+type ConversationLimits struct {
+	MaxRounds         int    `yaml:"max_rounds,omitempty"`
+	TurnTimeout       string `yaml:"turn_timeout,omitempty"`
+	Timeout           string `yaml:"timeout,omitempty"`
+	ToolOutputTokens  int    `yaml:"tool_output_tokens,omitempty"`
+	RecentToolOutputs int    `yaml:"recent_tool_outputs,omitempty"`
+	LoopRepeats       int    `yaml:"loop_repeats,omitempty"`
+}
+
+// Limits are resolved conversation limits.
+type Limits struct {
+	MaxRounds         int
+	TurnTimeout       time.Duration
+	Timeout           time.Duration
+	ToolOutputTokens  int
+	RecentToolOutputs int
+	LoopRepeats       int
+}
+
+// Merge returns l with the values set in override taking precedence.
+func (l Limits) Merge(override Limits) Limits {
+	if override.MaxRounds > 0 {
+		l.MaxRounds = override.MaxRounds
+	}
+	if override.TurnTimeout > 0 {
+		l.TurnTimeout = override.TurnTimeout
+	}
+	if override.Timeout > 0 {
+		l.Timeout = override.Timeout
+	}
+	if override.ToolOutputTokens > 0 {
+		l.ToolOutputTokens = override.ToolOutputTokens
+	}
+	if override.RecentToolOutputs > 0 {
+		l.RecentToolOutputs = override.RecentToolOutputs
+	}
+	if override.LoopRepeats > 0 {
+		l.LoopRepeats = override.LoopRepeats
+	}
+	return l
+}
+
+// GetConversationPath returns the path to the conversation.yaml file
+func GetConversationPath() (string, error) {
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "conversation.yaml"), nil
+}
+
+// LoadConversationConfig reads conversation.yaml. A missing file yields the
+// defaults.
+func LoadConversationConfig() (*ConversationConfig, error) {
+	var cfg ConversationConfig
+	path, err := GetConversationPath()
+	if err != nil {
+		return &cfg, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &cfg, nil
+	}
+	if err != nil {
+		return &cfg, fmt.Errorf("failed to read conversation settings: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return &ConversationConfig{}, fmt.Errorf("failed to parse conversation settings: %w", err)
+	}
+	if cfg.ArchiveAfterDays < 0 {
+		return &ConversationConfig{}, fmt.Errorf("%s: archive_after_days cannot be negative", path)
+	}
+	if _, err := cfg.ConversationLimits.resolve(); err != nil {
+		return &ConversationConfig{}, fmt.Errorf("%s: %w", path, err)
+	}
+	for name, l := range cfg.Agents {
+		if _, err := l.resolve(); err != nil {
+			return &ConversationConfig{}, fmt.Errorf("%s: agents.%s: %w", path, name, err)
+		}
+	}
+	return &cfg, nil
+}
+
+// LimitsFor returns the limits of agent's conversations.
+func (c *ConversationConfig) LimitsFor(agent string) Limits {
+	limits, _ := c.ConversationLimits.resolve()
+	if override, ok := c.Agents[agent]; ok {
+		agentLimits, _ := override.resolve()
+		limits = limits.Merge(agentLimits)
+	}
+	if limits.MaxRounds <= 0 {
+		limits.MaxRounds = DefaultMaxRounds
+	}
+	return limits
+}
+
+// ArchiveAfter is how long a conversation stays idle before it is archived,
+// or zero when conversations are never archived automatically.
+func (c *ConversationConfig) ArchiveAfter() time.Duration {
+	if c.ArchiveAfterDays <= 0 {
+		return 0
+	}
+	return time.Duration(c.ArchiveAfterDays) * 24 * time.Hour
+}
+
+func (l ConversationLimits) resolve() (Limits, error) {
+	if l.MaxRounds < 0 {
+		return Limits{}, fmt.Errorf("max_rounds cannot be negative")
+	}
+	if l.ToolOutputTokens < 0 {
+		return Limits{}, fmt.Errorf("tool_output_tokens cannot be negative")
+	}
+	if l.RecentToolOutputs < 0 {
+		return Limits{}, fmt.Errorf("recent_tool_outputs cannot be negative")
+	}
+	if l.LoopRepeats < 0 {
+		return Limits{}, fmt.Errorf("loop_repeats cannot be negative")
+	}
+	out := Limits{MaxRounds: l.MaxRounds, ToolOutputTokens: l.ToolOutputTokens, RecentToolOutputs: l.RecentToolOutputs, LoopRepeats: l.LoopRepeats}
+	for _, d := range []struct {
+		name  string
+		value string
+		dst   *time.Duration
+	}{
+		{"turn_timeout", l.TurnTimeout, &out.TurnTimeout},
+		{"timeout", l.Timeout, &out.Timeout},
+	} {
+		if d.value == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(d.value)
+		if err != nil || parsed < 0 {
+			return Limits{}, fmt.Errorf("%s: invalid duration %q", d.name, d.value)
+		}
+		*d.dst = parsed
+	}
+	return out, nil
+}
@@ -0,0 +1,684 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"opperator/internal/protocol"
+)
+
+// Log formats supported by the daemon.
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// DaemonSettings configures the behaviour of the local daemon process. It is
+// read from daemon.yaml in the config directory; every field is optional.
+type DaemonSettings struct {
+	Logging       LoggingConfig               `yaml:"logging"`
+	LogShipping   LogShippingConfig           `yaml:"log_shipping"`
+	Notifications NotificationsConfig         `yaml:"notifications"`
+	Auth          AuthConfig                  `yaml:"auth"`
+	Tasks         TasksConfig                 `yaml:"tasks"`
+	Triggers      []TriggerConfig             `yaml:"triggers"`
+	Email         []EmailChannel              `yaml:"email"`
+	Slack         *SlackConfig                `yaml:"slack,omitempty"`
+	Telegram      *TelegramConfig             `yaml:"telegram,omitempty"`
+	Dashboards    DashboardsConfig            `yaml:"dashboards"`
+	Updates       UpdatesConfig               `yaml:"updates"`
+	Backup        BackupConfig                `yaml:"backup"`
+	Policy        PolicyConfig                `yaml:"policy"`
+	GitSync       GitSyncConfig               `yaml:"git_sync"`
+	Budgets       BudgetsConfig               `yaml:"budgets"`
+	Blackboards   map[string]BlackboardConfig `yaml:"blackboards,omitempty"`
+	Hooks         []HookConfig                `yaml:"hooks,omitempty"`
+}
+
+// Release channels a daemon can update itself from.
+const (
+	UpdateChannelStable     = "stable"
+	UpdateChannelPreRelease = "pre-release"
+)
+
+// UpdatesConfig controls how a daemon running under systemd updates itself
+// from GitHub releases. Channel is "stable" (default) or "pre-release". With
+// Auto set the daemon checks every Interval (default 24h) and installs newer
+// releases on its own; otherwise it only updates on 'op cloud update
+// --remote-pull'.
+type UpdatesConfig struct {
+	Channel  string `yaml:"channel,omitempty"`
+	Auto     bool   `yaml:"auto,omitempty"`
+	Interval string `yaml:"interval,omitempty"`
+}
+
+// CheckInterval returns how often automatic updates are checked for.
+func (c UpdatesConfig) CheckInterval() time.Duration {
+	if d, err := time.ParseDuration(c.Interval); err == nil && d > 0 {
+		return d
+	}
+	return 24 * time.Hour
+}
+
+// BackupConfig snapshots the config directory (agents.yaml, the database and
+// agent directories, but not keyring secrets) to S3-compatible storage with
+// credentials from the AWS_* environment variables. With Interval set the
+// daemon takes a snapshot that often; Keep is how many snapshots are retained
+// (default 14). String values may reference environment variables as ${NAME}.
+type BackupConfig struct {
+	Bucket string `yaml:"bucket,omitempty"`
+	Region string `yaml:"region,omitempty"`
+	Prefix string `yaml:"prefix,omitempty"`
+	// Endpoint selects an S3-compatible service, addressed path-style.
+	Endpoint string `yaml:"endpoint,omitempty"`
+	Interval string `yaml:"interval,omitempty"`
+	Keep     int    `yaml:"keep,omitempty"`
+}
+
+// DefaultBackupKeep is how many snapshots are retained when keep is unset.
+const DefaultBackupKeep = 14
+
+// Enabled reports whether a bucket is configured.
+func (c BackupConfig) Enabled() bool {
+	return c.Bucket != ""
+}
+
+// ScheduleInterval returns how often scheduled backups run, or 0 when they
+// are off.
+func (c BackupConfig) ScheduleInterval() time.Duration {
+	d, err := time.ParseDuration(c.Interval)
+	if err != nil || d <= 0 {
+		return 0
+	}
+	return d
+}
+
+// Retain returns how many snapshots to keep.
+func (c BackupConfig) Retain() int {
+	if c.Keep > 0 {
+		return c.Keep
+	}
+	return DefaultBackupKeep
+}
+
+// DashboardsConfig enables the HTTP server for dashboards published by
+// agents. Listen is an address such as ":8765"; empty disables the server.
+// Requests must present a daemon auth token of any role.
+type DashboardsConfig struct {
+	Listen string `yaml:"listen,omitempty"`
+}
+
+// TriggerConfig binds an event source to a command of Agent. String values
+// may reference environment variables as ${NAME}.
+type TriggerConfig struct {
+	Agent                      string `yaml:"agent"`
+	protocol.TriggerDescriptor `yaml:",inline"`
+}
+
+// TasksConfig tunes the async task queue. Unset fields keep the built-in
+// defaults.
+type TasksConfig struct {
+	Workers              int `yaml:"workers,omitempty"`
+	MaxPendingPerSession int `yaml:"max_pending_per_session,omitempty"`
+	MaxPendingPerClient  int `yaml:"max_pending_per_client,omitempty"`
+	// MaxWorkers lets the queue grow past Workers, one worker at a time,
+	// while more than ScaleUpDepth tasks (default Workers) have been queued
+	// for ScaleUpAfter (default 10s). Workers idle for ScaleDownAfter
+	// (default 1m) are stopped again, down to Workers.
+	MaxWorkers     int    `yaml:"max_workers,omitempty"`
+	ScaleUpDepth   int    `yaml:"scale_up_depth,omitempty"`
+	ScaleUpAfter   string `yaml:"scale_up_after,omitempty"`
+	ScaleDownAfter string `yaml:"scale_down_after,omitempty"`
+	// Origins sets scheduling weights and quotas per task origin such as
+	// tui, cli, webhook or schedule.
+	Origins map[string]OriginQuota `yaml:"origins,omitempty"`
+}
+
+// OriginQuota limits the tasks of one origin. Weight is its share of workers
+// relative to other origins with queued tasks; MaxPending caps queued plus
+// running tasks and MaxRunning caps concurrently running ones (0 = unlimited).
+type OriginQuota struct {
+	Weight     int  `yaml:"weight,omitempty"`
+	MaxPending *int `yaml:"max_pending,omitempty"`
+	MaxRunning *int `yaml:"max_running,omitempty"`
+}
+
+// LoggingConfig controls the daemon log output.
+type LoggingConfig struct {
+	// Format is "text" (default) or "json" for one JSON object per line.
+	Format string `yaml:"format,omitempty"`
+	// Level is the minimum level written: debug, info (default), warn or error.
+	Level string `yaml:"level,omitempty"`
+	// AgentLogs also writes log messages emitted by agents to the daemon log,
+	// tagged with the agent name. Defaults to on in JSON mode.
+	AgentLogs *bool `yaml:"agent_logs,omitempty"`
+}
+
+// ForwardAgentLogs reports whether agent log messages belong in the daemon log.
+func (c LoggingConfig) ForwardAgentLogs() bool {
+	if c.AgentLogs != nil {
+		return *c.AgentLogs
+	}
+	return c.Format == LogFormatJSON
+}
+
+// Log shipping destination types.
+const (
+	LogDestinationSyslog = "syslog"
+	LogDestinationLoki   = "loki"
+	LogDestinationHTTP   = "http"
+)
+
+// Log shipping sources.
+const (
+	LogSourceDaemon = "daemon"
+	LogSourceAgents = "agents"
+)
+
+// LogShippingConfig forwards daemon and agent logs to external systems.
+type LogShippingConfig struct {
+	Destinations []LogDestination `yaml:"destinations"`
+}
+
+// LogDestination is one log shipping target. Which fields apply depends on
+// Type. String values may reference environment variables as ${NAME}.
+type LogDestination struct {
+	Name string `yaml:"name"`
+	Type string `yaml:"type"`
+	// Sources is daemon, agents or both (default).
+	Sources []string `yaml:"sources,omitempty"`
+	// Agents restricts agent logs to these agents.
+	Agents []string `yaml:"agents,omitempty"`
+
+	// syslog: udp://host:514 or tcp://host:601, the local syslog when empty
+	Address string `yaml:"address,omitempty"`
+	Tag     string `yaml:"tag,omitempty"`
+
+	// loki (the push API URL) and http
+	URL     string            `yaml:"url,omitempty"`
+	Headers map[string]string `yaml:"headers,omitempty"`
+	// Labels are added to every Loki stream.
+	Labels map[string]string `yaml:"labels,omitempty"`
+
+	// BatchSize is how many lines are sent at once (default 100) and
+	// FlushInterval how long lines wait for a batch to fill (default 5s).
+	BatchSize     int    `yaml:"batch_size,omitempty"`
+	FlushInterval string `yaml:"flush_interval,omitempty"`
+}
+
+// Ships reports whether the destination takes logs from source.
+func (d LogDestination) Ships(source string) bool {
+	return len(d.Sources) == 0 || slices.Contains(d.Sources, source)
+}
+
+// Flush returns how long lines wait for a batch to fill.
+func (d LogDestination) Flush() time.Duration {
+	if v, err := time.ParseDuration(d.FlushInterval); err == nil && v > 0 {
+		return v
+	}
+	return 5 * time.Second
+}
+
+// Notification sink types.
+const (
+	SinkSlack   = "slack"
+	SinkEmail   = "email"
+	SinkWebhook = "webhook"
+)
+
+// NotificationsConfig declares where daemon events are delivered. Routes map
+// event types to named sinks; an event matching no route is dropped.
+type NotificationsConfig struct {
+	Sinks  []NotificationSink  `yaml:"sinks"`
+	Routes []NotificationRoute `yaml:"routes"`
+}
+
+// NotificationSink is a delivery target. Which fields apply depends on Type.
+// String values may reference environment variables as ${NAME}.
+type NotificationSink struct {
+	Name string `yaml:"name"`
+	Type string `yaml:"type"`
+
+	// slack and webhook
+	URL     string            `yaml:"url,omitempty"`
+	Headers map[string]string `yaml:"headers,omitempty"`
+
+	// email
+	SMTPHost string   `yaml:"smtp_host,omitempty"`
+	SMTPPort int      `yaml:"smtp_port,omitempty"`
+	Username string   `yaml:"username,omitempty"`
+	Password string   `yaml:"password,omitempty"`
+	From     string   `yaml:"from,omitempty"`
+	To       []string `yaml:"to,omitempty"`
+}
+
+// NotificationRoute sends events of the listed types to the listed sinks.
+// Agents optionally restricts agent-scoped events to those agents.
+type NotificationRoute struct {
+	Events []string `yaml:"events"`
+	Sinks  []string `yaml:"sinks"`
+	Agents []string `yaml:"agents,omitempty"`
+}
+
+// Roles granted to TCP auth tokens, from least to most privileged.
+const (
+	RoleViewer   = "viewer"
+	RoleOperator = "operator"
+	RoleAdmin    = "admin"
+)
+
+// AuthConfig lists tokens accepted by the daemon's TCP listener in addition
+// to OPPERATOR_AUTH_TOKEN, which always carries the admin role.
+type AuthConfig struct {
+	Tokens []AuthToken `yaml:"tokens"`
+}
+
+// AuthToken is a credential for remote clients. Viewers can list agents,
+// watch tasks and read logs; operators can also start, stop and invoke
+// agents; admins can additionally manage secrets and the daemon itself.
+// Token may reference an environment variable as ${NAME}; TokenHash (see
+// HashAuthToken) keeps the secret itself out of the file. User is the
+// identity tasks are owned by; it defaults to Name, and several tokens, e.g.
+// one per device, may share it.
+type AuthToken struct {
+	Name      string `yaml:"name"`
+	Token     string `yaml:"token,omitempty"`
+	TokenHash string `yaml:"token_hash,omitempty"`
+	Role      string `yaml:"role,omitempty"`
+	User      string `yaml:"user,omitempty"`
+}
+
+// GetDaemonSettingsPath returns the path to the daemon.yaml file
+func GetDaemonSettingsPath() (string, error) {
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "daemon.yaml"), nil
+}
+
+// LoadDaemonSettings reads daemon.yaml, falling back to defaults when the file
+// does not exist. OPPERATOR_LOG_FORMAT, OPPERATOR_LOG_LEVEL and
+// OPPERATOR_DASHBOARD_LISTEN override the file so container deployments can
+// switch them without mounting config.
+func LoadDaemonSettings() (*DaemonSettings, error) {
+	path, err := GetDaemonSettingsPath()
+	if err != nil {
+		return nil, err
+	}
+
+	var settings DaemonSettings
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read daemon settings: %w", err)
+	}
+	if err == nil {
+		if err := yaml.Unmarshal(data, &settings); err != nil {
+			return nil, fmt.Errorf("failed to parse daemon settings: %w", err)
+		}
+	}
+
+	if v := os.Getenv("OPPERATOR_LOG_FORMAT"); v != "" {
+		settings.Logging.Format = v
+	}
+	if v := os.Getenv("OPPERATOR_LOG_LEVEL"); v != "" {
+		settings.Logging.Level = v
+	}
+	if v := os.Getenv("OPPERATOR_DASHBOARD_LISTEN"); v != "" {
+		settings.Dashboards.Listen = v
+	}
+	settings.Dashboards.Listen = strings.TrimSpace(expandEnvVars(settings.Dashboards.Listen))
+
+	for i := range settings.Notifications.Sinks {
+		sink := &settings.Notifications.Sinks[i]
+		sink.URL = expandEnvVars(sink.URL)
+		sink.Username = expandEnvVars(sink.Username)
+		sink.Password = expandEnvVars(sink.Password)
+		for k, v := range sink.Headers {
+			sink.Headers[k] = expandEnvVars(v)
+		}
+	}
+
+	for i := range settings.Auth.Tokens {
+		settings.Auth.Tokens[i].Token = expandEnvVars(settings.Auth.Tokens[i].Token)
+	}
+
+	for i := range settings.Triggers {
+		t := &settings.Triggers[i]
+		t.Path = expandEnvVars(t.Path)
+		t.URL = expandEnvVars(t.URL)
+		t.Address = expandEnvVars(t.Address)
+		t.Username = expandEnvVars(t.Username)
+		t.Password = expandEnvVars(t.Password)
+		t.QueueURL = expandEnvVars(t.QueueURL)
+		for k, v := range t.Headers {
+			t.Headers[k] = expandEnvVars(v)
+		}
+	}
+
+	for i := range settings.Email {
+		settings.Email[i].expandEnv()
+	}
+	for i := range settings.LogShipping.Destinations {
+		settings.LogShipping.Destinations[i].expandEnv()
+	}
+	settings.Backup.expandEnv()
+	settings.Policy.expandEnv()
+	settings.GitSync.expandEnv()
+	if settings.Slack != nil {
+		settings.Slack.expandEnv()
+	}
+
+	if err := settings.normalize(); err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+func (s *DaemonSettings) normalize() error {
+	s.Logging.Format = strings.ToLower(strings.TrimSpace(s.Logging.Format))
+	switch s.Logging.Format {
+	case "":
+		s.Logging.Format = LogFormatText
+	case LogFormatText, LogFormatJSON:
+	default:
+		return fmt.Errorf("invalid logging.format %q (expected text or json)", s.Logging.Format)
+	}
+
+	s.Logging.Level = strings.ToLower(strings.TrimSpace(s.Logging.Level))
+	switch s.Logging.Level {
+	case "":
+		s.Logging.Level = "info"
+	case "debug", "info", "warn", "error":
+	case "warning":
+		s.Logging.Level = "warn"
+	default:
+		return fmt.Errorf("invalid logging.level %q (expected debug, info, warn or error)", s.Logging.Level)
+	}
+
+	if err := s.LogShipping.validate(); err != nil {
+		return err
+	}
+	if err := s.Auth.validate(); err != nil {
+		return err
+	}
+	if err := s.Tasks.validate(); err != nil {
+		return err
+	}
+	if err := validateTriggers(s.Triggers); err != nil {
+		return err
+	}
+	names := make(map[string]bool)
+	for i, ch := range s.Channels() {
+		if ch.Name == "" && ch.Type == ChannelEmail {
+			return fmt.Errorf("email[%d]: name is required", i)
+		}
+		if err := ch.Validate(); err != nil {
+			return err
+		}
+		if names[ch.Name] {
+			return fmt.Errorf("duplicate channel %q", ch.Name)
+		}
+		names[ch.Name] = true
+	}
+	if err := s.Updates.validate(); err != nil {
+		return err
+	}
+	if err := s.Backup.Validate(); err != nil {
+		return err
+	}
+	if err := s.Policy.validate(); err != nil {
+		return err
+	}
+	if err := s.GitSync.validate(); err != nil {
+		return err
+	}
+	if err := s.Budgets.validate(); err != nil {
+		return err
+	}
+	if err := validateBlackboards(s.Blackboards); err != nil {
+		return err
+	}
+	if err := validateHooks(s.Hooks); err != nil {
+		return err
+	}
+	return s.Notifications.validate()
+}
+
+func (c *UpdatesConfig) validate() error {
+	c.Channel = strings.ToLower(strings.TrimSpace(c.Channel))
+	switch c.Channel {
+	case "":
+		c.Channel = UpdateChannelStable
+	case UpdateChannelStable, UpdateChannelPreRelease:
+	default:
+		return fmt.Errorf("invalid updates.channel %q (expected stable or pre-release)", c.Channel)
+	}
+	if c.Interval != "" {
+		d, err := time.ParseDuration(c.Interval)
+		if err != nil {
+			return fmt.Errorf("invalid updates.interval: %w", err)
+		}
+		if d < 10*time.Minute {
+			return fmt.Errorf("updates.interval must be at least 10m")
+		}
+	}
+	return nil
+}
+
+func (c *BackupConfig) expandEnv() {
+	c.Bucket = strings.TrimSpace(expandEnvVars(c.Bucket))
+	c.Region = strings.TrimSpace(expandEnvVars(c.Region))
+	c.Prefix = strings.TrimSpace(expandEnvVars(c.Prefix))
+	c.Endpoint = strings.TrimSpace(expandEnvVars(c.Endpoint))
+}
+
+// Validate checks the settings and fills in the default region. It is
+// exported for backup settings given on the command line.
+func (c *BackupConfig) Validate() error {
+	if c.Region == "" {
+		c.Region = "us-east-1"
+	}
+	if c.Keep < 0 {
+		return fmt.Errorf("backup.keep must not be negative")
+	}
+	if c.Interval != "" {
+		if c.Bucket == "" {
+			return fmt.Errorf("backup.interval requires backup.bucket")
+		}
+		d, err := time.ParseDuration(c.Interval)
+		if err != nil {
+			return fmt.Errorf("invalid backup.interval: %w", err)
+		}
+		if d < 10*time.Minute {
+			return fmt.Errorf("backup.interval must be at least 10m")
+		}
+	}
+	return nil
+}
+
+func (c *TasksConfig) validate() error {
+	if c.Workers < 0 || c.MaxPendingPerSession < 0 || c.MaxPendingPerClient < 0 || c.MaxWorkers < 0 || c.ScaleUpDepth < 0 {
+		return fmt.Errorf("tasks: workers and pending limits must not be negative")
+	}
+	if c.MaxWorkers > 0 && c.Workers > c.MaxWorkers {
+		return fmt.Errorf("tasks: max_workers must not be below workers")
+	}
+	for name, value := range map[string]string{"scale_up_after": c.ScaleUpAfter, "scale_down_after": c.ScaleDownAfter} {
+		if value == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(value); err != nil || d <= 0 {
+			return fmt.Errorf("invalid tasks.%s %q", name, value)
+		}
+	}
+	for origin, q := range c.Origins {
+		if q.Weight < 0 || (q.MaxPending != nil && *q.MaxPending < 0) || (q.MaxRunning != nil && *q.MaxRunning < 0) {
+			return fmt.Errorf("tasks.origins.%s: weight and limits must not be negative", origin)
+		}
+	}
+	return nil
+}
+
+// validateTriggers checks the fields common to every trigger; the daemon
+// validates type-specific fields when it starts them.
+func validateTriggers(triggers []TriggerConfig) error {
+	names := make(map[string]bool, len(triggers))
+	for i := range triggers {
+		t := &triggers[i]
+		t.Name = strings.TrimSpace(t.Name)
+		t.Agent = strings.TrimSpace(t.Agent)
+		t.Type = strings.ToLower(strings.TrimSpace(t.Type))
+		if t.Name == "" {
+			return fmt.Errorf("triggers[%d]: name is required", i)
+		}
+		if names[t.Name] {
+			return fmt.Errorf("triggers: duplicate trigger %q", t.Name)
+		}
+		names[t.Name] = true
+		if t.Agent == "" || strings.TrimSpace(t.Command) == "" {
+			return fmt.Errorf("trigger %q: agent and command are required", t.Name)
+		}
+	}
+	return nil
+}
+
+func (c *AuthConfig) validate() error {
+	names := make(map[string]bool, len(c.Tokens))
+	for i := range c.Tokens {
+		tok := &c.Tokens[i]
+		tok.Name = strings.TrimSpace(tok.Name)
+		tok.Token = strings.TrimSpace(tok.Token)
+		tok.TokenHash = strings.TrimSpace(tok.TokenHash)
+		tok.Role = strings.ToLower(strings.TrimSpace(tok.Role))
+		tok.User = strings.TrimSpace(tok.User)
+		if tok.Name == "" {
+			return fmt.Errorf("auth.tokens[%d]: name is required", i)
+		}
+		if names[tok.Name] {
+			return fmt.Errorf("auth.tokens: duplicate token %q", tok.Name)
+		}
+		names[tok.Name] = true
+		if (tok.Token == "") == (tok.TokenHash == "") {
+			return fmt.Errorf("auth token %q: set exactly one of token or token_hash", tok.Name)
+		}
+		if tok.TokenHash != "" && !strings.HasPrefix(tok.TokenHash, authTokenHashPrefix) {
+			return fmt.Errorf("auth token %q: token_hash must start with %q", tok.Name, authTokenHashPrefix)
+		}
+		switch tok.Role {
+		case "":
+			tok.Role = RoleViewer
+		case RoleViewer, RoleOperator, RoleAdmin:
+		default:
+			return fmt.Errorf("auth token %q: unknown role %q (expected viewer, operator or admin)", tok.Name, tok.Role)
+		}
+	}
+	return nil
+}
+
+func (d *LogDestination) expandEnv() {
+	d.Address = strings.TrimSpace(expandEnvVars(d.Address))
+	d.URL = strings.TrimSpace(expandEnvVars(d.URL))
+	for k, v := range d.Headers {
+		d.Headers[k] = expandEnvVars(v)
+	}
+	for k, v := range d.Labels {
+		d.Labels[k] = expandEnvVars(v)
+	}
+}
+
+func (c *LogShippingConfig) validate() error {
+	names := make(map[string]bool, len(c.Destinations))
+	for i := range c.Destinations {
+		d := &c.Destinations[i]
+		d.Name = strings.TrimSpace(d.Name)
+		d.Type = strings.ToLower(strings.TrimSpace(d.Type))
+		if d.Name == "" {
+			return fmt.Errorf("log_shipping.destinations[%d]: name is required", i)
+		}
+		if names[d.Name] {
+			return fmt.Errorf("log_shipping.destinations: duplicate destination %q", d.Name)
+		}
+		names[d.Name] = true
+
+		switch d.Type {
+		case LogDestinationSyslog:
+			if d.Address != "" {
+				network, _, ok := strings.Cut(d.Address, "://")
+				if !ok || (network != "udp" && network != "tcp") {
+					return fmt.Errorf("log destination %q: address must be udp://host:port or tcp://host:port", d.Name)
+				}
+			}
+		case LogDestinationLoki, LogDestinationHTTP:
+			if d.URL == "" {
+				return fmt.Errorf("log destination %q: url is required", d.Name)
+			}
+		default:
+			return fmt.Errorf("log destination %q: unknown type %q (expected syslog, loki or http)", d.Name, d.Type)
+		}
+		for _, source := range d.Sources {
+			if source != LogSourceDaemon && source != LogSourceAgents {
+				return fmt.Errorf("log destination %q: unknown source %q (expected daemon or agents)", d.Name, source)
+			}
+		}
+		if d.BatchSize < 0 {
+			return fmt.Errorf("log destination %q: batch_size must not be negative", d.Name)
+		}
+		if d.FlushInterval != "" {
+			if v, err := time.ParseDuration(d.FlushInterval); err != nil || v <= 0 {
+				return fmt.Errorf("log destination %q: invalid flush_interval %q", d.Name, d.FlushInterval)
+			}
+		}
+	}
+	return nil
+}
+
+func (c *NotificationsConfig) validate() error {
+	names := make(map[string]bool, len(c.Sinks))
+	for i := range c.Sinks {
+		sink := &c.Sinks[i]
+		sink.Name = strings.TrimSpace(sink.Name)
+		sink.Type = strings.ToLower(strings.TrimSpace(sink.Type))
+		if sink.Name == "" {
+			return fmt.Errorf("notifications.sinks[%d]: name is required", i)
+		}
+		if names[sink.Name] {
+			return fmt.Errorf("notifications.sinks: duplicate sink %q", sink.Name)
+		}
+		names[sink.Name] = true
+
+		switch sink.Type {
+		case SinkSlack, SinkWebhook:
+			if strings.TrimSpace(sink.URL) == "" {
+				return fmt.Errorf("notification sink %q: url is required", sink.Name)
+			}
+		case SinkEmail:
+			if sink.SMTPHost == "" || sink.From == "" || len(sink.To) == 0 {
+				return fmt.Errorf("notification sink %q: smtp_host, from and to are required", sink.Name)
+			}
+			if sink.SMTPPort == 0 {
+				sink.SMTPPort = 587
+			}
+		default:
+			return fmt.Errorf("notification sink %q: unknown type %q (expected slack, email or webhook)", sink.Name, sink.Type)
+		}
+	}
+
+	for i, route := range c.Routes {
+		if len(route.Events) == 0 || len(route.Sinks) == 0 {
+			return fmt.Errorf("notifications.routes[%d]: events and sinks are required", i)
+		}
+		for _, name := range route.Sinks {
+			if !names[name] {
+				return fmt.Errorf("notifications.routes[%d]: unknown sink %q", i, name)
+			}
+		}
+	}
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -20,6 +21,14 @@ type DaemonConfig struct {
 	Provider       string `yaml:"provider,omitempty"`        // "local", "hetzner", etc.
 	HetznerServerID int64  `yaml:"hetzner_server_id,omitempty"` // Hetzner Cloud server ID
 	SSHKeyName     string `yaml:"ssh_key_name,omitempty"`   // SSH key name for server access
+
+	// Recorded at deploy time for cost tracking
+	ServerType   string    `yaml:"server_type,omitempty"`   // Provider server type, e.g. "cx22"
+	Region       string    `yaml:"region,omitempty"`        // Provider location, e.g. "nbg1"
+	HourlyPrice  float64   `yaml:"hourly_price,omitempty"`  // Gross price per started hour
+	MonthlyPrice float64   `yaml:"monthly_price,omitempty"` // Gross monthly cap
+	Currency     string    `yaml:"currency,omitempty"`
+	DeployedAt   time.Time `yaml:"deployed_at,omitempty"`
 }
 
 // DaemonRegistry holds all configured daemon connections
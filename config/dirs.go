@@ -0,0 +1,219 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+const (
+	// ConfigDirEnv overrides the directory holding agents.yaml, agent code
+	// and the other files users edit.
+	ConfigDirEnv = "OPPERATOR_CONFIG_DIR"
+	// DataDirEnv overrides the directory holding the database and agent
+	// runtime data.
+	DataDirEnv = "OPPERATOR_DATA_DIR"
+)
+
+// Dirs are the directories an instance keeps its files in. Data and State
+// equal Config until the XDG directories exist or are overridden.
+type Dirs struct {
+	Config string
+	Data   string
+	State  string
+}
+
+// dataFiles are the files kept in the data directory.
+var dataFiles = []string{"opperator.db", "opperator.db-wal", "opperator.db-shm", "agent_data.json"}
+
+func instanceDirName() string {
+	if name := Instance(); name != "" {
+		return AppName + "-" + name
+	}
+	return AppName
+}
+
+func envDir(key string) string {
+	dir := strings.TrimSpace(os.Getenv(key))
+	if dir == "" {
+		return ""
+	}
+	if abs, err := filepath.Abs(dir); err == nil {
+		return abs
+	}
+	return dir
+}
+
+// xdgDir returns <$env or ~/fallback>/<instance dir name>.
+func xdgDir(env, fallback string) (string, error) {
+	base := envDir(env)
+	if base == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		base = filepath.Join(homeDir, fallback)
+	}
+	return filepath.Join(base, instanceDirName()), nil
+}
+
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+func resolveConfigDir() (string, error) {
+	if dir := envDir(ConfigDirEnv); dir != "" {
+		return dir, nil
+	}
+	if dir := homeOverride(); dir != "" {
+		return dir, nil
+	}
+	dir, err := xdgDir("XDG_CONFIG_HOME", ".config")
+	if err != nil || isDir(dir) {
+		return dir, err
+	}
+	// Installs from before XDG_CONFIG_HOME was honoured live in ~/.config
+	if homeDir, err := os.UserHomeDir(); err == nil {
+		if legacy := filepath.Join(homeDir, ".config", instanceDirName()); isDir(legacy) {
+			return legacy, nil
+		}
+	}
+	return dir, nil
+}
+
+// ResolveDirs works out the directories of the selected instance without
+// creating them. The database and logs stay next to the config until the
+// XDG data and state directories exist, so existing installs keep working
+// until 'op dirs migrate' moves them.
+func ResolveDirs() (Dirs, error) {
+	var dirs Dirs
+	var err error
+	if dirs.Config, err = resolveConfigDir(); err != nil {
+		return dirs, err
+	}
+
+	home := homeOverride()
+	switch {
+	case envDir(DataDirEnv) != "":
+		dirs.Data = envDir(DataDirEnv)
+	case home != "":
+		dirs.Data = home
+	default:
+		xdg, err := xdgDir("XDG_DATA_HOME", filepath.Join(".local", "share"))
+		if err != nil {
+			return dirs, err
+		}
+		dirs.Data = dirs.Config
+		if isDir(xdg) {
+			dirs.Data = xdg
+		}
+	}
+
+	switch {
+	case home != "":
+		dirs.State = home
+	default:
+		xdg, err := xdgDir("XDG_STATE_HOME", filepath.Join(".local", "state"))
+		if err != nil {
+			return dirs, err
+		}
+		dirs.State = dirs.Data
+		if isDir(xdg) {
+			dirs.State = xdg
+		}
+	}
+	return dirs, nil
+}
+
+// GetDataDir returns the directory holding the database and agent runtime
+// data.
+func GetDataDir() (string, error) {
+	dirs, err := ResolveDirs()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dirs.Data, 0755); err != nil {
+		return "", err
+	}
+	return dirs.Data, nil
+}
+
+// GetStateDir returns the directory holding logs.
+func GetStateDir() (string, error) {
+	dirs, err := ResolveDirs()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dirs.State, 0755); err != nil {
+		return "", err
+	}
+	return dirs.State, nil
+}
+
+// DirMove is a file or directory MigrateDirs moves.
+type DirMove struct {
+	From string
+	To   string
+}
+
+// PlanDirMigration lists what MigrateDirs would move to split the database
+// and logs out of the config directory into the XDG data and state
+// directories.
+func PlanDirMigration() ([]DirMove, error) {
+	dirs, err := ResolveDirs()
+	if err != nil {
+		return nil, err
+	}
+	dataDir := dirs.Data
+	if envDir(DataDirEnv) == "" && homeOverride() == "" {
+		if dataDir, err = xdgDir("XDG_DATA_HOME", filepath.Join(".local", "share")); err != nil {
+			return nil, err
+		}
+	}
+	stateDir := dirs.State
+	if homeOverride() == "" {
+		if stateDir, err = xdgDir("XDG_STATE_HOME", filepath.Join(".local", "state")); err != nil {
+			return nil, err
+		}
+	}
+
+	var moves []DirMove
+	if dataDir != dirs.Config {
+		for _, name := range dataFiles {
+			from := filepath.Join(dirs.Config, name)
+			if _, err := os.Stat(from); err == nil {
+				moves = append(moves, DirMove{From: from, To: filepath.Join(dataDir, name)})
+			}
+		}
+	}
+	// Logs follow the data directory when there is no state directory
+	logsFrom := filepath.Join(dirs.State, "logs")
+	if logsTo := filepath.Join(stateDir, "logs"); logsTo != logsFrom && isDir(logsFrom) {
+		moves = append(moves, DirMove{From: logsFrom, To: logsTo})
+	}
+	return moves, nil
+}
+
+// MigrateDirs carries out moves. The daemon must not be running. Moves fail
+// rather than overwrite existing files.
+func MigrateDirs(moves []DirMove) error {
+	for _, m := range moves {
+		if _, err := os.Stat(m.To); err == nil {
+			return fmt.Errorf("%s already exists; move or remove it first", m.To)
+		}
+		if err := os.MkdirAll(filepath.Dir(m.To), 0755); err != nil {
+			return err
+		}
+		if err := os.Rename(m.From, m.To); err != nil {
+			if errors.Is(err, syscall.EXDEV) {
+				return fmt.Errorf("cannot move %s to %s across filesystems; move it by hand", m.From, m.To)
+			}
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// GitSyncConfig lets 'op agent reload --from-git' pull the config directory,
+// which must be a git checkout, before reloading agents.yaml. Remote defaults
+// to origin and Branch to the branch checked out. With Webhook.Listen set the
+// daemon also pulls when a push webhook is POSTed to /webhooks/git on that
+// address.
+type GitSyncConfig struct {
+	Remote  string         `yaml:"remote,omitempty"`
+	Branch  string         `yaml:"branch,omitempty"`
+	Webhook GitSyncWebhook `yaml:"webhook,omitempty"`
+}
+
+// GitSyncWebhook accepts push notifications. Secret is required and is
+// checked either as a GitHub X-Hub-Signature-256 HMAC of the body or as a
+// bearer token. String values may reference environment variables as ${NAME}.
+type GitSyncWebhook struct {
+	Listen string `yaml:"listen,omitempty"`
+	Secret string `yaml:"secret,omitempty"`
+}
+
+// RemoteName returns the remote to pull from.
+func (c GitSyncConfig) RemoteName() string {
+	if c.Remote != "" {
+		return c.Remote
+	}
+	return "origin"
+}
+
+func (c *GitSyncConfig) expandEnv() {
+	c.Remote = strings.TrimSpace(expandEnvVars(c.Remote))
+	c.Branch = strings.TrimSpace(expandEnvVars(c.Branch))
+	c.Webhook.Listen = strings.TrimSpace(expandEnvVars(c.Webhook.Listen))
+	c.Webhook.Secret = strings.TrimSpace(expandEnvVars(c.Webhook.Secret))
+}
+
+func (c *GitSyncConfig) validate() error {
+	if c.Webhook.Listen != "" && c.Webhook.Secret == "" {
+		return fmt.Errorf("git_sync.webhook: secret is required when listen is set")
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Events hooks can run on.
+const (
+	HookAgentStarted    = "agent_started"
+	HookAgentCrashed    = "agent_crashed"
+	HookTaskFailed      = "task_failed"
+	HookUpdateInstalled = "update_installed"
+)
+
+// HookEvents lists the events hooks can run on.
+var HookEvents = []string{HookAgentStarted, HookAgentCrashed, HookTaskFailed, HookUpdateInstalled}
+
+// DefaultHookTimeout is how long a hook may run when it sets no timeout.
+const DefaultHookTimeout = 30 * time.Second
+
+// HookConfig runs Command with sh when one of Events ("*" for all) happens on
+// the daemon, with the event as JSON on stdin. Agents optionally restricts
+// agent-scoped events to those agents. Commands run in the config directory.
+type HookConfig struct {
+	Events  []string `yaml:"events"`
+	Command string   `yaml:"command"`
+	Agents  []string `yaml:"agents,omitempty"`
+	Timeout string   `yaml:"timeout,omitempty"`
+}
+
+// Matches reports whether the hook runs for event about agent, which is
+// empty for events not about an agent.
+func (h HookConfig) Matches(event, agent string) bool {
+	if !slices.Contains(h.Events, event) && !slices.Contains(h.Events, "*") {
+		return false
+	}
+	return len(h.Agents) == 0 || agent == "" || slices.Contains(h.Agents, agent)
+}
+
+// RunTimeout returns how long the hook may run.
+func (h HookConfig) RunTimeout() time.Duration {
+	if d, err := time.ParseDuration(h.Timeout); err == nil && d > 0 {
+		return d
+	}
+	return DefaultHookTimeout
+}
+
+func validateHooks(hooks []HookConfig) error {
+	for i := range hooks {
+		h := &hooks[i]
+		h.Command = strings.TrimSpace(h.Command)
+		if h.Command == "" {
+			return fmt.Errorf("hooks[%d]: command is required", i)
+		}
+		if len(h.Events) == 0 {
+			return fmt.Errorf("hooks[%d]: events are required", i)
+		}
+		for j, ev := range h.Events {
+			ev = strings.ToLower(strings.TrimSpace(ev))
+			if ev != "*" && !slices.Contains(HookEvents, ev) {
+				return fmt.Errorf("hooks[%d]: unknown event %q (expected one of %s)", i, ev, strings.Join(HookEvents, ", "))
+			}
+			h.Events[j] = ev
+		}
+		if h.Timeout != "" {
+			if d, err := time.ParseDuration(h.Timeout); err != nil || d <= 0 {
+				return fmt.Errorf("hooks[%d]: invalid timeout %q", i, h.Timeout)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+const (
+	// InstanceEnv names the local daemon instance to use. `op --instance`
+	// sets it so daemons and agents started from the CLI inherit it.
+	InstanceEnv = "OPPERATOR_INSTANCE"
+	// HomeEnv points an instance's config directory (agents.yaml, database,
+	// logs) somewhere else entirely.
+	HomeEnv = "OPPERATOR_HOME"
+)
+
+var instanceNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// ValidateInstanceName checks that name can be used in file and socket names.
+func ValidateInstanceName(name string) error {
+	if !instanceNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid instance name %q: use up to 32 lowercase letters, digits, '-' or '_'", name)
+	}
+	return nil
+}
+
+// SetInstance selects the instance for this process and the processes it
+// starts. An empty name selects the default instance.
+func SetInstance(name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return os.Unsetenv(InstanceEnv)
+	}
+	if err := ValidateInstanceName(name); err != nil {
+		return err
+	}
+	return os.Setenv(InstanceEnv, name)
+}
+
+// Instance returns the name of the selected instance, or "" for the default
+// one.
+func Instance() string {
+	name := strings.TrimSpace(os.Getenv(InstanceEnv))
+	if ValidateInstanceName(name) != nil {
+		return ""
+	}
+	return name
+}
+
+// instanceSuffix tells apart the sockets and PID files of instances that
+// share the temp directory: the instance name when one is selected, else a
+// hash of the config directory when OPPERATOR_CONFIG_DIR or OPPERATOR_HOME
+// moves it.
+func instanceSuffix() string {
+	if name := Instance(); name != "" {
+		return "-" + name
+	}
+	dir := envDir(ConfigDirEnv)
+	if dir == "" {
+		dir = homeOverride()
+	}
+	if dir != "" {
+		sum := sha256.Sum256([]byte(dir))
+		return "-" + hex.EncodeToString(sum[:])[:8]
+	}
+	return ""
+}
+
+func homeOverride() string {
+	return envDir(HomeEnv)
+}
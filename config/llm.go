@@ -0,0 +1,68 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// LLMConfig points model calls at an OpenAI-compatible server, such as
+// Ollama or vLLM, instead of the Opper API, for use without internet access.
+type LLMConfig struct {
+	// BaseURL is the server's API root, e.g. http://localhost:11434/v1.
+	BaseURL string `yaml:"base_url"`
+	// APIKey is sent as a bearer token when set. ${NAME} is read from the
+	// environment.
+	APIKey string `yaml:"api_key,omitempty"`
+	// Model is used for every call in place of the Opper model names.
+	Model string `yaml:"model"`
+	// EmbeddingModel embeds agent memories and the knowledge base; Model
+	// when empty.
+	EmbeddingModel string `yaml:"embedding_model,omitempty"`
+}
+
+// GetLLMPath returns the path to the llm.yaml file
+func GetLLMPath() (string, error) {
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "llm.yaml"), nil
+}
+
+// LoadLLMConfig reads llm.yaml. A missing file yields nil, which keeps
+// model calls on the Opper API.
+func LoadLLMConfig() (*LLMConfig, error) {
+	path, err := GetLLMPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read model settings: %w", err)
+	}
+	var cfg LLMConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse model settings: %w", err)
+	}
+	cfg.BaseURL = strings.TrimSpace(expandEnvVars(cfg.BaseURL))
+	cfg.APIKey = strings.TrimSpace(expandEnvVars(cfg.APIKey))
+	cfg.Model = strings.TrimSpace(cfg.Model)
+	cfg.EmbeddingModel = strings.TrimSpace(cfg.EmbeddingModel)
+	if cfg.EmbeddingModel == "" {
+		cfg.EmbeddingModel = cfg.Model
+	}
+	if cfg.BaseURL == "" {
+		return nil, fmt.Errorf("%s: base_url is required", path)
+	}
+	if cfg.Model == "" {
+		return nil, fmt.Errorf("%s: model is required", path)
+	}
+	return &cfg, nil
+}
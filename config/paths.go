@@ -7,14 +7,16 @@ import (
 
 const AppName = "opperator"
 
+// GetConfigDir returns the config directory of the selected instance:
+// OPPERATOR_CONFIG_DIR or OPPERATOR_HOME when set, else
+// $XDG_CONFIG_HOME/opperator (~/.config/opperator), or opperator-<instance>
+// for a named instance.
 func GetConfigDir() (string, error) {
-	homeDir, err := os.UserHomeDir()
+	configDir, err := resolveConfigDir()
 	if err != nil {
 		return "", err
 	}
 
-	configDir := filepath.Join(homeDir, ".config", AppName)
-
 	// Ensure the directory exists
 	if err := os.MkdirAll(configDir, 0755); err != nil {
 		return "", err
@@ -33,28 +35,28 @@ func GetConfigFile() (string, error) {
 }
 
 func GetSocketPath() (string, error) {
-	return filepath.Join(os.TempDir(), "opperator.sock"), nil
+	return filepath.Join(os.TempDir(), "opperator"+instanceSuffix()+".sock"), nil
 }
 
 func GetPIDFile() (string, error) {
-	return filepath.Join(os.TempDir(), "opperator.pid"), nil
+	return filepath.Join(os.TempDir(), "opperator"+instanceSuffix()+".pid"), nil
 }
 
 func GetDatabasePath() (string, error) {
-	configDir, err := GetConfigDir()
+	dataDir, err := GetDataDir()
 	if err != nil {
 		return "", err
 	}
-	return filepath.Join(configDir, "opperator.db"), nil
+	return filepath.Join(dataDir, "opperator.db"), nil
 }
 
 func GetLogsDir() (string, error) {
-	configDir, err := GetConfigDir()
+	stateDir, err := GetStateDir()
 	if err != nil {
 		return "", err
 	}
 
-	logsDir := filepath.Join(configDir, "logs")
+	logsDir := filepath.Join(stateDir, "logs")
 
 	// Ensure the directory exists
 	if err := os.MkdirAll(logsDir, 0755); err != nil {
@@ -89,3 +91,12 @@ func EnsureConfigExists() error {
 
 	return nil
 }
+
+// GetWorkflowsDir returns the directory holding workflow definitions.
+func GetWorkflowsDir() (string, error) {
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "workflows"), nil
+}
@@ -0,0 +1,123 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Policy effects and the kinds of execution policies apply to.
+const (
+	PolicyAllow = "allow"
+	PolicyDeny  = "deny"
+
+	PolicyKindCommand  = "command"
+	PolicyKindTool     = "tool"
+	PolicyKindWorkflow = "workflow"
+)
+
+// PolicyConfig authorizes every agent command, async tool and workflow the
+// daemon runs. Rules are checked in order and the first match decides; when
+// none matches, Default (allow unless set to deny) applies. If OPA is set, an
+// allowed execution is also put to the OPA server, which can still deny it.
+// Timezone names the IANA zone rule hours and days are evaluated in; the
+// daemon's local time when empty.
+type PolicyConfig struct {
+	Default  string       `yaml:"default,omitempty"`
+	Timezone string       `yaml:"timezone,omitempty"`
+	Rules    []PolicyRule `yaml:"rules,omitempty"`
+	OPA      *OPAConfig   `yaml:"opa,omitempty"`
+}
+
+// PolicyRule matches an execution when every condition it sets matches.
+// Agents, Commands, Origins and Users are glob patterns; Commands match
+// command, tool and workflow names. Args maps top-level argument names to
+// glob patterns for their values. Hours is "HH:MM-HH:MM" in 24-hour time and
+// may wrap midnight ("18:00-08:00"); Days lists mon through sun.
+type PolicyRule struct {
+	Name     string            `yaml:"name"`
+	Effect   string            `yaml:"effect"`
+	Kinds    []string          `yaml:"kinds,omitempty"`
+	Agents   []string          `yaml:"agents,omitempty"`
+	Commands []string          `yaml:"commands,omitempty"`
+	Origins  []string          `yaml:"origins,omitempty"`
+	Users    []string          `yaml:"users,omitempty"`
+	Args     map[string]string `yaml:"args,omitempty"`
+	Hours    string            `yaml:"hours,omitempty"`
+	Days     []string          `yaml:"days,omitempty"`
+	Reason   string            `yaml:"reason,omitempty"`
+}
+
+// OPAConfig points at an Open Policy Agent decision, e.g.
+// http://localhost:8181/v1/data/opperator/allow. The daemon POSTs
+// {"input": ...} and expects a boolean result or {"allow": bool, "reason":
+// string}. An unreachable server denies. Timeout defaults to 2s.
+type OPAConfig struct {
+	URL     string            `yaml:"url"`
+	Timeout string            `yaml:"timeout,omitempty"`
+	Headers map[string]string `yaml:"headers,omitempty"`
+}
+
+// Enabled reports whether any authorization is configured.
+func (c PolicyConfig) Enabled() bool {
+	return len(c.Rules) > 0 || c.OPA != nil || c.Default == PolicyDeny
+}
+
+var policyDays = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+func (c *PolicyConfig) expandEnv() {
+	if c.OPA == nil {
+		return
+	}
+	c.OPA.URL = strings.TrimSpace(expandEnvVars(c.OPA.URL))
+	for k, v := range c.OPA.Headers {
+		c.OPA.Headers[k] = expandEnvVars(v)
+	}
+}
+
+func (c *PolicyConfig) validate() error {
+	c.Default = strings.ToLower(strings.TrimSpace(c.Default))
+	switch c.Default {
+	case "", PolicyAllow, PolicyDeny:
+	default:
+		return fmt.Errorf("policy.default: unknown effect %q (expected allow or deny)", c.Default)
+	}
+	c.Timezone = strings.TrimSpace(c.Timezone)
+
+	for i := range c.Rules {
+		r := &c.Rules[i]
+		r.Name = strings.TrimSpace(r.Name)
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("rules[%d]", i)
+		}
+		r.Effect = strings.ToLower(strings.TrimSpace(r.Effect))
+		if r.Effect != PolicyAllow && r.Effect != PolicyDeny {
+			return fmt.Errorf("policy rule %q: effect must be allow or deny", r.Name)
+		}
+		for j, kind := range r.Kinds {
+			kind = strings.ToLower(strings.TrimSpace(kind))
+			switch kind {
+			case PolicyKindCommand, PolicyKindTool, PolicyKindWorkflow:
+			default:
+				return fmt.Errorf("policy rule %q: unknown kind %q (expected command, tool or workflow)", r.Name, kind)
+			}
+			r.Kinds[j] = kind
+		}
+		for j, day := range r.Days {
+			day = strings.ToLower(strings.TrimSpace(day))
+			if len(day) > 3 {
+				day = day[:3]
+			}
+			if !slices.Contains(policyDays, day) {
+				return fmt.Errorf("policy rule %q: unknown day %q", r.Name, r.Days[j])
+			}
+			r.Days[j] = day
+		}
+		r.Hours = strings.TrimSpace(r.Hours)
+	}
+
+	if c.OPA != nil && c.OPA.URL == "" {
+		return fmt.Errorf("policy.opa: url is required")
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Built-in PII detectors.
+const (
+	ScrubEmail = "email"
+	ScrubCard  = "card"
+	ScrubPhone = "phone"
+	ScrubIP    = "ip"
+)
+
+// ScrubConfig controls the scrubbing of conversation content before it is
+// sent to the Opper API. Detected values are replaced with placeholders such
+// as [EMAIL_1] and restored in the model's response.
+type ScrubConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Detectors lists the built-in detectors to run; all of them when empty.
+	Detectors []string       `yaml:"detectors,omitempty"`
+	Patterns  []ScrubPattern `yaml:"patterns,omitempty"`
+}
+
+// ScrubPattern is a custom detector. Name becomes the placeholder label.
+type ScrubPattern struct {
+	Name    string `yaml:"name"`
+	Pattern string `yaml:"pattern"`
+}
+
+// GetScrubPath returns the path to the scrubbing.yaml file
+func GetScrubPath() (string, error) {
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "scrubbing.yaml"), nil
+}
+
+// LoadScrubConfig reads scrubbing.yaml. A missing file yields nil, which
+// disables scrubbing.
+func LoadScrubConfig() (*ScrubConfig, error) {
+	path, err := GetScrubPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scrubbing settings: %w", err)
+	}
+	var cfg ScrubConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse scrubbing settings: %w", err)
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+func (c *ScrubConfig) validate() error {
+	for i, name := range c.Detectors {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch name {
+		case ScrubEmail, ScrubCard, ScrubPhone, ScrubIP:
+		default:
+			return fmt.Errorf("unknown detector %q (expected email, card, phone or ip)", name)
+		}
+		c.Detectors[i] = name
+	}
+	for _, p := range c.Patterns {
+		if strings.TrimSpace(p.Name) == "" {
+			return fmt.Errorf("pattern %q requires a name", p.Pattern)
+		}
+		if _, err := regexp.Compile(p.Pattern); err != nil {
+			return fmt.Errorf("pattern %s: %w", p.Name, err)
+		}
+	}
+	return nil
+}
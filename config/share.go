@@ -0,0 +1,113 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Share upload targets.
+const (
+	ShareTargetPaste = "paste"
+	ShareTargetS3    = "s3"
+)
+
+// ShareConfig is where 'op conversation share --upload' puts rendered
+// conversations. String values may reference environment variables as
+// ${NAME}.
+type ShareConfig struct {
+	Target string      `yaml:"target"`
+	Paste  *SharePaste `yaml:"paste,omitempty"`
+	S3     *ShareS3    `yaml:"s3,omitempty"`
+}
+
+// SharePaste POSTs the rendered conversation to a paste service. The link is
+// read from a JSON response's url or link field, or from a plain-text body.
+type SharePaste struct {
+	URL string `yaml:"url"`
+	// Field sends the content as this multipart form field instead of as
+	// the raw request body.
+	Field   string            `yaml:"field,omitempty"`
+	Headers map[string]string `yaml:"headers,omitempty"`
+}
+
+// ShareS3 uploads to a bucket with credentials from the AWS_* environment
+// variables.
+type ShareS3 struct {
+	Bucket string `yaml:"bucket"`
+	Region string `yaml:"region"`
+	Prefix string `yaml:"prefix,omitempty"`
+	// Endpoint selects an S3-compatible service, addressed path-style.
+	Endpoint string `yaml:"endpoint,omitempty"`
+	// PublicURL is prepended to the object key for the printed link, for
+	// buckets served through a CDN; the object URL is used otherwise.
+	PublicURL string `yaml:"public_url,omitempty"`
+}
+
+// GetSharePath returns the path to the share.yaml file
+func GetSharePath() (string, error) {
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "share.yaml"), nil
+}
+
+// LoadShareConfig reads and validates share.yaml.
+func LoadShareConfig() (*ShareConfig, error) {
+	path, err := GetSharePath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no upload target configured; create %s", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read share settings: %w", err)
+	}
+	var cfg ShareConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse share settings: %w", err)
+	}
+	cfg.expandEnv()
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+func (c *ShareConfig) expandEnv() {
+	if c.Paste != nil {
+		c.Paste.URL = expandEnvVars(c.Paste.URL)
+		for name, value := range c.Paste.Headers {
+			c.Paste.Headers[name] = expandEnvVars(value)
+		}
+	}
+	if c.S3 != nil {
+		c.S3.Bucket = expandEnvVars(c.S3.Bucket)
+		c.S3.Region = expandEnvVars(c.S3.Region)
+		c.S3.Endpoint = expandEnvVars(c.S3.Endpoint)
+		c.S3.PublicURL = expandEnvVars(c.S3.PublicURL)
+	}
+}
+
+func (c *ShareConfig) validate() error {
+	c.Target = strings.ToLower(strings.TrimSpace(c.Target))
+	switch c.Target {
+	case ShareTargetPaste:
+		if c.Paste == nil || strings.TrimSpace(c.Paste.URL) == "" {
+			return fmt.Errorf("paste target requires paste.url")
+		}
+	case ShareTargetS3:
+		if c.S3 == nil || strings.TrimSpace(c.S3.Bucket) == "" || strings.TrimSpace(c.S3.Region) == "" {
+			return fmt.Errorf("s3 target requires s3.bucket and s3.region")
+		}
+	default:
+		return fmt.Errorf("target must be paste or s3, got %q", c.Target)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SlashConfig holds user-defined slash command aliases for the TUI, each
+// mapping a name to an agent command:
+//
+//	aliases:
+//	  /fc: weather:forecast
+type SlashConfig struct {
+	Aliases map[string]string `yaml:"aliases"`
+}
+
+// SlashAlias is an alias and the agent command it runs.
+type SlashAlias struct {
+	// Name is the alias with its leading slash, e.g. /fc.
+	Name    string
+	Agent   string
+	Command string
+}
+
+// GetSlashPath returns the path to the slash.yaml file
+func GetSlashPath() (string, error) {
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "slash.yaml"), nil
+}
+
+// LoadSlashAliases reads the aliases in slash.yaml. A missing file yields
+// none.
+func LoadSlashAliases() ([]SlashAlias, error) {
+	path, err := GetSlashPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read slash command settings: %w", err)
+	}
+	var cfg SlashConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse slash command settings: %w", err)
+	}
+
+	aliases := make([]SlashAlias, 0, len(cfg.Aliases))
+	for name, target := range cfg.Aliases {
+		name = "/" + strings.TrimLeft(strings.ToLower(strings.TrimSpace(name)), "/")
+		if name == "/" || strings.ContainsAny(name, " \t") {
+			return nil, fmt.Errorf("%s: invalid alias %q", path, name)
+		}
+		agent, command, ok := strings.Cut(strings.TrimLeft(strings.TrimSpace(target), "/"), ":")
+		agent, command = strings.TrimSpace(agent), strings.TrimLeft(strings.TrimSpace(command), "/")
+		if !ok || agent == "" || command == "" {
+			return nil, fmt.Errorf("%s: alias %s must name agent:command, got %q", path, name, target)
+		}
+		aliases = append(aliases, SlashAlias{Name: name, Agent: agent, Command: command})
+	}
+	return aliases, nil
+}
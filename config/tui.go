@@ -0,0 +1,95 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultToolOutputMaxLines is how many lines of a finished tool call the
+// TUI shows before folding the rest.
+const DefaultToolOutputMaxLines = 20
+
+// TUIConfig holds display settings for the TUI:
+//
+//	tool_output:
+//	  max_lines: 20
+//	  tools:
+//	    bash:
+//	      max_lines: 50
+//	    read_documentation:
+//	      collapsed: true
+type TUIConfig struct {
+	ToolOutput ToolOutputConfig `yaml:"tool_output"`
+}
+
+// ToolOutputConfig sets how tool calls are folded in the conversation. The
+// inline settings apply to every tool; Tools overrides them per tool name.
+type ToolOutputConfig struct {
+	ToolDisplay `yaml:",inline"`
+	Tools       map[string]ToolDisplay `yaml:"tools"`
+}
+
+// ToolDisplay sets how one tool's output is shown. Unset fields fall back to
+// the defaults.
+type ToolDisplay struct {
+	// Collapsed shows only the first line until the call is expanded.
+	Collapsed *bool `yaml:"collapsed"`
+	// MaxLines folds output beyond this many lines; 0 shows everything.
+	MaxLines *int `yaml:"max_lines"`
+}
+
+// For returns whether a tool's output starts collapsed and how many lines of
+// it are shown.
+func (c ToolOutputConfig) For(tool string) (collapsed bool, maxLines int) {
+	maxLines = DefaultToolOutputMaxLines
+	apply := func(d ToolDisplay) {
+		if d.Collapsed != nil {
+			collapsed = *d.Collapsed
+		}
+		if d.MaxLines != nil {
+			maxLines = max(*d.MaxLines, 0)
+		}
+	}
+	apply(c.ToolDisplay)
+	tool = strings.ToLower(strings.TrimSpace(tool))
+	for name, d := range c.Tools {
+		if strings.ToLower(strings.TrimSpace(name)) == tool {
+			apply(d)
+			break
+		}
+	}
+	return collapsed, maxLines
+}
+
+// GetTUIPath returns the path to the tui.yaml file
+func GetTUIPath() (string, error) {
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "tui.yaml"), nil
+}
+
+// LoadTUIConfig reads tui.yaml. A missing file yields the defaults.
+func LoadTUIConfig() (*TUIConfig, error) {
+	path, err := GetTUIPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &TUIConfig{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read TUI settings: %w", err)
+	}
+	var cfg TUIConfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse TUI settings: %w", err)
+	}
+	return &cfg, nil
+}
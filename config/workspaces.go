@@ -0,0 +1,250 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// WorkspaceEnvVar selects the active workspace for one shell, overriding the
+// one chosen with 'op workspace use'.
+const WorkspaceEnvVar = "OPPERATOR_WORKSPACE"
+
+var workspaceNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// Workspace groups agents, secrets and conversations under a name, e.g.
+// "client-a" and "personal". Triggers follow the agents they invoke.
+type Workspace struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description,omitempty"`
+	// Daemon is where new agents are bootstrapped and which daemon wins when
+	// an agent name exists on several
+	Daemon string `yaml:"daemon,omitempty"`
+	// Model is used for conversations in place of the default model
+	Model   string   `yaml:"model,omitempty"`
+	Agents  []string `yaml:"agents,omitempty"`
+	Secrets []string `yaml:"secrets,omitempty"`
+}
+
+// WorkspaceRegistry holds the configured workspaces and the active one
+type WorkspaceRegistry struct {
+	Current    string      `yaml:"current,omitempty"`
+	Workspaces []Workspace `yaml:"workspaces"`
+}
+
+// HasAgent reports whether the agent belongs to the workspace. A nil
+// workspace, meaning none is active, includes everything.
+func (w *Workspace) HasAgent(name string) bool {
+	return w == nil || slices.Contains(w.Agents, name)
+}
+
+// HasSecret reports whether the secret belongs to the workspace. A nil
+// workspace includes everything.
+func (w *Workspace) HasSecret(name string) bool {
+	return w == nil || slices.Contains(w.Secrets, name)
+}
+
+// ValidateWorkspaceName checks that name is usable as a workspace name
+func ValidateWorkspaceName(name string) error {
+	if !workspaceNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid workspace name %q: use letters, digits, '.', '_' and '-'", name)
+	}
+	return nil
+}
+
+// GetWorkspaceRegistryPath returns the path to the workspaces.yaml file
+func GetWorkspaceRegistryPath() (string, error) {
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "workspaces.yaml"), nil
+}
+
+// LoadWorkspaceRegistry loads the workspace registry from disk. A missing
+// file yields an empty registry.
+func LoadWorkspaceRegistry() (*WorkspaceRegistry, error) {
+	registryPath, err := GetWorkspaceRegistryPath()
+	if err != nil {
+		return nil, err
+	}
+
+	registry := WorkspaceRegistry{Workspaces: []Workspace{}}
+	data, err := os.ReadFile(registryPath)
+	if os.IsNotExist(err) {
+		return &registry, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read workspace registry: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &registry); err != nil {
+		return nil, fmt.Errorf("failed to parse workspace registry: %w", err)
+	}
+	return &registry, nil
+}
+
+// SaveWorkspaceRegistry saves the workspace registry to disk
+func SaveWorkspaceRegistry(registry *WorkspaceRegistry) error {
+	registryPath, err := GetWorkspaceRegistryPath()
+	if err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(registry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal workspace registry: %w", err)
+	}
+
+	if err := os.WriteFile(registryPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write workspace registry: %w", err)
+	}
+
+	return nil
+}
+
+// GetWorkspace returns a pointer to the named workspace in the registry, so
+// changes to it are saved with the registry
+func (r *WorkspaceRegistry) GetWorkspace(name string) (*Workspace, error) {
+	for i := range r.Workspaces {
+		if r.Workspaces[i].Name == name {
+			return &r.Workspaces[i], nil
+		}
+	}
+	return nil, fmt.Errorf("workspace '%s' not found", name)
+}
+
+// AddWorkspace adds a new workspace to the registry
+func (r *WorkspaceRegistry) AddWorkspace(ws Workspace) error {
+	if err := ValidateWorkspaceName(ws.Name); err != nil {
+		return err
+	}
+	if _, err := r.GetWorkspace(ws.Name); err == nil {
+		return fmt.Errorf("workspace '%s' already exists", ws.Name)
+	}
+	r.Workspaces = append(r.Workspaces, ws)
+	return nil
+}
+
+// RemoveWorkspace removes a workspace by name, deactivating it if it was the
+// active one
+func (r *WorkspaceRegistry) RemoveWorkspace(name string) error {
+	for i, ws := range r.Workspaces {
+		if ws.Name == name {
+			r.Workspaces = append(r.Workspaces[:i], r.Workspaces[i+1:]...)
+			if r.Current == name {
+				r.Current = ""
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("workspace '%s' not found", name)
+}
+
+// ActiveWorkspaceName returns the workspace selected by OPPERATOR_WORKSPACE
+// or 'op workspace use', or "" when none is active
+func (r *WorkspaceRegistry) ActiveWorkspaceName() string {
+	if name := strings.TrimSpace(os.Getenv(WorkspaceEnvVar)); name != "" {
+		return name
+	}
+	return r.Current
+}
+
+// ActiveWorkspace returns the active workspace, or nil when none is active.
+// It fails when the active name does not match a configured workspace.
+func ActiveWorkspace() (*Workspace, error) {
+	registry, err := LoadWorkspaceRegistry()
+	if err != nil {
+		return nil, err
+	}
+	name := registry.ActiveWorkspaceName()
+	if name == "" {
+		return nil, nil
+	}
+	ws, err := registry.GetWorkspace(name)
+	if err != nil {
+		return nil, fmt.Errorf("active workspace '%s' does not exist; create it with 'op workspace create %s'", name, name)
+	}
+	return ws, nil
+}
+
+// AddToWorkspace records an agent or secret as a member of the active
+// workspace, if one is active. kind is "agent" or "secret". It returns the
+// workspace name, or "" when none is active.
+func AddToWorkspace(kind, name string) (string, error) {
+	registry, err := LoadWorkspaceRegistry()
+	if err != nil {
+		return "", err
+	}
+	active := registry.ActiveWorkspaceName()
+	if active == "" {
+		return "", nil
+	}
+	ws, err := registry.GetWorkspace(active)
+	if err != nil {
+		return "", err
+	}
+	if !ws.Add(kind, name) {
+		return ws.Name, nil
+	}
+	return ws.Name, SaveWorkspaceRegistry(registry)
+}
+
+// RemoveFromWorkspaces drops a deleted agent or secret from every workspace
+func RemoveFromWorkspaces(kind, name string) error {
+	registry, err := LoadWorkspaceRegistry()
+	if err != nil {
+		return err
+	}
+	changed := false
+	for i := range registry.Workspaces {
+		if registry.Workspaces[i].Remove(kind, name) {
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return SaveWorkspaceRegistry(registry)
+}
+
+// Add records a member of the given kind ("agent" or "secret"). It reports
+// whether the workspace changed.
+func (w *Workspace) Add(kind, name string) bool {
+	members := w.members(kind)
+	if members == nil || slices.Contains(*members, name) {
+		return false
+	}
+	*members = append(*members, name)
+	slices.Sort(*members)
+	return true
+}
+
+// Remove drops a member of the given kind. It reports whether the workspace
+// changed.
+func (w *Workspace) Remove(kind, name string) bool {
+	members := w.members(kind)
+	if members == nil {
+		return false
+	}
+	i := slices.Index(*members, name)
+	if i < 0 {
+		return false
+	}
+	*members = slices.Delete(*members, i, i+1)
+	return true
+}
+
+func (w *Workspace) members(kind string) *[]string {
+	switch kind {
+	case "agent":
+		return &w.Agents
+	case "secret":
+		return &w.Secrets
+	}
+	return nil
+}
@@ -13,7 +13,7 @@ import (
 	"syscall"
 	"time"
 
-	"opperator/config"
+	"opperator/internal/credentials"
 	"opperator/internal/protocol"
 	"tui/components/sidebar"
 )
@@ -25,6 +25,12 @@ const (
 	StatusRunning  ProcessStatus = "running"
 	StatusCrashed  ProcessStatus = "crashed"
 	StatusStopping ProcessStatus = "stopping"
+	// StatusCrashLooping marks an agent that crashed too often within the
+	// restart policy's window; it stays stopped until started manually.
+	StatusCrashLooping ProcessStatus = "crashlooping"
+	// StatusProvisioning is reported while the agent's runtime dependencies
+	// are being installed ahead of a start.
+	StatusProvisioning ProcessStatus = "provisioning"
 )
 
 type Agent struct {
@@ -35,15 +41,18 @@ type Agent struct {
 	RestartCount        int
 	systemPrompt        string
 	systemPromptReplace bool
+	instructions        string // InstructionsFile, appended to the system prompt
 	description         string
 	color               string
 	sectionStore        *SectionStore
 
-	cmd    *exec.Cmd
-	stdout io.ReadCloser
-	stderr io.ReadCloser
-	stdin  io.WriteCloser
-	mu     sync.RWMutex
+	cmd        *exec.Cmd
+	stdout     io.ReadCloser
+	stderr     io.ReadCloser
+	stdin      io.WriteCloser
+	stderrDone chan struct{}
+	stderrTail []string
+	mu         sync.RWMutex
 
 	// Protocol support
 	protocol *protocol.ProcessProtocol
@@ -54,14 +63,39 @@ type Agent struct {
 	// State change notification
 	stateChangeNotifier func(agentName string, changeType string, data interface{})
 
+	// kvHandler serves the key-value requests the agent sends
+	kvHandler KVHandler
+
 	// Log notification - single entry streaming (no throttling needed)
 	lastLogEntry string
 
 	// Early exit detection for startup stability check
 	earlyExitChan chan error
+	// exited is closed once the process of the latest start has exited
+	exited chan struct{}
 
 	// Last invocation directory for change detection (where user runs 'op' from)
 	lastInvocationDir string
+
+	// Crash loop tracking
+	recentCrashes []time.Time
+	lastExit      *ExitInfo
+
+	// paused is set while the agent rejects commands; see PauseAgent
+	paused *PauseInfo
+
+	// Capabilities negotiated in the ready handshake; nil until it arrives
+	capabilities *protocol.Capabilities
+	// ready is set once the agent reports ready after its latest start
+	ready bool
+	// metricsSectionPending is set while an update of the metrics section
+	// is scheduled
+	metricsSectionPending bool
+
+	// Recent protocol messages, for tracing; see ProtocolFrames
+	framesMu sync.Mutex
+	frames   []ProtocolFrame
+	frameSeq uint64
 }
 
 // MetadataUpdate captures the user-facing metadata for an agent.
@@ -73,7 +107,7 @@ type MetadataUpdate struct {
 }
 
 func NewAgent(config AgentConfig, persistence *AgentPersistence, sectionStore *SectionStore) *Agent {
-	return &Agent{
+	a := &Agent{
 		Config:       config,
 		Status:       StatusStopped,
 		systemPrompt: strings.TrimSpace(config.SystemPrompt),
@@ -82,28 +116,27 @@ func NewAgent(config AgentConfig, persistence *AgentPersistence, sectionStore *S
 		sectionStore: sectionStore,
 		persistence:  persistence,
 	}
+	if dir, err := config.WorkingDir(); err == nil {
+		a.instructions = readInstructions(dir)
+	}
+	return a
 }
 
 func (a *Agent) Start() error {
+	workingDir, err := a.workingDir()
+	if err != nil {
+		return err
+	}
+
+	if err := a.provisionBeforeStart(workingDir); err != nil {
+		return err
+	}
+
 	a.mu.Lock()
 
 	if a.Status == StatusRunning {
 		a.mu.Unlock()
-		return fmt.Errorf("agent %s is already running", a.Config.Name)
-	}
-
-	workingDir := strings.TrimSpace(a.Config.ProcessRoot)
-	if workingDir == "" || !filepath.IsAbs(workingDir) {
-		configDir, err := config.GetConfigDir()
-		if err != nil {
-			a.mu.Unlock()
-			return fmt.Errorf("resolve config directory: %w", err)
-		}
-		if workingDir == "" {
-			workingDir = configDir
-		} else {
-			workingDir = filepath.Join(configDir, workingDir)
-		}
+		return fmt.Errorf("agent %s is %w", a.Config.Name, ErrAlreadyRunning)
 	}
 
 	cmdPath := strings.TrimSpace(a.Config.Command)
@@ -121,46 +154,62 @@ func (a *Agent) Start() error {
 		Setpgid: true,
 	}
 
-	a.cmd.Env = os.Environ()
-	for key, value := range a.Config.Env {
-		a.cmd.Env = append(a.cmd.Env, fmt.Sprintf("%s=%s", key, value))
+	env, err := a.Config.Environment()
+	if err != nil {
+		a.mu.Unlock()
+		return err
 	}
+	a.cmd.Env = append(os.Environ(), env...)
 
-	var err error
 	a.stdout, err = a.cmd.StdoutPipe()
 	if err != nil {
 		a.mu.Unlock()
 		return fmt.Errorf("failed to create stdout pipe: %w", err)
 	}
 
-	a.stderr, err = a.cmd.StderrPipe()
+	// stderr goes through a pipe we own so Wait doesn't close it before the
+	// trailing lines (usually the interesting ones after a crash) are read
+	stderrReader, stderrWriter, err := os.Pipe()
 	if err != nil {
 		a.mu.Unlock()
 		return fmt.Errorf("failed to create stderr pipe: %w", err)
 	}
+	a.cmd.Stderr = stderrWriter
+	a.stderr = stderrReader
 
 	a.stdin, err = a.cmd.StdinPipe()
 	if err != nil {
+		stderrReader.Close()
+		stderrWriter.Close()
 		a.mu.Unlock()
 		return fmt.Errorf("failed to create stdin pipe: %w", err)
 	}
 
 	if err := a.cmd.Start(); err != nil {
+		stderrReader.Close()
+		stderrWriter.Close()
 		a.mu.Unlock()
 		return fmt.Errorf("failed to start process: %w", err)
 	}
+	stderrWriter.Close()
 
 	a.PID = a.cmd.Process.Pid
 	a.Status = StatusRunning
 	a.StartTime = time.Now()
+	a.stderrTail = nil
+	a.stderrDone = make(chan struct{})
+	a.capabilities = nil
+	a.ready = false
 
 	// Create channel for early exit detection
 	a.earlyExitChan = make(chan error, 1)
+	a.exited = make(chan struct{})
 
 	// Record start in persistence
 	if a.persistence != nil {
 		a.persistence.RecordStart(a.Config.Name)
 		a.persistence.RecordRunning(a.Config.Name)
+		a.persistence.RecordProcessGroup(a.Config.Name, a.cmd.Process.Pid)
 	}
 
 	// Setup protocol for all processes
@@ -197,45 +246,98 @@ func (a *Agent) Start() error {
 	}
 }
 
+func (a *Agent) workingDir() (string, error) {
+	return a.Config.WorkingDir()
+}
+
+// provisionBeforeStart installs runtime dependencies without holding a.mu,
+// reporting the provisioning status while it runs.
+func (a *Agent) provisionBeforeStart(workingDir string) error {
+	a.mu.Lock()
+	switch a.Status {
+	case StatusRunning:
+		a.mu.Unlock()
+		return fmt.Errorf("agent %s is %w", a.Config.Name, ErrAlreadyRunning)
+	case StatusProvisioning:
+		a.mu.Unlock()
+		return fmt.Errorf("agent %s is being provisioned: %w", a.Config.Name, ErrAlreadyRunning)
+	}
+	previous := a.Status
+	a.Status = StatusProvisioning
+	notifier := a.stateChangeNotifier
+	agentName := a.Config.Name
+	a.mu.Unlock()
+
+	if notifier != nil {
+		notifier(agentName, "status", string(StatusProvisioning))
+	}
+
+	err := a.ensureRuntime(workingDir)
+
+	a.mu.Lock()
+	if a.Status == StatusProvisioning {
+		a.Status = previous
+	}
+	a.mu.Unlock()
+
+	if err != nil {
+		if notifier != nil {
+			notifier(agentName, "status", string(previous))
+		}
+		return err
+	}
+	return nil
+}
+
 func (a *Agent) Stop() error {
-	return a.stop(false)
+	_, err := a.stop(false, StopOptions{})
+	return err
+}
+
+// StopWithOptions stops the agent as opts asks and reports how its process
+// ended.
+func (a *Agent) StopWithOptions(opts StopOptions) (StopMethod, error) {
+	return a.stop(false, opts)
 }
 
 func (a *Agent) StopPreservingState() error {
-	return a.stop(true)
+	_, err := a.stop(true, StopOptions{Reason: "daemon shutdown"})
+	return err
 }
 
-func (a *Agent) stop(preserveRunningState bool) error {
+func (a *Agent) stop(preserveRunningState bool, opts StopOptions) (StopMethod, error) {
 	a.mu.Lock()
 
 	if a.Status != StatusRunning {
 		a.mu.Unlock()
-		return fmt.Errorf("agent %s is not running", a.Config.Name)
+		return "", fmt.Errorf("agent %s is %w", a.Config.Name, ErrNotRunning)
 	}
 
 	a.Status = StatusStopping
 	cmd := a.cmd
+	exited := a.exited
 	a.mu.Unlock()
 
+	if opts.Reason == "" {
+		opts.Reason = "stop requested"
+	}
+	if opts.Force {
+		a.addLog(fmt.Sprintf("[stop] Killing (reason: %s)", opts.Reason))
+	} else {
+		a.addLog(fmt.Sprintf("[stop] Stopping (reason: %s)", opts.Reason))
+	}
+
 	// Do the blocking operations outside the lock
+	var method StopMethod
 	if cmd != nil && cmd.Process != nil {
-		// Try graceful termination first
-		syscall.Kill(-cmd.Process.Pid, syscall.SIGTERM)
-
-		done := make(chan error, 1)
-		go func() {
-			done <- cmd.Wait()
-		}()
-
-		select {
-		case <-done:
-		case <-time.After(3 * time.Second):
-			// Force kill if not terminated
-			syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
-			select {
-			case <-done:
-			case <-time.After(1 * time.Second):
-			}
+		method = a.terminate(cmd.Process.Pid, exited, opts)
+		switch method {
+		case StopGraceful:
+			a.addLog("[stop] Exited after the shutdown event")
+		case StopTerminated:
+			a.addLog("[stop] Exited after SIGTERM")
+		case StopKilled:
+			a.addLog("[stop] Killed with SIGKILL")
 		}
 	}
 
@@ -267,7 +369,7 @@ func (a *Agent) stop(preserveRunningState bool) error {
 		notifier(agentName, "status", string(StatusStopped))
 	}
 
-	return nil
+	return method, nil
 }
 
 func (a *Agent) Restart() error {
@@ -291,6 +393,13 @@ func (a *Agent) GetStatus() ProcessStatus {
 	return a.Status
 }
 
+// GetPID returns the process ID of the running agent, or 0.
+func (a *Agent) GetPID() int {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.PID
+}
+
 func (a *Agent) GetLogs() []string {
 	a.mu.RLock()
 	defer a.mu.RUnlock()
@@ -305,10 +414,17 @@ func (a *Agent) GetLogs() []string {
 func (a *Agent) addLog(line string) {
 	a.mu.Lock()
 	defer a.mu.Unlock()
+	a.addLogLocked(line)
+}
 
+// addLogLocked is addLog for callers already holding a.mu.
+func (a *Agent) addLogLocked(line string) {
 	if a.persistence != nil {
 		a.persistence.AddLog(a.Config.Name, line)
 	}
+	if sink := logSink.Load(); sink != nil {
+		(*sink)(a.Config.Name, line)
+	}
 
 	// Send immediate single log entry notification
 	a.lastLogEntry = line
@@ -317,23 +433,53 @@ func (a *Agent) addLog(line string) {
 	}
 }
 
-func (a *Agent) captureOutput(reader io.ReadCloser, prefix string) {
+// captureStderr logs stderr lines tagged with the stream and keeps a tail of
+// them for crash reports.
+func (a *Agent) captureStderr(reader io.ReadCloser, done chan struct{}) {
+	defer close(done)
 	scanner := bufio.NewScanner(reader)
 	for scanner.Scan() {
-		line := fmt.Sprintf("[%s] %s", prefix, scanner.Text())
-		a.addLog(line)
+		text := credentials.Redact(scanner.Text())
+		a.appendStderrTail(text)
+		a.addLog(fmt.Sprintf("[stderr] %s", text))
 	}
 }
 
 func (a *Agent) waitForExit() {
+	a.mu.RLock()
+	exited := a.exited
+	a.mu.RUnlock()
 	if a.cmd != nil {
 		err := a.cmd.Wait()
+		if exited != nil {
+			close(exited)
+		}
+
+		// Processes the agent spawned share its process group; don't let
+		// them outlive it
+		a.reapProcessGroup(a.cmd.Process.Pid)
 
 		// Stop protocol if it was running
 		if a.protocol != nil {
 			a.protocol.Stop()
 		}
 
+		// Give the stderr reader a moment to drain; orphaned children may
+		// still hold the pipe open, so don't wait on them forever
+		a.mu.RLock()
+		stderrDone := a.stderrDone
+		stderr := a.stderr
+		a.mu.RUnlock()
+		if stderrDone != nil {
+			select {
+			case <-stderrDone:
+			case <-time.After(2 * time.Second):
+			}
+		}
+		if stderr != nil {
+			stderr.Close()
+		}
+
 		if err != nil {
 			a.addLog(fmt.Sprintf("[error] Agent exited: %v", err))
 		}
@@ -361,6 +507,8 @@ func (a *Agent) waitForExit() {
 		a.mu.Lock()
 
 		if a.Status == StatusRunning {
+			exitInfo := newExitInfo(a.cmd.ProcessState)
+			a.lastExit = exitInfo
 			var newStatus ProcessStatus
 			if err == nil {
 				newStatus = StatusStopped // Normal completion, not crashed
@@ -375,29 +523,60 @@ func (a *Agent) waitForExit() {
 					a.persistence.RecordCrash(a.Config.Name)
 				}
 			}
+			if a.persistence != nil && exitInfo != nil {
+				a.persistence.RecordExit(a.Config.Name, *exitInfo)
+			}
+
+			policy := a.Config.RestartPolicy.resolve()
+			crashes := 0
+			if newStatus == StatusCrashed {
+				report := a.buildCrashReportLocked(exitInfo)
+				if a.persistence != nil {
+					a.persistence.RecordCrashReport(report)
+				}
+				if a.sectionStore != nil {
+					a.sectionStore.SaveSection(a.Config.Name, lastCrashSectionID, crashSection(report))
+					if a.stateChangeNotifier != nil {
+						a.stateChangeNotifier(a.Config.Name, "sections", a.sectionStore.GetSections(a.Config.Name))
+					}
+				}
+
+				crashes = a.recordCrashLocked(policy, time.Now())
+				if crashes >= policy.CrashLoopThreshold {
+					newStatus = StatusCrashLooping
+					a.addLogLocked(fmt.Sprintf("[error] Agent crashed %d times within %s, marking as crashlooping", crashes, policy.CrashLoopWindow))
+				}
+			}
+
 			a.Status = newStatus
 			a.PID = 0
 			notifier := a.stateChangeNotifier
 			agentName := a.Config.Name
+			update := StatusUpdate{Status: newStatus, LastExit: exitInfo}
 
 			// Only auto-restart if it crashed AND auto-restart is enabled
 			if a.Status == StatusCrashed && a.Config.AutoRestart && a.RestartCount < a.Config.MaxRestarts {
 				a.RestartCount++
+				delay := policy.Backoff(crashes)
+				a.addLogLocked(fmt.Sprintf("[restart] Restarting in %s (attempt %d)", delay.Round(time.Millisecond), a.RestartCount))
 				a.mu.Unlock()
 
 				// Notify about the crash before restarting
 				if notifier != nil {
-					notifier(agentName, "status", string(StatusCrashed))
+					notifier(agentName, "status", update)
 				}
 
-				time.Sleep(2 * time.Second)
-				a.Start()
+				time.Sleep(delay)
+				// A manual stop or start during the backoff wins over the restart
+				if a.GetStatus() == StatusCrashed {
+					a.Start()
+				}
 			} else {
 				a.mu.Unlock()
 
 				// Notify about status change
 				if notifier != nil {
-					notifier(agentName, "status", string(newStatus))
+					notifier(agentName, "status", update)
 				}
 			}
 		} else {
@@ -411,22 +590,26 @@ func (a *Agent) waitForExit() {
 
 // setupProtocol initializes the protocol handler for managed processes
 func (a *Agent) setupProtocol() {
-	a.protocol = protocol.NewProcessProtocol(a.stdin, a.stdout, a.stderr)
+	// stderr is captured separately by captureStderr so lines keep their stream tag
+	a.protocol = protocol.NewProcessProtocol(a.stdin, a.stdout, nil)
 
 	// Register handlers
 	a.protocol.RegisterDefaults(&protocol.DefaultHandlers{
 		OnReady: func(pid int, version string) {
 			a.mu.Lock()
 			a.PID = pid
+			a.ready = true
 			a.mu.Unlock()
 			a.addLog(fmt.Sprintf("[protocol] Agent ready (PID: %d, Version: %s)", pid, version))
 		},
+		OnHandshake: a.handleHandshake,
 		OnLog: func(level protocol.LogLevel, message string, fields map[string]interface{}) {
 			logLine := fmt.Sprintf("[%s] %s", level, message)
 			if len(fields) > 0 {
 				logLine += fmt.Sprintf(" %v", fields)
 			}
 			a.addLog(logLine)
+			a.forwardLog(level, message, fields)
 		},
 		OnEvent: func(name string, data map[string]interface{}) {
 			a.addLog(fmt.Sprintf("[event] %s: %v", name, data))
@@ -457,6 +640,7 @@ func (a *Agent) setupProtocol() {
 			if trimmed != "" {
 				a.addLog("[system-prompt] updated")
 			}
+			a.recordPromptVersion(PromptSourceAgent)
 			a.notifyMetadataChange()
 		},
 		OnDescription: func(description string) {
@@ -469,6 +653,7 @@ func (a *Agent) setupProtocol() {
 			} else {
 				a.addLog("[description] cleared")
 			}
+			a.recordPromptVersion(PromptSourceAgent)
 			a.notifyMetadataChange()
 		},
 		OnSidebarSection: func(section protocol.SidebarSectionMessage) {
@@ -484,6 +669,24 @@ func (a *Agent) setupProtocol() {
 				Content:   section.Content,
 				Collapsed: section.Collapsed,
 			}
+			if strings.EqualFold(strings.TrimSpace(section.Type), protocol.SidebarSectionDashboard) {
+				sec.Type = sidebar.SectionTypeDashboard
+				sec.Format = protocol.DashboardFormatMarkdown
+				if strings.EqualFold(strings.TrimSpace(section.Format), protocol.DashboardFormatHTML) {
+					sec.Format = protocol.DashboardFormatHTML
+				}
+			}
+			for _, action := range protocol.NormalizeSidebarActions(section.Actions) {
+				sec.Actions = append(sec.Actions, sidebar.SectionAction{
+					ID:          action.ID,
+					Label:       action.Label,
+					Type:        string(action.Type),
+					Command:     action.Command,
+					Args:        action.Args,
+					Arg:         action.Arg,
+					Placeholder: action.Placeholder,
+				})
+			}
 			a.sectionStore.SaveSection(a.Config.Name, sectionID, sec)
 
 			// Notify about sections change
@@ -517,16 +720,28 @@ func (a *Agent) setupProtocol() {
 			if a.stateChangeNotifier != nil {
 				out := make([]protocol.CommandDescriptor, len(normalized))
 				copy(out, normalized)
-				a.stateChangeNotifier(a.Config.Name, "commands", out)
+				a.stateChangeNotifier(a.Config.Name, "commands", a.applyCommandCapabilities(out))
 			}
 		},
+		OnTriggerRegistry: func(triggers []protocol.TriggerDescriptor) {
+			a.addLog(fmt.Sprintf("[triggers] registered %d trigger(s)", len(triggers)))
+			if a.stateChangeNotifier != nil {
+				a.stateChangeNotifier(a.Config.Name, "triggers", triggers)
+			}
+		},
+		OnMetric:    a.recordMetric,
+		OnKVRequest: a.handleKVRequest(a.protocol),
 	})
 
+	// Scrub stored secrets before anything the agent prints is logged,
+	// persisted or returned as a command result.
+	a.protocol.SetLineFilter(credentials.Redact)
+	a.protocol.SetFrameTap(a.recordFrame)
 	a.protocol.SetRawOutputHandler(func(line string) {
 		a.addLog(fmt.Sprintf("[stdout] %s", line))
 	})
 
-	go a.captureOutput(a.stderr, "stderr")
+	go a.captureStderr(a.stderr, a.stderrDone)
 
 	// Start protocol handler
 	a.protocol.Start()
@@ -541,7 +756,13 @@ func (a *Agent) RegisteredCommands() []protocol.CommandDescriptor {
 		return nil
 	}
 
-	return a.protocol.RegisteredCommands()
+	cmds := a.protocol.RegisteredCommands()
+	if a.capabilities != nil && !a.capabilities.AsyncCommands {
+		for i := range cmds {
+			cmds[i].Async = false
+		}
+	}
+	return cmds
 }
 
 // SendCommand sends a command to a managed process and waits for the response
@@ -552,11 +773,14 @@ func (a *Agent) SendCommand(ctx context.Context, command string, args map[string
 	a.mu.RUnlock()
 
 	if status != StatusRunning {
-		return nil, fmt.Errorf("agent %s is not running", a.Config.Name)
+		return nil, fmt.Errorf("agent %s is %w", a.Config.Name, ErrNotRunning)
 	}
 	if pro == nil {
 		return nil, fmt.Errorf("protocol not initialized for agent %s", a.Config.Name)
 	}
+	if err := a.PausedErr(); err != nil {
+		return nil, err
+	}
 
 	return pro.SendCommand(ctx, command, args, strings.TrimSpace(workingDir))
 }
@@ -569,11 +793,14 @@ func (a *Agent) SendCommandWithProgress(ctx context.Context, command string, arg
 	a.mu.RUnlock()
 
 	if status != StatusRunning {
-		return nil, fmt.Errorf("agent %s is not running", a.Config.Name)
+		return nil, fmt.Errorf("agent %s is %w", a.Config.Name, ErrNotRunning)
 	}
 	if pro == nil {
 		return nil, fmt.Errorf("protocol not initialized for agent %s", a.Config.Name)
 	}
+	if err := a.PausedErr(); err != nil {
+		return nil, err
+	}
 
 	return pro.SendCommandWithProgress(ctx, command, args, strings.TrimSpace(workingDir), progress)
 }
@@ -588,6 +815,12 @@ func (a *Agent) SendLifecycleEvent(eventType string, data map[string]interface{}
 		return fmt.Errorf("protocol not initialized for agent %s", a.Config.Name)
 	}
 
+	// Agents that did not negotiate lifecycle events would only log the
+	// unknown message, so skip sending it
+	if !a.supports(protocol.CapabilityLifecycleEvents) {
+		return nil
+	}
+
 	return pro.SendLifecycleEvent(eventType, data)
 }
 
@@ -630,13 +863,16 @@ func (a *Agent) CheckAndNotifyInvocationDirChange(newInvocationDir string) error
 	return nil
 }
 
+// SystemPrompt returns the prompt the agent set at runtime, or the one from
+// its config, followed by its InstructionsFile.
 func (a *Agent) SystemPrompt() string {
 	a.mu.RLock()
 	defer a.mu.RUnlock()
-	if trimmed := strings.TrimSpace(a.systemPrompt); trimmed != "" {
-		return trimmed
+	prompt := strings.TrimSpace(a.systemPrompt)
+	if prompt == "" {
+		prompt = strings.TrimSpace(a.Config.SystemPrompt)
 	}
-	return strings.TrimSpace(a.Config.SystemPrompt)
+	return withInstructions(prompt, a.instructions)
 }
 
 func (a *Agent) SystemPromptReplace() bool {
@@ -674,6 +910,7 @@ func (a *Agent) metadataSnapshot() MetadataUpdate {
 	a.mu.RLock()
 	systemPrompt := a.systemPrompt
 	systemPromptReplace := a.systemPromptReplace
+	instructions := a.instructions
 	description := a.description
 	color := a.color
 	a.mu.RUnlock()
@@ -689,6 +926,7 @@ func (a *Agent) metadataSnapshot() MetadataUpdate {
 	} else {
 		promptReplace = systemPromptReplace
 	}
+	prompt = withInstructions(prompt, instructions)
 	col := strings.TrimSpace(color)
 	if col == "" {
 		col = strings.TrimSpace(a.Config.Color)
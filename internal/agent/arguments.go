@@ -0,0 +1,84 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"opperator/internal/protocol"
+)
+
+// ArgumentError is a command argument that doesn't match the schema its
+// agent declared.
+type ArgumentError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// InvalidArgumentsError lists every argument of a command invocation that
+// doesn't match the command's declared arguments.
+type InvalidArgumentsError struct {
+	Command string
+	Fields  []ArgumentError
+}
+
+func (e *InvalidArgumentsError) Error() string {
+	parts := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		parts[i] = f.Message
+	}
+	return fmt.Sprintf("invalid arguments for %s: %s", e.Command, strings.Join(parts, "; "))
+}
+
+// validateCommandArgs checks args against the arguments the agent declared
+// for command, so malformed arguments fail before reaching the agent.
+// Commands the agent hasn't registered, or that declare no arguments, are
+// left for the agent to check.
+func validateCommandArgs(agent *Agent, command string, args map[string]interface{}) error {
+	for _, def := range agent.RegisteredCommands() {
+		if def.Name == command {
+			return ValidateArguments(def, args)
+		}
+	}
+	return nil
+}
+
+// ValidateArguments checks args against the arguments declared by def:
+// required arguments and each argument's JSON Schema, including enums,
+// bounds, patterns and the schemas of array items and object properties. Undeclared arguments are passed through. It returns
+// nil or an *InvalidArgumentsError listing every offending argument.
+func ValidateArguments(def protocol.CommandDescriptor, args map[string]interface{}) error {
+	if len(def.Arguments) == 0 {
+		return nil
+	}
+	// Validate the JSON form so numbers compare the same however the
+	// arguments were built.
+	decoded := map[string]any{}
+	if args != nil {
+		data, err := json.Marshal(args)
+		if err != nil {
+			return fmt.Errorf("invalid arguments for %s: %w", def.Name, err)
+		}
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			return fmt.Errorf("invalid arguments for %s: %w", def.Name, err)
+		}
+	}
+
+	var fields []ArgumentError
+	for _, arg := range def.Arguments {
+		value, ok := decoded[arg.Name]
+		if !ok || value == nil {
+			if arg.Required && arg.Default == nil {
+				fields = append(fields, ArgumentError{Field: arg.Name, Message: arg.Name + " is required"})
+			}
+			continue
+		}
+		if err := validateSchema(arg.JSONSchema(), value, arg.Name); err != nil {
+			fields = append(fields, ArgumentError{Field: arg.Name, Message: err.Error()})
+		}
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	return &InvalidArgumentsError{Command: def.Name, Fields: fields}
+}
@@ -0,0 +1,177 @@
+package agent
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"opperator/internal/protocol"
+)
+
+// invalidFields returns the fields named by err, an *InvalidArgumentsError,
+// or nil when err is nil.
+func invalidFields(t *testing.T, err error) []string {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	var invalid *InvalidArgumentsError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("Expected *InvalidArgumentsError, got %T: %v", err, err)
+	}
+	fields := make([]string, len(invalid.Fields))
+	for i, f := range invalid.Fields {
+		fields[i] = f.Field
+	}
+	return fields
+}
+
+func TestValidateArguments(t *testing.T) {
+	def := protocol.CommandDescriptor{
+		Name: "crawl",
+		Arguments: []protocol.CommandArgument{
+			{Name: "url", Type: "string", Required: true},
+			{Name: "depth", Type: "integer"},
+			{Name: "ratio", Type: "number"},
+			{Name: "follow", Type: "boolean"},
+			{Name: "tags", Type: "array"},
+			{Name: "headers", Type: "object"},
+			{Name: "mode", Type: "string", Required: true, Default: "fast"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		args    map[string]interface{}
+		invalid []string
+	}{
+		{
+			name: "valid",
+			args: map[string]interface{}{
+				"url":     "https://example.com",
+				"depth":   2,
+				"ratio":   0.5,
+				"follow":  true,
+				"tags":    []string{"a"},
+				"headers": map[string]string{"Accept": "text/html"},
+			},
+		},
+		{
+			name: "undeclared arguments pass through",
+			args: map[string]interface{}{"url": "https://example.com", "extra": 1},
+		},
+		{
+			name:    "missing required",
+			args:    map[string]interface{}{"depth": 1},
+			invalid: []string{"url"},
+		},
+		{
+			name:    "nil arguments",
+			args:    nil,
+			invalid: []string{"url"},
+		},
+		{
+			name:    "null required",
+			args:    map[string]interface{}{"url": nil},
+			invalid: []string{"url"},
+		},
+		{
+			name:    "wrong string type",
+			args:    map[string]interface{}{"url": 42},
+			invalid: []string{"url"},
+		},
+		{
+			name:    "fractional integer",
+			args:    map[string]interface{}{"url": "x", "depth": 1.5},
+			invalid: []string{"depth"},
+		},
+		{
+			name:    "number as string",
+			args:    map[string]interface{}{"url": "x", "ratio": "0.5"},
+			invalid: []string{"ratio"},
+		},
+		{
+			name:    "every offending argument",
+			args:    map[string]interface{}{"follow": "yes", "tags": "a", "headers": []string{}},
+			invalid: []string{"url", "follow", "tags", "headers"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateArguments(def, tt.args)
+			if got := invalidFields(t, err); !reflect.DeepEqual(got, tt.invalid) {
+				t.Errorf("Expected invalid fields %v, got %v (%v)", tt.invalid, got, err)
+			}
+		})
+	}
+}
+
+func TestValidateArguments_NoDeclaredArguments(t *testing.T) {
+	def := protocol.CommandDescriptor{Name: "ping"}
+	if err := ValidateArguments(def, map[string]interface{}{"anything": []int{1}}); err != nil {
+		t.Errorf("Expected commands without declared arguments to accept anything, got %v", err)
+	}
+}
+
+func TestValidateArguments_Constraints(t *testing.T) {
+	floatPtr := func(f float64) *float64 { return &f }
+	intPtr := func(i int) *int { return &i }
+
+	def := protocol.CommandDescriptor{
+		Name: "deploy",
+		Arguments: []protocol.CommandArgument{
+			{Name: "env", Type: "string", Enum: []interface{}{"staging", "production"}},
+			{Name: "replicas", Type: "integer", Minimum: floatPtr(1), Maximum: floatPtr(10)},
+			{Name: "tag", Type: "string", MinLength: intPtr(2), MaxLength: intPtr(8), Pattern: `^v\d+$`},
+			{Name: "hosts", Type: "array", MinItems: intPtr(1), MaxItems: intPtr(2), Items: map[string]interface{}{
+				"type":       "string",
+				"min_length": 3,
+			}},
+			{Name: "target", Type: "object", Properties: map[string]interface{}{
+				"region": map[string]interface{}{"type": "string", "required": true},
+				"zone":   map[string]interface{}{"type": "string", "enum": []interface{}{"a", "b"}},
+			}},
+			{Name: "loose", Type: "string", Pattern: "("},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		args    map[string]interface{}
+		invalid []string
+	}{
+		{
+			name: "valid",
+			args: map[string]interface{}{
+				"env":      "staging",
+				"replicas": 3,
+				"tag":      "v12",
+				"hosts":    []string{"web1", "web2"},
+				"target":   map[string]interface{}{"region": "eu", "zone": "a"},
+				"loose":    "an invalid pattern is ignored",
+			},
+		},
+		{name: "not in enum", args: map[string]interface{}{"env": "dev"}, invalid: []string{"env"}},
+		{name: "below minimum", args: map[string]interface{}{"replicas": 0}, invalid: []string{"replicas"}},
+		{name: "above maximum", args: map[string]interface{}{"replicas": 11}, invalid: []string{"replicas"}},
+		{name: "too short", args: map[string]interface{}{"tag": "v"}, invalid: []string{"tag"}},
+		{name: "too long", args: map[string]interface{}{"tag": "v123456789"}, invalid: []string{"tag"}},
+		{name: "pattern mismatch", args: map[string]interface{}{"tag": "latest"}, invalid: []string{"tag"}},
+		{name: "too few items", args: map[string]interface{}{"hosts": []string{}}, invalid: []string{"hosts"}},
+		{name: "too many items", args: map[string]interface{}{"hosts": []string{"web1", "web2", "web3"}}, invalid: []string{"hosts"}},
+		{name: "invalid item", args: map[string]interface{}{"hosts": []string{"web1", "x"}}, invalid: []string{"hosts"}},
+		{name: "wrong item type", args: map[string]interface{}{"hosts": []interface{}{"web1", 7}}, invalid: []string{"hosts"}},
+		{name: "missing nested required", args: map[string]interface{}{"target": map[string]interface{}{"zone": "a"}}, invalid: []string{"target"}},
+		{name: "invalid nested property", args: map[string]interface{}{"target": map[string]interface{}{"region": "eu", "zone": "c"}}, invalid: []string{"target"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateArguments(def, tt.args)
+			if got := invalidFields(t, err); !reflect.DeepEqual(got, tt.invalid) {
+				t.Errorf("Expected invalid fields %v, got %v (%v)", tt.invalid, got, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package agent
+
+import "tui/components/sidebar"
+
+// blackboardSectionID is the sidebar section the daemon maintains with the
+// latest writes to the blackboards an agent can read.
+const blackboardSectionID = "__blackboard"
+
+// SetBlackboardSection publishes content as the agent's blackboard section.
+func (a *Agent) SetBlackboardSection(content string) {
+	if a.sectionStore == nil {
+		return
+	}
+	a.sectionStore.SaveSection(a.Config.Name, blackboardSectionID, sidebar.CustomSection{
+		ID:      blackboardSectionID,
+		Title:   "Blackboard",
+		Content: content,
+	})
+	a.notifySections()
+}
+
+// ClearBlackboardSection removes the blackboard section once the agent can
+// no longer read any blackboard with entries.
+func (a *Agent) ClearBlackboardSection() {
+	a.clearSection(blackboardSectionID)
+}
@@ -0,0 +1,92 @@
+package agent
+
+import (
+	"fmt"
+
+	"opperator/internal/protocol"
+)
+
+// Capabilities returns the feature set negotiated with the running agent, or
+// nil before its ready message arrived.
+func (a *Agent) Capabilities() *protocol.Capabilities {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.capabilities == nil {
+		return nil
+	}
+	caps := *a.capabilities
+	return &caps
+}
+
+// supports reports whether the agent negotiated capability c. Agents that
+// have not completed the handshake yet are treated as legacy agents.
+func (a *Agent) supports(c protocol.Capability) bool {
+	a.mu.RLock()
+	caps := a.capabilities
+	a.mu.RUnlock()
+	if caps == nil {
+		return true
+	}
+	switch c {
+	case protocol.CapabilityAsyncCommands:
+		return caps.AsyncCommands
+	case protocol.CapabilityLifecycleEvents:
+		return caps.LifecycleEvents
+	case protocol.CapabilityCustomSections:
+		return caps.CustomSections
+	case protocol.CapabilityStructuredLogs:
+		return caps.StructuredLogs
+	case protocol.CapabilityCancellation:
+		return caps.Cancellation
+	}
+	return false
+}
+
+// handleHandshake stores the negotiated capabilities, acknowledges them to
+// agents that speak the versioned protocol and republishes commands that
+// need downgrading.
+func (a *Agent) handleHandshake(caps protocol.Capabilities) {
+	a.mu.Lock()
+	a.capabilities = &caps
+	pro := a.protocol
+	notifier := a.stateChangeNotifier
+	agentName := a.Config.Name
+	a.mu.Unlock()
+
+	if pro != nil {
+		pro.SetCancellationSupported(caps.Cancellation)
+	}
+
+	if caps.ProtocolVersion == 0 {
+		a.addLog("[protocol] Legacy agent without handshake, assuming default capabilities")
+	} else {
+		a.addLog(fmt.Sprintf("[protocol] Negotiated protocol v%d with capabilities %v", caps.ProtocolVersion, caps.List()))
+		if pro != nil {
+			if err := pro.SendHandshake(caps); err != nil {
+				a.addLog(fmt.Sprintf("[protocol] Failed to send handshake: %v", err))
+			}
+		}
+	}
+
+	if notifier == nil {
+		return
+	}
+	notifier(agentName, "capabilities", caps)
+	if !caps.AsyncCommands && pro != nil {
+		if cmds := pro.RegisteredCommands(); len(cmds) > 0 {
+			notifier(agentName, "commands", a.applyCommandCapabilities(cmds))
+		}
+	}
+}
+
+// applyCommandCapabilities strips the async flag when the agent cannot run
+// commands in the background, so callers fall back to synchronous calls.
+func (a *Agent) applyCommandCapabilities(cmds []protocol.CommandDescriptor) []protocol.CommandDescriptor {
+	if a.supports(protocol.CapabilityAsyncCommands) {
+		return cmds
+	}
+	for i := range cmds {
+		cmds[i].Async = false
+	}
+	return cmds
+}
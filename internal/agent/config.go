@@ -1,9 +1,14 @@
 package agent
 
 import (
+	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
+	"opperator/config"
 )
 
 type AgentConfig struct {
@@ -18,10 +23,51 @@ type AgentConfig struct {
 	MaxRestarts     int               `yaml:"max_restarts"`
 	StartWithDaemon *bool             `yaml:"start_with_daemon,omitempty"`
 	SystemPrompt    string            `yaml:"system_prompt,omitempty"`
+	RestartPolicy   *RestartPolicy    `yaml:"restart_policy,omitempty"`
+	// Runtime selects dependency provisioning: "auto" (default) detects
+	// pyproject.toml/requirements.txt/package.json, "none" disables it.
+	Runtime string `yaml:"runtime,omitempty"`
+	// Guardrails check model output and command arguments before they take
+	// effect.
+	Guardrails *Guardrails `yaml:"guardrails,omitempty"`
+	// Session is the conversation an ephemeral agent was spawned for. Such
+	// agents are removed when the conversation is deleted.
+	Session string `yaml:"session,omitempty"`
+	// DependsOn names agents that must be running and ready before this one
+	// starts.
+	DependsOn []string `yaml:"depends_on,omitempty"`
+	// OpperAPIKey names the keyring secret holding the Opper API key this
+	// agent uses instead of the daemon's OPPER_API_KEY, so its usage is billed
+	// to a different Opper project.
+	OpperAPIKey string `yaml:"opper_api_key,omitempty"`
+	// Secrets maps environment variables to the keyring secrets whose values
+	// they are set to, e.g. `CRM_TOKEN: CRM_TOKEN_PROD`.
+	Secrets map[string]string `yaml:"secrets,omitempty"`
+	// StopGracePeriod is how long the agent has to exit after the shutdown
+	// lifecycle event before it gets SIGTERM (default 10s).
+	StopGracePeriod time.Duration `yaml:"stop_grace_period,omitempty"`
+	// AllowedDirs are the directories commands may run in, including their
+	// subdirectories. Relative paths are resolved against the config
+	// directory. Defaults to the home directory and the process root.
+	AllowedDirs []string `yaml:"allowed_dirs,omitempty"`
+}
+
+// RestartPolicy tunes how crashed agents are restarted. Zero values fall back
+// to the defaults in DefaultRestartPolicy.
+type RestartPolicy struct {
+	InitialBackoff     time.Duration `yaml:"initial_backoff,omitempty"`
+	MaxBackoff         time.Duration `yaml:"max_backoff,omitempty"`
+	Multiplier         float64       `yaml:"multiplier,omitempty"`
+	Jitter             float64       `yaml:"jitter,omitempty"`
+	CrashLoopThreshold int           `yaml:"crash_loop_threshold,omitempty"`
+	CrashLoopWindow    time.Duration `yaml:"crash_loop_window,omitempty"`
 }
 
 type Config struct {
 	Agents []AgentConfig `yaml:"agents"`
+
+	// encrypted holds the values decrypted on load by their path
+	encrypted map[string]encryptedValue
 }
 
 func LoadConfig(path string) (*Config, error) {
@@ -30,11 +76,19 @@ func LoadConfig(path string) (*Config, error) {
 		return nil, err
 	}
 
-	var config Config
-	if err := yaml.Unmarshal(data, &config); err != nil {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
 		return nil, err
 	}
-
+	config := Config{encrypted: make(map[string]encryptedValue)}
+	if err := decryptConfig(&doc, config.encrypted); err != nil {
+		return nil, fmt.Errorf("decrypt %s: %w", filepath.Base(path), err)
+	}
+	if doc.Kind != 0 {
+		if err := doc.Decode(&config); err != nil {
+			return nil, err
+		}
+	}
 
 	return &config, nil
 }
@@ -46,3 +100,20 @@ func (c AgentConfig) StartWithDaemonEnabled() bool {
 	}
 	return false // default: do NOT auto-start unless explicitly enabled
 }
+
+// WorkingDir resolves the agent's process root against the config directory.
+func (c AgentConfig) WorkingDir() (string, error) {
+	workingDir := strings.TrimSpace(c.ProcessRoot)
+	if workingDir == "" || !filepath.IsAbs(workingDir) {
+		configDir, err := config.GetConfigDir()
+		if err != nil {
+			return "", fmt.Errorf("resolve config directory: %w", err)
+		}
+		if workingDir == "" {
+			workingDir = configDir
+		} else {
+			workingDir = filepath.Join(configDir, workingDir)
+		}
+	}
+	return workingDir, nil
+}
@@ -0,0 +1,74 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"tui/components/sidebar"
+)
+
+// maxStderrTail is how many trailing stderr lines are kept for crash reports.
+const maxStderrTail = 50
+
+// lastCrashSectionID is the sidebar section the daemon maintains for crashes.
+const lastCrashSectionID = "__last_crash"
+
+// CrashReport captures the exit status and trailing stderr of a crashed agent.
+type CrashReport struct {
+	AgentName  string    `json:"agent_name"`
+	Exit       ExitInfo  `json:"exit"`
+	StderrTail []string  `json:"stderr_tail,omitempty"`
+	At         time.Time `json:"at"`
+}
+
+func (a *Agent) appendStderrTail(line string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.stderrTail = append(a.stderrTail, line)
+	if len(a.stderrTail) > maxStderrTail {
+		a.stderrTail = a.stderrTail[len(a.stderrTail)-maxStderrTail:]
+	}
+}
+
+// buildCrashReportLocked snapshots the crash details; callers hold a.mu.
+func (a *Agent) buildCrashReportLocked(exit *ExitInfo) CrashReport {
+	report := CrashReport{
+		AgentName:  a.Config.Name,
+		StderrTail: append([]string(nil), a.stderrTail...),
+		At:         time.Now(),
+	}
+	if exit != nil {
+		report.Exit = *exit
+		report.At = exit.At
+	}
+	return report
+}
+
+// LastCrash returns the most recent crash report stored for the agent.
+func (a *Agent) LastCrash() (*CrashReport, error) {
+	if a.persistence == nil {
+		return nil, nil
+	}
+	return a.persistence.GetLastCrash(a.Config.Name)
+}
+
+// crashSection renders a crash report as a sidebar section.
+func crashSection(report CrashReport) sidebar.CustomSection {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s at %s\n", report.Exit.String(), report.At.Local().Format("2006-01-02 15:04:05"))
+	tail := report.StderrTail
+	if len(tail) > 10 {
+		tail = tail[len(tail)-10:]
+	}
+	if len(tail) == 0 {
+		b.WriteString("No stderr output captured")
+	} else {
+		b.WriteString(strings.Join(tail, "\n"))
+	}
+	return sidebar.CustomSection{
+		ID:      lastCrashSectionID,
+		Title:   "Last crash",
+		Content: b.String(),
+	}
+}
@@ -0,0 +1,208 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+)
+
+// dependencyReadyTimeout bounds how long dependents wait for an agent to
+// report ready. Agents that stay up that long without reporting, such as
+// ones not built on the SDK, are then treated as ready.
+const dependencyReadyTimeout = 15 * time.Second
+
+// StartOrder returns names together with every agent they depend on,
+// directly or indirectly, ordered so each agent comes after its
+// dependencies. It fails on unknown agents and dependency cycles.
+func (m *Manager) StartOrder(names []string) ([]string, error) {
+	m.mu.RLock()
+	deps := make(map[string][]string, len(m.config.Agents))
+	for _, c := range m.config.Agents {
+		deps[c.Name] = c.DependsOn
+	}
+	m.mu.RUnlock()
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[string]int)
+	var order []string
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("dependency cycle: %s", strings.Join(append(path, name), " -> "))
+		}
+		agentDeps, ok := deps[name]
+		if !ok {
+			if len(path) > 0 {
+				return fmt.Errorf("agent %s depends on unknown agent %s", path[len(path)-1], name)
+			}
+			return fmt.Errorf("agent %s %w", name, ErrNotFound)
+		}
+		state[name] = visiting
+		path = append(slices.Clip(path), name)
+		for _, dep := range agentDeps {
+			if err := visit(dep, path); err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+		order = append(order, name)
+		return nil
+	}
+	for _, name := range names {
+		if err := visit(name, nil); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// startDependencies starts the agents name depends on, in order, and waits
+// for each to be ready.
+func (m *Manager) startDependencies(name string) error {
+	order, err := m.StartOrder([]string{name})
+	if err != nil {
+		return err
+	}
+	for _, dep := range order[:len(order)-1] {
+		if err := m.ensureReady(dep); err != nil {
+			return fmt.Errorf("dependency %s of %s: %w", dep, name, err)
+		}
+	}
+	return nil
+}
+
+// ensureReady starts the agent unless it is running and waits until it is
+// ready.
+func (m *Manager) ensureReady(name string) error {
+	a, err := m.GetAgent(name)
+	if err != nil {
+		return err
+	}
+	if a.GetStatus() != StatusRunning {
+		log.Printf("Starting agent %s as a dependency", name)
+		if err := a.Start(); err != nil && !errors.Is(err, ErrAlreadyRunning) {
+			return err
+		}
+	}
+	return a.waitReady(dependencyReadyTimeout)
+}
+
+// StartAgents starts names in dependency order, along with the agents they
+// depend on, waiting for dependencies to be ready before starting their
+// dependents. An agent whose dependencies fail is not started. It returns
+// the agents it started and the failures by agent.
+func (m *Manager) StartAgents(names []string) ([]string, map[string]error) {
+	var started []string
+	failed := make(map[string]error)
+	done := make(map[string]bool)
+	for _, name := range names {
+		order, err := m.StartOrder([]string{name})
+		if err != nil {
+			failed[name] = err
+			continue
+		}
+		for _, n := range order {
+			if done[n] {
+				continue
+			}
+			done[n] = true
+			a, err := m.GetAgent(n)
+			if err != nil {
+				failed[n] = err
+				continue
+			}
+			if dep := m.failedDependency(n, failed); dep != "" {
+				failed[n] = fmt.Errorf("dependency %s failed to start", dep)
+				continue
+			}
+			if a.GetStatus() != StatusRunning {
+				if err := a.Start(); err != nil {
+					failed[n] = err
+					continue
+				}
+				started = append(started, n)
+			}
+			if len(m.Dependents(n)) > 0 {
+				if err := a.waitReady(dependencyReadyTimeout); err != nil {
+					failed[n] = err
+				}
+			}
+		}
+	}
+	return started, failed
+}
+
+func (m *Manager) failedDependency(name string, failed map[string]error) string {
+	for _, dep := range m.dependsOn(name) {
+		if _, ok := failed[dep]; ok {
+			return dep
+		}
+	}
+	return ""
+}
+
+func (m *Manager) dependsOn(name string) []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, c := range m.config.Agents {
+		if c.Name == name {
+			return c.DependsOn
+		}
+	}
+	return nil
+}
+
+// Dependents returns the agents that directly depend on name.
+func (m *Manager) Dependents(name string) []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var dependents []string
+	for _, c := range m.config.Agents {
+		if slices.Contains(c.DependsOn, name) {
+			dependents = append(dependents, c.Name)
+		}
+	}
+	return dependents
+}
+
+// RunningDependents returns the running agents that directly depend on
+// name, which stopping it may break.
+func (m *Manager) RunningDependents(name string) []string {
+	var running []string
+	for _, dep := range m.Dependents(name) {
+		if a, err := m.GetAgent(dep); err == nil && a.GetStatus() == StatusRunning {
+			running = append(running, dep)
+		}
+	}
+	return running
+}
+
+// waitReady waits for the agent to report ready after starting, or to have
+// stayed up for timeout.
+func (a *Agent) waitReady(timeout time.Duration) error {
+	for {
+		a.mu.RLock()
+		status, ready, startedAt := a.Status, a.ready, a.StartTime
+		a.mu.RUnlock()
+		if status != StatusRunning {
+			return fmt.Errorf("agent %s is %s", a.Config.Name, status)
+		}
+		if ready {
+			return nil
+		}
+		if time.Since(startedAt) >= timeout {
+			log.Printf("Agent %s has not reported ready after %s; treating it as ready", a.Config.Name, timeout)
+			return nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
@@ -0,0 +1,239 @@
+package agent
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"opperator/internal/credentials"
+)
+
+// EncryptedTag marks an agents.yaml value encrypted with the config key from
+// the keyring, e.g. `API_URL: !encrypted bXkgc2VjcmV0...`.
+const EncryptedTag = "!encrypted"
+
+// encryptedValue is an agents.yaml value decrypted on load.
+type encryptedValue struct {
+	plaintext  string
+	ciphertext string
+}
+
+// decryptConfig replaces every !encrypted scalar in doc with its plaintext.
+// Those of agents are recorded in encrypted by their path, the agent's name
+// followed by the mapping keys and sequence indices leading to the value,
+// e.g. "crawler/env/API_URL".
+func decryptConfig(doc *yaml.Node, encrypted map[string]encryptedValue) error {
+	for name, node := range agentNodes(doc) {
+		if err := decryptNodes(node, name, encrypted); err != nil {
+			return err
+		}
+	}
+	return decryptNodes(doc, "", nil)
+}
+
+func decryptNodes(node *yaml.Node, prefix string, encrypted map[string]encryptedValue) error {
+	return walkScalars(node, prefix, func(path string, n *yaml.Node) error {
+		if n.Tag != EncryptedTag {
+			return nil
+		}
+		plaintext, err := credentials.DecryptValue(n.Value)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", n.Line, err)
+		}
+		if encrypted != nil {
+			encrypted[path] = encryptedValue{plaintext: plaintext, ciphertext: n.Value}
+		}
+		n.Tag = "!!str"
+		n.Value = plaintext
+		return nil
+	})
+}
+
+// encryptNodes puts back the ciphertext of values that were decrypted on
+// load, so saving a loaded config never writes them in the clear. A value
+// is encrypted when it still holds the plaintext recorded at its path, or,
+// as renaming an agent or reordering a list moves values to other paths,
+// when it holds the plaintext of any recorded value. Agent names are left
+// as they are, since the file is matched to agents by them.
+func encryptNodes(agents *yaml.Node, encrypted map[string]encryptedValue) {
+	byPlaintext := make(map[string]string, len(encrypted))
+	for _, v := range encrypted {
+		if v.plaintext != "" {
+			byPlaintext[v.plaintext] = v.ciphertext
+		}
+	}
+	for name, node := range agentNodes(agents) {
+		_ = walkScalars(node, name, func(path string, n *yaml.Node) error {
+			if n.Tag != "!!str" || path == name+"/name" {
+				return nil
+			}
+			ciphertext, ok := byPlaintext[n.Value]
+			if v, at := encrypted[path]; at && n.Value == v.plaintext {
+				ciphertext, ok = v.ciphertext, true
+			}
+			if ok {
+				n.Tag = EncryptedTag
+				n.Value = ciphertext
+				n.Style = 0
+			}
+			return nil
+		})
+	}
+}
+
+// encryptedPaths returns the paths, relative to the agent, of agentName's
+// values that were decrypted on load.
+func (c *Config) encryptedPaths(agentName string) []string {
+	var paths []string
+	for path := range c.encrypted {
+		if rel, ok := strings.CutPrefix(path, agentName+"/"); ok {
+			paths = append(paths, "/"+rel)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// encryptAgent encrypts the values of agent at paths, relative to the agent,
+// with this machine's config key, so SaveConfig writes them as !encrypted.
+func (c *Config) encryptAgent(agent AgentConfig, paths []string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+	var node yaml.Node
+	if err := node.Encode(agent); err != nil {
+		return fmt.Errorf("failed to marshal agent: %w", err)
+	}
+	if c.encrypted == nil {
+		c.encrypted = make(map[string]encryptedValue)
+	}
+	return walkScalars(&node, agent.Name, func(path string, n *yaml.Node) error {
+		if !slices.Contains(paths, strings.TrimPrefix(path, agent.Name)) {
+			return nil
+		}
+		ciphertext, err := credentials.EncryptValue(n.Value)
+		if err != nil {
+			return fmt.Errorf("encrypt %s: %w", path, err)
+		}
+		c.encrypted[path] = encryptedValue{plaintext: n.Value, ciphertext: ciphertext}
+		return nil
+	})
+}
+
+// agentNodes returns the agent mappings of an agents.yaml document, or of
+// its agents sequence, by agent name.
+func agentNodes(node *yaml.Node) map[string]*yaml.Node {
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	if node.Kind == yaml.MappingNode {
+		node = mappingValue(node, "agents")
+	}
+	if node == nil || node.Kind != yaml.SequenceNode {
+		return nil
+	}
+	agents := make(map[string]*yaml.Node)
+	for _, item := range node.Content {
+		if name := mappingValue(item, "name"); name != nil && name.Value != "" {
+			agents[name.Value] = item
+		}
+	}
+	return agents
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// walkScalars calls fn with every scalar under node and its path: prefix
+// followed by the mapping keys and sequence indices leading to it, each
+// after a "/".
+func walkScalars(node *yaml.Node, prefix string, fn func(path string, n *yaml.Node) error) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		return fn(prefix, node)
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if err := walkScalars(node.Content[i+1], prefix+"/"+node.Content[i].Value, fn); err != nil {
+				return err
+			}
+		}
+	case yaml.SequenceNode:
+		for i, child := range node.Content {
+			if err := walkScalars(child, prefix+"/"+strconv.Itoa(i), fn); err != nil {
+				return err
+			}
+		}
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			if err := walkScalars(child, prefix, fn); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// SaveConfig writes cfg's agents to agents.yaml at path, keeping the file's
+// other top-level keys and re-encrypting values that were loaded from
+// !encrypted ones.
+func SaveConfig(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode}
+	}
+	if len(doc.Content) == 0 {
+		// An empty or comment-only file; its comments stay on the document
+		doc.Content = []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("failed to update config: top level is not a mapping")
+	}
+
+	var agents yaml.Node
+	if err := agents.Encode(cfg.Agents); err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+	encryptNodes(&agents, cfg.encrypted)
+
+	replaced := false
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == "agents" {
+			root.Content[i+1] = &agents
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "agents"}, &agents)
+	}
+
+	newData, err := yaml.Marshal(&doc)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+	if err := os.WriteFile(path, newData, 0644); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zalando/go-keyring"
+	"gopkg.in/yaml.v3"
+	"opperator/internal/credentials"
+)
+
+// writeEncryptedConfig writes an agents.yaml whose crawler agent has an
+// encrypted API_URL and returns its path.
+func writeEncryptedConfig(t *testing.T) string {
+	t.Helper()
+	ciphertext, err := credentials.EncryptValue("https://secret.example")
+	if err != nil {
+		t.Fatalf("EncryptValue failed: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "agents.yaml")
+	data := "agents:\n" +
+		"  - name: crawler\n" +
+		"    command: python\n" +
+		"    args: [\"main.py\"]\n" +
+		"    env:\n" +
+		"      API_URL: !encrypted " + ciphertext + "\n" +
+		"      MODE: fast\n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	return path
+}
+
+func TestSaveConfig_ReEncrypts(t *testing.T) {
+	keyring.MockInit()
+
+	tests := []struct {
+		name   string
+		modify func(cfg *Config)
+		// encrypted reports whether API_URL should be written encrypted
+		encrypted bool
+	}{
+		{
+			name:      "unchanged",
+			modify:    func(cfg *Config) {},
+			encrypted: true,
+		},
+		{
+			name: "agent renamed",
+			modify: func(cfg *Config) {
+				cfg.Agents[0].Name = "spider"
+			},
+			encrypted: true,
+		},
+		{
+			name: "agent moved down the list",
+			modify: func(cfg *Config) {
+				cfg.Agents = append([]AgentConfig{{Name: "first", Command: "true"}}, cfg.Agents...)
+			},
+			encrypted: true,
+		},
+		{
+			name: "value moved to another key",
+			modify: func(cfg *Config) {
+				cfg.Agents[0].Env["ENDPOINT"] = cfg.Agents[0].Env["API_URL"]
+				delete(cfg.Agents[0].Env, "API_URL")
+			},
+			encrypted: true,
+		},
+		{
+			name: "value changed",
+			modify: func(cfg *Config) {
+				cfg.Agents[0].Env["API_URL"] = "https://public.example"
+			},
+			encrypted: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeEncryptedConfig(t)
+			cfg, err := LoadConfig(path)
+			if err != nil {
+				t.Fatalf("LoadConfig failed: %v", err)
+			}
+			if got := cfg.Agents[0].Env["API_URL"]; got != "https://secret.example" {
+				t.Fatalf("Expected decrypted API_URL, got %q", got)
+			}
+
+			tt.modify(cfg)
+			if err := SaveConfig(path, cfg); err != nil {
+				t.Fatalf("SaveConfig failed: %v", err)
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("Failed to read config: %v", err)
+			}
+			if strings.Contains(string(data), "secret.example") {
+				t.Errorf("Expected no plaintext secret in saved config:\n%s", data)
+			}
+			if got := strings.Contains(string(data), EncryptedTag); got != tt.encrypted {
+				t.Errorf("Expected encrypted=%v in saved config:\n%s", tt.encrypted, data)
+			}
+			if !strings.Contains(string(data), "MODE: fast") {
+				t.Errorf("Expected unencrypted values to stay in the clear:\n%s", data)
+			}
+
+			reloaded, err := LoadConfig(path)
+			if err != nil {
+				t.Fatalf("LoadConfig after save failed: %v", err)
+			}
+			if len(reloaded.Agents) != len(cfg.Agents) {
+				t.Fatalf("Expected %d agents after reload, got %d", len(cfg.Agents), len(reloaded.Agents))
+			}
+		})
+	}
+}
+
+func TestSaveConfig_EmptyFile(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "empty", data: ""},
+		{name: "comments only", data: "# agents are added by op agent add\n"},
+		{name: "missing", data: "-"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "agents.yaml")
+			if tt.data != "-" {
+				if err := os.WriteFile(path, []byte(tt.data), 0644); err != nil {
+					t.Fatalf("Failed to write config: %v", err)
+				}
+			}
+
+			cfg := &Config{Agents: []AgentConfig{{Name: "crawler", Command: "python"}}}
+			if err := SaveConfig(path, cfg); err != nil {
+				t.Fatalf("SaveConfig failed: %v", err)
+			}
+
+			reloaded, err := LoadConfig(path)
+			if err != nil {
+				t.Fatalf("LoadConfig failed: %v", err)
+			}
+			if len(reloaded.Agents) != 1 || reloaded.Agents[0].Name != "crawler" {
+				t.Errorf("Expected the crawler agent after reload, got %+v", reloaded.Agents)
+			}
+		})
+	}
+}
+
+func TestDecryptConfig_Rejects(t *testing.T) {
+	keyring.MockInit()
+	if _, err := credentials.EncryptValue("create the key"); err != nil {
+		t.Fatalf("EncryptValue failed: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{name: "not base64", value: "not-base64!"},
+		{name: "too short", value: "AAAA"},
+		{name: "wrong key", value: "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var doc yaml.Node
+			data := "agents:\n  - name: crawler\n    env:\n      API_URL: !encrypted " + tt.value + "\n"
+			if err := yaml.Unmarshal([]byte(data), &doc); err != nil {
+				t.Fatalf("Failed to parse config: %v", err)
+			}
+			if err := decryptConfig(&doc, map[string]encryptedValue{}); err == nil {
+				t.Errorf("Expected an error for %s", tt.name)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package agent
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"opperator/internal/credentials"
+)
+
+// Environment returns the KEY=value variables the daemon adds to its own
+// environment when it starts the agent: its env entries, its secrets and
+// the variables the SDK reads. Secrets are read from the keyring.
+func (c AgentConfig) Environment() ([]string, error) {
+	vars := make(map[string]string, len(c.Env)+len(c.Secrets)+3)
+	for key, value := range c.Env {
+		vars[key] = value
+	}
+	for key, name := range c.Secrets {
+		name = strings.TrimSpace(name)
+		value, err := credentials.GetSecret(name)
+		if err != nil {
+			return nil, fmt.Errorf("secret %s of agent %s: %w (run: op secret create %s)", key, c.Name, err, name)
+		}
+		vars[key] = value
+	}
+	// The SDK sends it with get_secret so the daemon knows whom to tell
+	// when a secret is rotated
+	vars["OPPERATOR_AGENT_NAME"] = c.Name
+	if keyName := strings.TrimSpace(c.OpperAPIKey); keyName != "" {
+		apiKey, err := credentials.GetSecret(keyName)
+		if err != nil {
+			return nil, fmt.Errorf("opper_api_key of agent %s: %w (run: op secret create %s)", c.Name, err, keyName)
+		}
+		// The SDK's get_secret("OPPER_API_KEY") reads the secret named by
+		// OPPERATOR_OPPER_API_KEY_SECRET instead.
+		vars[credentials.OpperAPIKeyName] = apiKey
+		vars["OPPERATOR_OPPER_API_KEY_SECRET"] = keyName
+	}
+
+	keys := make([]string, 0, len(vars))
+	for key := range vars {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	env := make([]string, 0, len(keys))
+	for _, key := range keys {
+		env = append(env, key+"="+vars[key])
+	}
+	return env, nil
+}
+
+// UsesSecret reports whether the agent is given the secret name through
+// its secrets or opper_api_key.
+func (c AgentConfig) UsesSecret(name string) bool {
+	if strings.TrimSpace(c.OpperAPIKey) == name {
+		return true
+	}
+	for _, secret := range c.Secrets {
+		if strings.TrimSpace(secret) == name {
+			return true
+		}
+	}
+	return false
+}
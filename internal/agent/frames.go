@@ -0,0 +1,52 @@
+package agent
+
+import (
+	"encoding/json"
+	"time"
+
+	"opperator/internal/protocol"
+	"opperator/pkg/prototrace"
+)
+
+// maxProtocolFrames is how many recent protocol messages are kept per agent.
+const maxProtocolFrames = 500
+
+// ProtocolFrame is one JSON message exchanged between the daemon and an
+// agent process, with stored secrets redacted.
+type ProtocolFrame = prototrace.Frame
+
+// recordFrame keeps a message seen by the protocol's frame tap.
+func (a *Agent) recordFrame(outbound bool, msgType protocol.MessageType, raw string) {
+	frame := ProtocolFrame{
+		Time:      time.Now(),
+		Direction: prototrace.FromAgent,
+		Type:      string(msgType),
+		Message:   json.RawMessage(raw),
+	}
+	if outbound {
+		frame.Direction = prototrace.ToAgent
+	}
+
+	a.framesMu.Lock()
+	defer a.framesMu.Unlock()
+	a.frameSeq++
+	frame.Seq = a.frameSeq
+	a.frames = append(a.frames, frame)
+	if len(a.frames) > maxProtocolFrames {
+		a.frames = a.frames[len(a.frames)-maxProtocolFrames:]
+	}
+}
+
+// ProtocolFrames returns the kept protocol messages numbered after seq,
+// oldest first. Numbering carries on across restarts of the agent.
+func (a *Agent) ProtocolFrames(after uint64) []ProtocolFrame {
+	a.framesMu.Lock()
+	defer a.framesMu.Unlock()
+	var frames []ProtocolFrame
+	for _, frame := range a.frames {
+		if frame.Seq > after {
+			frames = append(frames, frame)
+		}
+	}
+	return frames
+}
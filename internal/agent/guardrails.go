@@ -0,0 +1,301 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Guardrail actions taken when a check is violated.
+const (
+	GuardrailBlock   = "block"
+	GuardrailConfirm = "confirm"
+)
+
+// validatorTimeout bounds one call of an agent's validator command.
+const validatorTimeout = 30 * time.Second
+
+// Guardrails are checks run over a managed agent's model output and over
+// the arguments of commands the model calls, before they take effect.
+type Guardrails struct {
+	// Deny lists regular expressions that must not match the model's text
+	// or a command's arguments (as JSON).
+	Deny []string `yaml:"deny,omitempty"`
+	// Schemas maps command names to JSON schemas their arguments must
+	// satisfy.
+	Schemas map[string]map[string]any `yaml:"schemas,omitempty"`
+	// Validator names a command of the agent that judges every check. It
+	// receives kind ("tool_call" or "output"), command, arguments and text,
+	// and returns {"allow": bool, "confirm": bool, "reason": string}.
+	Validator string `yaml:"validator,omitempty"`
+	// Action is what a violation of Deny or Schemas leads to: block
+	// (default) or confirm, which lets the user allow it.
+	Action string `yaml:"action,omitempty"`
+}
+
+// GuardrailCheck is one thing to check: a command call, or the model's text
+// when Command is empty.
+type GuardrailCheck struct {
+	Command   string         `json:"command,omitempty"`
+	Arguments map[string]any `json:"arguments,omitempty"`
+	Text      string         `json:"text,omitempty"`
+}
+
+// GuardrailVerdict is the outcome of a check. A disallowed check with
+// Confirm set may go ahead once the user agrees.
+type GuardrailVerdict struct {
+	Allowed bool   `json:"allowed"`
+	Confirm bool   `json:"confirm,omitempty"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+var guardrailAllowed = GuardrailVerdict{Allowed: true}
+
+// CheckGuardrails runs the agent's guardrails over check. Agents without
+// guardrails allow everything.
+func (m *Manager) CheckGuardrails(ctx context.Context, name string, check GuardrailCheck) (GuardrailVerdict, error) {
+	ag, err := m.GetAgent(name)
+	if err != nil {
+		return GuardrailVerdict{}, err
+	}
+	g := ag.Config.Guardrails
+	if g == nil {
+		return guardrailAllowed, nil
+	}
+
+	if reason, err := g.violation(check); err != nil {
+		return GuardrailVerdict{}, fmt.Errorf("agent %s guardrails: %w", name, err)
+	} else if reason != "" {
+		return GuardrailVerdict{Confirm: g.Action == GuardrailConfirm, Reason: reason}, nil
+	}
+
+	if g.Validator == "" {
+		return guardrailAllowed, nil
+	}
+	kind := "tool_call"
+	if check.Command == "" {
+		kind = "output"
+	}
+	resp, err := m.InvokeCommand(ctx, name, g.Validator, map[string]any{
+		"kind":      kind,
+		"command":   check.Command,
+		"arguments": check.Arguments,
+		"text":      check.Text,
+	}, "", validatorTimeout)
+	// A validator that cannot answer blocks rather than waving things
+	// through.
+	if err != nil {
+		return GuardrailVerdict{Reason: fmt.Sprintf("validator %s failed: %v", g.Validator, err)}, nil
+	}
+	if !resp.Success {
+		return GuardrailVerdict{Reason: fmt.Sprintf("validator %s failed: %s", g.Validator, resp.Error)}, nil
+	}
+	var verdict struct {
+		Allow   bool   `json:"allow"`
+		Confirm bool   `json:"confirm"`
+		Reason  string `json:"reason"`
+	}
+	data, _ := json.Marshal(resp.Result)
+	if err := json.Unmarshal(data, &verdict); err != nil {
+		return GuardrailVerdict{Reason: fmt.Sprintf("validator %s returned an invalid verdict", g.Validator)}, nil
+	}
+	if verdict.Allow {
+		return guardrailAllowed, nil
+	}
+	reason := strings.TrimSpace(verdict.Reason)
+	if reason == "" {
+		reason = "rejected by validator " + g.Validator
+	}
+	return GuardrailVerdict{Confirm: verdict.Confirm, Reason: reason}, nil
+}
+
+// violation returns why check breaks the deny list or a schema, or "".
+func (g *Guardrails) violation(check GuardrailCheck) (string, error) {
+	subject := check.Text
+	if check.Command != "" {
+		data, _ := json.Marshal(check.Arguments)
+		subject = string(data)
+	}
+	for _, pattern := range g.Deny {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return "", fmt.Errorf("deny pattern %q: %w", pattern, err)
+		}
+		if re.MatchString(subject) {
+			return fmt.Sprintf("matches denied pattern %q", pattern), nil
+		}
+	}
+	if check.Command == "" {
+		return "", nil
+	}
+	schema, ok := g.Schemas[check.Command]
+	if !ok {
+		return "", nil
+	}
+	// Validate the JSON form so numbers compare the same however the
+	// arguments were built.
+	var args any = map[string]any{}
+	if check.Arguments != nil {
+		data, _ := json.Marshal(check.Arguments)
+		if err := json.Unmarshal(data, &args); err != nil {
+			return "", err
+		}
+	}
+	if err := validateSchema(schema, args, "arguments"); err != nil {
+		return err.Error(), nil
+	}
+	return "", nil
+}
+
+// validateSchema checks value against the common subset of JSON Schema:
+// type, enum, const, properties, required, additionalProperties, items,
+// minLength, maxLength, pattern, minimum, maximum, minItems and maxItems.
+func validateSchema(schema map[string]any, value any, path string) error {
+	if t, ok := schema["type"].(string); ok && !hasType(value, t) {
+		return fmt.Errorf("%s must be of type %s", path, t)
+	}
+	if enum, ok := schema["enum"].([]any); ok {
+		found := false
+		for _, v := range enum {
+			if equalJSON(v, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s must be one of %v", path, enum)
+		}
+	}
+	if c, ok := schema["const"]; ok && !equalJSON(c, value) {
+		return fmt.Errorf("%s must be %v", path, c)
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		props, _ := schema["properties"].(map[string]any)
+		for _, name := range stringList(schema["required"]) {
+			if _, ok := v[name]; !ok {
+				return fmt.Errorf("%s.%s is required", path, name)
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			sub, ok := props[name].(map[string]any)
+			if !ok {
+				if extra, ok := schema["additionalProperties"].(bool); ok && !extra {
+					return fmt.Errorf("%s.%s is not allowed", path, name)
+				}
+				continue
+			}
+			if err := validateSchema(sub, v[name], path+"."+name); err != nil {
+				return err
+			}
+		}
+	case []any:
+		if n, ok := number(schema["minItems"]); ok && float64(len(v)) < n {
+			return fmt.Errorf("%s must have at least %v items", path, n)
+		}
+		if n, ok := number(schema["maxItems"]); ok && float64(len(v)) > n {
+			return fmt.Errorf("%s must have at most %v items", path, n)
+		}
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range v {
+				if err := validateSchema(items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	case string:
+		length := float64(len([]rune(v)))
+		if n, ok := number(schema["minLength"]); ok && length < n {
+			return fmt.Errorf("%s must be at least %v characters", path, n)
+		}
+		if n, ok := number(schema["maxLength"]); ok && length > n {
+			return fmt.Errorf("%s must be at most %v characters", path, n)
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return fmt.Errorf("%s: invalid pattern %q", path, pattern)
+			}
+			if !re.MatchString(v) {
+				return fmt.Errorf("%s must match %q", path, pattern)
+			}
+		}
+	case float64:
+		if n, ok := number(schema["minimum"]); ok && v < n {
+			return fmt.Errorf("%s must be at least %v", path, n)
+		}
+		if n, ok := number(schema["maximum"]); ok && v > n {
+			return fmt.Errorf("%s must be at most %v", path, n)
+		}
+	}
+	return nil
+}
+
+func hasType(value any, t string) bool {
+	switch t {
+	case "object":
+		_, ok := value.(map[string]any)
+		return ok
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "integer":
+		f, ok := value.(float64)
+		return ok && f == math.Trunc(f)
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	}
+	return true
+}
+
+// number reads a schema keyword, which YAML decodes as int or float64.
+func number(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+func stringList(v any) []string {
+	if list, ok := v.([]string); ok {
+		return list
+	}
+	items, _ := v.([]any)
+	out := make([]string, 0, len(items))
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// equalJSON compares a schema value from YAML with a decoded JSON value.
+func equalJSON(a, b any) bool {
+	x, _ := json.Marshal(a)
+	y, _ := json.Marshal(b)
+	return string(x) == string(y)
+}
@@ -0,0 +1,154 @@
+package agent
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// InstructionsFile is read from an agent's process root and appended to its
+// system prompt, so the prompt can be tuned without editing agents.yaml or
+// the agent's code.
+const InstructionsFile = "OPPERATOR.md"
+
+// maxInstructionsSize bounds how much of the instructions file is read.
+const maxInstructionsSize = 64 << 10
+
+// readInstructions returns the instructions file in dir, or "" when there
+// is none.
+func readInstructions(dir string) string {
+	f, err := os.Open(filepath.Join(dir, InstructionsFile))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Failed to read %s in %s: %v", InstructionsFile, dir, err)
+		}
+		return ""
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, maxInstructionsSize))
+	if err != nil {
+		log.Printf("Failed to read %s in %s: %v", InstructionsFile, dir, err)
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// withInstructions appends the instructions file to prompt.
+func withInstructions(prompt, instructions string) string {
+	if instructions == "" {
+		return prompt
+	}
+	if prompt == "" {
+		return instructions
+	}
+	return prompt + "\n\n" + instructions
+}
+
+// reloadInstructions re-reads the agent's instructions file and reports
+// whether it changed.
+func (a *Agent) reloadInstructions() bool {
+	dir, err := a.workingDir()
+	if err != nil {
+		return false
+	}
+	instructions := readInstructions(dir)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if instructions == a.instructions {
+		return false
+	}
+	a.instructions = instructions
+	return true
+}
+
+// watchInstructions reloads an agent's instructions file when it changes in
+// its process root and reports the new system prompt. Process roots are
+// watched again after every config reload.
+func (m *Manager) watchInstructions() {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Printf("Error creating instructions watcher: %v", err)
+		return
+	}
+	defer watcher.Close()
+
+	watched := make(map[string]bool)
+	watchDirs := func() {
+		for _, dir := range m.processRoots() {
+			if watched[dir] {
+				continue
+			}
+			if err := watcher.Add(dir); err == nil {
+				watched[dir] = true
+			}
+		}
+	}
+	watchDirs()
+
+	for {
+		select {
+		case <-m.stopWatching:
+			return
+		case <-m.rootsChanged:
+			watchDirs()
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if filepath.Base(event.Name) != InstructionsFile {
+				continue
+			}
+			m.reloadInstructions(filepath.Dir(event.Name))
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("Instructions watcher error: %v", err)
+		}
+	}
+}
+
+// processRoots returns the process root of every agent.
+func (m *Manager) processRoots() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var dirs []string
+	for _, agent := range m.agents {
+		if dir, err := agent.workingDir(); err == nil {
+			dirs = append(dirs, filepath.Clean(dir))
+		}
+	}
+	return dirs
+}
+
+// reloadInstructions reloads the instructions of the agents running in dir.
+func (m *Manager) reloadInstructions(dir string) {
+	m.mu.RLock()
+	var agents []*Agent
+	for _, agent := range m.agents {
+		if root, err := agent.workingDir(); err == nil && filepath.Clean(root) == filepath.Clean(dir) {
+			agents = append(agents, agent)
+		}
+	}
+	m.mu.RUnlock()
+
+	for _, agent := range agents {
+		if agent.reloadInstructions() {
+			log.Printf("Reloaded %s for agent %s", InstructionsFile, agent.Config.Name)
+			agent.addLog("[instructions] " + InstructionsFile + " reloaded")
+			agent.notifyMetadataChange()
+		}
+	}
+}
+
+// notifyRootsChanged asks watchInstructions to watch new process roots.
+func (m *Manager) notifyRootsChanged() {
+	select {
+	case m.rootsChanged <- struct{}{}:
+	default:
+	}
+}
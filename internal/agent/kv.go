@@ -0,0 +1,46 @@
+package agent
+
+import (
+	"log"
+
+	"opperator/internal/protocol"
+)
+
+// KVHandler serves a key-value request of agentName, the agent whose
+// protocol pipe the request arrived on.
+type KVHandler func(agentName string, req protocol.KVRequestMessage) protocol.KVResponseMessage
+
+// SetKVHandler sets what serves the key-value requests of agents.
+func (m *Manager) SetKVHandler(handler KVHandler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onKV = handler
+}
+
+func (m *Manager) handleKV(agentName string, req protocol.KVRequestMessage) protocol.KVResponseMessage {
+	m.mu.RLock()
+	handler := m.onKV
+	m.mu.RUnlock()
+
+	if handler == nil {
+		return protocol.KVResponseMessage{ID: req.ID, Error: "key-value state is not available"}
+	}
+	return handler(agentName, req)
+}
+
+// handleKVRequest answers key-value requests on p off the reader goroutine,
+// so a slow store does not hold up the agent's other messages.
+func (a *Agent) handleKVRequest(p *protocol.ProcessProtocol) func(protocol.KVRequestMessage) {
+	return func(req protocol.KVRequestMessage) {
+		go func() {
+			resp := protocol.KVResponseMessage{ID: req.ID, Error: "key-value state is not available"}
+			if a.kvHandler != nil {
+				resp = a.kvHandler(a.Config.Name, req)
+			}
+			resp.ID = req.ID
+			if err := p.SendKVResponse(resp); err != nil {
+				log.Printf("[KV] Failed to answer %s: %v", a.Config.Name, err)
+			}
+		}()
+	}
+}
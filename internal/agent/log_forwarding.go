@@ -0,0 +1,65 @@
+package agent
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"sync/atomic"
+
+	"opperator/internal/protocol"
+)
+
+var forwardLogs atomic.Bool
+
+// SetLogForwarding controls whether log messages emitted by agents are also
+// written to the daemon log as structured records tagged with the agent name.
+func SetLogForwarding(enabled bool) {
+	forwardLogs.Store(enabled)
+}
+
+var logSink atomic.Pointer[func(agentName, line string)]
+
+// SetLogSink registers a function that receives every line added to an
+// agent's log, such as a log shipper. A nil sink removes it.
+func SetLogSink(sink func(agentName, line string)) {
+	if sink == nil {
+		logSink.Store(nil)
+		return
+	}
+	logSink.Store(&sink)
+}
+
+func (a *Agent) forwardLog(level protocol.LogLevel, message string, fields map[string]interface{}) {
+	if !forwardLogs.Load() {
+		return
+	}
+
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	args := make([]any, 0, 4+len(fields)*2)
+	args = append(args, "component", "agent", "agent", a.Config.Name)
+	if len(keys) > 0 {
+		attrs := make([]any, 0, len(keys)*2)
+		for _, k := range keys {
+			attrs = append(attrs, k, fields[k])
+		}
+		args = append(args, slog.Group("fields", attrs...))
+	}
+	slog.Log(context.Background(), agentLogLevel(level), message, args...)
+}
+
+func agentLogLevel(level protocol.LogLevel) slog.Level {
+	switch level {
+	case protocol.LogDebug:
+		return slog.LevelDebug
+	case protocol.LogWarning:
+		return slog.LevelWarn
+	case protocol.LogError, protocol.LogFatal:
+		return slog.LevelError
+	}
+	return slog.LevelInfo
+}
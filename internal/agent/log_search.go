@@ -0,0 +1,151 @@
+package agent
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// DefaultLogSearchLimit is how many matches a log search returns when no
+// limit is given.
+const DefaultLogSearchLimit = 500
+
+// LogSearch selects the log lines matching a regular expression, optionally
+// of one agent and no older than Since.
+type LogSearch struct {
+	Pattern    string    `json:"pattern"`
+	IgnoreCase bool      `json:"ignore_case,omitempty"`
+	Agent      string    `json:"agent,omitempty"`
+	Since      time.Time `json:"since,omitempty"`
+	// Context is how many lines before and after each match to include
+	Context int `json:"context,omitempty"`
+	Limit   int `json:"limit,omitempty"`
+}
+
+// LogMatch is a log line that matched a search, with the lines of the same
+// agent around it.
+type LogMatch struct {
+	Agent  string    `json:"agent"`
+	Time   time.Time `json:"time"`
+	Line   string    `json:"line"`
+	Before []string  `json:"before,omitempty"`
+	After  []string  `json:"after,omitempty"`
+}
+
+// LogSearchResult is the matches of a search, oldest first per agent.
+// Truncated is set when there were more than the limit.
+type LogSearchResult struct {
+	Matches   []LogMatch `json:"matches"`
+	Truncated bool       `json:"truncated,omitempty"`
+}
+
+// Compile returns the search's regular expression.
+func (q LogSearch) Compile() (*regexp.Regexp, error) {
+	pattern := q.Pattern
+	if q.IgnoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+	return re, nil
+}
+
+// SearchLogs scans the logs kept in the database for lines matching q.
+func (p *AgentPersistence) SearchLogs(q LogSearch) (*LogSearchResult, error) {
+	re, err := q.Compile()
+	if err != nil {
+		return nil, err
+	}
+	result := &LogSearchResult{Matches: []LogMatch{}}
+	if p.db == nil {
+		return result, nil
+	}
+	limit := q.Limit
+	if limit <= 0 {
+		limit = DefaultLogSearchLimit
+	}
+	context := max(q.Context, 0)
+	var since int64
+	if !q.Since.IsZero() {
+		since = q.Since.Unix()
+	}
+
+	rows, err := p.db.Query(`
+		SELECT agent_name, log_line, created_at
+		FROM agent_logs
+		WHERE (? = '' OR agent_name = ?) AND created_at >= ?
+		ORDER BY agent_name, id`, q.Agent, q.Agent, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query logs: %w", err)
+	}
+	defer rows.Close()
+
+	var (
+		current string
+		before  []string
+		// open are the matches still collecting lines after them
+		open []int
+	)
+	for rows.Next() {
+		var name, line string
+		var created int64
+		if err := rows.Scan(&name, &line, &created); err != nil {
+			return nil, err
+		}
+		if name != current {
+			current, before, open = name, nil, nil
+			if result.Truncated {
+				break
+			}
+		}
+		still := open[:0]
+		for _, i := range open {
+			result.Matches[i].After = append(result.Matches[i].After, line)
+			if len(result.Matches[i].After) < context {
+				still = append(still, i)
+			}
+		}
+		open = still
+
+		if !result.Truncated && re.MatchString(line) {
+			if len(result.Matches) == limit {
+				result.Truncated = true
+			} else {
+				result.Matches = append(result.Matches, LogMatch{
+					Agent:  name,
+					Time:   time.Unix(created, 0),
+					Line:   line,
+					Before: append([]string(nil), before...),
+				})
+				if context > 0 {
+					open = append(open, len(result.Matches)-1)
+				}
+			}
+		}
+		// Past the limit, only the lines after the last matches are needed
+		if result.Truncated && len(open) == 0 {
+			break
+		}
+		if context > 0 {
+			before = append(before, line)
+			if len(before) > context {
+				before = before[1:]
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// SearchLogs scans the logs of every agent, including removed ones whose
+// logs are still kept, for lines matching q.
+func (m *Manager) SearchLogs(q LogSearch) (*LogSearchResult, error) {
+	if m.persistence == nil {
+		return &LogSearchResult{Matches: []LogMatch{}}, nil
+	}
+	return m.persistence.SearchLogs(q)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -13,12 +14,25 @@ import (
 	"time"
 
 	"github.com/fsnotify/fsnotify"
+	"opperator/config"
 	"opperator/internal/protocol"
 	"opperator/pkg/db"
 	"opperator/pkg/migration"
+	"opperator/pkg/tracing"
 	"tui/components/sidebar"
 )
 
+// Sentinel errors wrapped by agent operations so callers can classify failures.
+var (
+	ErrNotFound       = errors.New("not found")
+	ErrAlreadyRunning = errors.New("already running")
+	ErrNotRunning     = errors.New("not running")
+	ErrPaused         = errors.New("paused")
+	// ErrWorkingDirNotAllowed is returned for a command working directory
+	// outside the agent's allowed directories.
+	ErrWorkingDirNotAllowed = errors.New("working directory not allowed")
+)
+
 type StateChangeCallback func(agentName string, changeType string, data interface{})
 
 type Manager struct {
@@ -27,16 +41,18 @@ type Manager struct {
 	configPath    string
 	mu            sync.RWMutex
 	stopWatching  chan struct{}
+	rootsChanged  chan struct{}
 	lastModTime   time.Time
 	persistence   *AgentPersistence
 	sectionStore  *SectionStore
 	onStateChange StateChangeCallback
+	onKV          KVHandler
 }
 
 func New(configPath string) (*Manager, error) {
-	config, err := LoadConfig(configPath)
+	cfg, err := LoadConfig(configPath)
 	if err != nil {
-		config = &Config{Agents: []AgentConfig{}}
+		cfg = &Config{Agents: []AgentConfig{}}
 	}
 
 	var modTime time.Time
@@ -44,9 +60,14 @@ func New(configPath string) (*Manager, error) {
 		modTime = stat.ModTime()
 	}
 
-	configDir := filepath.Dir(configPath)
-
-	dbPath := filepath.Join(configDir, "opperator.db")
+	dbPath, err := config.GetDatabasePath()
+	if err != nil {
+		return nil, err
+	}
+	logsDir, err := config.GetLogsDir()
+	if err != nil {
+		return nil, err
+	}
 	if err := db.Initialize(dbPath); err != nil {
 		return nil, fmt.Errorf("failed to initialize database: %w", err)
 	}
@@ -62,7 +83,7 @@ func New(configPath string) (*Manager, error) {
 		return nil, fmt.Errorf("failed to run migrations: %w", err)
 	}
 
-	persistence := NewAgentPersistence(configDir, writeDB)
+	persistence := NewAgentPersistence(filepath.Dir(dbPath), logsDir, writeDB)
 
 	// Initialize section store for persisting custom sections using shared DB
 	sectionStore, err := NewSectionStore(writeDB, SectionStoreConfig{
@@ -74,31 +95,38 @@ func New(configPath string) (*Manager, error) {
 
 	m := &Manager{
 		agents:       make(map[string]*Agent),
-		config:       config,
+		config:       cfg,
 		configPath:   configPath,
 		stopWatching: make(chan struct{}),
+		rootsChanged: make(chan struct{}, 1),
 		lastModTime:  modTime,
 		persistence:  persistence,
 		sectionStore: sectionStore,
 	}
+	m.CleanupOrphans()
 
-	for _, agentConfig := range config.Agents {
+	for _, agentConfig := range cfg.Agents {
 		if agentConfig.MaxRestarts == 0 && agentConfig.AutoRestart {
 			agentConfig.MaxRestarts = 3
 		}
 		agent := NewAgent(agentConfig, persistence, sectionStore)
 
 		agent.stateChangeNotifier = m.notifyStateChange
+		agent.kvHandler = m.handleKV
 
 		// Restore persistent data
 		persistentData := persistence.GetAgentData(agentConfig.Name)
 		agent.RestartCount = persistentData.RestartCount
+		agent.lastExit = persistentData.LastExit
+		agent.paused = persistentData.Paused
+		agent.recordPromptVersion(PromptSourceConfig)
 
 		m.agents[agentConfig.Name] = agent
 	}
 
 	// Start config file watcher goroutine
 	go m.watchConfigFile()
+	go m.watchInstructions()
 
 	return m, nil
 }
@@ -125,7 +153,7 @@ func (m *Manager) GetAgent(name string) (*Agent, error) {
 
 	agent, exists := m.agents[name]
 	if !exists {
-		return nil, fmt.Errorf("agent %s not found", name)
+		return nil, fmt.Errorf("agent %s %w", name, ErrNotFound)
 	}
 
 	return agent, nil
@@ -169,12 +197,18 @@ func (m *Manager) GetAllAgentSections() map[string][]sidebar.CustomSection {
 	return result
 }
 
+// StartAgent starts the agent after starting the agents it depends on.
 func (m *Manager) StartAgent(name string) error {
 	agent, err := m.GetAgent(name)
 	if err != nil {
 		return err
 	}
+	if err := m.startDependencies(name); err != nil {
+		return err
+	}
 
+	// A manual start gives a crashlooping agent a fresh window
+	agent.ResetCrashHistory()
 	return agent.Start()
 }
 
@@ -187,17 +221,31 @@ func (m *Manager) StopAgent(name string) error {
 	return agent.Stop()
 }
 
+// StopAgentWithOptions stops the named agent as opts asks and reports how its
+// process ended.
+func (m *Manager) StopAgentWithOptions(name string, opts StopOptions) (StopMethod, error) {
+	agent, err := m.GetAgent(name)
+	if err != nil {
+		return "", err
+	}
+
+	return agent.StopWithOptions(opts)
+}
+
 func (m *Manager) RestartAgent(name string) error {
 	agent, err := m.GetAgent(name)
 	if err != nil {
 		return err
 	}
 
+	agent.ResetCrashHistory()
 	return agent.Restart()
 }
 
 // InvokeCommand sends a command to the specified managed agent and waits for a response.
-func (m *Manager) InvokeCommand(name, command string, args map[string]interface{}, workingDir string, timeout time.Duration) (*protocol.ResponseMessage, error) {
+// InvokeCommand sends a command to the agent and waits for its response. The
+// command is cancelled when ctx is done or timeout elapses.
+func (m *Manager) InvokeCommand(ctx context.Context, name, command string, args map[string]interface{}, workingDir string, timeout time.Duration) (*protocol.ResponseMessage, error) {
 	if timeout <= 0 {
 		timeout = 5 * time.Second
 	}
@@ -206,6 +254,13 @@ func (m *Manager) InvokeCommand(name, command string, args map[string]interface{
 	if err != nil {
 		return nil, err
 	}
+	if err := validateCommandArgs(agent, command, args); err != nil {
+		return nil, err
+	}
+	commandDir, err := commandWorkingDir(ctx, agent, command, workingDir)
+	if err != nil {
+		return nil, err
+	}
 
 	// Check and notify invocation directory changes
 	if err := agent.CheckAndNotifyInvocationDirChange(workingDir); err != nil {
@@ -213,14 +268,18 @@ func (m *Manager) InvokeCommand(name, command string, args map[string]interface{
 		log.Printf("Failed to notify invocation directory change for agent %s: %v", name, err)
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	return agent.SendCommand(ctx, command, args, strings.TrimSpace(workingDir))
+	ctx, span := tracing.Start(ctx, "agent.command", "agent.name", name, "agent.command", command)
+	resp, err := agent.SendCommand(ctx, command, args, commandDir)
+	endCommandSpan(span, resp, err)
+	return resp, err
 }
 
 // InvokeCommandAsync sends a command to the agent and forwards progress events via the provided callback.
-func (m *Manager) InvokeCommandAsync(name, command string, args map[string]interface{}, workingDir string, timeout time.Duration, progress func(protocol.CommandProgressMessage)) (*protocol.ResponseMessage, error) {
+// The command is cancelled when ctx is done or the optional timeout elapses.
+func (m *Manager) InvokeCommandAsync(ctx context.Context, name, command string, args map[string]interface{}, workingDir string, timeout time.Duration, progress func(protocol.CommandProgressMessage)) (*protocol.ResponseMessage, error) {
 	if timeout <= 0 {
 		timeout = 0
 	}
@@ -229,6 +288,13 @@ func (m *Manager) InvokeCommandAsync(name, command string, args map[string]inter
 	if err != nil {
 		return nil, err
 	}
+	if err := validateCommandArgs(agent, command, args); err != nil {
+		return nil, err
+	}
+	commandDir, err := commandWorkingDir(ctx, agent, command, workingDir)
+	if err != nil {
+		return nil, err
+	}
 
 	// Check and notify invocation directory changes
 	if err := agent.CheckAndNotifyInvocationDirChange(workingDir); err != nil {
@@ -236,20 +302,32 @@ func (m *Manager) InvokeCommandAsync(name, command string, args map[string]inter
 		log.Printf("Failed to notify invocation directory change for agent %s: %v", name, err)
 	}
 
-	var ctx context.Context
 	var cancel context.CancelFunc
 	if timeout > 0 {
-		ctx, cancel = context.WithTimeout(context.Background(), timeout)
+		ctx, cancel = context.WithTimeout(ctx, timeout)
 	} else {
-		ctx, cancel = context.WithCancel(context.Background())
+		ctx, cancel = context.WithCancel(ctx)
 	}
 	defer cancel()
 
-	return agent.SendCommandWithProgress(ctx, command, args, strings.TrimSpace(workingDir), progress)
+	ctx, span := tracing.Start(ctx, "agent.command", "agent.name", name, "agent.command", command, "agent.async", true)
+	resp, err := agent.SendCommandWithProgress(ctx, command, args, commandDir, progress)
+	endCommandSpan(span, resp, err)
+	return resp, err
+}
+
+// endCommandSpan finishes an agent command span, marking it failed when the
+// command could not be delivered or the agent reported an error.
+func endCommandSpan(span *tracing.Span, resp *protocol.ResponseMessage, err error) {
+	if err == nil && resp != nil && !resp.Success {
+		err = fmt.Errorf("%s", resp.Error)
+	}
+	span.RecordError(err)
+	span.End()
 }
 
 // ListCommands requests the set of registered command names from the agent.
-func (m *Manager) ListCommands(name string, timeout time.Duration) ([]protocol.CommandDescriptor, error) {
+func (m *Manager) ListCommands(ctx context.Context, name string, timeout time.Duration) ([]protocol.CommandDescriptor, error) {
 	if timeout <= 0 {
 		timeout = 5 * time.Second
 	}
@@ -266,7 +344,7 @@ func (m *Manager) ListCommands(name string, timeout time.Duration) ([]protocol.C
 		return copyCmds, nil
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
 	resp, err := agent.SendCommand(ctx, "__list_commands", nil, "")
@@ -386,12 +464,17 @@ func (m *Manager) AddAgent(config AgentConfig) {
 
 	agent := NewAgent(config, m.persistence, m.sectionStore)
 	agent.stateChangeNotifier = m.notifyStateChange
+	agent.kvHandler = m.handleKV
 	// Restore persistent data
 	if m.persistence != nil {
 		persistentData := m.persistence.GetAgentData(config.Name)
 		agent.RestartCount = persistentData.RestartCount
+		agent.lastExit = persistentData.LastExit
+		agent.paused = persistentData.Paused
 	}
 	m.agents[config.Name] = agent
+	agent.recordPromptVersion(PromptSourceConfig)
+	m.notifyRootsChanged()
 }
 
 func (m *Manager) RemoveAgent(name string) error {
@@ -400,7 +483,7 @@ func (m *Manager) RemoveAgent(name string) error {
 
 	agent, exists := m.agents[name]
 	if !exists {
-		return fmt.Errorf("agent %s not found", name)
+		return fmt.Errorf("agent %s %w", name, ErrNotFound)
 	}
 
 	if agent.GetStatus() == StatusRunning {
@@ -592,12 +675,16 @@ func (m *Manager) ReloadConfig() error {
 				if configChanged {
 					newAgentInstance := NewAgent(newAgent, m.persistence, m.sectionStore)
 					newAgentInstance.stateChangeNotifier = m.notifyStateChange
+					newAgentInstance.kvHandler = m.handleKV
 					// Restore persistent data
 					if m.persistence != nil {
 						persistentData := m.persistence.GetAgentData(newAgent.Name)
 						newAgentInstance.RestartCount = persistentData.RestartCount
+						newAgentInstance.lastExit = persistentData.LastExit
+						newAgentInstance.paused = persistentData.Paused
 					}
 					m.agents[name] = newAgentInstance
+					newAgentInstance.recordPromptVersion(PromptSourceConfig)
 
 					// Schedule restart if it was running before (check map, not current status)
 					if wasRunningMap[name] {
@@ -615,16 +702,12 @@ func (m *Manager) ReloadConfig() error {
 						agent.systemPromptReplace = false
 						agent.color = strings.TrimSpace(newAgent.Color)
 						agent.mu.Unlock()
+						agent.recordPromptVersion(PromptSourceConfig)
 
 						// Collect metadata change to notify after lock is released
 						metadataChanges = append(metadataChanges, metadataChange{
 							agentName: name,
-							update: MetadataUpdate{
-								Description:         newAgent.Description,
-								SystemPrompt:        newAgent.SystemPrompt,
-								SystemPromptReplace: false,
-								Color:               newAgent.Color,
-							},
+							update:    agent.metadataSnapshot(),
 						})
 					}
 				}
@@ -633,12 +716,16 @@ func (m *Manager) ReloadConfig() error {
 			log.Printf("Adding new agent: %s", name)
 			agent := NewAgent(newAgent, m.persistence, m.sectionStore)
 			agent.stateChangeNotifier = m.notifyStateChange
+			agent.kvHandler = m.handleKV
 			// Restore persistent data
 			if m.persistence != nil {
 				persistentData := m.persistence.GetAgentData(newAgent.Name)
 				agent.RestartCount = persistentData.RestartCount
+				agent.lastExit = persistentData.LastExit
+				agent.paused = persistentData.Paused
 			}
 			m.agents[name] = agent
+			agent.recordPromptVersion(PromptSourceConfig)
 		}
 	}
 
@@ -656,6 +743,7 @@ func (m *Manager) ReloadConfig() error {
 	for _, change := range metadataChanges {
 		m.notifyStateChange(change.agentName, "metadata", change.update)
 	}
+	m.notifyRootsChanged()
 
 	log.Printf("Configuration reloaded successfully")
 	return nil
@@ -686,6 +774,14 @@ func agentConfigEqual(a, b AgentConfig) bool {
 		return false
 	}
 
+	if !restartPolicyEqual(a.RestartPolicy, b.RestartPolicy) {
+		return false
+	}
+
+	if a.Runtime != b.Runtime || a.OpperAPIKey != b.OpperAPIKey {
+		return false
+	}
+
 	// Compare args
 	if len(a.Args) != len(b.Args) {
 		return false
@@ -740,6 +836,14 @@ func agentConfigEqualIgnoringMetadata(a, b AgentConfig) bool {
 		return false
 	}
 
+	if !restartPolicyEqual(a.RestartPolicy, b.RestartPolicy) {
+		return false
+	}
+
+	if a.Runtime != b.Runtime || a.OpperAPIKey != b.OpperAPIKey {
+		return false
+	}
+
 	// Compare args
 	if len(a.Args) != len(b.Args) {
 		return false
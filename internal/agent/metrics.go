@@ -0,0 +1,230 @@
+package agent
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"opperator/internal/protocol"
+	"opperator/pkg/sparkline"
+	"tui/components/sidebar"
+)
+
+// metricsSectionID is the sidebar section the daemon maintains for the
+// metrics an agent reports.
+const metricsSectionID = "__metrics"
+
+const (
+	// metricBucket is the resolution metrics are kept at
+	metricBucket = time.Minute
+	// metricHistory is how many buckets the sidebar and the metrics
+	// endpoint show
+	metricHistory = 30
+	// metricRetention is how long buckets are kept
+	metricRetention = 7 * 24 * time.Hour
+	// metricsSectionDelay batches the sidebar updates of an agent reporting
+	// many metrics at once
+	metricsSectionDelay = time.Second
+	maxMetricNameLength = 64
+)
+
+// MetricSeries is one metric of an agent: its total for a counter or
+// latest value for a gauge, and its per-minute values over the last
+// metricHistory minutes, oldest first.
+type MetricSeries struct {
+	Name   string
+	Type   string
+	Value  float64
+	Points []float64
+}
+
+// RecordMetric adds a counter increment or sets a gauge in the current
+// bucket of agentName's metric.
+func (p *AgentPersistence) RecordMetric(agentName string, metric protocol.MetricMessage, at time.Time) error {
+	if p.db == nil {
+		return nil
+	}
+	bucket := at.Truncate(metricBucket).Unix()
+	update := "value = value + excluded.value"
+	if metric.Type == protocol.MetricGauge {
+		update = "value = excluded.value"
+	}
+	if _, err := p.db.Exec(`
+		INSERT INTO agent_metrics (agent_name, name, kind, bucket, value) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(agent_name, name, bucket) DO UPDATE SET kind = excluded.kind, `+update,
+		agentName, metric.Name, metric.Type, bucket, metric.Value,
+	); err != nil {
+		return fmt.Errorf("failed to record metric: %w", err)
+	}
+	if _, err := p.db.Exec(`DELETE FROM agent_metrics WHERE agent_name = ? AND name = ? AND bucket < ?`,
+		agentName, metric.Name, at.Add(-metricRetention).Unix()); err != nil {
+		log.Printf("Warning: failed to trim metric %s of %s: %v", metric.Name, agentName, err)
+	}
+	return nil
+}
+
+// GetMetrics returns the metrics agentName reported, sorted by name.
+func (p *AgentPersistence) GetMetrics(agentName string, now time.Time) ([]MetricSeries, error) {
+	if p.db == nil {
+		return nil, nil
+	}
+	rows, err := p.db.Query(`
+		SELECT name, kind, bucket, value FROM agent_metrics
+		WHERE agent_name = ?
+		ORDER BY name, bucket`, agentName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query metrics: %w", err)
+	}
+	defer rows.Close()
+
+	first := now.Truncate(metricBucket).Add(-(metricHistory - 1) * metricBucket).Unix()
+	byName := make(map[string]*MetricSeries)
+	set := make(map[string][]bool)
+	var names []string
+	for rows.Next() {
+		var name, kind string
+		var bucket int64
+		var value float64
+		if err := rows.Scan(&name, &kind, &bucket, &value); err != nil {
+			return nil, err
+		}
+		series, ok := byName[name]
+		if !ok {
+			series = &MetricSeries{Name: name, Points: make([]float64, metricHistory)}
+			byName[name] = series
+			set[name] = make([]bool, metricHistory)
+			names = append(names, name)
+		}
+		series.Type = kind
+		if kind == protocol.MetricGauge {
+			series.Value = value
+		} else {
+			series.Value += value
+		}
+		if bucket >= first {
+			if i := int((bucket - first) / int64(metricBucket/time.Second)); i < metricHistory {
+				series.Points[i] = value
+				set[name][i] = true
+			}
+		} else if kind == protocol.MetricGauge {
+			// Carried into the window until the gauge is set again
+			series.Points[0] = value
+			set[name][0] = true
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	out := make([]MetricSeries, 0, len(names))
+	for _, name := range names {
+		series := byName[name]
+		if series.Type == protocol.MetricGauge {
+			// A gauge keeps its value through minutes it was not set in
+			for i := 1; i < len(series.Points); i++ {
+				if !set[series.Name][i] {
+					series.Points[i] = series.Points[i-1]
+				}
+			}
+		}
+		out = append(out, *series)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+// Metrics returns the metrics the agent reported.
+func (a *Agent) Metrics() ([]MetricSeries, error) {
+	if a.persistence == nil {
+		return nil, nil
+	}
+	return a.persistence.GetMetrics(a.Config.Name, time.Now())
+}
+
+// recordMetric stores a metric the agent reported and schedules an update
+// of its metrics section.
+func (a *Agent) recordMetric(metric protocol.MetricMessage) {
+	metric.Name = strings.TrimSpace(metric.Name)
+	if metric.Name == "" || len(metric.Name) > maxMetricNameLength {
+		a.addLog(fmt.Sprintf("[metric] ignored metric with invalid name %q", metric.Name))
+		return
+	}
+	switch strings.ToLower(strings.TrimSpace(metric.Type)) {
+	case "", protocol.MetricCounter:
+		metric.Type = protocol.MetricCounter
+		if metric.Value == 0 {
+			metric.Value = 1
+		}
+	case protocol.MetricGauge:
+		metric.Type = protocol.MetricGauge
+	default:
+		a.addLog(fmt.Sprintf("[metric] ignored %s with unknown type %q", metric.Name, metric.Type))
+		return
+	}
+	if a.persistence == nil {
+		return
+	}
+	if err := a.persistence.RecordMetric(a.Config.Name, metric, time.Now()); err != nil {
+		a.addLog(fmt.Sprintf("[metric] %v", err))
+		return
+	}
+
+	a.mu.Lock()
+	pending := a.metricsSectionPending
+	a.metricsSectionPending = true
+	a.mu.Unlock()
+	if !pending {
+		time.AfterFunc(metricsSectionDelay, a.publishMetricsSection)
+	}
+}
+
+// publishMetricsSection renders the agent's metrics into its metrics
+// section.
+func (a *Agent) publishMetricsSection() {
+	a.mu.Lock()
+	a.metricsSectionPending = false
+	a.mu.Unlock()
+	if a.sectionStore == nil {
+		return
+	}
+	series, err := a.Metrics()
+	if err != nil || len(series) == 0 {
+		return
+	}
+	a.sectionStore.SaveSection(a.Config.Name, metricsSectionID, metricsSection(series))
+	if a.stateChangeNotifier != nil {
+		a.stateChangeNotifier(a.Config.Name, "sections", a.sectionStore.GetSections(a.Config.Name))
+	}
+}
+
+// metricsSection renders metrics as a sidebar section: one line per metric
+// with its value and a sparkline of the last metricHistory minutes.
+func metricsSection(series []MetricSeries) sidebar.CustomSection {
+	width := 0
+	for _, s := range series {
+		width = max(width, len(s.Name))
+	}
+	// The sidebar is narrow; longer names push their line out instead
+	width = min(width, 20)
+	var b strings.Builder
+	for i, s := range series {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "%-*s %s %s", width, s.Name, sparkline.Render(s.Points), FormatMetricValue(s.Value))
+	}
+	return sidebar.CustomSection{
+		ID:      metricsSectionID,
+		Title:   "Metrics",
+		Content: b.String(),
+	}
+}
+
+// FormatMetricValue prints a metric value without a fraction when it is
+// whole.
+func FormatMetricValue(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,92 @@
+package agent
+
+import (
+	"fmt"
+	"time"
+)
+
+// PauseInfo records why and since when an agent is paused. A paused agent
+// keeps running but rejects command invocations and trigger events.
+type PauseInfo struct {
+	Reason string    `json:"reason,omitempty"`
+	Since  time.Time `json:"since"`
+}
+
+// Paused returns the agent's pause, or nil when it takes commands.
+func (a *Agent) Paused() *PauseInfo {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.paused == nil {
+		return nil
+	}
+	info := *a.paused
+	return &info
+}
+
+// PausedErr returns the error commands fail with while the agent is paused,
+// or nil.
+func (a *Agent) PausedErr() error {
+	info := a.Paused()
+	if info == nil {
+		return nil
+	}
+	if info.Reason != "" {
+		return fmt.Errorf("agent %s is %w: %s", a.Config.Name, ErrPaused, info.Reason)
+	}
+	return fmt.Errorf("agent %s is %w", a.Config.Name, ErrPaused)
+}
+
+func (a *Agent) setPaused(info *PauseInfo) {
+	a.mu.Lock()
+	a.paused = info
+	a.mu.Unlock()
+	if a.persistence != nil {
+		a.persistence.RecordPause(a.Config.Name, info)
+	}
+	if info == nil {
+		a.addLog("[pause] Resumed, accepting commands again")
+	} else if info.Reason != "" {
+		a.addLog(fmt.Sprintf("[pause] Paused: %s", info.Reason))
+	} else {
+		a.addLog("[pause] Paused")
+	}
+}
+
+// PauseAgent stops the agent from taking commands and trigger events without
+// stopping its process. The pause survives daemon restarts. Pausing a paused
+// agent again only replaces the reason.
+func (m *Manager) PauseAgent(name, reason string) error {
+	agent, err := m.GetAgent(name)
+	if err != nil {
+		return err
+	}
+	info := &PauseInfo{Reason: reason, Since: time.Now()}
+	if current := agent.Paused(); current != nil {
+		info.Since = current.Since
+	}
+	agent.setPaused(info)
+	return nil
+}
+
+// ResumeAgent lets a paused agent take commands again.
+func (m *Manager) ResumeAgent(name string) error {
+	agent, err := m.GetAgent(name)
+	if err != nil {
+		return err
+	}
+	if agent.Paused() == nil {
+		return fmt.Errorf("agent %s is not paused", name)
+	}
+	agent.setPaused(nil)
+	return nil
+}
+
+// RecordPause stores an agent's pause, or clears it when info is nil.
+func (p *AgentPersistence) RecordPause(agentName string, info *PauseInfo) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	data := p.getOrCreateData(agentName)
+	data.Paused = info
+	p.saveAsync()
+}
@@ -20,6 +20,12 @@ type AgentPersistentData struct {
 	LastStopped  time.Time `json:"last_stopped"`
 	CrashCount   int       `json:"crash_count"`
 	WasRunning   bool      `json:"was_running"` // Whether agent was running when daemon last stopped
+	LastExit     *ExitInfo `json:"last_exit,omitempty"`
+	// Paused is set while the agent is paused; see Manager.PauseAgent
+	Paused *PauseInfo `json:"paused,omitempty"`
+	// ProcessGroup is set while the agent's process group may have processes
+	// running in it
+	ProcessGroup *ProcessGroup `json:"process_group,omitempty"`
 }
 
 // AgentPersistence manages persistent storage for agent data
@@ -31,9 +37,10 @@ type AgentPersistence struct {
 	mu       sync.RWMutex
 }
 
-func NewAgentPersistence(configDir string, db *sql.DB) *AgentPersistence {
-	dataFile := filepath.Join(configDir, "agent_data.json")
-	logDir := filepath.Join(configDir, "logs")
+// NewAgentPersistence keeps agent data in dataDir and agent log files in
+// logDir.
+func NewAgentPersistence(dataDir, logDir string, db *sql.DB) *AgentPersistence {
+	dataFile := filepath.Join(dataDir, "agent_data.json")
 
 	p := &AgentPersistence{
 		dataFile: dataFile,
@@ -110,6 +117,92 @@ func (p *AgentPersistence) RecordCrash(agentName string) {
 	p.saveAsync()
 }
 
+// RecordExit stores how the agent process last exited
+func (p *AgentPersistence) RecordExit(agentName string, info ExitInfo) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	data := p.getOrCreateData(agentName)
+	data.LastExit = &info
+	p.saveAsync()
+}
+
+// RecordCrashReport stores a crash report in the database, keeping the most
+// recent 20 per agent
+func (p *AgentPersistence) RecordCrashReport(report CrashReport) {
+	if p.db == nil {
+		return
+	}
+
+	tail, err := json.Marshal(report.StderrTail)
+	if err != nil {
+		log.Printf("Warning: failed to encode stderr tail for %s: %v", report.AgentName, err)
+		return
+	}
+
+	if _, err := p.db.Exec(
+		`INSERT INTO agent_crashes (agent_name, exit_code, signal, stderr_tail, created_at) VALUES (?, ?, ?, ?, ?)`,
+		report.AgentName, report.Exit.Code, report.Exit.Signal, string(tail), report.At.Unix(),
+	); err != nil {
+		log.Printf("Warning: failed to record crash for %s: %v", report.AgentName, err)
+		return
+	}
+
+	if _, err := p.db.Exec(`
+		DELETE FROM agent_crashes
+		WHERE agent_name = ?
+		AND id NOT IN (
+			SELECT id FROM agent_crashes
+			WHERE agent_name = ?
+			ORDER BY id DESC
+			LIMIT 20
+		)
+	`, report.AgentName, report.AgentName); err != nil {
+		log.Printf("Warning: failed to trim crash reports for %s: %v", report.AgentName, err)
+	}
+}
+
+// GetLastCrash returns the most recent crash report for an agent, or nil if
+// it never crashed
+func (p *AgentPersistence) GetLastCrash(agentName string) (*CrashReport, error) {
+	if p.db == nil {
+		return nil, nil
+	}
+
+	var (
+		code      int
+		signal    sql.NullString
+		tail      sql.NullString
+		createdAt int64
+	)
+	err := p.db.QueryRow(`
+		SELECT exit_code, signal, stderr_tail, created_at
+		FROM agent_crashes
+		WHERE agent_name = ?
+		ORDER BY id DESC
+		LIMIT 1
+	`, agentName).Scan(&code, &signal, &tail, &createdAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query crash report: %w", err)
+	}
+
+	at := time.Unix(createdAt, 0)
+	report := &CrashReport{
+		AgentName: agentName,
+		Exit:      ExitInfo{Code: code, Signal: signal.String, At: at},
+		At:        at,
+	}
+	if tail.Valid && tail.String != "" {
+		if err := json.Unmarshal([]byte(tail.String), &report.StderrTail); err != nil {
+			log.Printf("Warning: failed to decode stderr tail for %s: %v", agentName, err)
+		}
+	}
+	return report, nil
+}
+
 func (p *AgentPersistence) AddLog(agentName string, logLine string) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
@@ -0,0 +1,190 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// leftoverGracePeriod is how long processes left in an agent's process group
+// after it exited have to exit after SIGTERM before they are killed.
+const leftoverGracePeriod = 2 * time.Second
+
+// ProcessGroup records the process group an agent runs in, so processes it
+// spawned can still be found when the daemon that started it is gone.
+type ProcessGroup struct {
+	PGID      int       `json:"pgid"`
+	DaemonPID int       `json:"daemon_pid"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// OrphanProcess is a process left over from an agent started by a daemon that
+// is no longer running.
+type OrphanProcess struct {
+	Agent   string
+	PGID    int
+	PID     int
+	Command string
+}
+
+// RecordProcessGroup stores the process group of an agent that just started.
+func (p *AgentPersistence) RecordProcessGroup(agentName string, pgid int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	data := p.getOrCreateData(agentName)
+	data.ProcessGroup = &ProcessGroup{PGID: pgid, DaemonPID: os.Getpid(), StartedAt: time.Now()}
+	p.saveAsync()
+}
+
+// ClearProcessGroup forgets an agent's process group once nothing is left
+// running in it.
+func (p *AgentPersistence) ClearProcessGroup(agentName string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	data, ok := p.data[agentName]
+	if !ok || data.ProcessGroup == nil {
+		return
+	}
+	data.ProcessGroup = nil
+	p.saveAsync()
+}
+
+// staleProcessGroups returns the recorded process groups of agents started by
+// a daemon that is no longer running.
+func staleProcessGroups(data map[string]*AgentPersistentData) map[string]ProcessGroup {
+	stale := make(map[string]ProcessGroup)
+	for name, d := range data {
+		if d == nil || d.ProcessGroup == nil {
+			continue
+		}
+		group := *d.ProcessGroup
+		if group.DaemonPID == os.Getpid() || processAlive(group.DaemonPID) {
+			continue
+		}
+		stale[name] = group
+	}
+	return stale
+}
+
+// CleanupOrphans kills what is left of agents started by a previous daemon
+// run that did not stop them, e.g. because it crashed.
+func (m *Manager) CleanupOrphans() {
+	if m.persistence == nil {
+		return
+	}
+	m.persistence.mu.RLock()
+	stale := staleProcessGroups(m.persistence.data)
+	m.persistence.mu.RUnlock()
+
+	for name, group := range stale {
+		if processGroupAlive(group.PGID) {
+			log.Printf("Killing orphaned processes of agent %s from a previous daemon run (process group %d)", name, group.PGID)
+			killProcessTree(group.PGID, leftoverGracePeriod)
+		}
+		m.persistence.ClearProcessGroup(name)
+	}
+}
+
+// FindOrphanProcesses lists the processes still running in the process groups
+// of agents whose daemon, per the agent data in dataDir, is gone.
+func FindOrphanProcesses(dataDir string) ([]OrphanProcess, error) {
+	raw, err := os.ReadFile(filepath.Join(dataDir, "agent_data.json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var data map[string]*AgentPersistentData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, fmt.Errorf("failed to parse agent data file: %w", err)
+	}
+
+	stale := staleProcessGroups(data)
+	if len(stale) == 0 {
+		return nil, nil
+	}
+	byGroup := make(map[int]string, len(stale))
+	for name, group := range stale {
+		byGroup[group.PGID] = name
+	}
+
+	out, err := exec.Command("ps", "-eo", "pid=,pgid=,command=", "-ww").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to run ps: %w", err)
+	}
+	var orphans []OrphanProcess
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+		pid, err1 := strconv.Atoi(fields[0])
+		pgid, err2 := strconv.Atoi(fields[1])
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		name, ok := byGroup[pgid]
+		if !ok {
+			continue
+		}
+		orphans = append(orphans, OrphanProcess{
+			Agent:   name,
+			PGID:    pgid,
+			PID:     pid,
+			Command: strings.Join(fields[2:], " "),
+		})
+	}
+	return orphans, nil
+}
+
+// reapProcessGroup kills what the agent's processes left behind in its
+// process group after the agent itself exited.
+func (a *Agent) reapProcessGroup(pgid int) {
+	if pgid > 0 && processGroupAlive(pgid) {
+		a.addLog(fmt.Sprintf("[cleanup] Killing processes left behind in process group %d", pgid))
+		killProcessTree(pgid, leftoverGracePeriod)
+	}
+	if a.persistence != nil {
+		a.persistence.ClearProcessGroup(a.Config.Name)
+	}
+}
+
+// killProcessTree sends SIGTERM to a process group and SIGKILL when it is
+// still alive after grace.
+func killProcessTree(pgid int, grace time.Duration) {
+	syscall.Kill(-pgid, syscall.SIGTERM)
+	deadline := time.Now().Add(grace)
+	for time.Now().Before(deadline) {
+		if !processGroupAlive(pgid) {
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	syscall.Kill(-pgid, syscall.SIGKILL)
+}
+
+func processGroupAlive(pgid int) bool {
+	if pgid <= 0 {
+		return false
+	}
+	err := syscall.Kill(-pgid, 0)
+	return err == nil || err == syscall.EPERM
+}
+
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
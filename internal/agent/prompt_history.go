@@ -0,0 +1,191 @@
+package agent
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// Sources of a prompt version.
+const (
+	// PromptSourceConfig is the prompt and description in agents.yaml.
+	PromptSourceConfig = "config"
+	// PromptSourceAgent is a prompt or description the agent process set.
+	PromptSourceAgent = "agent"
+	// PromptSourceRevert is a version restored with 'op agent prompt revert'.
+	PromptSourceRevert = "revert"
+)
+
+// maxPromptVersions is how many prompt versions are kept per agent.
+const maxPromptVersions = 100
+
+// PromptVersion is an agent's system prompt and description at one point in
+// time.
+type PromptVersion struct {
+	ID                  int64     `json:"id"`
+	AgentName           string    `json:"agent_name"`
+	SystemPrompt        string    `json:"system_prompt"`
+	SystemPromptReplace bool      `json:"system_prompt_replace"`
+	Description         string    `json:"description"`
+	Source              string    `json:"source"`
+	At                  time.Time `json:"at"`
+}
+
+func (v PromptVersion) sameContent(o PromptVersion) bool {
+	return v.SystemPrompt == o.SystemPrompt &&
+		v.SystemPromptReplace == o.SystemPromptReplace &&
+		v.Description == o.Description
+}
+
+// RecordPromptVersion stores a prompt version unless it matches the latest
+// one from the same source, so agents that set their prompt on every start
+// and config reloads don't pile up duplicates. Reverts are always stored.
+func (p *AgentPersistence) RecordPromptVersion(v PromptVersion) {
+	if p.db == nil {
+		return
+	}
+
+	if v.Source != PromptSourceRevert {
+		var last PromptVersion
+		err := p.db.QueryRow(`
+			SELECT system_prompt, system_prompt_replace, description
+			FROM agent_prompt_history
+			WHERE agent_name = ? AND source = ?
+			ORDER BY id DESC
+			LIMIT 1
+		`, v.AgentName, v.Source).Scan(&last.SystemPrompt, &last.SystemPromptReplace, &last.Description)
+		if err == nil && last.sameContent(v) {
+			return
+		}
+		if err != nil && err != sql.ErrNoRows {
+			log.Printf("Warning: failed to read prompt history for %s: %v", v.AgentName, err)
+			return
+		}
+		if err == sql.ErrNoRows && v.SystemPrompt == "" && v.Description == "" {
+			return
+		}
+	}
+
+	if _, err := p.db.Exec(
+		`INSERT INTO agent_prompt_history (agent_name, system_prompt, system_prompt_replace, description, source, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		v.AgentName, v.SystemPrompt, v.SystemPromptReplace, v.Description, v.Source, v.At.Unix(),
+	); err != nil {
+		log.Printf("Warning: failed to record prompt version for %s: %v", v.AgentName, err)
+		return
+	}
+
+	if _, err := p.db.Exec(`
+		DELETE FROM agent_prompt_history
+		WHERE agent_name = ?
+		AND id NOT IN (
+			SELECT id FROM agent_prompt_history
+			WHERE agent_name = ?
+			ORDER BY id DESC
+			LIMIT ?
+		)
+	`, v.AgentName, v.AgentName, maxPromptVersions); err != nil {
+		log.Printf("Warning: failed to trim prompt history for %s: %v", v.AgentName, err)
+	}
+}
+
+// GetPromptHistory returns an agent's prompt versions, newest first.
+func (p *AgentPersistence) GetPromptHistory(agentName string) ([]PromptVersion, error) {
+	if p.db == nil {
+		return nil, nil
+	}
+
+	rows, err := p.db.Query(`
+		SELECT id, system_prompt, system_prompt_replace, description, source, created_at
+		FROM agent_prompt_history
+		WHERE agent_name = ?
+		ORDER BY id DESC
+	`, agentName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query prompt history: %w", err)
+	}
+	defer rows.Close()
+
+	var versions []PromptVersion
+	for rows.Next() {
+		v := PromptVersion{AgentName: agentName}
+		var createdAt int64
+		if err := rows.Scan(&v.ID, &v.SystemPrompt, &v.SystemPromptReplace, &v.Description, &v.Source, &createdAt); err != nil {
+			return nil, fmt.Errorf("failed to read prompt history: %w", err)
+		}
+		v.At = time.Unix(createdAt, 0)
+		versions = append(versions, v)
+	}
+	return versions, rows.Err()
+}
+
+// currentPromptVersion snapshots the prompt and description the agent runs
+// with, without its instructions file.
+func (a *Agent) currentPromptVersion(source string) PromptVersion {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	v := PromptVersion{
+		AgentName:           a.Config.Name,
+		SystemPrompt:        strings.TrimSpace(a.systemPrompt),
+		SystemPromptReplace: a.systemPromptReplace,
+		Description:         strings.TrimSpace(a.description),
+		Source:              source,
+		At:                  time.Now(),
+	}
+	if v.SystemPrompt == "" {
+		v.SystemPrompt = strings.TrimSpace(a.Config.SystemPrompt)
+		v.SystemPromptReplace = false
+	}
+	if v.Description == "" {
+		v.Description = strings.TrimSpace(a.Config.Description)
+	}
+	return v
+}
+
+// recordPromptVersion adds the agent's current prompt to its history.
+func (a *Agent) recordPromptVersion(source string) {
+	if a.persistence == nil {
+		return
+	}
+	a.persistence.RecordPromptVersion(a.currentPromptVersion(source))
+}
+
+// PromptHistory returns the agent's prompt versions, newest first.
+func (a *Agent) PromptHistory() ([]PromptVersion, error) {
+	if a.persistence == nil {
+		return nil, nil
+	}
+	return a.persistence.GetPromptHistory(a.Config.Name)
+}
+
+// RevertPrompt restores the prompt and description of version id. The
+// agent process can still set a new prompt afterwards, for example when it
+// restarts.
+func (a *Agent) RevertPrompt(id int64) (PromptVersion, error) {
+	versions, err := a.PromptHistory()
+	if err != nil {
+		return PromptVersion{}, err
+	}
+	var target *PromptVersion
+	for i := range versions {
+		if versions[i].ID == id {
+			target = &versions[i]
+			break
+		}
+	}
+	if target == nil {
+		return PromptVersion{}, fmt.Errorf("prompt version %d of agent %s %w", id, a.Config.Name, ErrNotFound)
+	}
+
+	a.mu.Lock()
+	a.systemPrompt = target.SystemPrompt
+	a.systemPromptReplace = target.SystemPromptReplace && target.SystemPrompt != ""
+	a.description = target.Description
+	a.mu.Unlock()
+
+	a.addLog(fmt.Sprintf("[system-prompt] reverted to version %d", id))
+	a.recordPromptVersion(PromptSourceRevert)
+	a.notifyMetadataChange()
+	return *target, nil
+}
@@ -0,0 +1,57 @@
+package agent
+
+import "tui/components/sidebar"
+
+// resourcesSectionID is the sidebar section the daemon maintains with live
+// resource usage while the agent runs.
+const resourcesSectionID = "__resources"
+
+// SetResourceSection publishes content as the agent's resource usage section.
+// It is refreshed every few seconds, so it is kept in memory only.
+func (a *Agent) SetResourceSection(content string) {
+	if a.sectionStore == nil {
+		return
+	}
+	a.sectionStore.SaveTransientSection(a.Config.Name, resourcesSectionID, sidebar.CustomSection{
+		ID:        resourcesSectionID,
+		Title:     "Resources",
+		Content:   content,
+		Collapsed: true,
+	})
+	a.notifySections()
+}
+
+// ClearResourceSection removes the resource usage section once the agent is
+// no longer running. It is a no-op when no section is published.
+func (a *Agent) ClearResourceSection() {
+	a.clearSection(resourcesSectionID)
+}
+
+// clearSection removes the daemon-maintained section id, if it is
+// published.
+func (a *Agent) clearSection(id string) {
+	if a.sectionStore == nil {
+		return
+	}
+	found := false
+	for _, section := range a.sectionStore.GetSections(a.Config.Name) {
+		if section.ID == id {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return
+	}
+	a.sectionStore.DeleteSection(a.Config.Name, id)
+	a.notifySections()
+}
+
+func (a *Agent) notifySections() {
+	a.mu.RLock()
+	notifier := a.stateChangeNotifier
+	a.mu.RUnlock()
+	if notifier != nil {
+		notifier(a.Config.Name, "sections", a.sectionStore.GetSections(a.Config.Name))
+	}
+}
@@ -0,0 +1,145 @@
+package agent
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"os"
+	"syscall"
+	"time"
+)
+
+// DefaultRestartPolicy is applied to any field left unset in an agent's
+// restart_policy block.
+var DefaultRestartPolicy = RestartPolicy{
+	InitialBackoff:     2 * time.Second,
+	MaxBackoff:         time.Minute,
+	Multiplier:         2,
+	Jitter:             0.2,
+	CrashLoopThreshold: 5,
+	CrashLoopWindow:    5 * time.Minute,
+}
+
+// ExitInfo describes how an agent process last exited.
+type ExitInfo struct {
+	Code   int       `json:"code"`
+	Signal string    `json:"signal,omitempty"`
+	At     time.Time `json:"at"`
+}
+
+// String renders the exit as "exit 1" or "signal killed".
+func (e *ExitInfo) String() string {
+	if e == nil {
+		return ""
+	}
+	if e.Signal != "" {
+		return fmt.Sprintf("signal %s", e.Signal)
+	}
+	return fmt.Sprintf("exit %d", e.Code)
+}
+
+// StatusUpdate is sent through the state change notifier when a status change
+// carries exit details alongside the new status.
+type StatusUpdate struct {
+	Status   ProcessStatus
+	LastExit *ExitInfo
+}
+
+func newExitInfo(state *os.ProcessState) *ExitInfo {
+	if state == nil {
+		return nil
+	}
+	info := &ExitInfo{Code: state.ExitCode(), At: time.Now()}
+	if ws, ok := state.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+		info.Signal = ws.Signal().String()
+	}
+	return info
+}
+
+// resolve fills unset fields from DefaultRestartPolicy.
+func (p *RestartPolicy) resolve() RestartPolicy {
+	resolved := DefaultRestartPolicy
+	if p == nil {
+		return resolved
+	}
+	if p.InitialBackoff > 0 {
+		resolved.InitialBackoff = p.InitialBackoff
+	}
+	if p.MaxBackoff > 0 {
+		resolved.MaxBackoff = p.MaxBackoff
+	}
+	if p.Multiplier >= 1 {
+		resolved.Multiplier = p.Multiplier
+	}
+	if p.Jitter > 0 && p.Jitter <= 1 {
+		resolved.Jitter = p.Jitter
+	}
+	if p.CrashLoopThreshold > 0 {
+		resolved.CrashLoopThreshold = p.CrashLoopThreshold
+	}
+	if p.CrashLoopWindow > 0 {
+		resolved.CrashLoopWindow = p.CrashLoopWindow
+	}
+	if resolved.MaxBackoff < resolved.InitialBackoff {
+		resolved.MaxBackoff = resolved.InitialBackoff
+	}
+	return resolved
+}
+
+// Backoff returns the delay before restart attempt n (1-based), growing
+// exponentially up to MaxBackoff with +/- Jitter applied.
+func (p RestartPolicy) Backoff(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	delay := float64(p.InitialBackoff) * math.Pow(p.Multiplier, float64(attempt-1))
+	if delay > float64(p.MaxBackoff) {
+		delay = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		delay += delay * p.Jitter * (rand.Float64()*2 - 1)
+	}
+	if delay < 0 {
+		delay = 0
+	}
+	return time.Duration(delay)
+}
+
+// recordCrashLocked appends a crash timestamp, drops the ones outside the crash
+// loop window and returns how many crashes remain in the window.
+func (a *Agent) recordCrashLocked(policy RestartPolicy, at time.Time) int {
+	cutoff := at.Add(-policy.CrashLoopWindow)
+	kept := a.recentCrashes[:0]
+	for _, ts := range a.recentCrashes {
+		if ts.After(cutoff) {
+			kept = append(kept, ts)
+		}
+	}
+	a.recentCrashes = append(kept, at)
+	return len(a.recentCrashes)
+}
+
+// ResetCrashHistory clears the crash loop window, e.g. after a manual start.
+func (a *Agent) ResetCrashHistory() {
+	a.mu.Lock()
+	a.recentCrashes = nil
+	a.mu.Unlock()
+}
+
+// LastExit returns details about the most recent process exit, if any.
+func (a *Agent) LastExit() *ExitInfo {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.lastExit == nil {
+		return nil
+	}
+	info := *a.lastExit
+	return &info
+}
+
+func restartPolicyEqual(a, b *RestartPolicy) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
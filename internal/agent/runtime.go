@@ -0,0 +1,234 @@
+package agent
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// RuntimeKind identifies the language runtime an agent needs provisioned.
+type RuntimeKind string
+
+const (
+	RuntimeNone   RuntimeKind = "none"
+	RuntimePython RuntimeKind = "python"
+	RuntimeNode   RuntimeKind = "node"
+)
+
+// runtimeStampFile records the dependency fingerprint the runtime was last
+// provisioned for, relative to the agent's process root.
+const runtimeStampFile = ".opperator-runtime"
+
+var runtimeDependencyFiles = map[RuntimeKind][]string{
+	RuntimePython: {"pyproject.toml", "requirements.txt", "uv.lock"},
+	RuntimeNode:   {"package.json", "package-lock.json", "yarn.lock", "pnpm-lock.yaml"},
+}
+
+// DetectRuntime inspects dir for dependency manifests.
+func DetectRuntime(dir string) RuntimeKind {
+	for _, name := range []string{"pyproject.toml", "requirements.txt"} {
+		if fileExists(filepath.Join(dir, name)) {
+			return RuntimePython
+		}
+	}
+	if fileExists(filepath.Join(dir, "package.json")) {
+		return RuntimeNode
+	}
+	return RuntimeNone
+}
+
+// ResolveRuntime honours the agent's runtime setting, detecting it from dir
+// when unset or "auto".
+func (c AgentConfig) ResolveRuntime(dir string) RuntimeKind {
+	switch strings.ToLower(strings.TrimSpace(c.Runtime)) {
+	case "", "auto":
+		return DetectRuntime(dir)
+	case string(RuntimePython):
+		return RuntimePython
+	case string(RuntimeNode):
+		return RuntimeNode
+	default:
+		return RuntimeNone
+	}
+}
+
+// dependencyFingerprint hashes the runtime's dependency files so changes
+// trigger a re-provision.
+func dependencyFingerprint(dir string, kind RuntimeKind) (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n", kind)
+	for _, name := range runtimeDependencyFiles[kind] {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return "", err
+		}
+		fmt.Fprintf(h, "%s %d\n", name, len(data))
+		h.Write(data)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// runtimeUpToDate reports whether dir was already provisioned for fingerprint
+// and the environment directory still exists.
+func runtimeUpToDate(dir string, kind RuntimeKind, fingerprint string) bool {
+	stamp, err := os.ReadFile(filepath.Join(dir, runtimeStampFile))
+	if err != nil || strings.TrimSpace(string(stamp)) != fingerprint {
+		return false
+	}
+	switch kind {
+	case RuntimePython:
+		return fileExists(filepath.Join(dir, ".venv", "bin", "python"))
+	case RuntimeNode:
+		return fileExists(filepath.Join(dir, "node_modules"))
+	}
+	return true
+}
+
+// ensureRuntime provisions the agent's virtualenv or node_modules when the
+// dependency files changed since the last start. Output is streamed to the
+// agent log so the TUI can follow along. Agents without a process root run in
+// the config directory, which they share, so nothing is provisioned for them
+// unless they ask for a runtime, which is an error.
+func (a *Agent) ensureRuntime(dir string) error {
+	if strings.TrimSpace(a.Config.ProcessRoot) == "" {
+		switch runtime := strings.ToLower(strings.TrimSpace(a.Config.Runtime)); runtime {
+		case "", "auto", string(RuntimeNone):
+			return nil
+		default:
+			return fmt.Errorf("runtime %s needs a process_root for the agent's own environment", runtime)
+		}
+	}
+	kind := a.Config.ResolveRuntime(dir)
+	if kind == RuntimeNone {
+		return nil
+	}
+
+	fingerprint, err := dependencyFingerprint(dir, kind)
+	if err != nil {
+		return fmt.Errorf("fingerprint %s dependencies: %w", kind, err)
+	}
+	if runtimeUpToDate(dir, kind, fingerprint) {
+		return nil
+	}
+
+	a.addLog(fmt.Sprintf("[runtime] Provisioning %s environment in %s", kind, dir))
+	switch kind {
+	case RuntimePython:
+		err = a.provisionPython(dir)
+	case RuntimeNode:
+		err = a.provisionNode(dir)
+	}
+	if err != nil {
+		a.addLog(fmt.Sprintf("[runtime] Provisioning failed: %v", err))
+		return fmt.Errorf("provision %s runtime: %w", kind, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, runtimeStampFile), []byte(fingerprint+"\n"), 0644); err != nil {
+		return fmt.Errorf("write runtime stamp: %w", err)
+	}
+	a.addLog(fmt.Sprintf("[runtime] %s environment ready", kind))
+	return nil
+}
+
+func (a *Agent) provisionPython(dir string) error {
+	venvPython := filepath.Join(dir, ".venv", "bin", "python")
+	_, uvErr := exec.LookPath("uv")
+	useUV := uvErr == nil
+
+	if !fileExists(venvPython) {
+		if useUV {
+			if err := a.runProvisionStep(dir, "uv", "venv", ".venv"); err != nil {
+				return err
+			}
+		} else if err := a.runProvisionStep(dir, "python3", "-m", "venv", ".venv"); err != nil {
+			return err
+		}
+	}
+
+	var installArgs []string
+	if fileExists(filepath.Join(dir, "pyproject.toml")) {
+		installArgs = []string{"install", "-e", dir}
+	} else {
+		installArgs = []string{"install", "-r", filepath.Join(dir, "requirements.txt")}
+	}
+
+	if useUV {
+		args := append([]string{"pip"}, installArgs...)
+		args = append(args, "--python", venvPython)
+		if err := a.runProvisionStep(dir, "uv", args...); err == nil {
+			return nil
+		}
+		a.addLog("[runtime] uv install failed, falling back to pip")
+	}
+	return a.runProvisionStep(dir, venvPython, append([]string{"-m", "pip"}, installArgs...)...)
+}
+
+func (a *Agent) provisionNode(dir string) error {
+	switch {
+	case fileExists(filepath.Join(dir, "pnpm-lock.yaml")):
+		return a.runProvisionStep(dir, "pnpm", "install", "--frozen-lockfile")
+	case fileExists(filepath.Join(dir, "yarn.lock")):
+		return a.runProvisionStep(dir, "yarn", "install", "--frozen-lockfile")
+	case fileExists(filepath.Join(dir, "package-lock.json")):
+		return a.runProvisionStep(dir, "npm", "ci")
+	default:
+		return a.runProvisionStep(dir, "npm", "install")
+	}
+}
+
+// runProvisionStep runs a provisioning command, logging each output line.
+func (a *Agent) runProvisionStep(dir, name string, args ...string) error {
+	a.addLog(fmt.Sprintf("[runtime] $ %s %s", name, strings.Join(args, " ")))
+
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+	cmd.Env = os.Environ()
+	for key, value := range a.Config.Env {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", key, value))
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+
+	var wg sync.WaitGroup
+	stream := func(r io.Reader) {
+		defer wg.Done()
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			a.addLog("[runtime] " + scanner.Text())
+		}
+	}
+	wg.Add(2)
+	go stream(stdout)
+	go stream(stderr)
+	wg.Wait()
+
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("%s %s: %w", name, strings.Join(args, " "), err)
+	}
+	return nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -2,6 +2,7 @@ package agent
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"sync"
 	"time"
@@ -76,7 +77,7 @@ func NewInMemorySectionStore() *SectionStore {
 // loadCache loads all sections from database into memory cache
 func (s *SectionStore) loadCache() error {
 	rows, err := s.db.Query(`
-		SELECT agent_name, section_id, title, content, collapsed
+		SELECT agent_name, section_id, title, content, collapsed, actions, section_type, format
 		FROM custom_sections
 		ORDER BY agent_name, created_at
 	`)
@@ -91,11 +92,19 @@ func (s *SectionStore) loadCache() error {
 	for rows.Next() {
 		var agentName, sectionID, title, content string
 		var collapsed bool
+		var actionsJSON, sectionType, format sql.NullString
 
-		if err := rows.Scan(&agentName, &sectionID, &title, &content, &collapsed); err != nil {
+		if err := rows.Scan(&agentName, &sectionID, &title, &content, &collapsed, &actionsJSON, &sectionType, &format); err != nil {
 			return err
 		}
 
+		var actions []sidebar.SectionAction
+		if actionsJSON.Valid && actionsJSON.String != "" {
+			if err := json.Unmarshal([]byte(actionsJSON.String), &actions); err != nil {
+				return fmt.Errorf("decode actions of section %s: %w", sectionID, err)
+			}
+		}
+
 		if s.cache[agentName] == nil {
 			s.cache[agentName] = make(map[string]*sidebar.CustomSection)
 		}
@@ -105,6 +114,9 @@ func (s *SectionStore) loadCache() error {
 			Title:     title,
 			Content:   content,
 			Collapsed: collapsed,
+			Actions:   actions,
+			Type:      sectionType.String,
+			Format:    format.String,
 		}
 	}
 
@@ -127,6 +139,18 @@ func (s *SectionStore) SaveSection(agentName, sectionID string, section sidebar.
 	return nil
 }
 
+// SaveTransientSection saves a section to the cache only. It is for sections
+// the daemon refreshes constantly and rebuilds after a restart, which would
+// otherwise be written to the database on every refresh.
+func (s *SectionStore) SaveTransientSection(agentName, sectionID string, section sidebar.CustomSection) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cache[agentName] == nil {
+		s.cache[agentName] = make(map[string]*sidebar.CustomSection)
+	}
+	s.cache[agentName][sectionID] = &section
+}
+
 // DeleteSection removes a section from cache and database
 func (s *SectionStore) DeleteSection(agentName, sectionID string) error {
 	// Remove from cache immediately
@@ -259,8 +283,8 @@ func (s *SectionStore) flushDirty() error {
 
 	stmt, err := tx.Prepare(`
 		INSERT OR REPLACE INTO custom_sections
-		(agent_name, section_id, title, content, collapsed, updated_at)
-		VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+		(agent_name, section_id, title, content, collapsed, actions, section_type, format, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
 	`)
 	if err != nil {
 		tx.Rollback()
@@ -273,7 +297,18 @@ func (s *SectionStore) flushDirty() error {
 	for agentName, sections := range toFlush {
 		for sectionID := range sections {
 			if sec := s.cache[agentName][sectionID]; sec != nil {
-				if _, err := stmt.Exec(agentName, sectionID, sec.Title, sec.Content, sec.Collapsed); err != nil {
+				var actions sql.NullString
+				if len(sec.Actions) > 0 {
+					data, err := json.Marshal(sec.Actions)
+					if err != nil {
+						s.mu.RUnlock()
+						tx.Rollback()
+						return err
+					}
+					actions = sql.NullString{String: string(data), Valid: true}
+				}
+				if _, err := stmt.Exec(agentName, sectionID, sec.Title, sec.Content, sec.Collapsed, actions,
+					sql.NullString{String: sec.Type, Valid: sec.Type != ""}, sql.NullString{String: sec.Format, Valid: sec.Format != ""}); err != nil {
 					s.mu.RUnlock()
 					tx.Rollback()
 					return err
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 	"time"
 
@@ -29,6 +30,9 @@ func setupTestDB(t *testing.T) (*sql.DB, func()) {
 			title TEXT NOT NULL,
 			content TEXT NOT NULL,
 			collapsed BOOLEAN NOT NULL DEFAULT FALSE,
+			actions TEXT,
+			section_type TEXT,
+			format TEXT,
 			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 			PRIMARY KEY (agent_name, section_id)
@@ -143,6 +147,50 @@ func TestSectionStore_Persistence(t *testing.T) {
 	}
 }
 
+func TestSectionStore_PersistsActions(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	store, err := NewSectionStore(db, SectionStoreConfig{
+		FlushInterval: time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	section := sidebar.CustomSection{
+		ID:      "queue",
+		Title:   "Queue",
+		Content: "3 pending",
+		Actions: []sidebar.SectionAction{
+			{ID: "clear", Label: "Clear", Type: sidebar.SectionActionButton, Command: "clear_queue", Args: map[string]any{"force": true}},
+			{ID: "add", Label: "Add URL", Type: sidebar.SectionActionInput, Command: "enqueue", Arg: "url", Placeholder: "https://..."},
+		},
+	}
+	if err := store.SaveSection("agent1", "queue", section); err != nil {
+		t.Fatalf("Failed to save section: %v", err)
+	}
+
+	// Close flushes the section
+	store.Close()
+
+	store2, err := NewSectionStore(db, SectionStoreConfig{
+		FlushInterval: time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("Failed to reopen store: %v", err)
+	}
+	defer store2.Close()
+
+	sections := store2.GetSections("agent1")
+	if len(sections) != 1 {
+		t.Fatalf("Expected 1 section loaded from DB, got %d", len(sections))
+	}
+	if !reflect.DeepEqual(sections[0].Actions, section.Actions) {
+		t.Errorf("Expected actions %+v, got %+v", section.Actions, sections[0].Actions)
+	}
+}
+
 func TestSectionStore_RapidUpdates(t *testing.T) {
 	db, cleanup := setupTestDB(t)
 	defer cleanup()
@@ -0,0 +1,99 @@
+package agent
+
+import (
+	"fmt"
+	"syscall"
+	"time"
+
+	"opperator/internal/protocol"
+)
+
+const (
+	// DefaultStopGracePeriod is how long an agent has to exit after the
+	// shutdown lifecycle event when neither the request nor agents.yaml set
+	// stop_grace_period.
+	DefaultStopGracePeriod = 10 * time.Second
+	// termTimeout is how long an agent has to exit after SIGTERM before it is
+	// killed.
+	termTimeout = 3 * time.Second
+
+	lifecycleShutdown = "shutdown"
+)
+
+// StopMethod is how a stopped agent's process ended.
+type StopMethod string
+
+const (
+	// StopGraceful means the agent exited on its own after the shutdown
+	// lifecycle event.
+	StopGraceful StopMethod = "graceful"
+	// StopTerminated means the agent exited after SIGTERM.
+	StopTerminated StopMethod = "terminated"
+	// StopKilled means the agent was killed with SIGKILL.
+	StopKilled StopMethod = "killed"
+)
+
+// StopOptions tune how an agent is stopped.
+type StopOptions struct {
+	// GracePeriod is how long the agent has to exit after the shutdown
+	// lifecycle event before it gets SIGTERM. Zero uses the agent's
+	// stop_grace_period.
+	GracePeriod time.Duration `json:"grace_period,omitempty"`
+	// Force skips the shutdown event and SIGTERM and kills the agent.
+	Force bool `json:"force,omitempty"`
+	// Reason is logged and sent with the shutdown event.
+	Reason string `json:"reason,omitempty"`
+}
+
+func (a *Agent) stopGracePeriod(opts StopOptions) time.Duration {
+	switch {
+	case opts.GracePeriod > 0:
+		return opts.GracePeriod
+	case a.Config.StopGracePeriod > 0:
+		return a.Config.StopGracePeriod
+	}
+	return DefaultStopGracePeriod
+}
+
+// terminate ends the agent's process group: it asks the agent to shut down
+// with a lifecycle event when the agent supports them, sends SIGTERM once the
+// grace period is over and SIGKILL when that does not help either. exited is
+// closed when the process has been reaped.
+func (a *Agent) terminate(pid int, exited <-chan struct{}, opts StopOptions) StopMethod {
+	wait := func(d time.Duration) bool {
+		if exited == nil {
+			time.Sleep(d)
+			return false
+		}
+		select {
+		case <-exited:
+			return true
+		case <-time.After(d):
+			return false
+		}
+	}
+
+	if !opts.Force {
+		if a.supports(protocol.CapabilityLifecycleEvents) {
+			grace := a.stopGracePeriod(opts)
+			err := a.SendLifecycleEvent(lifecycleShutdown, map[string]interface{}{
+				"reason":               opts.Reason,
+				"grace_period_seconds": grace.Seconds(),
+			})
+			if err == nil {
+				if wait(grace) {
+					return StopGraceful
+				}
+				a.addLog(fmt.Sprintf("[stop] Still running %s after the shutdown event, sending SIGTERM", grace))
+			}
+		}
+		syscall.Kill(-pid, syscall.SIGTERM)
+		if wait(termTimeout) {
+			return StopTerminated
+		}
+		a.addLog(fmt.Sprintf("[stop] Still running %s after SIGTERM, sending SIGKILL", termTimeout))
+	}
+	syscall.Kill(-pid, syscall.SIGKILL)
+	wait(time.Second)
+	return StopKilled
+}
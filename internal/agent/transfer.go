@@ -14,8 +14,8 @@ import (
 
 	"github.com/charmbracelet/huh"
 	"github.com/charmbracelet/lipgloss"
-	"gopkg.in/yaml.v3"
 	"opperator/internal/credentials"
+	"opperator/internal/kv"
 )
 
 // AgentPackage represents a transferable agent with all its files
@@ -24,6 +24,8 @@ type AgentPackage struct {
 	FilesData  []byte            // tar.gz of agent directory
 	WasRunning bool              // Whether agent was running before transfer
 	Secrets    map[string]string // Secrets used by this agent (name -> value)
+	KV         []kv.Entry        // Key-value state kept by the daemon for this agent
+	Encrypted  []string          // Paths of Config values that were !encrypted, re-encrypted on the target
 }
 
 // identifyAgentSecrets finds all secrets referenced by scanning the agent's source code
@@ -357,6 +359,7 @@ func PackageAgentWithWizard(agentName, sourceDaemon, targetDaemon string, config
 		FilesData:  filesData,
 		WasRunning: wasRunning,
 		Secrets:    secrets,
+		Encrypted:  config.encryptedPaths(agentName),
 	}, nil
 }
 
@@ -429,6 +432,7 @@ func PackageAgent(agentName string, configPath string, wasRunning bool) (*AgentP
 		FilesData:  filesData,
 		WasRunning: wasRunning,
 		Secrets:    secrets,
+		Encrypted:  config.encryptedPaths(agentName),
 	}, nil
 }
 
@@ -445,9 +449,11 @@ func getSecretNames(secrets map[string]string) []string {
 func UnpackageAgent(pkg *AgentPackage, configPath string) error {
 	// Load existing config
 	config, err := LoadConfig(configPath)
-	if err != nil {
+	if os.IsNotExist(err) {
 		// If config doesn't exist, create empty one
 		config = &Config{Agents: []AgentConfig{}}
+	} else if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
 	}
 
 	// Check if agent already exists
@@ -457,6 +463,12 @@ func UnpackageAgent(pkg *AgentPackage, configPath string) error {
 		}
 	}
 
+	// Values that were !encrypted on the source are encrypted again with
+	// this machine's key rather than written in the clear
+	if err := config.encryptAgent(pkg.Config, pkg.Encrypted); err != nil {
+		return err
+	}
+
 	// Extract agent directory if present
 	if len(pkg.FilesData) > 0 {
 		// Resolve target directory
@@ -489,29 +501,7 @@ func UnpackageAgent(pkg *AgentPackage, configPath string) error {
 	config.Agents = append(config.Agents, pkg.Config)
 
 	// Save config
-	data, err := os.ReadFile(configPath)
-	if err != nil {
-		return fmt.Errorf("failed to read config file: %w", err)
-	}
-
-	var rawConfig map[string]interface{}
-	if err := yaml.Unmarshal(data, &rawConfig); err != nil {
-		return fmt.Errorf("failed to unmarshal config: %w", err)
-	}
-
-	// Update agents array
-	rawConfig["agents"] = config.Agents
-
-	newData, err := yaml.Marshal(rawConfig)
-	if err != nil {
-		return fmt.Errorf("failed to marshal config: %w", err)
-	}
-
-	if err := os.WriteFile(configPath, newData, 0644); err != nil {
-		return fmt.Errorf("failed to write config: %w", err)
-	}
-
-	return nil
+	return SaveConfig(configPath, config)
 }
 
 // OverwriteAgent replaces an existing agent with a new package
@@ -535,6 +525,9 @@ func OverwriteAgent(pkg *AgentPackage, configPath string) error {
 	if !found {
 		return fmt.Errorf("agent '%s' not found", pkg.Config.Name)
 	}
+	if err := config.encryptAgent(pkg.Config, pkg.Encrypted); err != nil {
+		return err
+	}
 
 	// Extract agent directory if present
 	if len(pkg.FilesData) > 0 {
@@ -573,29 +566,7 @@ func OverwriteAgent(pkg *AgentPackage, configPath string) error {
 	}
 
 	// Save config
-	data, err := os.ReadFile(configPath)
-	if err != nil {
-		return fmt.Errorf("failed to read config file: %w", err)
-	}
-
-	var rawConfig map[string]interface{}
-	if err := yaml.Unmarshal(data, &rawConfig); err != nil {
-		return fmt.Errorf("failed to unmarshal config: %w", err)
-	}
-
-	// Update agents array
-	rawConfig["agents"] = config.Agents
-
-	newData, err := yaml.Marshal(rawConfig)
-	if err != nil {
-		return fmt.Errorf("failed to marshal config: %w", err)
-	}
-
-	if err := os.WriteFile(configPath, newData, 0644); err != nil {
-		return fmt.Errorf("failed to write config: %w", err)
-	}
-
-	return nil
+	return SaveConfig(configPath, config)
 }
 
 // shouldExcludePath determines if a path should be excluded from packaging
@@ -0,0 +1,131 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"opperator/config"
+)
+
+type explicitWorkingDirKey struct{}
+
+// WithExplicitWorkingDir marks the working directory of commands invoked with
+// ctx as chosen by the user, so it takes precedence over the command's
+// declared default.
+func WithExplicitWorkingDir(ctx context.Context) context.Context {
+	return context.WithValue(ctx, explicitWorkingDirKey{}, true)
+}
+
+// commandWorkingDir returns the directory a command runs in. A directory the
+// user picked, or else the command's declared one, must be an existing
+// directory within the agent's allowed directories and is returned with
+// symlinks resolved. Otherwise the caller's directory, which may only exist
+// on the client's host, is passed on as is.
+func commandWorkingDir(ctx context.Context, a *Agent, command, workingDir string) (string, error) {
+	dir := strings.TrimSpace(workingDir)
+	if explicit, _ := ctx.Value(explicitWorkingDirKey{}).(bool); !explicit {
+		declared := ""
+		for _, def := range a.RegisteredCommands() {
+			if def.Name == command {
+				declared = strings.TrimSpace(def.WorkingDir)
+				break
+			}
+		}
+		if declared == "" {
+			// The caller's directory is only a hint for the agent; the
+			// process keeps running in its process root
+			return dir, nil
+		}
+		dir = declared
+		if !filepath.IsAbs(dir) {
+			root, err := a.Config.WorkingDir()
+			if err != nil {
+				return "", err
+			}
+			dir = filepath.Join(root, dir)
+		}
+	}
+	if dir == "" {
+		return "", nil
+	}
+	if !filepath.IsAbs(dir) {
+		return "", fmt.Errorf("%w: %s is not an absolute path", ErrWorkingDirNotAllowed, dir)
+	}
+	dir = filepath.Clean(dir)
+
+	// Symlinks are resolved so a link inside an allowed directory cannot
+	// point a command outside of it
+	resolved, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return "", fmt.Errorf("working directory %s: %w", dir, err)
+	}
+	if info, err := os.Stat(resolved); err != nil {
+		return "", fmt.Errorf("working directory %s: %w", dir, err)
+	} else if !info.IsDir() {
+		return "", fmt.Errorf("working directory %s is not a directory", dir)
+	}
+
+	roots, err := a.Config.allowedDirs()
+	if err != nil {
+		return "", err
+	}
+	for _, root := range roots {
+		if withinDir(root, resolved) {
+			return resolved, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s is outside %s (see allowed_dirs in agents.yaml)", ErrWorkingDirNotAllowed, dir, strings.Join(roots, ", "))
+}
+
+// allowedDirs resolves the directories the agent's commands may run in.
+func (c AgentConfig) allowedDirs() ([]string, error) {
+	dirs := c.AllowedDirs
+	if len(dirs) == 0 {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("resolve home directory: %w", err)
+		}
+		root, err := c.WorkingDir()
+		if err != nil {
+			return nil, err
+		}
+		dirs = []string{home, root}
+	}
+
+	var roots []string
+	for _, dir := range dirs {
+		dir = strings.TrimSpace(dir)
+		if dir == "" {
+			continue
+		}
+		if dir == "~" || strings.HasPrefix(dir, "~/") {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return nil, fmt.Errorf("resolve home directory: %w", err)
+			}
+			dir = filepath.Join(home, strings.TrimPrefix(dir, "~"))
+		} else if !filepath.IsAbs(dir) {
+			configDir, err := config.GetConfigDir()
+			if err != nil {
+				return nil, fmt.Errorf("resolve config directory: %w", err)
+			}
+			dir = filepath.Join(configDir, dir)
+		}
+		if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+			dir = resolved
+		}
+		roots = append(roots, filepath.Clean(dir))
+	}
+	return roots, nil
+}
+
+func withinDir(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
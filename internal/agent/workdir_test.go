@@ -0,0 +1,135 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"opperator/internal/protocol"
+)
+
+// agentWithCommands returns an agent that has registered commands, as if
+// its process had sent them.
+func agentWithCommands(t *testing.T, cfg AgentConfig, commands ...protocol.CommandDescriptor) *Agent {
+	t.Helper()
+	msg, err := protocol.NewMessage(protocol.MsgCommandRegistry, protocol.CommandRegistryMessage{Commands: commands})
+	if err != nil {
+		t.Fatalf("Failed to build registry message: %v", err)
+	}
+	line, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatalf("Failed to marshal registry message: %v", err)
+	}
+	p := protocol.NewProcessProtocol(nil, io.NopCloser(strings.NewReader(string(line)+"\n")), nil)
+	p.RegisterDefaults(&protocol.DefaultHandlers{})
+	p.Start()
+	p.Stop()
+	return &Agent{Config: cfg, protocol: p}
+}
+
+func TestCommandWorkingDir(t *testing.T) {
+	base, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to resolve temp dir: %v", err)
+	}
+	root := filepath.Join(base, "root")
+	outside := filepath.Join(base, "outside")
+	for _, dir := range []string{filepath.Join(root, "sub"), outside} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create %s: %v", dir, err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(root, "file.txt"), nil, 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "escape")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(root, "sub"), filepath.Join(outside, "inward")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+
+	a := agentWithCommands(t, AgentConfig{Name: "crawler", ProcessRoot: root, AllowedDirs: []string{root}},
+		protocol.CommandDescriptor{Name: "build", WorkingDir: "sub"},
+		protocol.CommandDescriptor{Name: "leak", WorkingDir: outside},
+		protocol.CommandDescriptor{Name: "plain"},
+	)
+
+	tests := []struct {
+		name       string
+		explicit   bool
+		command    string
+		workingDir string
+		want       string
+		// notAllowed expects ErrWorkingDirNotAllowed, err any other error
+		notAllowed bool
+		err        bool
+	}{
+		{name: "caller dir is passed on", command: "plain", workingDir: "/nonexistent/client/dir", want: "/nonexistent/client/dir"},
+		{name: "no dir", command: "plain", want: ""},
+		{name: "declared relative to process root", command: "build", workingDir: "/tmp", want: filepath.Join(root, "sub")},
+		{name: "declared outside allowed dirs", command: "leak", notAllowed: true},
+		{name: "explicit within allowed dirs", explicit: true, command: "plain", workingDir: filepath.Join(root, "sub"), want: filepath.Join(root, "sub")},
+		{name: "explicit overrides declared", explicit: true, command: "build", workingDir: root, want: root},
+		{name: "explicit outside allowed dirs", explicit: true, command: "plain", workingDir: outside, notAllowed: true},
+		{name: "explicit dot-dot escape", explicit: true, command: "plain", workingDir: filepath.Join(root, "sub", "..", "..", "outside"), notAllowed: true},
+		{name: "explicit relative", explicit: true, command: "plain", workingDir: "sub", notAllowed: true},
+		{name: "symlink out of allowed dirs", explicit: true, command: "plain", workingDir: filepath.Join(root, "escape"), notAllowed: true},
+		{name: "symlink into allowed dirs", explicit: true, command: "plain", workingDir: filepath.Join(outside, "inward"), want: filepath.Join(root, "sub")},
+		{name: "explicit missing", explicit: true, command: "plain", workingDir: filepath.Join(root, "missing"), err: true},
+		{name: "explicit file", explicit: true, command: "plain", workingDir: filepath.Join(root, "file.txt"), err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.explicit {
+				ctx = WithExplicitWorkingDir(ctx)
+			}
+			got, err := commandWorkingDir(ctx, a, tt.command, tt.workingDir)
+			switch {
+			case tt.notAllowed:
+				if !errors.Is(err, ErrWorkingDirNotAllowed) {
+					t.Errorf("Expected ErrWorkingDirNotAllowed, got %q, %v", got, err)
+				}
+			case tt.err:
+				if err == nil || errors.Is(err, ErrWorkingDirNotAllowed) {
+					t.Errorf("Expected an error other than ErrWorkingDirNotAllowed, got %q, %v", got, err)
+				}
+			default:
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if got != tt.want {
+					t.Errorf("Expected %q, got %q", tt.want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestWithinDir(t *testing.T) {
+	tests := []struct {
+		root, path string
+		want       bool
+	}{
+		{"/srv/app", "/srv/app", true},
+		{"/srv/app", "/srv/app/data", true},
+		{"/srv/app", "/srv/app/..data", true},
+		{"/srv/app", "/srv/application", false},
+		{"/srv/app", "/srv", false},
+		{"/srv/app", "/etc", false},
+		{"/", "/etc", true},
+	}
+
+	for _, tt := range tests {
+		if got := withinDir(tt.root, tt.path); got != tt.want {
+			t.Errorf("withinDir(%q, %q) = %v, expected %v", tt.root, tt.path, got, tt.want)
+		}
+	}
+}
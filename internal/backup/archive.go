@@ -0,0 +1,239 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"opperator/config"
+)
+
+// dbName is the database file inside the data directory and the archive.
+const dbName = "opperator.db"
+
+// agentDataName is the agent data file inside the data directory and the
+// archive.
+const agentDataName = "agent_data.json"
+
+// excludedDirs are skipped wherever they appear: logs are not state, and
+// virtualenvs and caches are rebuilt when agents are bootstrapped.
+var excludedDirs = map[string]bool{
+	"logs":          true,
+	".venv":         true,
+	"venv":          true,
+	"__pycache__":   true,
+	"node_modules":  true,
+	".git":          true,
+	".pytest_cache": true,
+	".mypy_cache":   true,
+	".tox":          true,
+}
+
+// excluded reports whether rel, a slash-separated path relative to the
+// config directory, stays out of snapshots. The live database is copied
+// separately, and .env files hold secrets like the keyring does.
+func excluded(rel string, isDir bool) bool {
+	base := filepath.Base(rel)
+	if isDir {
+		return excludedDirs[base]
+	}
+	switch {
+	case rel == dbName, strings.HasPrefix(rel, dbName+"-"), rel == agentDataName:
+		return true
+	case base == ".env", strings.HasPrefix(base, ".env."):
+		return true
+	case strings.HasSuffix(base, ".pyc"), strings.HasSuffix(base, ".sock"), strings.HasSuffix(base, ".pid"):
+		return true
+	case base == ".DS_Store":
+		return true
+	}
+	return false
+}
+
+// createArchive tars and gzips the config directory, with the database and
+// agent data of the data directory at the archive's root. db, when set, is
+// copied with VACUUM INTO so the snapshot is consistent while the daemon
+// writes to it; otherwise the database file is read directly.
+func createArchive(ctx context.Context, dirs config.Dirs, db *sql.DB) ([]byte, error) {
+	configDir := dirs.Config
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+
+	err := filepath.Walk(configDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(configDir, path)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		if excluded(rel, info.IsDir()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		// Symlinks may point outside the config directory
+		if !info.IsDir() && !info.Mode().IsRegular() {
+			return nil
+		}
+		return addFile(tw, path, rel, info)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("archive %s: %w", configDir, err)
+	}
+
+	if err := addDatabase(ctx, tw, dirs.Data, db); err != nil {
+		return nil, err
+	}
+	agentData := filepath.Join(dirs.Data, agentDataName)
+	if info, err := os.Stat(agentData); err == nil {
+		if err := addFile(tw, agentData, agentDataName, info); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func addFile(tw *tar.Writer, path, name string, info os.FileInfo) error {
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	if info.IsDir() {
+		header.Name += "/"
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+func addDatabase(ctx context.Context, tw *tar.Writer, dataDir string, db *sql.DB) error {
+	path := filepath.Join(dataDir, dbName)
+	if db != nil {
+		tmp, err := os.MkdirTemp("", "opperator-backup-")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(tmp)
+		copyPath := filepath.Join(tmp, dbName)
+		if _, err := db.ExecContext(ctx, "VACUUM INTO ?", copyPath); err != nil {
+			return fmt.Errorf("copy database: %w", err)
+		}
+		path = copyPath
+	}
+
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return addFile(tw, path, dbName, info)
+}
+
+// extractArchive unpacks data into the config directory, and the database
+// and agent data into the data directory, overwriting files it contains.
+// Stale WAL files are removed so SQLite does not replay them over the
+// restored database.
+func extractArchive(data []byte, dirs config.Dirs) (int, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return 0, fmt.Errorf("open snapshot: %w", err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+
+	root, err := filepath.Abs(dirs.Config)
+	if err != nil {
+		return 0, err
+	}
+	dataRoot, err := filepath.Abs(dirs.Data)
+	if err != nil {
+		return 0, err
+	}
+	files := 0
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return files, fmt.Errorf("read snapshot: %w", err)
+		}
+
+		target := filepath.Join(root, filepath.FromSlash(header.Name))
+		if target != root && !strings.HasPrefix(target, root+string(os.PathSeparator)) {
+			return files, fmt.Errorf("snapshot entry %q escapes the config directory", header.Name)
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return files, err
+			}
+		case tar.TypeReg:
+			if header.Name == dbName || header.Name == agentDataName {
+				target = filepath.Join(dataRoot, header.Name)
+				if err := os.MkdirAll(dataRoot, 0o755); err != nil {
+					return files, err
+				}
+			}
+			if header.Name == dbName {
+				for _, suffix := range []string{"-wal", "-shm", "-journal"} {
+					os.Remove(target + suffix)
+				}
+			}
+			if err := writeFile(target, tr, os.FileMode(header.Mode).Perm()); err != nil {
+				return files, err
+			}
+			files++
+		}
+	}
+}
+
+func writeFile(path string, r io.Reader, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
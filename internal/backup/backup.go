@@ -0,0 +1,167 @@
+// Package backup snapshots the opperator config directory to S3-compatible
+// object storage and restores it, so a lost server can be rebuilt from its
+// last snapshot.
+package backup
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"opperator/config"
+)
+
+// TimestampFormat names snapshots and is what 'op backup restore' accepts.
+const TimestampFormat = "20060102T150405Z"
+
+const (
+	keyPrefix = "opperator-backup-"
+	keySuffix = ".tar.gz"
+)
+
+// Snapshot is one backup in the bucket.
+type Snapshot struct {
+	Key       string    `json:"key"`
+	Timestamp time.Time `json:"timestamp"`
+	Size      int64     `json:"size"`
+}
+
+// ID is the timestamp that identifies the snapshot on the command line.
+func (s Snapshot) ID() string {
+	return s.Timestamp.UTC().Format(TimestampFormat)
+}
+
+// Run snapshots the config directory and the database and agent data from
+// the data directory, uploads the snapshot and deletes snapshots beyond the
+// retention count. db is the daemon's open database, or nil when no daemon
+// holds it.
+func Run(ctx context.Context, cfg config.BackupConfig, dirs config.Dirs, db *sql.DB) (*Snapshot, error) {
+	st, err := newStore(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := createArchive(ctx, dirs, db)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	snap := &Snapshot{
+		Key:       cfg.Prefix + keyPrefix + now.Format(TimestampFormat) + keySuffix,
+		Timestamp: now,
+		Size:      int64(len(data)),
+	}
+	if err := st.put(ctx, snap.Key, data); err != nil {
+		return nil, fmt.Errorf("upload snapshot: %w", err)
+	}
+
+	if err := prune(ctx, st, cfg); err != nil {
+		// The snapshot itself succeeded; old ones are retried next time
+		return snap, fmt.Errorf("snapshot %s uploaded but pruning failed: %w", snap.ID(), err)
+	}
+	return snap, nil
+}
+
+// List returns the snapshots in the bucket, newest first.
+func List(ctx context.Context, cfg config.BackupConfig) ([]Snapshot, error) {
+	st, err := newStore(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return listSnapshots(ctx, st, cfg)
+}
+
+// Find looks up the snapshot identified by id, or the newest one for
+// "latest", without downloading it.
+func Find(ctx context.Context, cfg config.BackupConfig, id string) (*Snapshot, error) {
+	snaps, err := List(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return findSnapshot(snaps, id)
+}
+
+// Restore downloads the snapshot identified by id, or the newest one for
+// "latest", and unpacks it over dirs. The daemon must be stopped.
+func Restore(ctx context.Context, cfg config.BackupConfig, id string, dirs config.Dirs) (*Snapshot, int, error) {
+	st, err := newStore(cfg)
+	if err != nil {
+		return nil, 0, err
+	}
+	snaps, err := listSnapshots(ctx, st, cfg)
+	if err != nil {
+		return nil, 0, err
+	}
+	snap, err := findSnapshot(snaps, id)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	data, err := st.get(ctx, snap.Key)
+	if err != nil {
+		return nil, 0, fmt.Errorf("download snapshot: %w", err)
+	}
+	files, err := extractArchive(data, dirs)
+	if err != nil {
+		return nil, files, err
+	}
+	return snap, files, nil
+}
+
+func listSnapshots(ctx context.Context, st *store, cfg config.BackupConfig) ([]Snapshot, error) {
+	objects, err := st.list(ctx, cfg.Prefix+keyPrefix)
+	if err != nil {
+		return nil, err
+	}
+	var snaps []Snapshot
+	for _, obj := range objects {
+		name := strings.TrimPrefix(obj.Key, cfg.Prefix+keyPrefix)
+		ts, err := time.Parse(TimestampFormat, strings.TrimSuffix(name, keySuffix))
+		if err != nil || !strings.HasSuffix(name, keySuffix) {
+			continue
+		}
+		snaps = append(snaps, Snapshot{Key: obj.Key, Timestamp: ts, Size: obj.Size})
+	}
+	sort.Slice(snaps, func(i, j int) bool { return snaps[i].Timestamp.After(snaps[j].Timestamp) })
+	return snaps, nil
+}
+
+// findSnapshot accepts "latest", a snapshot ID or an RFC 3339 timestamp.
+func findSnapshot(snaps []Snapshot, id string) (*Snapshot, error) {
+	if len(snaps) == 0 {
+		return nil, fmt.Errorf("no snapshots found in the backup bucket")
+	}
+	if id == "" || id == "latest" {
+		return &snaps[0], nil
+	}
+	ts, err := time.Parse(TimestampFormat, id)
+	if err != nil {
+		ts, err = time.Parse(time.RFC3339, id)
+		if err != nil {
+			return nil, fmt.Errorf("invalid snapshot %q (expected latest, %s or RFC 3339)", id, TimestampFormat)
+		}
+	}
+	for i := range snaps {
+		if snaps[i].Timestamp.Equal(ts.UTC().Truncate(time.Second)) {
+			return &snaps[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no snapshot taken at %s; list them with 'op backup list'", ts.UTC().Format(TimestampFormat))
+}
+
+// prune deletes the oldest snapshots beyond cfg.Retain().
+func prune(ctx context.Context, st *store, cfg config.BackupConfig) error {
+	snaps, err := listSnapshots(ctx, st, cfg)
+	if err != nil {
+		return err
+	}
+	for _, snap := range snaps[min(len(snaps), cfg.Retain()):] {
+		if err := st.delete(ctx, snap.Key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"opperator/config"
+	"opperator/internal/awssig"
+)
+
+var httpClient = &http.Client{Timeout: 10 * time.Minute}
+
+// store reads and writes objects in the configured bucket.
+type store struct {
+	cfg config.BackupConfig
+}
+
+type object struct {
+	Key          string    `xml:"Key"`
+	Size         int64     `xml:"Size"`
+	LastModified time.Time `xml:"LastModified"`
+}
+
+type listBucketResult struct {
+	Contents              []object `xml:"Contents"`
+	IsTruncated           bool     `xml:"IsTruncated"`
+	NextContinuationToken string   `xml:"NextContinuationToken"`
+}
+
+func newStore(cfg config.BackupConfig) (*store, error) {
+	if !cfg.Enabled() {
+		return nil, fmt.Errorf("no backup bucket configured; set backup.bucket in daemon.yaml")
+	}
+	if err := awssig.CheckEnv(); err != nil {
+		return nil, fmt.Errorf("%w for backups", err)
+	}
+	return &store{cfg: cfg}, nil
+}
+
+// bucketURL addresses the bucket path-style on a custom endpoint and
+// virtual-hosted style on AWS.
+func (s *store) bucketURL() string {
+	if s.cfg.Endpoint != "" {
+		return strings.TrimRight(s.cfg.Endpoint, "/") + "/" + s.cfg.Bucket
+	}
+	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com", s.cfg.Bucket, s.cfg.Region)
+}
+
+func (s *store) objectURL(key string) string {
+	return s.bucketURL() + "/" + (&url.URL{Path: key}).EscapedPath()
+}
+
+func (s *store) do(ctx context.Context, method, rawURL string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/gzip")
+	}
+	awssig.Sign(req, body, "s3", s.cfg.Region, time.Now())
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("s3 %s failed: %s: %s", strings.ToLower(method), resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return resp, nil
+}
+
+func (s *store) put(ctx context.Context, key string, data []byte) error {
+	resp, err := s.do(ctx, http.MethodPut, s.objectURL(key), data)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (s *store) get(ctx context.Context, key string) ([]byte, error) {
+	resp, err := s.do(ctx, http.MethodGet, s.objectURL(key), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+func (s *store) delete(ctx context.Context, key string) error {
+	resp, err := s.do(ctx, http.MethodDelete, s.objectURL(key), nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// list returns every object under prefix, following continuation tokens.
+func (s *store) list(ctx context.Context, prefix string) ([]object, error) {
+	var objects []object
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		// SigV4 wants spaces as %20 in the canonical query
+		rawQuery := strings.ReplaceAll(query.Encode(), "+", "%20")
+		resp, err := s.do(ctx, http.MethodGet, s.bucketURL()+"/?"+rawQuery, nil)
+		if err != nil {
+			return nil, err
+		}
+		var page listBucketResult
+		err = xml.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("s3 list: %w", err)
+		}
+		objects = append(objects, page.Contents...)
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return objects, nil
+		}
+		token = page.NextContinuationToken
+	}
+}
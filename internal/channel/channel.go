@@ -0,0 +1,303 @@
+// Package channel connects chat transports such as Slack, Telegram and email
+// to agents. A transport implements Channel; the Router runs it, keeps each
+// thread in order, and hands messages to a Conversations implementation
+// that talks to the agent.
+package channel
+
+import (
+	"context"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	defaultTimeout = 10 * time.Minute
+	minBackoff     = 2 * time.Second
+	maxBackoff     = 2 * time.Minute
+	// maxConcurrent bounds exchanges running at once across all channels.
+	maxConcurrent = 4
+)
+
+// Message is an inbound chat message.
+type Message struct {
+	// Thread groups the messages that share a conversation, such as a
+	// Slack thread, a Telegram chat or an email thread.
+	Thread string
+	Sender string
+	Text   string
+	// Agent overrides the channel's default agent when set.
+	Agent string
+	// Reset asks for the thread's conversation to be forgotten instead of
+	// answering Text.
+	Reset bool
+	// Reply is what the channel needs to answer the message, for example
+	// the original email.
+	Reply any
+}
+
+// Channel is a chat transport.
+type Channel interface {
+	// Receive delivers inbound messages until ctx is cancelled or the
+	// transport fails. deliver blocks until the message has been answered
+	// and only fails when ctx was cancelled first.
+	Receive(ctx context.Context, deliver func(ctx context.Context, msg Message) error) error
+	// Send answers msg with text.
+	Send(ctx context.Context, msg Message, text string) error
+	// Progress starts reporting that msg is being worked on.
+	Progress(ctx context.Context, msg Message) Progress
+}
+
+// Progress shows an exchange's state to the sender.
+type Progress interface {
+	// Update reports one progress line, such as a tool being run.
+	Update(line string)
+	// Done ends the report; err is nil when the agent answered.
+	Done(err error)
+}
+
+// Conversations maps threads to agent conversations.
+type Conversations interface {
+	// Respond sends msg to agent in the conversation of the channel's
+	// thread and returns the final answer.
+	Respond(ctx context.Context, channel, agent string, msg Message, progress func(string)) (string, error)
+	// Reset forgets the conversation of a thread.
+	Reset(ctx context.Context, channel, thread string) error
+}
+
+// Spec describes a channel added to the Router.
+type Spec struct {
+	Name    string
+	Type    string
+	Agent   string
+	Source  string
+	Timeout time.Duration
+}
+
+// Status reports a channel for 'op channel list'.
+type Status struct {
+	Spec
+	Connected   bool
+	Messages    int64
+	LastMessage time.Time
+	LastError   string
+}
+
+type running struct {
+	spec    Spec
+	channel Channel
+	cancel  context.CancelFunc
+	done    chan struct{}
+
+	mu          sync.Mutex
+	connected   bool
+	messages    int64
+	lastMessage time.Time
+	lastError   string
+}
+
+// Router runs channels and routes their messages to agents.
+type Router struct {
+	conversations Conversations
+	slots         chan struct{}
+
+	mu       sync.Mutex
+	channels map[string]*running
+	threads  map[string]*sync.Mutex
+}
+
+func NewRouter(conversations Conversations) *Router {
+	return &Router{
+		conversations: conversations,
+		slots:         make(chan struct{}, maxConcurrent),
+		channels:      make(map[string]*running),
+		threads:       make(map[string]*sync.Mutex),
+	}
+}
+
+// Add starts a channel, replacing any channel of the same name.
+func (r *Router) Add(spec Spec, ch Channel) {
+	if spec.Timeout <= 0 {
+		spec.Timeout = defaultTimeout
+	}
+	r.Remove(spec.Name)
+	ctx, cancel := context.WithCancel(context.Background())
+	run := &running{spec: spec, channel: ch, cancel: cancel, done: make(chan struct{})}
+	r.mu.Lock()
+	r.channels[spec.Name] = run
+	r.mu.Unlock()
+	go r.run(ctx, run)
+	log.Printf("[Channel] %s (%s) routing messages to agent %s", spec.Name, spec.Type, spec.Agent)
+}
+
+// Remove stops a channel and waits for its in-flight exchanges to end. It
+// reports whether the channel existed.
+func (r *Router) Remove(name string) bool {
+	r.mu.Lock()
+	run, ok := r.channels[name]
+	delete(r.channels, name)
+	r.mu.Unlock()
+	if !ok {
+		return false
+	}
+	run.cancel()
+	<-run.done
+	return true
+}
+
+// Get returns the spec of a running channel.
+func (r *Router) Get(name string) (Spec, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	run, ok := r.channels[name]
+	if !ok {
+		return Spec{}, false
+	}
+	return run.spec, true
+}
+
+// List returns every channel sorted by name.
+func (r *Router) List() []Status {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]Status, 0, len(r.channels))
+	for _, run := range r.channels {
+		run.mu.Lock()
+		out = append(out, Status{
+			Spec:        run.spec,
+			Connected:   run.connected,
+			Messages:    run.messages,
+			LastMessage: run.lastMessage,
+			LastError:   run.lastError,
+		})
+		run.mu.Unlock()
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Stop stops every channel.
+func (r *Router) Stop() {
+	r.mu.Lock()
+	names := make([]string, 0, len(r.channels))
+	for name := range r.channels {
+		names = append(names, name)
+	}
+	r.mu.Unlock()
+	for _, name := range names {
+		r.Remove(name)
+	}
+}
+
+// run keeps a channel receiving, restarting it with backoff after failures.
+func (r *Router) run(ctx context.Context, run *running) {
+	defer close(run.done)
+	deliver := func(ctx context.Context, msg Message) error {
+		return r.deliver(ctx, run, msg)
+	}
+	backoff := minBackoff
+	for ctx.Err() == nil {
+		started := time.Now()
+		run.setConnected(true, "")
+		err := run.channel.Receive(ctx, deliver)
+		if ctx.Err() != nil {
+			return
+		}
+		errText := ""
+		if err != nil {
+			errText = err.Error()
+			log.Printf("[Channel] %s failed: %v (retrying in %s)", run.spec.Name, err, backoff)
+		}
+		run.setConnected(false, errText)
+		if time.Since(started) > maxBackoff {
+			backoff = minBackoff
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+// deliver answers one message. Messages of a thread are answered in order.
+func (r *Router) deliver(ctx context.Context, run *running, msg Message) error {
+	key := run.spec.Name + "/" + msg.Thread
+	r.mu.Lock()
+	lock, ok := r.threads[key]
+	if !ok {
+		lock = &sync.Mutex{}
+		r.threads[key] = lock
+	}
+	r.mu.Unlock()
+	lock.Lock()
+	defer lock.Unlock()
+
+	if msg.Reset {
+		text := "Started a new conversation."
+		if err := r.conversations.Reset(ctx, run.spec.Name, msg.Thread); err != nil {
+			text = "Could not start a new conversation: " + err.Error()
+		}
+		r.send(ctx, run, msg, text)
+		return ctx.Err()
+	}
+
+	select {
+	case r.slots <- struct{}{}:
+		defer func() { <-r.slots }()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	run.mu.Lock()
+	run.messages++
+	run.lastMessage = time.Now()
+	run.mu.Unlock()
+
+	agent := msg.Agent
+	if agent == "" {
+		agent = run.spec.Agent
+	}
+	progress := run.channel.Progress(ctx, msg)
+	rctx, cancel := context.WithTimeout(ctx, run.spec.Timeout)
+	reply, err := r.conversations.Respond(rctx, run.spec.Name, agent, msg, progress.Update)
+	cancel()
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	progress.Done(err)
+	if err != nil {
+		log.Printf("[Channel] %s: agent %s failed on message from %s: %v", run.spec.Name, agent, msg.Sender, err)
+		run.setError(err.Error())
+		return nil
+	}
+	r.send(ctx, run, msg, reply)
+	return nil
+}
+
+func (r *Router) send(ctx context.Context, run *running, msg Message, text string) {
+	if text == "" {
+		return
+	}
+	if err := run.channel.Send(ctx, msg, text); err != nil {
+		log.Printf("[Channel] %s: failed to answer %s: %v", run.spec.Name, msg.Sender, err)
+		run.setError(err.Error())
+	}
+}
+
+func (run *running) setConnected(connected bool, errText string) {
+	run.mu.Lock()
+	run.connected = connected
+	if errText != "" {
+		run.lastError = errText
+	}
+	run.mu.Unlock()
+}
+
+func (run *running) setError(errText string) {
+	run.mu.Lock()
+	run.lastError = errText
+	run.mu.Unlock()
+}
@@ -0,0 +1,240 @@
+package cli
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"opperator/config"
+	"opperator/internal/agent"
+	"opperator/internal/ipc"
+	"opperator/pkg/prototrace"
+)
+
+// devDebounce is how long source changes must settle before the agent is
+// restarted, so a save touching several files restarts it once.
+const devDebounce = 300 * time.Millisecond
+
+// devPollInterval is how often logs and protocol frames are fetched.
+const devPollInterval = 500 * time.Millisecond
+
+// devFrameWidth bounds a frame printed to the terminal; the frames file has
+// them in full.
+const devFrameWidth = 160
+
+// Directories and files in an agent's source that never trigger a restart:
+// dependencies, caches and what the agent writes at runtime.
+var (
+	devIgnoredDirs = map[string]bool{
+		".git": true, ".venv": true, "venv": true, "node_modules": true,
+		"__pycache__": true, ".mypy_cache": true, ".pytest_cache": true,
+	}
+	devIgnoredExts = map[string]bool{
+		".pyc": true, ".log": true, ".db": true, ".sqlite": true, ".jsonl": true, ".swp": true,
+	}
+)
+
+// AgentDev runs a local agent in the foreground for development: it starts
+// the agent on the local daemon, restarts it whenever its source directory
+// changes, and prints its logs and protocol messages until interrupted. The
+// protocol messages are also written in full to framesFile as JSON lines,
+// by default <logs dir>/<name>.frames.jsonl. quiet keeps them off the
+// terminal. An agent the command started is stopped when it exits.
+func AgentDev(name, framesFile string, quiet bool) error {
+	cfg, err := loadLocalAgentConfig(name)
+	if err != nil {
+		return err
+	}
+	dir, err := cfg.WorkingDir()
+	if err != nil {
+		return err
+	}
+
+	client, err := ipc.NewClientFromRegistry("local")
+	if err != nil {
+		return fmt.Errorf("failed to connect to the local daemon: %w", err)
+	}
+	defer client.Close()
+
+	if framesFile == "" {
+		logsDir, err := config.GetLogsDir()
+		if err != nil {
+			return err
+		}
+		framesFile = filepath.Join(logsDir, name+".frames.jsonl")
+	}
+	frames, err := os.Create(framesFile)
+	if err != nil {
+		return fmt.Errorf("create frames file: %w", err)
+	}
+	defer frames.Close()
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("watch %s: %w", dir, err)
+	}
+	defer watcher.Close()
+	if err := watchSourceTree(watcher, dir); err != nil {
+		return fmt.Errorf("watch %s: %w", dir, err)
+	}
+
+	// Only show what happens from here on
+	logs, err := client.GetLogs(name)
+	if err != nil {
+		return err
+	}
+	logCount := len(logs)
+	var frameSeq uint64
+	if existing, err := client.ProtocolFrames(name, 0); err == nil && len(existing) > 0 {
+		frameSeq = existing[len(existing)-1].Seq
+	}
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigChan)
+
+	fmt.Printf("Developing '%s' from %s\n", name, dir)
+	fmt.Printf("Protocol frames: %s\n", framesFile)
+	fmt.Println("--- Restarting on source changes (Press Ctrl+C to exit) ---")
+
+	startedHere := false
+	if !agentRunning(client, name) {
+		if err := client.StartAgent(name); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to start '%s': %v\n", name, err)
+		} else {
+			startedHere = true
+		}
+	}
+
+	ticker := time.NewTicker(devPollInterval)
+	defer ticker.Stop()
+	restart := time.NewTimer(devDebounce)
+	restart.Stop()
+	var changed string
+
+	for {
+		select {
+		case <-sigChan:
+			if startedHere {
+				if _, err := client.StopAgent(name); err != nil {
+					fmt.Fprintf(os.Stderr, "\nFailed to stop '%s': %v\n", name, err)
+				}
+			}
+			fmt.Printf("\nStopped developing '%s'\n", name)
+			return nil
+
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if ignoredSourcePath(dir, event.Name) {
+				continue
+			}
+			if event.Op&fsnotify.Create != 0 {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					_ = watchSourceTree(watcher, event.Name)
+				}
+			}
+			if rel, err := filepath.Rel(dir, event.Name); err == nil {
+				changed = rel
+			}
+			restart.Reset(devDebounce)
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			fmt.Fprintf(os.Stderr, "Watch error: %v\n", err)
+
+		case <-restart.C:
+			fmt.Printf("--- %s changed, restarting '%s' ---\n", changed, name)
+			if err := client.RestartAgent(name); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to restart '%s': %v\n", name, err)
+			} else {
+				startedHere = true
+			}
+
+		case <-ticker.C:
+			newLogs, err := client.GetLogs(name)
+			if err != nil {
+				fmt.Printf("Error fetching logs: %v\n", err)
+				continue
+			}
+			if len(newLogs) < logCount {
+				logCount = 0
+			}
+			for _, line := range newLogs[logCount:] {
+				fmt.Println(line)
+			}
+			logCount = len(newLogs)
+
+			newFrames, err := client.ProtocolFrames(name, frameSeq)
+			if err != nil {
+				fmt.Printf("Error fetching protocol frames: %v\n", err)
+				continue
+			}
+			if len(newFrames) > 0 {
+				frameSeq = newFrames[len(newFrames)-1].Seq
+			}
+			if err := prototrace.Write(frames, newFrames); err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing protocol frames: %v\n", err)
+			}
+			if !quiet {
+				for _, frame := range newFrames {
+					fmt.Println(prototrace.Line(frame, devFrameWidth))
+				}
+			}
+		}
+	}
+}
+
+// agentRunning reports whether the local daemon runs the agent name.
+func agentRunning(client *ipc.Client, name string) bool {
+	processes, err := client.ListAgents()
+	if err != nil {
+		return false
+	}
+	for _, p := range processes {
+		if p.Name == name {
+			return p.Status == agent.StatusRunning
+		}
+	}
+	return false
+}
+
+// watchSourceTree watches root and the directories below it that may hold
+// source.
+func watchSourceTree(watcher *fsnotify.Watcher, root string) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if path != root && devIgnoredDirs[d.Name()] {
+			return filepath.SkipDir
+		}
+		return watcher.Add(path)
+	})
+}
+
+// ignoredSourcePath reports whether a change to path should not restart
+// the agent.
+func ignoredSourcePath(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return true
+	}
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		if devIgnoredDirs[part] {
+			return true
+		}
+	}
+	base := filepath.Base(path)
+	return strings.HasPrefix(base, ".") || strings.HasSuffix(base, "~") || devIgnoredExts[filepath.Ext(base)]
+}
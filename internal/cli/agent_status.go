@@ -0,0 +1,190 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+
+	"opperator/internal/agent"
+	"opperator/internal/ipc"
+	"opperator/internal/protocol"
+	"tui/components/sidebar"
+)
+
+// agentStatusReport is what 'op agent status' reports about an agent.
+type agentStatusReport struct {
+	Name         string                       `json:"name"`
+	Daemon       string                       `json:"daemon"`
+	Description  string                       `json:"description,omitempty"`
+	Status       agent.ProcessStatus          `json:"status"`
+	PID          int                          `json:"pid,omitempty"`
+	Uptime       int64                        `json:"uptime_seconds"`
+	RestartCount int                          `json:"restart_count"`
+	Color        string                       `json:"color,omitempty"`
+	LastExit     *agent.ExitInfo              `json:"last_exit,omitempty"`
+	Paused       *agent.PauseInfo             `json:"paused,omitempty"`
+	Commands     []protocol.CommandDescriptor `json:"commands"`
+	Sections     []agentStatusSection         `json:"sections"`
+}
+
+// agentStatusSection is a custom section with its content as plain text.
+type agentStatusSection struct {
+	sidebar.CustomSection
+	Text string `json:"text"`
+}
+
+// AgentStatus prints an agent's description, status, commands and custom
+// sidebar sections, the information the TUI shows in its sidebar.
+func AgentStatus(name, daemonName string, jsonOutput bool) error {
+	client, foundDaemon, err := getClientForAgent(name, daemonName)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	processes, err := client.ListAgents()
+	if err != nil {
+		return err
+	}
+	var info *ipc.ProcessInfo
+	for _, p := range processes {
+		if p.Name == name {
+			info = p
+			break
+		}
+	}
+	if info == nil {
+		return fmt.Errorf("agent '%s' not found on daemon '%s'", name, foundDaemon)
+	}
+
+	report := agentStatusReport{
+		Name:         info.Name,
+		Daemon:       foundDaemon,
+		Description:  strings.TrimSpace(info.Description),
+		Status:       info.Status,
+		PID:          info.PID,
+		Uptime:       info.Uptime,
+		RestartCount: info.RestartCount,
+		Color:        info.Color,
+		LastExit:     info.LastExit,
+		Paused:       info.Paused,
+		Commands:     []protocol.CommandDescriptor{},
+		Sections:     []agentStatusSection{},
+	}
+
+	// A stopped agent that never registered commands can't be asked for them
+	commands, err := client.ListCommands(name)
+	if err != nil && info.Status == agent.StatusRunning {
+		return fmt.Errorf("failed to list commands: %w", err)
+	}
+	if commands != nil {
+		report.Commands = commands
+	}
+
+	sections, err := client.GetCustomSections(name)
+	if err != nil {
+		return fmt.Errorf("failed to get custom sections: %w", err)
+	}
+	sort.Slice(sections, func(i, j int) bool { return sections[i].ID < sections[j].ID })
+	for _, section := range sections {
+		report.Sections = append(report.Sections, agentStatusSection{
+			CustomSection: section,
+			Text:          strings.TrimSpace(sidebar.StripMarkup(section.Content)),
+		})
+	}
+
+	if jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.SetEscapeHTML(false)
+		return enc.Encode(report)
+	}
+
+	printAgentStatus(report)
+	return nil
+}
+
+func printAgentStatus(report agentStatusReport) {
+	styled := term.IsTerminal(int(os.Stdout.Fd()))
+
+	fmt.Printf("%s (daemon: %s)\n", report.Name, report.Daemon)
+	status := string(report.Status)
+	if report.PID > 0 {
+		status += fmt.Sprintf(" (pid %d", report.PID)
+		if report.Uptime > 0 {
+			status += fmt.Sprintf(", up %s", time.Duration(report.Uptime)*time.Second)
+		}
+		status += ")"
+	}
+	fmt.Printf("  Status:      %s\n", status)
+	if report.Paused != nil {
+		paused := "since " + report.Paused.Since.Local().Format("2006-01-02 15:04")
+		if report.Paused.Reason != "" {
+			paused += ": " + report.Paused.Reason
+		}
+		fmt.Printf("  Paused:      %s\n", paused)
+	}
+	if report.Description != "" {
+		fmt.Printf("  Description: %s\n", report.Description)
+	}
+	if report.Color != "" {
+		fmt.Printf("  Color:       %s\n", report.Color)
+	}
+	if report.RestartCount > 0 {
+		fmt.Printf("  Restarts:    %d\n", report.RestartCount)
+	}
+	if report.LastExit != nil {
+		fmt.Printf("  Last exit:   %s\n", report.LastExit.String())
+	}
+
+	fmt.Println()
+	if len(report.Commands) == 0 {
+		fmt.Println("No registered commands")
+	} else {
+		fmt.Println("Commands:")
+		for _, cmd := range report.Commands {
+			line := "  - " + cmd.Name
+			if desc := strings.TrimSpace(cmd.Description); desc != "" {
+				line += " — " + desc
+			}
+			if slash := strings.TrimSpace(cmd.SlashCommand); slash != "" {
+				line += fmt.Sprintf(" (slash: %s)", slash)
+			}
+			fmt.Println(line)
+		}
+	}
+
+	for _, section := range report.Sections {
+		title := section.Title
+		if title == "" {
+			title = section.ID
+		}
+		fmt.Println()
+		if section.Type == sidebar.SectionTypeDashboard {
+			fmt.Printf("%s: dashboard at /dashboards/%s/%s\n", title, url.PathEscape(report.Name), url.PathEscape(section.ID))
+			continue
+		}
+		fmt.Printf("%s:\n", title)
+
+		content := section.Text
+		if styled {
+			content = sidebar.ParseMarkup(strings.TrimSpace(section.Content))
+		}
+		for _, line := range strings.Split(content, "\n") {
+			fmt.Println("  " + line)
+		}
+		for _, action := range section.Actions {
+			if action.Type == sidebar.SectionActionInput {
+				fmt.Printf("  [ %s… ] → %s %s=<text>\n", action.Label, action.Command, action.Arg)
+				continue
+			}
+			fmt.Printf("  [ %s ] → %s\n", action.Label, action.Command)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"opperator/pkg/prototrace"
+)
+
+// TraceOptions selects what AgentTrace shows.
+type TraceOptions struct {
+	Daemon string
+	// Types limits the trace to these message types
+	Types  []string
+	Follow bool
+	// Save appends every frame shown to this file as JSON lines
+	Save string
+	// File reads a saved trace, from Save or op agent dev, instead of asking
+	// the daemon
+	File string
+}
+
+// AgentTrace pretty-prints the protocol messages recently exchanged between
+// the daemon and the agent name, oldest first, and with Follow keeps
+// printing new ones until interrupted.
+func AgentTrace(name string, opts TraceOptions) error {
+	if opts.File != "" {
+		f, err := os.Open(opts.File)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		frames, err := prototrace.Read(f)
+		if err != nil {
+			return fmt.Errorf("read %s: %w", opts.File, err)
+		}
+		printFrames(frames, opts.Types)
+		return nil
+	}
+
+	client, foundDaemon, err := getClientForAgent(name, opts.Daemon)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	var save *os.File
+	if opts.Save != "" {
+		save, err = os.OpenFile(opts.Save, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			return fmt.Errorf("open %s: %w", opts.Save, err)
+		}
+		defer save.Close()
+	}
+
+	var seq uint64
+	show := func() error {
+		frames, err := client.ProtocolFrames(name, seq)
+		if err != nil {
+			return err
+		}
+		if len(frames) > 0 {
+			seq = frames[len(frames)-1].Seq
+		}
+		shown := printFrames(frames, opts.Types)
+		if save != nil {
+			return prototrace.Write(save, shown)
+		}
+		return nil
+	}
+
+	if err := show(); err != nil {
+		return err
+	}
+	if seq == 0 && !opts.Follow {
+		fmt.Printf("No protocol messages recorded for '%s' on daemon '%s' since the daemon started\n", name, foundDaemon)
+		return nil
+	}
+	if !opts.Follow {
+		return nil
+	}
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigChan)
+
+	fmt.Printf("--- Tracing %s on daemon '%s' (Press Ctrl+C to exit) ---\n", name, foundDaemon)
+	ticker := time.NewTicker(devPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-sigChan:
+			fmt.Printf("\nStopping trace of %s\n", name)
+			return nil
+		case <-ticker.C:
+			if err := show(); err != nil {
+				fmt.Printf("Error fetching protocol frames: %v\n", err)
+			}
+		}
+	}
+}
+
+// printFrames prints the frames of one of types and returns them.
+func printFrames(frames []prototrace.Frame, types []string) []prototrace.Frame {
+	var shown []prototrace.Frame
+	for _, frame := range frames {
+		if !prototrace.Matches(frame, types) {
+			continue
+		}
+		fmt.Println(prototrace.Pretty(frame))
+		shown = append(shown, frame)
+	}
+	return shown
+}
@@ -128,6 +128,16 @@ func MoveAgent(agentName, toDaemon string, force, noStart bool) error {
 			return fmt.Errorf("failed to package agent: %w", err)
 		}
 
+		// The agent's key-value state is kept in the local daemon's database
+		if socketPath, err := config.GetSocketPath(); err == nil {
+			if localClient, err := ipc.NewClient(socketPath); err == nil {
+				if pkg.KV, err = localClient.KVList(agentName, ""); err != nil {
+					fmt.Printf("Warning: failed to read the agent's key-value state: %v\n", err)
+				}
+				localClient.Close()
+			}
+		}
+
 		fmt.Println("✓ Packaged agent from local daemon")
 	} else {
 		// Package from remote daemon
@@ -172,6 +182,11 @@ func MoveAgent(agentName, toDaemon string, force, noStart bool) error {
 				if err := localClient.ReloadConfig(); err != nil {
 					fmt.Printf("Warning: failed to reload config: %v\n", err)
 				}
+				for _, entry := range pkg.KV {
+					if err := localClient.KVSet(agentName, entry.Key, entry.Value); err != nil {
+						fmt.Printf("Warning: failed to restore key '%s': %v\n", entry.Key, err)
+					}
+				}
 				localClient.Close()
 			}
 		}
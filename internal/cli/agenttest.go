@@ -0,0 +1,105 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"opperator/pkg/agenttest"
+)
+
+// TestAgent starts an agent from the local agents config under an isolated
+// daemon with its own temporary config dir, and runs the cases of the tests
+// file against it. Without a tests file it only checks the agent starts and
+// registers its commands.
+func TestAgent(name, testsPath string, timeout time.Duration) error {
+	cfg, err := loadLocalAgentConfig(name)
+	if err != nil {
+		return err
+	}
+
+	var suite *agenttest.Suite
+	if testsPath != "" {
+		if suite, err = agenttest.LoadSuite(testsPath); err != nil {
+			return err
+		}
+	}
+
+	binary, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate op binary: %w", err)
+	}
+
+	fmt.Printf("Starting %s under an isolated daemon...\n", name)
+	started := time.Now()
+	h, err := agenttest.Start(context.Background(), agenttest.Agent{
+		Name:        cfg.Name,
+		Command:     cfg.Command,
+		Args:        cfg.Args,
+		ProcessRoot: cfg.ProcessRoot,
+		Env:         cfg.Env,
+		Runtime:     cfg.Runtime,
+	}, agenttest.Options{
+		Binary:        binary,
+		StartTimeout:  timeout,
+		InvokeTimeout: timeout,
+	})
+	if err != nil {
+		return fmt.Errorf("agent failed to start: %w", err)
+	}
+	defer h.Close()
+
+	commands, err := h.Commands()
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(commands))
+	for _, c := range commands {
+		names = append(names, c.Name)
+	}
+	registered := "no commands"
+	if len(names) > 0 {
+		registered = strings.Join(names, ", ")
+	}
+	fmt.Printf("✓ started in %.1fs (%s)\n", time.Since(started).Seconds(), registered)
+
+	if suite == nil {
+		return nil
+	}
+
+	failed := 0
+	for _, c := range suite.Tests {
+		result := h.Run(context.Background(), c)
+		if result.Passed() {
+			fmt.Printf("✓ %s (%.1fs)\n", c.Name, result.Result.Duration.Seconds())
+			continue
+		}
+		failed++
+		fmt.Printf("✗ %s (%.1fs)\n", c.Name, result.Result.Duration.Seconds())
+		for _, f := range result.Failures {
+			fmt.Printf("    %s\n", f)
+		}
+		if text := result.Result.Text(); text != "" {
+			text = strings.Join(strings.Fields(text), " ")
+			if len(text) > 200 {
+				text = text[:197] + "..."
+			}
+			fmt.Printf("    output: %s\n", text)
+		}
+	}
+
+	fmt.Printf("\n%s: %d/%d passed\n", name, len(suite.Tests)-failed, len(suite.Tests))
+	if failed > 0 {
+		if logs, err := h.Logs(); err == nil && len(logs) > 0 {
+			start := max(len(logs)-20, 0)
+			fmt.Println("\nAgent logs:")
+			for _, line := range logs[start:] {
+				fmt.Println("  " + line)
+			}
+		}
+		return fmt.Errorf("%d of %d tests failed", failed, len(suite.Tests))
+	}
+	return nil
+}
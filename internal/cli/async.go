@@ -2,10 +2,13 @@ package cli
 
 import (
 	"fmt"
+	"os"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
+	"opperator/config"
 	"opperator/internal/ipc"
 )
 
@@ -14,36 +17,128 @@ type AsyncListOptions struct {
 	Origin  string
 	Session string
 	Client  string
+	// Daemon is the daemon to ask; every enabled daemon when empty
+	Daemon string
+	// AllUsers lists other users' tasks on a shared daemon (admin only)
+	AllUsers bool
 }
 
-func ListAsyncTasks(opts AsyncListOptions) error {
-	client, err := ipc.NewClientFromRegistry("local")
+// daemonTasks is the tasks of one daemon, or the error that kept them from
+// being listed.
+type daemonTasks struct {
+	daemon string
+	tasks  []*ipc.ToolTask
+	err    error
+}
+
+// selectDaemons returns the named daemon, or every enabled daemon when name
+// is empty.
+func selectDaemons(name string) ([]config.DaemonConfig, error) {
+	registry, err := config.LoadDaemonRegistry()
 	if err != nil {
-		if strings.Contains(err.Error(), "connection refused") || strings.Contains(err.Error(), "no such file") {
-			return fmt.Errorf("daemon is not running. Start it with: op daemon start")
+		return nil, fmt.Errorf("failed to load daemon registry: %w", err)
+	}
+	if name != "" {
+		daemon, err := registry.GetDaemon(name)
+		if err != nil {
+			return nil, err
 		}
-		return err
+		if !daemon.Enabled {
+			return nil, fmt.Errorf("daemon '%s' is disabled", name)
+		}
+		return []config.DaemonConfig{*daemon}, nil
 	}
-	defer client.Close()
+	var daemons []config.DaemonConfig
+	for _, daemon := range registry.Daemons {
+		if daemon.Enabled {
+			daemons = append(daemons, daemon)
+		}
+	}
+	if len(daemons) == 0 {
+		return nil, fmt.Errorf("no enabled daemons. Add one with: op daemon add")
+	}
+	return daemons, nil
+}
 
-	tasks, err := client.ListToolTasks()
+// collectAsyncTasks lists the tasks of the named daemon, or of every enabled
+// daemon in parallel when name is empty.
+func collectAsyncTasks(name string, allUsers bool) ([]daemonTasks, error) {
+	daemons, err := selectDaemons(name)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]daemonTasks, len(daemons))
+	var wg sync.WaitGroup
+	for i, daemon := range daemons {
+		results[i].daemon = daemon.Name
+		wg.Add(1)
+		go func(result *daemonTasks, daemon config.DaemonConfig) {
+			defer wg.Done()
+			client, err := ipc.NewClientWithAuth(daemon.Address, daemon.AuthToken)
+			if err != nil {
+				result.err = err
+				return
+			}
+			defer client.Close()
+			result.tasks, result.err = client.ListToolTasks(allUsers)
+		}(&results[i], daemon)
+	}
+	wg.Wait()
+	return results, nil
+}
+
+func ListAsyncTasks(opts AsyncListOptions) error {
+	results, err := collectAsyncTasks(strings.TrimSpace(opts.Daemon), opts.AllUsers)
 	if err != nil {
 		return err
 	}
+
+	type taskWithDaemon struct {
+		*ipc.ToolTask
+		daemon string
+	}
+	var tasks []taskWithDaemon
+	var unreachable []string
+	for _, result := range results {
+		if result.err != nil {
+			if len(results) == 1 {
+				if result.daemon == "local" && ipc.IsCode(result.err, ipc.ErrCodeUnavailable) {
+					return fmt.Errorf("daemon is not running. Start it with: op daemon start")
+				}
+				return result.err
+			}
+			fmt.Fprintf(os.Stderr, "Warning: could not list tasks of daemon '%s': %v\n", result.daemon, result.err)
+			unreachable = append(unreachable, result.daemon)
+			continue
+		}
+		for _, task := range result.tasks {
+			if task != nil {
+				tasks = append(tasks, taskWithDaemon{ToolTask: task, daemon: result.daemon})
+			}
+		}
+	}
+	if len(unreachable) == len(results) {
+		return fmt.Errorf("no daemon could be reached")
+	}
+	partial := ""
+	if len(unreachable) > 0 {
+		partial = fmt.Sprintf("Partial results: daemon(s) %s could not be reached", strings.Join(unreachable, ", "))
+	}
 	if len(tasks) == 0 {
 		fmt.Println("No async tasks recorded")
+		if partial != "" {
+			fmt.Println(partial)
+		}
 		return nil
 	}
 
-	filtered := make([]*ipc.ToolTask, 0, len(tasks))
+	filtered := make([]taskWithDaemon, 0, len(tasks))
 	statusFilter := strings.ToLower(strings.TrimSpace(opts.Status))
 	originFilter := strings.ToLower(strings.TrimSpace(opts.Origin))
 	sessionFilter := strings.TrimSpace(opts.Session)
 	clientFilter := strings.TrimSpace(opts.Client)
 	for _, task := range tasks {
-		if task == nil {
-			continue
-		}
 		if statusFilter != "" && strings.ToLower(strings.TrimSpace(task.Status)) != statusFilter {
 			continue
 		}
@@ -60,6 +155,9 @@ func ListAsyncTasks(opts AsyncListOptions) error {
 	}
 	if len(filtered) == 0 {
 		fmt.Println("No async tasks matched the provided filters")
+		if partial != "" {
+			fmt.Println(partial)
+		}
 		return nil
 	}
 
@@ -77,8 +175,8 @@ func ListAsyncTasks(opts AsyncListOptions) error {
 		return jt.Before(it)
 	})
 
-	fmt.Printf("%-36s %-10s %-8s %-8s %-8s %-10s %-10s %-20s\n", "TASK ID", "STATUS", "ORIGIN", "CLIENT", "SESSION", "CALL", "MODE", "TOOL")
-	fmt.Printf("%-36s %-10s %-8s %-8s %-8s %-10s %-10s %-20s\n", strings.Repeat("-", 36), strings.Repeat("-", 10), strings.Repeat("-", 8), strings.Repeat("-", 8), strings.Repeat("-", 8), strings.Repeat("-", 10), strings.Repeat("-", 10), strings.Repeat("-", 20))
+	fmt.Printf("%-36s %-12s %-10s %-8s %-8s %-8s %-8s %-10s %-10s %-20s\n", "TASK ID", "DAEMON", "STATUS", "OWNER", "ORIGIN", "CLIENT", "SESSION", "CALL", "MODE", "TOOL")
+	fmt.Printf("%-36s %-12s %-10s %-8s %-8s %-8s %-8s %-10s %-10s %-20s\n", strings.Repeat("-", 36), strings.Repeat("-", 12), strings.Repeat("-", 10), strings.Repeat("-", 8), strings.Repeat("-", 8), strings.Repeat("-", 8), strings.Repeat("-", 8), strings.Repeat("-", 10), strings.Repeat("-", 10), strings.Repeat("-", 20))
 
 	for _, task := range filtered {
 		status := strings.TrimSpace(task.Status)
@@ -97,7 +195,11 @@ func ListAsyncTasks(opts AsyncListOptions) error {
 		if tool == "" {
 			tool = "-"
 		}
-		fmt.Printf("%-36s %-10s %-8s %-8s %-8s %-10s %-10s %-20s\n", task.ID, status, origin, client, session, call, mode, tool)
+		fmt.Printf("%-36s %-12s %-10s %-8s %-8s %-8s %-8s %-10s %-10s %-20s\n", task.ID, task.daemon, status, orDash(task.Owner), origin, client, session, call, mode, tool)
+	}
+	if partial != "" {
+		fmt.Println()
+		fmt.Println(partial)
 	}
 
 	return nil
@@ -106,7 +208,7 @@ func ListAsyncTasks(opts AsyncListOptions) error {
 func ShowAsyncTask(id string) error {
 	client, err := ipc.NewClientFromRegistry("local")
 	if err != nil {
-		if strings.Contains(err.Error(), "connection refused") || strings.Contains(err.Error(), "no such file") {
+		if ipc.IsCode(err, ipc.ErrCodeUnavailable) {
 			return fmt.Errorf("daemon is not running. Start it with: op daemon start")
 		}
 		return err
@@ -129,7 +231,7 @@ func ShowAsyncTask(id string) error {
 func DeleteAsyncTask(id string) error {
 	client, err := ipc.NewClientFromRegistry("local")
 	if err != nil {
-		if strings.Contains(err.Error(), "connection refused") || strings.Contains(err.Error(), "no such file") {
+		if ipc.IsCode(err, ipc.ErrCodeUnavailable) {
 			return fmt.Errorf("daemon is not running. Start it with: op daemon start")
 		}
 		return err
@@ -150,12 +252,22 @@ func printTaskDetails(task *ipc.ToolTask) {
 	fmt.Printf("Status:      %s\n", strings.TrimSpace(task.Status))
 	fmt.Printf("Tool:        %s\n", strings.TrimSpace(task.ToolName))
 	fmt.Printf("Mode:        %s\n", strings.TrimSpace(task.Mode))
+	fmt.Printf("Owner:       %s\n", orDash(task.Owner))
 	fmt.Printf("Origin:      %s\n", orDash(task.Origin))
 	fmt.Printf("Client ID:   %s\n", orDash(task.ClientID))
 	fmt.Printf("Session ID:  %s\n", orDash(task.SessionID))
 	fmt.Printf("Call ID:     %s\n", orDash(task.CallID))
 	fmt.Printf("Agent:       %s\n", orDash(task.AgentName))
 	fmt.Printf("Command:     %s\n", orDash(task.CommandName))
+	if len(task.DependsOn) > 0 {
+		fmt.Printf("Depends On:  %s\n", strings.Join(task.DependsOn, ", "))
+	}
+	execution := task.Execution
+	if execution == "" {
+		execution = "at_least_once"
+	}
+	fmt.Printf("Execution:   %s\n", execution)
+	fmt.Printf("Attempts:    %d\n", task.Attempts)
 	fmt.Printf("Created At:  %s\n", orDash(task.CreatedAt))
 	fmt.Printf("Updated At:  %s\n", orDash(task.UpdatedAt))
 	fmt.Printf("Completed At:%s\n", orDash(task.CompletedAt))
@@ -163,6 +275,9 @@ func printTaskDetails(task *ipc.ToolTask) {
 	if trimmed := strings.TrimSpace(task.Args); trimmed != "" {
 		fmt.Printf("Args:        %s\n", trimmed)
 	}
+	if trimmed := strings.TrimSpace(task.CommandArgs); trimmed != "" && trimmed != strings.TrimSpace(task.Args) {
+		fmt.Printf("Command Args:%s\n", trimmed)
+	}
 	if trimmed := strings.TrimSpace(task.Metadata); trimmed != "" {
 		fmt.Printf("Metadata:    %s\n", trimmed)
 	}
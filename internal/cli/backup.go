@@ -0,0 +1,143 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"opperator/config"
+	"opperator/internal/backup"
+	"opperator/internal/ipc"
+)
+
+func backupClient(daemonName string) (*ipc.Client, error) {
+	if daemonName == "" {
+		daemonName = "local"
+	}
+	client, err := ipc.NewClientFromRegistry(daemonName)
+	if err != nil {
+		if ipc.IsCode(err, ipc.ErrCodeUnavailable) {
+			return nil, fmt.Errorf("daemon '%s' is not reachable. Start it with: op daemon start", daemonName)
+		}
+		return nil, err
+	}
+	return client, nil
+}
+
+// BackupSettings reads the backup section of daemon.yaml with the non-empty
+// fields of override applied, so a fresh server can restore before it has a
+// daemon.yaml of its own.
+func BackupSettings(override config.BackupConfig) (config.BackupConfig, error) {
+	var cfg config.BackupConfig
+	settings, err := config.LoadDaemonSettings()
+	if err != nil {
+		// A broken daemon.yaml must not stand in the way of a restore
+		if override.Bucket == "" {
+			return cfg, err
+		}
+	} else {
+		cfg = settings.Backup
+	}
+	if override.Bucket != "" {
+		cfg.Bucket = override.Bucket
+	}
+	if override.Region != "" {
+		cfg.Region = override.Region
+	}
+	if override.Prefix != "" {
+		cfg.Prefix = override.Prefix
+	}
+	if override.Endpoint != "" {
+		cfg.Endpoint = override.Endpoint
+	}
+	if err := cfg.Validate(); err != nil {
+		return cfg, err
+	}
+	if !cfg.Enabled() {
+		return cfg, fmt.Errorf("no backup bucket configured; set backup.bucket in daemon.yaml or pass --bucket")
+	}
+	return cfg, nil
+}
+
+// BackupNow has a daemon snapshot its state to its backup bucket.
+func BackupNow(daemonName string) error {
+	client, err := backupClient(daemonName)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	fmt.Println("Creating snapshot...")
+	snap, err := client.BackupNow()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("✓ Uploaded snapshot %s (%s)\n", snap.ID(), formatBackupSize(snap.Size))
+	fmt.Printf("  Restore it with: op backup restore %s\n", snap.ID())
+	return nil
+}
+
+// ListBackups prints the snapshots in the backup bucket, newest first.
+func ListBackups(cfg config.BackupConfig, jsonOutput bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	snaps, err := backup.List(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	if jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if snaps == nil {
+			snaps = []backup.Snapshot{}
+		}
+		return enc.Encode(snaps)
+	}
+
+	if len(snaps) == 0 {
+		fmt.Printf("No snapshots in bucket %s\n", cfg.Bucket)
+		return nil
+	}
+	fmt.Printf("%-18s %-22s %10s\n", "SNAPSHOT", "TAKEN", "SIZE")
+	for _, snap := range snaps {
+		fmt.Printf("%-18s %-22s %10s\n", snap.ID(), snap.Timestamp.Local().Format("2006-01-02 15:04:05"), formatBackupSize(snap.Size))
+	}
+	return nil
+}
+
+// RestoreBackup unpacks a snapshot over the config directory. The caller
+// makes sure the local daemon is stopped.
+func RestoreBackup(cfg config.BackupConfig, id string) error {
+	dirs, err := config.ResolveDirs()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	fmt.Printf("Restoring snapshot %s from %s into %s...\n", id, cfg.Bucket, dirs.Config)
+	snap, files, err := backup.Restore(ctx, cfg, id, dirs)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ Restored %d files from snapshot %s\n", files, snap.ID())
+	fmt.Println("\nSecrets are not part of backups. Set them again with 'op secret create',")
+	fmt.Println("then start the daemon; agents rebuild their environments on first start.")
+	return nil
+}
+
+func formatBackupSize(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1f GB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"opperator/internal/ipc"
+)
+
+func blackboardClient(daemonName string) (*ipc.Client, string, error) {
+	if daemonName == "" {
+		daemonName = "local"
+	}
+	client, err := ipc.NewClientFromRegistry(daemonName)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to connect to daemon '%s': %w", daemonName, err)
+	}
+	return client, daemonName, nil
+}
+
+// BlackboardGet prints the value of key on a blackboard as JSON.
+func BlackboardGet(namespace, key, daemonName string) error {
+	client, _, err := blackboardClient(daemonName)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	entry, err := client.BlackboardGet(namespace, key)
+	if err != nil {
+		return err
+	}
+	printKVValue(entry.Value)
+	return nil
+}
+
+// BlackboardSet stores value under key on a blackboard. A value that is not
+// JSON is stored as a string.
+func BlackboardSet(namespace, key, value, daemonName string) error {
+	client, _, err := blackboardClient(daemonName)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if err := client.BlackboardSet(namespace, key, parseKVValue(value)); err != nil {
+		return err
+	}
+	fmt.Printf("✓ Set %s on blackboard '%s'\n", key, namespace)
+	return nil
+}
+
+// BlackboardDelete removes key from a blackboard.
+func BlackboardDelete(namespace, key, daemonName string) error {
+	client, _, err := blackboardClient(daemonName)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if err := client.BlackboardDelete(namespace, key); err != nil {
+		return err
+	}
+	fmt.Printf("✓ Deleted %s from blackboard '%s'\n", key, namespace)
+	return nil
+}
+
+// BlackboardList prints a blackboard's keys starting with prefix, their
+// values and the agents that wrote them.
+func BlackboardList(namespace, prefix, daemonName string, jsonOutput bool) error {
+	client, foundDaemon, err := blackboardClient(daemonName)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	entries, err := client.BlackboardList(namespace, prefix)
+	if err != nil {
+		return err
+	}
+	if jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	}
+	if len(entries) == 0 {
+		fmt.Printf("No keys on blackboard '%s' of daemon '%s'\n", namespace, foundDaemon)
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tUPDATED\tWRITER\tVALUE")
+	for _, e := range entries {
+		writer := e.Writer
+		if writer == "" {
+			writer = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", e.Key, e.UpdatedAt.Local().Format("2006-01-02 15:04:05"), writer, kvListValue(e.Value))
+	}
+	return w.Flush()
+}
@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"opperator/internal/ipc"
+	"tui/tools"
+)
+
+// budgetContext marks ctx with the agent whose budget model calls are
+// charged to.
+func budgetContext(ctx context.Context, agentName string, isCoreAgent bool) context.Context {
+	if isCoreAgent {
+		return tools.WithAgentContext(ctx, "", agentName)
+	}
+	return tools.WithAgentContext(ctx, agentName, "")
+}
+
+// chargeModelCall counts a model call against the budget of the agent in
+// ctx, failing when the budget is spent. As with the TUI, a daemon that
+// cannot be reached does not hold the call back.
+func chargeModelCall(ctx context.Context, model string, input any) error {
+	return tools.ChargeModelCall(ctx, model, input)
+}
+
+// recordStreamOutput adds the text and tool calls a model streamed back to
+// the agent's estimated cost.
+func recordStreamOutput(ctx context.Context, model string, result StreamResult) {
+	output := result.Text
+	if len(result.ToolCalls) > 0 {
+		data, _ := json.Marshal(result.ToolCalls)
+		output += string(data)
+	}
+	tools.RecordModelOutput(ctx, model, output)
+}
+
+// BudgetStatus prints today's model calls and estimated cost of the agents
+// on a daemon, or of one agent, against their budgets.
+func BudgetStatus(agentName, daemonName string, jsonOutput bool) error {
+	var client *ipc.Client
+	var err error
+	if agentName != "" {
+		client, _, err = getClientForAgent(agentName, daemonName)
+	} else {
+		if daemonName == "" {
+			daemonName = "local"
+		}
+		client, err = ipc.NewClientFromRegistry(daemonName)
+		if err != nil {
+			err = fmt.Errorf("failed to connect to daemon '%s': %w", daemonName, err)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	budgets, err := client.BudgetStatus(agentName)
+	if err != nil {
+		return err
+	}
+	if jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(budgets)
+	}
+	if len(budgets) == 0 {
+		fmt.Println("No model calls today")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "AGENT\tCALLS\tTOKENS (IN/OUT)\tEST. COST")
+	for _, b := range budgets {
+		calls := fmt.Sprint(b.Calls)
+		if b.MaxCalls > 0 {
+			calls += fmt.Sprintf(" / %d", b.MaxCalls)
+		}
+		cost := fmt.Sprintf("$%.4f", b.Cost)
+		if b.MaxCost > 0 {
+			cost += fmt.Sprintf(" / $%.2f", b.MaxCost)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d/%d\t%s\n", b.Agent, calls, b.InputTokens, b.OutputTokens, cost)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"opperator/config"
+	"opperator/internal/ipc"
+)
+
+func channelClient() (*ipc.Client, error) {
+	client, err := ipc.NewClientFromRegistry("local")
+	if err != nil {
+		if ipc.IsCode(err, ipc.ErrCodeUnavailable) {
+			return nil, fmt.Errorf("daemon is not running. Start it with: op daemon start")
+		}
+		return nil, err
+	}
+	return client, nil
+}
+
+// ListChannels prints the chat channels running on the local daemon.
+func ListChannels() error {
+	client, err := channelClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	channels, err := client.ListChannels()
+	if err != nil {
+		return err
+	}
+	if len(channels) == 0 {
+		fmt.Println("No channels configured")
+		fmt.Println("\nAdd one with: op channel add <name> --type slack|telegram|email --agent <agent>")
+		return nil
+	}
+
+	fmt.Printf("%-20s %-9s %-20s %-9s %-10s %-9s %-20s %s\n", "NAME", "TYPE", "AGENT", "SOURCE", "CONNECTED", "MESSAGES", "LAST MESSAGE", "ERROR")
+	fmt.Printf("%-20s %-9s %-20s %-9s %-10s %-9s %-20s %s\n", "----", "----", "-----", "------", "---------", "--------", "------------", "-----")
+	for _, ch := range channels {
+		connected := "no"
+		if ch.Connected {
+			connected = "yes"
+		}
+		fmt.Printf("%-20s %-9s %-20s %-9s %-10s %-9d %-20s %s\n",
+			ch.Name, ch.Type, ch.Agent, ch.Source, connected, ch.Messages, orDash(ch.LastMessage), strings.TrimSpace(ch.LastError))
+	}
+	return nil
+}
+
+// AddChannel registers a chat channel with the local daemon, which starts
+// it right away and again on every start.
+func AddChannel(ch config.ChannelConfig) error {
+	if err := ch.Validate(); err != nil {
+		return err
+	}
+	client, err := channelClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if err := client.AddChannel(ch); err != nil {
+		return err
+	}
+	fmt.Printf("Added %s channel '%s' routing messages to agent %s\n", ch.Type, ch.Name, ch.Agent())
+	return nil
+}
+
+// RemoveChannel stops a channel added with 'op channel add' and forgets it.
+func RemoveChannel(name string) error {
+	client, err := channelClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if err := client.RemoveChannel(name); err != nil {
+		return err
+	}
+	fmt.Printf("Removed channel '%s'\n", name)
+	return nil
+}
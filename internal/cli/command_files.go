@@ -0,0 +1,120 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"opperator/internal/ipc"
+)
+
+// uploadFileArgs uploads the files that "@path" argument values refer to and
+// replaces the values with references to the uploaded blobs, which the agent
+// receives as paths of its own copies. "@@" starts a value that begins with a
+// literal "@".
+func uploadFileArgs(client *ipc.Client, args map[string]interface{}) (map[string]interface{}, error) {
+	var out map[string]interface{}
+	for key, value := range args {
+		s, ok := value.(string)
+		if !ok || !strings.HasPrefix(s, "@") {
+			continue
+		}
+		if out == nil {
+			out = make(map[string]interface{}, len(args))
+			for k, v := range args {
+				out[k] = v
+			}
+		}
+		if strings.HasPrefix(s, "@@") {
+			out[key] = s[1:]
+			continue
+		}
+
+		path := strings.TrimPrefix(s, "@")
+		if rest, ok := strings.CutPrefix(path, "~/"); ok {
+			if home, err := os.UserHomeDir(); err == nil {
+				path = filepath.Join(home, rest)
+			}
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("argument %s: %w (use @@ for a value starting with @)", key, err)
+		}
+		if info.IsDir() {
+			return nil, fmt.Errorf("argument %s: %s is a directory", key, path)
+		}
+		fmt.Fprintln(os.Stderr, mutedStyle.Render(fmt.Sprintf("Uploading %s (%s)...", path, formatBackupSize(info.Size()))))
+		id, err := client.UploadBlob(path)
+		if err != nil {
+			return nil, fmt.Errorf("argument %s: upload %s: %w", key, path, err)
+		}
+		out[key] = ipc.BlobRef(id)
+	}
+	if out == nil {
+		return args, nil
+	}
+	return out, nil
+}
+
+// saveCommandFiles downloads the files a command output into dir, or lists
+// them when no directory was given. Existing files are not overwritten; a
+// number is added to the name instead.
+func saveCommandFiles(client *ipc.Client, files []ipc.CommandFile, dir string) error {
+	if len(files) == 0 {
+		return nil
+	}
+	if dir == "" {
+		for _, file := range files {
+			fmt.Fprintln(os.Stderr, mutedStyle.Render("Output file: ")+valueStyle.Render(file.Name)+mutedStyle.Render(fmt.Sprintf(" (%s, %s)", file.MimeType, formatBackupSize(file.Size))))
+		}
+		fmt.Fprintln(os.Stderr, mutedStyle.Render("Save output files with --output-dir <dir>"))
+		return nil
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	for _, file := range files {
+		f, path, err := createUnique(dir, file.Name)
+		if err != nil {
+			return err
+		}
+		err = client.DownloadBlob(file.BlobID, f)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(path)
+			return fmt.Errorf("download %s: %w", file.Name, err)
+		}
+		fmt.Fprintln(os.Stderr, mutedStyle.Render("Saved ")+valueStyle.Render(path)+mutedStyle.Render(fmt.Sprintf(" (%s, %s)", file.MimeType, formatBackupSize(file.Size))))
+	}
+	return nil
+}
+
+// createUnique creates name in dir, or "name-2.ext" and so on when it exists.
+// Only the last element of name is used, since it comes from the daemon and
+// must not place files outside dir.
+func createUnique(dir, name string) (*os.File, string, error) {
+	name = filepath.Base(strings.TrimSpace(name))
+	if name == "." || name == ".." || name == string(filepath.Separator) {
+		return nil, "", fmt.Errorf("invalid output file name %q", name)
+	}
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for i := 1; ; i++ {
+		path := filepath.Join(dir, name)
+		if i > 1 {
+			path = filepath.Join(dir, fmt.Sprintf("%s-%d%s", base, i, ext))
+		}
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0o644)
+		if err == nil {
+			return f, path, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, "", err
+		}
+	}
+}
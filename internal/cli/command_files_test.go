@@ -0,0 +1,61 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCreateUnique(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing []string
+		want     string
+		err      bool
+	}{
+		{name: "report.pdf", want: "report.pdf"},
+		{name: "report.pdf", existing: []string{"report.pdf"}, want: "report-2.pdf"},
+		{name: "report.pdf", existing: []string{"report.pdf", "report-2.pdf"}, want: "report-3.pdf"},
+		{name: "README", existing: []string{"README"}, want: "README-2"},
+		{name: "../../escape.txt", want: "escape.txt"},
+		{name: "/etc/passwd", want: "passwd"},
+		{name: " padded.txt ", want: "padded.txt"},
+		{name: "..", err: true},
+		{name: ".", err: true},
+		{name: "", err: true},
+		{name: "/", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for _, name := range tt.existing {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte("old"), 0o644); err != nil {
+					t.Fatalf("Failed to create %s: %v", name, err)
+				}
+			}
+
+			f, path, err := createUnique(dir, tt.name)
+			if tt.err {
+				if err == nil {
+					f.Close()
+					t.Errorf("Expected an error, got %s", path)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("createUnique failed: %v", err)
+			}
+			f.Close()
+			if want := filepath.Join(dir, tt.want); path != want {
+				t.Errorf("Expected %s, got %s", want, path)
+			}
+			for _, name := range tt.existing {
+				data, err := os.ReadFile(filepath.Join(dir, name))
+				if err != nil || string(data) != "old" {
+					t.Errorf("Expected %s to be left alone, got %q, %v", name, data, err)
+				}
+			}
+		})
+	}
+}
@@ -6,15 +6,20 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"sort"
 	"strings"
 	"syscall"
+	"text/tabwriter"
 	"time"
 
 	"github.com/charmbracelet/lipgloss"
 	"opperator/config"
+	"opperator/internal/agent"
 	"opperator/internal/credentials"
 	"opperator/internal/ipc"
 	"opperator/pkg/argparser"
+	"opperator/pkg/sparkline"
+	"opperator/pkg/tracing"
 	"tui/opper"
 )
 
@@ -81,6 +86,14 @@ func findAgentDaemon(agentName string) (string, error) {
 	}
 
 	if len(foundDaemons) > 1 {
+		// The active workspace's daemon settles the ambiguity
+		if ws, err := config.ActiveWorkspace(); err == nil && ws != nil && ws.Daemon != "" {
+			for _, d := range foundDaemons {
+				if d == ws.Daemon {
+					return d, nil
+				}
+			}
+		}
 		return "", fmt.Errorf("agent '%s' exists on multiple daemons: %v. Please specify --daemon", agentName, foundDaemons)
 	}
 
@@ -108,13 +121,18 @@ func getClientForAgent(agentName, daemonName string) (*ipc.Client, string, error
 	return client, daemonName, nil
 }
 
-func ListAgents(runningOnly, stoppedOnly, crashedOnly bool, daemonFilter string) error {
+func ListAgents(runningOnly, stoppedOnly, crashedOnly bool, daemonFilter string, allWorkspaces bool) error {
 	// Load daemon registry
 	registry, err := config.LoadDaemonRegistry()
 	if err != nil {
 		return fmt.Errorf("failed to load daemon registry: %w", err)
 	}
 
+	workspace, err := activeWorkspaceFilter(allWorkspaces)
+	if err != nil {
+		return err
+	}
+
 	// Collect agents from all daemons
 	type AgentWithDaemon struct {
 		Agent      *ipc.ProcessInfo
@@ -150,6 +168,9 @@ func ListAgents(runningOnly, stoppedOnly, crashedOnly bool, daemonFilter string)
 
 		// Add to collection
 		for _, p := range processes {
+			if !workspace.HasAgent(p.Name) {
+				continue
+			}
 			allAgents = append(allAgents, AgentWithDaemon{
 				Agent:      p,
 				DaemonName: daemon.Name,
@@ -157,13 +178,21 @@ func ListAgents(runningOnly, stoppedOnly, crashedOnly bool, daemonFilter string)
 		}
 	}
 
+	if workspace != nil {
+		fmt.Printf("Workspace: %s (--all-workspaces to list every agent)\n\n", workspace.Name)
+	}
+
 	if len(allAgents) == 0 {
+		if workspace != nil {
+			fmt.Printf("No agents in workspace '%s'. Add one with: op workspace add %s --agent <name>\n", workspace.Name, workspace.Name)
+			return nil
+		}
 		fmt.Println("No agents configured")
 		return nil
 	}
 
-	fmt.Printf("%-15s %-20s %-10s %-10s %-8s %s\n", "DAEMON", "NAME", "STATUS", "PID", "UPTIME", "DESCRIPTION")
-	fmt.Printf("%-15s %-20s %-10s %-10s %-8s %s\n", "------", "----", "------", "---", "------", "-----------")
+	fmt.Printf("%-15s %-20s %-12s %-10s %-8s %-14s %s\n", "DAEMON", "NAME", "STATUS", "PID", "UPTIME", "LAST EXIT", "DESCRIPTION")
+	fmt.Printf("%-15s %-20s %-12s %-10s %-8s %-14s %s\n", "------", "----", "------", "---", "------", "---------", "-----------")
 
 	for _, item := range allAgents {
 		p := item.Agent
@@ -175,11 +204,14 @@ func ListAgents(runningOnly, stoppedOnly, crashedOnly bool, daemonFilter string)
 		if stoppedOnly && string(p.Status) != "stopped" {
 			continue
 		}
-		if crashedOnly && string(p.Status) != "crashed" {
+		if crashedOnly && p.Status != agent.StatusCrashed && p.Status != agent.StatusCrashLooping {
 			continue
 		}
 
 		status := string(p.Status)
+		if p.Paused != nil && p.Status == agent.StatusRunning {
+			status = "paused"
+		}
 		pid := "-"
 		if p.PID > 0 {
 			pid = fmt.Sprintf("%d", p.PID)
@@ -190,12 +222,20 @@ func ListAgents(runningOnly, stoppedOnly, crashedOnly bool, daemonFilter string)
 			uptime = fmt.Sprintf("%ds", p.Uptime)
 		}
 
+		lastExit := "-"
+		if p.LastExit != nil {
+			lastExit = p.LastExit.String()
+		}
+
 		desc := strings.TrimSpace(p.Description)
 		if desc == "" {
 			desc = "-"
 		}
+		if p.Session != "" {
+			desc = "[session] " + desc
+		}
 
-		fmt.Printf("%-15s %-20s %-10s %-10s %-8s %s\n", item.DaemonName, p.Name, status, pid, uptime, desc)
+		fmt.Printf("%-15s %-20s %-12s %-10s %-8s %-14s %s\n", item.DaemonName, p.Name, status, pid, uptime, lastExit, desc)
 	}
 
 	return nil
@@ -215,17 +255,28 @@ func StartAgent(name, daemonName string) error {
 	return nil
 }
 
-func StopAgent(name, daemonName string) error {
+// StopAgent stops an agent and reports how its process ended. force kills it
+// right away; otherwise grace, when set, overrides its stop_grace_period.
+func StopAgent(name, daemonName string, force bool, grace time.Duration) error {
 	client, foundDaemon, err := getClientForAgent(name, daemonName)
 	if err != nil {
 		return err
 	}
 	defer client.Close()
 
-	if err := client.StopAgent(name); err != nil {
+	opts := agent.StopOptions{GracePeriod: grace, Force: force, Reason: "op agent stop"}
+	dependents, method, err := client.StopAgentWithOptions(name, opts)
+	if err != nil {
 		return err
 	}
-	fmt.Printf("Stopped agent '%s' on daemon '%s'\n", name, foundDaemon)
+	if method != "" {
+		fmt.Printf("Stopped agent '%s' on daemon '%s' (%s)\n", name, foundDaemon, method)
+	} else {
+		fmt.Printf("Stopped agent '%s' on daemon '%s'\n", name, foundDaemon)
+	}
+	if len(dependents) > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: still running agents depend on '%s': %s\n", name, strings.Join(dependents, ", "))
+	}
 	return nil
 }
 
@@ -243,10 +294,51 @@ func RestartAgent(name, daemonName string) error {
 	return nil
 }
 
+// PauseAgent makes an agent reject commands and trigger events until it is
+// resumed, without stopping its process.
+func PauseAgent(name, daemonName, reason string) error {
+	client, foundDaemon, err := getClientForAgent(name, daemonName)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if err := client.PauseAgent(name, reason); err != nil {
+		return err
+	}
+	fmt.Printf("Paused agent '%s' on daemon '%s'; it keeps running but rejects commands until: op agent resume %s\n", name, foundDaemon, name)
+	return nil
+}
+
+func ResumeAgent(name, daemonName string) error {
+	client, foundDaemon, err := getClientForAgent(name, daemonName)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if err := client.ResumeAgent(name); err != nil {
+		return err
+	}
+	fmt.Printf("Resumed agent '%s' on daemon '%s'\n", name, foundDaemon)
+	return nil
+}
+
 func BootstrapAgent(name, description string, noStart bool) error {
-	client, err := ipc.NewClientFromRegistry("local")
+	// New agents go to the active workspace's daemon
+	daemonName := "local"
+	if ws, err := config.ActiveWorkspace(); err != nil {
+		return err
+	} else if ws != nil && ws.Daemon != "" {
+		daemonName = ws.Daemon
+	}
+
+	client, err := ipc.NewClientFromRegistry(daemonName)
 	if err != nil {
-		if strings.Contains(err.Error(), "connection refused") || strings.Contains(err.Error(), "no such file") {
+		if ipc.IsCode(err, ipc.ErrCodeUnavailable) {
+			if daemonName != "local" {
+				return fmt.Errorf("daemon '%s' is not reachable", daemonName)
+			}
 			return fmt.Errorf("daemon is not running. Start it with: op daemon start")
 		}
 		return err
@@ -265,6 +357,7 @@ func BootstrapAgent(name, description string, noStart bool) error {
 	}
 
 	fmt.Println(result)
+	addToActiveWorkspace("agent", name)
 
 	// Get config directory for display
 	configDir, _ := config.GetConfigDir()
@@ -333,13 +426,16 @@ func DeleteAgent(name string, force bool, daemonName string) error {
 	}
 
 	fmt.Printf("Agent '%s' has been successfully deleted.\n", name)
+	if err := config.RemoveFromWorkspaces("agent", name); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to remove agent from workspaces: %v\n", err)
+	}
 	return nil
 }
 
 func StopAllAgents() error {
 	client, err := ipc.NewClientFromRegistry("local")
 	if err != nil {
-		if strings.Contains(err.Error(), "connection refused") || strings.Contains(err.Error(), "no such file") {
+		if ipc.IsCode(err, ipc.ErrCodeUnavailable) {
 			return fmt.Errorf("daemon is not running. Start it with: op daemon start")
 		}
 		return err
@@ -353,7 +449,7 @@ func StopAllAgents() error {
 	return nil
 }
 
-func ReloadConfig(daemonName string) error {
+func ReloadConfig(daemonName string, fromGit bool) error {
 	// Default to local daemon if not specified
 	if daemonName == "" {
 		daemonName = "local"
@@ -361,13 +457,16 @@ func ReloadConfig(daemonName string) error {
 
 	client, err := ipc.NewClientFromRegistry(daemonName)
 	if err != nil {
-		if strings.Contains(err.Error(), "connection refused") || strings.Contains(err.Error(), "no such file") {
+		if ipc.IsCode(err, ipc.ErrCodeUnavailable) {
 			return fmt.Errorf("daemon '%s' is not running", daemonName)
 		}
 		return err
 	}
 	defer client.Close()
 
+	if fromGit {
+		return reloadFromGit(client, daemonName)
+	}
 	if err := client.ReloadConfig(); err != nil {
 		return err
 	}
@@ -375,30 +474,93 @@ func ReloadConfig(daemonName string) error {
 	return nil
 }
 
-func InvokeCommand(name, command string, args map[string]interface{}, timeout time.Duration, daemonName string) error {
-	client, foundDaemon, err := getClientForAgent(name, daemonName)
+func reloadFromGit(client *ipc.Client, daemonName string) error {
+	res, err := client.ReloadConfigFromGit()
+	if err != nil {
+		return err
+	}
+	if !res.Changed() {
+		fmt.Printf("Daemon '%s' is up to date with %s/%s (%s)\n", daemonName, res.Remote, res.Branch, shortCommit(res.To))
+		return nil
+	}
+	fmt.Printf("Pulled %s/%s %s..%s on daemon '%s'\n", res.Remote, res.Branch, shortCommit(res.From), shortCommit(res.To), daemonName)
+	for _, f := range res.Files {
+		fmt.Printf("  %s\n", f)
+	}
+	if len(res.Restarted) > 0 {
+		fmt.Printf("Restarted: %s\n", strings.Join(res.Restarted, ", "))
+	}
+	for _, f := range res.Files {
+		if f == "daemon.yaml" {
+			fmt.Println("daemon.yaml changed; restart the daemon to apply it")
+		}
+	}
+	return nil
+}
+
+func shortCommit(commit string) string {
+	if len(commit) > 7 {
+		return commit[:7]
+	}
+	return commit
+}
+
+// CommandOptions tune how 'op agent command' invokes a command.
+type CommandOptions struct {
+	Timeout time.Duration
+	// Daemon is the daemon the agent runs on; found automatically when empty.
+	Daemon string
+	// Dir, when set, is the directory the command runs in instead of the one
+	// it declares or the current one.
+	Dir string
+	// OutputDir, when set, is where files the command outputs are saved.
+	OutputDir string
+}
+
+func InvokeCommand(name, command string, args map[string]interface{}, opts CommandOptions) error {
+	client, foundDaemon, err := getClientForAgent(name, opts.Daemon)
 	if err != nil {
 		return err
 	}
 	defer client.Close()
 
-	resp, err := client.InvokeCommand(name, command, args, timeout)
+	// Interrupting the CLI cancels the command on the agent as well
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	ctx, span := tracing.Start(ctx, "cli.command", "agent.name", name, "agent.command", command)
+	defer span.End()
+
+	args, err = uploadFileArgs(client, args)
 	if err != nil {
 		return err
 	}
 
+	resp, err := client.InvokeCommandInDir(ctx, name, command, args, opts.Dir, opts.Timeout, nil)
+	if err != nil {
+		span.RecordError(err)
+		return err
+	}
+
+	// Get styles with proper stderr detection
+	_, valueStyle, mutedStyle, successStyle, _, _ := getCommandStyles()
+
 	if !resp.Success {
+		if resp.TaskID != "" {
+			fmt.Fprintln(os.Stderr, mutedStyle.Render("Replay with: op agent command --replay "+resp.TaskID))
+		}
 		if resp.Error == "" {
 			resp.Error = "command failed"
 		}
 		return fmt.Errorf("%s", resp.Error)
 	}
 
-	// Get styles with proper stderr detection
-	_, valueStyle, mutedStyle, successStyle, _, _ := getCommandStyles()
-
 	// Activity/status to stderr (styled)
-	fmt.Fprintln(os.Stderr, successStyle.Render("✓")+" Command "+valueStyle.Render("'"+command+"'")+" succeeded on agent "+valueStyle.Render("'"+name+"'")+" "+mutedStyle.Render("(daemon: "+foundDaemon+")"))
+	daemonNote := "(daemon: " + foundDaemon
+	if resp.TaskID != "" {
+		daemonNote += ", task: " + resp.TaskID
+	}
+	fmt.Fprintln(os.Stderr, successStyle.Render("✓")+" Command "+valueStyle.Render("'"+command+"'")+" succeeded on agent "+valueStyle.Render("'"+name+"'")+" "+mutedStyle.Render(daemonNote+")"))
 
 	// Result to stdout
 	if resp.Result != nil {
@@ -408,7 +570,61 @@ func InvokeCommand(name, command string, args map[string]interface{}, timeout ti
 			fmt.Printf("%v\n", resp.Result)
 		}
 	}
-	return nil
+	return saveCommandFiles(client, resp.Files, opts.OutputDir)
+}
+
+// ReplayCommand runs the agent command recorded as taskID again, with the
+// same arguments, as a new task on the daemon daemonName, and waits up to
+// timeout for its result.
+func ReplayCommand(taskID string, timeout time.Duration, daemonName string) error {
+	if daemonName == "" {
+		daemonName = "local"
+	}
+	client, err := ipc.NewClientFromRegistry(daemonName)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	original, err := client.GetToolTask(taskID)
+	if err != nil {
+		return err
+	}
+	if original.Mode != "agent" {
+		return fmt.Errorf("task %s is not an agent command (mode %s)", original.ID, orDash(original.Mode))
+	}
+	task, err := client.ReplayToolTask(original.ID)
+	if err != nil {
+		return err
+	}
+
+	_, valueStyle, mutedStyle, successStyle, _, _ := getCommandStyles()
+	fmt.Fprintln(os.Stderr, mutedStyle.Render("Replaying")+" "+valueStyle.Render("'"+original.CommandName+"'")+" on agent "+valueStyle.Render("'"+original.AgentName+"'")+" "+mutedStyle.Render("(task: "+task.ID+")"))
+	if args := strings.TrimSpace(original.CommandArgs); args != "" {
+		fmt.Fprintln(os.Stderr, mutedStyle.Render("Args: "+args))
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		task, err = client.GetToolTask(task.ID)
+		if err != nil {
+			return err
+		}
+		switch task.Status {
+		case "complete":
+			fmt.Fprintln(os.Stderr, successStyle.Render("✓")+" Command "+valueStyle.Render("'"+original.CommandName+"'")+" succeeded on agent "+valueStyle.Render("'"+original.AgentName+"'")+" "+mutedStyle.Render("(daemon: "+daemonName+", task: "+task.ID+")"))
+			if result := strings.TrimSpace(task.Result); result != "" && result != "command succeeded" {
+				fmt.Println(result)
+			}
+			return nil
+		case "failed", "skipped":
+			return fmt.Errorf("%s", orDash(strings.TrimSpace(task.Error)))
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("task %s is still %s after %s; follow it with: op async get %s", task.ID, task.Status, timeout, task.ID)
+		}
+		time.Sleep(workflowPollInterval)
+	}
 }
 
 // opperClientAdapter adapts tui/opper.Opper to argparser.OpperClient
@@ -464,8 +680,8 @@ func (a *jsonAggregatorAdapter) Assemble() (string, error) {
 	return a.aggregator.Assemble()
 }
 
-func InvokeCommandWithParsing(name, command, rawInput string, timeout time.Duration, daemonName string) error {
-	client, foundDaemon, err := getClientForAgent(name, daemonName)
+func InvokeCommandWithParsing(name, command, rawInput string, opts CommandOptions) error {
+	client, foundDaemon, err := getClientForAgent(name, opts.Daemon)
 	if err != nil {
 		return err
 	}
@@ -497,6 +713,7 @@ func InvokeCommandWithParsing(name, command, rawInput string, timeout time.Durat
 					Enum:        arg.Enum,
 					Items:       arg.Items,
 					Properties:  arg.Properties,
+					Schema:      arg.JSONSchema(),
 				}
 			}
 			break
@@ -510,12 +727,12 @@ func InvokeCommandWithParsing(name, command, rawInput string, timeout time.Durat
 	// If no arguments are expected, just invoke the command directly
 	if len(schema) == 0 {
 		fmt.Fprintln(os.Stderr, mutedStyle.Render("Command")+valueStyle.Render(" '"+command+"' ")+" expects no arguments, invoking directly...")
-		return InvokeCommand(name, command, nil, timeout, daemonName)
+		return InvokeCommand(name, command, nil, opts)
 	}
 
 	// Get API key
 	apiKey, err := credentials.GetSecret(credentials.OpperAPIKeyName)
-	if err != nil {
+	if err != nil && opper.Local() == nil {
 		return fmt.Errorf("failed to read Opper API key: %w (run: op secret create %s)", err, credentials.OpperAPIKeyName)
 	}
 
@@ -545,7 +762,8 @@ func InvokeCommandWithParsing(name, command, rawInput string, timeout time.Durat
 	fmt.Fprintln(os.Stderr)
 
 	// Now invoke the command with parsed args
-	return InvokeCommand(name, command, args, timeout, foundDaemon)
+	opts.Daemon = foundDaemon
+	return InvokeCommand(name, command, args, opts)
 }
 
 func ListAgentCommands(name, daemonName string) error {
@@ -601,7 +819,7 @@ func ListAgentCommands(name, daemonName string) error {
 func ShowToolTaskMetrics() error {
 	client, err := ipc.NewClientFromRegistry("local")
 	if err != nil {
-		if strings.Contains(err.Error(), "connection refused") || strings.Contains(err.Error(), "no such file") {
+		if ipc.IsCode(err, ipc.ErrCodeUnavailable) {
 			return fmt.Errorf("daemon is not running. Start it with: op daemon start")
 		}
 		return err
@@ -622,6 +840,32 @@ func ShowToolTaskMetrics() error {
 	fmt.Printf("Queue Depth:  %d\n", metrics.QueueDepth)
 	fmt.Printf("Worker Count: %d\n", metrics.WorkerCount)
 
+	if len(metrics.Origins) > 0 {
+		origins := make([]string, 0, len(metrics.Origins))
+		for origin := range metrics.Origins {
+			origins = append(origins, origin)
+		}
+		sort.Strings(origins)
+		fmt.Println()
+		fmt.Printf("%-12s %-8s %s\n", "ORIGIN", "QUEUED", "RUNNING")
+		for _, origin := range origins {
+			m := metrics.Origins[origin]
+			fmt.Printf("%-12s %-8d %d\n", origin, m.Queued, m.Running)
+		}
+	}
+
+	if len(metrics.Agents) > 0 {
+		fmt.Println()
+		fmt.Println("Agent Metrics (last 30 minutes)")
+		fmt.Println("-------------------------------")
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "AGENT\tMETRIC\tTYPE\tVALUE\tHISTORY")
+		for _, m := range metrics.Agents {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", m.Agent, m.Name, m.Type, agent.FormatMetricValue(m.Value), sparkline.Render(m.Points))
+		}
+		w.Flush()
+	}
+
 	return nil
 }
 
@@ -660,6 +904,42 @@ func GetLogs(name string, follow bool, lines int, daemonName string) error {
 	return nil
 }
 
+// ShowCrashReport prints the exit status and trailing stderr captured for the
+// agent's most recent crash.
+func ShowCrashReport(name, daemonName string) error {
+	client, foundDaemon, err := getClientForAgent(name, daemonName)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	report, err := client.GetCrashReport(name)
+	if err != nil {
+		return err
+	}
+
+	if report == nil {
+		fmt.Printf("No crashes recorded for agent '%s' on daemon '%s'\n", name, foundDaemon)
+		return nil
+	}
+
+	fmt.Printf("Last crash of '%s' (daemon: %s)\n", name, foundDaemon)
+	fmt.Printf("  Time:   %s\n", report.At.Local().Format("2006-01-02 15:04:05"))
+	fmt.Printf("  Status: %s\n", report.Exit.String())
+	fmt.Println()
+
+	if len(report.StderrTail) == 0 {
+		fmt.Println("No stderr output captured")
+		return nil
+	}
+
+	fmt.Printf("Last %d stderr line(s):\n", len(report.StderrTail))
+	for _, line := range report.StderrTail {
+		fmt.Println(line)
+	}
+	return nil
+}
+
 func streamLogs(client *ipc.Client, name string) error {
 	// Setup signal handling for Ctrl+C
 	sigChan := make(chan os.Signal, 1)
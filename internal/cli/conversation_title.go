@@ -0,0 +1,114 @@
+package cli
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"opperator/internal/credentials"
+	"opperator/pkg/db"
+	"opperator/pkg/migration"
+	"tui/opper"
+	"tui/sessionstate"
+)
+
+// titleTimeout bounds the model call that titles a conversation, so a slow
+// title never holds up the end of an exec.
+const titleTimeout = 30 * time.Second
+
+// updateConversationTitle titles the conversation after its first exchange
+// and retitles it every few messages when its topic moved on, unless it was
+// renamed by hand. Failures only warn: the title is a nicety.
+func updateConversationTitle(ctx context.Context, writeDB *sql.DB, apiKey, convID, current string, history []conversationMessage) {
+	messages := make([]sessionstate.Message, 0, len(history))
+	for _, h := range history {
+		messages = append(messages, sessionstate.Message{Role: h.Role, Content: h.Content})
+	}
+	count := sessionstate.CountUserMessages(messages)
+	if !sessionstate.NeedsTitle(count) {
+		return
+	}
+
+	var renamed bool
+	row := writeDB.QueryRowContext(ctx, `SELECT title_renamed FROM conversations WHERE id = ?`, convID)
+	if err := row.Scan(&renamed); err != nil || renamed {
+		return
+	}
+	if count == 1 {
+		current = ""
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, titleTimeout)
+	defer cancel()
+	title, changed, err := sessionstate.GenerateTitle(ctx, opper.New(apiKey), current, sessionstate.TitleMessages(messages))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render("Warning:")+" "+mutedStyle.Render("failed to title conversation: "+err.Error()))
+		return
+	}
+	if !changed {
+		return
+	}
+	if _, err := writeDB.ExecContext(ctx,
+		`UPDATE conversations SET title = ? WHERE id = ? AND title_renamed = 0`, title, convID); err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render("Warning:")+" "+mutedStyle.Render("failed to save conversation title: "+err.Error()))
+	}
+}
+
+// RenameConversation sets the title of a conversation. A renamed
+// conversation keeps its title; with an empty title the model titles it
+// again from its messages and keeps it up to date from then on.
+func RenameConversation(conversationID, title string) error {
+	ctx := context.Background()
+	if err := initializeExecDB(); err != nil {
+		return err
+	}
+	writeDB, err := db.GetWriteDB()
+	if err != nil {
+		return err
+	}
+	if err := migration.NewRunner(writeDB).Run(); err != nil {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+
+	title = strings.Join(strings.Fields(title), " ")
+	if title != "" {
+		res, err := writeDB.ExecContext(ctx,
+			`UPDATE conversations SET title = ?, title_renamed = 1 WHERE id = ?`, title, conversationID)
+		if err != nil {
+			return fmt.Errorf("failed to rename conversation: %w", err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return fmt.Errorf("conversation %q not found", conversationID)
+		}
+		fmt.Printf("Renamed conversation %s to %q\n", conversationID, title)
+		return nil
+	}
+
+	conv, err := loadShareConversation(ctx, conversationID)
+	if err != nil {
+		return err
+	}
+	var messages []sessionstate.Message
+	for _, m := range conv.Messages {
+		messages = append(messages, sessionstate.Message{Role: m.Role, Content: m.Text})
+	}
+	apiKey, err := credentials.GetSecret(credentials.OpperAPIKeyName)
+	if err != nil && opper.Local() == nil {
+		return fmt.Errorf("failed to read Opper API key: %w (run: op secret create %s)", err, credentials.OpperAPIKeyName)
+	}
+	genCtx, cancel := context.WithTimeout(ctx, titleTimeout)
+	defer cancel()
+	title, _, err = sessionstate.GenerateTitle(genCtx, opper.New(apiKey), "", sessionstate.TitleMessages(messages))
+	if err != nil {
+		return fmt.Errorf("failed to generate a title: %w", err)
+	}
+	if _, err := writeDB.ExecContext(ctx,
+		`UPDATE conversations SET title = ?, title_renamed = 0 WHERE id = ?`, title, conversationID); err != nil {
+		return fmt.Errorf("failed to rename conversation: %w", err)
+	}
+	fmt.Printf("Titled conversation %s %q\n", conversationID, title)
+	return nil
+}
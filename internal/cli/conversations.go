@@ -0,0 +1,197 @@
+package cli
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"opperator/config"
+	"opperator/pkg/db"
+	"opperator/pkg/migration"
+	"tui/tools"
+)
+
+// ConversationFilter selects the conversations 'op conversation list' shows.
+type ConversationFilter struct {
+	Pinned   bool
+	Archived bool
+	// All lists archived conversations along with the others
+	All bool
+}
+
+// openConversationDB opens the conversation database for writing, migrated.
+func openConversationDB() (*sql.DB, error) {
+	if err := initializeExecDB(); err != nil {
+		return nil, err
+	}
+	writeDB, err := db.GetWriteDB()
+	if err != nil {
+		return nil, err
+	}
+	if err := migration.NewRunner(writeDB).Run(); err != nil {
+		return nil, fmt.Errorf("failed to run migrations: %w", err)
+	}
+	return writeDB, nil
+}
+
+// ListConversations prints the saved conversations, pinned ones first,
+// after archiving the ones idle for longer than conversation.yaml's
+// archive_after_days.
+func ListConversations(filter ConversationFilter) error {
+	ctx := context.Background()
+	writeDB, err := openConversationDB()
+	if err != nil {
+		return err
+	}
+	archiveIdleConversations(ctx, writeDB)
+
+	var where []string
+	switch {
+	case filter.Archived:
+		where = append(where, "archived_at IS NOT NULL")
+	case !filter.All:
+		where = append(where, "archived_at IS NULL")
+	}
+	if filter.Pinned {
+		where = append(where, "pinned = 1")
+	}
+	query := `SELECT id, title, created_at, active_agent, workspace, pinned, archived_at IS NOT NULL FROM conversations`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY pinned DESC, created_at DESC"
+
+	rows, err := writeDB.QueryContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to list conversations: %w", err)
+	}
+	defer rows.Close()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tTITLE\tAGENT\tWORKSPACE\tCREATED\tFLAGS")
+	count := 0
+	for rows.Next() {
+		var id, title string
+		var created int64
+		var agent, workspace sql.NullString
+		var pinned, archived bool
+		if err := rows.Scan(&id, &title, &created, &agent, &workspace, &pinned, &archived); err != nil {
+			return err
+		}
+		var flags []string
+		if pinned {
+			flags = append(flags, "pinned")
+		}
+		if archived {
+			flags = append(flags, "archived")
+		}
+		if runes := []rune(title); len(runes) > 50 {
+			title = string(runes[:49]) + "…"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", id, title, orDash(agent.String), orDash(workspace.String),
+			time.Unix(created, 0).Format("2006-01-02 15:04"), orDash(strings.Join(flags, ",")))
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if count == 0 {
+		fmt.Println("No conversations")
+		return nil
+	}
+	return w.Flush()
+}
+
+// PinConversation pins or unpins a conversation.
+func PinConversation(conversationID string, pinned bool) error {
+	writeDB, err := openConversationDB()
+	if err != nil {
+		return err
+	}
+	res, err := writeDB.ExecContext(context.Background(),
+		`UPDATE conversations SET pinned = ? WHERE id = ?`, pinned, conversationID)
+	if err != nil {
+		return fmt.Errorf("failed to update conversation: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("conversation %q not found", conversationID)
+	}
+	if pinned {
+		fmt.Printf("Pinned conversation %s\n", conversationID)
+	} else {
+		fmt.Printf("Unpinned conversation %s\n", conversationID)
+	}
+	return nil
+}
+
+// ArchiveConversation archives a conversation, unpinning it and ending its
+// session agents, or restores it.
+func ArchiveConversation(conversationID string, archived bool) error {
+	ctx := context.Background()
+	writeDB, err := openConversationDB()
+	if err != nil {
+		return err
+	}
+	var res sql.Result
+	if archived {
+		res, err = writeDB.ExecContext(ctx,
+			`UPDATE conversations SET archived_at = COALESCE(archived_at, ?), pinned = 0 WHERE id = ?`,
+			time.Now().Unix(), conversationID)
+	} else {
+		res, err = writeDB.ExecContext(ctx,
+			`UPDATE conversations SET archived_at = NULL WHERE id = ?`, conversationID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update conversation: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("conversation %q not found", conversationID)
+	}
+	if !archived {
+		fmt.Printf("Restored conversation %s\n", conversationID)
+		return nil
+	}
+	// Without a reachable daemon there are no session agents to end
+	_ = tools.EndSessionAgents(ctx, conversationID)
+	fmt.Printf("Archived conversation %s\n", conversationID)
+	return nil
+}
+
+// archiveIdleConversations archives the unpinned conversations without a
+// message for conversation.yaml's archive_after_days and ends their session
+// agents. Failures are ignored: listing still works without it.
+func archiveIdleConversations(ctx context.Context, writeDB *sql.DB) {
+	cfg, err := config.LoadConversationConfig()
+	if err != nil || cfg.ArchiveAfter() <= 0 {
+		return
+	}
+	cutoff := time.Now().Add(-cfg.ArchiveAfter()).Unix()
+	rows, err := writeDB.QueryContext(ctx,
+		`SELECT id FROM conversations
+		 WHERE archived_at IS NULL AND pinned = 0
+		   AND COALESCE((SELECT MAX(created_at) FROM messages WHERE session_id = conversations.id), created_at) < ?`,
+		cutoff)
+	if err != nil {
+		return
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	rows.Close()
+	now := time.Now().Unix()
+	for _, id := range ids {
+		if _, err := writeDB.ExecContext(ctx,
+			`UPDATE conversations SET archived_at = ? WHERE id = ?`, now, id); err != nil {
+			return
+		}
+		_ = tools.EndSessionAgents(ctx, id)
+	}
+}
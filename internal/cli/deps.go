@@ -0,0 +1,331 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"opperator/config"
+	"opperator/internal/agent"
+)
+
+const osvQueryBatchURL = "https://api.osv.dev/v1/querybatch"
+
+// dependency is a single installed package of an agent.
+type dependency struct {
+	Name      string   `json:"name"`
+	Version   string   `json:"version"`
+	Latest    string   `json:"latest,omitempty"`
+	Vulns     []string `json:"vulnerabilities,omitempty"`
+	ecosystem string
+}
+
+// AuditAgentDependencies inspects a local agent's dependency manifest and
+// reports outdated packages and known vulnerabilities from OSV. With update
+// set, the lockfile/environment is upgraded afterwards.
+func AuditAgentDependencies(name string, update, jsonOutput bool) error {
+	agentCfg, err := loadLocalAgentConfig(name)
+	if err != nil {
+		return err
+	}
+
+	dir, err := agentCfg.WorkingDir()
+	if err != nil {
+		return err
+	}
+
+	// An explicit runtime in agents.yaml wins over the manifests in dir
+	kind := agentCfg.ResolveRuntime(dir)
+	var deps []dependency
+	switch kind {
+	case agent.RuntimePython:
+		deps, err = pythonDependencies(dir)
+	case agent.RuntimeNode:
+		deps, err = nodeDependencies(dir)
+	default:
+		if runtime := strings.TrimSpace(agentCfg.Runtime); runtime != "" && runtime != "auto" {
+			return fmt.Errorf("agent %s has runtime %s; dependencies are not managed", name, runtime)
+		}
+		return fmt.Errorf("no dependency manifest found in %s", dir)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := queryOSV(deps); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: vulnerability lookup failed: %v\n", err)
+	}
+
+	if jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(deps); err != nil {
+			return err
+		}
+	} else {
+		printDependencyReport(name, kind, deps)
+	}
+
+	if !update {
+		return nil
+	}
+	return updateAgentDependencies(dir, kind, deps)
+}
+
+func loadLocalAgentConfig(name string) (*agent.AgentConfig, error) {
+	configFile, err := config.GetConfigFile()
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := agent.LoadConfig(configFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load agents config: %w", err)
+	}
+	for i := range cfg.Agents {
+		if cfg.Agents[i].Name == name {
+			return &cfg.Agents[i], nil
+		}
+	}
+	return nil, fmt.Errorf("agent '%s' not found in local agents config", name)
+}
+
+func pythonDependencies(dir string) ([]dependency, error) {
+	python := filepath.Join(dir, ".venv", "bin", "python")
+	if _, err := os.Stat(python); err != nil {
+		return nil, fmt.Errorf("virtualenv not found in %s (start the agent once to provision it)", dir)
+	}
+
+	type pipPackage struct {
+		Name          string `json:"name"`
+		Version       string `json:"version"`
+		LatestVersion string `json:"latest_version"`
+	}
+
+	var installed []pipPackage
+	if err := runJSON(dir, &installed, python, "-m", "pip", "list", "--format=json"); err != nil {
+		return nil, fmt.Errorf("pip list: %w", err)
+	}
+
+	var outdated []pipPackage
+	if err := runJSON(dir, &outdated, python, "-m", "pip", "list", "--outdated", "--format=json"); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not check for outdated packages: %v\n", err)
+	}
+	latest := make(map[string]string, len(outdated))
+	for _, pkg := range outdated {
+		latest[strings.ToLower(pkg.Name)] = pkg.LatestVersion
+	}
+
+	deps := make([]dependency, 0, len(installed))
+	for _, pkg := range installed {
+		deps = append(deps, dependency{
+			Name:      pkg.Name,
+			Version:   pkg.Version,
+			Latest:    latest[strings.ToLower(pkg.Name)],
+			ecosystem: "PyPI",
+		})
+	}
+	return deps, nil
+}
+
+func nodeDependencies(dir string) ([]dependency, error) {
+	var tree struct {
+		Dependencies map[string]struct {
+			Version string `json:"version"`
+		} `json:"dependencies"`
+	}
+	// npm ls exits non-zero on peer dependency problems but still prints the tree.
+	if err := runJSON(dir, &tree, "npm", "ls", "--json", "--depth=0"); err != nil && tree.Dependencies == nil {
+		return nil, fmt.Errorf("npm ls: %w", err)
+	}
+
+	// npm outdated exits 1 whenever something is outdated.
+	var outdated map[string]struct {
+		Latest string `json:"latest"`
+	}
+	_ = runJSON(dir, &outdated, "npm", "outdated", "--json")
+
+	deps := make([]dependency, 0, len(tree.Dependencies))
+	for name, info := range tree.Dependencies {
+		dep := dependency{Name: name, Version: info.Version, ecosystem: "npm"}
+		if o, ok := outdated[name]; ok && o.Latest != info.Version {
+			dep.Latest = o.Latest
+		}
+		deps = append(deps, dep)
+	}
+	sort.Slice(deps, func(i, j int) bool { return deps[i].Name < deps[j].Name })
+	return deps, nil
+}
+
+// runJSON runs a command in dir and decodes its stdout into v.
+func runJSON(dir string, v any, name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	runErr := cmd.Run()
+	if stdout.Len() > 0 {
+		if err := json.Unmarshal(stdout.Bytes(), v); err != nil {
+			return fmt.Errorf("parse %s output: %w", name, err)
+		}
+	}
+	if runErr != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%w: %s", runErr, msg)
+		}
+		return runErr
+	}
+	return nil
+}
+
+// queryOSV fills in known vulnerability IDs for each dependency.
+func queryOSV(deps []dependency) error {
+	if len(deps) == 0 {
+		return nil
+	}
+
+	type osvPackage struct {
+		Name      string `json:"name"`
+		Ecosystem string `json:"ecosystem"`
+	}
+	type osvQuery struct {
+		Package osvPackage `json:"package"`
+		Version string     `json:"version"`
+	}
+
+	queries := make([]osvQuery, len(deps))
+	for i, dep := range deps {
+		queries[i] = osvQuery{
+			Package: osvPackage{Name: dep.Name, Ecosystem: dep.ecosystem},
+			Version: dep.Version,
+		}
+	}
+
+	payload, err := json.Marshal(map[string]any{"queries": queries})
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Post(osvQueryBatchURL, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("OSV API returned status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Results []struct {
+			Vulns []struct {
+				ID string `json:"id"`
+			} `json:"vulns"`
+		} `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("decode OSV response: %w", err)
+	}
+
+	for i := range result.Results {
+		if i >= len(deps) {
+			break
+		}
+		for _, v := range result.Results[i].Vulns {
+			deps[i].Vulns = append(deps[i].Vulns, v.ID)
+		}
+	}
+	return nil
+}
+
+func printDependencyReport(name string, kind agent.RuntimeKind, deps []dependency) {
+	var outdated, vulnerable int
+	for _, dep := range deps {
+		if dep.Latest != "" {
+			outdated++
+		}
+		if len(dep.Vulns) > 0 {
+			vulnerable++
+		}
+	}
+
+	fmt.Printf("Dependencies for '%s' (%s): %d packages, %d outdated, %d vulnerable\n\n",
+		name, kind, len(deps), outdated, vulnerable)
+
+	if outdated == 0 && vulnerable == 0 {
+		fmt.Println("All dependencies are up to date with no known vulnerabilities")
+		return
+	}
+
+	fmt.Printf("%-30s %-15s %-15s %s\n", "PACKAGE", "INSTALLED", "LATEST", "VULNERABILITIES")
+	fmt.Println(strings.Repeat("-", 90))
+	for _, dep := range deps {
+		if dep.Latest == "" && len(dep.Vulns) == 0 {
+			continue
+		}
+		latest := dep.Latest
+		if latest == "" {
+			latest = "-"
+		}
+		vulns := "-"
+		if len(dep.Vulns) > 0 {
+			vulns = strings.Join(dep.Vulns, ", ")
+		}
+		fmt.Printf("%-30s %-15s %-15s %s\n", dep.Name, dep.Version, latest, vulns)
+	}
+}
+
+// updateAgentDependencies upgrades outdated or vulnerable packages. The
+// daemon re-provisions the environment on the next start since the lockfile
+// fingerprint changes.
+func updateAgentDependencies(dir string, kind agent.RuntimeKind, deps []dependency) error {
+	var names []string
+	for _, dep := range deps {
+		if dep.Latest != "" || len(dep.Vulns) > 0 {
+			names = append(names, dep.Name)
+		}
+	}
+	if len(names) == 0 {
+		fmt.Println("\nNothing to update")
+		return nil
+	}
+
+	fmt.Printf("\nUpdating %d package(s)...\n", len(names))
+
+	var cmd *exec.Cmd
+	switch kind {
+	case agent.RuntimePython:
+		if _, err := os.Stat(filepath.Join(dir, "uv.lock")); err == nil {
+			args := []string{"lock"}
+			for _, n := range names {
+				args = append(args, "--upgrade-package", n)
+			}
+			cmd = exec.Command("uv", args...)
+		} else {
+			python := filepath.Join(dir, ".venv", "bin", "python")
+			cmd = exec.Command(python, append([]string{"-m", "pip", "install", "--upgrade"}, names...)...)
+		}
+	case agent.RuntimeNode:
+		cmd = exec.Command("npm", append([]string{"update"}, names...)...)
+	default:
+		return fmt.Errorf("updates are not supported for runtime %s", kind)
+	}
+
+	cmd.Dir = dir
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("update dependencies: %w", err)
+	}
+
+	fmt.Println("Dependencies updated; restart the agent to pick up the changes")
+	return nil
+}
@@ -0,0 +1,47 @@
+package cli
+
+import (
+	"fmt"
+
+	"opperator/config"
+)
+
+// ShowDirs prints where the selected instance keeps its files.
+func ShowDirs() error {
+	dirs, err := config.ResolveDirs()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Config: %s\n", dirs.Config)
+	fmt.Printf("Data:   %s\n", dirs.Data)
+	fmt.Printf("State:  %s\n", dirs.State)
+	if instance := config.Instance(); instance != "" {
+		fmt.Printf("Instance: %s\n", instance)
+	}
+	return nil
+}
+
+// MigrateDirs moves the database, agent data and logs out of the config
+// directory into the XDG data and state directories. The caller makes sure
+// the local daemon is stopped.
+func MigrateDirs(dryRun bool) error {
+	moves, err := config.PlanDirMigration()
+	if err != nil {
+		return err
+	}
+	if len(moves) == 0 {
+		fmt.Println("Nothing to move; the database and logs are already in place")
+		return nil
+	}
+	for _, m := range moves {
+		fmt.Printf("%s -> %s\n", m.From, m.To)
+	}
+	if dryRun {
+		return nil
+	}
+	if err := config.MigrateDirs(moves); err != nil {
+		return err
+	}
+	fmt.Printf("✓ Moved %d item(s)\n", len(moves))
+	return nil
+}
@@ -7,6 +7,8 @@ import (
 	"os"
 	"strings"
 	"sync"
+
+	"golang.org/x/term"
 )
 
 // EventEmitter defines the interface for emitting execution events
@@ -77,6 +79,14 @@ func NewJSONEmitter() *JSONEmitter {
 	}
 }
 
+// newJSONEmitterTo creates a JSON emitter that writes each event to w in a
+// single Write call.
+func newJSONEmitterTo(w io.Writer) *JSONEmitter {
+	return &JSONEmitter{
+		output: w,
+	}
+}
+
 func (e *JSONEmitter) emit(event interface{}) {
 	e.mu.Lock()
 	defer e.mu.Unlock()
@@ -235,11 +245,19 @@ func (e *JSONEmitter) PrintResumeInfo(conversationID string)
 // StderrEmitter writes pretty-formatted output to stderr (existing behavior)
 type StderrEmitter struct {
 	mu sync.Mutex
+	// markdown renders streamed responses as markdown instead of raw text
+	markdown bool
+}
+
+// NewStderrEmitter creates a new stderr emitter (pretty-print mode).
+// Responses are rendered as markdown when stderr is a terminal, unless plain
+// is set.
+func NewStderrEmitter(plain bool) *StderrEmitter {
+	return &StderrEmitter{markdown: !plain && term.IsTerminal(int(os.Stderr.Fd()))}
 }
 
-// NewStderrEmitter creates a new stderr emitter (pretty-print mode)
-func NewStderrEmitter() *StderrEmitter {
-	return &StderrEmitter{}
+func (e *StderrEmitter) rendersMarkdown() bool {
+	return e.markdown
 }
 
 // Event methods (no-ops for stderr mode - we use Print* methods instead)
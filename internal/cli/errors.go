@@ -0,0 +1,38 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"opperator/internal/ipc"
+)
+
+// PrintError writes err to stderr followed by a hint for error categories the
+// user can act on.
+func PrintError(err error) {
+	if err == nil {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	if hint := errorHint(err); hint != "" {
+		fmt.Fprintf(os.Stderr, "Hint: %s\n", hint)
+	}
+}
+
+func errorHint(err error) string {
+	switch ipc.CodeOf(err) {
+	case ipc.ErrCodeNotFound:
+		return "check the name; 'op agent list' shows the agents on each daemon"
+	case ipc.ErrCodeUnauthorized:
+		return "the daemon rejected the auth token; re-add it with 'op daemon add'"
+	case ipc.ErrCodeForbidden:
+		return "the auth token's role does not allow this; use a token with a higher role"
+	case ipc.ErrCodeBusy:
+		return "the daemon is busy with this resource; retry in a moment"
+	case ipc.ErrCodeTimeout:
+		return "the request timed out; retry or raise --timeout"
+	case ipc.ErrCodeUnavailable:
+		return "make sure the daemon and agent are running ('op daemon start', 'op agent start')"
+	}
+	return ""
+}
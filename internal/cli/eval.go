@@ -0,0 +1,252 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"opperator/internal/credentials"
+	"opperator/internal/eval"
+	"opperator/pkg/db"
+	"tui/opper"
+)
+
+// EvalOptions configures 'op eval run'.
+type EvalOptions struct {
+	// Agent overrides the agent named in the suite.
+	Agent string
+	// JudgeModel is the model grading judge checks; empty uses the default.
+	JudgeModel string
+	// Keep leaves the conversations the cases ran in.
+	Keep bool
+	JSON bool
+	// MinPassRate fails the run below this share of passing cases.
+	MinPassRate float64
+}
+
+// EvalRun runs every case of the suite at path against an agent or core
+// agent and reports the pass rate.
+func EvalRun(path string, opts EvalOptions) error {
+	suite, err := eval.LoadSuite(path)
+	if err != nil {
+		return err
+	}
+	if suite.Name == "" {
+		suite.Name = path
+	}
+	ctx := context.Background()
+
+	var kept []string
+	runner := func(ctx context.Context, agentName string, messages []string) (string, error) {
+		reply, conversationID, err := runEvalConversation(ctx, agentName, messages)
+		if conversationID != "" {
+			if opts.Keep {
+				kept = append(kept, conversationID)
+			} else {
+				deleteEvalConversation(ctx, conversationID)
+			}
+		}
+		return reply, err
+	}
+
+	report := eval.Run(ctx, suite, eval.Options{
+		Agent:            opts.Agent,
+		Run:              runner,
+		Judge:            opperJudge(opts.JudgeModel),
+		LoadConversation: loadEvalConversation,
+		Progress: func(r eval.Result) {
+			if !opts.JSON {
+				printEvalResult(r)
+			}
+		},
+	})
+
+	if opts.JSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			return err
+		}
+	} else {
+		agentName := report.Agent
+		if agentName == "" {
+			agentName = "default agent"
+		}
+		fmt.Printf("\n%s (%s): %d/%d passed (%.0f%%)\n", report.Suite, agentName, report.Passed, report.Total, report.PassRate()*100)
+		if len(kept) > 0 {
+			fmt.Println("Conversations kept:", strings.Join(kept, ", "))
+		}
+	}
+
+	if report.PassRate() < opts.MinPassRate {
+		return fmt.Errorf("pass rate %.0f%% is below %.0f%%", report.PassRate()*100, opts.MinPassRate*100)
+	}
+	return nil
+}
+
+func printEvalResult(r eval.Result) {
+	if r.Passed {
+		fmt.Printf("✓ %s (%.1fs)\n", r.Case, float64(r.DurationMS)/1000)
+		return
+	}
+	fmt.Printf("✗ %s (%.1fs)\n", r.Case, float64(r.DurationMS)/1000)
+	if r.Error != "" {
+		fmt.Printf("    error: %s\n", r.Error)
+	}
+	for _, f := range r.Failures {
+		fmt.Printf("    %s\n", f)
+	}
+	if r.Reply != "" {
+		reply := strings.Join(strings.Fields(r.Reply), " ")
+		if len(reply) > 200 {
+			reply = reply[:197] + "..."
+		}
+		fmt.Printf("    reply: %s\n", reply)
+	}
+}
+
+// runEvalConversation sends messages in one new saved conversation, the
+// same way 'op exec --resume' would, and returns the last reply and the
+// conversation ID.
+func runEvalConversation(ctx context.Context, agentName string, messages []string) (string, string, error) {
+	var conversationID, reply string
+	for _, message := range messages {
+		var out bytes.Buffer
+		err := execSession(ctx, message, agentName, conversationID, false, false, newJSONEmitterTo(&out))
+
+		var failure string
+		scanner := bufio.NewScanner(&out)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		for scanner.Scan() {
+			var ev struct {
+				Type          string `json:"type"`
+				SessionID     string `json:"session_id"`
+				FinalResponse string `json:"final_response"`
+				Error         string `json:"error"`
+			}
+			if json.Unmarshal(scanner.Bytes(), &ev) != nil {
+				continue
+			}
+			switch ev.Type {
+			case "session.started":
+				conversationID = ev.SessionID
+			case "session.completed":
+				reply = ev.FinalResponse
+			case "session.failed":
+				failure = ev.Error
+			}
+		}
+		if err != nil {
+			return "", conversationID, err
+		}
+		if failure != "" {
+			return "", conversationID, fmt.Errorf("%s", failure)
+		}
+	}
+	return reply, conversationID, nil
+}
+
+// loadEvalConversation returns the user messages of a recorded
+// conversation and its last assistant reply.
+func loadEvalConversation(ctx context.Context, id string) ([]string, string, error) {
+	conv, err := loadShareConversation(ctx, id)
+	if err != nil {
+		return nil, "", err
+	}
+	var messages []string
+	var lastReply string
+	for _, msg := range conv.Messages {
+		switch msg.Role {
+		case "user":
+			messages = append(messages, msg.Text)
+		case "assistant":
+			lastReply = msg.Text
+		}
+	}
+	return messages, lastReply, nil
+}
+
+func deleteEvalConversation(ctx context.Context, id string) {
+	writeDB, err := db.GetWriteDB()
+	if err != nil {
+		return
+	}
+	if _, err := writeDB.ExecContext(ctx, `DELETE FROM conversations WHERE id = ?`, id); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to delete eval conversation %s: %v\n", id, err)
+	}
+}
+
+// opperJudge grades replies with a model call through Opper.
+func opperJudge(model string) eval.Judge {
+	return func(ctx context.Context, in eval.JudgeInput) (bool, string, error) {
+		apiKey, err := credentials.GetSecret(credentials.OpperAPIKeyName)
+		if err != nil && opper.Local() == nil {
+			return false, "", fmt.Errorf("failed to read Opper API key: %w (run: op secret create %s)", err, credentials.OpperAPIKeyName)
+		}
+
+		instructions := "You grade an AI assistant's response. Decide whether the response meets the criteria, " +
+			"using the reference response, when given, as an example of a passing answer. " +
+			"Be strict about facts and requested content, lenient about wording and formatting. " +
+			"Give a one-sentence reason."
+		req := opper.StreamRequest{
+			Name:         "opperator.eval_judge",
+			Instructions: &instructions,
+			Input:        in,
+			OutputSchema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"reason": map[string]any{"type": "string"},
+					"pass":   map[string]any{"type": "boolean"},
+				},
+				"required": []string{"reason", "pass"},
+			},
+			Model: modelIdentifier(),
+		}
+		if model != "" {
+			req.Model = model
+		}
+
+		events, err := opper.New(apiKey).Stream(ctx, req)
+		if err != nil {
+			return false, "", err
+		}
+		agg := opper.NewJSONChunkAggregator()
+		chunks := 0
+		var streamErr error
+		for event := range events {
+			if event.Err != nil {
+				streamErr = event.Err
+			}
+			if event.Data.JSONPath != "" && event.Data.Delta != nil {
+				agg.Add(event.Data.JSONPath, event.Data.Delta)
+				chunks++
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return false, "", err
+		}
+		if streamErr != nil {
+			return false, "", streamErr
+		}
+		if chunks == 0 {
+			return false, "", fmt.Errorf("no verdict returned")
+		}
+
+		assembled, err := agg.Assemble()
+		if err != nil {
+			return false, "", fmt.Errorf("failed to assemble verdict: %w", err)
+		}
+		var verdict struct {
+			Reason string `json:"reason"`
+			Pass   bool   `json:"pass"`
+		}
+		if err := json.Unmarshal([]byte(assembled), &verdict); err != nil {
+			return false, "", fmt.Errorf("failed to parse verdict: %w", err)
+		}
+		return verdict.Pass, verdict.Reason, nil
+	}
+}
@@ -6,23 +6,26 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
-	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/charmbracelet/lipgloss"
 	"opperator/config"
+	"opperator/internal/agent"
 	"opperator/internal/credentials"
 	"opperator/internal/ipc"
 	"opperator/internal/protocol"
 	"opperator/pkg/db"
+	"opperator/pkg/keyringerr"
+	"opperator/pkg/loopdetect"
+	"opperator/pkg/msgstore"
+	"opperator/pkg/tracing"
 	"tui/coreagent"
 	"tui/opper"
+	"tui/sessionstate"
 	"tui/tools"
 )
 
-const maxFollowUpRounds = 60 // Prevent infinite loops
-
 // Styles for CLI output (matching TUI theme)
 var (
 	primary   = lipgloss.Color("#f7c0af") // orangish/peach
@@ -48,32 +51,45 @@ var (
 
 // ExecMessage sends a message to an agent and returns the response.
 // Activity is streamed to stderr (or as JSON events), final response to stdout.
-func ExecMessage(messageText, agentName, conversationID string, jsonMode, noSave bool) error {
-	ctx := context.Background()
+// With mockTools set, tool calls resolve from that fixtures file instead of
+// reaching agents. Limits set in limits override those of
+// conversation.yaml.
+func ExecMessage(messageText, agentName, conversationID string, jsonMode, plain, noSave, record bool, mockTools string, limits config.Limits) error {
+	ctx := withLimitOverrides(context.Background(), limits)
+	if mockTools != "" {
+		fixtures, err := tools.LoadMockFixtures(mockTools)
+		if err != nil {
+			return err
+		}
+		ctx = tools.WithMockFixtures(ctx, fixtures)
+	}
 
 	// Create the appropriate emitter based on mode
 	var emitter EventEmitter
 	if jsonMode {
 		emitter = NewJSONEmitter()
 	} else {
-		emitter = NewStderrEmitter()
+		emitter = NewStderrEmitter(plain)
 	}
+	return execSession(ctx, messageText, agentName, conversationID, noSave, record, emitter)
+}
 
-	// Get API key
+// execSession runs one message through the conversation loop, reporting
+// activity to emitter. The CLI and the web chat both go through it. With
+// record, or OPPERATOR_RECORD=1, its model calls are stored with the
+// conversation for 'op conversation replay'.
+func execSession(ctx context.Context, messageText, agentName, conversationID string, noSave, record bool, emitter EventEmitter) error {
+	// Get API key; not needed when llm.yaml points at a local server
 	apiKey, err := credentials.GetSecret(credentials.OpperAPIKeyName)
-	if err != nil {
+	if err != nil && opper.Local() == nil {
+		if keyringerr.Is(err) {
+			return fmt.Errorf("failed to read Opper API key: %w", err)
+		}
 		return fmt.Errorf("failed to read Opper API key: %w (run: op secret create %s)", err, credentials.OpperAPIKeyName)
 	}
 
-	// Initialize database
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return fmt.Errorf("failed to get home directory: %w", err)
-	}
-
-	dbPath := filepath.Join(home, ".config", "opperator", "opperator.db")
-	if err := db.Initialize(dbPath); err != nil {
-		return fmt.Errorf("failed to initialize database: %w", err)
+	if err := initializeExecDB(); err != nil {
+		return err
 	}
 
 	readDB, err := db.GetReadDB()
@@ -106,21 +122,12 @@ func ExecMessage(messageText, agentName, conversationID string, jsonMode, noSave
 		}
 
 		// Load message history
-		rows, err := readDB.QueryContext(ctx,
-			`SELECT role, metadata FROM messages WHERE session_id = ? ORDER BY id`,
-			conversationID)
+		stored, err := msgstore.List(ctx, readDB, conversationID, msgstore.ListOptions{})
 		if err != nil {
 			return fmt.Errorf("failed to load conversation history: %w", err)
 		}
-		defer rows.Close()
-
-		for rows.Next() {
-			var role, metadata string
-			if err := rows.Scan(&role, &metadata); err != nil {
-				continue
-			}
-			msg := parseMessageFromMetadata(role, metadata)
-			history = append(history, msg)
+		for _, m := range stored {
+			history = append(history, messageFromStored(m))
 		}
 	} else {
 		// Create new conversation
@@ -133,9 +140,13 @@ func ExecMessage(messageText, agentName, conversationID string, jsonMode, noSave
 		convID = fmt.Sprintf("%d", time.Now().UnixNano())
 
 		if !noSave {
+			var workspace interface{}
+			if ws, err := config.ActiveWorkspace(); err == nil && ws != nil {
+				workspace = ws.Name
+			}
 			_, err = writeDB.ExecContext(ctx,
-				`INSERT INTO conversations(id, title, created_at) VALUES(?, ?, ?)`,
-				convID, convTitle, time.Now().Unix())
+				`INSERT INTO conversations(id, title, created_at, workspace) VALUES(?, ?, ?, ?)`,
+				convID, convTitle, time.Now().Unix(), workspace)
 			if err != nil {
 				return fmt.Errorf("failed to create conversation: %w", err)
 			}
@@ -181,8 +192,10 @@ func ExecMessage(messageText, agentName, conversationID string, jsonMode, noSave
 		agentPrompt = prompt
 		agentPromptReplace = promptReplace
 
-		// Convert commands to tool specs
+		// Convert commands to tool specs; memory and knowledge tools run in
+		// this process
 		toolSpecs = commandsToToolSpecs(agentName, commands)
+		toolSpecs = append(toolSpecs, tools.SharedSpecs()...)
 
 		// Display agent info
 		emitter.PrintAgentInfo(agentName, AgentTypeManaged, agentDesc, len(toolSpecs))
@@ -205,12 +218,9 @@ func ExecMessage(messageText, agentName, conversationID string, jsonMode, noSave
 	}
 
 	// Add user message to history and save
-	now := time.Now().Unix()
-	userMetadata := createTextMetadata(messageText)
+	var userMessageID int64
 	if !noSave {
-		_, err = writeDB.ExecContext(ctx,
-			`INSERT INTO messages(session_id, role, metadata, created_at, updated_at) VALUES(?, ?, ?, ?, ?)`,
-			convID, "user", userMetadata, now, now)
+		userMessageID, err = msgstore.Insert(ctx, writeDB, convID, "user", []msgstore.Part{msgstore.TextPart(messageText)})
 		if err != nil {
 			return fmt.Errorf("failed to save user message: %w", err)
 		}
@@ -223,8 +233,17 @@ func ExecMessage(messageText, agentName, conversationID string, jsonMode, noSave
 	// Build instructions - match TUI behavior with agent context
 	instructions := buildInstructions(agentName, agentPrompt, agentPromptReplace, isCoreAgent)
 
-	// Create Opper client
-	client := opper.New(apiKey)
+	// Create Opper client, recording its calls when asked to
+	var client modelClient = opper.New(apiKey)
+	if recordingRequested(record) {
+		if noSave {
+			fmt.Fprintln(os.Stderr, errorStyle.Render("Warning:")+" "+mutedStyle.Render("model calls are not recorded for unsaved conversations"))
+		} else if recorder, err := newRecordingClient(client, writeDB, convID, userMessageID, agentName); err != nil {
+			fmt.Fprintln(os.Stderr, errorStyle.Render("Warning:")+" "+mutedStyle.Render(err.Error()))
+		} else {
+			client = recorder
+		}
+	}
 
 	// Get IPC client for tool execution (not needed for core agents)
 	var ipcClient *ipc.Client
@@ -266,6 +285,11 @@ func ExecMessage(messageText, agentName, conversationID string, jsonMode, noSave
 	}
 	duration := time.Since(startTime)
 
+	if !noSave {
+		history = append(history, conversationMessage{Role: "assistant", Content: finalResponse})
+		updateConversationTitle(ctx, writeDB, apiKey, convID, convTitle, history)
+	}
+
 	// Emit session completed event
 	emitter.EmitSessionCompleted(SessionCompletedEvent{
 		SessionID:      convID,
@@ -280,10 +304,22 @@ func ExecMessage(messageText, agentName, conversationID string, jsonMode, noSave
 	return nil
 }
 
+// initializeExecDB opens the conversation database shared with the TUI.
+func initializeExecDB() error {
+	dbPath, err := config.GetDatabasePath()
+	if err != nil {
+		return fmt.Errorf("failed to resolve database path: %w", err)
+	}
+	if err := db.Initialize(dbPath); err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	return nil
+}
+
 // executeConversationLoop handles the full conversation loop with tool execution
 func executeConversationLoop(
 	ctx context.Context,
-	client *opper.Opper,
+	client modelClient,
 	ipcClient *ipc.Client,
 	agentName string,
 	history []conversationMessage,
@@ -294,17 +330,39 @@ func executeConversationLoop(
 	emitter EventEmitter,
 	noSave bool,
 ) (finalResponse string, totalTurns int, totalToolCalls int, err error) {
+	ctx, span := tracing.Start(ctx, "exec.conversation", "agent.name", agentName, "session.id", convID)
+	defer func() {
+		span.SetAttributes("exec.turns", totalTurns, "exec.tool_calls", totalToolCalls)
+		span.RecordError(err)
+		span.End()
+	}()
+	ctx = withModelClient(ctx, client)
+
+	limits := conversationLimits(ctx, agentName)
+	if limits.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, limits.Timeout)
+		defer cancel()
+	}
+	cancelTurn := context.CancelFunc(func() {})
+	defer func() { cancelTurn() }()
+
 	currentHistory := append([]conversationMessage{}, history...)
 	roundCount := 0
 	turnNumber := 0
+	loops := loopdetect.New(limits.LoopRepeats)
 
 	for {
-		if roundCount >= maxFollowUpRounds {
-			return "", 0, 0, fmt.Errorf("exceeded maximum follow-up rounds (%d)", maxFollowUpRounds)
+		if roundCount >= limits.MaxRounds {
+			return "", 0, 0, roundLimitError("", limits.MaxRounds)
 		}
 		roundCount++
 		turnNumber++
 
+		var turnCtx context.Context
+		cancelTurn()
+		turnCtx, cancelTurn = turnContext(ctx, limits.TurnTimeout)
+
 		// Emit turn started event
 		emitter.EmitTurnStarted(TurnStartedEvent{
 			SessionID:  convID,
@@ -313,7 +371,7 @@ func executeConversationLoop(
 		})
 
 		// Build conversation for API
-		conversation := buildConversation(currentHistory)
+		conversation := buildConversation(currentHistory, limits)
 
 		// Build request
 		input := map[string]any{
@@ -335,8 +393,18 @@ func executeConversationLoop(
 
 		// Stream response
 		turnStart := time.Now()
-		events, err := client.Stream(ctx, req)
+		streamCtx, streamSpan := tracing.Start(turnCtx, "opper.stream", "exec.turn", turnNumber)
+		budgetCtx := budgetContext(streamCtx, agentName, ipcClient == nil)
+		model := fmt.Sprint(req.Model)
+		err := chargeModelCall(budgetCtx, model, req.Input)
+		var events <-chan opper.SSEEvent
+		if err == nil {
+			events, err = client.Stream(streamCtx, req)
+		}
 		if err != nil {
+			err = limitError(err, ctx, turnCtx, limits)
+			streamSpan.RecordError(err)
+			streamSpan.End()
 			emitter.EmitTurnFailed(TurnFailedEvent{
 				SessionID:  convID,
 				TurnNumber: turnNumber,
@@ -346,8 +414,28 @@ func executeConversationLoop(
 		}
 
 		// Parse streaming response (no indentation for main agent)
-		result, err := parseStreamingResponse(ctx, events, "", convID, emitter)
+		result, err := parseStreamingResponse(streamCtx, events, "", convID, emitter)
+		recordStreamOutput(budgetCtx, model, result)
 		if err != nil {
+			err = limitError(err, ctx, turnCtx, limits)
+		}
+		streamSpan.SetAttributes("exec.tool_calls", len(result.ToolCalls))
+		streamSpan.RecordError(err)
+		streamSpan.End()
+		if err != nil {
+			if !noSave && result.Text != "" {
+				savePartialResponse(context.WithoutCancel(ctx), writeDB, convID, result.Text)
+			}
+			emitter.EmitTurnFailed(TurnFailedEvent{
+				SessionID:  convID,
+				TurnNumber: turnNumber,
+				Error:      err.Error(),
+			})
+			return "", totalTurns, totalToolCalls, err
+		}
+
+		if err := checkLoop(loops, result); err != nil {
+			span.SetAttributes(err.Attributes()...)
 			emitter.EmitTurnFailed(TurnFailedEvent{
 				SessionID:  convID,
 				TurnNumber: turnNumber,
@@ -358,13 +446,15 @@ func executeConversationLoop(
 
 		// If no tool calls, we're done - return the text
 		if len(result.ToolCalls) == 0 {
+			if ipcClient != nil && strings.TrimSpace(result.Text) != "" {
+				if reason := checkGuardrail(ipcClient, agentName, agent.GuardrailCheck{Text: result.Text}, emitter); reason != "" {
+					result.Text = "Response withheld by guardrail: " + reason
+				}
+			}
+
 			// Save assistant message if we have text
 			if !noSave && strings.TrimSpace(result.Text) != "" {
-				assistantMetadata := createTextMetadata(result.Text)
-				now := time.Now().Unix()
-				_, err = writeDB.ExecContext(ctx,
-					`INSERT INTO messages(session_id, role, metadata, created_at, updated_at) VALUES(?, ?, ?, ?, ?)`,
-					convID, "assistant", assistantMetadata, now, now)
+				_, err = msgstore.Insert(ctx, writeDB, convID, "assistant", []msgstore.Part{msgstore.TextPart(result.Text)})
 				if err != nil {
 					fmt.Fprintln(os.Stderr, errorStyle.Render("Warning:")+" "+mutedStyle.Render(fmt.Sprintf("failed to save message: %v", err)))
 				}
@@ -392,11 +482,7 @@ func executeConversationLoop(
 
 			// Save assistant message to database
 			if !noSave {
-				assistantMetadata := createTextMetadata(result.Text)
-				now := time.Now().Unix()
-				_, err = writeDB.ExecContext(ctx,
-					`INSERT INTO messages(session_id, role, metadata, created_at, updated_at) VALUES(?, ?, ?, ?, ?)`,
-					convID, "assistant", assistantMetadata, now, now)
+				_, err = msgstore.Insert(ctx, writeDB, convID, "assistant", []msgstore.Part{msgstore.TextPart(result.Text)})
 				if err != nil {
 					fmt.Fprintln(os.Stderr, errorStyle.Render("Warning:")+" "+mutedStyle.Render(fmt.Sprintf("failed to save assistant message: %v", err)))
 				}
@@ -415,11 +501,8 @@ func executeConversationLoop(
 		if !noSave {
 			err = db.WithTx(ctx, func(tx *sql.Tx) error {
 				for _, tc := range result.ToolCalls {
-					toolCallMetadata := createToolCallMetadata(tc)
-					now := time.Now().Unix()
-					_, err := tx.ExecContext(ctx,
-						`INSERT INTO messages(session_id, role, metadata, created_at, updated_at) VALUES(?, ?, ?, ?, ?)`,
-						convID, "tool_call", toolCallMetadata, now, now)
+					argsJSON, _ := json.Marshal(tc.Arguments)
+					_, err := msgstore.InsertTx(ctx, tx, convID, "tool_call", []msgstore.Part{msgstore.ToolCallPart(tc.ID, tc.Name, string(argsJSON))})
 					if err != nil {
 						return err
 					}
@@ -432,7 +515,7 @@ func executeConversationLoop(
 		}
 
 		// Execute tool calls (emitter handles the display)
-		toolResults := executeToolCalls(ctx, ipcClient, agentName, result.ToolCalls, convID, emitter)
+		toolResults := executeToolCalls(turnCtx, ipcClient, agentName, result.ToolCalls, convID, emitter)
 
 		// Track tool call count
 		totalToolCalls += len(result.ToolCalls)
@@ -450,11 +533,7 @@ func executeConversationLoop(
 		if !noSave {
 			err = db.WithTx(ctx, func(tx *sql.Tx) error {
 				for _, toolResult := range toolResults {
-					toolResponseMetadata := createToolCallResponseMetadata(toolResult.ID, toolResult.Name, toolResult.Output)
-					now := time.Now().Unix()
-					_, err := tx.ExecContext(ctx,
-						`INSERT INTO messages(session_id, role, metadata, created_at, updated_at) VALUES(?, ?, ?, ?, ?)`,
-						convID, "tool_call_response", toolResponseMetadata, now, now)
+					_, err := msgstore.InsertTx(ctx, tx, convID, "tool_call_response", []msgstore.Part{msgstore.ToolResultPart(toolResult.ID, toolResult.Name, toolResult.Output, false)})
 					if err != nil {
 						return err
 					}
@@ -466,6 +545,16 @@ func executeConversationLoop(
 			}
 		}
 
+		if turnCtx.Err() != nil {
+			err := limitError(turnCtx.Err(), ctx, turnCtx, limits)
+			emitter.EmitTurnFailed(TurnFailedEvent{
+				SessionID:  convID,
+				TurnNumber: turnNumber,
+				Error:      err.Error(),
+			})
+			return "", totalTurns, totalToolCalls, err
+		}
+
 		// Emit turn completed event
 		turnDuration := time.Since(turnStart)
 		emitter.EmitTurnCompleted(TurnCompletedEvent{
@@ -487,6 +576,23 @@ type StreamResult struct {
 	ToolCalls []ToolCall
 }
 
+// checkLoop feeds a round that called tools to loops, returning the loop
+// it completes, if any. Rounds without tool calls end the turn anyway.
+func checkLoop(loops *loopdetect.Detector, result StreamResult) *loopdetect.LoopError {
+	var err error
+	if len(result.ToolCalls) > 0 {
+		err = loops.Text(result.Text)
+	}
+	for _, tc := range result.ToolCalls {
+		if err != nil {
+			break
+		}
+		err = loops.ToolCall(tc.Name, tc.Arguments)
+	}
+	loop, _ := err.(*loopdetect.LoopError)
+	return loop
+}
+
 // ToolCall represents a tool invocation
 type ToolCall struct {
 	ID        string
@@ -525,8 +631,54 @@ func parseStreamingResponse(ctx context.Context, events <-chan opper.SSEEvent, i
 	// Track if we've started streaming to set color once
 	streamStarted := false
 	atLineStart := true // Track if we're at the beginning of a line
+	var markdown *markdownStream
+	if e, ok := emitter.(interface{ rendersMarkdown() bool }); ok && e.rendersMarkdown() {
+		markdown = newMarkdownStream(emitter.PrintStreamingText, indent)
+	}
+	streamText := func(deltaStr string) {
+		textBuilder.WriteString(deltaStr)
+
+		// Emit item updated event
+		emitter.EmitItemUpdated(ItemEvent{
+			SessionID: sessionID,
+			Item: Item{
+				ID:     itemID,
+				Type:   ItemTypeAgentMessage,
+				Status: "streaming",
+				Text:   textBuilder.String(),
+			},
+		})
+
+		if markdown != nil {
+			streamStarted = true
+			markdown.Write(deltaStr)
+			return
+		}
+		if !streamStarted {
+			if indent != "" {
+				emitter.PrintStreamingText(indent)
+			}
+			emitter.PrintStreamingText(responseColorStart)
+			streamStarted = true
+			atLineStart = false // We just printed the indent
+		}
+
+		// Add indentation at the start of each line
+		for _, ch := range deltaStr {
+			if atLineStart && indent != "" {
+				emitter.PrintStreamingText(indent)
+			}
+			emitter.PrintStreamingText(string(ch))
+			atLineStart = (ch == '\n')
+		}
+	}
+	var streamErr error
 
 	for event := range events {
+		if event.Err != nil {
+			streamErr = event.Err
+			continue
+		}
 		chunk := event.Data
 
 		// Handle JSON chunks (structured output)
@@ -541,78 +693,40 @@ func parseStreamingResponse(ctx context.Context, events <-chan opper.SSEEvent, i
 			isTextField := path == "text" || strings.HasSuffix(path, ".text")
 			if isTextField {
 				if deltaStr, ok := chunk.Delta.(string); ok && deltaStr != "" {
-					if !streamStarted {
-						if indent != "" {
-							emitter.PrintStreamingText(indent)
-						}
-						emitter.PrintStreamingText(responseColorStart)
-						streamStarted = true
-						atLineStart = false // We just printed the indent
-					}
-					textBuilder.WriteString(deltaStr)
-
-					// Emit item updated event
-					emitter.EmitItemUpdated(ItemEvent{
-						SessionID: sessionID,
-						Item: Item{
-							ID:     itemID,
-							Type:   ItemTypeAgentMessage,
-							Status: "streaming",
-							Text:   textBuilder.String(),
-						},
-					})
-
-					// Add indentation at the start of each line
-					for _, ch := range deltaStr {
-						if atLineStart && indent != "" {
-							emitter.PrintStreamingText(indent)
-						}
-						emitter.PrintStreamingText(string(ch))
-						atLineStart = (ch == '\n')
-					}
+					streamText(deltaStr)
 				}
 			}
 		} else if deltaStr, ok := chunk.Delta.(string); ok && deltaStr != "" {
 			// Plain text streaming
-			if !streamStarted {
-				if indent != "" {
-					emitter.PrintStreamingText(indent)
-				}
-				emitter.PrintStreamingText(responseColorStart)
-				streamStarted = true
-				atLineStart = false // We just printed the indent
-			}
-			textBuilder.WriteString(deltaStr)
-
-			// Emit item updated event
-			emitter.EmitItemUpdated(ItemEvent{
-				SessionID: sessionID,
-				Item: Item{
-					ID:     itemID,
-					Type:   ItemTypeAgentMessage,
-					Status: "streaming",
-					Text:   textBuilder.String(),
-				},
-			})
-
-			// Add indentation at the start of each line
-			for _, ch := range deltaStr {
-				if atLineStart && indent != "" {
-					emitter.PrintStreamingText(indent)
-				}
-				emitter.PrintStreamingText(string(ch))
-				atLineStart = (ch == '\n')
-			}
+			streamText(deltaStr)
 		}
 	}
 
-	// Reset color at end of stream
-	if streamStarted {
+	if markdown != nil {
+		markdown.Close()
+	} else if streamStarted {
+		// Reset color at end of stream
 		emitter.PrintStreamingText(colorReset)
+	}
+	if streamStarted {
 		emitter.PrintStreamingComplete()
 	}
 
 	result.Text = strings.TrimSpace(textBuilder.String())
+	if streamErr != nil {
+		// Tool calls of a cut-off answer may be incomplete, so only the text
+		// is kept
+		emitter.EmitItemCompleted(ItemEvent{
+			SessionID: sessionID,
+			Item: Item{
+				ID:     itemID,
+				Type:   ItemTypeAgentMessage,
+				Status: "interrupted",
+				Text:   result.Text,
+			},
+		})
+		return result, streamErr
+	}
 
 	// Try to parse structured output for tool calls
 	assembled, err := aggregator.Assemble()
@@ -664,9 +778,12 @@ func executeToolCalls(ctx context.Context, ipcClient *ipc.Client, agentName stri
 		// Extract command name from tool name (format: agentName__commandName)
 		commandName := strings.TrimPrefix(call.Name, agentName+"__")
 
+		// Memory and knowledge tools run in this process for managed agents too
+		localTool := isCoreAgent || tools.IsSharedToolName(call.Name)
+
 		// Display tool name without agentName__ prefix
 		displayName := call.Name
-		if isCoreAgent {
+		if localTool {
 			displayName = commandName
 		}
 
@@ -688,10 +805,33 @@ func executeToolCalls(ctx context.Context, ipcClient *ipc.Client, agentName stri
 		var output string
 		var isError bool
 		startTime := time.Now()
-
+		callCtx, callSpan := tracing.Start(ctx, "exec.tool_call", "tool.name", call.Name, "agent.name", agentName)
+		// Memory tools find their agent and conversation in the context
 		if isCoreAgent {
+			callCtx = tools.WithAgentContext(callCtx, "", agentName)
+		} else {
+			callCtx = tools.WithAgentContext(callCtx, agentName, "")
+		}
+		callCtx = tools.WithSessionContext(callCtx, sessionID, call.ID)
+
+		// With --mock-tools every call resolves from fixtures; the agent
+		// tool without a fixture still runs its sub-agent, with mocked tools
+		mocks := tools.MockFixturesFromContext(callCtx)
+		if mocks != nil && (call.Name != "agent" || mocks.Has(call.Name)) {
+			argsJSON, _ := json.Marshal(call.Arguments)
+			result := mocks.Resolve(call.Name, string(argsJSON))
+			output, isError = result.Output, result.Error
+			if isError {
+				emitter.PrintToolError("mocked")
+			} else {
+				emitter.PrintToolSuccess("mocked")
+			}
+		} else if localTool {
 			// Execute core agent tool directly
-			output, isError = executeCoreAgentTool(ctx, call.Name, call.Arguments)
+			output, isError = executeCoreAgentTool(callCtx, call.Name, call.Arguments)
+			// Core tools run in this process, so their output has not been
+			// scrubbed by the daemon.
+			output = credentials.Redact(output)
 			if isError {
 				emitter.PrintToolError("failed")
 			} else {
@@ -722,7 +862,13 @@ func executeToolCalls(ctx context.Context, ipcClient *ipc.Client, agentName stri
 					})
 				}
 			}
-			resp, err := ipcClient.InvokeCommandWithProgress(agentName, commandName, call.Arguments, 30*time.Minute, progressFn)
+			var resp *ipc.CommandResponse
+			var err error
+			if reason := checkGuardrail(ipcClient, agentName, agent.GuardrailCheck{Command: commandName, Arguments: call.Arguments}, emitter); reason != "" {
+				err = fmt.Errorf("blocked by guardrail: %s", reason)
+			} else {
+				resp, err = ipcClient.InvokeCommandContext(callCtx, agentName, commandName, call.Arguments, 30*time.Minute, progressFn)
+			}
 
 			if err != nil {
 				output = fmt.Sprintf("Error: %v", err)
@@ -753,6 +899,10 @@ func executeToolCalls(ctx context.Context, ipcClient *ipc.Client, agentName stri
 		}
 
 		duration := time.Since(startTime)
+		if isError {
+			callSpan.RecordError(fmt.Errorf("%s", output))
+		}
+		callSpan.End()
 
 		// Emit item completed event
 		itemStatus := "completed"
@@ -825,6 +975,10 @@ func executeCoreAgentTool(ctx context.Context, toolName string, arguments map[st
 		output, _ := tools.RunMoveAgent(ctx, argsStr)
 		return output, strings.HasPrefix(strings.ToLower(output), "error")
 
+	case tools.SpawnSessionAgentToolName:
+		output, _ := tools.RunSpawnSessionAgent(ctx, argsStr)
+		return output, strings.HasPrefix(strings.ToLower(output), "error")
+
 	case tools.FocusAgentToolName:
 		output, _ := tools.RunFocusAgent(ctx, argsStr)
 		return output, strings.HasPrefix(strings.ToLower(output), "error")
@@ -837,6 +991,22 @@ func executeCoreAgentTool(ctx context.Context, toolName string, arguments map[st
 		output, _ := tools.RunReadDocumentation(ctx, argsStr)
 		return output, strings.HasPrefix(strings.ToLower(output), "error")
 
+	case tools.RememberToolName:
+		output, _ := tools.RunRemember(ctx, argsStr)
+		return output, strings.HasPrefix(strings.ToLower(output), "error")
+
+	case tools.RecallToolName:
+		output, _ := tools.RunRecall(ctx, argsStr)
+		return output, strings.HasPrefix(strings.ToLower(output), "error")
+
+	case tools.SearchKnowledgeToolName:
+		output, _ := tools.RunSearchKnowledge(ctx, argsStr)
+		return output, strings.HasPrefix(strings.ToLower(output), "error")
+
+	case tools.ReadMoreToolName:
+		output, _ := tools.RunReadMore(argsStr)
+		return output, strings.HasPrefix(strings.ToLower(output), "error")
+
 	default:
 		return fmt.Sprintf("Unknown core agent tool: %s", toolName), true
 	}
@@ -867,15 +1037,17 @@ func executeSubAgent(ctx context.Context, arguments map[string]any) (string, boo
 	fmt.Fprintln(os.Stderr, "\n"+bracketStyle.Render("[")+labelStyle.Render("Sub-Agent")+bracketStyle.Render("]")+" "+valueStyle.Render(subAgentDisplay))
 	fmt.Fprintln(os.Stderr, "  "+mutedStyle.Render(taskDisplay))
 
-	// Get API key
-	apiKey, err := credentials.GetSecret(credentials.OpperAPIKeyName)
-	if err != nil {
-		return fmt.Sprintf("Error: failed to read Opper API key: %v", err), true
+	// Share the main loop's client, so sub-agent calls are recorded and
+	// replayed with it
+	client := modelClientFrom(ctx)
+	if client == nil {
+		apiKey, err := credentials.GetSecret(credentials.OpperAPIKeyName)
+		if err != nil && opper.Local() == nil {
+			return fmt.Sprintf("Error: failed to read Opper API key: %v", err), true
+		}
+		client = opper.New(apiKey)
 	}
 
-	// Create Opper client for sub-agent
-	client := opper.New(apiKey)
-
 	// Get managed agent metadata (Builder not allowed in CLI)
 	agentDesc, subAgentPrompt, subAgentPromptReplace, commands, err := getAgentMetadataAndCommands(agentName)
 	if err != nil {
@@ -886,6 +1058,7 @@ func executeSubAgent(ctx context.Context, arguments map[string]any) (string, boo
 
 	// Use managed agent
 	subAgentTools := commandsToToolSpecs(agentName, commands)
+	subAgentTools = append(subAgentTools, tools.SharedSpecs()...)
 
 	// Get IPC client
 	ipcClient, _, err := getClientForAgent(agentName, "")
@@ -920,7 +1093,7 @@ func executeSubAgent(ctx context.Context, arguments map[string]any) (string, boo
 // executeSubAgentLoop runs the conversation loop for a managed sub-agent
 func executeSubAgentLoop(
 	ctx context.Context,
-	client *opper.Opper,
+	client modelClient,
 	ipcClient *ipc.Client,
 	agentName string,
 	history []conversationMessage,
@@ -929,15 +1102,17 @@ func executeSubAgentLoop(
 ) (string, error) {
 	currentHistory := append([]conversationMessage{}, history...)
 	roundCount := 0
+	limits := conversationLimits(ctx, agentName)
+	loops := loopdetect.New(limits.LoopRepeats)
 
 	for {
-		if roundCount >= maxFollowUpRounds {
-			return "", fmt.Errorf("sub-agent exceeded maximum follow-up rounds (%d)", maxFollowUpRounds)
+		if roundCount >= limits.MaxRounds {
+			return "", roundLimitError(agentName, limits.MaxRounds)
 		}
 		roundCount++
 
 		// Build conversation for API
-		conversation := buildConversation(currentHistory)
+		conversation := buildConversation(currentHistory, limits)
 
 		// Build request
 		input := map[string]any{
@@ -958,6 +1133,11 @@ func executeSubAgentLoop(
 		}
 
 		// Stream response
+		budgetCtx := budgetContext(ctx, agentName, false)
+		model := fmt.Sprint(req.Model)
+		if err := chargeModelCall(budgetCtx, model, req.Input); err != nil {
+			return "", err
+		}
 		events, err := client.Stream(ctx, req)
 		if err != nil {
 			return "", fmt.Errorf("failed to start stream: %w", err)
@@ -965,8 +1145,9 @@ func executeSubAgentLoop(
 
 		// Parse streaming response (with 2-space indentation for sub-agent)
 		// TODO: Implement proper sub-agent events with subagent_id
-		dummyEmitter := NewStderrEmitter()
+		dummyEmitter := NewStderrEmitter(true)
 		result, err := parseStreamingResponse(ctx, events, "  ", "", dummyEmitter)
+		recordStreamOutput(budgetCtx, model, result)
 		if err != nil {
 			return "", err
 		}
@@ -976,8 +1157,15 @@ func executeSubAgentLoop(
 			fmt.Fprintln(os.Stderr, "")
 		}
 
+		if err := checkLoop(loops, result); err != nil {
+			return "", err
+		}
+
 		// If no tool calls, we're done - return the text
 		if len(result.ToolCalls) == 0 {
+			if reason := checkGuardrail(ipcClient, agentName, agent.GuardrailCheck{Text: result.Text}, dummyEmitter); reason != "" {
+				return "Response withheld by guardrail: " + reason, nil
+			}
 			return result.Text, nil
 		}
 
@@ -1017,8 +1205,9 @@ type conversationMessage struct {
 	ToolCallID string // For tool_call_output role
 }
 
-// buildConversation converts message history to API format
-func buildConversation(history []conversationMessage) []map[string]any {
+// buildConversation converts message history to API format, older tool
+// outputs compacted and the others cut to the limits' tool output budget
+func buildConversation(history []conversationMessage, limits config.Limits) []map[string]any {
 	conversation := make([]map[string]any, 0, len(history))
 
 	for i, msg := range history {
@@ -1086,113 +1275,41 @@ func buildConversation(history []conversationMessage) []map[string]any {
 		}
 	}
 
-	return conversation
+	conversation = tools.CompactToolOutputs(conversation, limits.RecentToolOutputs, limits.ToolOutputTokens)
+	return tools.TruncateToolOutputs(conversation, limits.ToolOutputTokens)
 }
 
-// parseMessageFromMetadata parses a complete message from metadata JSON
-func parseMessageFromMetadata(role, metadata string) conversationMessage {
-	msg := conversationMessage{Role: role}
-
-	if metadata == "" {
-		return msg
-	}
-
-	// Try to parse as array of content parts
-	var parts []map[string]any
-	if err := json.Unmarshal([]byte(metadata), &parts); err != nil {
-		return msg
-	}
-
-	for _, part := range parts {
-		// Extract text content
-		if text, ok := part["Text"].(string); ok && text != "" {
-			msg.Content = text
-		}
-		if text, ok := part["text"].(string); ok && text != "" {
-			msg.Content = text
-		}
-
-		// Extract tool_call metadata (role: tool_call)
-		if id, hasID := part["id"].(string); hasID {
-			if name, hasName := part["name"].(string); hasName {
-				toolCall := ToolCall{
-					ID:   id,
-					Name: name,
+// messageFromStored converts a stored message to the history format.
+func messageFromStored(m msgstore.Message) conversationMessage {
+	msg := conversationMessage{Role: m.Role}
+	for _, p := range m.Parts {
+		switch p.Kind {
+		case msgstore.KindText:
+			msg.Content = p.Text
+		case msgstore.KindToolCall:
+			toolCall := ToolCall{ID: p.ToolCallID, Name: p.ToolName}
+			if p.ToolInput != "" {
+				var args map[string]any
+				if err := json.Unmarshal([]byte(p.ToolInput), &args); err == nil {
+					toolCall.Arguments = args
 				}
-				// Parse input JSON string back to map
-				if input, ok := part["input"].(string); ok && input != "" {
-					var args map[string]any
-					if err := json.Unmarshal([]byte(input), &args); err == nil {
-						toolCall.Arguments = args
-					}
-				}
-				msg.ToolCalls = append(msg.ToolCalls, toolCall)
 			}
-		}
-
-		// Extract tool_call_id and content for tool_call_response
-		if toolCallID, ok := part["tool_call_id"].(string); ok {
-			msg.ToolCallID = toolCallID
-		}
-		if content, ok := part["content"].(string); ok {
-			msg.Content = content
-		}
-
-		// Also handle tool_id for backward compatibility with tool_call_output
-		if toolID, ok := part["tool_id"].(string); ok {
-			msg.ToolCallID = toolID
+			msg.ToolCalls = append(msg.ToolCalls, toolCall)
+		case msgstore.KindToolResult:
+			msg.ToolCallID = p.ToolCallID
+			msg.Content = p.Text
 		}
 	}
-
 	return msg
 }
 
-// extractTextFromMetadata extracts text content from message metadata JSON
-func extractTextFromMetadata(metadata string) string {
-	msg := parseMessageFromMetadata("", metadata)
-	return msg.Content
-}
-
-// createTextMetadata creates message metadata JSON for text content
-func createTextMetadata(text string) string {
-	metadata := []map[string]string{
-		{"Text": text},
-	}
-	data, _ := json.Marshal(metadata)
-	return string(data)
-}
-
-// createToolCallMetadata creates metadata for tool_call message (matches TUI format)
-func createToolCallMetadata(tc ToolCall) string {
-	argsJSON, _ := json.Marshal(tc.Arguments)
-	parts := []map[string]any{
-		{
-			"id":       tc.ID,
-			"name":     tc.Name,
-			"input":    string(argsJSON),
-			"type":     "function",
-			"finished": true,
-			"reason":   "",
-		},
-	}
-	data, _ := json.Marshal(parts)
-	return string(data)
-}
-
-// createToolCallResponseMetadata creates metadata for tool_call_response message (matches TUI format)
-func createToolCallResponseMetadata(toolCallID, name, content string) string {
-	parts := []map[string]any{
-		{
-			"tool_call_id": toolCallID,
-			"name":         name,
-			"content":      content,
-			"metadata":     "",
-			"is_error":     false,
-			"pending":      false,
-		},
+// savePartialResponse keeps the text of an answer whose stream was cut off,
+// marked as interrupted, so resuming the conversation does not lose it.
+func savePartialResponse(ctx context.Context, writeDB *sql.DB, convID, text string) {
+	_, err := msgstore.Insert(ctx, writeDB, convID, "assistant", []msgstore.Part{msgstore.TextPart(text + "\n\n[response interrupted]")})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render("Warning:")+" "+mutedStyle.Render(fmt.Sprintf("failed to save partial response: %v", err)))
 	}
-	data, _ := json.Marshal(parts)
-	return string(data)
 }
 
 // getDefaultAgent returns the default core agent ID
@@ -1300,6 +1417,9 @@ type CommandArgument struct {
 	Description string
 	Required    bool
 	Default     any
+	// Schema is the argument's full JSON Schema, with enums, bounds,
+	// patterns and nested schemas.
+	Schema map[string]any
 }
 
 // getAgentMetadataAndCommands retrieves agent description, system prompt, and commands
@@ -1348,6 +1468,7 @@ func getAgentMetadataAndCommands(agentName string) (description, systemPrompt st
 				Description: arg.Description,
 				Required:    arg.Required,
 				Default:     arg.Default,
+				Schema:      arg.JSONSchema(),
 			})
 		}
 		commands = append(commands, CommandDescriptor{
@@ -1374,14 +1495,17 @@ func commandsToToolSpecs(agentName string, commands []CommandDescriptor) []tools
 		required := []string{}
 
 		for _, arg := range cmd.Arguments {
-			paramSchema := map[string]any{
-				"type": arg.Type,
-			}
-			if arg.Description != "" {
-				paramSchema["description"] = arg.Description
-			}
-			if arg.Default != nil {
-				paramSchema["default"] = arg.Default
+			paramSchema := arg.Schema
+			if paramSchema == nil {
+				paramSchema = map[string]any{
+					"type": arg.Type,
+				}
+				if arg.Description != "" {
+					paramSchema["description"] = arg.Description
+				}
+				if arg.Default != nil {
+					paramSchema["default"] = arg.Default
+				}
 			}
 
 			properties[arg.Name] = paramSchema
@@ -1572,6 +1696,11 @@ func buildInstructions(agentName, agentPrompt string, agentPromptReplace, isCore
 	}
 
 	// For core agents (Opperator or Builder)
+	// Add the user's context files, as the TUI does
+	if section := config.FormatContextFiles(sessionstate.ContextFiles()); section != "" {
+		base += "\n\n" + section
+	}
+
 	// Only show agent list for non-Builder agents (Builder doesn't have the agent tool)
 	coreAgentID := ""
 	if coreDef, ok := coreagent.Lookup(agentName); ok {
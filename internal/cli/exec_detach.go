@@ -0,0 +1,112 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"opperator/internal/ipc"
+)
+
+// execAttachPollInterval is how often 'op exec attach' checks on a detached
+// conversation.
+const execAttachPollInterval = 500 * time.Millisecond
+
+// ExecDetached queues a message as a background conversation on the local
+// daemon and prints its handle without waiting for the response.
+func ExecDetached(messageText, agentName, conversationID string) error {
+	client, err := workflowClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	workingDir, _ := os.Getwd()
+	task, err := client.ExecDetached(agentName, messageText, conversationID, workingDir)
+	if err != nil {
+		return err
+	}
+	fmt.Println(task.ID)
+	fmt.Fprintln(os.Stderr, mutedStyle.Render("Follow it with: ")+"op exec attach "+valueStyle.Render(task.ID))
+	return nil
+}
+
+// AttachExec follows a detached conversation by its handle, or a unique
+// prefix of it, printing progress to stderr until it finishes and the
+// response to stdout.
+func AttachExec(handle string) error {
+	client, err := workflowClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	task, err := resolveExecHandle(client, handle)
+	if err != nil {
+		return err
+	}
+
+	printed := 0
+	for {
+		for _, entry := range task.Progress[min(printed, len(task.Progress)):] {
+			if text := strings.TrimSpace(entry.Text); text != "" {
+				fmt.Fprintln(os.Stderr, "  "+mutedStyle.Render("→")+" "+mutedStyle.Render(text))
+			}
+		}
+		printed = len(task.Progress)
+
+		switch task.Status {
+		case "complete":
+			fmt.Println(task.Result)
+			var meta struct {
+				ConversationID string `json:"conversation_id"`
+			}
+			if json.Unmarshal([]byte(task.Metadata), &meta) == nil && meta.ConversationID != "" {
+				NewStderrEmitter(true).PrintResumeInfo(meta.ConversationID)
+			}
+			return nil
+		case "failed", "skipped":
+			return fmt.Errorf("detached exec %s %s: %s", task.ID, task.Status, strings.TrimSpace(task.Error))
+		}
+
+		time.Sleep(execAttachPollInterval)
+		if task, err = client.GetToolTask(task.ID); err != nil {
+			return err
+		}
+	}
+}
+
+// resolveExecHandle finds the detached conversation a handle refers to.
+func resolveExecHandle(client *ipc.Client, handle string) (*ipc.ToolTask, error) {
+	handle = strings.TrimSpace(handle)
+	if handle == "" {
+		return nil, fmt.Errorf("a handle is required")
+	}
+	if task, err := client.GetToolTask(handle); err == nil {
+		if task.Mode != "conversation" {
+			return nil, fmt.Errorf("task %s is not a detached exec (mode %s)", task.ID, orDash(task.Mode))
+		}
+		return task, nil
+	}
+
+	tasks, err := client.ListToolTasks(false)
+	if err != nil {
+		return nil, err
+	}
+	var match *ipc.ToolTask
+	for _, task := range tasks {
+		if task.Mode != "conversation" || !strings.HasPrefix(task.ID, handle) {
+			continue
+		}
+		if match != nil {
+			return nil, fmt.Errorf("handle %q matches more than one detached exec", handle)
+		}
+		match = task
+	}
+	if match == nil {
+		return nil, fmt.Errorf("no detached exec with handle %q", handle)
+	}
+	return match, nil
+}
@@ -0,0 +1,222 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"golang.org/x/term"
+	"opperator/config"
+)
+
+// replHistorySize is how many lines of 'op exec -i' input are kept.
+const replHistorySize = 500
+
+// heredocStart matches a line opening multi-line input, such as "<<EOF".
+var heredocStart = regexp.MustCompile(`^<<\s*([A-Za-z_][A-Za-z0-9_]*)$`)
+
+// replEmitter prints turns like 'op exec' does and remembers the
+// conversation they were saved in, so the next line continues it.
+type replEmitter struct {
+	*StderrEmitter
+	sessionID string
+}
+
+func (e *replEmitter) EmitSessionStarted(event SessionStartedEvent) {
+	e.sessionID = event.SessionID
+}
+
+// PrintResumeInfo is shown once when the REPL exits rather than after every
+// turn.
+func (e *replEmitter) PrintResumeInfo(conversationID string) {}
+
+// ExecInteractive chats with an agent line by line on stdin, continuing one
+// conversation. A line "<<WORD" starts a multi-line message ending at a line
+// consisting of WORD. "/new" starts a new conversation; "/exit", Ctrl-D or
+// Ctrl-C at the prompt leave. Ctrl-C during a turn interrupts only the turn.
+func ExecInteractive(agentName, conversationID string, plain bool, limits config.Limits) error {
+	ctx := withLimitOverrides(context.Background(), limits)
+	in := newREPLInput()
+	defer in.close()
+
+	if in.terminal != nil {
+		fmt.Fprintln(os.Stderr, mutedStyle.Render("Type a message, <<EOF for several lines, /new for a new conversation, /exit to leave."))
+	}
+	for {
+		message, err := in.readMessage()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return err
+		}
+		switch strings.TrimSpace(message) {
+		case "":
+			continue
+		case "/exit", "/quit":
+			return finishREPL(conversationID)
+		case "/new":
+			conversationID = ""
+			fmt.Fprintln(os.Stderr, mutedStyle.Render("Started a new conversation."))
+			continue
+		}
+
+		emitter := &replEmitter{StderrEmitter: NewStderrEmitter(plain), sessionID: conversationID}
+		turnCtx, stop := signal.NotifyContext(ctx, os.Interrupt)
+		err = execSession(turnCtx, message, agentName, conversationID, false, false, emitter)
+		stop()
+		conversationID = emitter.sessionID
+		if err != nil {
+			PrintError(err)
+		}
+	}
+	return finishREPL(conversationID)
+}
+
+func finishREPL(conversationID string) error {
+	if conversationID != "" {
+		NewStderrEmitter(true).PrintResumeInfo(conversationID)
+	}
+	return nil
+}
+
+// replInput reads REPL lines, with line editing and history when stdin is a
+// terminal.
+type replInput struct {
+	terminal *term.Terminal
+	scanner  *bufio.Scanner
+}
+
+func newREPLInput() *replInput {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		scanner := bufio.NewScanner(os.Stdin)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		return &replInput{scanner: scanner}
+	}
+	t := term.NewTerminal(struct {
+		io.Reader
+		io.Writer
+	}{os.Stdin, os.Stderr}, "› ")
+	if width, height, err := term.GetSize(fd); err == nil {
+		t.SetSize(width, height)
+	}
+	t.History = loadREPLHistory()
+	return &replInput{terminal: t}
+}
+
+// readMessage reads one message, collecting the lines of a heredoc.
+func (in *replInput) readMessage() (string, error) {
+	line, err := in.readLine("› ")
+	if err != nil {
+		return "", err
+	}
+	match := heredocStart.FindStringSubmatch(strings.TrimSpace(line))
+	if match == nil {
+		return line, nil
+	}
+	var lines []string
+	for {
+		next, err := in.readLine("… ")
+		if err != nil {
+			return "", err
+		}
+		if strings.TrimSpace(next) == match[1] {
+			return strings.Join(lines, "\n"), nil
+		}
+		lines = append(lines, next)
+	}
+}
+
+// readLine reads a line, putting the terminal in raw mode only while it
+// reads so that Ctrl-C interrupts turns.
+func (in *replInput) readLine(prompt string) (string, error) {
+	if in.scanner != nil {
+		if !in.scanner.Scan() {
+			if err := in.scanner.Err(); err != nil {
+				return "", err
+			}
+			return "", io.EOF
+		}
+		return in.scanner.Text(), nil
+	}
+
+	fd := int(os.Stdin.Fd())
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return "", err
+	}
+	defer term.Restore(fd, state)
+	in.terminal.SetPrompt(prompt)
+	line, err := in.terminal.ReadLine()
+	if errors.Is(err, term.ErrPasteIndicator) {
+		err = nil
+	}
+	return line, err
+}
+
+func (in *replInput) close() {
+	if in.terminal != nil {
+		if h, ok := in.terminal.History.(*replHistory); ok {
+			h.save()
+		}
+	}
+}
+
+// replHistory keeps REPL input across runs in the state directory.
+type replHistory struct {
+	path    string
+	entries []string // oldest first
+}
+
+func loadREPLHistory() *replHistory {
+	h := &replHistory{}
+	dir, err := config.GetStateDir()
+	if err != nil {
+		return h
+	}
+	h.path = filepath.Join(dir, "exec_history")
+	if data, err := os.ReadFile(h.path); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			if line != "" {
+				h.entries = append(h.entries, line)
+			}
+		}
+	}
+	return h
+}
+
+func (h *replHistory) Add(entry string) {
+	if entry == "" || (len(h.entries) > 0 && h.entries[len(h.entries)-1] == entry) {
+		return
+	}
+	h.entries = append(h.entries, entry)
+	if len(h.entries) > replHistorySize {
+		h.entries = h.entries[len(h.entries)-replHistorySize:]
+	}
+}
+
+func (h *replHistory) Len() int {
+	return len(h.entries)
+}
+
+func (h *replHistory) At(idx int) string {
+	return h.entries[len(h.entries)-1-idx]
+}
+
+func (h *replHistory) save() {
+	if h.path == "" {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(h.path), 0o700); err != nil {
+		return
+	}
+	_ = os.WriteFile(h.path, []byte(strings.Join(h.entries, "\n")+"\n"), 0o600)
+}
@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"opperator/internal/agent"
+	"opperator/internal/ipc"
+)
+
+// checkGuardrail runs the agent's guardrails over check and returns why it
+// is refused, or "" when it may go ahead. Checks that ask for confirmation
+// are put to the user when exec runs interactively and refused otherwise.
+func checkGuardrail(ipcClient *ipc.Client, agentName string, check agent.GuardrailCheck, emitter EventEmitter) string {
+	verdict, err := ipcClient.CheckGuardrails(agentName, check)
+	if err != nil {
+		// Daemons without guardrail support reject the request type; an
+		// unknown agent fails on the command itself.
+		if ipc.IsCode(err, ipc.ErrCodeValidation) || ipc.IsCode(err, ipc.ErrCodeNotFound) {
+			return ""
+		}
+		return fmt.Sprintf("could not check guardrails: %v", err)
+	}
+	if verdict.Allowed {
+		return ""
+	}
+	if verdict.Confirm && confirmGuardrail(emitter, check, verdict.Reason) {
+		return ""
+	}
+	return verdict.Reason
+}
+
+// confirmGuardrail asks on the terminal whether to go ahead despite reason.
+func confirmGuardrail(emitter EventEmitter, check agent.GuardrailCheck, reason string) bool {
+	if _, ok := emitter.(*StderrEmitter); !ok {
+		return false
+	}
+	if stat, err := os.Stdin.Stat(); err != nil || stat.Mode()&os.ModeCharDevice == 0 {
+		return false
+	}
+	subject := "the response"
+	if check.Command != "" {
+		subject = "command " + check.Command
+	}
+	fmt.Fprintf(os.Stderr, "\n%s %s: %s\n%s ", errorStyle.Render("Guardrail:"), subject, reason, labelStyle.Render("Allow anyway? [y/N]"))
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
@@ -0,0 +1,154 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"opperator/internal/credentials"
+	"opperator/internal/kb"
+	"opperator/pkg/db"
+	"opperator/pkg/migration"
+	"tui/opper"
+)
+
+// openKnowledgeBase opens the knowledge base in the local database, which
+// the daemon searches for the search_knowledge tool.
+func openKnowledgeBase() (*kb.Store, error) {
+	if err := initializeExecDB(); err != nil {
+		return nil, err
+	}
+	writeDB, err := db.GetWriteDB()
+	if err != nil {
+		return nil, err
+	}
+	if err := migration.NewRunner(writeDB).Run(); err != nil {
+		return nil, fmt.Errorf("failed to run migrations: %w", err)
+	}
+	return kb.NewStore(writeDB, embedKnowledge), nil
+}
+
+func embedKnowledge(ctx context.Context, texts []string) ([][]float32, string, error) {
+	apiKey, err := credentials.GetSecret(credentials.OpperAPIKeyName)
+	if err != nil && opper.Local() == nil {
+		return nil, "", fmt.Errorf("failed to read Opper API key: %w (run: op secret create %s)", err, credentials.OpperAPIKeyName)
+	}
+	return opper.New(apiKey).Embed(ctx, texts)
+}
+
+// KBAdd loads, chunks and embeds each source, a file, a directory or a URL,
+// replacing documents added from the same source before.
+func KBAdd(sources []string) error {
+	store, err := openKnowledgeBase()
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	added, failed := 0, 0
+	for _, source := range sources {
+		docs, err := kb.Load(ctx, source)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "✗ %v\n", err)
+			failed++
+			continue
+		}
+		for _, doc := range docs {
+			info, err := store.Add(ctx, doc)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "✗ %v\n", err)
+				failed++
+				continue
+			}
+			fmt.Printf("✓ %s (%d chunks)\n", info.Source, info.Chunks)
+			added++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d documents failed", failed, added+failed)
+	}
+	return nil
+}
+
+// KBList prints the documents in the knowledge base.
+func KBList() error {
+	store, err := openKnowledgeBase()
+	if err != nil {
+		return err
+	}
+	docs, err := store.List(context.Background())
+	if err != nil {
+		return err
+	}
+	if len(docs) == 0 {
+		fmt.Println("Knowledge base is empty")
+		fmt.Println("\nAdd documents with: op kb add <path|url>")
+		return nil
+	}
+
+	fmt.Printf("%-5s %-30s %-7s %-17s %s\n", "ID", "TITLE", "CHUNKS", "ADDED", "SOURCE")
+	fmt.Printf("%-5s %-30s %-7s %-17s %s\n", "--", "-----", "------", "-----", "------")
+	for _, doc := range docs {
+		title := doc.Title
+		if len(title) > 30 {
+			title = title[:27] + "..."
+		}
+		fmt.Printf("%-5d %-30s %-7d %-17s %s\n",
+			doc.ID, title, doc.Chunks, doc.AddedAt.Format("2006-01-02 15:04"), doc.Source)
+	}
+	return nil
+}
+
+// KBRemove removes a document by ID, path or URL; a directory removes every
+// document added from it.
+func KBRemove(ref string) error {
+	store, err := openKnowledgeBase()
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(ref, "http://") && !strings.HasPrefix(ref, "https://") {
+		if info, statErr := os.Stat(ref); statErr == nil {
+			if abs, absErr := filepath.Abs(ref); absErr == nil {
+				ref = abs
+				if info.IsDir() {
+					ref += string(filepath.Separator)
+				}
+			}
+		}
+	}
+
+	n, err := store.Remove(context.Background(), ref)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("no document matches '%s'", ref)
+	}
+	fmt.Printf("Removed %d document(s)\n", n)
+	return nil
+}
+
+// KBSearch prints the passages most related to query.
+func KBSearch(query string, limit int) error {
+	store, err := openKnowledgeBase()
+	if err != nil {
+		return err
+	}
+	results, err := store.Search(context.Background(), query, limit)
+	if err != nil {
+		return err
+	}
+	if len(results) == 0 {
+		fmt.Println("No matching passages")
+		return nil
+	}
+	for i, r := range results {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("[%.2f] %s — %s\n%s\n", r.Score, r.Title, r.Source, r.Content)
+	}
+	return nil
+}
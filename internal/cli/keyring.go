@@ -0,0 +1,31 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+
+	"golang.org/x/term"
+
+	"opperator/pkg/keyringerr"
+)
+
+// RetryKeyring runs check, which reads the keyring, until it stops failing
+// on a locked or unavailable keyring. Each time it does, it explains how to
+// fix the keyring and waits for Enter on the terminal. Without a terminal,
+// and for any other error, it returns the error as is.
+func RetryKeyring(check func() error) error {
+	for {
+		err := check()
+		if !keyringerr.Is(err) {
+			return err
+		}
+		if !term.IsTerminal(int(os.Stdin.Fd())) {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "%s %v\n%s ", errorStyle.Render("Keyring:"), err, labelStyle.Render("Press Enter to retry, or Ctrl+C to quit"))
+		if _, readErr := bufio.NewReader(os.Stdin).ReadString('\n'); readErr != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// kvListValueWidth caps the values shown by 'op agent kv list'.
+const kvListValueWidth = 60
+
+// KVGet prints an agent's value for key as JSON.
+func KVGet(agentName, key, daemonName string) error {
+	client, _, err := getClientForAgent(agentName, daemonName)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	entry, err := client.KVGet(agentName, key)
+	if err != nil {
+		return err
+	}
+	printKVValue(entry.Value)
+	return nil
+}
+
+// KVSet stores value as an agent's value for key. A value that is not JSON
+// is stored as a string.
+func KVSet(agentName, key, value, daemonName string) error {
+	client, _, err := getClientForAgent(agentName, daemonName)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if err := client.KVSet(agentName, key, parseKVValue(value)); err != nil {
+		return err
+	}
+	fmt.Printf("✓ Set %s of agent '%s'\n", key, agentName)
+	return nil
+}
+
+// KVDelete removes an agent's value for key.
+func KVDelete(agentName, key, daemonName string) error {
+	client, _, err := getClientForAgent(agentName, daemonName)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if err := client.KVDelete(agentName, key); err != nil {
+		return err
+	}
+	fmt.Printf("✓ Deleted %s of agent '%s'\n", key, agentName)
+	return nil
+}
+
+// KVList prints an agent's keys starting with prefix and their values.
+func KVList(agentName, prefix, daemonName string, jsonOutput bool) error {
+	client, foundDaemon, err := getClientForAgent(agentName, daemonName)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	entries, err := client.KVList(agentName, prefix)
+	if err != nil {
+		return err
+	}
+	if jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	}
+	if len(entries) == 0 {
+		fmt.Printf("No keys stored for agent '%s' on daemon '%s'\n", agentName, foundDaemon)
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tUPDATED\tVALUE")
+	for _, e := range entries {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", e.Key, e.UpdatedAt.Local().Format("2006-01-02 15:04:05"), kvListValue(e.Value))
+	}
+	return w.Flush()
+}
+
+// kvListValue compacts a value onto one line of at most kvListValueWidth.
+func kvListValue(raw json.RawMessage) string {
+	var compact bytes.Buffer
+	value := string(raw)
+	if json.Compact(&compact, raw) == nil {
+		value = compact.String()
+	}
+	if runes := []rune(value); len(runes) > kvListValueWidth {
+		value = string(runes[:kvListValueWidth-3]) + "..."
+	}
+	return value
+}
+
+// printKVValue prints a value as indented JSON.
+func printKVValue(raw json.RawMessage) {
+	var out bytes.Buffer
+	if json.Indent(&out, raw, "", "  ") != nil {
+		out.Reset()
+		out.Write(raw)
+	}
+	fmt.Println(out.String())
+}
+
+// parseKVValue reads a value given on the command line: JSON, or else a
+// string.
+func parseKVValue(value string) json.RawMessage {
+	raw := json.RawMessage(strings.TrimSpace(value))
+	if !json.Valid(raw) {
+		raw, _ = json.Marshal(value)
+	}
+	return raw
+}
@@ -0,0 +1,57 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"opperator/config"
+)
+
+type limitsKey struct{}
+
+// withLimitOverrides makes limits, from command line flags, take precedence
+// over conversation.yaml for the conversations run with ctx.
+func withLimitOverrides(ctx context.Context, limits config.Limits) context.Context {
+	return context.WithValue(ctx, limitsKey{}, limits)
+}
+
+// conversationLimits returns the limits of agentName's conversation loop.
+func conversationLimits(ctx context.Context, agentName string) config.Limits {
+	cfg, err := config.LoadConversationConfig()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render("Warning:")+" "+mutedStyle.Render(err.Error()))
+	}
+	overrides, _ := ctx.Value(limitsKey{}).(config.Limits)
+	return cfg.LimitsFor(agentName).Merge(overrides)
+}
+
+// turnContext bounds one model round and its tool calls by timeout, if set.
+func turnContext(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// limitError explains err when it comes from running out of the
+// conversation's or the round's time.
+func limitError(err error, ctx, turnCtx context.Context, limits config.Limits) error {
+	switch {
+	case limits.Timeout > 0 && errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return fmt.Errorf("conversation timed out after %s (raise timeout in conversation.yaml or pass --timeout)", limits.Timeout)
+	case limits.TurnTimeout > 0 && errors.Is(turnCtx.Err(), context.DeadlineExceeded):
+		return fmt.Errorf("model round timed out after %s (raise turn_timeout in conversation.yaml or pass --turn-timeout)", limits.TurnTimeout)
+	}
+	return err
+}
+
+func roundLimitError(subAgent string, maxRounds int) error {
+	prefix := "exceeded"
+	if subAgent != "" {
+		prefix = "sub-agent " + subAgent + " exceeded"
+	}
+	return fmt.Errorf("%s maximum follow-up rounds (%d); raise max_rounds in conversation.yaml or pass --max-rounds", prefix, maxRounds)
+}
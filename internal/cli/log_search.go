@@ -0,0 +1,159 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"opperator/config"
+	"opperator/internal/agent"
+	"opperator/internal/ipc"
+)
+
+// LogSearchOptions are the flags of 'op logs search'.
+type LogSearchOptions struct {
+	Agent string
+	// Since is a duration such as 2h or 7d, a date or an RFC 3339 time
+	Since string
+	// Daemon is the daemon to search; every enabled daemon when empty
+	Daemon     string
+	Context    int
+	IgnoreCase bool
+	Limit      int
+}
+
+// SearchLogs prints the agent log lines matching a regular expression on one
+// or every enabled daemon.
+func SearchLogs(pattern string, opts LogSearchOptions) error {
+	q := agent.LogSearch{
+		Pattern:    pattern,
+		IgnoreCase: opts.IgnoreCase,
+		Agent:      strings.TrimSpace(opts.Agent),
+		Context:    opts.Context,
+		Limit:      opts.Limit,
+	}
+	// Fail on a bad pattern before asking any daemon
+	if _, err := q.Compile(); err != nil {
+		return err
+	}
+	if opts.Since != "" {
+		since, err := parseSince(opts.Since, time.Now())
+		if err != nil {
+			return err
+		}
+		q.Since = since
+	}
+	daemons, err := selectDaemons(strings.TrimSpace(opts.Daemon))
+	if err != nil {
+		return err
+	}
+
+	type daemonMatches struct {
+		result *agent.LogSearchResult
+		err    error
+	}
+	results := make([]daemonMatches, len(daemons))
+	var wg sync.WaitGroup
+	for i, daemon := range daemons {
+		wg.Add(1)
+		go func(result *daemonMatches, daemon config.DaemonConfig) {
+			defer wg.Done()
+			client, err := ipc.NewClientWithAuth(daemon.Address, daemon.AuthToken)
+			if err != nil {
+				result.err = err
+				return
+			}
+			defer client.Close()
+			result.result, result.err = client.SearchLogs(q)
+		}(&results[i], daemon)
+	}
+	wg.Wait()
+
+	count := 0
+	var unreachable, truncated []string
+	for i, daemon := range daemons {
+		result := results[i]
+		if result.err != nil {
+			if len(daemons) == 1 {
+				if daemon.Name == "local" && ipc.IsCode(result.err, ipc.ErrCodeUnavailable) {
+					return fmt.Errorf("daemon is not running. Start it with: op daemon start")
+				}
+				return result.err
+			}
+			fmt.Fprintf(os.Stderr, "Warning: could not search logs of daemon '%s': %v\n", daemon.Name, result.err)
+			unreachable = append(unreachable, daemon.Name)
+			continue
+		}
+		if result.result.Truncated {
+			truncated = append(truncated, daemon.Name)
+		}
+		for _, match := range result.result.Matches {
+			source := match.Agent
+			if len(daemons) > 1 {
+				source = daemon.Name + "/" + match.Agent
+			}
+			printLogMatch(source, match, q.Context > 0, count > 0)
+			count++
+		}
+	}
+	if len(unreachable) == len(daemons) {
+		return fmt.Errorf("no daemon could be reached")
+	}
+
+	if count == 0 {
+		fmt.Println("No log lines matched")
+	}
+	if len(truncated) > 0 {
+		fmt.Printf("\nOnly the first matches per daemon are shown (%s); narrow the search with --agent or --since, or raise --limit\n", strings.Join(truncated, ", "))
+	}
+	if len(unreachable) > 0 {
+		fmt.Printf("\nPartial results: daemon(s) %s could not be reached\n", strings.Join(unreachable, ", "))
+	}
+	return nil
+}
+
+// printLogMatch prints a match on one line, or grep-style with its context
+// lines and a separator between matches.
+func printLogMatch(source string, match agent.LogMatch, withContext, separate bool) {
+	_, _, muted, _, _, _ := getCommandStyles()
+	header := fmt.Sprintf("%s %s", source, match.Time.Local().Format("2006-01-02 15:04:05"))
+	if !withContext {
+		fmt.Printf("%s  %s\n", muted.Render(header), match.Line)
+		return
+	}
+	if separate {
+		fmt.Println(muted.Render("--"))
+	}
+	fmt.Println(muted.Render(header))
+	for _, line := range match.Before {
+		fmt.Println(muted.Render("  " + line))
+	}
+	fmt.Println("> " + match.Line)
+	for _, line := range match.After {
+		fmt.Println(muted.Render("  " + line))
+	}
+}
+
+// parseSince reads a duration back from now (with d for days), a date, or
+// an RFC 3339 time.
+func parseSince(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --since %q (expected a duration like 2h or 7d, a date or an RFC 3339 time)", value)
+}
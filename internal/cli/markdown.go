@@ -0,0 +1,141 @@
+package cli
+
+import (
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/glamour/v2"
+	"github.com/charmbracelet/x/ansi"
+	"golang.org/x/term"
+	"tui/styles"
+)
+
+// maxMarkdownWidth caps the wrap width of rendered responses on wide
+// terminals.
+const maxMarkdownWidth = 120
+
+var (
+	headingLine = regexp.MustCompile(`^#{1,6}(\s|$)`)
+	// linePadding matches the styled spaces glamour pads lines to the wrap
+	// width with.
+	linePadding = regexp.MustCompile(`(\x1b\[[0-9;]*m| )+$`)
+)
+
+// markdownStream renders a streamed response as markdown one block at a time,
+// with the TUI's markdown styles. A block is printed as soon as the blank
+// line, heading or closing code fence that ends it arrives, so long answers
+// are readable while they stream.
+type markdownStream struct {
+	out      func(string)
+	indent   string
+	renderer *glamour.TermRenderer
+
+	pending string   // the incomplete last line
+	block   []string // complete lines of the block being streamed
+	fence   string   // opening fence of the code block being streamed, if any
+	printed bool
+}
+
+func newMarkdownStream(out func(string), indent string) *markdownStream {
+	width := 80
+	if w, _, err := term.GetSize(int(os.Stderr.Fd())); err == nil && w > 0 {
+		width = w
+	}
+	width = min(width, maxMarkdownWidth) - len(indent)
+
+	theme := styles.CurrentTheme()
+	renderer, _ := glamour.NewTermRenderer(
+		glamour.WithStyles(theme.S().Markdown),
+		glamour.WithWordWrap(max(width, 20)),
+	)
+	return &markdownStream{out: out, indent: indent, renderer: renderer}
+}
+
+// Write adds streamed text, printing the blocks it completes.
+func (s *markdownStream) Write(text string) {
+	s.pending += text
+	for {
+		i := strings.IndexByte(s.pending, '\n')
+		if i < 0 {
+			return
+		}
+		line := s.pending[:i]
+		s.pending = s.pending[i+1:]
+		s.addLine(line)
+	}
+}
+
+// Close prints what is left of the response.
+func (s *markdownStream) Close() {
+	if s.pending != "" {
+		s.block = append(s.block, s.pending)
+		s.pending = ""
+	}
+	s.flush()
+}
+
+func (s *markdownStream) addLine(line string) {
+	trimmed := strings.TrimSpace(line)
+	if s.fence != "" {
+		s.block = append(s.block, line)
+		if strings.HasPrefix(trimmed, s.fence) && strings.Trim(trimmed, s.fence[:1]) == "" {
+			s.fence = ""
+			s.flush()
+		}
+		return
+	}
+
+	switch {
+	case trimmed == "":
+		s.flush()
+	case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+		s.flush()
+		s.fence = trimmed[:len(trimmed)-len(strings.TrimLeft(trimmed, trimmed[:1]))]
+		s.block = append(s.block, line)
+	case headingLine.MatchString(trimmed):
+		s.flush()
+		s.block = append(s.block, line)
+		s.flush()
+	default:
+		s.block = append(s.block, line)
+	}
+}
+
+// flush renders and prints the current block. Blocks are separated by a
+// blank line; the last one is left without a newline for the caller to end.
+func (s *markdownStream) flush() {
+	if len(s.block) == 0 {
+		return
+	}
+	source := strings.Join(s.block, "\n")
+	s.block = s.block[:0]
+
+	rendered := source
+	if s.renderer != nil {
+		if out, err := s.renderer.Render(source); err == nil {
+			rendered = out
+		}
+	}
+	lines := strings.Split(rendered, "\n")
+	for len(lines) > 0 && strings.TrimSpace(ansi.Strip(lines[0])) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(ansi.Strip(lines[len(lines)-1])) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		return
+	}
+
+	if s.printed {
+		s.out("\n\n")
+	}
+	for i, line := range lines {
+		if i > 0 {
+			s.out("\n")
+		}
+		s.out(s.indent + linePadding.ReplaceAllString(line, "") + colorReset)
+	}
+	s.printed = true
+}
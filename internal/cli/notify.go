@@ -0,0 +1,18 @@
+package cli
+
+import "opperator/internal/ipc"
+
+// ReportUpdateFailure passes a failed update to the local daemon so its
+// notification sinks can alert on it. It is best effort: nothing is reported
+// when the daemon is not reachable.
+func ReportUpdateFailure(target string, updateErr error) {
+	if updateErr == nil {
+		return
+	}
+	client, err := ipc.NewClientFromRegistry("local")
+	if err != nil {
+		return
+	}
+	defer client.Close()
+	_ = client.ReportUpdateFailure(target, updateErr.Error())
+}
@@ -0,0 +1,107 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+)
+
+const (
+	// pluginPrefix starts the file name of CLI plugins on PATH.
+	pluginPrefix = "op-"
+	// pluginEnv holds the name op ran a plugin as.
+	pluginEnv = "OPPERATOR_PLUGIN"
+)
+
+// Plugin is an executable on PATH that adds 'op <name>'.
+type Plugin struct {
+	Name string
+	Path string
+	// Shadowed is set for plugins named like a built-in command, or found
+	// after another plugin of the same name on PATH; they never run.
+	Shadowed bool
+}
+
+// LookupPlugin returns the path of the op-<name> plugin on PATH.
+func LookupPlugin(name string) (string, bool) {
+	if name == "" || strings.HasPrefix(name, "-") || strings.ContainsRune(name, filepath.Separator) {
+		return "", false
+	}
+	path, err := exec.LookPath(pluginPrefix + name)
+	if err != nil {
+		return "", false
+	}
+	return path, true
+}
+
+// RunPlugin replaces the op process with the plugin at path, passing it args
+// and the name it runs as. OPPERATOR_INSTANCE and the rest of the
+// environment are inherited.
+func RunPlugin(name, path string, args []string) error {
+	env := append(os.Environ(), pluginEnv+"="+name)
+	argv := append([]string{path}, args...)
+	if err := syscall.Exec(path, argv, env); err != nil {
+		return fmt.Errorf("failed to run plugin %s: %w", path, err)
+	}
+	return nil
+}
+
+// FindPlugins returns the op-<name> executables on PATH in PATH order.
+// builtin reports the names of built-in commands, which plugins cannot
+// replace.
+func FindPlugins(builtin func(name string) bool) []Plugin {
+	var plugins []Plugin
+	seen := make(map[string]bool)
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		if dir == "" {
+			dir = "."
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			name, ok := strings.CutPrefix(e.Name(), pluginPrefix)
+			if !ok || name == "" || e.IsDir() {
+				continue
+			}
+			path := filepath.Join(dir, e.Name())
+			info, err := os.Stat(path)
+			if err != nil || info.IsDir() || info.Mode()&0111 == 0 {
+				continue
+			}
+			plugins = append(plugins, Plugin{Name: name, Path: path, Shadowed: seen[name] || builtin(name)})
+			seen[name] = true
+		}
+	}
+	return plugins
+}
+
+// ListPlugins prints the plugins found on PATH.
+func ListPlugins(builtin func(name string) bool) error {
+	plugins := FindPlugins(builtin)
+	if len(plugins) == 0 {
+		fmt.Println("No plugins found. Plugins are executables named op-<name> on PATH.")
+		return nil
+	}
+	sort.SliceStable(plugins, func(i, j int) bool { return plugins[i].Name < plugins[j].Name })
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "COMMAND\tPATH\tNOTE")
+	for _, p := range plugins {
+		note := ""
+		if p.Shadowed {
+			note = "shadowed"
+			if builtin(p.Name) {
+				note = "shadowed by built-in command"
+			}
+		}
+		fmt.Fprintf(w, "op %s\t%s\t%s\n", p.Name, p.Path, note)
+	}
+	return w.Flush()
+}
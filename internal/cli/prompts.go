@@ -0,0 +1,128 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	udiff "github.com/aymanbagabas/go-udiff"
+
+	"opperator/internal/agent"
+)
+
+// ShowPromptHistory lists the recorded versions of an agent's system prompt
+// and description, newest first.
+func ShowPromptHistory(name, daemonName string) error {
+	client, foundDaemon, err := getClientForAgent(name, daemonName)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	versions, err := client.GetPromptHistory(name)
+	if err != nil {
+		return err
+	}
+	if len(versions) == 0 {
+		fmt.Printf("No prompt history recorded for agent '%s' on daemon '%s'\n", name, foundDaemon)
+		return nil
+	}
+
+	fmt.Printf("%-6s %-19s %-7s %-8s %s\n", "ID", "TIME", "SOURCE", "PROMPT", "DESCRIPTION")
+	fmt.Printf("%-6s %-19s %-7s %-8s %s\n", "--", "----", "------", "------", "-----------")
+	for _, v := range versions {
+		size := fmt.Sprintf("%d ch", len([]rune(v.SystemPrompt)))
+		if v.SystemPrompt == "" {
+			size = "-"
+		}
+		desc := strings.Join(strings.Fields(v.Description), " ")
+		if len(desc) > 60 {
+			desc = desc[:57] + "..."
+		}
+		fmt.Printf("%-6d %-19s %-7s %-8s %s\n", v.ID, v.At.Local().Format("2006-01-02 15:04:05"), v.Source, size, orDash(desc))
+	}
+	fmt.Println("\nCompare versions with: op agent prompt diff", name, "<id> [id]")
+	return nil
+}
+
+// DiffPrompts prints a unified diff of two prompt versions. With to zero,
+// from is compared with the version before it.
+func DiffPrompts(name string, from, to int64, daemonName string) error {
+	client, _, err := getClientForAgent(name, daemonName)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	versions, err := client.GetPromptHistory(name)
+	if err != nil {
+		return err
+	}
+
+	// versions are newest first, so the one before index i is at i+1
+	fromIdx, toIdx := -1, -1
+	for i, v := range versions {
+		if v.ID == from {
+			fromIdx = i
+		}
+		if v.ID == to {
+			toIdx = i
+		}
+	}
+	if fromIdx < 0 {
+		return fmt.Errorf("prompt version %d not found for agent '%s'", from, name)
+	}
+	if to == 0 {
+		if fromIdx+1 >= len(versions) {
+			return fmt.Errorf("version %d is the oldest recorded; give a second version to compare with", from)
+		}
+		fromIdx, toIdx = fromIdx+1, fromIdx
+	} else if toIdx < 0 {
+		return fmt.Errorf("prompt version %d not found for agent '%s'", to, name)
+	}
+
+	before, after := versions[fromIdx], versions[toIdx]
+	diff := udiff.Unified(promptLabel(before), promptLabel(after), promptText(before), promptText(after))
+	if diff == "" {
+		fmt.Printf("Versions %d and %d are identical\n", before.ID, after.ID)
+		return nil
+	}
+	fmt.Print(diff)
+	return nil
+}
+
+// RevertPrompt restores an agent's prompt and description from version id.
+func RevertPrompt(name string, id int64, daemonName string) error {
+	client, foundDaemon, err := getClientForAgent(name, daemonName)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	version, err := client.RevertPrompt(name, id)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Reverted '%s' on daemon '%s' to prompt version %d from %s\n",
+		name, foundDaemon, version.ID, version.At.Local().Format("2006-01-02 15:04:05"))
+	fmt.Println("The agent replaces it if it sets its own prompt again, for example on restart.")
+	return nil
+}
+
+func promptLabel(v agent.PromptVersion) string {
+	return fmt.Sprintf("version %d (%s, %s)", v.ID, v.Source, v.At.Local().Format("2006-01-02 15:04:05"))
+}
+
+// promptText renders a version for diffing.
+func promptText(v agent.PromptVersion) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Description: %s\n", v.Description)
+	if v.SystemPromptReplace {
+		b.WriteString("Replaces the core prompt: yes\n")
+	}
+	b.WriteString("\n")
+	b.WriteString(v.SystemPrompt)
+	if !strings.HasSuffix(v.SystemPrompt, "\n") {
+		b.WriteString("\n")
+	}
+	return b.String()
+}
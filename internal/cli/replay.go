@@ -0,0 +1,347 @@
+package cli
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"opperator/internal/credentials"
+	"opperator/internal/ipc"
+	"opperator/pkg/db"
+	"opperator/pkg/migration"
+	"opperator/pkg/msgstore"
+	"tui/coreagent"
+	"tui/opper"
+)
+
+// RecordEnv turns on recording of model calls for every 'op exec' run,
+// including those behind the web chat and chat channels, when set to 1.
+const RecordEnv = "OPPERATOR_RECORD"
+
+// modelClient makes the model calls of the conversation loop, so they can
+// be recorded or served from a recording.
+type modelClient interface {
+	Stream(ctx context.Context, req opper.StreamRequest) (<-chan opper.SSEEvent, error)
+}
+
+type modelClientKey struct{}
+
+// withModelClient makes client available to sub-agents started by tools.
+func withModelClient(ctx context.Context, client modelClient) context.Context {
+	return context.WithValue(ctx, modelClientKey{}, client)
+}
+
+func modelClientFrom(ctx context.Context) modelClient {
+	client, _ := ctx.Value(modelClientKey{}).(modelClient)
+	return client
+}
+
+func recordingRequested(record bool) bool {
+	return record || os.Getenv(RecordEnv) == "1"
+}
+
+// recordedCall is one model request and the events it streamed back.
+type recordedCall struct {
+	AgentName string
+	Request   string
+	Events    []opper.SSEEvent
+}
+
+// recordingClient stores every model call made while answering one user
+// message, in order, alongside the conversation.
+type recordingClient struct {
+	client    modelClient
+	db        *sql.DB
+	sessionID string
+	messageID int64
+	agentName string
+
+	mu  sync.Mutex
+	seq int
+}
+
+func newRecordingClient(client modelClient, writeDB *sql.DB, sessionID string, messageID int64, agentName string) (*recordingClient, error) {
+	if err := migration.NewRunner(writeDB).Run(); err != nil {
+		return nil, fmt.Errorf("failed to run migrations: %w", err)
+	}
+	return &recordingClient{
+		client:    client,
+		db:        writeDB,
+		sessionID: sessionID,
+		messageID: messageID,
+		agentName: agentName,
+	}, nil
+}
+
+func (r *recordingClient) Stream(ctx context.Context, req opper.StreamRequest) (<-chan opper.SSEEvent, error) {
+	request, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record model request: %w", err)
+	}
+	events, err := r.client.Stream(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	seq := r.seq
+	r.seq++
+	r.mu.Unlock()
+
+	out := make(chan opper.SSEEvent)
+	go func() {
+		defer close(out)
+		var recorded []opper.SSEEvent
+		for event := range events {
+			recorded = append(recorded, event)
+			select {
+			case out <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+		if ctx.Err() == nil {
+			r.save(seq, string(request), recorded)
+		}
+	}()
+	return out, nil
+}
+
+func (r *recordingClient) save(seq int, request string, events []opper.SSEEvent) {
+	data, err := json.Marshal(events)
+	if err == nil {
+		_, err = r.db.Exec(
+			`INSERT INTO model_recordings (session_id, message_id, agent_name, seq, request, events, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			r.sessionID, r.messageID, r.agentName, seq, request, string(data), time.Now().Unix())
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, errorStyle.Render("Warning:")+" "+mutedStyle.Render(fmt.Sprintf("failed to record model call: %v", err)))
+	}
+}
+
+// replayClient answers model calls from a recording in order. Online, calls
+// go to live once the loop sends a request the recording doesn't have.
+type replayClient struct {
+	calls []recordedCall
+	live  modelClient
+
+	mu       sync.Mutex
+	next     int
+	differed int
+	liveFrom int // index of the first live call, -1 while replaying
+}
+
+func (r *replayClient) Stream(ctx context.Context, req opper.StreamRequest) (<-chan opper.SSEEvent, error) {
+	request, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	i := r.next
+	r.next++
+	if r.liveFrom >= 0 {
+		r.mu.Unlock()
+		return r.live.Stream(ctx, req)
+	}
+	if i >= len(r.calls) {
+		r.mu.Unlock()
+		if r.live == nil {
+			return nil, fmt.Errorf("the recording has no model call %d; the loop made more calls than were recorded", i+1)
+		}
+		r.goLive(i, "the loop made more model calls than were recorded")
+		return r.live.Stream(ctx, req)
+	}
+	call := r.calls[i]
+	matches := call.Request == string(request)
+	if !matches {
+		r.differed++
+	}
+	r.mu.Unlock()
+
+	if !matches {
+		if r.live != nil {
+			r.goLive(i, fmt.Sprintf("model request %d differs from the recording", i+1))
+			return r.live.Stream(ctx, req)
+		}
+		fmt.Fprintln(os.Stderr, errorStyle.Render("Diverged:")+" "+mutedStyle.Render(fmt.Sprintf("model request %d differs from the recording; replaying the recorded response", i+1)))
+	}
+
+	out := make(chan opper.SSEEvent, len(call.Events))
+	for _, event := range call.Events {
+		out <- event
+	}
+	close(out)
+	return out, nil
+}
+
+func (r *replayClient) goLive(i int, reason string) {
+	r.mu.Lock()
+	r.liveFrom = i
+	r.mu.Unlock()
+	fmt.Fprintln(os.Stderr, errorStyle.Render("Diverged:")+" "+mutedStyle.Render(reason+"; continuing against the model"))
+}
+
+// ReplayConversation re-runs the conversation loop for every recorded user
+// message of a conversation, answering model calls from the recording.
+// Tools run for real, so tool-execution bugs reproduce without the model
+// choosing differently. Offline, no model calls are made at all; otherwise
+// the loop continues against the model once it diverges from the recording.
+// Nothing is saved to the conversation.
+func ReplayConversation(conversationID string, offline bool) error {
+	ctx := context.Background()
+	if err := initializeExecDB(); err != nil {
+		return err
+	}
+	writeDB, err := db.GetWriteDB()
+	if err != nil {
+		return err
+	}
+	if err := migration.NewRunner(writeDB).Run(); err != nil {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+	readDB, err := db.GetReadDB()
+	if err != nil {
+		return err
+	}
+
+	runs, order, err := loadRecordings(ctx, readDB, conversationID)
+	if err != nil {
+		return err
+	}
+	if len(order) == 0 {
+		return fmt.Errorf("conversation %s has no recorded model calls (record with 'op exec --record' or %s=1)", conversationID, RecordEnv)
+	}
+
+	var live modelClient
+	if !offline {
+		apiKey, err := credentials.GetSecret(credentials.OpperAPIKeyName)
+		if err != nil && opper.Local() == nil {
+			return fmt.Errorf("failed to read Opper API key: %w (run: op secret create %s, or replay with --offline)", err, credentials.OpperAPIKeyName)
+		}
+		live = opper.New(apiKey)
+	}
+
+	emitter := NewStderrEmitter(false)
+	for n, messageID := range order {
+		calls := runs[messageID]
+		history, err := loadHistoryThrough(ctx, readDB, conversationID, messageID)
+		if err != nil {
+			return err
+		}
+		agentName := calls[0].AgentName
+		instructions, toolDefs, err := recordedLoopSettings(calls[0].Request)
+		if err != nil {
+			return err
+		}
+
+		prompt := ""
+		if len(history) > 0 {
+			prompt = strings.Join(strings.Fields(history[len(history)-1].Content), " ")
+		}
+		if len(prompt) > 120 {
+			prompt = prompt[:117] + "..."
+		}
+		emitter.PrintSectionHeader(fmt.Sprintf("Replay %d/%d", n+1, len(order)))
+		fmt.Fprintln(os.Stderr, mutedStyle.Render(prompt))
+
+		var ipcClient *ipc.Client
+		if _, isCore := coreagent.Lookup(agentName); !isCore {
+			ipcClient, _, err = getClientForAgent(agentName, "")
+			if err != nil {
+				return fmt.Errorf("failed to connect to agent daemon: %w", err)
+			}
+		}
+
+		replay := &replayClient{calls: calls, live: live, liveFrom: -1}
+		_, _, _, err = executeConversationLoop(ctx, replay, ipcClient, agentName, history, toolDefs, instructions, nil, conversationID, emitter, true)
+		if ipcClient != nil {
+			ipcClient.Close()
+		}
+		if err != nil {
+			return fmt.Errorf("replay of message %d failed: %w", n+1, err)
+		}
+
+		summary := fmt.Sprintf("%d of %d recorded model calls replayed", min(replay.next, len(calls)), len(calls))
+		if replay.differed > 0 {
+			summary += fmt.Sprintf(", %d with a different request", replay.differed)
+		}
+		if replay.liveFrom >= 0 {
+			summary += fmt.Sprintf(", live from call %d", replay.liveFrom+1)
+		}
+		fmt.Fprintln(os.Stderr, "\n"+bracketStyle.Render("[")+mutedStyle.Render(summary)+bracketStyle.Render("]"))
+	}
+	return nil
+}
+
+// loadRecordings returns a conversation's recorded calls per user message
+// and the message IDs in order.
+func loadRecordings(ctx context.Context, readDB *sql.DB, conversationID string) (map[int64][]recordedCall, []int64, error) {
+	rows, err := readDB.QueryContext(ctx, `
+		SELECT message_id, agent_name, request, events
+		FROM model_recordings
+		WHERE session_id = ?
+		ORDER BY message_id, seq
+	`, conversationID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load recording: %w", err)
+	}
+	defer rows.Close()
+
+	runs := make(map[int64][]recordedCall)
+	var order []int64
+	for rows.Next() {
+		var messageID int64
+		var call recordedCall
+		var events string
+		if err := rows.Scan(&messageID, &call.AgentName, &call.Request, &events); err != nil {
+			return nil, nil, fmt.Errorf("failed to read recording: %w", err)
+		}
+		if err := json.Unmarshal([]byte(events), &call.Events); err != nil {
+			return nil, nil, fmt.Errorf("failed to read recorded events: %w", err)
+		}
+		if _, ok := runs[messageID]; !ok {
+			order = append(order, messageID)
+		}
+		runs[messageID] = append(runs[messageID], call)
+	}
+	return runs, order, rows.Err()
+}
+
+// loadHistoryThrough returns a conversation's messages up to and including
+// messageID.
+func loadHistoryThrough(ctx context.Context, readDB *sql.DB, conversationID string, messageID int64) ([]conversationMessage, error) {
+	stored, err := msgstore.List(ctx, readDB, conversationID, msgstore.ListOptions{ThroughID: messageID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load conversation history: %w", err)
+	}
+
+	history := make([]conversationMessage, 0, len(stored))
+	for _, m := range stored {
+		history = append(history, messageFromStored(m))
+	}
+	return history, nil
+}
+
+// recordedLoopSettings returns the instructions and tools of a recorded
+// request, so the replayed loop sends the same ones.
+func recordedLoopSettings(request string) (string, []map[string]any, error) {
+	var req struct {
+		Instructions *string `json:"instructions"`
+		Input        struct {
+			Tools []map[string]any `json:"tools"`
+		} `json:"input"`
+	}
+	if err := json.Unmarshal([]byte(request), &req); err != nil {
+		return "", nil, fmt.Errorf("failed to read recorded request: %w", err)
+	}
+	instructions := ""
+	if req.Instructions != nil {
+		instructions = *req.Instructions
+	}
+	return instructions, req.Input.Tools, nil
+}
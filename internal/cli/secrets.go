@@ -4,14 +4,21 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"os/exec"
+	"slices"
 	"strings"
 
 	"golang.org/x/term"
 
+	"opperator/config"
+	"opperator/internal/agent"
 	"opperator/internal/credentials"
+	"opperator/internal/ipc"
 )
 
-func CreateSecret(name, value string) error {
+// CreateSecret stores a new secret in the system keyring, or in daemonName's
+// keyring when set.
+func CreateSecret(name, value, daemonName string) error {
 	name = strings.TrimSpace(name)
 	if name == "" {
 		return fmt.Errorf("secret name cannot be empty")
@@ -20,6 +27,9 @@ func CreateSecret(name, value string) error {
 	if err != nil {
 		return err
 	}
+	if daemonName != "" {
+		return storeDaemonSecret(daemonName, name, secret, "create")
+	}
 
 	exists, err := credentials.HasSecret(name)
 	if err != nil {
@@ -37,11 +47,13 @@ func CreateSecret(name, value string) error {
 	}
 
 	fmt.Printf("Stored secret %q in the system keyring\n", name)
+	addToActiveWorkspace("secret", name)
 	return nil
 }
 
-// UpdateSecret replaces the existing secret in the system keyring.
-func UpdateSecret(name, value string) error {
+// UpdateSecret replaces the existing secret in the system keyring, or in
+// daemonName's keyring when set.
+func UpdateSecret(name, value, daemonName string) error {
 	name = strings.TrimSpace(name)
 	if name == "" {
 		return fmt.Errorf("secret name cannot be empty")
@@ -50,6 +62,9 @@ func UpdateSecret(name, value string) error {
 	if err != nil {
 		return err
 	}
+	if daemonName != "" {
+		return storeDaemonSecret(daemonName, name, secret, "update")
+	}
 
 	exists, err := credentials.HasSecret(name)
 	if err != nil {
@@ -70,11 +85,141 @@ func UpdateSecret(name, value string) error {
 	return nil
 }
 
-func DeleteSecret(name string) error {
+// RotateSecret gives an existing secret a new value, generated with generate
+// characters when set. The local secret is also replaced on every remote
+// daemon that registered it, and each daemon tells the agents using it. With
+// daemonName set, only that daemon's secret is rotated.
+func RotateSecret(name, value string, generate int, daemonName string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return fmt.Errorf("secret name cannot be empty")
+	}
+	var (
+		secret string
+		err    error
+	)
+	if generate > 0 {
+		if strings.TrimSpace(value) != "" {
+			return fmt.Errorf("pass either a value or --generate, not both")
+		}
+		secret, err = credentials.GenerateSecret(generate)
+	} else {
+		secret, err = ensureSecretInput(value, fmt.Sprintf("Enter new value for %s: ", name))
+	}
+	if err != nil {
+		return err
+	}
+
+	if daemonName != "" {
+		client, err := ipc.NewClientFromRegistry(daemonName)
+		if err != nil {
+			return err
+		}
+		defer client.Close()
+		notified, err := client.ReplaceSecret(name, secret, "update")
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Rotated secret %q on daemon '%s'%s\n", name, daemonName, describeNotified(notified))
+		return nil
+	}
+
+	exists, err := credentials.HasSecret(name)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("no secret named %q is stored; use 'create' to add one", name)
+	}
+	if err := credentials.SetSecret(name, secret); err != nil {
+		return err
+	}
+	if err := credentials.RegisterSecret(name); err != nil {
+		return err
+	}
+	fmt.Printf("Rotated secret %q in the system keyring\n", name)
+
+	// The local daemon reads the same keyring; it only needs to tell its agents
+	if client, err := ipc.NewClientFromRegistry("local"); err == nil {
+		notified, err := client.SecretRotated(name)
+		client.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to notify local agents: %v\n", err)
+		} else if len(notified) > 0 {
+			fmt.Printf("Local daemon%s\n", describeNotified(notified))
+		}
+	}
+
+	registry, err := config.LoadDaemonRegistry()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to load daemon registry: %v\n", err)
+		return nil
+	}
+	failed := 0
+	for _, daemon := range registry.Daemons {
+		if !daemon.Enabled || daemon.Name == "local" {
+			continue
+		}
+		notified, pushed, err := pushRotatedSecret(daemon, name, secret)
+		switch {
+		case err != nil:
+			failed++
+			fmt.Fprintf(os.Stderr, "Warning: failed to rotate %q on daemon '%s': %v\n", name, daemon.Name, err)
+		case pushed:
+			fmt.Printf("Pushed new value to daemon '%s'%s\n", daemon.Name, describeNotified(notified))
+		}
+	}
+	if failed > 0 {
+		fmt.Fprintf(os.Stderr, "Retry those with: op secret rotate %s <value> --daemon <name>\n", name)
+	}
+	return nil
+}
+
+// pushRotatedSecret replaces name on daemon if the daemon registered it.
+func pushRotatedSecret(daemon config.DaemonConfig, name, value string) ([]string, bool, error) {
+	client, err := ipc.NewClientWithAuth(daemon.Address, daemon.AuthToken)
+	if err != nil {
+		return nil, false, err
+	}
+	defer client.Close()
+	names, err := client.ListSecrets()
+	if err != nil {
+		return nil, false, err
+	}
+	if !slices.Contains(names, name) {
+		return nil, false, nil
+	}
+	notified, err := client.ReplaceSecret(name, value, "update")
+	if err != nil {
+		return nil, false, err
+	}
+	return notified, true, nil
+}
+
+func describeNotified(agents []string) string {
+	if len(agents) == 0 {
+		return ""
+	}
+	return "; notified " + strings.Join(agents, ", ")
+}
+
+func DeleteSecret(name, daemonName string) error {
 	name = strings.TrimSpace(name)
 	if name == "" {
 		return fmt.Errorf("secret name cannot be empty")
 	}
+	if daemonName != "" {
+		client, err := ipc.NewClientFromRegistry(daemonName)
+		if err != nil {
+			return err
+		}
+		defer client.Close()
+		if err := client.DeleteSecret(name); err != nil {
+			return err
+		}
+		fmt.Printf("Removed secret %q from daemon '%s'\n", name, daemonName)
+		return nil
+	}
 	if err := credentials.DeleteSecret(name); err != nil {
 		if errors.Is(err, credentials.ErrNotFound) {
 			return fmt.Errorf("no secret named %q is stored", name)
@@ -86,6 +231,24 @@ func DeleteSecret(name string) error {
 	}
 
 	fmt.Printf("Removed secret %q from the system keyring\n", name)
+	if err := config.RemoveFromWorkspaces("secret", name); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to remove secret from workspaces: %v\n", err)
+	}
+	return nil
+}
+
+// storeDaemonSecret stores a secret in a daemon's own keyring, such as the
+// OPPER_API_KEY a remote daemon's agents use.
+func storeDaemonSecret(daemonName, name, value, mode string) error {
+	client, err := ipc.NewClientFromRegistry(daemonName)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	if err := client.StoreSecret(name, value, mode); err != nil {
+		return err
+	}
+	fmt.Printf("Stored secret %q on daemon '%s'\n", name, daemonName)
 	return nil
 }
 
@@ -107,12 +270,21 @@ func SecretStatus(name string) error {
 	return nil
 }
 
-// ListSecrets prints all recorded secret names.
-func ListSecrets() error {
+// ListSecrets prints the recorded secret names, limited to the active
+// workspace unless allWorkspaces is set.
+func ListSecrets(allWorkspaces bool) error {
 	names, err := credentials.ListSecrets()
 	if err != nil {
 		return err
 	}
+	workspace, err := activeWorkspaceFilter(allWorkspaces)
+	if err != nil {
+		return err
+	}
+	if workspace != nil {
+		names = slices.DeleteFunc(names, func(name string) bool { return !workspace.HasSecret(name) })
+		fmt.Printf("Workspace: %s (--all-workspaces to list every secret)\n\n", workspace.Name)
+	}
 	if len(names) == 0 {
 		fmt.Println("No secrets have been registered yet")
 		return nil
@@ -145,6 +317,62 @@ func ReadSecret(name string) (string, error) {
 	return secret, nil
 }
 
+// SecretEnv builds the environment the daemon gives agentName, from the
+// local agents.yaml and keyring. Without command it prints the variables as
+// shell export statements; with one it runs command in this environment and
+// returns its exit code.
+func SecretEnv(agentName string, command []string) (int, error) {
+	cfg, err := loadLocalAgentConfig(strings.TrimSpace(agentName))
+	if err != nil {
+		return 1, err
+	}
+	env, err := cfg.Environment()
+	if err != nil {
+		return 1, err
+	}
+
+	if len(command) == 0 {
+		for _, kv := range env {
+			key, value, _ := strings.Cut(kv, "=")
+			fmt.Printf("export %s=%s\n", key, shellQuote(value))
+		}
+		return 0, nil
+	}
+
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode(), nil
+		}
+		return 1, err
+	}
+	return 0, nil
+}
+
+// shellQuote quotes value for a POSIX shell.
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// EncryptValue encrypts value with the config key in this machine's keyring
+// and returns it as an agents.yaml value the daemon here can decrypt.
+func EncryptValue(value string) (string, error) {
+	plaintext, err := ensureSecretInput(value, "Enter value to encrypt: ")
+	if err != nil {
+		return "", err
+	}
+	ciphertext, err := credentials.EncryptValue(plaintext)
+	if err != nil {
+		return "", err
+	}
+	return agent.EncryptedTag + " " + ciphertext, nil
+}
+
 func ensureSecretInput(raw, prompt string) (string, error) {
 	trimmed := strings.TrimSpace(raw)
 	if trimmed != "" {
@@ -0,0 +1,140 @@
+package cli
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"opperator/config"
+	"opperator/internal/credentials"
+	"opperator/internal/share"
+	"opperator/pkg/db"
+	"opperator/pkg/msgstore"
+)
+
+// ShareOptions configures 'op conversation share'.
+type ShareOptions struct {
+	Format     string
+	StripTools bool
+	// Output writes the rendering to a file instead of stdout.
+	Output string
+	// Upload sends the rendering to the target in share.yaml.
+	Upload bool
+	// Redact lists extra strings to scrub.
+	Redact []string
+}
+
+// ShareConversation renders a conversation with secrets scrubbed and writes
+// it out or uploads it, printing the link.
+func ShareConversation(conversationID string, opts ShareOptions) error {
+	var target *config.ShareConfig
+	if opts.Upload {
+		cfg, err := config.LoadShareConfig()
+		if err != nil {
+			return err
+		}
+		target = cfg
+	}
+
+	conv, err := loadShareConversation(context.Background(), conversationID)
+	if err != nil {
+		return err
+	}
+	rendered, err := share.Render(conv, share.NewRedactor(append(storedSecretValues(), opts.Redact...)), share.Options{
+		Format:     opts.Format,
+		StripTools: opts.StripTools,
+	})
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case target != nil:
+		name := "conversation-" + conversationID + share.Extension(opts.Format)
+		link, err := share.Upload(context.Background(), target, name, share.ContentType(opts.Format), []byte(rendered))
+		if err != nil {
+			return fmt.Errorf("failed to upload conversation: %w", err)
+		}
+		fmt.Println(link)
+	case opts.Output != "":
+		if err := os.WriteFile(opts.Output, []byte(rendered), 0o644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", opts.Output, err)
+		}
+		fmt.Printf("Wrote %s\n", opts.Output)
+	default:
+		fmt.Print(rendered)
+	}
+	return nil
+}
+
+func loadShareConversation(ctx context.Context, conversationID string) (share.Conversation, error) {
+	if err := initializeExecDB(); err != nil {
+		return share.Conversation{}, err
+	}
+	readDB, err := db.GetReadDB()
+	if err != nil {
+		return share.Conversation{}, err
+	}
+
+	var conv share.Conversation
+	var agent sql.NullString
+	var created int64
+	row := readDB.QueryRowContext(ctx,
+		`SELECT title, active_agent, created_at FROM conversations WHERE id = ?`, conversationID)
+	if err := row.Scan(&conv.Title, &agent, &created); err != nil {
+		if err == sql.ErrNoRows {
+			return conv, fmt.Errorf("conversation %q not found", conversationID)
+		}
+		return conv, fmt.Errorf("failed to load conversation: %w", err)
+	}
+	conv.Agent = agent.String
+	conv.Created = time.Unix(created, 0)
+
+	stored, err := msgstore.List(ctx, readDB, conversationID, msgstore.ListOptions{})
+	if err != nil {
+		return conv, fmt.Errorf("failed to load conversation history: %w", err)
+	}
+
+	// Tool results only carry the call ID; name them after their call.
+	toolNames := make(map[string]string)
+	for _, m := range stored {
+		role := m.Role
+		msg := messageFromStored(m)
+		switch role {
+		case "user", "assistant":
+			if msg.Content != "" {
+				conv.Messages = append(conv.Messages, share.Message{Role: role, Text: msg.Content})
+			}
+		case "tool_call":
+			for _, tc := range msg.ToolCalls {
+				toolNames[tc.ID] = tc.Name
+				input, _ := json.MarshalIndent(tc.Arguments, "", "  ")
+				conv.Messages = append(conv.Messages, share.Message{Role: role, ToolName: tc.Name, ToolInput: string(input)})
+			}
+		case "tool_call_response", "tool_call_output":
+			conv.Messages = append(conv.Messages, share.Message{
+				Role:     "tool_call_response",
+				ToolName: toolNames[msg.ToolCallID],
+				Text:     msg.Content,
+			})
+		}
+	}
+	return conv, nil
+}
+
+// storedSecretValues returns the values of the secrets in the keyring so
+// they are scrubbed wherever they appear.
+func storedSecretValues() []string {
+	secrets, err := credentials.SecretValues()
+	if err != nil {
+		return nil
+	}
+	values := make([]string, 0, len(secrets))
+	for _, value := range secrets {
+		values = append(values, value)
+	}
+	return values
+}
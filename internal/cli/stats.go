@@ -0,0 +1,159 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"opperator/config"
+	"opperator/internal/agent"
+	"opperator/internal/ipc"
+)
+
+// fleetStats is one row of 'op stats': the stats of a daemon, or the error
+// that kept them from being read.
+type fleetStats struct {
+	Daemon string           `json:"daemon"`
+	Stats  *ipc.DaemonStats `json:"stats,omitempty"`
+	Error  string           `json:"error,omitempty"`
+}
+
+// FleetStats prints agent counts, task throughput, failures in the last 24
+// hours and versions for every enabled daemon in the registry.
+func FleetStats(jsonOutput bool) error {
+	registry, err := config.LoadDaemonRegistry()
+	if err != nil {
+		return fmt.Errorf("failed to load daemon registry: %w", err)
+	}
+
+	var daemons []config.DaemonConfig
+	for _, daemon := range registry.Daemons {
+		if daemon.Enabled {
+			daemons = append(daemons, daemon)
+		}
+	}
+	if len(daemons) == 0 {
+		return fmt.Errorf("no enabled daemons. Add one with: op daemon add")
+	}
+
+	// Query daemons in parallel so one slow remote doesn't hold up the rest
+	rows := make([]fleetStats, len(daemons))
+	var wg sync.WaitGroup
+	for i, daemon := range daemons {
+		rows[i].Daemon = daemon.Name
+		wg.Add(1)
+		go func(row *fleetStats, daemon config.DaemonConfig) {
+			defer wg.Done()
+			client, err := ipc.NewClientWithAuth(daemon.Address, daemon.AuthToken)
+			if err != nil {
+				row.Error = err.Error()
+				return
+			}
+			defer client.Close()
+			stats, err := client.DaemonStats()
+			if err != nil {
+				row.Error = err.Error()
+				return
+			}
+			row.Stats = stats
+		}(&rows[i], daemon)
+	}
+	wg.Wait()
+
+	if jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(rows)
+	}
+
+	printFleetStats(rows)
+	return nil
+}
+
+func printFleetStats(rows []fleetStats) {
+	const format = "%-15s %-10s %-8s %6s %7s %7s %7s %6s %6s %8s %10s\n"
+	fmt.Printf(format, "DAEMON", "VERSION", "UPTIME", "AGENTS", "RUNNING", "STOPPED", "CRASHED", "QUEUED", "ACTIVE", "DONE 24H", "FAILED 24H")
+
+	var total ipc.DaemonStats
+	reachable := 0
+	for _, row := range rows {
+		if row.Stats == nil {
+			fmt.Printf("%-15s unreachable: %s\n", row.Daemon, row.Error)
+			continue
+		}
+		reachable++
+		s := row.Stats
+		agents, running, stopped, crashed := agentCounts(s.Agents)
+		fmt.Printf(format,
+			row.Daemon,
+			s.Version,
+			formatStatsUptime(time.Since(s.StartedAt)),
+			fmt.Sprint(agents),
+			fmt.Sprint(running),
+			fmt.Sprint(stopped),
+			fmt.Sprint(crashed),
+			fmt.Sprint(s.Tasks.QueueDepth),
+			fmt.Sprint(s.Tasks.InFlight),
+			fmt.Sprint(s.Completed24h),
+			fmt.Sprint(s.Failed24h),
+		)
+
+		if total.Agents == nil {
+			total.Agents = make(map[agent.ProcessStatus]int)
+		}
+		for status, n := range s.Agents {
+			total.Agents[status] += n
+		}
+		total.Tasks.QueueDepth += s.Tasks.QueueDepth
+		total.Tasks.InFlight += s.Tasks.InFlight
+		total.Completed24h += s.Completed24h
+		total.Failed24h += s.Failed24h
+	}
+
+	if reachable > 1 {
+		agents, running, stopped, crashed := agentCounts(total.Agents)
+		fmt.Printf(format,
+			"TOTAL", "", "",
+			fmt.Sprint(agents),
+			fmt.Sprint(running),
+			fmt.Sprint(stopped),
+			fmt.Sprint(crashed),
+			fmt.Sprint(total.Tasks.QueueDepth),
+			fmt.Sprint(total.Tasks.InFlight),
+			fmt.Sprint(total.Completed24h),
+			fmt.Sprint(total.Failed24h),
+		)
+	}
+}
+
+// agentCounts totals agents by status. Crash-looping agents count as crashed;
+// agents starting, stopping or provisioning only count towards the total.
+func agentCounts(byStatus map[agent.ProcessStatus]int) (total, running, stopped, crashed int) {
+	for status, n := range byStatus {
+		total += n
+		switch status {
+		case agent.StatusRunning:
+			running += n
+		case agent.StatusStopped:
+			stopped += n
+		case agent.StatusCrashed, agent.StatusCrashLooping:
+			crashed += n
+		}
+	}
+	return total, running, stopped, crashed
+}
+
+func formatStatsUptime(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh%dm", int(d.Hours()), int(d.Minutes())%60)
+	default:
+		return fmt.Sprintf("%dd%dh", int(d.Hours())/24, int(d.Hours())%24)
+	}
+}
@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"fmt"
+	"time"
+
+	"opperator/internal/ipc"
+)
+
+func tokenClient(daemonName string) (*ipc.Client, error) {
+	if daemonName == "" {
+		daemonName = "local"
+	}
+	client, err := ipc.NewClientFromRegistry(daemonName)
+	if err != nil {
+		if ipc.IsCode(err, ipc.ErrCodeUnavailable) {
+			return nil, fmt.Errorf("daemon '%s' is not reachable. Start it with: op daemon start", daemonName)
+		}
+		return nil, err
+	}
+	return client, nil
+}
+
+// CreateAuthToken creates a named token on a daemon and prints it once. user
+// is the identity the token's tasks belong to; the token name when empty.
+func CreateAuthToken(daemonName, name, role, user string) error {
+	client, err := tokenClient(daemonName)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	secret, err := client.CreateAuthToken(name, role, user)
+	if err != nil {
+		return err
+	}
+	if role == "" {
+		role = "viewer"
+	}
+
+	if user == "" {
+		user = name
+	}
+
+	fmt.Printf("Created %s token '%s' for user '%s'. It will not be shown again:\n\n", role, name, user)
+	fmt.Printf("  %s\n\n", secret)
+	fmt.Printf("Connect with: op daemon add <name> tcp://<host>:<port> --token=%s\n", secret)
+	return nil
+}
+
+// RevokeAuthToken removes a managed token. Connections using it are closed on
+// their next request.
+func RevokeAuthToken(daemonName, name string) error {
+	client, err := tokenClient(daemonName)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if err := client.RevokeAuthToken(name); err != nil {
+		return err
+	}
+	fmt.Printf("Revoked token '%s'\n", name)
+	return nil
+}
+
+// ListAuthTokens prints the tokens a daemon accepts with their last use.
+func ListAuthTokens(daemonName string) error {
+	client, err := tokenClient(daemonName)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	tokens, err := client.ListAuthTokens()
+	if err != nil {
+		return err
+	}
+	if len(tokens) == 0 {
+		fmt.Println("No auth tokens configured")
+		fmt.Printf("\nCreate one with: op daemon token create <name> --role=viewer\n")
+		return nil
+	}
+
+	fmt.Printf("%-20s %-10s %-16s %-9s %-20s %s\n", "NAME", "ROLE", "USER", "SOURCE", "CREATED", "LAST USED")
+	fmt.Printf("%-20s %-10s %-16s %-9s %-20s %s\n", "----", "----", "----", "------", "-------", "---------")
+	for _, t := range tokens {
+		fmt.Printf("%-20s %-10s %-16s %-9s %-20s %s\n", t.Name, t.Role, orDash(t.User), t.Source, formatTokenTime(t.CreatedAt), formatTokenTime(t.LastUsedAt))
+	}
+	return nil
+}
+
+func formatTokenTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
@@ -0,0 +1,118 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"opperator/internal/ipc"
+)
+
+// DaemonTop shows live resource usage of a daemon and its agents, refreshing
+// every interval until interrupted. With once set it prints a single sample.
+func DaemonTop(daemonName string, interval time.Duration, once bool) error {
+	if daemonName == "" {
+		daemonName = "local"
+	}
+	if interval < time.Second {
+		interval = time.Second
+	}
+
+	client, err := ipc.NewClientFromRegistry(daemonName)
+	if err != nil {
+		if ipc.IsCode(err, ipc.ErrCodeUnavailable) {
+			return fmt.Errorf("daemon '%s' is not reachable. Start it with: op daemon start", daemonName)
+		}
+		return err
+	}
+	defer client.Close()
+
+	// CPU usage is measured between samples, so take a baseline first
+	if _, err := client.ResourceUsage(); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		report, err := client.ResourceUsage()
+		if err != nil {
+			return err
+		}
+		if !once {
+			// Clear the screen and move the cursor home
+			fmt.Print("\033[H\033[2J")
+		}
+		printResourceReport(daemonName, report)
+		if once {
+			return nil
+		}
+	}
+}
+
+func printResourceReport(daemonName string, report *ipc.ResourceReport) {
+	fmt.Printf("Daemon '%s' — %s\n\n", daemonName, report.SampledAt.Local().Format("15:04:05"))
+	fmt.Printf("%-24s %-12s %8s %7s %10s %6s %8s %8s\n", "NAME", "STATUS", "PID", "CPU%", "RSS", "FDS", "THREADS", "CHILDREN")
+	printResourceRow("(daemon)", "running", &report.Daemon, "")
+	for _, a := range report.Agents {
+		printResourceRow(a.Name, string(a.Status), a.Usage, a.Error)
+	}
+	fmt.Fprintln(os.Stdout)
+}
+
+func printResourceRow(name, status string, usage *ipc.ResourceUsage, errMsg string) {
+	if len(name) > 24 {
+		name = name[:21] + "..."
+	}
+	if usage == nil {
+		detail := "-"
+		if strings.TrimSpace(errMsg) != "" {
+			detail = errMsg
+		}
+		fmt.Printf("%-24s %-12s %8s %s\n", name, status, "-", detail)
+		return
+	}
+	fmt.Printf("%-24s %-12s %8d %7.1f %10s %6s %8s %8s\n",
+		name,
+		status,
+		usage.PID,
+		usage.CPUPercent,
+		formatResourceBytes(usage.RSSBytes),
+		formatResourceCount(usage.OpenFDs),
+		formatResourceCount(usage.Threads),
+		formatResourceCount(usage.Children),
+	)
+}
+
+func formatResourceCount(n int) string {
+	if n < 0 {
+		return "n/a"
+	}
+	return fmt.Sprintf("%d", n)
+}
+
+func formatResourceBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"opperator/internal/ipc"
+)
+
+// ListTriggers prints the triggers running on the local daemon.
+func ListTriggers(allWorkspaces bool) error {
+	client, err := ipc.NewClientFromRegistry("local")
+	if err != nil {
+		if ipc.IsCode(err, ipc.ErrCodeUnavailable) {
+			return fmt.Errorf("daemon is not running. Start it with: op daemon start")
+		}
+		return err
+	}
+	defer client.Close()
+
+	triggers, err := client.ListTriggers()
+	if err != nil {
+		return err
+	}
+	// Triggers belong to the workspace of the agent they invoke
+	workspace, err := activeWorkspaceFilter(allWorkspaces)
+	if err != nil {
+		return err
+	}
+	if workspace != nil {
+		triggers = slices.DeleteFunc(triggers, func(t ipc.TriggerInfo) bool { return !workspace.HasAgent(t.Agent) })
+		fmt.Printf("Workspace: %s (--all-workspaces to list every trigger)\n\n", workspace.Name)
+	}
+	if len(triggers) == 0 {
+		fmt.Println("No triggers registered")
+		fmt.Println("\nDeclare them under 'triggers:' in daemon.yaml or register them from an agent")
+		return nil
+	}
+
+	fmt.Printf("%-20s %-16s %-6s %-30s %-6s %-20s %s\n", "NAME", "OWNER", "TYPE", "COMMAND", "FIRED", "LAST FIRED", "ERROR")
+	fmt.Printf("%-20s %-16s %-6s %-30s %-6s %-20s %s\n", "----", "-----", "----", "-------", "-----", "----------", "-----")
+	for _, t := range triggers {
+		fmt.Printf("%-20s %-16s %-6s %-30s %-6d %-20s %s\n",
+			t.Name, t.Owner, t.Type, t.Agent+"."+t.Command, t.Fired, orDash(t.LastFired), strings.TrimSpace(t.LastError))
+	}
+	return nil
+}
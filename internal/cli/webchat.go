@@ -0,0 +1,263 @@
+package cli
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"opperator/pkg/db"
+	"opperator/pkg/msgstore"
+	"tui/coreagent"
+)
+
+//go:embed webchat.html
+var webChatPage []byte
+
+// maxChatMessage caps a message posted to the web chat.
+const maxChatMessage = 1 << 20
+
+// webChat serves a browser chat UI on top of the same conversation loop as
+// 'op exec', so conversations show up in the TUI as well.
+type webChat struct {
+	token string
+	agent string
+
+	mu    sync.Mutex
+	convs map[string]*sync.Mutex
+	// created holds the conversations started through this web chat; other
+	// conversations in the database are not reachable from it
+	created map[string]bool
+}
+
+type chatRequest struct {
+	Message        string `json:"message"`
+	Agent          string `json:"agent"`
+	ConversationID string `json:"conversation_id"`
+}
+
+type chatAgent struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+type chatHistoryMessage struct {
+	Role string `json:"role"`
+	Text string `json:"text"`
+}
+
+// ServeWebChat serves the web chat on addr until interrupted. Every request
+// must carry token, which is generated when empty; defaultAgent is
+// preselected in the UI.
+func ServeWebChat(addr, token, defaultAgent string) error {
+	if err := initializeExecDB(); err != nil {
+		return err
+	}
+	if token == "" {
+		buf := make([]byte, 16)
+		if _, err := rand.Read(buf); err != nil {
+			return err
+		}
+		token = hex.EncodeToString(buf)
+	}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("listen on %s: %w", addr, err)
+	}
+	chat := &webChat{token: token, agent: defaultAgent, convs: make(map[string]*sync.Mutex), created: make(map[string]bool)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", chat.handlePage)
+	mux.HandleFunc("GET /api/agents", chat.authorized(chat.handleAgents))
+	mux.HandleFunc("GET /api/conversations/{id}", chat.authorized(chat.handleHistory))
+	mux.HandleFunc("POST /api/chat", chat.authorized(chat.handleChat))
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+	if ip := net.ParseIP(host); ip == nil || ip.IsUnspecified() {
+		host = "localhost"
+	}
+	fmt.Println(labelStyle.Render("Web chat:") + " " + valueStyle.Render(fmt.Sprintf("http://%s/?token=%s", net.JoinHostPort(host, port), token)))
+	fmt.Println(mutedStyle.Render("Share the link only with people who may talk to your agents. Press Ctrl+C to stop."))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+	if err := srv.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// authorized requires the token as a bearer token or token query parameter.
+func (c *webChat) authorized(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := r.URL.Query().Get("token")
+		if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			token = bearer
+		}
+		if subtle.ConstantTimeCompare([]byte(token), []byte(c.token)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (c *webChat) handlePage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", "default-src 'self'; script-src 'unsafe-inline'; style-src 'unsafe-inline'")
+	w.Write(webChatPage)
+}
+
+// handleAgents lists the core agents and the agents on enabled daemons.
+func (c *webChat) handleAgents(w http.ResponseWriter, r *http.Request) {
+	var agents []chatAgent
+	for _, def := range coreagent.All() {
+		agents = append(agents, chatAgent{Name: def.ID, Description: "Built-in " + def.Name + " agent"})
+	}
+	for _, opt := range getAgentOptions() {
+		agents = append(agents, chatAgent{Name: opt.Value, Description: opt.Description})
+	}
+	writeChatJSON(w, map[string]any{"agents": agents, "default": c.agent})
+}
+
+// handleHistory returns the user and assistant messages of a conversation so
+// a reloaded page can show them again.
+func (c *webChat) handleHistory(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if !c.owns(id) {
+		http.NotFound(w, r)
+		return
+	}
+	readDB, err := db.GetReadDB()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	stored, err := msgstore.List(r.Context(), readDB, id, msgstore.ListOptions{Roles: []string{"user", "assistant"}})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	messages := []chatHistoryMessage{}
+	for _, m := range stored {
+		if text := m.Text(); text != "" {
+			messages = append(messages, chatHistoryMessage{Role: m.Role, Text: text})
+		}
+	}
+	writeChatJSON(w, map[string]any{"messages": messages})
+}
+
+// handleChat runs one message and streams the 'op exec --json' events back
+// as server-sent events. Closing the page cancels the exchange.
+func (c *webChat) handleChat(w http.ResponseWriter, r *http.Request) {
+	var req chatRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxChatMessage)).Decode(&req); err != nil {
+		http.Error(w, "invalid request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	req.Message = strings.TrimSpace(req.Message)
+	if req.Message == "" {
+		http.Error(w, "message is required", http.StatusBadRequest)
+		return
+	}
+	if req.ConversationID != "" && !c.owns(req.ConversationID) {
+		http.NotFound(w, r)
+		return
+	}
+	if req.Agent == "" {
+		req.Agent = c.agent
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	// Messages of one conversation are answered in order.
+	if req.ConversationID != "" {
+		lock := c.conversationLock(req.ConversationID)
+		lock.Lock()
+		defer lock.Unlock()
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	emitter := &webChatEmitter{EventEmitter: newJSONEmitterTo(&sseWriter{w: w, flusher: flusher}), chat: c}
+	if err := execSession(r.Context(), req.Message, req.Agent, req.ConversationID, false, false, emitter); err != nil {
+		emitter.EmitSessionFailed(SessionFailedEvent{SessionID: req.ConversationID, Error: err.Error()})
+	}
+}
+
+func (c *webChat) conversationLock(id string) *sync.Mutex {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	lock, ok := c.convs[id]
+	if !ok {
+		lock = &sync.Mutex{}
+		c.convs[id] = lock
+	}
+	return lock
+}
+
+// owns reports whether the conversation id was started through this web
+// chat.
+func (c *webChat) owns(id string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.created[id]
+}
+
+// webChatEmitter records the conversation each exchange runs in, so the
+// page may continue it and read its history.
+type webChatEmitter struct {
+	EventEmitter
+	chat *webChat
+}
+
+func (e *webChatEmitter) EmitSessionStarted(event SessionStartedEvent) {
+	e.chat.mu.Lock()
+	e.chat.created[event.SessionID] = true
+	e.chat.mu.Unlock()
+	e.EventEmitter.EmitSessionStarted(event)
+}
+
+// sseWriter frames each JSON line written to it as a server-sent event.
+type sseWriter struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+func (s *sseWriter) Write(p []byte) (int, error) {
+	if _, err := fmt.Fprintf(s.w, "data: %s\n\n", strings.TrimRight(string(p), "\n")); err != nil {
+		return 0, err
+	}
+	s.flusher.Flush()
+	return len(p), nil
+}
+
+func writeChatJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
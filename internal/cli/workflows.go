@@ -0,0 +1,212 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"opperator/internal/ipc"
+	"opperator/internal/workflow"
+)
+
+// workflowPollInterval is how often 'op workflow run --wait' refreshes.
+const workflowPollInterval = time.Second
+
+func workflowClient() (*ipc.Client, error) {
+	client, err := ipc.NewClientFromRegistry("local")
+	if err != nil {
+		if ipc.IsCode(err, ipc.ErrCodeUnavailable) {
+			return nil, fmt.Errorf("daemon is not running. Start it with: op daemon start")
+		}
+		return nil, err
+	}
+	return client, nil
+}
+
+// ListWorkflows prints the workflow definitions the daemon can run.
+func ListWorkflows() error {
+	client, err := workflowClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	workflows, err := client.ListWorkflows()
+	if err != nil {
+		return err
+	}
+	if len(workflows) == 0 {
+		fmt.Println("No workflows defined")
+		fmt.Println("\nAdd YAML definitions to ~/.config/opperator/workflows/")
+		return nil
+	}
+
+	fmt.Printf("%-24s %-6s %-30s %s\n", "NAME", "STEPS", "INPUTS", "DESCRIPTION")
+	fmt.Printf("%-24s %-6s %-30s %s\n", "----", "-----", "------", "-----------")
+	for _, wf := range workflows {
+		inputs := make([]string, 0, len(wf.Inputs))
+		for _, in := range wf.Inputs {
+			name := in.Name
+			if in.Required {
+				name += "*"
+			}
+			inputs = append(inputs, name)
+		}
+		fmt.Printf("%-24s %-6d %-30s %s\n", wf.Name, len(wf.Steps), orDash(strings.Join(inputs, ",")), wf.Description)
+	}
+	return nil
+}
+
+// RunWorkflow queues a workflow with inputs given as key=value pairs. With
+// wait it follows the run until it finishes.
+func RunWorkflow(name string, rawInputs []string, wait bool) error {
+	inputs := make(map[string]string, len(rawInputs))
+	for _, raw := range rawInputs {
+		key, value, ok := strings.Cut(raw, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return fmt.Errorf("invalid input %q (expected key=value)", raw)
+		}
+		inputs[strings.TrimSpace(key)] = value
+	}
+
+	client, err := workflowClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	workingDir, _ := os.Getwd()
+	task, err := client.RunWorkflow(name, inputs, workingDir)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Started workflow '%s' as task %s\n", name, task.ID)
+	if !wait {
+		fmt.Printf("Follow it with: op workflow status %s\n", task.ID)
+		return nil
+	}
+
+	printed := 0
+	for {
+		task, err = client.GetToolTask(task.ID)
+		if err != nil {
+			return err
+		}
+		for _, entry := range task.Progress[min(printed, len(task.Progress)):] {
+			if text := strings.TrimSpace(entry.Text); text != "" {
+				fmt.Printf("  %s\n", text)
+			}
+		}
+		printed = len(task.Progress)
+		switch task.Status {
+		case "complete":
+			fmt.Printf("Workflow '%s' complete\n", name)
+			return nil
+		case "failed", "skipped":
+			return fmt.Errorf("workflow '%s' %s: %s", name, task.Status, strings.TrimSpace(task.Error))
+		}
+		time.Sleep(workflowPollInterval)
+	}
+}
+
+// ShowWorkflowStatus prints the state of each step of a workflow task.
+func ShowWorkflowStatus(taskID string) error {
+	client, err := workflowClient()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	task, err := client.GetToolTask(taskID)
+	if err != nil {
+		return err
+	}
+	if task.Mode != "workflow" {
+		return fmt.Errorf("task %s is not a workflow (mode %s)", task.ID, orDash(task.Mode))
+	}
+
+	states := workflowStepStates(task)
+	fmt.Printf("Workflow: %s\n", task.CommandName)
+	fmt.Printf("Task:     %s\n", task.ID)
+	fmt.Printf("Status:   %s\n", task.Status)
+	if trimmed := strings.TrimSpace(task.Error); trimmed != "" {
+		fmt.Printf("Error:    %s\n", trimmed)
+	}
+	fmt.Println()
+
+	// Show steps in definition order when the definition is still around,
+	// otherwise in the order they reported progress.
+	var steps []ipc.WorkflowStepInfo
+	if workflows, err := client.ListWorkflows(); err == nil {
+		for _, wf := range workflows {
+			if wf.Name == task.CommandName {
+				steps = wf.Steps
+			}
+		}
+	}
+	if steps == nil {
+		for _, id := range states.order {
+			steps = append(steps, ipc.WorkflowStepInfo{ID: id})
+		}
+	}
+
+	fmt.Printf("%-20s %-10s %-30s %s\n", "STEP", "STATE", "COMMAND", "DETAIL")
+	fmt.Printf("%-20s %-10s %-30s %s\n", "----", "-----", "-------", "------")
+	for _, step := range steps {
+		state, ok := states.byStep[step.ID]
+		if !ok {
+			state = workflow.StepResult{Status: workflow.StepPending}
+		}
+		command := "-"
+		if step.Agent != "" {
+			command = step.Agent + "." + step.Command
+		}
+		fmt.Printf("%-20s %-10s %-30s %s\n", step.ID, state.Status, command, state.Error)
+	}
+	return nil
+}
+
+type stepStates struct {
+	byStep map[string]workflow.StepResult
+	order  []string
+}
+
+// workflowStepStates reconstructs step states from the final result when the
+// workflow completed, and from progress events while it runs.
+func workflowStepStates(task *ipc.ToolTask) stepStates {
+	states := stepStates{byStep: make(map[string]workflow.StepResult)}
+	for _, entry := range task.Progress {
+		var meta workflow.ProgressMetadata
+		if err := json.Unmarshal([]byte(entry.Metadata), &meta); err != nil || meta.Step == "" {
+			continue
+		}
+		current, seen := states.byStep[meta.Step]
+		if !seen {
+			states.order = append(states.order, meta.Step)
+		}
+		// Forwarded agent progress carries step metadata and must not
+		// overwrite the step's own state transitions.
+		if meta.StepMetadata != "" && seen {
+			continue
+		}
+		current.Status = meta.State
+		if meta.State == workflow.StepFailed || meta.State == workflow.StepSkipped {
+			_, detail, _ := strings.Cut(entry.Text, ": ")
+			current.Error = detail
+		}
+		states.byStep[meta.Step] = current
+	}
+
+	var result workflow.Result
+	if err := json.Unmarshal([]byte(task.Result), &result); err == nil {
+		for id, step := range result.Steps {
+			if _, seen := states.byStep[id]; !seen {
+				states.order = append(states.order, id)
+			}
+			states.byStep[id] = *step
+		}
+	}
+	return states
+}
@@ -0,0 +1,285 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"opperator/config"
+	"opperator/pkg/db"
+	"opperator/pkg/migration"
+)
+
+// WorkspaceChanges are the settings 'op workspace set' changes; nil fields
+// are left alone
+type WorkspaceChanges struct {
+	Description *string
+	Daemon      *string
+	Model       *string
+}
+
+// activeWorkspaceFilter returns the workspace list commands filter by, or
+// nil when all is set or no workspace is active
+func activeWorkspaceFilter(all bool) (*config.Workspace, error) {
+	if all {
+		return nil, nil
+	}
+	return config.ActiveWorkspace()
+}
+
+// addToActiveWorkspace records a new agent or secret in the active
+// workspace, warning instead of failing since the item itself was created
+func addToActiveWorkspace(kind, name string) {
+	ws, err := config.AddToWorkspace(kind, name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to add %s '%s' to the active workspace: %v\n", kind, name, err)
+		return
+	}
+	if ws != "" {
+		fmt.Printf("Added %s '%s' to workspace '%s'\n", kind, name, ws)
+	}
+}
+
+func validateWorkspaceDaemon(name string) error {
+	if name == "" {
+		return nil
+	}
+	registry, err := config.LoadDaemonRegistry()
+	if err != nil {
+		return fmt.Errorf("failed to load daemon registry: %w", err)
+	}
+	if _, err := registry.GetDaemon(name); err != nil {
+		return fmt.Errorf("daemon '%s' not found; add it with 'op daemon add'", name)
+	}
+	return nil
+}
+
+// CreateWorkspace adds a workspace and optionally switches to it
+func CreateWorkspace(ws config.Workspace, use bool) error {
+	if err := validateWorkspaceDaemon(ws.Daemon); err != nil {
+		return err
+	}
+	registry, err := config.LoadWorkspaceRegistry()
+	if err != nil {
+		return err
+	}
+	if err := registry.AddWorkspace(ws); err != nil {
+		return err
+	}
+	if use {
+		registry.Current = ws.Name
+	}
+	if err := config.SaveWorkspaceRegistry(registry); err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ Created workspace '%s'\n", ws.Name)
+	if use {
+		fmt.Printf("  Now using workspace '%s'\n", ws.Name)
+	} else {
+		fmt.Printf("  Switch to it with: op workspace use %s\n", ws.Name)
+	}
+	return nil
+}
+
+// ListWorkspaces prints the configured workspaces, marking the active one
+func ListWorkspaces() error {
+	registry, err := config.LoadWorkspaceRegistry()
+	if err != nil {
+		return err
+	}
+	if len(registry.Workspaces) == 0 {
+		fmt.Println("No workspaces configured")
+		fmt.Printf("\nCreate one with: op workspace create <name>\n")
+		return nil
+	}
+
+	active := registry.ActiveWorkspaceName()
+	fmt.Printf("  %-20s %-15s %-28s %-7s %-8s %s\n", "NAME", "DAEMON", "MODEL", "AGENTS", "SECRETS", "DESCRIPTION")
+	for _, ws := range registry.Workspaces {
+		marker := " "
+		if ws.Name == active {
+			marker = "*"
+		}
+		fmt.Printf("%s %-20s %-15s %-28s %-7d %-8d %s\n",
+			marker, ws.Name, orDash(ws.Daemon), orDash(ws.Model), len(ws.Agents), len(ws.Secrets), orDash(ws.Description))
+	}
+	if name := strings.TrimSpace(os.Getenv(config.WorkspaceEnvVar)); name != "" {
+		fmt.Printf("\n%s=%s overrides 'op workspace use' in this shell\n", config.WorkspaceEnvVar, name)
+	}
+	return nil
+}
+
+// UseWorkspace makes name the active workspace, or clears it when name is
+// empty
+func UseWorkspace(name string) error {
+	registry, err := config.LoadWorkspaceRegistry()
+	if err != nil {
+		return err
+	}
+	if name != "" {
+		if _, err := registry.GetWorkspace(name); err != nil {
+			return err
+		}
+	}
+	registry.Current = name
+	if err := config.SaveWorkspaceRegistry(registry); err != nil {
+		return err
+	}
+
+	if name == "" {
+		fmt.Println("✓ No workspace active; commands show everything")
+	} else {
+		fmt.Printf("✓ Now using workspace '%s'\n", name)
+	}
+	if env := strings.TrimSpace(os.Getenv(config.WorkspaceEnvVar)); env != "" && env != name {
+		fmt.Printf("  Note: %s=%s still overrides this in the current shell\n", config.WorkspaceEnvVar, env)
+	}
+	return nil
+}
+
+// ShowWorkspace prints a workspace's settings and members; the active one
+// when name is empty
+func ShowWorkspace(name string) error {
+	registry, err := config.LoadWorkspaceRegistry()
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		name = registry.ActiveWorkspaceName()
+		if name == "" {
+			fmt.Println("No workspace active")
+			fmt.Printf("\nSwitch to one with: op workspace use <name>\n")
+			return nil
+		}
+	}
+	ws, err := registry.GetWorkspace(name)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Workspace: %s\n", ws.Name)
+	if ws.Description != "" {
+		fmt.Printf("Description: %s\n", ws.Description)
+	}
+	fmt.Printf("Default daemon: %s\n", orDash(ws.Daemon))
+	fmt.Printf("Default model: %s\n", orDash(ws.Model))
+	fmt.Printf("Agents: %s\n", orDash(strings.Join(ws.Agents, ", ")))
+	fmt.Printf("Secrets: %s\n", orDash(strings.Join(ws.Secrets, ", ")))
+	return nil
+}
+
+// UpdateWorkspace changes a workspace's description and defaults
+func UpdateWorkspace(name string, changes WorkspaceChanges) error {
+	registry, err := config.LoadWorkspaceRegistry()
+	if err != nil {
+		return err
+	}
+	ws, err := registry.GetWorkspace(name)
+	if err != nil {
+		return err
+	}
+	if changes.Daemon != nil {
+		if err := validateWorkspaceDaemon(*changes.Daemon); err != nil {
+			return err
+		}
+		ws.Daemon = *changes.Daemon
+	}
+	if changes.Model != nil {
+		ws.Model = strings.TrimSpace(*changes.Model)
+	}
+	if changes.Description != nil {
+		ws.Description = *changes.Description
+	}
+	if err := config.SaveWorkspaceRegistry(registry); err != nil {
+		return err
+	}
+	fmt.Printf("✓ Updated workspace '%s'\n", name)
+	return nil
+}
+
+// AssignToWorkspace adds agents and secrets to a workspace, or removes them
+// when remove is set
+func AssignToWorkspace(name string, agents, secrets []string, remove bool) error {
+	if len(agents) == 0 && len(secrets) == 0 {
+		return fmt.Errorf("nothing to change; pass --agent or --secret")
+	}
+	registry, err := config.LoadWorkspaceRegistry()
+	if err != nil {
+		return err
+	}
+	ws, err := registry.GetWorkspace(name)
+	if err != nil {
+		return err
+	}
+
+	changed := 0
+	apply := func(kind string, names []string) {
+		for _, n := range names {
+			n = strings.TrimSpace(n)
+			if n == "" {
+				continue
+			}
+			if remove && ws.Remove(kind, n) {
+				fmt.Printf("✓ Removed %s '%s' from workspace '%s'\n", kind, n, name)
+				changed++
+			} else if !remove && ws.Add(kind, n) {
+				fmt.Printf("✓ Added %s '%s' to workspace '%s'\n", kind, n, name)
+				changed++
+			}
+		}
+	}
+	apply("agent", agents)
+	apply("secret", secrets)
+
+	if changed == 0 {
+		fmt.Println("Workspace unchanged")
+		return nil
+	}
+	return config.SaveWorkspaceRegistry(registry)
+}
+
+// DeleteWorkspace removes a workspace. Its agents, secrets and
+// conversations are kept; conversations lose their workspace tag.
+func DeleteWorkspace(name string) error {
+	registry, err := config.LoadWorkspaceRegistry()
+	if err != nil {
+		return err
+	}
+	if err := registry.RemoveWorkspace(name); err != nil {
+		return err
+	}
+	if err := config.SaveWorkspaceRegistry(registry); err != nil {
+		return err
+	}
+
+	untagged, err := untagConversations(name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to untag conversations: %v\n", err)
+	}
+	fmt.Printf("✓ Deleted workspace '%s'\n", name)
+	if untagged > 0 {
+		fmt.Printf("  %d conversation(s) are no longer in a workspace\n", untagged)
+	}
+	return nil
+}
+
+func untagConversations(workspace string) (int64, error) {
+	if err := initializeExecDB(); err != nil {
+		return 0, err
+	}
+	writeDB, err := db.GetWriteDB()
+	if err != nil {
+		return 0, err
+	}
+	if err := migration.NewRunner(writeDB).Run(); err != nil {
+		return 0, fmt.Errorf("failed to run migrations: %w", err)
+	}
+	res, err := writeDB.ExecContext(context.Background(),
+		`UPDATE conversations SET workspace = NULL WHERE workspace = ?`, workspace)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,98 @@
+package credentials
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ConfigKeyName is the keyring entry holding the key that encrypts
+// !encrypted values in agents.yaml. It is not registered, so it is neither
+// listed nor handed to agents.
+const ConfigKeyName = "OPPERATOR_CONFIG_KEY"
+
+var configKey struct {
+	mu  sync.Mutex
+	key []byte
+}
+
+// EncryptValue encrypts plaintext with the config key, creating the key on
+// first use, and returns it base64-encoded for an !encrypted YAML value.
+func EncryptValue(plaintext string) (string, error) {
+	gcm, err := configCipher(true)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptValue reverses EncryptValue. The plaintext is scrubbed from agent
+// output like a stored secret.
+func DecryptValue(ciphertext string) (string, error) {
+	gcm, err := configCipher(false)
+	if err != nil {
+		return "", err
+	}
+	data, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil || len(data) < gcm.NonceSize() {
+		return "", fmt.Errorf("malformed encrypted value")
+	}
+	nonce, sealed := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return "", fmt.Errorf("cannot decrypt value; it was encrypted with a different %s", ConfigKeyName)
+	}
+	addRedactedValue(string(plaintext))
+	return string(plaintext), nil
+}
+
+func configCipher(create bool) (cipher.AEAD, error) {
+	configKey.mu.Lock()
+	defer configKey.mu.Unlock()
+	if configKey.key == nil {
+		key, err := loadConfigKey(create)
+		if err != nil {
+			return nil, err
+		}
+		configKey.key = key
+	}
+	block, err := aes.NewCipher(configKey.key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func loadConfigKey(create bool) ([]byte, error) {
+	encoded, err := GetSecret(ConfigKeyName)
+	if errors.Is(err, ErrNotFound) {
+		if !create {
+			return nil, fmt.Errorf("no config encryption key in the keyring (%s); values must be encrypted on this machine with 'op secret encrypt'", ConfigKeyName)
+		}
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+		if err := SetSecret(ConfigKeyName, base64.StdEncoding.EncodeToString(key)); err != nil {
+			return nil, err
+		}
+		return key, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("keyring entry %s is not a valid key", ConfigKeyName)
+	}
+	return key, nil
+}
@@ -0,0 +1,26 @@
+package credentials
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+)
+
+const generateAlphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+
+// GenerateSecret returns a random alphanumeric value of length characters.
+func GenerateSecret(length int) (string, error) {
+	if length <= 0 {
+		return "", fmt.Errorf("secret length must be positive")
+	}
+	max := big.NewInt(int64(len(generateAlphabet)))
+	out := make([]byte, length)
+	for i := range out {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		out[i] = generateAlphabet[n.Int64()]
+	}
+	return string(out), nil
+}
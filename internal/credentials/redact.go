@@ -0,0 +1,136 @@
+package credentials
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// minRedactedLength keeps short values such as "true" from being
+	// scrubbed everywhere they appear.
+	minRedactedLength = 6
+	// redactionRefresh bounds how long a secret stored by another process
+	// goes unnoticed.
+	redactionRefresh = time.Minute
+)
+
+var redaction struct {
+	mu       sync.Mutex
+	loaded   time.Time
+	replacer *strings.Replacer
+	// decrypted holds values decrypted from agents.yaml, which are not in
+	// the keyring but are just as secret
+	decrypted map[string]bool
+}
+
+// SecretValues returns the value of every stored secret by name.
+func SecretValues() (map[string]string, error) {
+	names, err := ListSecrets()
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]string, len(names))
+	for _, name := range names {
+		value, err := GetSecret(name)
+		if err != nil {
+			continue
+		}
+		values[name] = value
+	}
+	return values, nil
+}
+
+// Redact replaces the values of stored secrets in text with
+// [REDACTED:NAME]. Values are also matched in their JSON-escaped form, so
+// protocol messages can be scrubbed before they are decoded.
+func Redact(text string) string {
+	replacer := secretReplacer()
+	if replacer == nil {
+		return text
+	}
+	return replacer.Replace(text)
+}
+
+// addRedactedValue scrubs value alongside the stored secrets.
+func addRedactedValue(value string) {
+	redaction.mu.Lock()
+	defer redaction.mu.Unlock()
+	if redaction.decrypted[value] {
+		return
+	}
+	if redaction.decrypted == nil {
+		redaction.decrypted = make(map[string]bool)
+	}
+	redaction.decrypted[value] = true
+	redaction.loaded = time.Time{}
+}
+
+// invalidateRedaction makes the next Redact reload the stored secrets.
+func invalidateRedaction() {
+	redaction.mu.Lock()
+	redaction.loaded = time.Time{}
+	redaction.mu.Unlock()
+}
+
+func secretReplacer() *strings.Replacer {
+	redaction.mu.Lock()
+	defer redaction.mu.Unlock()
+	if !redaction.loaded.IsZero() && time.Since(redaction.loaded) < redactionRefresh {
+		return redaction.replacer
+	}
+	redaction.loaded = time.Now()
+	values, err := SecretValues()
+	if err != nil {
+		// Keep scrubbing what was known rather than nothing.
+		return redaction.replacer
+	}
+
+	type pair struct{ value, name string }
+	var pairs []pair
+	named := make([]pair, 0, len(values)+len(redaction.decrypted))
+	for name, value := range values {
+		named = append(named, pair{value, name})
+	}
+	for value := range redaction.decrypted {
+		named = append(named, pair{value, "ENCRYPTED"})
+	}
+	for _, p := range named {
+		name, value := p.name, strings.TrimSpace(p.value)
+		if len(value) < minRedactedLength {
+			continue
+		}
+		pairs = append(pairs, pair{value, name})
+		if escaped := jsonEscape(value); escaped != value {
+			pairs = append(pairs, pair{escaped, name})
+		}
+	}
+	if len(pairs) == 0 {
+		redaction.replacer = nil
+		return nil
+	}
+	// The replacer tries values in order; longer first so a secret
+	// containing another is removed whole.
+	sort.Slice(pairs, func(i, j int) bool { return len(pairs[i].value) > len(pairs[j].value) })
+	oldnew := make([]string, 0, len(pairs)*2)
+	for _, p := range pairs {
+		oldnew = append(oldnew, p.value, "[REDACTED:"+p.name+"]")
+	}
+	redaction.replacer = strings.NewReplacer(oldnew...)
+	return redaction.replacer
+}
+
+// jsonEscape returns value as it appears inside a JSON string.
+func jsonEscape(value string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(value); err != nil {
+		return value
+	}
+	out := strings.TrimSpace(buf.String())
+	return out[1 : len(out)-1]
+}
@@ -4,13 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
 	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"opperator/config"
 	"opperator/pkg/db"
 	"opperator/pkg/migration"
 )
@@ -24,15 +23,12 @@ var (
 
 func initDB() error {
 	initOnce.Do(func() {
-		home, err := os.UserHomeDir()
+		dbPath, err := config.GetDatabasePath()
 		if err != nil {
 			initErr = err
 			return
 		}
 
-		dir := filepath.Join(home, ".config", "opperator")
-		dbPath := filepath.Join(dir, "opperator.db")
-
 		if err := db.Initialize(dbPath); err != nil {
 			initErr = err
 			return
@@ -77,6 +73,7 @@ func RegisterSecret(name string) error {
 		`INSERT INTO secrets(name, created_at, updated_at) VALUES(?, ?, ?)
 		 ON CONFLICT(name) DO UPDATE SET updated_at = ?`,
 		trimmed, now, now, now)
+	invalidateRedaction()
 	return err
 }
 
@@ -97,6 +94,7 @@ func UnregisterSecret(name string) error {
 
 	ctx := context.Background()
 	_, err = writeDB.ExecContext(ctx, `DELETE FROM secrets WHERE name = ?`, trimmed)
+	invalidateRedaction()
 	return err
 }
 
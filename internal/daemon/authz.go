@@ -0,0 +1,346 @@
+package daemon
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"opperator/config"
+	"opperator/internal/ipc"
+)
+
+// lastUsedFlushInterval bounds how often last-used times of managed tokens
+// are written back to disk.
+const lastUsedFlushInterval = time.Minute
+
+// Token sources reported by 'op daemon token list'.
+const (
+	tokenSourceEnv     = "env"
+	tokenSourceConfig  = "config"
+	tokenSourceManaged = "managed"
+)
+
+// authToken is a credential accepted on the TCP listener.
+type authToken struct {
+	name      string
+	hash      string
+	role      string
+	user      string
+	source    string
+	createdAt time.Time
+	lastUsed  time.Time
+}
+
+var roleRank = map[string]int{
+	config.RoleViewer:   0,
+	config.RoleOperator: 1,
+	config.RoleAdmin:    2,
+}
+
+// requiredRoles lists the minimum role for each request type. Types missing
+// from the map require admin so new requests are locked down by default.
+var requiredRoles = map[ipc.RequestType]string{
+	ipc.RequestListAgents:        config.RoleViewer,
+	ipc.RequestGetLogs:           config.RoleViewer,
+	ipc.RequestGetCrashReport:    config.RoleViewer,
+	ipc.RequestSearchLogs:        config.RoleViewer,
+	ipc.RequestPromptHistory:     config.RoleViewer,
+	ipc.RequestGetCustomSections: config.RoleViewer,
+	ipc.RequestListCommands:      config.RoleViewer,
+	ipc.RequestGetToolTask:       config.RoleViewer,
+	ipc.RequestListToolTasks:     config.RoleViewer,
+	ipc.RequestWatchToolTask:     config.RoleViewer,
+	ipc.RequestToolTaskMetrics:   config.RoleViewer,
+	ipc.RequestWatchAgentState:   config.RoleViewer,
+	ipc.RequestWatchAllTasks:     config.RoleViewer,
+	ipc.RequestGetAgentConfig:    config.RoleViewer,
+	ipc.RequestGetInvocationDir:  config.RoleViewer,
+	ipc.RequestRecall:            config.RoleViewer,
+	ipc.RequestSearchKnowledge:   config.RoleViewer,
+	ipc.RequestResourceUsage:     config.RoleViewer,
+	ipc.RequestDaemonStats:       config.RoleViewer,
+	ipc.RequestHello:             config.RoleViewer,
+	ipc.RequestListWorkflows:     config.RoleViewer,
+	ipc.RequestListTriggers:      config.RoleViewer,
+	ipc.RequestListChannels:      config.RoleViewer,
+	ipc.RequestBudgetStatus:      config.RoleViewer,
+
+	ipc.RequestStartAgent:          config.RoleOperator,
+	ipc.RequestStopAgent:           config.RoleOperator,
+	ipc.RequestRestartAgent:        config.RoleOperator,
+	ipc.RequestPauseAgent:          config.RoleOperator,
+	ipc.RequestResumeAgent:         config.RoleOperator,
+	ipc.RequestStopAll:             config.RoleOperator,
+	ipc.RequestCommand:             config.RoleOperator,
+	ipc.RequestRemember:            config.RoleOperator,
+	ipc.RequestRevertPrompt:        config.RoleOperator,
+	ipc.RequestCheckGuardrails:     config.RoleOperator,
+	ipc.RequestSubmitToolTask:      config.RoleOperator,
+	ipc.RequestReplayToolTask:      config.RoleOperator,
+	ipc.RequestRunWorkflow:         config.RoleOperator,
+	ipc.RequestExecDetached:        config.RoleOperator,
+	ipc.RequestUploadBlob:          config.RoleOperator,
+	ipc.RequestDownloadBlob:        config.RoleOperator,
+	ipc.RequestKVGet:               config.RoleOperator,
+	ipc.RequestKVSet:               config.RoleOperator,
+	ipc.RequestKVDelete:            config.RoleOperator,
+	ipc.RequestKVList:              config.RoleOperator,
+	ipc.RequestDeleteToolTask:      config.RoleOperator,
+	ipc.RequestLifecycleEvent:      config.RoleOperator,
+	ipc.RequestSetInvocationDir:    config.RoleOperator,
+	ipc.RequestListSecrets:         config.RoleOperator,
+	ipc.RequestReportUpdateFailure: config.RoleOperator,
+	ipc.RequestSpawnSessionAgent:   config.RoleOperator,
+	ipc.RequestEndSession:          config.RoleOperator,
+	ipc.RequestProtocolFrames:      config.RoleOperator,
+	ipc.RequestBudgetCharge:        config.RoleOperator,
+}
+
+// owner is the user the token acts as: its user, or its name when none is
+// set.
+func (t authToken) owner() string {
+	if t.user != "" {
+		return t.user
+	}
+	return t.name
+}
+
+// authorize rejects requests the token's role does not permit. Seeing other
+// users' tasks with AllUsers requires admin whatever the request type.
+func (t authToken) authorize(req ipc.Request) *ipc.Response {
+	required, ok := requiredRoles[req.Type]
+	if !ok || req.AllUsers {
+		required = config.RoleAdmin
+	}
+	if roleRank[t.role] >= roleRank[required] {
+		return nil
+	}
+	if req.AllUsers {
+		resp := ipc.NewErrorResponse(ipc.ErrCodeForbidden,
+			fmt.Sprintf("token %q has role %s; seeing all users' tasks requires admin", t.name, t.role))
+		return &resp
+	}
+	resp := ipc.NewErrorResponse(ipc.ErrCodeForbidden,
+		fmt.Sprintf("token %q has role %s; %s requires %s", t.name, t.role, req.Type, required))
+	return &resp
+}
+
+// tokenRegistry holds every token accepted on the TCP listener: the
+// OPPERATOR_AUTH_TOKEN admin token, tokens declared in daemon.yaml, and
+// managed tokens persisted in auth_tokens.yaml. Only hashes are kept.
+type tokenRegistry struct {
+	mu        sync.Mutex
+	tokens    []*authToken
+	dirty     bool
+	lastFlush time.Time
+}
+
+func newTokenRegistry(cfg config.AuthConfig) *tokenRegistry {
+	r := &tokenRegistry{lastFlush: time.Now()}
+	if env := os.Getenv("OPPERATOR_AUTH_TOKEN"); env != "" {
+		r.add(&authToken{name: "default", hash: config.HashAuthToken(env), role: config.RoleAdmin, source: tokenSourceEnv})
+	}
+	for _, t := range cfg.Tokens {
+		hash := t.TokenHash
+		if hash == "" {
+			hash = config.HashAuthToken(t.Token)
+		}
+		r.add(&authToken{name: t.Name, hash: hash, role: t.Role, user: t.User, source: tokenSourceConfig})
+	}
+
+	managed, err := config.LoadManagedTokens()
+	if err != nil {
+		log.Printf("[Auth] Failed to load managed tokens: %v", err)
+	}
+	for _, t := range managed {
+		tok := &authToken{name: t.Name, hash: t.Hash, role: t.Role, user: t.User, source: tokenSourceManaged, createdAt: t.CreatedAt}
+		if t.LastUsedAt != nil {
+			tok.lastUsed = *t.LastUsedAt
+		}
+		r.add(tok)
+	}
+	return r
+}
+
+func (r *tokenRegistry) add(tok *authToken) {
+	if r.find(tok.name) != nil {
+		log.Printf("[Auth] Ignoring duplicate token name %q (%s)", tok.name, tok.source)
+		return
+	}
+	r.tokens = append(r.tokens, tok)
+}
+
+func (r *tokenRegistry) find(name string) *authToken {
+	for _, t := range r.tokens {
+		if t.name == name {
+			return t
+		}
+	}
+	return nil
+}
+
+func (r *tokenRegistry) empty() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.tokens) == 0
+}
+
+// authenticate finds the token matching presented, comparing every candidate
+// in constant time, and records its use.
+func (r *tokenRegistry) authenticate(presented string) (authToken, bool) {
+	hash := []byte(config.HashAuthToken(presented))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var match *authToken
+	for _, t := range r.tokens {
+		if subtle.ConstantTimeCompare([]byte(t.hash), hash) == 1 && match == nil {
+			match = t
+		}
+	}
+	if match == nil {
+		return authToken{}, false
+	}
+	match.lastUsed = time.Now()
+	if match.source == tokenSourceManaged {
+		r.dirty = true
+		if time.Since(r.lastFlush) >= lastUsedFlushInterval {
+			r.saveLocked()
+		}
+	}
+	return *match, true
+}
+
+// valid reports whether tok is still accepted, so revoked tokens lose access
+// on connections opened before the revocation.
+func (r *tokenRegistry) valid(tok authToken) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	current := r.find(tok.name)
+	return current != nil && current.hash == tok.hash
+}
+
+func (r *tokenRegistry) create(name, role, user string) (string, error) {
+	name = strings.TrimSpace(name)
+	role = strings.ToLower(strings.TrimSpace(role))
+	user = strings.TrimSpace(user)
+	if name == "" {
+		return "", ipc.NewError(ipc.ErrCodeValidation, "token name is required")
+	}
+	if role == "" {
+		role = config.RoleViewer
+	}
+	if _, ok := roleRank[role]; !ok {
+		return "", ipc.NewError(ipc.ErrCodeValidation, fmt.Sprintf("unknown role %q (expected viewer, operator or admin)", role))
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	secret := hex.EncodeToString(buf)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.find(name) != nil {
+		return "", ipc.NewError(ipc.ErrCodeValidation, fmt.Sprintf("token %q already exists", name))
+	}
+	tok := &authToken{
+		name:      name,
+		hash:      config.HashAuthToken(secret),
+		role:      role,
+		user:      user,
+		source:    tokenSourceManaged,
+		createdAt: time.Now(),
+	}
+	r.tokens = append(r.tokens, tok)
+	if err := r.saveLocked(); err != nil {
+		r.tokens = r.tokens[:len(r.tokens)-1]
+		return "", err
+	}
+	log.Printf("[Auth] Created token %q (role %s, user %s)", name, role, tok.owner())
+	return secret, nil
+}
+
+func (r *tokenRegistry) revoke(name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, t := range r.tokens {
+		if t.name != name {
+			continue
+		}
+		if t.source != tokenSourceManaged {
+			return ipc.NewError(ipc.ErrCodeValidation, fmt.Sprintf("token %q is defined by %s; remove it there", name, tokenSourceLabel(t.source)))
+		}
+		r.tokens = append(r.tokens[:i], r.tokens[i+1:]...)
+		if err := r.saveLocked(); err != nil {
+			return err
+		}
+		log.Printf("[Auth] Revoked token %q", name)
+		return nil
+	}
+	return ipc.NewError(ipc.ErrCodeNotFound, fmt.Sprintf("token %q not found", name))
+}
+
+func (r *tokenRegistry) list() []ipc.AuthTokenInfo {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	infos := make([]ipc.AuthTokenInfo, 0, len(r.tokens))
+	for _, t := range r.tokens {
+		info := ipc.AuthTokenInfo{Name: t.name, Role: t.role, User: t.owner(), Source: t.source}
+		if !t.createdAt.IsZero() {
+			created := t.createdAt
+			info.CreatedAt = &created
+		}
+		if !t.lastUsed.IsZero() {
+			used := t.lastUsed
+			info.LastUsedAt = &used
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// flush persists pending last-used updates.
+func (r *tokenRegistry) flush() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.dirty {
+		r.saveLocked()
+	}
+}
+
+func (r *tokenRegistry) saveLocked() error {
+	var managed []config.ManagedToken
+	for _, t := range r.tokens {
+		if t.source != tokenSourceManaged {
+			continue
+		}
+		mt := config.ManagedToken{Name: t.name, Hash: t.hash, Role: t.role, User: t.user, CreatedAt: t.createdAt}
+		if !t.lastUsed.IsZero() {
+			used := t.lastUsed
+			mt.LastUsedAt = &used
+		}
+		managed = append(managed, mt)
+	}
+	r.lastFlush = time.Now()
+	if err := config.SaveManagedTokens(managed); err != nil {
+		log.Printf("[Auth] Failed to save managed tokens: %v", err)
+		return err
+	}
+	r.dirty = false
+	return nil
+}
+
+func tokenSourceLabel(source string) string {
+	if source == tokenSourceEnv {
+		return "OPPERATOR_AUTH_TOKEN"
+	}
+	return "daemon.yaml"
+}
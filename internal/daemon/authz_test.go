@@ -0,0 +1,147 @@
+package daemon
+
+import (
+	"testing"
+
+	"opperator/config"
+	"opperator/internal/ipc"
+)
+
+func TestAuthToken_Authorize(t *testing.T) {
+	tests := []struct {
+		name    string
+		role    string
+		req     ipc.Request
+		allowed bool
+	}{
+		{name: "viewer lists agents", role: config.RoleViewer, req: ipc.Request{Type: ipc.RequestListAgents}, allowed: true},
+		{name: "viewer reads logs", role: config.RoleViewer, req: ipc.Request{Type: ipc.RequestGetLogs}, allowed: true},
+		{name: "viewer runs a command", role: config.RoleViewer, req: ipc.Request{Type: ipc.RequestCommand}},
+		{name: "viewer stops an agent", role: config.RoleViewer, req: ipc.Request{Type: ipc.RequestStopAgent}},
+		{name: "viewer reads agent state", role: config.RoleViewer, req: ipc.Request{Type: ipc.RequestKVGet}},
+		{name: "viewer charges a budget", role: config.RoleViewer, req: ipc.Request{Type: ipc.RequestBudgetCharge}},
+		{name: "operator runs a command", role: config.RoleOperator, req: ipc.Request{Type: ipc.RequestCommand}, allowed: true},
+		{name: "operator lists agents", role: config.RoleOperator, req: ipc.Request{Type: ipc.RequestListAgents}, allowed: true},
+		{name: "operator reads a secret", role: config.RoleOperator, req: ipc.Request{Type: ipc.RequestGetSecret}},
+		{name: "operator sets a secret", role: config.RoleOperator, req: ipc.Request{Type: ipc.RequestSetSecret}},
+		{name: "operator deletes an agent", role: config.RoleOperator, req: ipc.Request{Type: ipc.RequestDeleteAgent}},
+		{name: "operator shuts down", role: config.RoleOperator, req: ipc.Request{Type: ipc.RequestShutdown}},
+		{name: "operator updates the daemon", role: config.RoleOperator, req: ipc.Request{Type: ipc.RequestSelfUpdate}},
+		{name: "unlisted request needs admin", role: config.RoleOperator, req: ipc.Request{Type: "made_up_request"}},
+		{name: "operator sees all users", role: config.RoleOperator, req: ipc.Request{Type: ipc.RequestListToolTasks, AllUsers: true}},
+		{name: "viewer sees all users", role: config.RoleViewer, req: ipc.Request{Type: ipc.RequestListToolTasks, AllUsers: true}},
+		{name: "admin sees all users", role: config.RoleAdmin, req: ipc.Request{Type: ipc.RequestListToolTasks, AllUsers: true}, allowed: true},
+		{name: "admin shuts down", role: config.RoleAdmin, req: ipc.Request{Type: ipc.RequestShutdown}, allowed: true},
+		{name: "admin unlisted request", role: config.RoleAdmin, req: ipc.Request{Type: "made_up_request"}, allowed: true},
+		{name: "unknown role ranks as viewer", role: "superuser", req: ipc.Request{Type: ipc.RequestCommand}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tok := authToken{name: "ci", role: tt.role}
+			resp := tok.authorize(tt.req)
+			if tt.allowed {
+				if resp != nil {
+					t.Errorf("Expected allowed, got %s", resp.Error)
+				}
+				return
+			}
+			if resp == nil {
+				t.Fatalf("Expected %s to be rejected for %s", tt.req.Type, tt.role)
+			}
+			if resp.Success || resp.ErrorCode != ipc.ErrCodeForbidden {
+				t.Errorf("Expected a forbidden error, got %+v", resp)
+			}
+		})
+	}
+}
+
+func TestRequiredRoles_KnownRoles(t *testing.T) {
+	for reqType, role := range requiredRoles {
+		if _, ok := roleRank[role]; !ok {
+			t.Errorf("Request %s requires unknown role %q", reqType, role)
+		}
+		if role == config.RoleAdmin {
+			t.Errorf("Request %s is listed as admin; unlisted requests already require admin", reqType)
+		}
+	}
+}
+
+func TestTokenRegistry_Authenticate(t *testing.T) {
+	t.Setenv("OPPERATOR_AUTH_TOKEN", "")
+	t.Setenv(config.ConfigDirEnv, t.TempDir())
+	r := newTokenRegistry(config.AuthConfig{Tokens: []config.AuthToken{
+		{Name: "dashboard", Token: "viewer-secret", Role: config.RoleViewer},
+	}})
+
+	secret, err := r.create("ci", config.RoleOperator, "build-bot")
+	if err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		presented string
+		wantName  string
+	}{
+		{name: "declared token", presented: "viewer-secret", wantName: "dashboard"},
+		{name: "managed token", presented: secret, wantName: "ci"},
+		{name: "wrong secret", presented: "viewer-secret2"},
+		{name: "empty", presented: ""},
+		{name: "hash instead of secret", presented: config.HashAuthToken(secret)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tok, ok := r.authenticate(tt.presented)
+			if ok != (tt.wantName != "") {
+				t.Fatalf("Expected ok=%v, got %v", tt.wantName != "", ok)
+			}
+			if ok && tok.name != tt.wantName {
+				t.Errorf("Expected token %q, got %q", tt.wantName, tok.name)
+			}
+		})
+	}
+
+	ci, _ := r.authenticate(secret)
+	if ci.owner() != "build-bot" {
+		t.Errorf("Expected the token to act as build-bot, got %q", ci.owner())
+	}
+	if err := r.revoke("ci"); err != nil {
+		t.Fatalf("revoke failed: %v", err)
+	}
+	if r.valid(ci) {
+		t.Errorf("Expected a revoked token to be invalid on open connections")
+	}
+	if _, ok := r.authenticate(secret); ok {
+		t.Errorf("Expected a revoked token to be rejected")
+	}
+}
+
+func TestTokenRegistry_Rejects(t *testing.T) {
+	t.Setenv("OPPERATOR_AUTH_TOKEN", "")
+	t.Setenv(config.ConfigDirEnv, t.TempDir())
+	r := newTokenRegistry(config.AuthConfig{Tokens: []config.AuthToken{
+		{Name: "dashboard", Token: "viewer-secret", Role: config.RoleViewer},
+	}})
+
+	tests := []struct {
+		name string
+		fn   func() error
+		code ipc.ErrorCode
+	}{
+		{name: "create without name", fn: func() error { _, err := r.create(" ", config.RoleViewer, ""); return err }, code: ipc.ErrCodeValidation},
+		{name: "create with unknown role", fn: func() error { _, err := r.create("x", "root", ""); return err }, code: ipc.ErrCodeValidation},
+		{name: "create duplicate", fn: func() error { _, err := r.create("dashboard", config.RoleViewer, ""); return err }, code: ipc.ErrCodeValidation},
+		{name: "revoke declared token", fn: func() error { return r.revoke("dashboard") }, code: ipc.ErrCodeValidation},
+		{name: "revoke unknown token", fn: func() error { return r.revoke("nobody") }, code: ipc.ErrCodeNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ipc.CodeOf(tt.fn()); got != tt.code {
+				t.Errorf("Expected error code %q, got %q", tt.code, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package daemon
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"opperator/config"
+	"opperator/internal/backup"
+	"opperator/internal/ipc"
+	"opperator/internal/notify"
+)
+
+// backupMu keeps a scheduled and a requested backup from running at once.
+var backupMu sync.Mutex
+
+func (s *Server) backupNow() ipc.Response {
+	snap, err := s.runBackup()
+	if err != nil && snap == nil {
+		return ipc.ErrorResponse(err)
+	}
+	if err != nil {
+		log.Printf("[Backup] %v", err)
+	}
+	return ipc.Response{Success: true, Backup: snap}
+}
+
+// runBackup snapshots the config directory with a consistent copy of the
+// open database.
+func (s *Server) runBackup() (*backup.Snapshot, error) {
+	if !s.backup.Enabled() {
+		return nil, ipc.NewError(ipc.ErrCodeValidation, "no backup bucket configured; set backup.bucket in daemon.yaml")
+	}
+	if !backupMu.TryLock() {
+		return nil, ipc.NewError(ipc.ErrCodeBusy, "a backup is already in progress")
+	}
+	defer backupMu.Unlock()
+
+	dirs, err := config.ResolveDirs()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	start := time.Now()
+	snap, err := backup.Run(ctx, s.backup, dirs, s.db)
+	if snap != nil {
+		log.Printf("[Backup] Uploaded snapshot %s (%d bytes) in %s", snap.ID(), snap.Size, time.Since(start).Round(time.Millisecond))
+	}
+	return snap, err
+}
+
+// scheduleBackups takes a snapshot every configured interval.
+func (s *Server) scheduleBackups(stop <-chan struct{}) {
+	interval := s.backup.ScheduleInterval()
+	if interval == 0 {
+		return
+	}
+	log.Printf("[Backup] Snapshots to %s every %s, keeping %d", s.backup.Bucket, interval, s.backup.Retain())
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		if _, err := s.runBackup(); err != nil {
+			log.Printf("[Backup] Scheduled backup failed: %v", err)
+			s.notifier.Notify(notify.Event{
+				Type:    notify.EventBackupFailed,
+				Title:   "Scheduled backup failed",
+				Message: err.Error(),
+				Fields:  map[string]string{"Bucket": s.backup.Bucket},
+			})
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"opperator/config"
+	"opperator/internal/ipc"
+	"opperator/internal/kv"
+	"opperator/internal/protocol"
+)
+
+const (
+	// blackboardSectionDelay batches the sidebar updates of agents writing
+	// many keys at once
+	blackboardSectionDelay = time.Second
+	// blackboardSectionEntries is how many recent writes the sidebar shows
+	blackboardSectionEntries = 8
+	// blackboardValueWidth caps the values shown in the sidebar
+	blackboardValueWidth = 40
+)
+
+// blackboard returns the configuration of the namespace blackboard.
+func (s *Server) blackboard(namespace string) (config.BlackboardConfig, error) {
+	board, ok := s.blackboards[namespace]
+	if !ok {
+		return board, ipc.NewError(ipc.ErrCodeNotFound,
+			fmt.Sprintf("no blackboard %q; add it under blackboards in daemon.yaml", namespace))
+	}
+	return board, nil
+}
+
+// checkBlackboard checks agentName against the read or write list of the
+// namespace blackboard.
+func (s *Server) checkBlackboard(namespace, agentName string, write bool) error {
+	board, err := s.blackboard(namespace)
+	if err != nil {
+		return err
+	}
+	if write && !board.CanWrite(agentName) {
+		return ipc.NewError(ipc.ErrCodeForbidden,
+			fmt.Sprintf("agent %s may not write to blackboard %s", agentName, namespace))
+	}
+	if !write && !board.CanRead(agentName) {
+		return ipc.NewError(ipc.ErrCodeForbidden,
+			fmt.Sprintf("agent %s may not read blackboard %s", agentName, namespace))
+	}
+	return nil
+}
+
+// blackboardOp runs op on the namespace blackboard. writer is the agent
+// writing, already checked with checkBlackboard, or empty for an operator.
+func (s *Server) blackboardOp(ctx context.Context, op, namespace, writer, key string, value json.RawMessage, prefix string) ([]kv.Entry, error) {
+	if _, err := s.blackboard(namespace); err != nil {
+		return nil, err
+	}
+	switch op {
+	case protocol.KVGet:
+		entry, err := s.kv.GetShared(ctx, namespace, key)
+		if err != nil {
+			return nil, err
+		}
+		return []kv.Entry{entry}, nil
+	case protocol.KVSet:
+		entry, err := s.kv.SetShared(ctx, namespace, writer, key, value)
+		if err != nil {
+			return nil, err
+		}
+		s.scheduleBlackboardSections(namespace)
+		return []kv.Entry{entry}, nil
+	case protocol.KVDelete:
+		if err := s.kv.DeleteShared(ctx, namespace, key); err != nil {
+			return nil, err
+		}
+		s.scheduleBlackboardSections(namespace)
+		return nil, nil
+	case protocol.KVList:
+		return s.kv.ListShared(ctx, namespace, prefix)
+	}
+	return nil, ipc.NewError(ipc.ErrCodeValidation, fmt.Sprintf("unknown key-value operation %q", op))
+}
+
+// scheduleBlackboardSections updates the blackboard sections of the agents
+// that can read namespace after blackboardSectionDelay.
+func (s *Server) scheduleBlackboardSections(namespace string) {
+	s.blackboardMu.Lock()
+	defer s.blackboardMu.Unlock()
+	pending := len(s.blackboardDirty) > 0
+	if s.blackboardDirty == nil {
+		s.blackboardDirty = make(map[string]bool)
+	}
+	s.blackboardDirty[namespace] = true
+	if !pending {
+		time.AfterFunc(blackboardSectionDelay, func() {
+			s.blackboardMu.Lock()
+			dirty := s.blackboardDirty
+			s.blackboardDirty = nil
+			s.blackboardMu.Unlock()
+			s.publishBlackboardSections(dirty)
+		})
+	}
+}
+
+// publishBlackboardSections renders the latest writes to the blackboards
+// each agent can read into its blackboard section. Only agents that can read
+// one of the dirty namespaces are updated; nil updates every agent.
+func (s *Server) publishBlackboardSections(dirty map[string]bool) {
+	for _, a := range s.manager.GetAllAgents() {
+		var namespaces []string
+		affected := dirty == nil
+		for ns, board := range s.blackboards {
+			if board.CanRead(a.Config.Name) {
+				namespaces = append(namespaces, ns)
+				affected = affected || dirty[ns]
+			}
+		}
+		if !affected {
+			continue
+		}
+		entries, err := s.kv.Recent(context.Background(), namespaces, blackboardSectionEntries)
+		if err != nil {
+			log.Printf("[Blackboard] Failed to read recent writes for %s: %v", a.Config.Name, err)
+			continue
+		}
+		if len(entries) == 0 {
+			a.ClearBlackboardSection()
+			continue
+		}
+		a.SetBlackboardSection(formatBlackboardEntries(entries))
+	}
+}
+
+// formatBlackboardEntries renders entries two lines each: when and by whom
+// the key was written, then its value.
+func formatBlackboardEntries(entries []kv.Entry) string {
+	var b strings.Builder
+	for i, e := range entries {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		writer := e.Writer
+		if writer == "" {
+			writer = "operator"
+		}
+		value := string(e.Value)
+		var compact bytes.Buffer
+		if json.Compact(&compact, e.Value) == nil {
+			value = compact.String()
+		}
+		if runes := []rune(value); len(runes) > blackboardValueWidth {
+			value = string(runes[:blackboardValueWidth-3]) + "..."
+		}
+		fmt.Fprintf(&b, "%s %s/%s (%s)\n  %s", e.UpdatedAt.Local().Format("15:04"), e.Namespace, e.Key, writer, value)
+	}
+	return b.String()
+}
@@ -0,0 +1,120 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"opperator/config"
+	"opperator/internal/ipc"
+	"opperator/internal/protocol"
+)
+
+// newBlackboardTestServer returns a key-value test server with the plan
+// blackboard, written by planner and read by worker, and the open one,
+// written by every agent.
+func newBlackboardTestServer(t *testing.T) *Server {
+	t.Helper()
+	s := newKVTestServer(t)
+	s.blackboards = map[string]config.BlackboardConfig{
+		"plan": {Read: []string{"worker"}, Write: []string{"planner"}},
+		"open": {Write: []string{"*"}},
+	}
+	// An update already pending keeps writes from scheduling sidebar
+	// updates; the test server has no agents to update
+	s.blackboardDirty = map[string]bool{"": true}
+	return s
+}
+
+func TestHandleAgentKV_BlackboardAccess(t *testing.T) {
+	s := newBlackboardTestServer(t)
+	if resp := s.handleAgentKV("planner", protocol.KVRequestMessage{Op: protocol.KVSet, Namespace: "plan", Key: "step", Value: json.RawMessage(`"crawl"`)}); !resp.Success {
+		t.Fatalf("Expected planner to write the plan, got %s", resp.Error)
+	}
+
+	tests := []struct {
+		name    string
+		agent   string
+		req     protocol.KVRequestMessage
+		success bool
+		code    ipc.ErrorCode
+	}{
+		{name: "writer reads", agent: "planner", req: protocol.KVRequestMessage{Op: protocol.KVGet, Namespace: "plan", Key: "step"}, success: true},
+		{name: "reader reads", agent: "worker", req: protocol.KVRequestMessage{Op: protocol.KVGet, Namespace: "plan", Key: "step"}, success: true},
+		{name: "reader lists", agent: "worker", req: protocol.KVRequestMessage{Op: protocol.KVList, Namespace: "plan"}, success: true},
+		{name: "reader writes", agent: "worker", req: protocol.KVRequestMessage{Op: protocol.KVSet, Namespace: "plan", Key: "step", Value: json.RawMessage(`"done"`)}, code: ipc.ErrCodeForbidden},
+		{name: "reader deletes", agent: "worker", req: protocol.KVRequestMessage{Op: protocol.KVDelete, Namespace: "plan", Key: "step"}, code: ipc.ErrCodeForbidden},
+		{name: "outsider reads", agent: "crawler", req: protocol.KVRequestMessage{Op: protocol.KVGet, Namespace: "plan", Key: "step"}, code: ipc.ErrCodeForbidden},
+		{name: "outsider lists", agent: "crawler", req: protocol.KVRequestMessage{Op: protocol.KVList, Namespace: "plan"}, code: ipc.ErrCodeForbidden},
+		{name: "unknown blackboard", agent: "planner", req: protocol.KVRequestMessage{Op: protocol.KVGet, Namespace: "secrets", Key: "step"}, code: ipc.ErrCodeNotFound},
+		{name: "anyone writes an open blackboard", agent: "crawler", req: protocol.KVRequestMessage{Op: protocol.KVSet, Namespace: "open", Key: "seen", Value: json.RawMessage(`1`)}, success: true},
+		{name: "agent state is not the blackboard", agent: "planner", req: protocol.KVRequestMessage{Op: protocol.KVGet, Key: "step"}, code: ipc.ErrCodeNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := s.handleAgentKV(tt.agent, tt.req)
+			if resp.Success != tt.success {
+				t.Fatalf("Expected success=%v, got %+v", tt.success, resp)
+			}
+			if !tt.success && resp.ErrorCode != string(tt.code) {
+				t.Errorf("Expected error code %q, got %q (%s)", tt.code, resp.ErrorCode, resp.Error)
+			}
+		})
+	}
+
+	// The rejected write and delete left the plan alone
+	resp := s.handleAgentKV("worker", protocol.KVRequestMessage{Op: protocol.KVGet, Namespace: "plan", Key: "step"})
+	if !resp.Success || string(resp.Entries[0].Value) != `"crawl"` {
+		t.Fatalf("Expected the plan to be unchanged, got %+v", resp)
+	}
+	if resp.Entries[0].Writer != "planner" {
+		t.Errorf("Expected the write to be recorded as planner's, got %q", resp.Entries[0].Writer)
+	}
+}
+
+func TestHandleKV_BlackboardOperator(t *testing.T) {
+	s := newBlackboardTestServer(t)
+
+	tests := []struct {
+		name    string
+		req     ipc.Request
+		success bool
+		code    ipc.ErrorCode
+	}{
+		{
+			name:    "operator writes",
+			req:     ipc.Request{Type: ipc.RequestKVSet, KVNamespace: "plan", KVKey: "step", KVValue: json.RawMessage(`"crawl"`)},
+			success: true,
+		},
+		{
+			// Agents must not reach a blackboard through IPC, where the
+			// agent name is only a claim
+			name: "claimed agent",
+			req:  ipc.Request{Type: ipc.RequestKVSet, AgentName: "planner", KVNamespace: "plan", KVKey: "step", KVValue: json.RawMessage(`"x"`)},
+			code: ipc.ErrCodeValidation,
+		},
+		{
+			name: "unknown blackboard",
+			req:  ipc.Request{Type: ipc.RequestKVGet, KVNamespace: "secrets", KVKey: "step"},
+			code: ipc.ErrCodeNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := s.handleKV(context.Background(), tt.req)
+			if resp.Success != tt.success {
+				t.Fatalf("Expected success=%v, got %+v", tt.success, resp)
+			}
+			if !tt.success && resp.ErrorCode != tt.code {
+				t.Errorf("Expected error code %q, got %q (%s)", tt.code, resp.ErrorCode, resp.Error)
+			}
+		})
+	}
+
+	resp := s.handleKV(context.Background(), ipc.Request{Type: ipc.RequestKVGet, KVNamespace: "plan", KVKey: "step"})
+	if !resp.Success || resp.KV[0].Writer != "" {
+		t.Errorf("Expected the operator's write without a writer, got %+v", resp)
+	}
+}
@@ -0,0 +1,285 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"opperator/config"
+	"opperator/internal/ipc"
+	"opperator/internal/protocol"
+)
+
+const (
+	// maxBlobSize bounds the size of a file uploaded as a command argument
+	// or output by a command.
+	maxBlobSize = 1 << 30
+	// blobTTL is how long blobs are kept, so commands using uploaded files
+	// can be replayed and output files downloaded for a while.
+	blobTTL = 24 * time.Hour
+	// blobPartSuffix marks the file of a blob still being uploaded.
+	blobPartSuffix = ".part"
+)
+
+// blobStore keeps files uploaded as command arguments and files commands
+// output in the data directory, one directory per blob holding the file
+// under its own name.
+type blobStore struct {
+	mu sync.Mutex
+}
+
+func newBlobStore() *blobStore {
+	return &blobStore{}
+}
+
+func (b *blobStore) root() (string, error) {
+	dataDir, err := config.GetDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dataDir, "blobs"), nil
+}
+
+// upload appends the chunk in req to its blob, starting a new blob when req
+// has no ID, and returns the blob's ID.
+func (b *blobStore) upload(req ipc.Request) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	root, err := b.root()
+	if err != nil {
+		return "", err
+	}
+
+	id := req.BlobID
+	var part string
+	if id == "" {
+		b.sweep(root)
+		name := filepath.Base(strings.TrimSpace(req.BlobName))
+		if name == "." || name == ".." || name == string(filepath.Separator) || name == "" {
+			name = "blob"
+		}
+		id = uuid.NewString()
+		if err := os.MkdirAll(filepath.Join(root, id), 0o700); err != nil {
+			return "", err
+		}
+		part = filepath.Join(root, id, name+blobPartSuffix)
+	} else {
+		if _, err := uuid.Parse(id); err != nil {
+			return "", ipc.NewError(ipc.ErrCodeValidation, "invalid blob id "+id)
+		}
+		matches, _ := filepath.Glob(filepath.Join(root, id, "*"+blobPartSuffix))
+		if len(matches) != 1 {
+			return "", ipc.NewError(ipc.ErrCodeNotFound, "no upload in progress for blob "+id)
+		}
+		part = matches[0]
+	}
+
+	f, err := os.OpenFile(part, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return "", err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return "", err
+	}
+	if info.Size() != req.BlobOffset {
+		f.Close()
+		return "", ipc.NewError(ipc.ErrCodeValidation, fmt.Sprintf("blob %s has %d bytes, chunk starts at %d", id, info.Size(), req.BlobOffset))
+	}
+	if info.Size()+int64(len(req.BlobData)) > maxBlobSize {
+		f.Close()
+		os.RemoveAll(filepath.Join(root, id))
+		return "", ipc.NewError(ipc.ErrCodeValidation, fmt.Sprintf("blob is larger than %d MiB", maxBlobSize>>20))
+	}
+	if _, err := f.Write(req.BlobData); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+
+	if req.BlobDone {
+		if err := os.Rename(part, strings.TrimSuffix(part, blobPartSuffix)); err != nil {
+			return "", err
+		}
+	}
+	return id, nil
+}
+
+// path returns the file of a completely uploaded blob.
+func (b *blobStore) path(id string) (string, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return "", ipc.NewError(ipc.ErrCodeValidation, "invalid blob id "+id)
+	}
+	root, err := b.root()
+	if err != nil {
+		return "", err
+	}
+	entries, err := os.ReadDir(filepath.Join(root, id))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", ipc.NewError(ipc.ErrCodeNotFound, fmt.Sprintf("blob %s is not available (uploaded files are kept for %s)", id, blobTTL))
+		}
+		return "", err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() && !strings.HasSuffix(entry.Name(), blobPartSuffix) {
+			return filepath.Join(root, id, entry.Name()), nil
+		}
+	}
+	return "", ipc.NewError(ipc.ErrCodeValidation, fmt.Sprintf("blob %s was not completely uploaded", id))
+}
+
+// read returns up to ipc.BlobChunkSize bytes of a blob starting at offset,
+// and whether they are its last ones.
+func (b *blobStore) read(id string, offset int64) ([]byte, bool, error) {
+	path, err := b.path(id)
+	if err != nil {
+		return nil, false, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, false, err
+	}
+	defer f.Close()
+	buf := make([]byte, ipc.BlobChunkSize)
+	n, err := f.ReadAt(buf, offset)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, false, err
+	}
+	return buf[:n], n < len(buf) || errors.Is(err, io.EOF), nil
+}
+
+// store copies a file a command output into a new blob.
+func (b *blobStore) store(file protocol.FileOutput) (ipc.CommandFile, error) {
+	if !filepath.IsAbs(file.Path) {
+		return ipc.CommandFile{}, fmt.Errorf("output file %s is not an absolute path", file.Path)
+	}
+	src, err := os.Open(file.Path)
+	if err != nil {
+		return ipc.CommandFile{}, err
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return ipc.CommandFile{}, err
+	}
+	if !info.Mode().IsRegular() {
+		return ipc.CommandFile{}, fmt.Errorf("output file %s is not a regular file", file.Path)
+	}
+	if info.Size() > maxBlobSize {
+		return ipc.CommandFile{}, fmt.Errorf("output file %s is larger than %d MiB", file.Path, maxBlobSize>>20)
+	}
+
+	name := filepath.Base(strings.TrimSpace(file.Name))
+	if name == "." || name == ".." || name == string(filepath.Separator) || name == "" {
+		name = filepath.Base(file.Path)
+	}
+	mimeType := strings.TrimSpace(file.MimeType)
+	if mimeType == "" {
+		mimeType = mime.TypeByExtension(filepath.Ext(name))
+	}
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	root, err := b.root()
+	if err != nil {
+		return ipc.CommandFile{}, err
+	}
+	b.sweep(root)
+	id := uuid.NewString()
+	if err := os.MkdirAll(filepath.Join(root, id), 0o700); err != nil {
+		return ipc.CommandFile{}, err
+	}
+	dst, err := os.OpenFile(filepath.Join(root, id, name), os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0o600)
+	if err != nil {
+		return ipc.CommandFile{}, err
+	}
+	size, err := io.Copy(dst, src)
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.RemoveAll(filepath.Join(root, id))
+		return ipc.CommandFile{}, err
+	}
+	return ipc.CommandFile{Name: name, MimeType: mimeType, Size: size, BlobID: id}, nil
+}
+
+// storeOutputs keeps copies of the files a successful command output,
+// skipping the ones that cannot be read.
+func (b *blobStore) storeOutputs(agentName, command string, resp *protocol.ResponseMessage) []ipc.CommandFile {
+	if resp == nil || !resp.Success {
+		return nil
+	}
+	var files []ipc.CommandFile
+	for _, file := range resp.Files {
+		stored, err := b.store(file)
+		if err != nil {
+			log.Printf("Failed to keep output file of command %s on %s: %v", command, agentName, err)
+			continue
+		}
+		files = append(files, stored)
+	}
+	return files
+}
+
+// resolveArgs returns args with blob references replaced by the paths of the
+// blobs' files.
+func (b *blobStore) resolveArgs(args map[string]interface{}) (map[string]interface{}, error) {
+	var resolved map[string]interface{}
+	for key, value := range args {
+		id, ok := ipc.BlobRefID(value)
+		if !ok {
+			continue
+		}
+		path, err := b.path(id)
+		if err != nil {
+			return nil, fmt.Errorf("argument %s: %w", key, err)
+		}
+		if resolved == nil {
+			resolved = make(map[string]interface{}, len(args))
+			for k, v := range args {
+				resolved[k] = v
+			}
+		}
+		resolved[key] = path
+	}
+	if resolved == nil {
+		return args, nil
+	}
+	return resolved, nil
+}
+
+// sweep removes blobs older than blobTTL. Callers hold b.mu.
+func (b *blobStore) sweep(root string) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return
+	}
+	cutoff := time.Now().Add(-blobTTL)
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || info.ModTime().After(cutoff) {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(root, entry.Name())); err != nil {
+			log.Printf("Failed to remove expired blob %s: %v", entry.Name(), err)
+		}
+	}
+}
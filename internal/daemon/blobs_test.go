@@ -0,0 +1,229 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"opperator/config"
+	"opperator/internal/ipc"
+	"opperator/internal/protocol"
+)
+
+// newTestBlobStore returns a blob store kept in a temporary data directory,
+// and that directory's blobs root.
+func newTestBlobStore(t *testing.T) (*blobStore, string) {
+	t.Helper()
+	dataDir := t.TempDir()
+	t.Setenv(config.DataDirEnv, dataDir)
+	return newBlobStore(), filepath.Join(dataDir, "blobs")
+}
+
+func TestBlobStore_UploadName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "report.pdf", want: "report.pdf"},
+		{name: "../../etc/passwd", want: "passwd"},
+		{name: "/etc/cron.d/job", want: "job"},
+		{name: "dir/", want: "dir"},
+		{name: "..", want: "blob"},
+		{name: "/", want: "blob"},
+		{name: "", want: "blob"},
+		{name: "  ", want: "blob"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, root := newTestBlobStore(t)
+			id, err := b.upload(ipc.Request{BlobName: tt.name, BlobData: []byte("data"), BlobDone: true})
+			if err != nil {
+				t.Fatalf("upload failed: %v", err)
+			}
+			path, err := b.path(id)
+			if err != nil {
+				t.Fatalf("path failed: %v", err)
+			}
+			if filepath.Dir(path) != filepath.Join(root, id) {
+				t.Errorf("Expected the blob inside %s, got %s", filepath.Join(root, id), path)
+			}
+			if got := filepath.Base(path); got != tt.want {
+				t.Errorf("Expected file name %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestBlobStore_UploadRejects(t *testing.T) {
+	b, root := newTestBlobStore(t)
+	started, err := b.upload(ipc.Request{BlobName: "data.bin", BlobData: []byte("abc")})
+	if err != nil {
+		t.Fatalf("upload failed: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		req  ipc.Request
+		code ipc.ErrorCode
+	}{
+		{name: "path as id", req: ipc.Request{BlobID: "../../etc", BlobData: []byte("x")}, code: ipc.ErrCodeValidation},
+		{name: "glob as id", req: ipc.Request{BlobID: "*", BlobData: []byte("x")}, code: ipc.ErrCodeValidation},
+		{name: "unknown id", req: ipc.Request{BlobID: "8e6b5d2c-9b0e-4c47-9d43-2a1f0f0f0f0f", BlobData: []byte("x")}, code: ipc.ErrCodeNotFound},
+		{name: "gap in offsets", req: ipc.Request{BlobID: started, BlobOffset: 10, BlobData: []byte("x")}, code: ipc.ErrCodeValidation},
+		{name: "overlapping offsets", req: ipc.Request{BlobID: started, BlobOffset: 1, BlobData: []byte("x")}, code: ipc.ErrCodeValidation},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := b.upload(tt.req)
+			if got := ipc.CodeOf(err); got != tt.code {
+				t.Errorf("Expected error code %q, got %q (%v)", tt.code, got, err)
+			}
+		})
+	}
+
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		t.Fatalf("Failed to read blobs: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name() != started {
+		t.Errorf("Expected only blob %s, got %v", started, entries)
+	}
+}
+
+func TestBlobStore_Path(t *testing.T) {
+	b, _ := newTestBlobStore(t)
+	partial, err := b.upload(ipc.Request{BlobName: "big.bin", BlobData: []byte("abc")})
+	if err != nil {
+		t.Fatalf("upload failed: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		id   string
+		code ipc.ErrorCode
+	}{
+		{name: "traversal", id: "../blobs", code: ipc.ErrCodeValidation},
+		{name: "absolute path", id: "/etc", code: ipc.ErrCodeValidation},
+		{name: "empty", id: "", code: ipc.ErrCodeValidation},
+		{name: "missing", id: "8e6b5d2c-9b0e-4c47-9d43-2a1f0f0f0f0f", code: ipc.ErrCodeNotFound},
+		{name: "still uploading", id: partial, code: ipc.ErrCodeValidation},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := b.path(tt.id)
+			if got := ipc.CodeOf(err); got != tt.code {
+				t.Errorf("Expected error code %q, got %q (%v)", tt.code, got, err)
+			}
+		})
+	}
+}
+
+func TestBlobStore_ResolveArgs(t *testing.T) {
+	b, root := newTestBlobStore(t)
+	id, err := b.upload(ipc.Request{BlobName: "input.csv", BlobData: []byte("a,b"), BlobDone: true})
+	if err != nil {
+		t.Fatalf("upload failed: %v", err)
+	}
+
+	args := map[string]interface{}{"file": ipc.BlobRef(id), "name": "report"}
+	resolved, err := b.resolveArgs(args)
+	if err != nil {
+		t.Fatalf("resolveArgs failed: %v", err)
+	}
+	if want := filepath.Join(root, id, "input.csv"); resolved["file"] != want {
+		t.Errorf("Expected file %s, got %v", want, resolved["file"])
+	}
+	if resolved["name"] != "report" {
+		t.Errorf("Expected other arguments unchanged, got %v", resolved["name"])
+	}
+	if _, ok := args["file"].(map[string]interface{}); !ok {
+		t.Errorf("Expected the caller's arguments unchanged, got %v", args["file"])
+	}
+
+	_, err = b.resolveArgs(map[string]interface{}{"file": ipc.BlobRef("../../etc/passwd")})
+	if err == nil || !strings.Contains(err.Error(), "argument file") {
+		t.Errorf("Expected an error naming the argument, got %v", err)
+	}
+}
+
+func TestBlobStore_Store(t *testing.T) {
+	src := t.TempDir()
+	report := filepath.Join(src, "report.pdf")
+	if err := os.WriteFile(report, []byte("%PDF"), 0o644); err != nil {
+		t.Fatalf("Failed to write output file: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		file     protocol.FileOutput
+		wantName string
+		wantMime string
+		err      bool
+	}{
+		{name: "path only", file: protocol.FileOutput{Path: report}, wantName: "report.pdf", wantMime: "application/pdf"},
+		{name: "own name", file: protocol.FileOutput{Path: report, Name: "summary.txt", MimeType: "text/x-summary"}, wantName: "summary.txt", wantMime: "text/x-summary"},
+		{name: "name with directories", file: protocol.FileOutput{Path: report, Name: "../../outside.pdf"}, wantName: "outside.pdf", wantMime: "application/pdf"},
+		{name: "dot-dot name", file: protocol.FileOutput{Path: report, Name: ".."}, wantName: "report.pdf", wantMime: "application/pdf"},
+		{name: "unknown type", file: protocol.FileOutput{Path: report, Name: "data.zzz"}, wantName: "data.zzz", wantMime: "application/octet-stream"},
+		{name: "relative path", file: protocol.FileOutput{Path: "report.pdf"}, err: true},
+		{name: "directory", file: protocol.FileOutput{Path: src}, err: true},
+		{name: "missing", file: protocol.FileOutput{Path: filepath.Join(src, "missing.pdf")}, err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, root := newTestBlobStore(t)
+			stored, err := b.store(tt.file)
+			if tt.err {
+				if err == nil {
+					t.Errorf("Expected an error, got %+v", stored)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("store failed: %v", err)
+			}
+			if stored.Name != tt.wantName || stored.MimeType != tt.wantMime || stored.Size != 4 {
+				t.Errorf("Expected %s (%s, 4 bytes), got %+v", tt.wantName, tt.wantMime, stored)
+			}
+			path, err := b.path(stored.BlobID)
+			if err != nil {
+				t.Fatalf("path failed: %v", err)
+			}
+			if want := filepath.Join(root, stored.BlobID, tt.wantName); path != want {
+				t.Errorf("Expected the copy at %s, got %s", want, path)
+			}
+		})
+	}
+}
+
+func TestBlobStore_StoreOutputs(t *testing.T) {
+	b, _ := newTestBlobStore(t)
+	out := filepath.Join(t.TempDir(), "out.txt")
+	if err := os.WriteFile(out, []byte("done"), 0o644); err != nil {
+		t.Fatalf("Failed to write output file: %v", err)
+	}
+	files := []protocol.FileOutput{{Path: out}, {Path: "relative.txt"}}
+
+	tests := []struct {
+		name string
+		resp *protocol.ResponseMessage
+		want int
+	}{
+		{name: "no response", resp: nil, want: 0},
+		{name: "failed command", resp: &protocol.ResponseMessage{Success: false, Files: files}, want: 0},
+		{name: "unreadable files are skipped", resp: &protocol.ResponseMessage{Success: true, Files: files}, want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := b.storeOutputs("crawler", "export", tt.resp); len(got) != tt.want {
+				t.Errorf("Expected %d stored files, got %+v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package daemon
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"opperator/config"
+	"opperator/internal/ipc"
+	"opperator/internal/notify"
+)
+
+// budgetLedger counts model calls per agent and day in the budget_usage
+// table and declines charges past the configured limits. Model calls do not
+// pass through the daemon: clients charge before each call and honour a
+// declined charge, so budgets are advisory rather than enforced here.
+type budgetLedger struct {
+	cfg config.BudgetsConfig
+	db  *sql.DB
+	mu  sync.Mutex
+	// notified records the day each agent's exhaustion was last reported,
+	// so the notification goes out once rather than for every refused call
+	notified map[string]string
+}
+
+func newBudgetLedger(cfg config.BudgetsConfig, db *sql.DB) *budgetLedger {
+	return &budgetLedger{cfg: cfg, db: db, notified: make(map[string]string)}
+}
+
+func budgetDay(t time.Time) string {
+	return t.Format("2006-01-02")
+}
+
+func (s *Server) chargeBudget(ctx context.Context, req ipc.Request) ipc.Response {
+	agentName := strings.TrimSpace(req.AgentName)
+	if agentName == "" || req.BudgetCharge == nil {
+		return ipc.NewErrorResponse(ipc.ErrCodeValidation, "agent and charge are required")
+	}
+	if c := req.BudgetCharge; c.Calls < 0 || c.InputTokens < 0 || c.OutputTokens < 0 {
+		return ipc.NewErrorResponse(ipc.ErrCodeValidation, "charge counts must not be negative")
+	}
+	status, err := s.budgets.charge(ctx, agentName, *req.BudgetCharge)
+	if err != nil {
+		if ipc.IsCode(err, ipc.ErrCodeBudgetExceeded) {
+			s.notifyBudgetExceeded(status, err.Error())
+		}
+		return ipc.ErrorResponse(err)
+	}
+	return ipc.Response{Success: true, Budgets: []ipc.BudgetStatus{status}}
+}
+
+// charge adds c to today's usage of agent. A charge for a new call fails
+// with ErrCodeBudgetExceeded once the agent's calls or estimated cost have
+// reached their limit.
+func (l *budgetLedger) charge(ctx context.Context, agent string, c ipc.BudgetCharge) (ipc.BudgetStatus, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	day := budgetDay(time.Now())
+	status, err := l.usage(ctx, agent, day)
+	if err != nil {
+		return status, err
+	}
+	if c.Calls > 0 {
+		if reason := l.exceeded(status); reason != "" {
+			return status, ipc.NewError(ipc.ErrCodeBudgetExceeded, fmt.Sprintf("agent %s has used its daily model budget (%s)", agent, reason))
+		}
+	}
+	cost := l.cfg.Cost(c.Model, c.InputTokens, c.OutputTokens)
+	_, err = l.db.ExecContext(ctx, `
+		INSERT INTO budget_usage(agent_name, day, calls, input_tokens, output_tokens, cost)
+		VALUES(?, ?, ?, ?, ?, ?)
+		ON CONFLICT(agent_name, day) DO UPDATE SET
+			calls = calls + excluded.calls,
+			input_tokens = input_tokens + excluded.input_tokens,
+			output_tokens = output_tokens + excluded.output_tokens,
+			cost = cost + excluded.cost`,
+		agent, day, c.Calls, c.InputTokens, c.OutputTokens, cost)
+	if err != nil {
+		return status, fmt.Errorf("record budget usage: %w", err)
+	}
+	status.Calls += c.Calls
+	status.InputTokens += c.InputTokens
+	status.OutputTokens += c.OutputTokens
+	status.Cost += cost
+	return status, nil
+}
+
+// exceeded names the limit status has reached, or returns "".
+func (l *budgetLedger) exceeded(status ipc.BudgetStatus) string {
+	switch {
+	case status.MaxCalls > 0 && status.Calls >= status.MaxCalls:
+		return fmt.Sprintf("%d of %d calls", status.Calls, status.MaxCalls)
+	case status.MaxCost > 0 && status.Cost >= status.MaxCost:
+		return fmt.Sprintf("$%.2f of $%.2f", status.Cost, status.MaxCost)
+	}
+	return ""
+}
+
+func (l *budgetLedger) usage(ctx context.Context, agent, day string) (ipc.BudgetStatus, error) {
+	status := ipc.BudgetStatus{Agent: agent}
+	if limit, ok := l.cfg.Limit(agent); ok {
+		status.MaxCalls, status.MaxCost = limit.MaxCalls, limit.MaxCost
+	}
+	err := l.db.QueryRowContext(ctx,
+		`SELECT calls, input_tokens, output_tokens, cost FROM budget_usage WHERE agent_name = ? AND day = ?`,
+		agent, day).Scan(&status.Calls, &status.InputTokens, &status.OutputTokens, &status.Cost)
+	if err != nil && err != sql.ErrNoRows {
+		return status, fmt.Errorf("read budget usage: %w", err)
+	}
+	return status, nil
+}
+
+// status reports today's usage of every agent that has used or may use the
+// model, limited to agent when set.
+func (l *budgetLedger) status(ctx context.Context, agent string) ([]ipc.BudgetStatus, error) {
+	day := budgetDay(time.Now())
+	names := map[string]bool{}
+	rows, err := l.db.QueryContext(ctx, `SELECT agent_name FROM budget_usage WHERE day = ?`, day)
+	if err != nil {
+		return nil, fmt.Errorf("read budget usage: %w", err)
+	}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err == nil {
+			names[name] = true
+		}
+	}
+	rows.Close()
+	for name := range l.cfg.Agents {
+		names[name] = true
+	}
+
+	var out []ipc.BudgetStatus
+	for name := range names {
+		if agent != "" && name != agent {
+			continue
+		}
+		status, err := l.usage(ctx, name, day)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, status)
+	}
+	if agent != "" && len(out) == 0 {
+		status, err := l.usage(ctx, agent, day)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, status)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Agent < out[j].Agent })
+	return out, nil
+}
+
+func (s *Server) budgetStatus(ctx context.Context, req ipc.Request) ipc.Response {
+	statuses, err := s.budgets.status(ctx, strings.TrimSpace(req.AgentName))
+	if err != nil {
+		return ipc.ErrorResponse(err)
+	}
+	return ipc.Response{Success: true, Budgets: statuses}
+}
+
+func (s *Server) notifyBudgetExceeded(status ipc.BudgetStatus, message string) {
+	day := budgetDay(time.Now())
+	s.budgets.mu.Lock()
+	if s.budgets.notified[status.Agent] == day {
+		s.budgets.mu.Unlock()
+		return
+	}
+	s.budgets.notified[status.Agent] = day
+	s.budgets.mu.Unlock()
+
+	log.Printf("[Budget] %s", message)
+	fields := map[string]string{"Calls": fmt.Sprint(status.Calls)}
+	if status.MaxCalls > 0 {
+		fields["Calls"] = fmt.Sprintf("%d / %d", status.Calls, status.MaxCalls)
+	}
+	if status.MaxCost > 0 {
+		fields["Estimated cost"] = fmt.Sprintf("$%.2f / $%.2f", status.Cost, status.MaxCost)
+	}
+	s.notifier.Notify(notify.Event{
+		Type:    notify.EventBudgetExceeded,
+		Title:   fmt.Sprintf("Agent %s hit its daily model budget", status.Agent),
+		Message: message + "; further model calls for it fail until tomorrow",
+		Agent:   status.Agent,
+		Fields:  fields,
+	})
+}
@@ -0,0 +1,212 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"opperator/config"
+	"opperator/internal/channel"
+	"opperator/internal/credentials"
+	"opperator/internal/email"
+	"opperator/internal/ipc"
+	"opperator/internal/slack"
+	"opperator/internal/telegram"
+)
+
+// Channel sources reported by 'op channel list'.
+const (
+	channelSourceConfig   = "config"
+	channelSourceRegistry = "registry"
+)
+
+// channelConversations maps chat threads to exec conversations in
+// channel_threads, so a thread continues the same conversation, which can
+// also be resumed from the TUI or with 'op exec --resume'.
+type channelConversations struct {
+	s *Server
+}
+
+// Respond runs a chat message through 'op exec' with agent.
+func (c channelConversations) Respond(ctx context.Context, name, agent string, msg channel.Message, progress func(string)) (string, error) {
+	// A thread without a row starts a new conversation.
+	var conversationID string
+	if msg.Thread != "" {
+		_ = c.s.db.QueryRowContext(ctx,
+			`SELECT conversation_id FROM channel_threads WHERE channel = ? AND thread_id = ?`,
+			name, msg.Thread).Scan(&conversationID)
+	}
+
+	reply, started, err := execConversation(ctx, agent, conversationID, msg.Text, progress)
+	if err != nil {
+		return "", err
+	}
+	if started != "" && msg.Thread != "" {
+		if _, err := c.s.db.ExecContext(ctx,
+			`INSERT OR REPLACE INTO channel_threads (channel, thread_id, conversation_id, created_at) VALUES (?, ?, ?, ?)`,
+			name, msg.Thread, started, time.Now().Unix()); err != nil {
+			return "", fmt.Errorf("record channel thread: %w", err)
+		}
+	}
+	return reply, nil
+}
+
+func (c channelConversations) Reset(ctx context.Context, name, thread string) error {
+	_, err := c.s.db.ExecContext(ctx,
+		`DELETE FROM channel_threads WHERE channel = ? AND thread_id = ?`, name, thread)
+	return err
+}
+
+// newChannel builds the transport for a validated channel with environment
+// references resolved.
+func newChannel(cfg config.ChannelConfig) (channel.Channel, error) {
+	switch cfg.Type {
+	case config.ChannelEmail:
+		return email.New(*cfg.Email), nil
+	case config.ChannelSlack:
+		return slack.New(*cfg.Slack), nil
+	case config.ChannelTelegram:
+		token, err := credentials.GetSecret(cfg.Telegram.TokenSecret)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read secret %s: %w (run: op secret create %s)", cfg.Telegram.TokenSecret, err, cfg.Telegram.TokenSecret)
+		}
+		return telegram.New(*cfg.Telegram, token), nil
+	}
+	return nil, fmt.Errorf("unknown channel type %q", cfg.Type)
+}
+
+func (s *Server) startChannel(cfg config.ChannelConfig, source string) error {
+	cfg = cfg.Expanded()
+	ch, err := newChannel(cfg)
+	if err != nil {
+		return err
+	}
+	s.channels.Add(channel.Spec{
+		Name:    cfg.Name,
+		Type:    cfg.Type,
+		Agent:   cfg.Agent(),
+		Source:  source,
+		Timeout: cfg.Timeout(),
+	}, ch)
+	return nil
+}
+
+// startChannels starts the channels from daemon.yaml and channels.yaml. A
+// channel that cannot start is logged and skipped rather than keeping the
+// daemon from starting.
+func (s *Server) startChannels(configured []config.ChannelConfig) {
+	for _, cfg := range configured {
+		if err := s.startChannel(cfg, channelSourceConfig); err != nil {
+			log.Printf("[Channel] %s disabled: %v", cfg.Name, err)
+		}
+	}
+	registered, err := config.LoadRegisteredChannels()
+	if err != nil {
+		log.Printf("[Channel] Failed to load registered channels: %v", err)
+		return
+	}
+	for _, cfg := range registered {
+		if _, exists := s.channels.Get(cfg.Name); exists {
+			log.Printf("[Channel] Skipping registered channel %q: name already used in daemon.yaml", cfg.Name)
+			continue
+		}
+		if err := cfg.Validate(); err != nil {
+			log.Printf("[Channel] Skipping registered channel: %v", err)
+			continue
+		}
+		if err := s.startChannel(cfg, channelSourceRegistry); err != nil {
+			log.Printf("[Channel] %s disabled: %v", cfg.Name, err)
+		}
+	}
+}
+
+func (s *Server) listChannels() ipc.Response {
+	statuses := s.channels.List()
+	infos := make([]ipc.ChannelInfo, 0, len(statuses))
+	for _, st := range statuses {
+		info := ipc.ChannelInfo{
+			Name:      st.Name,
+			Type:      st.Type,
+			Agent:     st.Agent,
+			Source:    st.Source,
+			Connected: st.Connected,
+			Messages:  st.Messages,
+			LastError: st.LastError,
+		}
+		if !st.LastMessage.IsZero() {
+			info.LastMessage = st.LastMessage.UTC().Format(time.RFC3339)
+		}
+		infos = append(infos, info)
+	}
+	return ipc.Response{Success: true, Channels: infos}
+}
+
+// addChannel registers a channel in channels.yaml and starts it, replacing
+// a registered channel of the same name.
+func (s *Server) addChannel(req ipc.Request) ipc.Response {
+	if req.Channel == nil {
+		return ipc.NewErrorResponse(ipc.ErrCodeValidation, "channel is required")
+	}
+	cfg := *req.Channel
+	if err := cfg.Validate(); err != nil {
+		return ipc.NewErrorResponse(ipc.ErrCodeValidation, err.Error())
+	}
+	if spec, ok := s.channels.Get(cfg.Name); ok && spec.Source == channelSourceConfig {
+		return ipc.NewErrorResponse(ipc.ErrCodeValidation,
+			fmt.Sprintf("channel %q is defined in daemon.yaml", cfg.Name))
+	}
+
+	s.channelsMu.Lock()
+	defer s.channelsMu.Unlock()
+	registered, err := config.LoadRegisteredChannels()
+	if err != nil {
+		return ipc.ErrorResponse(err)
+	}
+	registered = slices.DeleteFunc(registered, func(c config.ChannelConfig) bool {
+		return strings.TrimSpace(c.Name) == cfg.Name
+	})
+	if err := config.SaveRegisteredChannels(append(registered, cfg)); err != nil {
+		return ipc.ErrorResponse(err)
+	}
+	if err := s.startChannel(cfg, channelSourceRegistry); err != nil {
+		return ipc.ErrorResponse(fmt.Errorf("channel %q saved but not started: %w", cfg.Name, err))
+	}
+	return ipc.Response{Success: true}
+}
+
+// removeChannel stops a registered channel and drops it from channels.yaml.
+// Channels from daemon.yaml are removed by editing that file.
+func (s *Server) removeChannel(req ipc.Request) ipc.Response {
+	name := strings.TrimSpace(req.ChannelName)
+	if name == "" {
+		return ipc.NewErrorResponse(ipc.ErrCodeValidation, "channel name is required")
+	}
+	if spec, ok := s.channels.Get(name); ok && spec.Source == channelSourceConfig {
+		return ipc.NewErrorResponse(ipc.ErrCodeValidation,
+			fmt.Sprintf("channel %q is defined in daemon.yaml; remove it there", name))
+	}
+
+	s.channelsMu.Lock()
+	defer s.channelsMu.Unlock()
+	registered, err := config.LoadRegisteredChannels()
+	if err != nil {
+		return ipc.ErrorResponse(err)
+	}
+	kept := slices.DeleteFunc(slices.Clone(registered), func(c config.ChannelConfig) bool {
+		return strings.TrimSpace(c.Name) == name
+	})
+	stopped := s.channels.Remove(name)
+	if len(kept) == len(registered) {
+		if stopped {
+			return ipc.Response{Success: true}
+		}
+		return ipc.NewErrorResponse(ipc.ErrCodeNotFound, fmt.Sprintf("channel %q not found", name))
+	}
+	if err := config.SaveRegisteredChannels(kept); err != nil {
+		return ipc.ErrorResponse(err)
+	}
+	return ipc.Response{Success: true}
+}
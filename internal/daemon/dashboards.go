@@ -0,0 +1,251 @@
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"html/template"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+
+	"opperator/config"
+	"opperator/internal/protocol"
+	"tui/components/sidebar"
+)
+
+// dashboardCookie carries the auth token once a dashboard link with a token
+// query parameter has been opened, so the token drops out of the address bar.
+const dashboardCookie = "opperator_dashboard"
+
+var dashboardMarkdown = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+var dashboardPage = template.Must(template.New("dashboard").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+{{if .Refresh}}<meta http-equiv="refresh" content="{{.Refresh}}">
+{{end}}<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", sans-serif; margin: 0; color: #1f2328; background: #f6f8fa; }
+header { padding: 12px 24px; background: #24292f; color: #fff; display: flex; gap: 16px; align-items: baseline; }
+header a { color: #9ecbff; text-decoration: none; }
+main { max-width: 1100px; margin: 24px auto; padding: 24px; background: #fff; border: 1px solid #d0d7de; border-radius: 6px; }
+table { border-collapse: collapse; margin: 12px 0; }
+th, td { border: 1px solid #d0d7de; padding: 6px 12px; text-align: left; }
+th { background: #f6f8fa; }
+pre { background: #f6f8fa; padding: 12px; overflow-x: auto; }
+.muted { color: #656d76; font-size: 0.9em; }
+</style>
+</head>
+<body>
+<header><strong>{{.Title}}</strong>{{if .Agent}}<span>{{.Agent}}</span>{{end}}<a href="/dashboards">All dashboards</a></header>
+<main>
+{{.Body}}
+</main>
+</body>
+</html>
+`))
+
+var dashboardIndex = template.Must(template.New("index").Parse(`{{if .}}<ul>
+{{range .}}<li><a href="{{.URL}}">{{.Title}}</a> <span class="muted">{{.Agent}}</span></li>
+{{end}}</ul>{{else}}<p class="muted">No agent has published a dashboard yet.</p>{{end}}`))
+
+type dashboardLink struct {
+	Agent string
+	Title string
+	URL   string
+}
+
+// startDashboards serves the dashboards published by agents on cfg.Listen.
+func (s *Server) startDashboards(cfg config.DashboardsConfig) {
+	if cfg.Listen == "" {
+		return
+	}
+	listener, err := net.Listen("tcp", cfg.Listen)
+	if err != nil {
+		log.Printf("[Dashboards] disabled: listen on %s: %v", cfg.Listen, err)
+		return
+	}
+	if s.tokens.empty() {
+		log.Printf("WARNING: dashboards enabled but no auth token configured; requests will be rejected until a token is created with 'op daemon token create'")
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /dashboards", s.dashboardAuth(s.handleDashboardIndex))
+	mux.HandleFunc("GET /dashboards/{agent}/{id}", s.dashboardAuth(s.handleDashboard))
+	s.dashboards = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	log.Printf("[Dashboards] Serving on http://%s/dashboards", listener.Addr())
+	go func() {
+		if err := s.dashboards.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("[Dashboards] server stopped: %v", err)
+		}
+	}()
+}
+
+func (s *Server) stopDashboards() {
+	if s.dashboards == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_ = s.dashboards.Shutdown(ctx)
+	s.dashboards = nil
+}
+
+// dashboardAuth accepts any daemon auth token as a bearer token, token query
+// parameter or cookie. A token passed in the query is moved into a cookie
+// and the request redirected without it.
+func (s *Server) dashboardAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		token := query.Get("token")
+		fromQuery := token != ""
+		if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			token, fromQuery = bearer, false
+		} else if !fromQuery {
+			if c, err := r.Cookie(dashboardCookie); err == nil {
+				token = c.Value
+			}
+		}
+		if token == "" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if _, ok := s.tokens.authenticate(token); !ok {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if fromQuery {
+			http.SetCookie(w, &http.Cookie{
+				Name:     dashboardCookie,
+				Value:    token,
+				Path:     "/dashboards",
+				HttpOnly: true,
+				SameSite: http.SameSiteStrictMode,
+				Secure:   r.TLS != nil,
+			})
+			query.Del("token")
+			target := url.URL{Path: r.URL.Path, RawQuery: query.Encode()}
+			http.Redirect(w, r, target.String(), http.StatusSeeOther)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (s *Server) handleDashboardIndex(w http.ResponseWriter, r *http.Request) {
+	var links []dashboardLink
+	for _, a := range s.manager.GetAllAgents() {
+		for _, section := range a.CustomSections() {
+			if section.Type != sidebar.SectionTypeDashboard {
+				continue
+			}
+			links = append(links, dashboardLink{
+				Agent: a.Config.Name,
+				Title: dashboardTitle(section),
+				URL:   "/dashboards/" + url.PathEscape(a.Config.Name) + "/" + url.PathEscape(section.ID),
+			})
+		}
+	}
+	sort.Slice(links, func(i, j int) bool {
+		if links[i].Agent != links[j].Agent {
+			return links[i].Agent < links[j].Agent
+		}
+		return links[i].Title < links[j].Title
+	})
+
+	var body bytes.Buffer
+	if err := dashboardIndex.Execute(&body, links); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeDashboardPage(w, r, "Dashboards", "", template.HTML(body.String()), false)
+}
+
+func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
+	a, err := s.manager.GetAgent(r.PathValue("agent"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	var section *sidebar.CustomSection
+	for _, sec := range a.CustomSections() {
+		if sec.ID == r.PathValue("id") && sec.Type == sidebar.SectionTypeDashboard {
+			section = &sec
+			break
+		}
+	}
+	if section == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	if section.Format == protocol.DashboardFormatHTML {
+		// A complete document is served as is; a fragment gets the page chrome
+		if strings.Contains(strings.ToLower(section.Content), "<html") {
+			setDashboardHeaders(w, true)
+			w.Write([]byte(section.Content))
+			return
+		}
+		writeDashboardPage(w, r, dashboardTitle(*section), a.Config.Name, template.HTML(section.Content), true)
+		return
+	}
+
+	var body bytes.Buffer
+	if err := dashboardMarkdown.Convert([]byte(section.Content), &body); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeDashboardPage(w, r, dashboardTitle(*section), a.Config.Name, template.HTML(body.String()), false)
+}
+
+// writeDashboardPage renders body in the page template. A refresh query
+// parameter reloads the page every that many seconds.
+func writeDashboardPage(w http.ResponseWriter, r *http.Request, title, agentName string, body template.HTML, scripts bool) {
+	refresh, _ := strconv.Atoi(r.URL.Query().Get("refresh"))
+	if refresh < 0 {
+		refresh = 0
+	}
+	setDashboardHeaders(w, scripts)
+	err := dashboardPage.Execute(w, struct {
+		Title   string
+		Agent   string
+		Body    template.HTML
+		Refresh int
+	}{title, agentName, body, refresh})
+	if err != nil {
+		log.Printf("[Dashboards] render %q: %v", title, err)
+	}
+}
+
+// setDashboardHeaders allows scripts and external resources only for HTML
+// dashboards, which agents write to draw charts; markdown renders as static
+// content. Raw HTML in markdown is never rendered.
+func setDashboardHeaders(w http.ResponseWriter, scripts bool) {
+	csp := "default-src 'none'; img-src data: https:; style-src 'unsafe-inline'"
+	if scripts {
+		csp = "default-src 'self' data: https:; script-src 'self' 'unsafe-inline' https:; style-src 'self' 'unsafe-inline' https:"
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", csp)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "no-store")
+}
+
+func dashboardTitle(section sidebar.CustomSection) string {
+	if title := strings.TrimSpace(section.Title); title != "" {
+		return title
+	}
+	return section.ID
+}
@@ -1,6 +1,7 @@
 package daemon
 
 import (
+	"opperator/internal/agent"
 	"opperator/internal/protocol"
 	"opperator/internal/taskqueue"
 	"tui/components/sidebar"
@@ -15,6 +16,8 @@ const (
 	AgentStateSections AgentStateChangeType = "sections" // Custom sections changed
 	AgentStateStatus   AgentStateChangeType = "status"   // Agent started/stopped
 	AgentStateCommands AgentStateChangeType = "commands" // Command registry updated
+	// Protocol handshake completed
+	AgentStateCapabilities AgentStateChangeType = "capabilities"
 )
 
 type AgentStateChange struct {
@@ -35,7 +38,11 @@ type AgentStateChange struct {
 	Commands       []protocol.CommandDescriptor
 
 	// Status fields (populated when Type == AgentStateStatus)
-	Status string
+	Status   string
+	LastExit *agent.ExitInfo
+
+	// Populated when Type == AgentStateCapabilities
+	Capabilities *protocol.Capabilities
 }
 
 // TaskEventType identifies what kind of task event occurred
@@ -0,0 +1,201 @@
+package daemon
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"opperator/internal/credentials"
+	"opperator/internal/ipc"
+	"opperator/internal/taskqueue"
+	"opperator/pkg/tracing"
+)
+
+// execEvent is the subset of 'op exec --json' events the daemon reacts to.
+type execEvent struct {
+	Type          string `json:"type"`
+	SessionID     string `json:"session_id"`
+	FinalResponse string `json:"final_response"`
+	Error         string `json:"error"`
+	AgentName     string `json:"agent_name"`
+	Item          struct {
+		Type        string `json:"type"`
+		Status      string `json:"status"`
+		Name        string `json:"name"`
+		DisplayName string `json:"display_name"`
+		Error       string `json:"error"`
+	} `json:"item"`
+	Progress struct {
+		Text string `json:"text"`
+	} `json:"progress"`
+}
+
+// progressLine renders an event as a one-line status update, or "" when the
+// event is not worth showing.
+func (ev execEvent) progressLine() string {
+	name := ev.Item.DisplayName
+	if name == "" {
+		name = ev.Item.Name
+	}
+	switch ev.Type {
+	case "item.started":
+		if ev.Item.Type == "tool_call" {
+			return "Running " + name
+		}
+	case "item.completed":
+		if ev.Item.Type == "tool_call" && ev.Item.Error != "" {
+			return name + " failed: " + ev.Item.Error
+		}
+	case "command.progress":
+		return strings.TrimSpace(ev.Progress.Text)
+	case "subagent.started":
+		return "Delegating to " + ev.AgentName
+	}
+	return ""
+}
+
+// execConversation sends message to agentName through 'op exec', the same
+// conversation loop the CLI uses, continuing conversationID when set. A
+// deleted conversation is started over. It returns the final response and,
+// when a new conversation was started, its ID.
+func execConversation(ctx context.Context, agentName, conversationID, message string, progress func(string)) (string, string, error) {
+	reply, sessionID, err := runExec(ctx, agentName, conversationID, message, "", progress)
+	if err != nil && conversationID != "" && strings.Contains(err.Error(), "conversation not found") {
+		conversationID = ""
+		reply, sessionID, err = runExec(ctx, agentName, "", message, "", progress)
+	}
+	if err != nil {
+		return "", "", err
+	}
+	if conversationID != "" {
+		sessionID = ""
+	}
+	return reply, sessionID, nil
+}
+
+// runExec runs 'op exec' in JSON mode, in dir or the home directory, and
+// returns the final response and the conversation it was saved in.
+func runExec(ctx context.Context, agentName, conversationID, message, dir string, progress func(string)) (string, string, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return "", "", err
+	}
+	args := []string{"exec", message, "--agent", agentName, "--json"}
+	if conversationID != "" {
+		args = append(args, "--resume", conversationID)
+	}
+	cmd := exec.CommandContext(ctx, executable, args...)
+	cmd.Dir = dir
+	if home, err := os.UserHomeDir(); err == nil && dir == "" {
+		cmd.Dir = home
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return "", "", err
+	}
+	if err := cmd.Start(); err != nil {
+		return "", "", err
+	}
+
+	var sessionID, reply, failure string
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var ev execEvent
+		if json.Unmarshal(scanner.Bytes(), &ev) != nil {
+			continue
+		}
+		switch ev.Type {
+		case "session.started":
+			sessionID = ev.SessionID
+		case "session.completed":
+			reply = ev.FinalResponse
+		case "session.failed":
+			failure = ev.Error
+		default:
+			if progress != nil {
+				if line := ev.progressLine(); line != "" {
+					progress(credentials.Redact(line))
+				}
+			}
+		}
+	}
+	if err := cmd.Wait(); err != nil {
+		if failure == "" {
+			failure = strings.TrimSpace(stderr.String())
+		}
+		if failure == "" {
+			failure = err.Error()
+		}
+		return "", sessionID, fmt.Errorf("exec with agent %s: %s", agentName, credentials.Redact(failure))
+	}
+	return reply, sessionID, nil
+}
+
+// daemonConversationRunner runs detached exec tasks through 'op exec'.
+type daemonConversationRunner struct{}
+
+func (daemonConversationRunner) Execute(ctx context.Context, agentName, message, conversationID, workingDir string, progress func(taskqueue.ProgressEvent)) (string, string, error) {
+	reply, sessionID, err := runExec(ctx, agentName, conversationID, message, workingDir, func(line string) {
+		progress(taskqueue.ProgressEvent{Text: line})
+	})
+	if err != nil {
+		return "", "", err
+	}
+	if sessionID == "" {
+		sessionID = conversationID
+	}
+	metadata, err := json.Marshal(map[string]string{"agent": agentName, "conversation_id": sessionID})
+	if err != nil {
+		return "", "", err
+	}
+	return reply, string(metadata), nil
+}
+
+// execDetached queues a message as a background conversation. It runs at
+// most once: a conversation cut short by a daemon restart is not sent again,
+// since its tool calls may already have run.
+func (s *Server) execDetached(ctx context.Context, req ipc.Request) ipc.Response {
+	if s.tasks == nil {
+		return ipc.NewErrorResponse(ipc.ErrCodeUnavailable, "tool task manager unavailable")
+	}
+	if strings.TrimSpace(req.Message) == "" {
+		return ipc.NewErrorResponse(ipc.ErrCodeValidation, "message is required")
+	}
+	toolName := "exec"
+	if req.AgentName != "" {
+		toolName = "exec:" + req.AgentName
+	}
+	origin := taskScopeFrom(ctx).origin(req.Origin, "cli")
+	task, err := s.tasks.Submit(context.Background(), taskqueue.SubmitRequest{
+		ToolName:    toolName,
+		WorkingDir:  req.WorkingDir,
+		SessionID:   req.SessionID,
+		Mode:        "conversation",
+		AgentName:   req.AgentName,
+		CommandArgs: req.Message,
+		Origin:      origin,
+		ClientID:    req.ClientID,
+		TraceParent: tracing.TraceParent(ctx),
+		Owner:       taskScopeFrom(ctx).user,
+		Execution:   taskqueue.ExecutionAtMostOnce,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, taskqueue.ErrPendingLimit):
+			return ipc.NewErrorResponse(ipc.ErrCodeBusy, err.Error())
+		case errors.Is(err, taskqueue.ErrClosed):
+			return ipc.NewErrorResponse(ipc.ErrCodeUnavailable, err.Error())
+		}
+		return ipc.ErrorResponse(err)
+	}
+	return ipc.Response{Success: true, Task: convertTask(task)}
+}
@@ -0,0 +1,188 @@
+package daemon
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"opperator/config"
+	"opperator/internal/agent"
+	"opperator/internal/gitsync"
+	"opperator/internal/ipc"
+)
+
+// gitSyncMu keeps a webhook and a requested pull from running at once.
+var gitSyncMu sync.Mutex
+
+func (s *Server) reloadFromGit() ipc.Response {
+	res, err := s.syncFromGit()
+	if err != nil {
+		return ipc.ErrorResponse(err)
+	}
+	return ipc.Response{Success: true, GitSync: res}
+}
+
+// syncFromGit fast-forwards the config directory, reloads agents.yaml and
+// restarts the running agents whose process root changed.
+func (s *Server) syncFromGit() (*gitsync.Result, error) {
+	if !gitSyncMu.TryLock() {
+		return nil, ipc.NewError(ipc.ErrCodeBusy, "a git sync is already in progress")
+	}
+	defer gitSyncMu.Unlock()
+
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	res, err := gitsync.Pull(ctx, configDir, s.gitSync.RemoteName(), s.gitSync.Branch)
+	if err != nil {
+		if errors.Is(err, gitsync.ErrDirty) {
+			return nil, ipc.NewError(ipc.ErrCodeValidation, err.Error()+"; commit or discard them first")
+		}
+		return nil, ipc.NewError(ipc.ErrCodeInternal, err.Error())
+	}
+	if !res.Changed() {
+		log.Printf("[GitSync] %s/%s is up to date at %s", res.Remote, res.Branch, shortCommit(res.To))
+		return res, nil
+	}
+	log.Printf("[GitSync] Pulled %s/%s %s..%s (%d files changed)", res.Remote, res.Branch, shortCommit(res.From), shortCommit(res.To), len(res.Files))
+
+	if err := s.manager.ReloadConfigManual(); err != nil {
+		return nil, ipc.NewError(ipc.ErrCodeInternal, "pulled "+shortCommit(res.To)+" but failed to reload config: "+err.Error())
+	}
+	for _, f := range res.Files {
+		if f == "daemon.yaml" {
+			log.Printf("[GitSync] daemon.yaml changed; restart the daemon to apply it")
+		}
+	}
+	for _, a := range s.manager.GetAllAgents() {
+		if a.GetStatus() != agent.StatusRunning || !codeChanged(a.Config, configDir, res.Files) {
+			continue
+		}
+		if err := s.manager.RestartAgent(a.Config.Name); err != nil {
+			log.Printf("[GitSync] Failed to restart %s: %v", a.Config.Name, err)
+			continue
+		}
+		res.Restarted = append(res.Restarted, a.Config.Name)
+	}
+	if len(res.Restarted) > 0 {
+		log.Printf("[GitSync] Restarted %s", strings.Join(res.Restarted, ", "))
+	}
+	return res, nil
+}
+
+// codeChanged reports whether any of files, relative to configDir, lies in
+// the agent's process root. The config files themselves are left to the
+// reload.
+func codeChanged(cfg agent.AgentConfig, configDir string, files []string) bool {
+	root, err := cfg.WorkingDir()
+	if err != nil {
+		return false
+	}
+	root = filepath.Clean(root)
+	for _, f := range files {
+		if f == "agents.yaml" || f == "daemon.yaml" {
+			continue
+		}
+		path := filepath.Join(configDir, filepath.FromSlash(f))
+		if path == root || strings.HasPrefix(path, root+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+func shortCommit(commit string) string {
+	if len(commit) > 7 {
+		return commit[:7]
+	}
+	return commit
+}
+
+// startGitWebhook pulls the config directory when a push webhook is POSTed
+// to /webhooks/git on cfg.Webhook.Listen.
+func (s *Server) startGitWebhook(cfg config.GitSyncConfig) {
+	if cfg.Webhook.Listen == "" {
+		return
+	}
+	listener, err := net.Listen("tcp", cfg.Webhook.Listen)
+	if err != nil {
+		log.Printf("[GitSync] webhook disabled: listen on %s: %v", cfg.Webhook.Listen, err)
+		return
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /webhooks/git", s.handleGitWebhook)
+	s.gitWebhook = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	log.Printf("[GitSync] Webhook listening on http://%s/webhooks/git", listener.Addr())
+	go func() {
+		if err := s.gitWebhook.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("[GitSync] webhook server stopped: %v", err)
+		}
+	}()
+}
+
+func (s *Server) stopGitWebhook() {
+	if s.gitWebhook == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_ = s.gitWebhook.Shutdown(ctx)
+	s.gitWebhook = nil
+}
+
+// handleGitWebhook accepts the secret as a GitHub X-Hub-Signature-256 HMAC
+// of the body or as a bearer token, answers at once and pulls in the
+// background so the sender does not time out.
+func (s *Server) handleGitWebhook(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, 5<<20))
+	if err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	if !gitWebhookAuthorized(r, body, s.gitSync.Webhook.Secret) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if r.Header.Get("X-GitHub-Event") == "ping" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+	go func() {
+		if _, err := s.syncFromGit(); err != nil {
+			log.Printf("[GitSync] Webhook pull failed: %v", err)
+		}
+	}()
+}
+
+func gitWebhookAuthorized(r *http.Request, body []byte, secret string) bool {
+	if sig, ok := strings.CutPrefix(r.Header.Get("X-Hub-Signature-256"), "sha256="); ok {
+		got, err := hex.DecodeString(sig)
+		if err != nil {
+			return false
+		}
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		return hmac.Equal(got, mac.Sum(nil))
+	}
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return subtle.ConstantTimeCompare([]byte(token), []byte(secret)) == 1
+	}
+	return false
+}
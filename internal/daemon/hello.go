@@ -0,0 +1,26 @@
+package daemon
+
+import (
+	"log"
+
+	"opperator/internal/ipc"
+	"opperator/version"
+)
+
+// hello answers the version handshake clients send on connect. Clients judge
+// compatibility themselves; the daemon only logs skew for diagnosis.
+func (s *Server) hello(req ipc.Request) ipc.Response {
+	switch version.Compare(req.ClientVersion, req.ClientProtocol, 0) {
+	case version.Skewed:
+		log.Printf("[Version] Client %s (protocol %d) connected to daemon %s (protocol %d)",
+			req.ClientVersion, req.ClientProtocol, version.Get(), version.Protocol)
+	case version.PeerTooOld:
+		log.Printf("[Version] Client %s speaks protocol %d, below the minimum %d",
+			req.ClientVersion, req.ClientProtocol, version.MinProtocol)
+	}
+	return ipc.Response{Success: true, Daemon: &ipc.DaemonInfo{
+		Version:     version.Get(),
+		Protocol:    version.Protocol,
+		MinProtocol: version.MinProtocol,
+	}}
+}
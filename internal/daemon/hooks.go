@@ -0,0 +1,47 @@
+package daemon
+
+import (
+	"opperator/config"
+	"opperator/internal/agent"
+	"opperator/internal/hooks"
+	"opperator/internal/taskqueue"
+)
+
+// runCrashHooks runs the agent_crashed hooks when update reports a crash.
+func (s *Server) runCrashHooks(agentName string, update agent.StatusUpdate) {
+	if update.Status != agent.StatusCrashed && update.Status != agent.StatusCrashLooping {
+		return
+	}
+	data := map[string]any{
+		"status":     string(update.Status),
+		"crash_loop": update.Status == agent.StatusCrashLooping,
+	}
+	if update.LastExit != nil {
+		data["exit_code"] = update.LastExit.Code
+		if update.LastExit.Signal != "" {
+			data["signal"] = update.LastExit.Signal
+		}
+	}
+	s.hooks.Run(hooks.Event{Event: config.HookAgentCrashed, Agent: agentName, Data: data})
+}
+
+func taskFailedHookEvent(task *taskqueue.Task, errMsg string) hooks.Event {
+	if errMsg == "" {
+		errMsg = task.Error
+	}
+	data := map[string]any{
+		"task":   task.ID,
+		"origin": task.Origin,
+		"error":  errMsg,
+	}
+	if task.CommandName != "" {
+		data["command"] = task.CommandName
+	}
+	if task.ToolName != "" {
+		data["tool"] = task.ToolName
+	}
+	if task.SessionID != "" {
+		data["session"] = task.SessionID
+	}
+	return hooks.Event{Event: config.HookTaskFailed, Agent: task.AgentName, Data: data}
+}
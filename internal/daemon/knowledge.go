@@ -0,0 +1,19 @@
+package daemon
+
+import (
+	"context"
+	"strings"
+
+	"opperator/internal/ipc"
+)
+
+func (s *Server) searchKnowledge(ctx context.Context, req ipc.Request) ipc.Response {
+	if strings.TrimSpace(req.KnowledgeQuery) == "" {
+		return ipc.NewErrorResponse(ipc.ErrCodeValidation, "query is required")
+	}
+	results, err := s.knowledge.Search(ctx, req.KnowledgeQuery, req.KnowledgeLimit)
+	if err != nil {
+		return ipc.ErrorResponse(err)
+	}
+	return ipc.Response{Success: true, Knowledge: results}
+}
@@ -0,0 +1,109 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"opperator/internal/ipc"
+	"opperator/internal/kv"
+	"opperator/internal/protocol"
+)
+
+// kvTimeout bounds a key-value request of an agent.
+const kvTimeout = 10 * time.Second
+
+// handleKV serves the key-value requests of 'op agent kv' and
+// 'op blackboard'. Agents use kv_request protocol messages instead, which
+// handleAgentKV answers.
+func (s *Server) handleKV(ctx context.Context, req ipc.Request) ipc.Response {
+	op := kvOp(req.Type)
+	var (
+		entries []kv.Entry
+		err     error
+	)
+	if namespace := strings.TrimSpace(req.KVNamespace); namespace != "" {
+		if strings.TrimSpace(req.AgentName) != "" {
+			return ipc.NewErrorResponse(ipc.ErrCodeValidation,
+				"blackboard requests over IPC are for operators; agents use kv_request protocol messages")
+		}
+		entries, err = s.blackboardOp(ctx, op, namespace, "", req.KVKey, req.KVValue, req.KVPrefix)
+	} else {
+		agentName := strings.TrimSpace(req.AgentName)
+		if agentName == "" {
+			return ipc.NewErrorResponse(ipc.ErrCodeValidation, "agent name is required")
+		}
+		entries, err = s.agentKVOp(ctx, op, agentName, req.KVKey, req.KVValue, req.KVPrefix)
+	}
+	if err != nil {
+		return ipc.ErrorResponse(err)
+	}
+	return ipc.Response{Success: true, KV: entries}
+}
+
+// handleAgentKV answers a kv_request message of agentName, which the
+// manager takes from the pipe the message arrived on rather than from the
+// message, so blackboard access lists cannot be bypassed.
+func (s *Server) handleAgentKV(agentName string, req protocol.KVRequestMessage) protocol.KVResponseMessage {
+	ctx, cancel := context.WithTimeout(context.Background(), kvTimeout)
+	defer cancel()
+
+	var (
+		entries []kv.Entry
+		err     error
+	)
+	if namespace := strings.TrimSpace(req.Namespace); namespace != "" {
+		err = s.checkBlackboard(namespace, agentName, req.Op == protocol.KVSet || req.Op == protocol.KVDelete)
+		if err == nil {
+			entries, err = s.blackboardOp(ctx, req.Op, namespace, agentName, req.Key, req.Value, req.Prefix)
+		}
+	} else {
+		entries, err = s.agentKVOp(ctx, req.Op, agentName, req.Key, req.Value, req.Prefix)
+	}
+	if err != nil {
+		return protocol.KVResponseMessage{ID: req.ID, Error: err.Error(), ErrorCode: string(ipc.CodeOf(err))}
+	}
+	resp := protocol.KVResponseMessage{ID: req.ID, Success: true}
+	for _, e := range entries {
+		resp.Entries = append(resp.Entries, protocol.KVEntry{Key: e.Key, Value: e.Value, Writer: e.Writer, UpdatedAt: e.UpdatedAt})
+	}
+	return resp
+}
+
+// agentKVOp runs op on the key-value state of agentName.
+func (s *Server) agentKVOp(ctx context.Context, op, agentName, key string, value json.RawMessage, prefix string) ([]kv.Entry, error) {
+	switch op {
+	case protocol.KVGet:
+		entry, err := s.kv.Get(ctx, agentName, key)
+		if err != nil {
+			return nil, err
+		}
+		return []kv.Entry{entry}, nil
+	case protocol.KVSet:
+		entry, err := s.kv.Set(ctx, agentName, key, value)
+		if err != nil {
+			return nil, err
+		}
+		return []kv.Entry{entry}, nil
+	case protocol.KVDelete:
+		return nil, s.kv.Delete(ctx, agentName, key)
+	case protocol.KVList:
+		return s.kv.List(ctx, agentName, prefix)
+	}
+	return nil, ipc.NewError(ipc.ErrCodeValidation, fmt.Sprintf("unknown key-value operation %q", op))
+}
+
+// kvOp maps a key-value IPC request to its protocol operation.
+func kvOp(t ipc.RequestType) string {
+	switch t {
+	case ipc.RequestKVGet:
+		return protocol.KVGet
+	case ipc.RequestKVSet:
+		return protocol.KVSet
+	case ipc.RequestKVDelete:
+		return protocol.KVDelete
+	}
+	return protocol.KVList
+}
@@ -0,0 +1,100 @@
+package daemon
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	_ "modernc.org/sqlite"
+
+	"opperator/internal/ipc"
+	"opperator/internal/kv"
+	"opperator/internal/protocol"
+	"opperator/pkg/migration"
+)
+
+// newKVTestServer returns a server with just a key-value store, backed by a
+// migrated database in a temporary directory.
+func newKVTestServer(t *testing.T) *Server {
+	t.Helper()
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := migration.NewRunner(db).Run(); err != nil {
+		t.Fatalf("Failed to run migrations: %v", err)
+	}
+	return &Server{kv: kv.NewStore(db)}
+}
+
+func TestHandleAgentKV_OwnStateOnly(t *testing.T) {
+	s := newKVTestServer(t)
+	set := s.handleAgentKV("crawler", protocol.KVRequestMessage{ID: "1", Op: protocol.KVSet, Key: "cursor", Value: json.RawMessage(`42`)})
+	if !set.Success {
+		t.Fatalf("Expected set to succeed, got %s", set.Error)
+	}
+
+	tests := []struct {
+		name    string
+		agent   string
+		req     protocol.KVRequestMessage
+		success bool
+		code    string
+		entries int
+	}{
+		{name: "owner reads", agent: "crawler", req: protocol.KVRequestMessage{Op: protocol.KVGet, Key: "cursor"}, success: true, entries: 1},
+		{name: "owner lists", agent: "crawler", req: protocol.KVRequestMessage{Op: protocol.KVList}, success: true, entries: 1},
+		{name: "other agent reads", agent: "indexer", req: protocol.KVRequestMessage{Op: protocol.KVGet, Key: "cursor"}, code: string(ipc.ErrCodeNotFound)},
+		{name: "other agent lists", agent: "indexer", req: protocol.KVRequestMessage{Op: protocol.KVList}, success: true, entries: 0},
+		{name: "other agent deletes", agent: "indexer", req: protocol.KVRequestMessage{Op: protocol.KVDelete, Key: "cursor"}, code: string(ipc.ErrCodeNotFound)},
+		{name: "unknown operation", agent: "crawler", req: protocol.KVRequestMessage{Op: "drop", Key: "cursor"}, code: string(ipc.ErrCodeValidation)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.req.ID = "req"
+			resp := s.handleAgentKV(tt.agent, tt.req)
+			if resp.ID != "req" {
+				t.Errorf("Expected the request ID back, got %q", resp.ID)
+			}
+			if resp.Success != tt.success {
+				t.Fatalf("Expected success=%v, got %+v", tt.success, resp)
+			}
+			if !tt.success && resp.ErrorCode != tt.code {
+				t.Errorf("Expected error code %q, got %q (%s)", tt.code, resp.ErrorCode, resp.Error)
+			}
+			if tt.success && len(resp.Entries) != tt.entries {
+				t.Errorf("Expected %d entries, got %+v", tt.entries, resp.Entries)
+			}
+		})
+	}
+
+	// The owner's value survived the other agent's delete
+	if resp := s.handleAgentKV("crawler", protocol.KVRequestMessage{Op: protocol.KVGet, Key: "cursor"}); !resp.Success || string(resp.Entries[0].Value) != "42" {
+		t.Errorf("Expected crawler's value to be kept, got %+v", resp)
+	}
+}
+
+func TestHandleKV_RequiresAgent(t *testing.T) {
+	s := newKVTestServer(t)
+	tests := []struct {
+		name string
+		req  ipc.Request
+		code ipc.ErrorCode
+	}{
+		{name: "no agent", req: ipc.Request{Type: ipc.RequestKVGet, KVKey: "cursor"}, code: ipc.ErrCodeValidation},
+		{name: "blank agent", req: ipc.Request{Type: ipc.RequestKVSet, AgentName: "  ", KVKey: "cursor", KVValue: json.RawMessage(`1`)}, code: ipc.ErrCodeValidation},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := s.handleKV(context.Background(), tt.req)
+			if resp.Success || resp.ErrorCode != tt.code {
+				t.Errorf("Expected error code %q, got %+v", tt.code, resp)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package daemon
+
+import (
+	"context"
+	"io"
+	"log"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode"
+
+	"opperator/config"
+	"opperator/internal/agent"
+	"opperator/internal/ipc"
+)
+
+// configureLogging points the standard logger and slog at w using the
+// configured format. In JSON mode existing log.Printf call sites are routed
+// through a bridge that lifts the "[Component]" prefix and level markers into
+// structured fields.
+func configureLogging(w io.Writer, cfg config.LoggingConfig) {
+	level := parseLogLevel(cfg.Level)
+	agent.SetLogForwarding(cfg.ForwardAgentLogs())
+
+	if cfg.Format != config.LogFormatJSON {
+		log.SetOutput(w)
+		log.SetFlags(log.LstdFlags | log.Lmicroseconds | log.Lshortfile)
+		slog.SetLogLoggerLevel(level)
+		return
+	}
+
+	handler := slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level})
+	slog.SetDefault(slog.New(handler))
+	// SetDefault redirects the log package into the handler; replace that
+	// with the bridge so plain log lines get component and level fields.
+	log.SetFlags(0)
+	log.SetOutput(&logBridge{handler: handler})
+}
+
+func parseLogLevel(s string) slog.Level {
+	switch s {
+	case "debug":
+		return slog.LevelDebug
+	case "warn":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	}
+	return slog.LevelInfo
+}
+
+// logBridge turns lines written by the log package into slog records.
+type logBridge struct {
+	handler slog.Handler
+}
+
+func (b *logBridge) Write(p []byte) (int, error) {
+	line := strings.TrimRight(string(p), "\r\n")
+	attrs := make([]slog.Attr, 0, 2)
+
+	component, conn, msg := splitComponent(line)
+	level, msg := splitLevel(msg)
+
+	ctx := context.Background()
+	if !b.handler.Enabled(ctx, level) {
+		return len(p), nil
+	}
+	if component != "" {
+		attrs = append(attrs, slog.String("component", component))
+	}
+	if conn != "" {
+		attrs = append(attrs, slog.String("conn", conn))
+	}
+
+	rec := slog.NewRecord(time.Now(), level, msg, 0)
+	rec.AddAttrs(attrs...)
+	if err := b.handler.Handle(ctx, rec); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// splitComponent extracts the component from "[Component] msg" or
+// "component: msg" prefixes. Connection IDs used as prefixes by the IPC
+// handlers are reported as the ipc component with a conn field.
+func splitComponent(line string) (component, conn, msg string) {
+	if strings.HasPrefix(line, "[") {
+		if end := strings.Index(line, "]"); end > 0 {
+			tag := line[1:end]
+			msg = strings.TrimSpace(line[end+1:])
+			switch {
+			case strings.HasPrefix(tag, "Connection "):
+				return "ipc", strings.TrimPrefix(tag, "Connection "), msg
+			case strings.HasPrefix(tag, "0x"):
+				return "ipc", tag, msg
+			}
+			return tag, "", msg
+		}
+	}
+
+	if head, rest, ok := strings.Cut(line, ":"); ok && isComponentName(head) {
+		return strings.ToLower(head), "", strings.TrimSpace(rest)
+	}
+	return "", "", line
+}
+
+func isComponentName(s string) bool {
+	if s == "" || len(s) > 20 || isLevelMarker(s) {
+		return false
+	}
+	for _, r := range s {
+		if !unicode.IsLetter(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func isLevelMarker(s string) bool {
+	switch strings.ToUpper(s) {
+	case "ERROR", "WARNING", "WARN":
+		return true
+	}
+	return false
+}
+
+// splitLevel infers a level from "ERROR:"/"Warning:" markers and messages
+// that start by reporting a failure.
+func splitLevel(msg string) (slog.Level, string) {
+	if head, rest, ok := strings.Cut(msg, ":"); ok && isLevelMarker(head) {
+		rest = strings.TrimSpace(rest)
+		if strings.EqualFold(head, "ERROR") {
+			return slog.LevelError, rest
+		}
+		return slog.LevelWarn, rest
+	}
+	lower := strings.ToLower(msg)
+	if strings.HasPrefix(lower, "failed") || strings.HasPrefix(lower, "error") {
+		return slog.LevelError, msg
+	}
+	return slog.LevelInfo, msg
+}
+
+func logRequest(connID string, n int, req ipc.Request) {
+	slog.Info("Request received",
+		"component", "ipc",
+		"conn", connID,
+		"request", n,
+		"type", req.Type,
+		"agent", req.AgentName,
+	)
+}
+
+func logRequestDone(connID string, n int, req ipc.Request, resp ipc.Response) {
+	level := slog.LevelInfo
+	args := []any{
+		"component", "ipc",
+		"conn", connID,
+		"request", n,
+		"type", req.Type,
+		"agent", req.AgentName,
+		"success", resp.Success,
+	}
+	if !resp.Success {
+		level = slog.LevelWarn
+		args = append(args, "error_code", resp.ErrorCode, "error", resp.Error)
+	}
+	slog.Log(context.Background(), level, "Request completed", args...)
+}
@@ -0,0 +1,68 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"opperator/internal/credentials"
+	"opperator/internal/ipc"
+	"opperator/internal/memory"
+
+	"tui/opper"
+)
+
+// embedTexts embeds memories and knowledge base queries with the Opper API,
+// or with the server in llm.yaml. The API key is read per call so one stored
+// after startup is picked up.
+func embedTexts(ctx context.Context, texts []string) ([][]float32, string, error) {
+	apiKey, err := credentials.GetSecret(credentials.OpperAPIKeyName)
+	if err != nil && opper.Local() == nil {
+		return nil, "", fmt.Errorf("failed to read Opper API key: %w (run: op secret create %s)", err, credentials.OpperAPIKeyName)
+	}
+	return opper.New(apiKey).Embed(ctx, texts)
+}
+
+func (s *Server) remember(ctx context.Context, req ipc.Request) ipc.Response {
+	if strings.TrimSpace(req.AgentName) == "" || strings.TrimSpace(req.MemoryText) == "" {
+		return ipc.NewErrorResponse(ipc.ErrCodeValidation, "agent and memory text are required")
+	}
+	conversationID := ""
+	switch req.MemoryScope {
+	case "", memory.ScopeAgent:
+	case memory.ScopeConversation:
+		if req.SessionID == "" {
+			return ipc.NewErrorResponse(ipc.ErrCodeValidation, "conversation scope needs a conversation")
+		}
+		conversationID = req.SessionID
+	default:
+		return ipc.NewErrorResponse(ipc.ErrCodeValidation, fmt.Sprintf("unknown memory scope %q", req.MemoryScope))
+	}
+	m, err := s.memory.Remember(ctx, req.AgentName, conversationID, req.MemoryText)
+	if err != nil {
+		return ipc.ErrorResponse(err)
+	}
+	return ipc.Response{Success: true, Memories: []memory.Memory{m}}
+}
+
+func (s *Server) recall(ctx context.Context, req ipc.Request) ipc.Response {
+	if strings.TrimSpace(req.AgentName) == "" || strings.TrimSpace(req.MemoryText) == "" {
+		return ipc.NewErrorResponse(ipc.ErrCodeValidation, "agent and query are required")
+	}
+	conversationOnly := false
+	switch req.MemoryScope {
+	case "", memory.ScopeAgent:
+	case memory.ScopeConversation:
+		if req.SessionID == "" {
+			return ipc.NewErrorResponse(ipc.ErrCodeValidation, "conversation scope needs a conversation")
+		}
+		conversationOnly = true
+	default:
+		return ipc.NewErrorResponse(ipc.ErrCodeValidation, fmt.Sprintf("unknown memory scope %q", req.MemoryScope))
+	}
+	memories, err := s.memory.Recall(ctx, req.AgentName, req.SessionID, req.MemoryText, conversationOnly, req.MemoryLimit)
+	if err != nil {
+		return ipc.ErrorResponse(err)
+	}
+	return ipc.Response{Success: true, Memories: memories}
+}
@@ -0,0 +1,115 @@
+package daemon
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"opperator/config"
+	"opperator/internal/agent"
+	"opperator/internal/hooks"
+	"opperator/internal/notify"
+	"opperator/internal/taskqueue"
+	"opperator/version"
+)
+
+// crashReportLines caps how much stderr is included in crash notifications.
+const crashReportLines = 10
+
+func (s *Server) notifyCrashLoop(agentName string, update agent.StatusUpdate) {
+	ev := notify.Event{
+		Type:    notify.EventAgentCrashLoop,
+		Title:   fmt.Sprintf("Agent %s is crash-looping", agentName),
+		Message: "The agent crashed repeatedly and will not be restarted automatically. Run 'op agent start' once it is fixed.",
+		Agent:   agentName,
+		Fields:  map[string]string{},
+	}
+	if update.LastExit != nil {
+		ev.Fields["Last exit"] = update.LastExit.String()
+	}
+	if a, err := s.manager.GetAgent(agentName); err == nil {
+		if report, err := a.LastCrash(); err == nil && report != nil && len(report.StderrTail) > 0 {
+			tail := report.StderrTail
+			if len(tail) > crashReportLines {
+				tail = tail[len(tail)-crashReportLines:]
+			}
+			ev.Fields["Stderr"] = "\n" + strings.Join(tail, "\n")
+		}
+	}
+	s.notifier.Notify(ev)
+}
+
+func taskFailedEvent(task *taskqueue.Task, errMsg string) notify.Event {
+	if errMsg == "" {
+		errMsg = task.Error
+	}
+	name := task.ToolName
+	if task.AgentName != "" && task.CommandName != "" {
+		name = task.AgentName + "." + task.CommandName
+	}
+	ev := notify.Event{
+		Type:    notify.EventTaskFailed,
+		Title:   fmt.Sprintf("Async task %s failed", name),
+		Message: errMsg,
+		Agent:   task.AgentName,
+		Fields: map[string]string{
+			"Task":   task.ID,
+			"Origin": task.Origin,
+		},
+	}
+	if task.SessionID != "" {
+		ev.Fields["Session"] = task.SessionID
+	}
+	return ev
+}
+
+// notifyVersionChange reports a daemon update when the binary version differs
+// from the one recorded on the previous start.
+func (s *Server) notifyVersionChange() {
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return
+	}
+	path := filepath.Join(configDir, "daemon.version")
+	current := version.Get()
+
+	data, err := os.ReadFile(path)
+	previous := strings.TrimSpace(string(data))
+	if err := os.WriteFile(path, []byte(current+"\n"), 0644); err != nil {
+		log.Printf("[Notify] Failed to record daemon version: %v", err)
+	}
+	if err != nil || previous == "" || previous == current {
+		return
+	}
+
+	s.hooks.Run(hooks.Event{
+		Event: config.HookUpdateInstalled,
+		Data:  map[string]any{"previous_version": previous, "version": current},
+	})
+	s.notifier.Notify(notify.Event{
+		Type:    notify.EventDaemonUpdated,
+		Title:   fmt.Sprintf("Daemon updated to %s", current),
+		Message: fmt.Sprintf("The daemon restarted on version %s (previously %s).", current, previous),
+		Fields: map[string]string{
+			"Previous version": previous,
+			"Version":          current,
+		},
+	})
+}
+
+func (s *Server) notifyUpdateFailed(target, errMsg string) {
+	if target == "" {
+		target = "local"
+	}
+	s.notifier.Notify(notify.Event{
+		Type:    notify.EventDaemonUpdateFailed,
+		Title:   fmt.Sprintf("Update of daemon %s failed", target),
+		Message: errMsg,
+		Fields: map[string]string{
+			"Target":  target,
+			"Version": version.Get(),
+		},
+	})
+}
@@ -0,0 +1,60 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+	"time"
+
+	"opperator/config"
+	"opperator/internal/policy"
+	"opperator/internal/taskqueue"
+)
+
+// taskPolicyInput describes an execution for the policy engine, taking its
+// origin, user and trigger from the queued task ctx runs in, if any.
+func taskPolicyInput(ctx context.Context, kind, agentName, name string, args map[string]any) policy.Input {
+	in := policy.Input{Kind: kind, Agent: agentName, Command: name, Args: args, Time: time.Now()}
+	task, ok := taskqueue.TaskFromContext(ctx)
+	if !ok {
+		return in
+	}
+	in.Origin = task.Origin
+	in.User = task.Owner
+	if task.Origin == triggerOrigin {
+		in.Trigger = strings.TrimPrefix(task.ClientID, "trigger:")
+	}
+	if kind != config.PolicyKindWorkflow && strings.EqualFold(task.Mode, "workflow") {
+		in.Workflow = task.CommandName
+	}
+	return in
+}
+
+// checkPolicy authorizes an execution, logging denials.
+func checkPolicy(ctx context.Context, engine *policy.Engine, in policy.Input) error {
+	err := engine.Check(ctx, in)
+	if err != nil {
+		target := in.Command
+		if in.Agent != "" {
+			target = in.Agent + "/" + in.Command
+		}
+		log.Printf("[Policy] %s %s (origin: %s, user: %s): %v", in.Kind, target, orUnknown(in.Origin), orUnknown(in.User), err)
+	}
+	return err
+}
+
+// decodePolicyArgs decodes JSON tool arguments for rule matching; arguments
+// that are not an object match no argument conditions.
+func decodePolicyArgs(args string) map[string]any {
+	var parsed map[string]any
+	_ = json.Unmarshal([]byte(args), &parsed)
+	return parsed
+}
+
+func orUnknown(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
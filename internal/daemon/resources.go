@@ -0,0 +1,172 @@
+package daemon
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"opperator/internal/agent"
+	"opperator/internal/ipc"
+)
+
+// resourceSectionInterval is how often running agents' resource sidebar
+// sections are refreshed.
+const resourceSectionInterval = 5 * time.Second
+
+// processSample is the raw per-process data a platform sampler reports.
+type processSample struct {
+	cpuTime  time.Duration
+	rssBytes uint64
+	openFDs  int
+	threads  int
+}
+
+type cpuReading struct {
+	cpuTime time.Duration
+	at      time.Time
+}
+
+// resourceSampler turns raw process samples into usage figures. CPU usage is
+// the share of one core used since the previous sample of the same PID.
+type resourceSampler struct {
+	mu   sync.Mutex
+	prev map[int]cpuReading
+}
+
+func newResourceSampler() *resourceSampler {
+	return &resourceSampler{prev: make(map[int]cpuReading)}
+}
+
+// sample reports usage for pid, aggregating CPU and memory across its
+// descendants so helper processes spawned by an agent are accounted for.
+func (r *resourceSampler) sample(pid int) (ipc.ResourceUsage, error) {
+	usage := ipc.ResourceUsage{PID: pid}
+	if pid <= 0 {
+		return usage, fmt.Errorf("invalid process id: %d", pid)
+	}
+
+	root, err := readProcessSample(pid)
+	if err != nil {
+		return usage, err
+	}
+	usage.RSSBytes = root.rssBytes
+	usage.OpenFDs = root.openFDs
+	usage.Threads = root.threads
+
+	cpuTime := root.cpuTime
+	descendants, err := listDescendants(pid)
+	if err != nil {
+		usage.Children = -1
+	} else {
+		usage.Children = len(descendants)
+		for _, child := range descendants {
+			if s, err := readProcessSample(child); err == nil {
+				cpuTime += s.cpuTime
+				usage.RSSBytes += s.rssBytes
+			}
+		}
+	}
+
+	now := time.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if prev, ok := r.prev[pid]; ok && now.After(prev.at) && cpuTime >= prev.cpuTime {
+		usage.CPUPercent = float64(cpuTime-prev.cpuTime) / float64(now.Sub(prev.at)) * 100
+	}
+	r.prev[pid] = cpuReading{cpuTime: cpuTime, at: now}
+	for p, reading := range r.prev {
+		if now.Sub(reading.at) > time.Minute {
+			delete(r.prev, p)
+		}
+	}
+	return usage, nil
+}
+
+// resourceReport samples the daemon and every agent it manages.
+func (s *Server) resourceReport() ipc.Response {
+	report := &ipc.ResourceReport{SampledAt: time.Now()}
+
+	daemonUsage, err := s.resources.sample(os.Getpid())
+	if err != nil {
+		return ipc.NewErrorResponse(ipc.ErrCodeInternal, fmt.Sprintf("failed to sample daemon: %v", err))
+	}
+	report.Daemon = daemonUsage
+
+	for _, a := range s.manager.GetAllAgents() {
+		entry := ipc.AgentResourceUsage{Name: a.Config.Name, Status: a.GetStatus()}
+		if pid := a.GetPID(); entry.Status == agent.StatusRunning && pid > 0 {
+			usage, err := s.resources.sample(pid)
+			if err != nil {
+				entry.Error = err.Error()
+			} else {
+				entry.Usage = &usage
+			}
+		}
+		report.Agents = append(report.Agents, entry)
+	}
+
+	return ipc.Response{Success: true, Resources: report}
+}
+
+// monitorResources keeps a daemon-managed sidebar section with live resource
+// usage up to date for every running agent.
+func (s *Server) monitorResources(stop <-chan struct{}) {
+	ticker := time.NewTicker(resourceSectionInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		for _, a := range s.manager.GetAllAgents() {
+			pid := a.GetPID()
+			if a.GetStatus() != agent.StatusRunning || pid <= 0 {
+				a.ClearResourceSection()
+				continue
+			}
+			usage, err := s.resources.sample(pid)
+			if err != nil {
+				log.Printf("[Resources] Failed to sample agent %s: %v", a.Config.Name, err)
+				continue
+			}
+			a.SetResourceSection(formatResourceUsage(usage))
+		}
+	}
+}
+
+func formatResourceUsage(u ipc.ResourceUsage) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "CPU: %.1f%%\n", u.CPUPercent)
+	fmt.Fprintf(&b, "Memory: %s\n", formatBytes(u.RSSBytes))
+	fmt.Fprintf(&b, "Open files: %s\n", formatCount(u.OpenFDs))
+	fmt.Fprintf(&b, "Threads: %s\n", formatCount(u.Threads))
+	fmt.Fprintf(&b, "Child processes: %s", formatCount(u.Children))
+	return b.String()
+}
+
+func formatCount(n int) string {
+	if n < 0 {
+		return "n/a"
+	}
+	return fmt.Sprintf("%d", n)
+}
+
+// formatBytes renders a byte count using binary units.
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
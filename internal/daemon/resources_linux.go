@@ -0,0 +1,94 @@
+//go:build linux
+
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clockTicks is USER_HZ, the unit of CPU times in /proc. It is 100 on every
+// Linux architecture Go supports.
+const clockTicks = 100
+
+// readProcessSample reads CPU time, RSS, thread and descriptor counts from /proc.
+func readProcessSample(pid int) (processSample, error) {
+	fields, err := readStatFields(pid)
+	if err != nil {
+		return processSample{}, err
+	}
+	// Fields after the command name start at stat field 3 (state)
+	if len(fields) < 22 {
+		return processSample{}, fmt.Errorf("unexpected /proc/%d/stat format", pid)
+	}
+	utime, _ := strconv.ParseUint(fields[11], 10, 64)
+	stime, _ := strconv.ParseUint(fields[12], 10, 64)
+	threads, _ := strconv.Atoi(fields[17])
+	rssPages, _ := strconv.ParseUint(fields[21], 10, 64)
+
+	sample := processSample{
+		cpuTime:  time.Duration(utime+stime) * time.Second / clockTicks,
+		rssBytes: rssPages * uint64(os.Getpagesize()),
+		threads:  threads,
+		openFDs:  -1,
+	}
+	if entries, err := os.ReadDir(filepath.Join("/proc", strconv.Itoa(pid), "fd")); err == nil {
+		sample.openFDs = len(entries)
+	}
+	return sample, nil
+}
+
+// listDescendants returns the PIDs of all processes below pid.
+func listDescendants(pid int) ([]int, error) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+	children := make(map[int][]int)
+	for _, entry := range entries {
+		child, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		fields, err := readStatFields(child)
+		if err != nil || len(fields) < 2 {
+			continue
+		}
+		parent, err := strconv.Atoi(fields[1])
+		if err != nil {
+			continue
+		}
+		children[parent] = append(children[parent], child)
+	}
+
+	var out []int
+	queue := []int{pid}
+	for len(queue) > 0 {
+		next := queue[0]
+		queue = queue[1:]
+		for _, child := range children[next] {
+			out = append(out, child)
+			queue = append(queue, child)
+		}
+	}
+	return out, nil
+}
+
+// readStatFields returns the /proc/<pid>/stat fields following the command
+// name, which may itself contain spaces and parentheses.
+func readStatFields(pid int) ([]string, error) {
+	data, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return nil, err
+	}
+	stat := string(data)
+	end := strings.LastIndexByte(stat, ')')
+	if end < 0 {
+		return nil, fmt.Errorf("unexpected /proc/%d/stat format", pid)
+	}
+	return strings.Fields(stat[end+1:]), nil
+}
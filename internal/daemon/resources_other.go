@@ -0,0 +1,80 @@
+//go:build !linux
+
+package daemon
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// readProcessSample asks ps for CPU time and RSS. Thread and descriptor counts
+// are not available without elevated tools and are reported as unknown.
+func readProcessSample(pid int) (processSample, error) {
+	out, err := exec.Command("ps", "-o", "rss=,time=", "-p", strconv.Itoa(pid)).Output()
+	if err != nil {
+		return processSample{}, fmt.Errorf("ps failed for pid %d: %w", pid, err)
+	}
+	fields := strings.Fields(string(out))
+	if len(fields) < 2 {
+		return processSample{}, fmt.Errorf("process %d not found", pid)
+	}
+	rssKB, _ := strconv.ParseUint(fields[0], 10, 64)
+	return processSample{
+		cpuTime:  parsePSTime(fields[1]),
+		rssBytes: rssKB * 1024,
+		openFDs:  -1,
+		threads:  -1,
+	}, nil
+}
+
+// listDescendants returns the PIDs of all processes below pid.
+func listDescendants(pid int) ([]int, error) {
+	out, err := exec.Command("ps", "-A", "-o", "pid=,ppid=").Output()
+	if err != nil {
+		return nil, err
+	}
+	children := make(map[int][]int)
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		child, err1 := strconv.Atoi(fields[0])
+		parent, err2 := strconv.Atoi(fields[1])
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		children[parent] = append(children[parent], child)
+	}
+
+	var descendants []int
+	queue := []int{pid}
+	for len(queue) > 0 {
+		next := queue[0]
+		queue = queue[1:]
+		for _, child := range children[next] {
+			descendants = append(descendants, child)
+			queue = append(queue, child)
+		}
+	}
+	return descendants, nil
+}
+
+// parsePSTime parses ps cumulative CPU time in [[dd-]hh:]mm:ss[.ss] form.
+func parsePSTime(value string) time.Duration {
+	var days int
+	if idx := strings.IndexByte(value, '-'); idx >= 0 {
+		days, _ = strconv.Atoi(value[:idx])
+		value = value[idx+1:]
+	}
+	parts := strings.Split(value, ":")
+	var total float64
+	for _, part := range parts {
+		n, _ := strconv.ParseFloat(part, 64)
+		total = total*60 + n
+	}
+	return time.Duration(days)*24*time.Hour + time.Duration(total*float64(time.Second))
+}
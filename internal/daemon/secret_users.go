@@ -0,0 +1,73 @@
+package daemon
+
+import (
+	"log"
+	"sort"
+	"strings"
+	"sync"
+
+	"opperator/internal/agent"
+)
+
+// lifecycleSecretRotated tells an agent that a secret it uses has a new
+// value, so it can fetch it again and refresh its connections.
+const lifecycleSecretRotated = "secret_rotated"
+
+// secretUsers remembers which agents fetched which secrets since the daemon
+// started. Agents fetch their secrets when they start, so the running ones
+// are all known.
+type secretUsers struct {
+	mu    sync.Mutex
+	users map[string]map[string]bool
+}
+
+func newSecretUsers() *secretUsers {
+	return &secretUsers{users: make(map[string]map[string]bool)}
+}
+
+// record notes that agentName fetched the secret name.
+func (u *secretUsers) record(name, agentName string) {
+	agentName = strings.TrimSpace(agentName)
+	if agentName == "" {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.users[name] == nil {
+		u.users[name] = make(map[string]bool)
+	}
+	u.users[name][agentName] = true
+}
+
+func (u *secretUsers) of(name string) map[string]bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	users := make(map[string]bool, len(u.users[name]))
+	for agentName := range u.users[name] {
+		users[agentName] = true
+	}
+	return users
+}
+
+// notifySecretRotated sends a secret_rotated lifecycle event to the running
+// agents that use the secret name: the ones that fetched it and the ones
+// given it through secrets or opper_api_key. It returns the agents notified.
+func (s *Server) notifySecretRotated(name string) []string {
+	users := s.secretUsers.of(name)
+	var notified []string
+	for _, ag := range s.manager.GetAllAgents() {
+		if ag.GetStatus() != agent.StatusRunning {
+			continue
+		}
+		if !users[ag.Config.Name] && !ag.Config.UsesSecret(name) {
+			continue
+		}
+		if err := ag.SendLifecycleEvent(lifecycleSecretRotated, map[string]interface{}{"name": name}); err != nil {
+			log.Printf("Failed to tell agent %s that secret %s was rotated: %v", ag.Config.Name, name, err)
+			continue
+		}
+		notified = append(notified, ag.Config.Name)
+	}
+	sort.Strings(notified)
+	return notified
+}
@@ -0,0 +1,140 @@
+package daemon
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"opperator/config"
+	"opperator/internal/ipc"
+	"opperator/updater"
+	"opperator/version"
+)
+
+// exitCodeRestart is the exit status after a self update. It is non-zero so
+// systemd units with Restart=on-failure start the new binary.
+const exitCodeRestart = 75
+
+// selfUpdateMu keeps a scheduled and a requested update from racing.
+var selfUpdateMu sync.Mutex
+
+// selfUpdate installs the latest release of channel, or of the configured
+// channel when empty, and restarts the daemon through its service manager.
+func (s *Server) selfUpdate(channel string) ipc.Response {
+	if channel == "" {
+		channel = s.updates.Channel
+	}
+	if channel != config.UpdateChannelStable && channel != config.UpdateChannelPreRelease {
+		return ipc.NewErrorResponse(ipc.ErrCodeValidation, fmt.Sprintf("unknown update channel %q (expected stable or pre-release)", channel))
+	}
+
+	result, err := s.pullUpdate(channel)
+	if err != nil {
+		return ipc.ErrorResponse(err)
+	}
+	if result.Updated {
+		s.restartForUpdate()
+	}
+	return ipc.Response{Success: true, SelfUpdate: result}
+}
+
+// pullUpdate downloads, verifies and installs the latest release of channel
+// over the running binary when it is newer.
+func (s *Server) pullUpdate(channel string) (*ipc.SelfUpdateResult, error) {
+	if !selfUpdateMu.TryLock() {
+		return nil, ipc.NewError(ipc.ErrCodeBusy, "an update is already in progress")
+	}
+	defer selfUpdateMu.Unlock()
+
+	result := &ipc.SelfUpdateResult{Channel: channel, CurrentVersion: version.Get()}
+	if result.CurrentVersion == "dev" {
+		return nil, ipc.NewError(ipc.ErrCodeValidation, "dev builds do not update themselves; push a build with 'op cloud update' instead")
+	}
+	// Without a service manager nothing would start the new binary
+	if os.Getenv("INVOCATION_ID") == "" {
+		return nil, ipc.NewError(ipc.ErrCodeValidation, "the daemon is not running under systemd; update it with 'op version update' and restart it")
+	}
+
+	info, err := updater.CheckForUpdates(channel == config.UpdateChannelPreRelease)
+	if err != nil {
+		return nil, ipc.NewError(ipc.ErrCodeUnavailable, err.Error())
+	}
+	result.LatestVersion = info.LatestVersion
+	if !info.Available {
+		return result, nil
+	}
+
+	if err := checkBinaryWritable(); err != nil {
+		return nil, err
+	}
+	log.Printf("[Update] Installing %s (channel %s) over %s", info.LatestVersion, channel, result.CurrentVersion)
+	if err := updater.DownloadAndInstallVerified(info); err != nil {
+		return nil, ipc.NewError(ipc.ErrCodeInternal, err.Error())
+	}
+	result.Updated = true
+	return result, nil
+}
+
+// checkBinaryWritable fails early when the service sandbox keeps the binary
+// directory read-only, as units from before self updates do.
+func checkBinaryWritable() error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+	dir := filepath.Dir(exe)
+	probe, err := os.CreateTemp(dir, ".update-check-*")
+	if err != nil {
+		return ipc.NewError(ipc.ErrCodeForbidden, fmt.Sprintf(
+			"cannot write to %s: %v; add ReadWritePaths=%s to the systemd unit or update with 'op cloud update' over SSH", dir, err, dir))
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+	return nil
+}
+
+// restartForUpdate stops the daemon shortly after the response is sent and
+// exits so systemd starts the new binary.
+func (s *Server) restartForUpdate() {
+	go func() {
+		time.Sleep(500 * time.Millisecond)
+		log.Printf("[Update] Restarting on the new binary")
+		s.Stop()
+		os.Exit(exitCodeRestart)
+	}()
+}
+
+// scheduleUpdates checks for and installs new releases every interval when
+// automatic updates are enabled.
+func (s *Server) scheduleUpdates(stop <-chan struct{}) {
+	if !s.updates.Auto {
+		return
+	}
+	interval := s.updates.CheckInterval()
+	log.Printf("[Update] Automatic updates from the %s channel every %s", s.updates.Channel, interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		result, err := s.pullUpdate(s.updates.Channel)
+		if err != nil {
+			log.Printf("[Update] Automatic update failed: %v", err)
+			s.notifyUpdateFailed("local", err.Error())
+			continue
+		}
+		if result.Updated {
+			s.restartForUpdate()
+			return
+		}
+	}
+}
@@ -7,8 +7,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
@@ -17,16 +19,24 @@ import (
 
 	"opperator/config"
 	"opperator/internal/agent"
+	"opperator/internal/channel"
 	"opperator/internal/credentials"
+	"opperator/internal/hooks"
 	"opperator/internal/ipc"
+	"opperator/internal/kb"
+	"opperator/internal/kv"
+	"opperator/internal/logship"
+	"opperator/internal/memory"
+	"opperator/internal/notify"
+	"opperator/internal/policy"
 	"opperator/internal/protocol"
 	"opperator/internal/taskqueue"
+	"opperator/internal/trigger"
 	"opperator/pkg/db"
 	"opperator/pkg/migration"
+	"opperator/pkg/tracing"
 	"tui/components/sidebar"
 	"tui/tools"
-
-	"gopkg.in/yaml.v3"
 )
 
 type Server struct {
@@ -38,8 +48,33 @@ type Server struct {
 	stateBroker        *Broker[AgentStateChange]
 	taskBroker         *Broker[TaskEvent]
 	logFile            *os.File
+	logShipper         *logship.Shipper
+	notifier           *notify.Dispatcher
+	hooks              *hooks.Runner
+	policy             *policy.Engine
+	tokens             *tokenRegistry
+	triggers           *trigger.Manager
+	channels           *channel.Router
+	channelsMu         sync.Mutex
+	dashboards         *http.Server
+	gitWebhook         *http.Server
+	memory             *memory.Store
+	knowledge          *kb.Store
+	kv                 *kv.Store
+	blackboards        map[string]config.BlackboardConfig
+	blackboardMu       sync.Mutex
+	blackboardDirty    map[string]bool
 	lastInvocationDir  string
 	invocationDirMutex sync.RWMutex
+	resources          *resourceSampler
+	startedAt          time.Time
+	updates            config.UpdatesConfig
+	backup             config.BackupConfig
+	gitSync            config.GitSyncConfig
+	budgets            *budgetLedger
+	secretUsers        *secretUsers
+	blobs              *blobStore
+	stopMonitor        chan struct{}
 }
 
 func NewServer() (*Server, error) {
@@ -54,17 +89,49 @@ func NewServer() (*Server, error) {
 		return nil, fmt.Errorf("failed to get daemon log path: %w", err)
 	}
 
+	settings, err := config.LoadDaemonSettings()
+	if err != nil {
+		lock.Release()
+		return nil, err
+	}
+
 	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
 	if err != nil {
 		lock.Release()
 		return nil, fmt.Errorf("failed to open log file: %w", err)
 	}
 
-	log.SetOutput(logFile)
-	log.SetFlags(log.LstdFlags | log.Lmicroseconds | log.Lshortfile)
+	logShipper, err := logship.New(settings.LogShipping)
+	if err != nil {
+		logFile.Close()
+		lock.Release()
+		return nil, err
+	}
+	if logShipper != nil {
+		configureLogging(io.MultiWriter(logFile, logShipper), settings.Logging)
+		agent.SetLogSink(logShipper.ShipAgentLine)
+	} else {
+		configureLogging(logFile, settings.Logging)
+	}
+
+	notifier, err := notify.New(settings.Notifications)
+	if err != nil {
+		logFile.Close()
+		lock.Release()
+		return nil, err
+	}
+
+	hookRunner := hooks.New(settings.Hooks)
+
+	policyEngine, err := policy.New(settings.Policy)
+	if err != nil {
+		logFile.Close()
+		lock.Release()
+		return nil, err
+	}
 
 	log.Printf("=== Daemon starting ===")
-	log.Printf("Log file: %s", logPath)
+	log.Printf("Log file: %s (format: %s, level: %s)", logPath, settings.Logging.Format, settings.Logging.Level)
 
 	// Ensure config exists and get path
 	if err := config.EnsureConfigExists(); err != nil {
@@ -115,9 +182,13 @@ func NewServer() (*Server, error) {
 		return nil, fmt.Errorf("failed to run migrations: %w", err)
 	}
 
-	taskRunner := newDaemonToolRunner()
-	agentRunner := newDaemonAgentRunner(manager)
-	taskManager, err := taskqueue.NewManager(context.Background(), writeDB, taskRunner, agentRunner)
+	blobs := newBlobStore()
+	taskRunner := newDaemonToolRunner(policyEngine)
+	agentRunner := newDaemonAgentRunner(manager, policyEngine, blobs)
+	taskOptions := taskQueueOptions(settings.Tasks)
+	taskOptions.Workflow = newDaemonWorkflowRunner(agentRunner, policyEngine)
+	taskOptions.Conversation = daemonConversationRunner{}
+	taskManager, err := taskqueue.NewManagerWithOptions(context.Background(), writeDB, taskRunner, agentRunner, taskOptions)
 	if err != nil {
 		logFile.Close()
 		lock.Release()
@@ -136,6 +207,10 @@ func NewServer() (*Server, error) {
 			Type: TaskEventType(ev.Type),
 			Task: taskCopy,
 		})
+		if ev.Type == taskqueue.TaskEventFailed && taskCopy != nil {
+			notifier.Notify(taskFailedEvent(taskCopy, ev.Error))
+			hookRunner.Run(taskFailedHookEvent(taskCopy, ev.Error))
+		}
 	})
 
 	server := &Server{
@@ -143,18 +218,51 @@ func NewServer() (*Server, error) {
 		tasks:       taskManager,
 		lock:        lock,
 		db:          writeDB,
+		memory:      memory.NewStore(writeDB, embedTexts),
+		knowledge:   kb.NewStore(writeDB, embedTexts),
+		kv:          kv.NewStore(writeDB),
+		blackboards: settings.Blackboards,
 		stateBroker: stateBroker,
 		taskBroker:  taskBroker,
 		logFile:     logFile,
+		logShipper:  logShipper,
+		notifier:    notifier,
+		hooks:       hookRunner,
+		policy:      policyEngine,
+		tokens:      newTokenRegistry(settings.Auth),
+		resources:   newResourceSampler(),
+		startedAt:   time.Now(),
+		updates:     settings.Updates,
+		backup:      settings.Backup,
+		gitSync:     settings.GitSync,
+		budgets:     newBudgetLedger(settings.Budgets, writeDB),
+		secretUsers: newSecretUsers(),
+		blobs:       blobs,
+		stopMonitor: make(chan struct{}),
 	}
 
 	manager.SetStateChangeCallback(func(agentName string, changeType string, data interface{}) {
 		server.publishStateChange(agentName, changeType, data)
 	})
+	manager.SetKVHandler(server.handleAgentKV)
+
+	server.triggers = trigger.NewManager(context.Background(), server.invokeTrigger)
+	server.startConfigTriggers(settings.Triggers)
+	server.channels = channel.NewRouter(channelConversations{server})
+	server.startChannels(settings.Channels())
+	server.startDashboards(settings.Dashboards)
+	server.startGitWebhook(settings.GitSync)
+	server.publishBlackboardSections(nil)
+
+	server.notifyVersionChange()
 
 	// Start previously running agents
 	server.startPreviouslyRunningAgents()
 
+	go server.monitorResources(server.stopMonitor)
+	go server.scheduleUpdates(server.stopMonitor)
+	go server.scheduleBackups(server.stopMonitor)
+
 	return server, nil
 }
 
@@ -186,13 +294,11 @@ func (s *Server) Start() (err error) {
 	// Optionally start TCP listener if configured
 	tcpPort := os.Getenv("OPPERATOR_TCP_PORT")
 	if tcpPort != "" {
-		authToken := os.Getenv("OPPERATOR_AUTH_TOKEN")
-		if authToken == "" {
-			log.Printf("WARNING: TCP port specified but OPPERATOR_AUTH_TOKEN not set!")
-			log.Printf("WARNING: TCP listener will not be started for security reasons")
-		} else {
-			go s.startTCPListener(tcpPort, authToken)
+		if s.tokens.empty() {
+			log.Printf("WARNING: TCP port specified but no auth token configured!")
+			log.Printf("WARNING: TCP connections will be rejected until a token is created with 'op daemon token create'")
 		}
+		go s.startTCPListener(tcpPort)
 	}
 
 	for {
@@ -212,7 +318,7 @@ func (s *Server) Start() (err error) {
 }
 
 // startTCPListener starts a TCP listener for remote connections
-func (s *Server) startTCPListener(port, authToken string) {
+func (s *Server) startTCPListener(port string) {
 	addr := ":" + port
 	listener, err := net.Listen("tcp", addr)
 	if err != nil {
@@ -234,12 +340,12 @@ func (s *Server) startTCPListener(port, authToken string) {
 			return
 		}
 
-		go s.handleTCPConnection(conn, authToken)
+		go s.handleTCPConnection(conn)
 	}
 }
 
 // handleTCPConnection handles a TCP connection with authentication
-func (s *Server) handleTCPConnection(conn net.Conn, expectedToken string) {
+func (s *Server) handleTCPConnection(conn net.Conn) {
 	defer conn.Close()
 	connID := fmt.Sprintf("TCP-%p", conn)
 	log.Printf("[%s] New TCP connection from %s", connID, conn.RemoteAddr())
@@ -265,26 +371,26 @@ func (s *Server) handleTCPConnection(conn net.Conn, expectedToken string) {
 		return
 	}
 
-	token := parts[1]
-	if token != expectedToken {
+	token, ok := s.tokens.authenticate(parts[1])
+	if !ok {
 		log.Printf("[%s] Authentication failed: invalid token", connID)
 		conn.Write([]byte("ERR invalid token\n"))
 		return
 	}
 
 	// Auth successful
-	log.Printf("[%s] Authentication successful", connID)
+	log.Printf("[%s] Authentication successful (token %s, role %s)", connID, token.name, token.role)
 	conn.Write([]byte("OK\n"))
 
 	// Clear deadline for normal operation
 	conn.SetDeadline(time.Time{})
 
 	// Now handle as normal connection
-	s.handleConnectionAuthenticated(conn, connID, reader)
+	s.handleConnectionAuthenticated(conn, connID, reader, token)
 }
 
 // handleConnectionAuthenticated handles an authenticated connection
-func (s *Server) handleConnectionAuthenticated(conn net.Conn, connID string, reader *bufio.Reader) {
+func (s *Server) handleConnectionAuthenticated(conn net.Conn, connID string, reader *bufio.Reader, token authToken) {
 	log.Printf("[%s] Connection authenticated, switching to request mode", connID)
 
 	requestCount := 0
@@ -298,18 +404,34 @@ func (s *Server) handleConnectionAuthenticated(conn net.Conn, connID string, rea
 		req, err := ipc.DecodeRequest(data)
 		if err != nil {
 			log.Printf("[%s] Invalid request: %v", connID, err)
-			resp := ipc.Response{Success: false, Error: "invalid request"}
+			resp := ipc.NewErrorResponse(ipc.ErrCodeValidation, "invalid request")
 			b, _ := ipc.EncodeResponse(resp)
 			_, _ = conn.Write(append(b, '\n'))
 			continue
 		}
 
 		requestCount++
-		log.Printf("[%s] Request #%d: type=%s, agent=%s", connID, requestCount, req.Type, req.AgentName)
+		logRequest(connID, requestCount, req)
 
+		if !s.tokens.valid(token) {
+			log.Printf("[%s] Token %s was revoked, closing connection", connID, token.name)
+			resp := ipc.NewErrorResponse(ipc.ErrCodeUnauthorized, "auth token has been revoked")
+			b, _ := ipc.EncodeResponse(resp)
+			_, _ = conn.Write(append(b, '\n'))
+			return
+		}
+
+		if denied := token.authorize(req); denied != nil {
+			b, _ := ipc.EncodeResponse(*denied)
+			_, _ = conn.Write(append(b, '\n'))
+			logRequestDone(connID, requestCount, req, *denied)
+			continue
+		}
+
+		scope := tokenScope(token, req)
 		if req.Type == ipc.RequestWatchToolTask {
 			log.Printf("[%s] Switching to tool task streaming mode", connID)
-			s.streamToolTask(conn, req)
+			s.streamToolTask(conn, scope, req)
 			return
 		}
 
@@ -321,20 +443,20 @@ func (s *Server) handleConnectionAuthenticated(conn net.Conn, connID string, rea
 
 		if req.Type == ipc.RequestWatchAllTasks {
 			log.Printf("[%s] Switching to task streaming mode", connID)
-			s.streamAllTasks(conn, req)
+			s.streamAllTasks(conn, scope, req)
 			return
 		}
 
 		if req.Type == ipc.RequestCommand {
 			log.Printf("[%s] Handling command request with progress streaming", connID)
-			s.handleCommandWithProgress(conn, req)
+			s.handleCommandWithProgress(conn, reader, scope, req)
 			continue
 		}
 
-		resp := s.processRequest(req)
+		resp := s.serveRequest(scope, req)
 		b, _ := ipc.EncodeResponse(resp)
 		_, _ = conn.Write(append(b, '\n'))
-		log.Printf("[%s] Request #%d completed: success=%v", connID, requestCount, resp.Success)
+		logRequestDone(connID, requestCount, req, resp)
 	}
 }
 
@@ -355,18 +477,18 @@ func (s *Server) handleConnection(conn net.Conn) {
 		req, err := ipc.DecodeRequest(data)
 		if err != nil {
 			log.Printf("[Connection %s] Invalid request: %v", connID, err)
-			resp := ipc.Response{Success: false, Error: "invalid request"}
+			resp := ipc.NewErrorResponse(ipc.ErrCodeValidation, "invalid request")
 			b, _ := ipc.EncodeResponse(resp)
 			_, _ = conn.Write(append(b, '\n'))
 			continue
 		}
 
 		requestCount++
-		log.Printf("[Connection %s] Request #%d: type=%s, agent=%s", connID, requestCount, req.Type, req.AgentName)
+		logRequest(connID, requestCount, req)
 
 		if req.Type == ipc.RequestWatchToolTask {
 			log.Printf("[Connection %s] Switching to tool task streaming mode", connID)
-			s.streamToolTask(conn, req)
+			s.streamToolTask(conn, localScope, req)
 			return
 		}
 
@@ -378,20 +500,20 @@ func (s *Server) handleConnection(conn net.Conn) {
 
 		if req.Type == ipc.RequestWatchAllTasks {
 			log.Printf("[Connection %s] Switching to task streaming mode", connID)
-			s.streamAllTasks(conn, req)
+			s.streamAllTasks(conn, localScope, req)
 			return
 		}
 
 		if req.Type == ipc.RequestCommand {
 			log.Printf("[Connection %s] Handling command request with progress streaming", connID)
-			s.handleCommandWithProgress(conn, req)
+			s.handleCommandWithProgress(conn, reader, localScope, req)
 			continue
 		}
 
-		resp := s.processRequest(req)
+		resp := s.serveRequest(localScope, req)
 		b, _ := ipc.EncodeResponse(resp)
 		_, _ = conn.Write(append(b, '\n'))
-		log.Printf("[Connection %s] Request #%d completed: success=%v", connID, requestCount, resp.Success)
+		logRequestDone(connID, requestCount, req, resp)
 	}
 }
 
@@ -399,7 +521,7 @@ func (s *Server) streamAgentState(conn net.Conn, req ipc.Request) {
 	log.Printf("[AgentStateStream] New client connected to agent state stream")
 	if s.stateBroker == nil {
 		log.Printf("[AgentStateStream] ERROR: state broker unavailable")
-		resp := ipc.Response{Success: false, Error: "state broker unavailable"}
+		resp := ipc.NewErrorResponse(ipc.ErrCodeUnavailable, "state broker unavailable")
 		if b, err := ipc.EncodeResponse(resp); err == nil {
 			_, _ = conn.Write(append(b, '\n'))
 		}
@@ -451,11 +573,11 @@ func (s *Server) streamAgentState(conn net.Conn, req ipc.Request) {
 	log.Printf("[AgentStateStream] Client disconnected")
 }
 
-func (s *Server) streamAllTasks(conn net.Conn, req ipc.Request) {
+func (s *Server) streamAllTasks(conn net.Conn, scope taskScope, req ipc.Request) {
 	log.Printf("[TaskStream] New client connected to task stream")
 	if s.taskBroker == nil {
 		log.Printf("[TaskStream] ERROR: task broker unavailable")
-		resp := ipc.Response{Success: false, Error: "task broker unavailable"}
+		resp := ipc.NewErrorResponse(ipc.ErrCodeUnavailable, "task broker unavailable")
 		if b, err := ipc.EncodeResponse(resp); err == nil {
 			_, _ = conn.Write(append(b, '\n'))
 		}
@@ -482,7 +604,7 @@ func (s *Server) streamAllTasks(conn net.Conn, req ipc.Request) {
 	if s.tasks != nil {
 		initial := s.tasks.ActiveTasks()
 		for _, task := range initial {
-			if task == nil {
+			if !scope.sees(task) {
 				continue
 			}
 			payload := ipc.ToolTaskEvent{
@@ -502,6 +624,9 @@ func (s *Server) streamAllTasks(conn net.Conn, req ipc.Request) {
 	// Stream events
 	eventCount := 0
 	for ev := range events {
+		if !scope.sees(ev.Task) {
+			continue
+		}
 		eventCount++
 		taskID := ""
 		if ev.Task != nil {
@@ -527,9 +652,9 @@ func (s *Server) streamAllTasks(conn net.Conn, req ipc.Request) {
 	log.Printf("[TaskStream] Client disconnected after receiving %d events", eventCount)
 }
 
-func (s *Server) streamToolTask(conn net.Conn, req ipc.Request) {
+func (s *Server) streamToolTask(conn net.Conn, scope taskScope, req ipc.Request) {
 	if s.tasks == nil {
-		resp := ipc.Response{Success: false, Error: "tool task manager unavailable"}
+		resp := ipc.NewErrorResponse(ipc.ErrCodeUnavailable, "tool task manager unavailable")
 		if b, err := ipc.EncodeResponse(resp); err == nil {
 			_, _ = conn.Write(append(b, '\n'))
 		}
@@ -537,7 +662,14 @@ func (s *Server) streamToolTask(conn net.Conn, req ipc.Request) {
 	}
 	taskID := strings.TrimSpace(req.TaskID)
 	if taskID == "" {
-		resp := ipc.Response{Success: false, Error: "task id is required"}
+		resp := ipc.NewErrorResponse(ipc.ErrCodeValidation, "task id is required")
+		if b, err := ipc.EncodeResponse(resp); err == nil {
+			_, _ = conn.Write(append(b, '\n'))
+		}
+		return
+	}
+	if task, ok := s.tasks.Get(taskID); ok && !scope.sees(task) {
+		resp := ipc.NewErrorResponse(ipc.ErrCodeNotFound, "task not found")
 		if b, err := ipc.EncodeResponse(resp); err == nil {
 			_, _ = conn.Write(append(b, '\n'))
 		}
@@ -545,7 +677,7 @@ func (s *Server) streamToolTask(conn net.Conn, req ipc.Request) {
 	}
 	events, cancel, err := s.tasks.SubscribeTask(taskID)
 	if err != nil {
-		resp := ipc.Response{Success: false, Error: err.Error()}
+		resp := ipc.ErrorResponse(err)
 		if b, encodeErr := ipc.EncodeResponse(resp); encodeErr == nil {
 			_, _ = conn.Write(append(b, '\n'))
 		}
@@ -577,10 +709,88 @@ func (s *Server) shutdown() ipc.Response {
 	return ipc.Response{Success: true}
 }
 
-// processRequest routes requests to the appropriate handlers.
-func (s *Server) handleCommandWithProgress(conn net.Conn, req ipc.Request) {
+// requestContext returns a context that expires once the request timeout, if
+// the client sent one, has passed, and carries the client's trace context.
+func requestContext(req ipc.Request) (context.Context, context.CancelFunc) {
+	base := tracing.WithTraceParent(context.Background(), req.TraceParent)
+	if req.TimeoutMS <= 0 {
+		return context.WithCancel(base)
+	}
+	return context.WithTimeout(base, time.Duration(req.TimeoutMS)*time.Millisecond)
+}
+
+// commandDir returns the directory a command request runs in, marking ctx
+// when the user picked it so it overrides the command's declared one.
+func commandDir(ctx context.Context, req ipc.Request) (context.Context, string) {
+	if req.CommandDir == "" {
+		return ctx, req.WorkingDir
+	}
+	return agent.WithExplicitWorkingDir(ctx), req.CommandDir
+}
+
+// serveRequest processes a single non-streaming request inside a trace span.
+// scope limits the tool tasks the request sees.
+func (s *Server) serveRequest(scope taskScope, req ipc.Request) ipc.Response {
+	ctx, cancel := requestContext(req)
+	defer cancel()
+	ctx = withTaskScope(ctx, scope)
+	ctx, span := tracing.Start(ctx, "daemon.request",
+		"ipc.request_type", string(req.Type),
+		"agent.name", req.AgentName,
+	)
+	defer span.End()
+
+	resp := s.processRequest(ctx, req)
+	if !resp.Success {
+		span.RecordError(resp.Err())
+		span.SetAttributes("ipc.error_code", string(resp.ErrorCode))
+	}
+	return resp
+}
+
+// watchDisconnect cancels the request when the client closes the connection
+// while a command is in flight. The returned stop function must be called
+// before the connection is read again.
+func watchDisconnect(conn net.Conn, reader *bufio.Reader, cancel context.CancelFunc) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if _, err := reader.Peek(1); err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				return
+			}
+			cancel()
+		}
+	}()
+	return func() {
+		// Unblock the pending Peek; buffered data stays available
+		_ = conn.SetReadDeadline(time.Now())
+		<-done
+		_ = conn.SetReadDeadline(time.Time{})
+	}
+}
+
+func (s *Server) handleCommandWithProgress(conn net.Conn, reader *bufio.Reader, scope taskScope, req ipc.Request) {
 	if req.Command == "" {
-		resp := ipc.Response{Success: false, Error: "command is required"}
+		resp := ipc.NewErrorResponse(ipc.ErrCodeValidation, "command is required")
+		b, _ := ipc.EncodeResponse(resp)
+		conn.Write(append(b, '\n'))
+		return
+	}
+
+	origin := scope.origin(req.Origin, "client")
+	in := policy.Input{
+		Kind:    config.PolicyKindCommand,
+		Agent:   req.AgentName,
+		Command: req.Command,
+		Args:    req.Args,
+		Origin:  origin,
+		User:    scope.user,
+		Time:    time.Now(),
+	}
+	if err := checkPolicy(context.Background(), s.policy, in); err != nil {
+		resp := ipc.NewErrorResponse(ipc.ErrCodeForbidden, err.Error())
 		b, _ := ipc.EncodeResponse(resp)
 		conn.Write(append(b, '\n'))
 		return
@@ -591,75 +801,228 @@ func (s *Server) handleCommandWithProgress(conn net.Conn, req ipc.Request) {
 		s.setInvocationDir(req.WorkingDir)
 	}
 
+	ctx, cancel := requestContext(req)
+	defer cancel()
+	ctx, span := tracing.Start(ctx, "daemon.command",
+		"agent.name", req.AgentName,
+		"agent.command", req.Command,
+	)
+	defer span.End()
+	stopWatching := watchDisconnect(conn, reader, cancel)
+
+	started := time.Now()
+	ctx, dir := commandDir(ctx, req)
+	args, err := s.blobs.resolveArgs(req.Args)
+	if err != nil {
+		stopWatching()
+		resp := ipc.ErrorResponse(err)
+		b, _ := ipc.EncodeResponse(resp)
+		conn.Write(append(b, '\n'))
+		return
+	}
 	// Use InvokeCommandAsync to get progress updates
-	resp, err := s.manager.InvokeCommandAsync(req.AgentName, req.Command, req.Args, req.WorkingDir, 30*time.Minute, func(prog protocol.CommandProgressMessage) {
+	resp, err := s.manager.InvokeCommandAsync(ctx, req.AgentName, req.Command, args, dir, 30*time.Minute, func(prog protocol.CommandProgressMessage) {
 		// Send progress message to client
 		progressResp := ipc.Response{
 			Success:  true,
 			Progress: &prog,
 		}
 		b, _ := ipc.EncodeResponse(progressResp)
-		conn.Write(append(b, '\n'))
+		if _, err := conn.Write(append(b, '\n')); err != nil {
+			cancel()
+		}
 	})
+	stopWatching()
+	taskID := s.recordCommand(req, origin, scope.user, started, resp, err)
 
 	// Send final response
 	if err != nil {
-		finalResp := ipc.Response{Success: false, Error: err.Error()}
+		span.RecordError(err)
+		finalResp := ipc.ErrorResponse(err)
 		b, _ := ipc.EncodeResponse(finalResp)
 		conn.Write(append(b, '\n'))
 		return
 	}
 
+	span.SetAttributes("command.success", resp.Success)
 	cmdResp := &ipc.CommandResponse{
 		Success: resp.Success,
 		Error:   resp.Error,
 		Result:  resp.Result,
+		TaskID:  taskID,
+		Files:   s.blobs.storeOutputs(req.AgentName, req.Command, resp),
 	}
 	finalResp := ipc.Response{Success: true, Command: cmdResp}
 	b, _ := ipc.EncodeResponse(finalResp)
 	conn.Write(append(b, '\n'))
 }
 
-func (s *Server) processRequest(req ipc.Request) ipc.Response {
+// submitErrorResponse maps a task submission error to its response.
+func submitErrorResponse(err error) ipc.Response {
+	switch {
+	case errors.Is(err, taskqueue.ErrUnknownDependency), errors.Is(err, taskqueue.ErrInvalidExecution):
+		return ipc.NewErrorResponse(ipc.ErrCodeValidation, err.Error())
+	case errors.Is(err, taskqueue.ErrPendingLimit):
+		return ipc.NewErrorResponse(ipc.ErrCodeBusy, err.Error())
+	case errors.Is(err, taskqueue.ErrClosed):
+		return ipc.NewErrorResponse(ipc.ErrCodeUnavailable, err.Error())
+	}
+	return ipc.ErrorResponse(err)
+}
+
+// recordCommand stores a synchronous command invocation as a finished task,
+// with its exact arguments, so it can be replayed later. It returns the
+// task's ID, or "" when it could not be recorded.
+func (s *Server) recordCommand(req ipc.Request, origin, owner string, started time.Time, resp *protocol.ResponseMessage, invokeErr error) string {
+	if s.tasks == nil {
+		return ""
+	}
+	args := ""
+	if len(req.Args) > 0 {
+		b, err := json.Marshal(req.Args)
+		if err != nil {
+			log.Printf("Failed to record command %s on %s: %v", req.Command, req.AgentName, err)
+			return ""
+		}
+		args = string(b)
+	}
+	result, metadata, err := commandTaskOutcome(req.AgentName, req.Command, resp)
+	if invokeErr != nil {
+		err = invokeErr
+	}
+	errMsg := ""
+	if err != nil {
+		errMsg = err.Error()
+	}
+	task, err := s.tasks.Record(taskqueue.SubmitRequest{
+		ToolName:    req.Command,
+		Args:        args,
+		WorkingDir:  req.WorkingDir,
+		Mode:        "agent",
+		AgentName:   req.AgentName,
+		Command:     req.Command,
+		CommandArgs: args,
+		Origin:      origin,
+		Owner:       owner,
+	}, result, metadata, errMsg, started)
+	if err != nil {
+		log.Printf("Failed to record command %s on %s: %v", req.Command, req.AgentName, err)
+		return ""
+	}
+	return task.ID
+}
+
+// processRequest routes requests to the appropriate handlers.
+func (s *Server) processRequest(ctx context.Context, req ipc.Request) ipc.Response {
+	if ctx.Err() != nil {
+		return ipc.NewErrorResponse(ipc.ErrCodeTimeout, "request deadline exceeded before processing")
+	}
 	switch req.Type {
 	case ipc.RequestListAgents:
 		return s.listAgents()
 	case ipc.RequestStartAgent:
 		if err := s.manager.StartAgent(req.AgentName); err != nil {
-			return ipc.Response{Success: false, Error: err.Error()}
+			return ipc.ErrorResponse(err)
 		}
 		// Send current invocation directory to newly started agent
 		s.sendInvocationDirToAgent(req.AgentName)
 		return ipc.Response{Success: true}
 	case ipc.RequestStopAgent:
-		if err := s.manager.StopAgent(req.AgentName); err != nil {
-			return ipc.Response{Success: false, Error: err.Error()}
+		dependents := s.manager.RunningDependents(req.AgentName)
+		var opts agent.StopOptions
+		if req.StopOptions != nil {
+			opts = *req.StopOptions
 		}
-		return ipc.Response{Success: true}
+		method, err := s.manager.StopAgentWithOptions(req.AgentName, opts)
+		if err != nil {
+			return ipc.ErrorResponse(err)
+		}
+		log.Printf("Stopped agent %s (%s)", req.AgentName, method)
+		if len(dependents) > 0 {
+			log.Printf("Warning: stopped agent %s while %s depend on it", req.AgentName, strings.Join(dependents, ", "))
+		}
+		return ipc.Response{Success: true, Dependents: dependents, StopMethod: method}
 	case ipc.RequestRestartAgent:
 		if err := s.manager.RestartAgent(req.AgentName); err != nil {
-			return ipc.Response{Success: false, Error: err.Error()}
+			return ipc.ErrorResponse(err)
 		}
 		// Send current invocation directory to restarted agent
 		s.sendInvocationDirToAgent(req.AgentName)
 		return ipc.Response{Success: true}
+	case ipc.RequestPauseAgent:
+		if err := s.manager.PauseAgent(req.AgentName, strings.TrimSpace(req.PauseReason)); err != nil {
+			return ipc.ErrorResponse(err)
+		}
+		log.Printf("Paused agent %s", req.AgentName)
+		return ipc.Response{Success: true}
+	case ipc.RequestResumeAgent:
+		if err := s.manager.ResumeAgent(req.AgentName); err != nil {
+			return ipc.ErrorResponse(err)
+		}
+		log.Printf("Resumed agent %s", req.AgentName)
+		return ipc.Response{Success: true}
 	case ipc.RequestStopAll:
 		if err := s.manager.StopAll(); err != nil {
-			return ipc.Response{Success: false, Error: err.Error()}
+			return ipc.ErrorResponse(err)
 		}
 		return ipc.Response{Success: true}
 	case ipc.RequestGetLogs:
 		ag, err := s.manager.GetAgent(req.AgentName)
 		if err != nil {
-			return ipc.Response{Success: false, Error: err.Error()}
+			return ipc.ErrorResponse(err)
 		}
 		return ipc.Response{Success: true, Logs: ag.GetLogs()}
+	case ipc.RequestSearchLogs:
+		if req.LogSearch == nil {
+			return ipc.NewErrorResponse(ipc.ErrCodeValidation, "log search is required")
+		}
+		result, err := s.manager.SearchLogs(*req.LogSearch)
+		if err != nil {
+			return ipc.NewErrorResponse(ipc.ErrCodeValidation, err.Error())
+		}
+		return ipc.Response{Success: true, LogMatches: result}
+	case ipc.RequestProtocolFrames:
+		ag, err := s.manager.GetAgent(req.AgentName)
+		if err != nil {
+			return ipc.ErrorResponse(err)
+		}
+		return ipc.Response{Success: true, Frames: ag.ProtocolFrames(req.FramesAfter)}
+	case ipc.RequestGetCrashReport:
+		ag, err := s.manager.GetAgent(req.AgentName)
+		if err != nil {
+			return ipc.ErrorResponse(err)
+		}
+		report, err := ag.LastCrash()
+		if err != nil {
+			return ipc.ErrorResponse(err)
+		}
+		return ipc.Response{Success: true, CrashReport: report}
+	case ipc.RequestPromptHistory:
+		ag, err := s.manager.GetAgent(req.AgentName)
+		if err != nil {
+			return ipc.ErrorResponse(err)
+		}
+		versions, err := ag.PromptHistory()
+		if err != nil {
+			return ipc.ErrorResponse(err)
+		}
+		return ipc.Response{Success: true, Prompts: versions}
+	case ipc.RequestRevertPrompt:
+		ag, err := s.manager.GetAgent(req.AgentName)
+		if err != nil {
+			return ipc.ErrorResponse(err)
+		}
+		version, err := ag.RevertPrompt(req.PromptVersionID)
+		if err != nil {
+			return ipc.ErrorResponse(err)
+		}
+		return ipc.Response{Success: true, Prompts: []agent.PromptVersion{version}}
 	case ipc.RequestGetCustomSections:
 		log.Printf("[CustomSections] Request to get custom sections for agent: %s", req.AgentName)
 		ag, err := s.manager.GetAgent(req.AgentName)
 		if err != nil {
 			log.Printf("[CustomSections] Failed to get agent %s: %v", req.AgentName, err)
-			return ipc.Response{Success: false, Error: err.Error()}
+			return ipc.ErrorResponse(err)
 		}
 		sections := ag.CustomSections()
 		log.Printf("[CustomSections] Retrieved %d custom sections for agent %s", len(sections), req.AgentName)
@@ -670,31 +1033,57 @@ func (s *Server) processRequest(req ipc.Request) ipc.Response {
 		return ipc.Response{Success: true, Sections: sections}
 	case ipc.RequestCommand:
 		if req.Command == "" {
-			return ipc.Response{Success: false, Error: "command is required"}
+			return ipc.NewErrorResponse(ipc.ErrCodeValidation, "command is required")
 		}
 		// Store invocation directory for future agent starts
 		if req.WorkingDir != "" {
 			s.setInvocationDir(req.WorkingDir)
 		}
-		resp, err := s.manager.InvokeCommand(req.AgentName, req.Command, req.Args, req.WorkingDir, 10*time.Second)
+		ctx, dir := commandDir(ctx, req)
+		args, err := s.blobs.resolveArgs(req.Args)
+		if err != nil {
+			return ipc.ErrorResponse(err)
+		}
+		resp, err := s.manager.InvokeCommand(ctx, req.AgentName, req.Command, args, dir, 10*time.Second)
 		if err != nil {
-			return ipc.Response{Success: false, Error: err.Error()}
+			return ipc.ErrorResponse(err)
 		}
 		cmdResp := &ipc.CommandResponse{
 			Success: resp.Success,
 			Error:   resp.Error,
 			Result:  resp.Result,
+			Files:   s.blobs.storeOutputs(req.AgentName, req.Command, resp),
 		}
 		return ipc.Response{Success: true, Command: cmdResp}
+	case ipc.RequestCheckGuardrails:
+		if req.Guardrail == nil {
+			return ipc.NewErrorResponse(ipc.ErrCodeValidation, "guardrail check is required")
+		}
+		verdict, err := s.manager.CheckGuardrails(ctx, req.AgentName, *req.Guardrail)
+		if err != nil {
+			return ipc.ErrorResponse(err)
+		}
+		return ipc.Response{Success: true, Guardrail: &verdict}
+	case ipc.RequestRemember:
+		return s.remember(ctx, req)
+	case ipc.RequestRecall:
+		return s.recall(ctx, req)
+	case ipc.RequestSearchKnowledge:
+		return s.searchKnowledge(ctx, req)
+	case ipc.RequestKVGet, ipc.RequestKVSet, ipc.RequestKVDelete, ipc.RequestKVList:
+		return s.handleKV(ctx, req)
 	case ipc.RequestListCommands:
-		commands, err := s.manager.ListCommands(req.AgentName, 0)
+		commands, err := s.manager.ListCommands(ctx, req.AgentName, 0)
 		if err != nil {
-			return ipc.Response{Success: false, Error: err.Error()}
+			return ipc.ErrorResponse(err)
 		}
 		return ipc.Response{Success: true, Commands: commands}
 	case ipc.RequestReloadConfig:
+		if req.FromGit {
+			return s.reloadFromGit()
+		}
 		if err := s.manager.ReloadConfigManual(); err != nil {
-			return ipc.Response{Success: false, Error: err.Error()}
+			return ipc.ErrorResponse(err)
 		}
 		return ipc.Response{Success: true}
 	case ipc.RequestShutdown:
@@ -705,12 +1094,57 @@ func (s *Server) processRequest(req ipc.Request) ipc.Response {
 			return ipc.Response{Success: true}
 		}
 		if err := ag.SendLifecycleEvent(req.LifecycleType, req.LifecycleData); err != nil {
-			return ipc.Response{Success: false, Error: err.Error()}
+			return ipc.ErrorResponse(err)
+		}
+		return ipc.Response{Success: true}
+	case ipc.RequestCreateAuthToken:
+		secret, err := s.tokens.create(req.TokenName, req.TokenRole, req.TokenUser)
+		if err != nil {
+			return ipc.ErrorResponse(err)
+		}
+		return ipc.Response{Success: true, AuthToken: secret}
+	case ipc.RequestRevokeAuthToken:
+		if err := s.tokens.revoke(req.TokenName); err != nil {
+			return ipc.ErrorResponse(err)
+		}
+		return ipc.Response{Success: true}
+	case ipc.RequestListAuthTokens:
+		return ipc.Response{Success: true, AuthTokens: s.tokens.list()}
+	case ipc.RequestListWorkflows:
+		return s.listWorkflows()
+	case ipc.RequestListTriggers:
+		return s.listTriggers()
+	case ipc.RequestListChannels:
+		return s.listChannels()
+	case ipc.RequestAddChannel:
+		return s.addChannel(req)
+	case ipc.RequestRemoveChannel:
+		return s.removeChannel(req)
+	case ipc.RequestRunWorkflow:
+		return s.runWorkflow(ctx, req)
+	case ipc.RequestUploadBlob:
+		id, err := s.blobs.upload(req)
+		if err != nil {
+			return ipc.ErrorResponse(err)
+		}
+		return ipc.Response{Success: true, BlobID: id}
+	case ipc.RequestDownloadBlob:
+		data, done, err := s.blobs.read(req.BlobID, req.BlobOffset)
+		if err != nil {
+			return ipc.ErrorResponse(err)
+		}
+		return ipc.Response{Success: true, BlobData: data, BlobDone: done}
+	case ipc.RequestExecDetached:
+		return s.execDetached(ctx, req)
+	case ipc.RequestReportUpdateFailure:
+		if strings.TrimSpace(req.UpdateError) == "" {
+			return ipc.NewErrorResponse(ipc.ErrCodeValidation, "update error is required")
 		}
+		s.notifyUpdateFailed(req.UpdateTarget, req.UpdateError)
 		return ipc.Response{Success: true}
 	case ipc.RequestSubmitToolTask:
 		if s.tasks == nil {
-			return ipc.Response{Success: false, Error: "tool task manager unavailable"}
+			return ipc.NewErrorResponse(ipc.ErrCodeUnavailable, "tool task manager unavailable")
 		}
 		// Store invocation directory for future agent starts
 		if req.WorkingDir != "" {
@@ -726,66 +1160,97 @@ func (s *Server) processRequest(req ipc.Request) ipc.Response {
 			AgentName:   req.AgentName,
 			Command:     req.Command,
 			CommandArgs: req.CommandArgs,
-			Origin:      req.Origin,
+			Origin:      taskScopeFrom(ctx).origin(req.Origin, ""),
 			ClientID:    req.ClientID,
+			TraceParent: tracing.TraceParent(ctx),
+			DependsOn:   req.DependsOn,
+			Owner:       taskScopeFrom(ctx).user,
+			Execution:   req.Execution,
 		})
 		if err != nil {
-			return ipc.Response{Success: false, Error: err.Error()}
+			return submitErrorResponse(err)
+		}
+		return ipc.Response{Success: true, Task: convertTask(task)}
+	case ipc.RequestReplayToolTask:
+		if s.tasks == nil {
+			return ipc.NewErrorResponse(ipc.ErrCodeUnavailable, "tool task manager unavailable")
+		}
+		scope := taskScopeFrom(ctx)
+		original, ok := s.tasks.Get(req.TaskID)
+		if !ok || !scope.sees(original) {
+			return ipc.NewErrorResponse(ipc.ErrCodeNotFound, "task not found")
+		}
+		task, err := s.tasks.Replay(context.Background(), original.ID, scope.origin(req.Origin, ""), scope.user)
+		if err != nil {
+			return submitErrorResponse(err)
 		}
 		return ipc.Response{Success: true, Task: convertTask(task)}
 	case ipc.RequestGetToolTask:
 		if s.tasks == nil {
-			return ipc.Response{Success: false, Error: "tool task manager unavailable"}
+			return ipc.NewErrorResponse(ipc.ErrCodeUnavailable, "tool task manager unavailable")
 		}
 		task, ok := s.tasks.Get(req.TaskID)
-		if !ok {
-			return ipc.Response{Success: false, Error: "task not found"}
+		if !ok || !taskScopeFrom(ctx).sees(task) {
+			return ipc.NewErrorResponse(ipc.ErrCodeNotFound, "task not found")
 		}
 		return ipc.Response{Success: true, Task: convertTask(task)}
 	case ipc.RequestListToolTasks:
 		if s.tasks == nil {
-			return ipc.Response{Success: false, Error: "tool task manager unavailable"}
+			return ipc.NewErrorResponse(ipc.ErrCodeUnavailable, "tool task manager unavailable")
 		}
+		scope := taskScopeFrom(ctx)
 		tasks := s.tasks.List()
 		converted := make([]*ipc.ToolTask, 0, len(tasks))
 		for _, task := range tasks {
-			converted = append(converted, convertTask(task))
+			if scope.sees(task) {
+				converted = append(converted, convertTask(task))
+			}
 		}
 		return ipc.Response{Success: true, Tasks: converted}
 	case ipc.RequestDeleteToolTask:
 		if s.tasks == nil {
-			return ipc.Response{Success: false, Error: "tool task manager unavailable"}
+			return ipc.NewErrorResponse(ipc.ErrCodeUnavailable, "tool task manager unavailable")
 		}
 		taskID := strings.TrimSpace(req.TaskID)
 		callID := strings.TrimSpace(req.CallID)
 		sessionID := strings.TrimSpace(req.SessionID)
+		if scope := taskScopeFrom(ctx); !scope.all {
+			return s.deleteScopedTasks(scope, taskID, callID, sessionID)
+		}
 		switch {
 		case taskID != "":
 			if _, err := s.tasks.DeleteTask(context.Background(), taskID); err != nil {
-				return ipc.Response{Success: false, Error: err.Error()}
+				return ipc.ErrorResponse(err)
 			}
 		case callID != "":
 			if _, err := s.tasks.DeleteTasksByCall(context.Background(), callID); err != nil {
-				return ipc.Response{Success: false, Error: err.Error()}
+				return ipc.ErrorResponse(err)
 			}
 		case sessionID != "":
 			if _, err := s.tasks.DeleteTasksBySession(context.Background(), sessionID); err != nil {
-				return ipc.Response{Success: false, Error: err.Error()}
+				return ipc.ErrorResponse(err)
 			}
 		default:
-			return ipc.Response{Success: false, Error: "missing task identifier"}
+			return ipc.NewErrorResponse(ipc.ErrCodeValidation, "missing task identifier")
 		}
 		return ipc.Response{Success: true}
 	case ipc.RequestToolTaskMetrics:
 		if s.tasks == nil {
-			return ipc.Response{Success: false, Error: "tool task manager unavailable"}
+			return ipc.NewErrorResponse(ipc.ErrCodeUnavailable, "tool task manager unavailable")
 		}
-		metrics := s.tasks.MetricsSnapshot()
-		return ipc.Response{Success: true, Metrics: convertTaskMetrics(metrics)}
+		metrics := convertTaskMetrics(s.tasks.MetricsSnapshot())
+		metrics.Agents = s.agentMetrics()
+		return ipc.Response{Success: true, Metrics: metrics}
 	case ipc.RequestGetSecret:
-		return s.getSecret(req.SecretName)
+		return s.getSecret(req.SecretName, req.AgentName)
 	case ipc.RequestSetSecret:
 		return s.setSecret(req.SecretName, req.SecretValue, req.Mode)
+	case ipc.RequestSecretRotated:
+		name := strings.TrimSpace(req.SecretName)
+		if name == "" {
+			return ipc.NewErrorResponse(ipc.ErrCodeValidation, "secret name is required")
+		}
+		return ipc.Response{Success: true, Notified: s.notifySecretRotated(name)}
 	case ipc.RequestDeleteSecret:
 		return s.deleteSecret(req.SecretName)
 	case ipc.RequestListSecrets:
@@ -793,13 +1258,21 @@ func (s *Server) processRequest(req ipc.Request) ipc.Response {
 	case ipc.RequestGetAgentConfig:
 		ag, err := s.manager.GetAgent(req.AgentName)
 		if err != nil {
-			return ipc.Response{Success: false, Error: err.Error()}
+			return ipc.ErrorResponse(err)
 		}
 		return ipc.Response{Success: true, ProcessRoot: ag.Config.ProcessRoot}
 	case ipc.RequestBootstrapAgent:
 		return s.bootstrapAgent(req)
 	case ipc.RequestDeleteAgent:
 		return s.deleteAgent(req)
+	case ipc.RequestSpawnSessionAgent:
+		return s.spawnSessionAgent(req)
+	case ipc.RequestEndSession:
+		return s.endSession(req)
+	case ipc.RequestBudgetCharge:
+		return s.chargeBudget(ctx, req)
+	case ipc.RequestBudgetStatus:
+		return s.budgetStatus(ctx, req)
 	case ipc.RequestReceiveAgent:
 		return s.receiveAgent(req)
 	case ipc.RequestPackageAgent:
@@ -810,6 +1283,21 @@ func (s *Server) processRequest(req ipc.Request) ipc.Response {
 		}
 		return ipc.Response{Success: true}
 
+	case ipc.RequestResourceUsage:
+		return s.resourceReport()
+
+	case ipc.RequestDaemonStats:
+		return s.daemonStats()
+
+	case ipc.RequestHello:
+		return s.hello(req)
+
+	case ipc.RequestSelfUpdate:
+		return s.selfUpdate(req.UpdateChannel)
+
+	case ipc.RequestBackupNow:
+		return s.backupNow()
+
 	case ipc.RequestGetInvocationDir:
 		s.invocationDirMutex.RLock()
 		invocationDir := s.lastInvocationDir
@@ -817,80 +1305,88 @@ func (s *Server) processRequest(req ipc.Request) ipc.Response {
 		return ipc.Response{Success: true, InvocationDir: invocationDir}
 
 	default:
-		return ipc.Response{Success: false, Error: fmt.Sprintf("unknown request type: %q", req.Type)}
+		return ipc.NewErrorResponse(ipc.ErrCodeValidation, fmt.Sprintf("unknown request type: %q", req.Type))
 	}
 }
 
-func (s *Server) getSecret(name string) ipc.Response {
+// getSecret reads a secret for agentName, which is empty when the request
+// does not come from an agent.
+func (s *Server) getSecret(name, agentName string) ipc.Response {
 	trimmed := strings.TrimSpace(name)
 	if trimmed == "" {
-		return ipc.Response{Success: false, Error: "secret name is required"}
+		return ipc.NewErrorResponse(ipc.ErrCodeValidation, "secret name is required")
 	}
 	value, err := credentials.GetSecret(trimmed)
 	if err != nil {
 		if errors.Is(err, credentials.ErrNotFound) {
-			return ipc.Response{Success: false, Error: fmt.Sprintf("secret %q not found", trimmed)}
+			return ipc.NewErrorResponse(ipc.ErrCodeNotFound, fmt.Sprintf("secret %q not found", trimmed))
 		}
-		return ipc.Response{Success: false, Error: err.Error()}
+		return ipc.ErrorResponse(err)
 	}
 	if err := credentials.RegisterSecret(trimmed); err != nil {
-		return ipc.Response{Success: false, Error: err.Error()}
+		return ipc.ErrorResponse(err)
 	}
+	s.secretUsers.record(trimmed, agentName)
 	return ipc.Response{Success: true, Secret: value}
 }
 
 func (s *Server) setSecret(name, value, mode string) ipc.Response {
 	trimmedName := strings.TrimSpace(name)
 	if trimmedName == "" {
-		return ipc.Response{Success: false, Error: "secret name is required"}
+		return ipc.NewErrorResponse(ipc.ErrCodeValidation, "secret name is required")
 	}
 
 	trimmedValue := strings.TrimSpace(value)
 	if trimmedValue == "" {
-		return ipc.Response{Success: false, Error: "secret value is required"}
+		return ipc.NewErrorResponse(ipc.ErrCodeValidation, "secret value is required")
 	}
 
 	exists, err := credentials.HasSecret(trimmedName)
 	if err != nil {
-		return ipc.Response{Success: false, Error: err.Error()}
+		return ipc.ErrorResponse(err)
 	}
 
 	switch strings.ToLower(strings.TrimSpace(mode)) {
 	case "", "upsert", "update", "create":
 		// Allow create/update via mode but preserve CLI semantics when possible.
 		if strings.EqualFold(strings.TrimSpace(mode), "create") && exists {
-			return ipc.Response{Success: false, Error: fmt.Sprintf("secret %q already exists", trimmedName)}
+			return ipc.NewErrorResponse(ipc.ErrCodeValidation, fmt.Sprintf("secret %q already exists", trimmedName))
 		}
 		if strings.EqualFold(strings.TrimSpace(mode), "update") && !exists {
-			return ipc.Response{Success: false, Error: fmt.Sprintf("secret %q is not stored", trimmedName)}
+			return ipc.NewErrorResponse(ipc.ErrCodeNotFound, fmt.Sprintf("secret %q is not stored", trimmedName))
 		}
 	default:
-		return ipc.Response{Success: false, Error: fmt.Sprintf("unsupported secret mode %q", mode)}
+		return ipc.NewErrorResponse(ipc.ErrCodeValidation, fmt.Sprintf("unsupported secret mode %q", mode))
 	}
 
 	if err := credentials.SetSecret(trimmedName, trimmedValue); err != nil {
-		return ipc.Response{Success: false, Error: err.Error()}
+		return ipc.ErrorResponse(err)
 	}
 	if err := credentials.RegisterSecret(trimmedName); err != nil {
-		return ipc.Response{Success: false, Error: err.Error()}
+		return ipc.ErrorResponse(err)
 	}
 
-	return ipc.Response{Success: true}
+	// Agents holding the old value of a replaced secret get to refresh it
+	var notified []string
+	if exists {
+		notified = s.notifySecretRotated(trimmedName)
+	}
+	return ipc.Response{Success: true, Notified: notified}
 }
 
 func (s *Server) deleteSecret(name string) ipc.Response {
 	trimmedName := strings.TrimSpace(name)
 	if trimmedName == "" {
-		return ipc.Response{Success: false, Error: "secret name is required"}
+		return ipc.NewErrorResponse(ipc.ErrCodeValidation, "secret name is required")
 	}
 	if err := credentials.DeleteSecret(trimmedName); err != nil {
 		if errors.Is(err, credentials.ErrNotFound) {
-			return ipc.Response{Success: false, Error: fmt.Sprintf("secret %q not found", trimmedName)}
+			return ipc.NewErrorResponse(ipc.ErrCodeNotFound, fmt.Sprintf("secret %q not found", trimmedName))
 		}
-		return ipc.Response{Success: false, Error: err.Error()}
+		return ipc.ErrorResponse(err)
 	}
 	if err := credentials.UnregisterSecret(trimmedName); err != nil {
-		return ipc.Response{Success: false, Error: err.Error()}
+		return ipc.ErrorResponse(err)
 	}
 	return ipc.Response{Success: true}
 }
@@ -898,7 +1394,7 @@ func (s *Server) deleteSecret(name string) ipc.Response {
 func (s *Server) listSecrets() ipc.Response {
 	names, err := credentials.ListSecrets()
 	if err != nil {
-		return ipc.Response{Success: false, Error: err.Error()}
+		return ipc.ErrorResponse(err)
 	}
 	return ipc.Response{Success: true, Secrets: names}
 }
@@ -906,7 +1402,7 @@ func (s *Server) listSecrets() ipc.Response {
 func (s *Server) bootstrapAgent(req ipc.Request) ipc.Response {
 	agentName := strings.TrimSpace(req.AgentName)
 	if agentName == "" {
-		return ipc.Response{Success: false, Error: "agent name is required"}
+		return ipc.NewErrorResponse(ipc.ErrCodeValidation, "agent name is required")
 	}
 
 	// Prepare parameters for bootstrap
@@ -919,7 +1415,7 @@ func (s *Server) bootstrapAgent(req ipc.Request) ipc.Response {
 
 	paramsJSON, err := json.Marshal(params)
 	if err != nil {
-		return ipc.Response{Success: false, Error: fmt.Sprintf("failed to prepare parameters: %v", err)}
+		return ipc.NewErrorResponse(ipc.ErrCodeInternal, fmt.Sprintf("failed to prepare parameters: %v", err))
 	}
 
 	// Call the bootstrap function
@@ -928,7 +1424,7 @@ func (s *Server) bootstrapAgent(req ipc.Request) ipc.Response {
 
 	// Check for errors in the result
 	if strings.HasPrefix(result, "Error:") {
-		return ipc.Response{Success: false, Error: strings.TrimPrefix(result, "Error: ")}
+		return ipc.NewErrorResponse(ipc.ErrCodeInternal, strings.TrimPrefix(result, "Error: "))
 	}
 
 	// If NoStart flag is set, stop the agent that was auto-started
@@ -944,7 +1440,7 @@ func (s *Server) bootstrapAgent(req ipc.Request) ipc.Response {
 func (s *Server) deleteAgent(req ipc.Request) ipc.Response {
 	agentName := strings.TrimSpace(req.AgentName)
 	if agentName == "" {
-		return ipc.Response{Success: false, Error: "agent name is required"}
+		return ipc.NewErrorResponse(ipc.ErrCodeValidation, "agent name is required")
 	}
 
 	log.Printf("Starting deletion of agent: %s", agentName)
@@ -952,19 +1448,28 @@ func (s *Server) deleteAgent(req ipc.Request) ipc.Response {
 	// Get agent to check if it exists and get its directory
 	ag, err := s.manager.GetAgent(agentName)
 	if err != nil {
-		return ipc.Response{Success: false, Error: fmt.Sprintf("agent not found: %v", err)}
+		return ipc.NewErrorResponse(ipc.ErrCodeNotFound, fmt.Sprintf("agent not found: %v", err))
+	}
+
+	// Session agents run in their template's directory, which must survive
+	if ag.Config.Session != "" {
+		if _, err := s.removeSessionAgents(func(a agent.AgentConfig) bool { return a.Name == agentName }); err != nil {
+			return ipc.ErrorResponse(err)
+		}
+		log.Printf("Successfully deleted session agent: %s", agentName)
+		return ipc.Response{Success: true}
 	}
 
 	// Get the agent's process root for directory deletion
 	processRoot := ag.Config.ProcessRoot
 	configDir, err := config.GetConfigDir()
 	if err != nil {
-		return ipc.Response{Success: false, Error: fmt.Sprintf("failed to get config directory: %v", err)}
+		return ipc.NewErrorResponse(ipc.ErrCodeInternal, fmt.Sprintf("failed to get config directory: %v", err))
 	}
 
 	// Safety check: processRoot must be non-empty and specific
 	if processRoot == "" {
-		return ipc.Response{Success: false, Error: "agent has empty process_root - refusing to delete"}
+		return ipc.NewErrorResponse(ipc.ErrCodeValidation, "agent has empty process_root - refusing to delete")
 	}
 
 	// Clean the path to normalize it
@@ -972,14 +1477,14 @@ func (s *Server) deleteAgent(req ipc.Request) ipc.Response {
 
 	// Safety check: processRoot must not be a parent directory like "agents", ".", "..", or "./"
 	if cleanedRoot == "agents" || cleanedRoot == "." || cleanedRoot == ".." || processRoot == "./" || processRoot == "../" {
-		return ipc.Response{Success: false, Error: fmt.Sprintf("agent has unsafe process_root '%s' - refusing to delete", processRoot)}
+		return ipc.NewErrorResponse(ipc.ErrCodeValidation, fmt.Sprintf("agent has unsafe process_root '%s' - refusing to delete", processRoot))
 	}
 
 	agentDir := filepath.Join(configDir, processRoot)
 
 	// Final safety check: agentDir must contain the agent name
 	if !strings.Contains(agentDir, agentName) {
-		return ipc.Response{Success: false, Error: fmt.Sprintf("agent directory '%s' does not contain agent name '%s' - refusing to delete for safety", agentDir, agentName)}
+		return ipc.NewErrorResponse(ipc.ErrCodeValidation, fmt.Sprintf("agent directory '%s' does not contain agent name '%s' - refusing to delete for safety", agentDir, agentName))
 	}
 
 	log.Printf("Agent directory to delete: %s", agentDir)
@@ -1008,18 +1513,18 @@ func (s *Server) deleteAgent(req ipc.Request) ipc.Response {
 	log.Printf("Removing agent %s from configuration", agentName)
 	configFile, err := config.GetConfigFile()
 	if err != nil {
-		return ipc.Response{Success: false, Error: fmt.Sprintf("failed to get config file: %v", err)}
+		return ipc.NewErrorResponse(ipc.ErrCodeInternal, fmt.Sprintf("failed to get config file: %v", err))
 	}
 
 	// Load config using proper loader
 	agentsConfig, err := agent.LoadConfig(configFile)
 	if err != nil {
-		return ipc.Response{Success: false, Error: fmt.Sprintf("failed to load config: %v", err)}
+		return ipc.NewErrorResponse(ipc.ErrCodeInternal, fmt.Sprintf("failed to load config: %v", err))
 	}
 
 	// Verify we have agents before proceeding (safety check)
 	if len(agentsConfig.Agents) == 0 {
-		return ipc.Response{Success: false, Error: "config has no agents - refusing to delete to prevent data loss"}
+		return ipc.NewErrorResponse(ipc.ErrCodeValidation, "config has no agents - refusing to delete to prevent data loss")
 	}
 
 	// Find the agent to ensure it exists
@@ -1031,7 +1536,7 @@ func (s *Server) deleteAgent(req ipc.Request) ipc.Response {
 		}
 	}
 	if !agentFound {
-		return ipc.Response{Success: false, Error: fmt.Sprintf("agent '%s' not found in config", agentName)}
+		return ipc.NewErrorResponse(ipc.ErrCodeNotFound, fmt.Sprintf("agent '%s' not found in config", agentName))
 	}
 
 	// Filter out the agent to delete
@@ -1043,29 +1548,9 @@ func (s *Server) deleteAgent(req ipc.Request) ipc.Response {
 	}
 	agentsConfig.Agents = newAgents
 
-	// Read the raw config to preserve structure
-	data, err := os.ReadFile(configFile)
-	if err != nil {
-		return ipc.Response{Success: false, Error: fmt.Sprintf("failed to read config file: %v", err)}
-	}
-
-	// Parse as generic map to preserve all fields
-	var rawConfig map[string]interface{}
-	if err := yaml.Unmarshal(data, &rawConfig); err != nil {
-		return ipc.Response{Success: false, Error: fmt.Sprintf("failed to unmarshal config: %v", err)}
-	}
-
-	// Update only the agents array, preserving other fields
-	rawConfig["agents"] = agentsConfig.Agents
-
-	// Write back the config
-	newData, err := yaml.Marshal(rawConfig)
-	if err != nil {
-		return ipc.Response{Success: false, Error: fmt.Sprintf("failed to marshal config: %v", err)}
-	}
-
-	if err := os.WriteFile(configFile, newData, 0644); err != nil {
-		return ipc.Response{Success: false, Error: fmt.Sprintf("failed to write config: %v", err)}
+	// Write back the config, preserving its other fields
+	if err := agent.SaveConfig(configFile, agentsConfig); err != nil {
+		return ipc.NewErrorResponse(ipc.ErrCodeInternal, err.Error())
 	}
 
 	// Step 4: Reload configuration to refresh the manager (this picks up agents.yaml changes)
@@ -1091,10 +1576,20 @@ func (s *Server) deleteAgent(req ipc.Request) ipc.Response {
 			if _, err := s.manager.GetDB().ExecContext(ctx, `DELETE FROM agent_logs WHERE agent_name = ?`, agentName); err != nil {
 				log.Printf("Warning: failed to delete database logs for agent %s: %v", agentName, err)
 			}
+			if _, err := s.manager.GetDB().ExecContext(ctx, `DELETE FROM agent_crashes WHERE agent_name = ?`, agentName); err != nil {
+				log.Printf("Warning: failed to delete crash reports for agent %s: %v", agentName, err)
+			}
+			if _, err := s.manager.GetDB().ExecContext(ctx, `DELETE FROM agent_prompt_history WHERE agent_name = ?`, agentName); err != nil {
+				log.Printf("Warning: failed to delete prompt history for agent %s: %v", agentName, err)
+			}
 		}
 
 		// Delete agent log file from disk
-		logFile := filepath.Join(configDir, "logs", fmt.Sprintf("%s.log", agentName))
+		logsDir, err := config.GetLogsDir()
+		if err != nil {
+			logsDir = filepath.Join(configDir, "logs")
+		}
+		logFile := filepath.Join(logsDir, fmt.Sprintf("%s.log", agentName))
 		log.Printf("Deleting log file: %s", logFile)
 		if err := os.Remove(logFile); err != nil && !os.IsNotExist(err) {
 			log.Printf("Warning: failed to delete log file %s: %v", logFile, err)
@@ -1115,7 +1610,7 @@ func (s *Server) deleteAgent(req ipc.Request) ipc.Response {
 
 func (s *Server) receiveAgent(req ipc.Request) ipc.Response {
 	if req.AgentPackage == nil {
-		return ipc.Response{Success: false, Error: "agent package is required"}
+		return ipc.NewErrorResponse(ipc.ErrCodeValidation, "agent package is required")
 	}
 
 	pkg := req.AgentPackage
@@ -1126,13 +1621,13 @@ func (s *Server) receiveAgent(req ipc.Request) ipc.Response {
 	// Get config file path
 	configFile, err := config.GetConfigFile()
 	if err != nil {
-		return ipc.Response{Success: false, Error: fmt.Sprintf("failed to get config file: %v", err)}
+		return ipc.NewErrorResponse(ipc.ErrCodeInternal, fmt.Sprintf("failed to get config file: %v", err))
 	}
 
 	// Check if agent already exists
 	if _, err := s.manager.GetAgent(agentName); err == nil {
 		if !req.Force {
-			return ipc.Response{Success: false, Error: fmt.Sprintf("agent '%s' already exists (use --force to overwrite)", agentName)}
+			return ipc.NewErrorResponse(ipc.ErrCodeValidation, fmt.Sprintf("agent '%s' already exists (use --force to overwrite)", agentName))
 		}
 
 		// Overwrite existing agent
@@ -1145,18 +1640,24 @@ func (s *Server) receiveAgent(req ipc.Request) ipc.Response {
 
 		// Use overwrite function
 		if err := agent.OverwriteAgent(pkg, configFile); err != nil {
-			return ipc.Response{Success: false, Error: fmt.Sprintf("failed to overwrite agent: %v", err)}
+			return ipc.NewErrorResponse(ipc.ErrCodeInternal, fmt.Sprintf("failed to overwrite agent: %v", err))
 		}
 	} else {
 		// Add new agent
 		if err := agent.UnpackageAgent(pkg, configFile); err != nil {
-			return ipc.Response{Success: false, Error: fmt.Sprintf("failed to unpackage agent: %v", err)}
+			return ipc.NewErrorResponse(ipc.ErrCodeInternal, fmt.Sprintf("failed to unpackage agent: %v", err))
+		}
+	}
+
+	if len(pkg.KV) > 0 {
+		if err := s.kv.Import(context.Background(), agentName, pkg.KV); err != nil {
+			return ipc.ErrorResponse(err)
 		}
 	}
 
 	// Reload agent manager to pick up the new agent
 	if err := s.manager.ReloadConfig(); err != nil {
-		return ipc.Response{Success: false, Error: fmt.Sprintf("failed to reload config: %v", err)}
+		return ipc.NewErrorResponse(ipc.ErrCodeInternal, fmt.Sprintf("failed to reload config: %v", err))
 	}
 
 	// Start agent if requested and it was running before
@@ -1174,7 +1675,7 @@ func (s *Server) receiveAgent(req ipc.Request) ipc.Response {
 func (s *Server) packageAgent(req ipc.Request) ipc.Response {
 	agentName := strings.TrimSpace(req.AgentName)
 	if agentName == "" {
-		return ipc.Response{Success: false, Error: "agent name is required"}
+		return ipc.NewErrorResponse(ipc.ErrCodeValidation, "agent name is required")
 	}
 
 	log.Printf("Packaging agent: %s", agentName)
@@ -1182,7 +1683,7 @@ func (s *Server) packageAgent(req ipc.Request) ipc.Response {
 	// Get agent to check if it exists
 	ag, err := s.manager.GetAgent(agentName)
 	if err != nil {
-		return ipc.Response{Success: false, Error: fmt.Sprintf("agent not found: %v", err)}
+		return ipc.NewErrorResponse(ipc.ErrCodeNotFound, fmt.Sprintf("agent not found: %v", err))
 	}
 
 	// Check if agent is running
@@ -1191,13 +1692,16 @@ func (s *Server) packageAgent(req ipc.Request) ipc.Response {
 	// Get config file path
 	configFile, err := config.GetConfigFile()
 	if err != nil {
-		return ipc.Response{Success: false, Error: fmt.Sprintf("failed to get config file: %v", err)}
+		return ipc.NewErrorResponse(ipc.ErrCodeInternal, fmt.Sprintf("failed to get config file: %v", err))
 	}
 
 	// Package the agent
 	pkg, err := agent.PackageAgent(agentName, configFile, wasRunning)
 	if err != nil {
-		return ipc.Response{Success: false, Error: fmt.Sprintf("failed to package agent: %v", err)}
+		return ipc.NewErrorResponse(ipc.ErrCodeInternal, fmt.Sprintf("failed to package agent: %v", err))
+	}
+	if pkg.KV, err = s.kv.List(context.Background(), agentName, ""); err != nil {
+		return ipc.ErrorResponse(err)
 	}
 
 	log.Printf("Successfully packaged agent: %s", agentName)
@@ -1225,6 +1729,10 @@ func (s *Server) listAgents() ipc.Response {
 			SystemPrompt:        a.SystemPrompt(),
 			SystemPromptReplace: a.SystemPromptReplace(),
 			Color:               a.Color(),
+			LastExit:            a.LastExit(),
+			Capabilities:        a.Capabilities(),
+			Session:             a.Config.Session,
+			Paused:              a.Paused(),
 		}
 	}
 
@@ -1235,12 +1743,26 @@ func (s *Server) listAgents() ipc.Response {
 
 func (s *Server) Stop() {
 	log.Printf("=== Daemon stopping ===")
+	if s.stopMonitor != nil {
+		close(s.stopMonitor)
+		s.stopMonitor = nil
+	}
+	// Stop chat channels first so in-flight exchanges are cancelled rather
+	// than failing on stopped agents; unseen mail is picked up after restart
+	if s.channels != nil {
+		s.channels.Stop()
+	}
+	s.stopDashboards()
+	s.stopGitWebhook()
 	// Snapshot running agents to support auto-restart on next start
 	s.manager.SnapshotRunningAgents()
 	// Stop agents while preserving state
 	s.manager.StopAllPreservingState()
 	// Cleanup scheduler, watchers, etc.
 	s.manager.Cleanup()
+	if s.triggers != nil {
+		s.triggers.Stop()
+	}
 	if s.tasks != nil {
 		s.tasks.Shutdown()
 	}
@@ -1250,6 +1772,18 @@ func (s *Server) Stop() {
 	if s.taskBroker != nil {
 		s.taskBroker.Shutdown()
 	}
+	// Give pending notifications (e.g. a final task failure) a chance to go out
+	notifyCtx, cancelNotify := context.WithTimeout(context.Background(), 5*time.Second)
+	s.notifier.Wait(notifyCtx)
+	s.hooks.Wait(notifyCtx)
+	cancelNotify()
+	if s.logShipper != nil {
+		agent.SetLogSink(nil)
+		shipCtx, cancelShip := context.WithTimeout(context.Background(), 5*time.Second)
+		s.logShipper.Close(shipCtx)
+		cancelShip()
+	}
+	s.tokens.flush()
 	if s.db != nil {
 		_ = s.db.Close()
 		s.db = nil
@@ -1343,11 +1877,40 @@ func (s *Server) publishStateChange(agentName string, changeType string, data in
 		} else {
 			log.Printf("[StateChange] WARNING: commands data is not []protocol.CommandDescriptor, got type %T", data)
 		}
+	case "capabilities":
+		change.Type = AgentStateCapabilities
+		if caps, ok := data.(protocol.Capabilities); ok {
+			change.Capabilities = &caps
+			log.Printf("[StateChange] Publishing capabilities for agent %s: protocol v%d %v", agentName, caps.ProtocolVersion, caps.List())
+		} else {
+			log.Printf("[StateChange] WARNING: capabilities data is not protocol.Capabilities, got type %T", data)
+		}
+	case "triggers":
+		if triggers, ok := data.([]protocol.TriggerDescriptor); ok && s.triggers != nil {
+			s.setAgentTriggers(agentName, triggers)
+		}
+		return
 	case "status":
 		change.Type = AgentStateStatus
-		if status, ok := data.(string); ok {
+		switch status := data.(type) {
+		case string:
 			change.Status = status
 			log.Printf("[StateChange] Publishing status change for agent %s: %s", agentName, status)
+			if status == string(agent.StatusRunning) {
+				s.hooks.Run(hooks.Event{Event: config.HookAgentStarted, Agent: agentName})
+			}
+		case agent.StatusUpdate:
+			change.Status = string(status.Status)
+			change.LastExit = status.LastExit
+			log.Printf("[StateChange] Publishing status change for agent %s: %s (last exit: %s)", agentName, status.Status, status.LastExit)
+			if status.Status == agent.StatusCrashLooping {
+				go s.notifyCrashLoop(agentName, status)
+			}
+			s.runCrashHooks(agentName, status)
+		}
+		// Agents re-register their triggers when they start again.
+		if change.Status != string(agent.StatusRunning) && s.triggers != nil {
+			s.triggers.Replace(agentTriggerOwner(agentName), nil)
 		}
 	default:
 		log.Printf("[StateChange] WARNING: Unknown change type %s for agent %s", changeType, agentName)
@@ -1372,7 +1935,9 @@ func convertAgentStateEvent(ev AgentStateChange) ipc.AgentStateEvent {
 		LogEntry:            ev.LogEntry,
 		CustomSections:      ev.CustomSections,
 		Status:              ev.Status,
+		LastExit:            ev.LastExit,
 		Commands:            ev.Commands,
+		Capabilities:        ev.Capabilities,
 	}
 }
 
@@ -1393,8 +1958,40 @@ func convertTaskEvent(ev taskqueue.TaskEvent) ipc.ToolTaskEvent {
 	return result
 }
 
+// taskQueueOptions maps the tasks section of daemon.yaml onto queue options,
+// leaving unset values at the queue's defaults.
+func taskQueueOptions(cfg config.TasksConfig) *taskqueue.ManagerOptions {
+	opts := &taskqueue.ManagerOptions{
+		WorkerCount:          cfg.Workers,
+		MaxPendingPerSession: -1,
+		MaxPendingPerClient:  cfg.MaxPendingPerClient,
+		MaxWorkers:           cfg.MaxWorkers,
+		ScaleUpDepth:         cfg.ScaleUpDepth,
+	}
+	// Validated when daemon.yaml is loaded
+	opts.ScaleUpAfter, _ = time.ParseDuration(cfg.ScaleUpAfter)
+	opts.ScaleDownAfter, _ = time.ParseDuration(cfg.ScaleDownAfter)
+	if cfg.MaxPendingPerSession > 0 {
+		opts.MaxPendingPerSession = cfg.MaxPendingPerSession
+	}
+	if len(cfg.Origins) > 0 {
+		opts.Origins = make(map[string]taskqueue.OriginPolicy, len(cfg.Origins))
+		for origin, q := range cfg.Origins {
+			policy := taskqueue.OriginPolicy{Weight: q.Weight, MaxPending: -1, MaxRunning: -1}
+			if q.MaxPending != nil {
+				policy.MaxPending = *q.MaxPending
+			}
+			if q.MaxRunning != nil {
+				policy.MaxRunning = *q.MaxRunning
+			}
+			opts.Origins[origin] = policy
+		}
+	}
+	return opts
+}
+
 func convertTaskMetrics(snapshot taskqueue.MetricsSnapshot) *ipc.ToolTaskMetrics {
-	return &ipc.ToolTaskMetrics{
+	metrics := &ipc.ToolTaskMetrics{
 		Submitted:   snapshot.Submitted,
 		InFlight:    snapshot.InFlight,
 		Completed:   snapshot.Completed,
@@ -1402,6 +1999,13 @@ func convertTaskMetrics(snapshot taskqueue.MetricsSnapshot) *ipc.ToolTaskMetrics
 		QueueDepth:  snapshot.QueueDepth,
 		WorkerCount: snapshot.WorkerCount,
 	}
+	if len(snapshot.Origins) > 0 {
+		metrics.Origins = make(map[string]ipc.ToolTaskOriginMetrics, len(snapshot.Origins))
+		for origin, stats := range snapshot.Origins {
+			metrics.Origins[origin] = ipc.ToolTaskOriginMetrics{Queued: int64(stats.Queued), Running: int64(stats.Running)}
+		}
+	}
+	return metrics
 }
 
 func convertTask(task *taskqueue.Task) *ipc.ToolTask {
@@ -1421,12 +2025,16 @@ func convertTask(task *taskqueue.Task) *ipc.ToolTask {
 		CommandArgs: task.CommandArgs,
 		Origin:      task.Origin,
 		ClientID:    task.ClientID,
+		Owner:       task.Owner,
 		Status:      string(task.Status),
 		Result:      task.Result,
 		Metadata:    task.Metadata,
 		Error:       task.Error,
 		CreatedAt:   task.CreatedAt.Format(time.RFC3339Nano),
 		UpdatedAt:   task.UpdatedAt.Format(time.RFC3339Nano),
+		DependsOn:   task.DependsOn,
+		Attempts:    task.Attempts,
+		Execution:   task.Execution,
 	}
 	if task.CompletedAt != nil {
 		converted.CompletedAt = task.CompletedAt.Format(time.RFC3339Nano)
@@ -1458,20 +2066,23 @@ func (s *Server) startPreviouslyRunningAgents() {
 
 	previouslyRunning := s.manager.GetPreviouslyRunningAgents()
 
+	var toStart []string
 	for _, agentName := range previouslyRunning {
-		if autoStart, exists := agentConfigs[agentName]; !exists || !autoStart {
-			continue
+		if autoStart, exists := agentConfigs[agentName]; exists && autoStart {
+			toStart = append(toStart, agentName)
 		}
+	}
 
-		// Start the agent
-		if err := s.manager.StartAgent(agentName); err != nil {
-			// Log the error but continue with other agents
-			log.Printf("Failed to auto-start agent %s: %v", agentName, err)
-		} else {
-			log.Printf("Auto-started agent: %s", agentName)
-			// Send invocation directory to auto-started agent
-			s.sendInvocationDirToAgent(agentName)
-		}
+	// Dependencies start first, and dependents only once they are ready
+	started, failed := s.manager.StartAgents(toStart)
+	for agentName, err := range failed {
+		// Log the error but continue with other agents
+		log.Printf("Failed to auto-start agent %s: %v", agentName, err)
+	}
+	for _, agentName := range started {
+		log.Printf("Auto-started agent: %s", agentName)
+		// Send invocation directory to auto-started agent
+		s.sendInvocationDirToAgent(agentName)
 	}
 }
 
@@ -0,0 +1,199 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"opperator/config"
+	"opperator/internal/agent"
+	"opperator/internal/ipc"
+)
+
+// sessionAgentsMu serializes agents.yaml edits for session agents so two
+// spawns cannot pick the same name or drop each other's entry.
+var sessionAgentsMu sync.Mutex
+
+// spawnSessionAgent starts a temporary copy of the AgentName agent for the
+// SessionID conversation. The copy shares the template's process root and
+// command but gets its own name, logs and state, and is removed by
+// endSession.
+func (s *Server) spawnSessionAgent(req ipc.Request) ipc.Response {
+	templateName := strings.TrimSpace(req.AgentName)
+	sessionID := strings.TrimSpace(req.SessionID)
+	if templateName == "" || sessionID == "" {
+		return ipc.NewErrorResponse(ipc.ErrCodeValidation, "template agent and session are required")
+	}
+	template, err := s.manager.GetAgent(templateName)
+	if err != nil {
+		return ipc.NewErrorResponse(ipc.ErrCodeNotFound, fmt.Sprintf("agent not found: %v", err))
+	}
+	if template.Config.Session != "" {
+		return ipc.NewErrorResponse(ipc.ErrCodeValidation, fmt.Sprintf("agent %q is itself a session agent", templateName))
+	}
+
+	sessionAgentsMu.Lock()
+	name, err := s.addSessionAgent(template.Config, sessionID, req.Description)
+	sessionAgentsMu.Unlock()
+	if err != nil {
+		return ipc.ErrorResponse(err)
+	}
+	log.Printf("[Session] Spawned %s from %s for session %s", name, templateName, sessionID)
+
+	if err := s.manager.StartAgent(name); err != nil {
+		return ipc.NewErrorResponse(ipc.ErrCodeInternal, fmt.Sprintf("session agent %s created but failed to start: %v", name, err))
+	}
+	return ipc.Response{Success: true, SessionAgents: []string{name}}
+}
+
+func (s *Server) addSessionAgent(template agent.AgentConfig, sessionID, description string) (string, error) {
+	configFile, err := config.GetConfigFile()
+	if err != nil {
+		return "", err
+	}
+	agentsConfig, err := agent.LoadConfig(configFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to load config: %w", err)
+	}
+
+	taken := make(map[string]bool, len(agentsConfig.Agents))
+	for _, a := range agentsConfig.Agents {
+		taken[a.Name] = true
+	}
+	base := template.Name + "-s" + sessionSuffix(sessionID)
+	name := base
+	for i := 2; taken[name]; i++ {
+		name = fmt.Sprintf("%s-%d", base, i)
+	}
+
+	cfg := template
+	cfg.Name = name
+	cfg.Session = sessionID
+	cfg.StartWithDaemon = nil
+	if description = strings.TrimSpace(description); description != "" {
+		cfg.Description = description
+	}
+	agentsConfig.Agents = append(agentsConfig.Agents, cfg)
+	if err := agent.SaveConfig(configFile, agentsConfig); err != nil {
+		return "", err
+	}
+	if err := s.manager.ReloadConfigManual(); err != nil {
+		return "", fmt.Errorf("failed to reload config: %w", err)
+	}
+	return name, nil
+}
+
+// endSession removes the agents spawned for a conversation.
+func (s *Server) endSession(req ipc.Request) ipc.Response {
+	sessionID := strings.TrimSpace(req.SessionID)
+	if sessionID == "" {
+		return ipc.NewErrorResponse(ipc.ErrCodeValidation, "session is required")
+	}
+	removed, err := s.removeSessionAgents(func(a agent.AgentConfig) bool { return a.Session == sessionID })
+	if err != nil {
+		return ipc.ErrorResponse(err)
+	}
+	if len(removed) > 0 {
+		log.Printf("[Session] Removed %d agent(s) of session %s: %s", len(removed), sessionID, strings.Join(removed, ", "))
+	}
+	return ipc.Response{Success: true, SessionAgents: removed}
+}
+
+// removeSessionAgents stops the session agents match selects, drops them
+// from agents.yaml and deletes their tasks, logs and persisted state. Their
+// process root belongs to the template and is kept.
+func (s *Server) removeSessionAgents(match func(agent.AgentConfig) bool) ([]string, error) {
+	sessionAgentsMu.Lock()
+	defer sessionAgentsMu.Unlock()
+
+	configFile, err := config.GetConfigFile()
+	if err != nil {
+		return nil, err
+	}
+	agentsConfig, err := agent.LoadConfig(configFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	var removed []string
+	kept := make([]agent.AgentConfig, 0, len(agentsConfig.Agents))
+	for _, a := range agentsConfig.Agents {
+		if a.Session != "" && match(a) {
+			removed = append(removed, a.Name)
+			continue
+		}
+		kept = append(kept, a)
+	}
+	if len(removed) == 0 {
+		return nil, nil
+	}
+
+	for _, name := range removed {
+		if ag, err := s.manager.GetAgent(name); err == nil && ag.GetStatus() == agent.StatusRunning {
+			if err := s.manager.StopAgent(name); err != nil {
+				log.Printf("[Session] Warning: failed to stop %s: %v", name, err)
+			}
+		}
+	}
+	agentsConfig.Agents = kept
+	if err := agent.SaveConfig(configFile, agentsConfig); err != nil {
+		return nil, err
+	}
+	if err := s.manager.ReloadConfigManual(); err != nil {
+		log.Printf("[Session] Warning: failed to reload config: %v", err)
+	}
+	for _, name := range removed {
+		s.deleteSessionAgentData(name)
+	}
+	return removed, nil
+}
+
+func (s *Server) deleteSessionAgentData(name string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if s.tasks != nil {
+		if _, err := s.tasks.DeleteTasksByAgent(ctx, name); err != nil {
+			log.Printf("[Session] Warning: failed to delete tasks of %s: %v", name, err)
+		}
+	}
+	if err := s.manager.DeleteAgentPersistentData(name); err != nil {
+		log.Printf("[Session] Warning: failed to delete persistent data of %s: %v", name, err)
+	}
+	if db := s.manager.GetDB(); db != nil {
+		for _, table := range []string{"agent_logs", "agent_crashes", "agent_prompt_history"} {
+			if _, err := db.ExecContext(ctx, `DELETE FROM `+table+` WHERE agent_name = ?`, name); err != nil {
+				log.Printf("[Session] Warning: failed to delete %s of %s: %v", table, name, err)
+			}
+		}
+	}
+	if logsDir, err := config.GetLogsDir(); err == nil {
+		logFile := filepath.Join(logsDir, name+".log")
+		if err := os.Remove(logFile); err != nil && !os.IsNotExist(err) {
+			log.Printf("[Session] Warning: failed to delete log file %s: %v", logFile, err)
+		}
+	}
+}
+
+// sessionSuffix shortens a conversation ID for use in agent names.
+// Conversation IDs are nanosecond timestamps, so the tail is what differs.
+func sessionSuffix(sessionID string) string {
+	var b strings.Builder
+	for _, r := range sessionID {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	suffix := strings.ToLower(b.String())
+	if len(suffix) > 6 {
+		suffix = suffix[len(suffix)-6:]
+	}
+	if suffix == "" {
+		suffix = "x"
+	}
+	return suffix
+}
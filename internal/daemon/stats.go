@@ -0,0 +1,62 @@
+package daemon
+
+import (
+	"log"
+	"sort"
+	"time"
+
+	"opperator/internal/agent"
+	"opperator/internal/ipc"
+	"opperator/internal/taskqueue"
+	"opperator/version"
+)
+
+// statsWindow is the period 'op stats' reports finished tasks for.
+const statsWindow = 24 * time.Hour
+
+func (s *Server) daemonStats() ipc.Response {
+	stats := &ipc.DaemonStats{
+		Version:   version.Get(),
+		StartedAt: s.startedAt,
+		Agents:    make(map[agent.ProcessStatus]int),
+	}
+	for _, a := range s.manager.GetAllAgents() {
+		stats.Agents[a.GetStatus()]++
+	}
+
+	if s.tasks != nil {
+		stats.Tasks = *convertTaskMetrics(s.tasks.MetricsSnapshot())
+		since := time.Now().Add(-statsWindow)
+		for _, task := range s.tasks.List() {
+			if task.CompletedAt == nil || task.CompletedAt.Before(since) {
+				continue
+			}
+			switch task.Status {
+			case taskqueue.StatusComplete:
+				stats.Completed24h++
+			case taskqueue.StatusFailed:
+				stats.Failed24h++
+			}
+		}
+	}
+
+	return ipc.Response{Success: true, Stats: stats}
+}
+
+// agentMetrics collects the metrics every agent reported, by agent name.
+func (s *Server) agentMetrics() []ipc.AgentMetric {
+	agents := s.manager.GetAllAgents()
+	sort.Slice(agents, func(i, j int) bool { return agents[i].Config.Name < agents[j].Config.Name })
+	var out []ipc.AgentMetric
+	for _, a := range agents {
+		series, err := a.Metrics()
+		if err != nil {
+			log.Printf("[Metrics] failed to read metrics of %s: %v", a.Config.Name, err)
+			continue
+		}
+		for _, m := range series {
+			out = append(out, ipc.AgentMetric{Agent: a.Config.Name, Name: m.Name, Type: m.Type, Value: m.Value, Points: m.Points})
+		}
+	}
+	return out
+}
@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"strings"
 
+	"opperator/config"
 	"opperator/internal/agent"
+	"opperator/internal/policy"
 	"opperator/internal/protocol"
 	"opperator/internal/taskqueue"
 
@@ -15,13 +17,19 @@ import (
 
 // daemonToolRunner adapts the existing tool implementations for use within the
 // daemon-managed asynchronous task queue.
-type daemonToolRunner struct{}
+type daemonToolRunner struct {
+	policy *policy.Engine
+}
 
-func newDaemonToolRunner() *daemonToolRunner {
-	return &daemonToolRunner{}
+func newDaemonToolRunner(engine *policy.Engine) *daemonToolRunner {
+	return &daemonToolRunner{policy: engine}
 }
 
 func (r *daemonToolRunner) Execute(ctx context.Context, name, args, workingDir string) (string, string, error) {
+	in := taskPolicyInput(ctx, config.PolicyKindTool, "", name, decodePolicyArgs(args))
+	if err := checkPolicy(ctx, r.policy, in); err != nil {
+		return "", "", err
+	}
 	lower := strings.ToLower(strings.TrimSpace(name))
 	switch lower {
 	case tooling.ViewToolName:
@@ -76,10 +84,12 @@ func (r *daemonToolRunner) Execute(ctx context.Context, name, args, workingDir s
 
 type daemonAgentRunner struct {
 	manager *agent.Manager
+	policy  *policy.Engine
+	blobs   *blobStore
 }
 
-func newDaemonAgentRunner(manager *agent.Manager) *daemonAgentRunner {
-	return &daemonAgentRunner{manager: manager}
+func newDaemonAgentRunner(manager *agent.Manager, engine *policy.Engine, blobs *blobStore) *daemonAgentRunner {
+	return &daemonAgentRunner{manager: manager, policy: engine, blobs: blobs}
 }
 
 func (r *daemonAgentRunner) Execute(ctx context.Context, agentName, command, args, workingDir string, progress func(taskqueue.ProgressEvent)) (string, string, error) {
@@ -98,6 +108,14 @@ func (r *daemonAgentRunner) Execute(ctx context.Context, agentName, command, arg
 			return "", "", fmt.Errorf("decode command args: %w", err)
 		}
 	}
+	in := taskPolicyInput(ctx, config.PolicyKindCommand, agentName, command, parsed)
+	if err := checkPolicy(ctx, r.policy, in); err != nil {
+		return "", "", err
+	}
+	parsed, err := r.blobs.resolveArgs(parsed)
+	if err != nil {
+		return "", "", err
+	}
 
 	cb := func(msg protocol.CommandProgressMessage) {
 		if progress == nil {
@@ -116,10 +134,16 @@ func (r *daemonAgentRunner) Execute(ctx context.Context, agentName, command, arg
 		})
 	}
 
-	resp, err := r.manager.InvokeCommandAsync(agentName, command, parsed, workingDir, 0, cb)
+	resp, err := r.manager.InvokeCommandAsync(ctx, agentName, command, parsed, workingDir, 0, cb)
 	if err != nil {
 		return "", "", err
 	}
+	return commandTaskOutcome(agentName, command, resp)
+}
+
+// commandTaskOutcome turns an agent's reply to a command into the result and
+// metadata stored on its task, or the error the task failed with.
+func commandTaskOutcome(agentName, command string, resp *protocol.ResponseMessage) (string, string, error) {
 	if resp == nil {
 		return "", "", fmt.Errorf("agent returned no response")
 	}
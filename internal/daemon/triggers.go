@@ -0,0 +1,103 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"maps"
+	"time"
+
+	"opperator/config"
+	"opperator/internal/ipc"
+	"opperator/internal/protocol"
+	"opperator/internal/taskqueue"
+	"opperator/internal/trigger"
+)
+
+// triggerOrigin is the task origin of commands invoked by triggers.
+const triggerOrigin = "trigger"
+
+// agentTriggerOwner keeps agent-registered triggers apart from those in
+// daemon.yaml even when an agent is called "config".
+func agentTriggerOwner(agentName string) string {
+	return "agent:" + agentName
+}
+
+// invokeTrigger queues the trigger's command with the event as an async agent
+// task, so triggered work shares the worker pool and quotas with everything
+// else.
+func (s *Server) invokeTrigger(ctx context.Context, t trigger.Trigger, event map[string]any) error {
+	// Failing leaves queued events unacknowledged until the agent resumes
+	if a, err := s.manager.GetAgent(t.Agent); err == nil {
+		if err := a.PausedErr(); err != nil {
+			return err
+		}
+	}
+	args := make(map[string]any, len(t.Args)+2)
+	maps.Copy(args, t.Args)
+	args["trigger"] = t.Name
+	args["event"] = event
+	encoded, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+	_, err = s.tasks.Submit(ctx, taskqueue.SubmitRequest{
+		ToolName:    "trigger:" + t.Name,
+		Mode:        "agent",
+		AgentName:   t.Agent,
+		Command:     t.Command,
+		CommandArgs: string(encoded),
+		Origin:      triggerOrigin,
+		ClientID:    "trigger:" + t.Name,
+	})
+	return err
+}
+
+// startConfigTriggers starts the triggers declared in daemon.yaml, skipping
+// invalid ones so a typo does not keep the daemon from starting.
+func (s *Server) startConfigTriggers(configured []config.TriggerConfig) {
+	triggers := make([]trigger.Trigger, 0, len(configured))
+	for _, t := range configured {
+		if err := trigger.Validate(t.TriggerDescriptor); err != nil {
+			log.Printf("[Trigger] Skipping %q from daemon.yaml: %v", t.Name, err)
+			continue
+		}
+		triggers = append(triggers, trigger.Trigger{TriggerDescriptor: t.TriggerDescriptor, Agent: t.Agent})
+	}
+	s.triggers.Replace(trigger.OwnerConfig, triggers)
+}
+
+// setAgentTriggers replaces the triggers registered by an agent. They invoke
+// commands of that agent.
+func (s *Server) setAgentTriggers(agentName string, descriptors []protocol.TriggerDescriptor) {
+	triggers := make([]trigger.Trigger, 0, len(descriptors))
+	for _, d := range descriptors {
+		if err := trigger.Validate(d); err != nil {
+			log.Printf("[Trigger] Agent %s registered invalid trigger: %v", agentName, err)
+			continue
+		}
+		triggers = append(triggers, trigger.Trigger{TriggerDescriptor: d, Agent: agentName})
+	}
+	s.triggers.Replace(agentTriggerOwner(agentName), triggers)
+}
+
+func (s *Server) listTriggers() ipc.Response {
+	statuses := s.triggers.List()
+	infos := make([]ipc.TriggerInfo, 0, len(statuses))
+	for _, st := range statuses {
+		info := ipc.TriggerInfo{
+			Name:      st.Name,
+			Owner:     st.Owner,
+			Agent:     st.Agent,
+			Type:      st.Type,
+			Command:   st.Command,
+			Fired:     st.Fired,
+			LastError: st.LastError,
+		}
+		if !st.LastFired.IsZero() {
+			info.LastFired = st.LastFired.UTC().Format(time.RFC3339)
+		}
+		infos = append(infos, info)
+	}
+	return ipc.Response{Success: true, Triggers: infos}
+}
@@ -0,0 +1,115 @@
+package daemon
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"opperator/internal/ipc"
+	"opperator/internal/taskqueue"
+)
+
+// taskScope limits the tool tasks a connection sees when several people
+// share a daemon. Tasks belong to the user of the token that submitted them;
+// tasks without an owner come from local clients and the daemon itself
+// (triggers, chat channels) and are visible to everyone.
+type taskScope struct {
+	// user owns the tasks the connection submits; "" on the local socket
+	user string
+	// all lifts the filter: local connections, and admins passing AllUsers
+	all bool
+	// remote is set for TCP connections, whose claimed origins are limited
+	remote bool
+}
+
+// localScope is used on the unix socket, whose clients run on the daemon's
+// host and see every task as before.
+var localScope = taskScope{all: true}
+
+// tokenScope is the scope of a TCP request. authorize has already checked
+// that only admins set AllUsers.
+func tokenScope(tok authToken, req ipc.Request) taskScope {
+	return taskScope{user: tok.owner(), all: req.AllUsers, remote: true}
+}
+
+// remoteOrigin is the task origin of TCP requests that claim none of
+// remoteClientOrigins.
+const remoteOrigin = "remote"
+
+// remoteClientOrigins are the origins TCP clients may claim for themselves.
+var remoteClientOrigins = []string{"cli", "tui"}
+
+// origin returns the task origin of a request claiming claimed, or fallback
+// when it claims none. Policy rules and queue quotas go by origin, so over
+// TCP only the interactive client origins are taken from the request and
+// everything else runs as "remote"; local clients are trusted like the
+// daemon's user, but none may pass itself off as a trigger.
+func (sc taskScope) origin(claimed, fallback string) string {
+	claimed = strings.TrimSpace(claimed)
+	switch {
+	case claimed == "":
+		claimed = fallback
+	case claimed == triggerOrigin:
+		claimed = fallback
+	}
+	if sc.remote && !slices.Contains(remoteClientOrigins, claimed) {
+		return remoteOrigin
+	}
+	return claimed
+}
+
+// sees reports whether the task is visible in the scope.
+func (sc taskScope) sees(task *taskqueue.Task) bool {
+	return task != nil && (sc.all || task.Owner == "" || task.Owner == sc.user)
+}
+
+// manages reports whether the scope may delete the task. Unowned tasks are
+// only managed locally or with the admin override.
+func (sc taskScope) manages(task *taskqueue.Task) bool {
+	return task != nil && (sc.all || task.Owner == sc.user)
+}
+
+type taskScopeKey struct{}
+
+func withTaskScope(ctx context.Context, sc taskScope) context.Context {
+	return context.WithValue(ctx, taskScopeKey{}, sc)
+}
+
+// taskScopeFrom returns the scope of the request ctx belongs to, defaulting
+// to localScope for work the daemon starts itself.
+func taskScopeFrom(ctx context.Context) taskScope {
+	if sc, ok := ctx.Value(taskScopeKey{}).(taskScope); ok {
+		return sc
+	}
+	return localScope
+}
+
+// deleteScopedTasks deletes a task, or the tasks of a tool call or session,
+// that the scope manages. Other users' tasks are reported as not found or
+// left alone.
+func (s *Server) deleteScopedTasks(scope taskScope, taskID, callID, sessionID string) ipc.Response {
+	if taskID != "" {
+		task, ok := s.tasks.Get(taskID)
+		if !ok || !scope.manages(task) {
+			return ipc.NewErrorResponse(ipc.ErrCodeNotFound, "task not found")
+		}
+		if _, err := s.tasks.DeleteTask(context.Background(), taskID); err != nil {
+			return ipc.ErrorResponse(err)
+		}
+		return ipc.Response{Success: true}
+	}
+	if callID == "" && sessionID == "" {
+		return ipc.NewErrorResponse(ipc.ErrCodeValidation, "missing task identifier")
+	}
+	for _, task := range s.tasks.List() {
+		if !scope.manages(task) {
+			continue
+		}
+		if (callID != "" && task.CallID == callID) || (callID == "" && task.SessionID == sessionID) {
+			if _, err := s.tasks.DeleteTask(context.Background(), task.ID); err != nil {
+				return ipc.ErrorResponse(err)
+			}
+		}
+	}
+	return ipc.Response{Success: true}
+}
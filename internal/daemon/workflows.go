@@ -0,0 +1,156 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"opperator/config"
+	"opperator/internal/ipc"
+	"opperator/internal/policy"
+	"opperator/internal/taskqueue"
+	"opperator/internal/workflow"
+	"opperator/pkg/tracing"
+)
+
+// daemonWorkflowRunner runs workflow tasks, invoking each step through the
+// agent runner inside the workflow's own task so a pipeline occupies a single
+// worker and cannot deadlock waiting for its steps to be scheduled.
+type daemonWorkflowRunner struct {
+	agent  taskqueue.AgentRunner
+	policy *policy.Engine
+}
+
+func newDaemonWorkflowRunner(agent taskqueue.AgentRunner, engine *policy.Engine) *daemonWorkflowRunner {
+	return &daemonWorkflowRunner{agent: agent, policy: engine}
+}
+
+func (r *daemonWorkflowRunner) Execute(ctx context.Context, name, inputs, workingDir string, progress func(taskqueue.ProgressEvent)) (string, string, error) {
+	wf, err := workflow.Load(name)
+	if err != nil {
+		return "", "", err
+	}
+	var parsed map[string]string
+	if trimmed := strings.TrimSpace(inputs); trimmed != "" {
+		if err := json.Unmarshal([]byte(trimmed), &parsed); err != nil {
+			return "", "", fmt.Errorf("decode workflow inputs: %w", err)
+		}
+	}
+	policyArgs := make(map[string]any, len(parsed))
+	for k, v := range parsed {
+		policyArgs[k] = v
+	}
+	in := taskPolicyInput(ctx, config.PolicyKindWorkflow, "", wf.Name, policyArgs)
+	if err := checkPolicy(ctx, r.policy, in); err != nil {
+		return "", "", err
+	}
+
+	result, err := workflow.Run(ctx, wf, parsed, workingDir, r.agent, progress)
+	if err != nil {
+		return "", "", err
+	}
+	content, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return "", "", err
+	}
+	metadata, err := json.Marshal(map[string]any{"workflow": wf.Name, "steps": result.Steps})
+	if err != nil {
+		return "", "", err
+	}
+	return string(content), string(metadata), nil
+}
+
+func (s *Server) listWorkflows() ipc.Response {
+	workflows, err := workflow.LoadAll()
+	if err != nil {
+		return ipc.ErrorResponse(err)
+	}
+	infos := make([]ipc.WorkflowInfo, 0, len(workflows))
+	for _, wf := range workflows {
+		infos = append(infos, convertWorkflow(wf))
+	}
+	return ipc.Response{Success: true, Workflows: infos}
+}
+
+// runWorkflow validates the definition and inputs up front, then queues the
+// whole workflow as one task.
+func (s *Server) runWorkflow(ctx context.Context, req ipc.Request) ipc.Response {
+	if s.tasks == nil {
+		return ipc.NewErrorResponse(ipc.ErrCodeUnavailable, "tool task manager unavailable")
+	}
+	wf, err := workflow.Load(req.Workflow)
+	if err != nil {
+		if errors.Is(err, workflow.ErrNotFound) {
+			return ipc.NewErrorResponse(ipc.ErrCodeNotFound, err.Error())
+		}
+		return ipc.NewErrorResponse(ipc.ErrCodeValidation, err.Error())
+	}
+	inputs, err := wf.ResolveInputs(req.WorkflowInputs)
+	if err != nil {
+		return ipc.NewErrorResponse(ipc.ErrCodeValidation, err.Error())
+	}
+	encoded, err := json.Marshal(inputs)
+	if err != nil {
+		return ipc.ErrorResponse(err)
+	}
+
+	if req.WorkingDir != "" {
+		s.setInvocationDir(req.WorkingDir)
+	}
+	origin := taskScopeFrom(ctx).origin(req.Origin, "cli")
+	task, err := s.tasks.Submit(context.Background(), taskqueue.SubmitRequest{
+		ToolName:    "workflow:" + wf.Name,
+		WorkingDir:  req.WorkingDir,
+		SessionID:   req.SessionID,
+		Mode:        "workflow",
+		Command:     wf.Name,
+		CommandArgs: string(encoded),
+		Origin:      origin,
+		ClientID:    req.ClientID,
+		TraceParent: tracing.TraceParent(ctx),
+		DependsOn:   req.DependsOn,
+		Owner:       taskScopeFrom(ctx).user,
+		Execution:   req.Execution,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, taskqueue.ErrUnknownDependency), errors.Is(err, taskqueue.ErrInvalidExecution):
+			return ipc.NewErrorResponse(ipc.ErrCodeValidation, err.Error())
+		case errors.Is(err, taskqueue.ErrPendingLimit):
+			return ipc.NewErrorResponse(ipc.ErrCodeBusy, err.Error())
+		case errors.Is(err, taskqueue.ErrClosed):
+			return ipc.NewErrorResponse(ipc.ErrCodeUnavailable, err.Error())
+		}
+		return ipc.ErrorResponse(err)
+	}
+	return ipc.Response{Success: true, Task: convertTask(task)}
+}
+
+func convertWorkflow(wf *workflow.Workflow) ipc.WorkflowInfo {
+	info := ipc.WorkflowInfo{
+		Name:        wf.Name,
+		Description: wf.Description,
+		Path:        wf.Path,
+		Steps:       make([]ipc.WorkflowStepInfo, 0, len(wf.Steps)),
+	}
+	for _, in := range wf.Inputs {
+		info.Inputs = append(info.Inputs, ipc.WorkflowInputInfo{
+			Name:        in.Name,
+			Description: in.Description,
+			Required:    in.Required,
+			Default:     in.Default,
+		})
+	}
+	for _, step := range wf.Steps {
+		info.Steps = append(info.Steps, ipc.WorkflowStepInfo{
+			ID:      step.ID,
+			Agent:   step.Agent,
+			Command: step.Command,
+			Needs:   step.Needs,
+			If:      step.If,
+		})
+	}
+	return info
+}
@@ -0,0 +1,203 @@
+package deployment
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"time"
+
+	"opperator/config"
+	"opperator/internal/credentials"
+)
+
+// DeploymentCost is one row of 'op cloud costs'
+type DeploymentCost struct {
+	Daemon       string    `json:"daemon"`
+	Provider     string    `json:"provider"`
+	ServerType   string    `json:"server_type,omitempty"`
+	Region       string    `json:"region,omitempty"`
+	HourlyPrice  float64   `json:"hourly_price"`
+	MonthlyPrice float64   `json:"monthly_price"`
+	Currency     string    `json:"currency,omitempty"`
+	DeployedAt   time.Time `json:"deployed_at"`
+	MonthToDate  float64   `json:"month_to_date"`
+	Projected    float64   `json:"projected_month"`
+	// Source is "live" when pricing came from the provider API and
+	// "recorded" when it came from the registry
+	Source string `json:"source"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Costs prints month-to-date and projected spend for this calendar month for
+// every cloud deployment. Prices come from the provider API when an API key
+// is stored, falling back to the prices recorded at deploy time.
+func Costs(jsonOutput bool) error {
+	registry, err := config.LoadDaemonRegistry()
+	if err != nil {
+		return fmt.Errorf("failed to load daemon registry: %w", err)
+	}
+
+	var hetzner *HetznerClient
+	if apiKey, err := credentials.GetSecret(hetznerAPIKeySecret); err == nil && apiKey != "" {
+		hetzner = NewHetznerClient(apiKey)
+	}
+
+	now := time.Now().UTC()
+	var rows []DeploymentCost
+	for _, daemon := range registry.Daemons {
+		// Disabled daemons still run and cost money
+		if daemon.Provider == "" || daemon.Provider == "local" {
+			continue
+		}
+		rows = append(rows, deploymentCost(hetzner, daemon, now))
+	}
+
+	if jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if rows == nil {
+			rows = []DeploymentCost{}
+		}
+		return enc.Encode(rows)
+	}
+
+	if len(rows) == 0 {
+		fmt.Println("No cloud deployments found")
+		fmt.Printf("\nDeploy to cloud with: op cloud deploy\n")
+		return nil
+	}
+	printCosts(rows, now)
+	return nil
+}
+
+// deploymentCost prices one deployment, preferring live data
+func deploymentCost(hetzner *HetznerClient, daemon config.DaemonConfig, now time.Time) DeploymentCost {
+	row := DeploymentCost{
+		Daemon:       daemon.Name,
+		Provider:     daemon.Provider,
+		ServerType:   daemon.ServerType,
+		Region:       daemon.Region,
+		HourlyPrice:  daemon.HourlyPrice,
+		MonthlyPrice: daemon.MonthlyPrice,
+		Currency:     daemon.Currency,
+		DeployedAt:   daemon.DeployedAt,
+		Source:       "recorded",
+	}
+
+	if daemon.Provider == "hetzner" && hetzner != nil && daemon.HetznerServerID != 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		server, err := hetzner.GetServer(ctx, daemon.HetznerServerID)
+		cancel()
+		if err != nil {
+			row.Error = err.Error()
+		} else {
+			// The server may have been rescaled since it was deployed
+			row.ServerType = server.Type
+			row.Region = server.Location
+			row.DeployedAt = server.CreatedAt
+			if server.Pricing != nil {
+				row.HourlyPrice = server.Pricing.Hourly
+				row.MonthlyPrice = server.Pricing.Monthly
+				row.Currency = server.Pricing.Currency
+				row.Source = "live"
+			}
+		}
+	}
+
+	if row.HourlyPrice == 0 && row.MonthlyPrice == 0 {
+		row.Source = "unknown"
+		if row.Error == "" {
+			row.Error = "no price recorded; store a Hetzner API key to look it up"
+		}
+		return row
+	}
+	row.MonthToDate, row.Projected = estimateMonthlyCost(row.HourlyPrice, row.MonthlyPrice, row.DeployedAt, now)
+	return row
+}
+
+// estimateMonthlyCost bills every started hour since the later of the start
+// of the month and deployedAt, capped at the monthly price. Projected assumes
+// the server keeps running until the end of the month. An unknown deploy time
+// counts as running all month.
+func estimateMonthlyCost(hourly, monthlyCap float64, deployedAt, now time.Time) (monthToDate, projected float64) {
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	monthEnd := monthStart.AddDate(0, 1, 0)
+	start := monthStart
+	if deployedAt.After(start) {
+		start = deployedAt
+	}
+
+	billed := func(until time.Time) float64 {
+		if !until.After(start) {
+			return 0
+		}
+		cost := math.Ceil(until.Sub(start).Hours()) * hourly
+		if monthlyCap > 0 && cost > monthlyCap {
+			cost = monthlyCap
+		}
+		return cost
+	}
+	return billed(now), billed(monthEnd)
+}
+
+func printCosts(rows []DeploymentCost, now time.Time) {
+	const format = "%-15s %-8s %-6s %10s %10s %14s %14s  %s\n"
+	fmt.Printf("Estimated server spend for %s (gross; traffic, backups and IPs not included)\n\n", now.Format("January 2006"))
+	fmt.Printf(format, "DAEMON", "TYPE", "REGION", "HOURLY", "MONTHLY", "MONTH-TO-DATE", "PROJECTED", "PRICING")
+
+	totals := make(map[string][2]float64)
+	var currencies []string
+	priced := 0
+	for _, row := range rows {
+		if row.Source == "unknown" {
+			fmt.Printf("%-15s %-8s %-6s %s\n", row.Daemon, dashIfEmpty(row.ServerType), dashIfEmpty(row.Region), row.Error)
+			continue
+		}
+		source := row.Source
+		if row.Error != "" {
+			source += " (live lookup failed)"
+		}
+		fmt.Printf(format,
+			row.Daemon,
+			dashIfEmpty(row.ServerType),
+			dashIfEmpty(row.Region),
+			formatMoney(row.HourlyPrice, row.Currency, 4),
+			formatMoney(row.MonthlyPrice, row.Currency, 2),
+			formatMoney(row.MonthToDate, row.Currency, 2),
+			formatMoney(row.Projected, row.Currency, 2),
+			source,
+		)
+
+		priced++
+		if _, ok := totals[row.Currency]; !ok {
+			currencies = append(currencies, row.Currency)
+		}
+		t := totals[row.Currency]
+		t[0] += row.MonthToDate
+		t[1] += row.Projected
+		totals[row.Currency] = t
+	}
+
+	if priced > 1 {
+		for _, currency := range currencies {
+			t := totals[currency]
+			fmt.Printf(format, "TOTAL", "", "", "", "", formatMoney(t[0], currency, 2), formatMoney(t[1], currency, 2), "")
+		}
+	}
+}
+
+func formatMoney(amount float64, currency string, decimals int) string {
+	if currency == "" {
+		currency = "EUR"
+	}
+	return fmt.Sprintf("%.*f %s", decimals, amount, currency)
+}
+
+func dashIfEmpty(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -13,8 +13,16 @@ import (
 
 const hetznerAPIKeySecret = "HETZNER_API_KEY"
 
-// Deploy runs the full deployment workflow
-func Deploy() error {
+// Deploy runs the full deployment workflow. hardening preselects how the new
+// server is locked down; the wizard lets the user adjust it.
+func Deploy(hardening HardeningOptions) error {
+	if err := validateAllowFrom(hardening.AllowFrom); err != nil {
+		return err
+	}
+	if hardening.SwapGB < 0 {
+		return fmt.Errorf("swap size cannot be negative")
+	}
+
 	spinnerStyle := lipgloss.NewStyle().MarginLeft(2).Foreground(lipgloss.Color("#f7c0af"))
 	ctx := context.Background()
 
@@ -87,11 +95,32 @@ func Deploy() error {
 	}
 
 	// Run second part of wizard with dynamic options
+	input.Hardening = hardening
 	input, err = RunWizardPartTwo(input, serverTypes, locations)
 	if err != nil {
 		return err
 	}
 
+	// Resolve "my IP" before creating anything, so a failed lookup never
+	// leaves a server with the daemon port open
+	if input.Hardening.RestrictToMyIP && input.Hardening.AllowFrom == "" {
+		var ipErr error
+		err = spinner.New().
+			Title("Detecting your public IP...").
+			Style(spinnerStyle).
+			Action(func() {
+				input.Hardening.AllowFrom, ipErr = detectPublicIP(ctx)
+			}).
+			Run()
+		if err != nil {
+			return err
+		}
+		if ipErr != nil {
+			return fmt.Errorf("%w; pass --allow-from to set the address yourself", ipErr)
+		}
+		fmt.Printf("✓ Daemon port will only accept connections from %s\n", input.Hardening.AllowFrom)
+	}
+
 	// Track created resources for cleanup on failure
 	var serverInfo *ServerInfo
 	var hetznerClient *HetznerClient
@@ -182,7 +211,7 @@ func Deploy() error {
 			}
 			defer provisioner.Close()
 
-			provisionErr = provisioner.Provision(ctx, authToken)
+			provisionErr = provisioner.Provision(ctx, authToken, input.Hardening)
 		}).
 		Run()
 	if err != nil {
@@ -208,6 +237,14 @@ func Deploy() error {
 		Enabled:         true,
 		Provider:        "hetzner",
 		HetznerServerID: serverInfo.ID,
+		ServerType:      serverInfo.Type,
+		Region:          serverInfo.Location,
+		DeployedAt:      serverInfo.CreatedAt,
+	}
+	if serverInfo.Pricing != nil {
+		daemon.HourlyPrice = serverInfo.Pricing.Hourly
+		daemon.MonthlyPrice = serverInfo.Pricing.Monthly
+		daemon.Currency = serverInfo.Pricing.Currency
 	}
 
 	if err := registry.AddDaemon(daemon); err != nil {
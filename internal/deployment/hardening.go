@@ -0,0 +1,242 @@
+package deployment
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// publicIPURL answers with the caller's public IP address as plain text
+const publicIPURL = "https://api.ipify.org"
+
+// HardeningOptions selects how a newly provisioned server is locked down
+// beyond the provider's defaults
+type HardeningOptions struct {
+	// AllowFrom restricts the daemon TCP port to this address or CIDR; empty
+	// leaves the port open to everyone
+	AllowFrom string
+	// RestrictToMyIP sets AllowFrom to this machine's public IP when empty
+	RestrictToMyIP bool
+	// UnattendedUpgrades installs security updates automatically
+	UnattendedUpgrades bool
+	// Fail2ban bans addresses that repeatedly fail SSH logins
+	Fail2ban bool
+	// SSHKeyOnly disables password and keyboard-interactive SSH logins
+	SSHKeyOnly bool
+	// SwapGB is the size of the swap file to create; 0 creates none
+	SwapGB int
+}
+
+// Wizard keys for the hardening choices
+const (
+	hardenFirewall           = "firewall"
+	hardenUnattendedUpgrades = "unattended-upgrades"
+	hardenFail2ban           = "fail2ban"
+	hardenSSHKeyOnly         = "ssh-key-only"
+)
+
+// selected lists the wizard keys of the enabled choices
+func (h HardeningOptions) selected() []string {
+	var keys []string
+	if h.RestrictToMyIP || h.AllowFrom != "" {
+		keys = append(keys, hardenFirewall)
+	}
+	if h.UnattendedUpgrades {
+		keys = append(keys, hardenUnattendedUpgrades)
+	}
+	if h.Fail2ban {
+		keys = append(keys, hardenFail2ban)
+	}
+	if h.SSHKeyOnly {
+		keys = append(keys, hardenSSHKeyOnly)
+	}
+	return keys
+}
+
+// applySelected enables exactly the choices in keys
+func (h *HardeningOptions) applySelected(keys []string) {
+	has := func(key string) bool {
+		for _, k := range keys {
+			if k == key {
+				return true
+			}
+		}
+		return false
+	}
+	if !has(hardenFirewall) {
+		h.AllowFrom = ""
+		h.RestrictToMyIP = false
+	} else if h.AllowFrom == "" {
+		h.RestrictToMyIP = true
+	}
+	h.UnattendedUpgrades = has(hardenUnattendedUpgrades)
+	h.Fail2ban = has(hardenFail2ban)
+	h.SSHKeyOnly = has(hardenSSHKeyOnly)
+}
+
+// Summary describes the choices for the deployment confirmation
+func (h HardeningOptions) Summary() string {
+	var parts []string
+	switch {
+	case h.AllowFrom != "":
+		parts = append(parts, fmt.Sprintf("daemon port limited to %s", h.AllowFrom))
+	case h.RestrictToMyIP:
+		parts = append(parts, "daemon port limited to your IP")
+	default:
+		parts = append(parts, "daemon port open to all")
+	}
+	if h.UnattendedUpgrades {
+		parts = append(parts, "unattended upgrades")
+	}
+	if h.Fail2ban {
+		parts = append(parts, "fail2ban")
+	}
+	if h.SSHKeyOnly {
+		parts = append(parts, "SSH key-only login")
+	}
+	if h.SwapGB > 0 {
+		parts = append(parts, fmt.Sprintf("%d GB swap", h.SwapGB))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// validateAllowFrom accepts an IP address or CIDR range
+func validateAllowFrom(s string) error {
+	if s == "" {
+		return nil
+	}
+	if net.ParseIP(s) != nil {
+		return nil
+	}
+	if _, _, err := net.ParseCIDR(s); err == nil {
+		return nil
+	}
+	return fmt.Errorf("%q is not an IP address or CIDR range", s)
+}
+
+// detectPublicIP asks publicIPURL for the address this machine connects from
+func detectPublicIP(ctx context.Context) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, publicIPURL, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("detect public IP: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("detect public IP: %s", resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64))
+	if err != nil {
+		return "", fmt.Errorf("detect public IP: %w", err)
+	}
+	ip := strings.TrimSpace(string(body))
+	if net.ParseIP(ip) == nil {
+		return "", fmt.Errorf("detect public IP: unexpected answer %q", ip)
+	}
+	return ip, nil
+}
+
+// harden applies the host-level hardening choices. The firewall is handled
+// by configureFirewall.
+func (p *Provisioner) harden(h HardeningOptions) error {
+	if h.SwapGB > 0 {
+		if err := p.configureSwap(h.SwapGB); err != nil {
+			return fmt.Errorf("configure swap: %w", err)
+		}
+	}
+	if h.UnattendedUpgrades {
+		if err := p.configureUnattendedUpgrades(); err != nil {
+			return fmt.Errorf("configure unattended upgrades: %w", err)
+		}
+	}
+	if h.Fail2ban {
+		if err := p.configureFail2ban(); err != nil {
+			return fmt.Errorf("configure fail2ban: %w", err)
+		}
+	}
+	if h.SSHKeyOnly {
+		if err := p.configureSSHKeyOnly(); err != nil {
+			return fmt.Errorf("configure SSH: %w", err)
+		}
+	}
+	return nil
+}
+
+// configureSwap creates and enables a swap file of sizeGB gigabytes
+func (p *Provisioner) configureSwap(sizeGB int) error {
+	fmt.Printf("Creating %d GB swap file...\n", sizeGB)
+	script := fmt.Sprintf(`
+		set -e
+		if swapon --show=NAME --noheadings | grep -qx /swapfile; then
+			exit 0
+		fi
+		fallocate -l %dG /swapfile
+		chmod 600 /swapfile
+		mkswap /swapfile
+		swapon /swapfile
+		grep -q '^/swapfile ' /etc/fstab || echo '/swapfile none swap sw 0 0' >> /etc/fstab
+	`, sizeGB)
+	return p.runCommand(script)
+}
+
+// configureUnattendedUpgrades installs security updates daily
+func (p *Provisioner) configureUnattendedUpgrades() error {
+	fmt.Println("Enabling unattended upgrades...")
+	if err := p.runCommand("DEBIAN_FRONTEND=noninteractive apt-get install -y -qq unattended-upgrades"); err != nil {
+		return err
+	}
+	periodic := `APT::Periodic::Update-Package-Lists "1";
+APT::Periodic::Unattended-Upgrade "1";
+APT::Periodic::AutocleanInterval "7";
+`
+	if err := p.uploadFile([]byte(periodic), "/etc/apt/apt.conf.d/20auto-upgrades"); err != nil {
+		return err
+	}
+	return p.runCommand("systemctl enable --now unattended-upgrades")
+}
+
+// configureFail2ban bans addresses after repeated failed SSH logins
+func (p *Provisioner) configureFail2ban() error {
+	fmt.Println("Installing fail2ban...")
+	if err := p.runCommand("DEBIAN_FRONTEND=noninteractive apt-get install -y -qq fail2ban"); err != nil {
+		return err
+	}
+	jail := `[sshd]
+enabled = true
+backend = systemd
+maxretry = 5
+findtime = 10m
+bantime = 1h
+`
+	if err := p.uploadFile([]byte(jail), "/etc/fail2ban/jail.d/opperator.local"); err != nil {
+		return err
+	}
+	if err := p.runCommand("systemctl enable fail2ban"); err != nil {
+		return err
+	}
+	return p.runCommand("systemctl restart fail2ban")
+}
+
+// configureSSHKeyOnly turns off password logins. Root keeps key access, which
+// updates use.
+func (p *Provisioner) configureSSHKeyOnly() error {
+	fmt.Println("Restricting SSH to key authentication...")
+	sshdConfig := `PasswordAuthentication no
+KbdInteractiveAuthentication no
+PermitRootLogin prohibit-password
+`
+	if err := p.uploadFile([]byte(sshdConfig), "/etc/ssh/sshd_config.d/60-opperator.conf"); err != nil {
+		return err
+	}
+	return p.runCommand("sshd -t && (systemctl reload ssh || systemctl reload sshd)")
+}
@@ -130,8 +130,9 @@ type ServerInfo struct {
 	Type       string
 	Location   string
 	CreatedAt  time.Time
-	PrivateKey string // SSH private key for access
-	SSHKeyName string // Name of SSH key in Hetzner
+	Pricing    *ServerPricing // Nil when the type has no price for the location
+	PrivateKey string         // SSH private key for access
+	SSHKeyName string         // Name of SSH key in Hetzner
 }
 
 // CreateServer creates a new Hetzner Cloud server
@@ -214,6 +215,7 @@ func (h *HetznerClient) CreateServer(ctx context.Context, name, serverType, loca
 		Type:       server.ServerType.Name,
 		Location:   server.Datacenter.Location.Name,
 		CreatedAt:  server.Created,
+		Pricing:    serverTypePricing(st, loc.Name),
 		PrivateKey: privateKey,
 		SSHKeyName: sshKeyName,
 	}
@@ -275,9 +277,45 @@ func (h *HetznerClient) GetServer(ctx context.Context, serverID int64) (*ServerI
 		Type:      server.ServerType.Name,
 		Location:  server.Datacenter.Location.Name,
 		CreatedAt: server.Created,
+		Pricing:   serverTypePricing(server.ServerType, server.Datacenter.Location.Name),
 	}, nil
 }
 
+// ServerPricing is the gross price of a server type in one location.
+// Hetzner bills every started hour up to the monthly price.
+type ServerPricing struct {
+	Hourly   float64
+	Monthly  float64
+	Currency string
+}
+
+// serverTypePricing picks the price of st in location from the API response
+func serverTypePricing(st *hcloud.ServerType, location string) *ServerPricing {
+	if st == nil {
+		return nil
+	}
+	for _, p := range st.Pricings {
+		if p.Location == nil || p.Location.Name != location {
+			continue
+		}
+		hourly, err := strconv.ParseFloat(p.Hourly.Gross, 64)
+		if err != nil {
+			return nil
+		}
+		monthly, err := strconv.ParseFloat(p.Monthly.Gross, 64)
+		if err != nil {
+			return nil
+		}
+		currency := p.Hourly.Currency
+		if currency == "" {
+			// Hetzner bills in euros; server type prices omit the currency
+			currency = "EUR"
+		}
+		return &ServerPricing{Hourly: hourly, Monthly: monthly, Currency: currency}
+	}
+	return nil
+}
+
 // waitForServer waits for a server to be running
 func (h *HetznerClient) waitForServer(ctx context.Context, serverID int64) error {
 	timeout := time.After(5 * time.Minute)
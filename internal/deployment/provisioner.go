@@ -9,6 +9,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/pkg/sftp"
@@ -43,7 +44,7 @@ NoNewPrivileges=true
 PrivateTmp=true
 ProtectSystem=strict
 ProtectHome=true
-ReadWritePaths=/var/lib/opperator /var/log/opperator
+ReadWritePaths=/var/lib/opperator /var/log/opperator /opt/opperator
 
 [Install]
 WantedBy=multi-user.target
@@ -102,8 +103,9 @@ func (p *Provisioner) Close() error {
 	return nil
 }
 
-// Provision sets up the opperator daemon on the remote server
-func (p *Provisioner) Provision(ctx context.Context, authToken string) error {
+// Provision sets up the opperator daemon on the remote server and applies the
+// hardening choices
+func (p *Provisioner) Provision(ctx context.Context, authToken string, hardening HardeningOptions) error {
 	// Step 1: Create user with home directory at /var/lib/opperator
 	if err := p.runCommand("useradd -d /var/lib/opperator -m -s /bin/bash opperator || true"); err != nil {
 		return fmt.Errorf("create user: %w", err)
@@ -134,6 +136,11 @@ func (p *Provisioner) Provision(ctx context.Context, authToken string) error {
 		return fmt.Errorf("install system dependencies: %w", err)
 	}
 
+	// Step 2b: Harden the host
+	if err := p.harden(hardening); err != nil {
+		return fmt.Errorf("harden server: %w", err)
+	}
+
 	// Step 3: Build and upload binary
 	if err := p.uploadBinary(); err != nil {
 		return fmt.Errorf("upload binary: %w", err)
@@ -145,7 +152,7 @@ func (p *Provisioner) Provision(ctx context.Context, authToken string) error {
 	}
 
 	// Step 5: Configure firewall
-	if err := p.configureFirewall(); err != nil {
+	if err := p.configureFirewall(hardening.AllowFrom); err != nil {
 		return fmt.Errorf("configure firewall: %w", err)
 	}
 
@@ -343,8 +350,14 @@ exec /opt/opperator/opperator daemon start --foreground
 	return nil
 }
 
-// configureFirewall configures UFW to allow opperator port
-func (p *Provisioner) configureFirewall() error {
+// configureFirewall configures UFW to allow opperator port. With allowFrom
+// set only that address or range may reach it, and UFW is enabled so the rule
+// takes effect.
+func (p *Provisioner) configureFirewall(allowFrom string) error {
+	if allowFrom != "" {
+		return p.restrictDaemonPort(allowFrom)
+	}
+
 	// Check if UFW is installed
 	if err := p.runCommand("which ufw"); err == nil {
 		// UFW is installed, use it
@@ -361,6 +374,42 @@ func (p *Provisioner) configureFirewall() error {
 	return nil
 }
 
+// restrictDaemonPort opens the daemon port to allowFrom only, keeping SSH
+// reachable for updates
+func (p *Provisioner) restrictDaemonPort(allowFrom string) error {
+	fmt.Printf("Restricting port %s to %s...\n", opperatorPort, allowFrom)
+	if err := p.runCommand("which ufw"); err == nil {
+		script := fmt.Sprintf(`
+			set -e
+			ufw default deny incoming
+			ufw default allow outgoing
+			ufw allow 22/tcp
+			ufw allow from %s to any port %s proto tcp
+			ufw --force enable
+		`, allowFrom, opperatorPort)
+		if err := p.runCommand(script); err != nil {
+			return fmt.Errorf("restrict port in UFW: %w", err)
+		}
+		return nil
+	}
+
+	// Fall back to iptables
+	iptables := "iptables"
+	if strings.Contains(allowFrom, ":") {
+		iptables = "ip6tables"
+	}
+	script := fmt.Sprintf(`
+		set -e
+		%[1]s -A INPUT -p tcp --dport %[2]s -s %[3]s -j ACCEPT
+		iptables -A INPUT -p tcp --dport %[2]s -j DROP
+		ip6tables -A INPUT -p tcp --dport %[2]s -j DROP
+	`, iptables, opperatorPort, allowFrom)
+	if err := p.runCommand(script); err != nil {
+		return fmt.Errorf("restrict port in iptables: %w", err)
+	}
+	return nil
+}
+
 // createSystemdService creates and enables the systemd service
 func (p *Provisioner) createSystemdService(authToken string) error {
 	serviceContent := fmt.Sprintf(systemdServiceTemplate, opperatorPort, authToken)
@@ -0,0 +1,571 @@
+package deployment
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/huh"
+	"github.com/charmbracelet/huh/spinner"
+	"github.com/charmbracelet/lipgloss"
+	"opperator/config"
+	"opperator/internal/agent"
+	"opperator/internal/awssig"
+	"opperator/internal/backup"
+	"opperator/internal/credentials"
+	"opperator/internal/ipc"
+)
+
+// backupEnvPath holds the storage credentials on a recovered server, so the
+// restore and the daemon's own scheduled backups can reach the bucket.
+const backupEnvPath = "/etc/opperator/backup.env"
+
+// remoteConfigDir is the opperator config directory on provisioned servers
+const remoteConfigDir = "/var/lib/opperator/.config/opperator"
+
+// RecoverOptions controls how Recover rebuilds a cloud daemon.
+type RecoverOptions struct {
+	// Backup is the bucket the daemon backed up to
+	Backup config.BackupConfig
+	// Snapshot is "latest", a snapshot ID or an RFC 3339 timestamp
+	Snapshot string
+	// ServerType and Location default to what the lost server used
+	ServerType string
+	Location   string
+	Hardening  HardeningOptions
+	// DeleteOld deletes the lost server once the new one has taken over
+	DeleteOld bool
+}
+
+// Recover rebuilds a cloud daemon after data loss: it provisions a fresh
+// server, restores a backup onto it, points the registry entry at it, asks
+// for the secrets the backup could not carry and starts the agents that were
+// running when the snapshot was taken. The old server is left alone until
+// the new one has taken over.
+func Recover(daemonName string, opts RecoverOptions) error {
+	if err := validateAllowFrom(opts.Hardening.AllowFrom); err != nil {
+		return err
+	}
+	if opts.Hardening.SwapGB < 0 {
+		return fmt.Errorf("swap size cannot be negative")
+	}
+	// The new server gets the same credentials this machine uses to reach
+	// the bucket
+	if err := awssig.CheckEnv(); err != nil {
+		return fmt.Errorf("%w to restore backups", err)
+	}
+
+	spinnerStyle := lipgloss.NewStyle().MarginLeft(2).Foreground(lipgloss.Color("#f7c0af"))
+	ctx := context.Background()
+
+	registry, err := config.LoadDaemonRegistry()
+	if err != nil {
+		return fmt.Errorf("failed to load daemon registry: %w", err)
+	}
+	daemon, err := registry.GetDaemon(daemonName)
+	if err != nil {
+		return fmt.Errorf("daemon '%s' not found", daemonName)
+	}
+	if daemon.Provider != "hetzner" {
+		return fmt.Errorf("daemon '%s' is not a Hetzner deployment (provider: %s)", daemonName, daemon.Provider)
+	}
+	if daemon.AuthToken == "" {
+		return fmt.Errorf("daemon '%s' has no auth token to carry over", daemonName)
+	}
+
+	apiKey, err := credentials.GetSecret(hetznerAPIKeySecret)
+	if err != nil || apiKey == "" {
+		return fmt.Errorf("Hetzner API key not found; store it by deploying with 'op cloud deploy'")
+	}
+	hetznerClient := NewHetznerClient(apiKey)
+
+	// Find the snapshot before creating anything
+	var snap *backup.Snapshot
+	var findErr error
+	err = spinner.New().
+		Title(fmt.Sprintf("Looking up snapshot in %s...", opts.Backup.Bucket)).
+		Style(spinnerStyle).
+		Action(func() {
+			snap, findErr = backup.Find(ctx, opts.Backup, opts.Snapshot)
+		}).
+		Run()
+	if err != nil {
+		return err
+	}
+	if findErr != nil {
+		return findErr
+	}
+
+	// The lost server may still exist, e.g. when only its disk was wiped
+	var oldServer *ServerInfo
+	if daemon.HetznerServerID != 0 {
+		oldServer, _ = hetznerClient.GetServer(ctx, daemon.HetznerServerID)
+	}
+
+	serverType := firstNonEmpty(opts.ServerType, daemon.ServerType)
+	location := firstNonEmpty(opts.Location, daemon.Region)
+	if oldServer != nil {
+		serverType = firstNonEmpty(serverType, oldServer.Type)
+		location = firstNonEmpty(location, oldServer.Location)
+	}
+	if serverType == "" || location == "" {
+		return fmt.Errorf("no server type or location recorded for '%s'; pass --server-type and --location", daemonName)
+	}
+
+	if err := confirmRecovery(daemonName, serverType, location, snap, oldServer, opts); err != nil {
+		return err
+	}
+
+	hardening := opts.Hardening
+	if hardening.RestrictToMyIP && hardening.AllowFrom == "" {
+		var ipErr error
+		err = spinner.New().
+			Title("Detecting your public IP...").
+			Style(spinnerStyle).
+			Action(func() {
+				hardening.AllowFrom, ipErr = detectPublicIP(ctx)
+			}).
+			Run()
+		if err != nil {
+			return err
+		}
+		if ipErr != nil {
+			return fmt.Errorf("%w; pass --allow-from to set the address yourself", ipErr)
+		}
+	}
+
+	// Step 1: Create the replacement server. Hetzner server names are
+	// unique, and the old server may still hold the daemon's name.
+	var serverInfo *ServerInfo
+	var hetznerErr error
+	serverName := fmt.Sprintf("%s-%s", daemonName, time.Now().UTC().Format("0102-1504"))
+	err = spinner.New().
+		Title(fmt.Sprintf("Creating Hetzner %s server in %s...", serverType, location)).
+		Style(spinnerStyle).
+		Action(func() {
+			serverInfo, hetznerErr = hetznerClient.CreateServer(ctx, serverName, serverType, location)
+		}).
+		Run()
+	if err != nil {
+		return err
+	}
+	if hetznerErr != nil {
+		return fmt.Errorf("failed to create server: %w", hetznerErr)
+	}
+	fmt.Printf("\n✓ Server created: %s (%s)\n\n", serverInfo.Name, serverInfo.PublicIP)
+
+	cleanup := func() {
+		fmt.Println()
+		cleanupErr := spinner.New().
+			Title(fmt.Sprintf("Deleting server %s...", serverInfo.Name)).
+			Style(spinnerStyle).
+			Action(func() {
+				_ = hetznerClient.DeleteServer(ctx, serverInfo.ID)
+			}).
+			Run()
+		if cleanupErr == nil {
+			fmt.Println(" ✓ Server deleted; the registry still points at the old server")
+		}
+	}
+
+	// Step 2: Provision it with the old auth token and restore the snapshot
+	var wasRunning []string
+	var provisionErr error
+	err = spinner.New().
+		Title(fmt.Sprintf("Provisioning server and restoring snapshot %s...", snap.ID())).
+		Style(spinnerStyle).
+		Action(func() {
+			// Wait a bit for SSH to be ready
+			time.Sleep(10 * time.Second)
+
+			provisioner, err := NewProvisioner(serverInfo.PublicIP, serverInfo.PrivateKey)
+			if err != nil {
+				provisionErr = err
+				return
+			}
+			defer provisioner.Close()
+
+			if err := provisioner.Provision(ctx, daemon.AuthToken, hardening); err != nil {
+				provisionErr = err
+				return
+			}
+			wasRunning, provisionErr = provisioner.restoreSnapshot(opts.Backup, snap.ID())
+		}).
+		Run()
+	if err != nil {
+		cleanup()
+		return err
+	}
+	if provisionErr != nil {
+		cleanup()
+		return fmt.Errorf("failed to recover server: %w", provisionErr)
+	}
+	fmt.Printf("✓ Restored snapshot %s\n", snap.ID())
+
+	// Step 3: Point the registry entry at the new server
+	oldServerID := daemon.HetznerServerID
+	daemon.Address = fmt.Sprintf("tcp://%s:%s", serverInfo.PublicIP, opperatorPort)
+	daemon.HetznerServerID = serverInfo.ID
+	daemon.ServerType = serverInfo.Type
+	daemon.Region = serverInfo.Location
+	daemon.DeployedAt = serverInfo.CreatedAt
+	if serverInfo.Pricing != nil {
+		daemon.HourlyPrice = serverInfo.Pricing.Hourly
+		daemon.MonthlyPrice = serverInfo.Pricing.Monthly
+		daemon.Currency = serverInfo.Pricing.Currency
+	}
+	if err := registry.AddDaemon(*daemon); err != nil {
+		cleanup()
+		return fmt.Errorf("failed to update daemon: %w", err)
+	}
+	if err := config.SaveDaemonRegistry(registry); err != nil {
+		cleanup()
+		return fmt.Errorf("failed to save daemon registry: %w", err)
+	}
+	fmt.Printf("✓ Daemon '%s' now points at %s\n", daemonName, serverInfo.PublicIP)
+
+	sshKeySecretName := fmt.Sprintf("HETZNER_SSH_KEY_%s", daemonName)
+	if err := credentials.SetSecret(sshKeySecretName, serverInfo.PrivateKey); err != nil {
+		fmt.Printf("\nWarning: Failed to store SSH key: %v\n", err)
+		fmt.Printf("You may need to manually update this daemon in the future.\n")
+	} else if err := credentials.RegisterSecret(sshKeySecretName); err != nil {
+		fmt.Printf("\nWarning: Failed to register SSH key secret: %v\n", err)
+	}
+
+	// Step 4: Secrets and agents need the daemon itself
+	var client *ipc.Client
+	var connectErr error
+	err = spinner.New().
+		Title("Waiting for the daemon to come up...").
+		Style(spinnerStyle).
+		Action(func() {
+			client, connectErr = connectWhenReady(daemonName, 90*time.Second)
+		}).
+		Run()
+	if err != nil {
+		return err
+	}
+	if connectErr != nil {
+		return fmt.Errorf("server restored but the daemon is not reachable: %w\nSet secrets with 'op secret create' and start agents once it is", connectErr)
+	}
+	defer client.Close()
+
+	if err := restoreSecrets(client); err != nil {
+		fmt.Printf("\nWarning: %v\nSet the remaining secrets with 'op secret create'\n", err)
+	}
+	startRecoveredAgents(client, wasRunning)
+
+	// Step 5: Retire the old server
+	if oldServer != nil && oldServerID != 0 {
+		if opts.DeleteOld {
+			var deleteErr error
+			err = spinner.New().
+				Title(fmt.Sprintf("Deleting old server %s...", oldServer.Name)).
+				Style(spinnerStyle).
+				Action(func() {
+					deleteErr = hetznerClient.DeleteServer(ctx, oldServerID)
+				}).
+				Run()
+			if err == nil && deleteErr == nil {
+				fmt.Printf("✓ Deleted old server %s\n", oldServer.Name)
+			} else {
+				fmt.Printf("Warning: failed to delete old server %s (ID %d): %v\n", oldServer.Name, oldServerID, errors.Join(err, deleteErr))
+			}
+		} else {
+			fmt.Printf("\nThe old server %s (ID %d) still exists and is still billed.\n", oldServer.Name, oldServerID)
+			fmt.Println("Delete it in the Hetzner console once you no longer need it.")
+		}
+	}
+
+	fg := lipgloss.Color("#dddddd")
+	primary := lipgloss.Color("#f7c0af")
+	baseStyle := lipgloss.NewStyle().Foreground(fg)
+	highlightStyle := lipgloss.NewStyle().Foreground(primary).Bold(true)
+
+	fmt.Println()
+	fmt.Println(baseStyle.Render(" ✔︎ Recovery complete!"))
+	fmt.Println()
+	fmt.Print(baseStyle.Render(" Check the daemon with: "))
+	fmt.Print(highlightStyle.Render(fmt.Sprintf("op daemon test %s", daemonName)))
+	fmt.Println()
+	fmt.Println()
+	return nil
+}
+
+func confirmRecovery(daemonName, serverType, location string, snap *backup.Snapshot, oldServer *ServerInfo, opts RecoverOptions) error {
+	old := "The old server is gone."
+	if oldServer != nil {
+		old = fmt.Sprintf("The old server %s (%s) still exists and is kept.", oldServer.Name, oldServer.PublicIP)
+		if opts.DeleteOld {
+			old = fmt.Sprintf("The old server %s (%s) is deleted once recovery succeeds.", oldServer.Name, oldServer.PublicIP)
+		}
+	}
+	description := fmt.Sprintf(
+		"Provision a new Hetzner %s server in %s, restore snapshot %s (taken %s) and point '%s' at it?\n\n%s\nHardening: %s",
+		serverType, location, snap.ID(), snap.Timestamp.Local().Format("2006-01-02 15:04"), daemonName, old, opts.Hardening.Summary(),
+	)
+
+	var confirmed bool
+	form := huh.NewForm(
+		huh.NewGroup(
+			huh.NewConfirm().
+				Title("Recover Daemon").
+				Description(description).
+				Value(&confirmed).
+				Affirmative("Recover").
+				Negative("Cancel"),
+		),
+	).
+		WithTheme(createHuhTheme()).
+		WithWidth(80).
+		WithShowHelp(false).
+		WithShowErrors(false)
+
+	if err := form.Run(); err != nil || !confirmed {
+		return errors.New("cancelled")
+	}
+	return nil
+}
+
+// restoreSnapshot stops the freshly provisioned daemon, restores the
+// snapshot into its config directory and starts it again. It returns the
+// agents that were running when the snapshot was taken.
+func (p *Provisioner) restoreSnapshot(cfg config.BackupConfig, snapshotID string) ([]string, error) {
+	if err := p.runCommand("systemctl stop opperator"); err != nil {
+		return nil, fmt.Errorf("stop daemon: %w", err)
+	}
+
+	var env strings.Builder
+	for _, key := range []string{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN"} {
+		if value := os.Getenv(key); value != "" {
+			fmt.Fprintf(&env, "%s=%s\n", key, shellQuote(value))
+		}
+	}
+	if err := p.uploadFile([]byte(env.String()), backupEnvPath); err != nil {
+		return nil, fmt.Errorf("upload backup credentials: %w", err)
+	}
+	if err := p.runCommand(fmt.Sprintf("chown root:opperator %[1]s && chmod 640 %[1]s", backupEnvPath)); err != nil {
+		return nil, fmt.Errorf("protect backup credentials: %w", err)
+	}
+	dropIn := fmt.Sprintf("[Service]\nEnvironmentFile=%s\n", backupEnvPath)
+	if err := p.uploadFile([]byte(dropIn), "/etc/systemd/system/opperator.service.d/backup.conf"); err != nil {
+		return nil, fmt.Errorf("upload service drop-in: %w", err)
+	}
+
+	args := []string{"/opt/opperator/opperator", "backup", "restore", snapshotID, "--bucket", cfg.Bucket}
+	for _, flag := range []struct{ name, value string }{
+		{"--region", cfg.Region},
+		{"--prefix", cfg.Prefix},
+		{"--endpoint", cfg.Endpoint},
+	} {
+		if flag.value != "" {
+			args = append(args, flag.name, flag.value)
+		}
+	}
+	for i := range args {
+		args[i] = shellQuote(args[i])
+	}
+	restore := fmt.Sprintf("set -a; . %s; set +a; HOME=/var/lib/opperator %s", backupEnvPath, strings.Join(args, " "))
+	if out, err := p.runCommandOutput("su -s /bin/bash opperator -c " + shellQuote(restore)); err != nil {
+		return nil, fmt.Errorf("restore snapshot: %w: %s", err, strings.TrimSpace(out))
+	}
+
+	// Read this before the daemon starts and rewrites it
+	data, err := p.runCommandOutput(fmt.Sprintf("cat %s/agent_data.json 2>/dev/null || true", remoteConfigDir))
+	if err != nil {
+		return nil, fmt.Errorf("read agent data: %w", err)
+	}
+	wasRunning := runningAgents([]byte(data))
+
+	if err := p.runCommand("systemctl daemon-reload"); err != nil {
+		return nil, fmt.Errorf("reload systemd: %w", err)
+	}
+	if err := p.startDaemon(); err != nil {
+		return nil, err
+	}
+	return wasRunning, nil
+}
+
+// runningAgents lists the agents agent_data.json marks as running, sorted
+func runningAgents(data []byte) []string {
+	var agents map[string]struct {
+		WasRunning bool `json:"was_running"`
+	}
+	if err := json.Unmarshal(data, &agents); err != nil {
+		return nil
+	}
+	var names []string
+	for name, a := range agents {
+		if a.WasRunning {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// connectWhenReady dials the daemon until it answers or timeout passes
+func connectWhenReady(daemonName string, timeout time.Duration) (*ipc.Client, error) {
+	var lastErr error
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		client, err := ipc.NewClientFromRegistry(daemonName)
+		if err == nil {
+			if _, err = client.Hello(); err == nil {
+				return client, nil
+			}
+			client.Close()
+		}
+		lastErr = err
+		time.Sleep(2 * time.Second)
+	}
+	return nil, lastErr
+}
+
+// restoreSecrets re-registers the secrets the restored daemon knows by name.
+// Backups never contain secret values, so each one is copied from this
+// machine's keyring where the user agrees, or typed in; blank ones are
+// skipped.
+func restoreSecrets(client *ipc.Client) error {
+	names, err := client.ListSecrets()
+	if err != nil {
+		return fmt.Errorf("failed to list secrets: %w", err)
+	}
+	if len(names) == 0 {
+		return nil
+	}
+
+	local := make(map[string]string)
+	var localNames []string
+	for _, name := range names {
+		if value, err := credentials.GetSecret(name); err == nil && value != "" {
+			local[name] = value
+			localNames = append(localNames, name)
+		}
+	}
+
+	theme := createHuhTheme()
+	fmt.Printf("\nThe restored daemon uses %d secret(s); backups do not contain their values.\n\n", len(names))
+
+	copied := append([]string(nil), localNames...)
+	if len(localNames) > 0 {
+		options := make([]huh.Option[string], len(localNames))
+		for i, name := range localNames {
+			options[i] = huh.NewOption(name, name)
+		}
+		form := huh.NewForm(huh.NewGroup(
+			huh.NewMultiSelect[string]().
+				Title("Copy from this machine").
+				Description("These secrets are in your local keyring. Space to toggle, Enter to continue").
+				Options(options...).
+				Value(&copied).
+				Filterable(false),
+		)).
+			WithTheme(theme).
+			WithWidth(80).
+			WithShowHelp(false)
+		if err := form.Run(); err != nil {
+			return errors.New("cancelled")
+		}
+	}
+
+	values := make(map[string]*string)
+	for _, name := range copied {
+		value := local[name]
+		values[name] = &value
+	}
+	var fields []huh.Field
+	for _, name := range names {
+		if _, ok := values[name]; ok {
+			continue
+		}
+		value := ""
+		values[name] = &value
+		fields = append(fields, huh.NewInput().
+			Title(name).
+			Description("Leave blank to skip").
+			Password(true).
+			Value(&value))
+	}
+	if len(fields) > 0 {
+		form := huh.NewForm(huh.NewGroup(fields...)).
+			WithTheme(theme).
+			WithWidth(80).
+			WithShowHelp(false)
+		if err := form.Run(); err != nil {
+			return errors.New("cancelled")
+		}
+	}
+
+	var failed, skipped []string
+	set := 0
+	for _, name := range names {
+		value := strings.TrimSpace(*values[name])
+		if value == "" {
+			skipped = append(skipped, name)
+			continue
+		}
+		if err := client.SetSecret(name, value); err != nil {
+			failed = append(failed, fmt.Sprintf("%s (%v)", name, err))
+			continue
+		}
+		set++
+	}
+	fmt.Printf("✓ Set %d of %d secret(s)\n", set, len(names))
+	if len(skipped) > 0 {
+		fmt.Printf("  Skipped: %s\n", strings.Join(skipped, ", "))
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to set %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// startRecoveredAgents brings back the agents that were running when the
+// snapshot was taken. Agents the daemon already started are restarted so
+// they pick up the secrets set since.
+func startRecoveredAgents(client *ipc.Client, names []string) {
+	if len(names) == 0 {
+		return
+	}
+	status := make(map[string]agent.ProcessStatus)
+	if processes, err := client.ListAgents(); err == nil {
+		for _, proc := range processes {
+			status[proc.Name] = proc.Status
+		}
+	}
+
+	fmt.Printf("\nStarting %d agent(s) that were running before...\n", len(names))
+	for _, name := range names {
+		var err error
+		if status[name] == agent.StatusRunning {
+			err = client.RestartAgent(name)
+		} else {
+			err = client.StartAgent(name)
+		}
+		if err != nil {
+			fmt.Printf("  ✗ %s: %v\n", name, err)
+			continue
+		}
+		fmt.Printf("  ✓ %s\n", name)
+	}
+}
+
+// shellQuote quotes s for POSIX shells and systemd environment files
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,282 @@
+package deployment
+
+import (
+	"fmt"
+	"time"
+
+	"opperator/config"
+	"opperator/internal/agent"
+	"opperator/internal/ipc"
+)
+
+// Rollout strategies for UpdateAllCloudDaemons
+const (
+	// RolloutAll updates every cloud daemon in turn regardless of failures
+	RolloutAll = "all"
+	// RolloutCanary updates one daemon, watches it through a soak period and
+	// only then updates the rest, one at a time
+	RolloutCanary = "canary"
+)
+
+// rolloutHealthTimeout is how long a daemon may take to answer again after
+// its binary was replaced.
+const rolloutHealthTimeout = 90 * time.Second
+
+// rolloutPollInterval is how often a soaking daemon is checked.
+const rolloutPollInterval = 10 * time.Second
+
+// rolloutMaxMisses is how many checks in a row a soaking daemon may miss
+// before it counts as down.
+const rolloutMaxMisses = 3
+
+// RolloutOptions controls how UpdateAllCloudDaemons rolls out an update.
+type RolloutOptions struct {
+	Strategy string
+	// Canary names the daemon to update first; defaults to the first cloud
+	// daemon in the registry
+	Canary string
+	// Soak is how long the canary must stay healthy before the rest follow
+	Soak time.Duration
+}
+
+type rolloutResult struct {
+	daemonName string
+	err        error
+	rolledBack bool
+	skipped    bool
+}
+
+// canaryRollout updates the canary, soaks it and then updates the remaining
+// daemons one at a time. If a daemon fails to update, does not come back or
+// its agents start crash-looping, every daemon updated so far is rolled back
+// to its previous binary and the rest are left alone.
+func canaryRollout(daemons []*config.DaemonConfig, preRelease bool, opts RolloutOptions) error {
+	order, err := canaryOrder(daemons, opts.Canary)
+	if err != nil {
+		return err
+	}
+
+	results := make([]rolloutResult, len(order))
+	for i, daemon := range order {
+		results[i].daemonName = daemon.Name
+	}
+
+	var updated []int
+	for i, daemon := range order {
+		canary := i == 0
+		if canary {
+			fmt.Printf("🐤 Updating canary '%s'...\n", daemon.Name)
+		} else {
+			fmt.Printf("🔄 Updating daemon '%s'...\n", daemon.Name)
+		}
+
+		// Agents already crash-looping before the update don't count against it
+		baseline, _ := probeDaemon(daemon.Name)
+
+		if err := updateSingleCloudDaemon(daemon, preRelease); err != nil {
+			results[i].err = err
+			fmt.Printf("  ✗ Failed to update '%s': %v\n", daemon.Name, err)
+			abortRollout(order, results, append(updated, i), i)
+			printRolloutSummary(results)
+			return fmt.Errorf("rollout aborted: daemon '%s' failed to update: %w", daemon.Name, err)
+		}
+		updated = append(updated, i)
+
+		err := waitForHealthy(daemon.Name, baseline)
+		if err == nil && canary && opts.Soak > 0 {
+			fmt.Printf("  ✓ Canary '%s' is healthy; soaking for %s\n", daemon.Name, opts.Soak)
+			err = soakDaemon(daemon.Name, baseline, opts.Soak)
+		}
+		if err != nil {
+			results[i].err = err
+			fmt.Printf("  ✗ Daemon '%s' is unhealthy after the update: %v\n", daemon.Name, err)
+			abortRollout(order, results, updated, i)
+			printRolloutSummary(results)
+			return fmt.Errorf("rollout aborted: daemon '%s' is unhealthy after the update: %w", daemon.Name, err)
+		}
+		fmt.Printf("  ✓ Successfully updated '%s'\n\n", daemon.Name)
+	}
+
+	printRolloutSummary(results)
+	return nil
+}
+
+// canaryOrder puts the named canary, or the first daemon, at the front.
+func canaryOrder(daemons []*config.DaemonConfig, canary string) ([]*config.DaemonConfig, error) {
+	if canary == "" {
+		return daemons, nil
+	}
+	order := make([]*config.DaemonConfig, 0, len(daemons))
+	for _, daemon := range daemons {
+		if daemon.Name == canary {
+			order = append(order, daemon)
+		}
+	}
+	if len(order) == 0 {
+		return nil, fmt.Errorf("canary '%s' is not an enabled cloud daemon", canary)
+	}
+	for _, daemon := range daemons {
+		if daemon.Name != canary {
+			order = append(order, daemon)
+		}
+	}
+	return order, nil
+}
+
+// abortRollout rolls back the updated daemons and marks the ones after failed
+// as skipped.
+func abortRollout(order []*config.DaemonConfig, results []rolloutResult, updated []int, failed int) {
+	fmt.Println("\n⏪ Aborting rollout")
+	for _, i := range updated {
+		daemon := order[i]
+		fmt.Printf("  Rolling back '%s'...\n", daemon.Name)
+		if err := rollbackCloudDaemon(daemon); err != nil {
+			fmt.Printf("  ✗ Failed to roll back '%s': %v\n", daemon.Name, err)
+			if results[i].err == nil {
+				results[i].err = fmt.Errorf("rollback failed: %w", err)
+			}
+			continue
+		}
+		results[i].rolledBack = true
+		fmt.Printf("  ✓ Rolled back '%s'\n", daemon.Name)
+	}
+	for i := failed + 1; i < len(order); i++ {
+		results[i].skipped = true
+	}
+	fmt.Println()
+}
+
+// rollbackCloudDaemon restores the binary the last update backed up and
+// restarts the daemon on it.
+func rollbackCloudDaemon(daemon *config.DaemonConfig) error {
+	serverIP, sshKey, err := cloudServerAccess(daemon)
+	if err != nil {
+		return err
+	}
+	provisioner, err := NewProvisioner(serverIP, sshKey)
+	if err != nil {
+		return fmt.Errorf("failed to connect to server: %w", err)
+	}
+	defer provisioner.Close()
+
+	restore := `
+		set -e
+		test -f /opt/opperator/opperator.bak
+		systemctl stop opperator
+		cp /opt/opperator/opperator.bak /opt/opperator/opperator
+		chmod +x /opt/opperator/opperator
+		chown opperator:opperator /opt/opperator/opperator
+		systemctl start opperator
+	`
+	if err := provisioner.runCommand(restore); err != nil {
+		return fmt.Errorf("failed to restore previous binary: %w", err)
+	}
+
+	time.Sleep(2 * time.Second)
+	if err := provisioner.runCommand("systemctl is-active opperator"); err != nil {
+		return fmt.Errorf("daemon failed to start after rollback: %w", err)
+	}
+	return nil
+}
+
+// probeDaemon reads the stats of a daemon from the registry.
+func probeDaemon(daemonName string) (*ipc.DaemonStats, error) {
+	client, err := ipc.NewClientFromRegistry(daemonName)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+	return client.DaemonStats()
+}
+
+// judgeHealth fails when more agents crash-loop than did before the update.
+func judgeHealth(stats, baseline *ipc.DaemonStats) error {
+	looping := stats.Agents[agent.StatusCrashLooping]
+	before := 0
+	if baseline != nil {
+		before = baseline.Agents[agent.StatusCrashLooping]
+	}
+	if looping > before {
+		return fmt.Errorf("%d agent(s) crash-looping (%d before the update)", looping, before)
+	}
+	return nil
+}
+
+// waitForHealthy waits for a restarted daemon to answer and checks its agents.
+func waitForHealthy(daemonName string, baseline *ipc.DaemonStats) error {
+	var lastErr error
+	deadline := time.Now().Add(rolloutHealthTimeout)
+	for time.Now().Before(deadline) {
+		stats, err := probeDaemon(daemonName)
+		if err != nil {
+			lastErr = err
+			time.Sleep(3 * time.Second)
+			continue
+		}
+		return judgeHealth(stats, baseline)
+	}
+	return fmt.Errorf("daemon did not respond within %s: %v", rolloutHealthTimeout, lastErr)
+}
+
+// soakDaemon watches a daemon for soak, failing as soon as its agents start
+// crash-looping or it stops answering.
+func soakDaemon(daemonName string, baseline *ipc.DaemonStats, soak time.Duration) error {
+	misses := 0
+	deadline := time.Now().Add(soak)
+	for time.Now().Before(deadline) {
+		wait := rolloutPollInterval
+		if remaining := time.Until(deadline); remaining < wait {
+			wait = remaining
+		}
+		time.Sleep(wait)
+
+		stats, err := probeDaemon(daemonName)
+		if err != nil {
+			misses++
+			if misses >= rolloutMaxMisses {
+				return fmt.Errorf("daemon stopped responding during the soak: %w", err)
+			}
+			continue
+		}
+		misses = 0
+		if err := judgeHealth(stats, baseline); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func printRolloutSummary(results []rolloutResult) {
+	var succeeded, failed, rolledBack, skipped int
+	for _, result := range results {
+		switch {
+		case result.skipped:
+			skipped++
+		case result.rolledBack:
+			rolledBack++
+		case result.err != nil:
+			failed++
+		default:
+			succeeded++
+		}
+	}
+
+	fmt.Println("─────────────────────────────────────")
+	fmt.Printf("Update Summary: %d successful, %d failed", succeeded, failed)
+	if rolledBack > 0 {
+		fmt.Printf(", %d rolled back", rolledBack)
+	}
+	if skipped > 0 {
+		fmt.Printf(", %d skipped", skipped)
+	}
+	fmt.Println()
+
+	if failed+rolledBack > 0 {
+		fmt.Println("\nFailed updates:")
+		for _, result := range results {
+			if result.err != nil {
+				fmt.Printf("  ✗ %s: %v\n", result.daemonName, result.err)
+			}
+		}
+	}
+}
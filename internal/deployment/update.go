@@ -12,11 +12,13 @@ import (
 	"github.com/charmbracelet/lipgloss"
 	"opperator/config"
 	"opperator/internal/credentials"
+	"opperator/internal/ipc"
 	"opperator/version"
 )
 
-// UpdateAllCloudDaemons updates all cloud daemons in the registry
-func UpdateAllCloudDaemons(preRelease bool) error {
+// UpdateAllCloudDaemons updates all cloud daemons in the registry, all at
+// once or as a canary rollout depending on opts.Strategy
+func UpdateAllCloudDaemons(preRelease bool, opts RolloutOptions) error {
 	// Load daemon registry
 	registry, err := config.LoadDaemonRegistry()
 	if err != nil {
@@ -43,23 +45,20 @@ func UpdateAllCloudDaemons(preRelease bool) error {
 	}
 	fmt.Println()
 
-	// Track results
-	type updateResult struct {
-		daemonName string
-		success    bool
-		err        error
+	switch opts.Strategy {
+	case "", RolloutAll:
+	case RolloutCanary:
+		return canaryRollout(cloudDaemons, preRelease, opts)
+	default:
+		return fmt.Errorf("unknown rollout strategy %q (expected %s or %s)", opts.Strategy, RolloutAll, RolloutCanary)
 	}
-	results := make([]updateResult, 0, len(cloudDaemons))
 
 	// Update each cloud daemon
+	results := make([]rolloutResult, 0, len(cloudDaemons))
 	for _, daemon := range cloudDaemons {
 		fmt.Printf("🔄 Updating daemon '%s'...\n", daemon.Name)
 		err := updateSingleCloudDaemon(daemon, preRelease)
-		results = append(results, updateResult{
-			daemonName: daemon.Name,
-			success:    err == nil,
-			err:        err,
-		})
+		results = append(results, rolloutResult{daemonName: daemon.Name, err: err})
 
 		if err != nil {
 			fmt.Printf("  ✗ Failed to update '%s': %v\n\n", daemon.Name, err)
@@ -68,73 +67,19 @@ func UpdateAllCloudDaemons(preRelease bool) error {
 		}
 	}
 
-	// Print summary
-	successCount := 0
-	failureCount := 0
-	for _, result := range results {
-		if result.success {
-			successCount++
-		} else {
-			failureCount++
-		}
-	}
-
-	fmt.Println("─────────────────────────────────────")
-	fmt.Printf("Update Summary: %d successful, %d failed\n", successCount, failureCount)
-
-	if failureCount > 0 {
-		fmt.Println("\nFailed updates:")
-		for _, result := range results {
-			if !result.success {
-				fmt.Printf("  ✗ %s: %v\n", result.daemonName, result.err)
-			}
-		}
-	}
-
+	printRolloutSummary(results)
 	return nil
 }
 
 // updateSingleCloudDaemon updates a single cloud daemon without UI elements (for batch updates)
 func updateSingleCloudDaemon(daemon *config.DaemonConfig, preRelease bool) error {
-	ctx := context.Background()
-
-	// Get server info and SSH credentials
-	var serverIP string
-	var sshKey string
-
-	if daemon.Provider == "hetzner" {
-		if daemon.HetznerServerID == 0 {
-			return fmt.Errorf("no Hetzner server ID found for daemon '%s'", daemon.Name)
-		}
-
-		// Get Hetzner API key
-		apiKey, err := credentials.GetSecret(hetznerAPIKeySecret)
-		if err != nil || apiKey == "" {
-			return fmt.Errorf("Hetzner API key not found")
-		}
-
-		// Get server info from Hetzner
-		client := NewHetznerClient(apiKey)
-		serverInfo, err := client.GetServer(ctx, daemon.HetznerServerID)
-		if err != nil {
-			return fmt.Errorf("failed to get server info: %w", err)
-		}
-
-		serverIP = serverInfo.PublicIP
-
-		// Get SSH key from stored credentials
-		sshKeyName := fmt.Sprintf("HETZNER_SSH_KEY_%s", daemon.Name)
-		sshKey, err = credentials.GetSecret(sshKeyName)
-		if err != nil || sshKey == "" {
-			return fmt.Errorf("SSH key not found for daemon '%s'. Please run 'op cloud update %s' first to save the key", daemon.Name, daemon.Name)
-		}
-	} else {
-		return fmt.Errorf("updating '%s' provider daemons is not yet supported", daemon.Provider)
+	serverIP, sshKey, err := cloudServerAccess(daemon)
+	if err != nil {
+		return err
 	}
 
 	// Update binary and restart daemon
 	currentVersion := version.Get()
-	var err error
 
 	// Determine update strategy based on version
 	if currentVersion == "dev" {
@@ -152,6 +97,38 @@ func updateSingleCloudDaemon(daemon *config.DaemonConfig, preRelease bool) error
 	return nil
 }
 
+// cloudServerAccess looks up the address and stored SSH key of a cloud
+// daemon's server without prompting
+func cloudServerAccess(daemon *config.DaemonConfig) (serverIP, sshKey string, err error) {
+	if daemon.Provider != "hetzner" {
+		return "", "", fmt.Errorf("updating '%s' provider daemons is not yet supported", daemon.Provider)
+	}
+	if daemon.HetznerServerID == 0 {
+		return "", "", fmt.Errorf("no Hetzner server ID found for daemon '%s'", daemon.Name)
+	}
+
+	// Get Hetzner API key
+	apiKey, err := credentials.GetSecret(hetznerAPIKeySecret)
+	if err != nil || apiKey == "" {
+		return "", "", fmt.Errorf("Hetzner API key not found")
+	}
+
+	// Get server info from Hetzner
+	client := NewHetznerClient(apiKey)
+	serverInfo, err := client.GetServer(context.Background(), daemon.HetznerServerID)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get server info: %w", err)
+	}
+
+	// Get SSH key from stored credentials
+	sshKeyName := fmt.Sprintf("HETZNER_SSH_KEY_%s", daemon.Name)
+	sshKey, err = credentials.GetSecret(sshKeyName)
+	if err != nil || sshKey == "" {
+		return "", "", fmt.Errorf("SSH key not found for daemon '%s'. Please run 'op cloud update %s' first to save the key", daemon.Name, daemon.Name)
+	}
+	return serverInfo.PublicIP, sshKey, nil
+}
+
 // Update updates the opperator binary on a cloud daemon
 func Update(daemonName string, preRelease bool) error {
 	spinnerStyle := lipgloss.NewStyle().MarginLeft(2).Foreground(lipgloss.Color("#f7c0af"))
@@ -312,6 +289,90 @@ func Update(daemonName string, preRelease bool) error {
 	return nil
 }
 
+// PullUpdate asks a cloud daemon to download, verify and install the latest
+// GitHub release itself and restart through systemd, without SSH. The release
+// channel is the daemon's configured one unless preRelease is set.
+func PullUpdate(daemonName string, preRelease bool) error {
+	spinnerStyle := lipgloss.NewStyle().MarginLeft(2).Foreground(lipgloss.Color("#f7c0af"))
+
+	client, err := ipc.NewClientFromRegistry(daemonName)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	channel := ""
+	if preRelease {
+		channel = config.UpdateChannelPreRelease
+	}
+
+	fmt.Printf("\n🔄 Asking daemon '%s' to update itself\n\n", daemonName)
+
+	var result *ipc.SelfUpdateResult
+	var updateErr error
+	err = spinner.New().
+		Title("Daemon is downloading and verifying the latest release...").
+		Style(spinnerStyle).
+		Action(func() {
+			result, updateErr = client.SelfUpdate(channel)
+		}).
+		Run()
+	if err != nil {
+		return err
+	}
+	if updateErr != nil {
+		return updateErr
+	}
+
+	if !result.Updated {
+		fmt.Printf("✓ Daemon '%s' already runs the latest %s release (%s)\n", daemonName, result.Channel, result.CurrentVersion)
+		return nil
+	}
+	fmt.Printf("✓ Installed %s (was %s)\n", result.LatestVersion, result.CurrentVersion)
+
+	// The daemon restarts after responding; wait for it to come back
+	var running string
+	err = spinner.New().
+		Title("Waiting for the daemon to restart...").
+		Style(spinnerStyle).
+		Action(func() {
+			running = waitForDaemonVersion(daemonName, result.LatestVersion, 90*time.Second)
+		}).
+		Run()
+	if err != nil {
+		return err
+	}
+	if running != result.LatestVersion {
+		return fmt.Errorf("daemon '%s' did not come back on %s; check 'journalctl -u opperator' on the server", daemonName, result.LatestVersion)
+	}
+	fmt.Printf("✓ Daemon '%s' restarted on %s\n", daemonName, running)
+	return nil
+}
+
+// waitForDaemonVersion polls the daemon until it reports want or timeout
+// passes, returning the last version seen.
+func waitForDaemonVersion(daemonName, want string, timeout time.Duration) string {
+	var seen string
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		time.Sleep(2 * time.Second)
+		client, err := ipc.NewClientFromRegistry(daemonName)
+		if err != nil {
+			continue
+		}
+		info, err := client.Hello()
+		client.Close()
+		if err != nil {
+			continue
+		}
+		seen = info.Version
+		if seen == want {
+			break
+		}
+	}
+	return seen
+}
+
 // updateFromSource builds the binary locally and uploads it (for dev versions)
 func updateFromSource(serverIP, sshKey string) error {
 	// Get current working directory
@@ -19,7 +19,8 @@ type DeploymentInput struct {
 	APIKey     string
 	ServerType string // cx23, cx33, etc.
 	Location   string // nbg1, fsn1, etc.
-	Confirmed  bool   // Whether user confirmed deployment
+	Hardening  HardeningOptions
+	Confirmed  bool // Whether user confirmed deployment
 }
 
 func buildServerTypeGroup(input *DeploymentInput, serverTypes []ServerTypeOption) *huh.Group {
@@ -78,6 +79,38 @@ func buildLocationGroup(input *DeploymentInput, locations []LocationOption, serv
 	)
 }
 
+func buildHardeningGroup(input *DeploymentInput, selected *[]string) *huh.Group {
+	firewallLabel := "Restrict the daemon port to my IP"
+	if input.Hardening.AllowFrom != "" {
+		firewallLabel = fmt.Sprintf("Restrict the daemon port to %s", input.Hardening.AllowFrom)
+	}
+	options := []huh.Option[string]{
+		huh.NewOption(firewallLabel, hardenFirewall),
+		huh.NewOption("Install security updates automatically", hardenUnattendedUpgrades),
+		huh.NewOption("Ban repeated failed SSH logins (fail2ban)", hardenFail2ban),
+		huh.NewOption("Allow SSH key login only", hardenSSHKeyOnly),
+	}
+
+	swapOptions := []huh.Option[int]{huh.NewOption("No swap", 0)}
+	for _, gb := range []int{1, 2, 4, 8} {
+		swapOptions = append(swapOptions, huh.NewOption(fmt.Sprintf("%d GB", gb), gb))
+	}
+
+	return huh.NewGroup(
+		huh.NewMultiSelect[string]().
+			Title("Server Hardening").
+			Description("Space to toggle, Enter to continue").
+			Options(options...).
+			Value(selected).
+			Filterable(false),
+		huh.NewSelect[int]().
+			Title("Swap").
+			Description("Swap file size; helps small servers survive memory spikes").
+			Options(swapOptions...).
+			Value(&input.Hardening.SwapGB),
+	)
+}
+
 // RunWizardPartOne runs the first part: name and API key
 func RunWizardPartOne(hasExistingKey bool) (*DeploymentInput, error) {
 	input := &DeploymentInput{}
@@ -150,7 +183,8 @@ func RunWizardPartOne(hasExistingKey bool) (*DeploymentInput, error) {
 	return input, nil
 }
 
-// RunWizardPartTwo runs the second part: server type, location, and confirmation
+// RunWizardPartTwo runs the second part: server type, location, hardening,
+// and confirmation
 func RunWizardPartTwo(input *DeploymentInput, serverTypes []ServerTypeOption, locations []LocationOption) (*DeploymentInput, error) {
 	theme := createHuhTheme()
 	theme.FieldSeparator = lipgloss.NewStyle().SetString("\n")
@@ -181,12 +215,27 @@ func RunWizardPartTwo(input *DeploymentInput, serverTypes []ServerTypeOption, lo
 		return nil, errors.New("cancelled")
 	}
 
-	// Step 3: Confirm deployment
+	// Step 3: Choose hardening, starting from the options passed in
+	selected := input.Hardening.selected()
+	hardeningForm := huh.NewForm(
+		buildHardeningGroup(input, &selected),
+	).
+		WithTheme(theme).
+		WithWidth(80).
+		WithShowHelp(false).
+		WithShowErrors(false)
+
+	if err := hardeningForm.Run(); err != nil {
+		return nil, errors.New("cancelled")
+	}
+	input.Hardening.applySelected(selected)
+
+	// Step 4: Confirm deployment
 	confirmForm := huh.NewForm(
 		huh.NewGroup(
 			huh.NewConfirm().
 				Title("Ready to Deploy").
-				Description(fmt.Sprintf("Deploy daemon '%s' to Hetzner %s in %s?", input.Name, input.ServerType, input.Location)).
+				Description(fmt.Sprintf("Deploy daemon '%s' to Hetzner %s in %s?\n\nHardening: %s", input.Name, input.ServerType, input.Location, input.Hardening.Summary())).
 				Value(&input.Confirmed).
 				Affirmative("Deploy").
 				Negative("Cancel"),
@@ -17,6 +17,7 @@ import (
 	"opperator/internal/daemon"
 	"opperator/internal/ipc"
 	"opperator/internal/onboarding"
+	"opperator/pkg/keyringerr"
 )
 
 type Status string
@@ -80,7 +81,7 @@ func GenerateReport() Report {
 	configResult, cfgInfo := checkConfig()
 	checks = append(checks, configResult)
 
-	secretsResult := checkSecrets()
+	secretsResult := checkSecrets(cfgInfo)
 	checks = append(checks, secretsResult)
 
 	daemonResult, dInfo := checkDaemon()
@@ -89,6 +90,9 @@ func GenerateReport() Report {
 	agentResult := checkAgentRuntime(dInfo)
 	checks = append(checks, agentResult)
 
+	orphanResult := checkOrphanProcesses()
+	checks = append(checks, orphanResult)
+
 	onboardingResult := checkOnboarding()
 	checks = append(checks, onboardingResult)
 
@@ -170,6 +174,14 @@ func checkConfig() (CheckResult, *configInfo) {
 	}
 	info.dir = configDir
 	result.Details = append(result.Details, fmt.Sprintf("Config directory: %s", configDir))
+	if dirs, err := config.ResolveDirs(); err == nil {
+		if dirs.Data != configDir {
+			result.Details = append(result.Details, fmt.Sprintf("Data directory: %s", dirs.Data))
+		}
+		if dirs.State != dirs.Data {
+			result.Details = append(result.Details, fmt.Sprintf("State directory: %s", dirs.State))
+		}
+	}
 
 	stat, err := os.Stat(configDir)
 	if err != nil {
@@ -256,7 +268,7 @@ func checkDirWritable(dir string) error {
 	return nil
 }
 
-func checkSecrets() CheckResult {
+func checkSecrets(cfg *configInfo) CheckResult {
 	result := CheckResult{Name: "OPPER Authentication Status", Status: StatusOK}
 
 	exists, err := credentials.HasAPIKey()
@@ -264,7 +276,17 @@ func checkSecrets() CheckResult {
 		result.Status = StatusFail
 		result.Summary = "Unable to access system keyring"
 		result.Details = append(result.Details, err.Error())
-		result.Actions = append(result.Actions, "confirm keyring backend is available")
+		var keyringErr *keyringerr.Error
+		switch {
+		case errors.As(err, &keyringErr) && keyringErr.Locked:
+			result.Summary = "System keyring is locked"
+			result.Actions = append(result.Actions, "unlock the keyring and run 'op doctor' again")
+		case errors.As(err, &keyringErr):
+			result.Summary = "No system keyring is running"
+			result.Actions = append(result.Actions, "start a keyring service for this session (see details)")
+		default:
+			result.Actions = append(result.Actions, "confirm keyring backend is available")
+		}
 		return result
 	}
 
@@ -276,6 +298,21 @@ func checkSecrets() CheckResult {
 		result.Actions = append(result.Actions, "run 'op setup' to configure authentication")
 	}
 
+	// Agents with their own key fail to start without it
+	if cfg == nil {
+		return result
+	}
+	for _, a := range cfg.agentConfs {
+		if a.OpperAPIKey == "" {
+			continue
+		}
+		if ok, err := credentials.HasSecret(a.OpperAPIKey); err == nil && !ok {
+			result.Status = StatusWarn
+			result.Details = append(result.Details, fmt.Sprintf("agent %s uses %s, which is not stored", a.Name, a.OpperAPIKey))
+			result.Actions = append(result.Actions, fmt.Sprintf("run 'op secret create %s'", a.OpperAPIKey))
+		}
+	}
+
 	return result
 }
 
@@ -390,7 +427,7 @@ func checkAgentRuntime(info *daemonInfo) CheckResult {
 	for _, proc := range processes {
 		key := string(proc.Status)
 		statusCounts[key]++
-		if proc.Status == agent.StatusCrashed {
+		if proc.Status == agent.StatusCrashed || proc.Status == agent.StatusCrashLooping {
 			crashed = append(crashed, proc.Name)
 		}
 		if proc.Status == agent.StatusRunning {
@@ -408,7 +445,7 @@ func checkAgentRuntime(info *daemonInfo) CheckResult {
 
 	// Compose details
 	var detailParts []string
-	for _, name := range []string{"running", "stopped", "crashed", "stopping"} {
+	for _, name := range []string{"running", "stopped", "crashed", "crashlooping", "stopping"} {
 		if count, ok := statusCounts[name]; ok && count > 0 {
 			detailParts = append(detailParts, fmt.Sprintf("%s=%d", name, count))
 		}
@@ -428,6 +465,44 @@ func checkAgentRuntime(info *daemonInfo) CheckResult {
 	return result
 }
 
+// checkOrphanProcesses looks for processes of agents started by a daemon run
+// that ended without stopping them.
+func checkOrphanProcesses() CheckResult {
+	result := CheckResult{Name: "Orphan Processes", Status: StatusOK}
+
+	dataDir, err := config.GetDataDir()
+	if err != nil {
+		result.Status = StatusWarn
+		result.Summary = "Data directory unknown; orphan scan skipped"
+		result.Details = append(result.Details, err.Error())
+		return result
+	}
+
+	orphans, err := agent.FindOrphanProcesses(dataDir)
+	if err != nil {
+		result.Status = StatusWarn
+		result.Summary = "Unable to scan for orphaned agent processes"
+		result.Details = append(result.Details, err.Error())
+		return result
+	}
+	if len(orphans) == 0 {
+		result.Summary = "No processes left over from previous daemon runs"
+		return result
+	}
+
+	result.Status = StatusWarn
+	result.Summary = fmt.Sprintf("%d process(es) left over from previous daemon runs", len(orphans))
+	groups := map[int]bool{}
+	for _, o := range orphans {
+		result.Details = append(result.Details, fmt.Sprintf("%s: PID %d (group %d) %s", o.Agent, o.PID, o.PGID, o.Command))
+		if !groups[o.PGID] {
+			groups[o.PGID] = true
+			result.Actions = append(result.Actions, fmt.Sprintf("run 'kill -- -%d' to stop what is left of agent %s, or start the daemon to clean up", o.PGID, o.Agent))
+		}
+	}
+	return result
+}
+
 func checkOnboarding() CheckResult {
 	result := CheckResult{Name: "Onboarding", Status: StatusOK}
 
@@ -0,0 +1,228 @@
+// Package email is a chat channel that lets agents act as email assistants.
+// Inbound mail arrives by polling IMAP or through a provider webhook, is
+// delivered to the channel's agent, and the answer is mailed back over SMTP.
+package email
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"opperator/config"
+	"opperator/internal/channel"
+	"opperator/internal/imap"
+)
+
+const (
+	defaultInterval = time.Minute
+	// webhookQueueSize bounds webhook messages waiting for the agent.
+	webhookQueueSize = 100
+)
+
+// Channel handles the mail of one configured email channel. Messages are
+// delivered one at a time so replies in a thread stay in order.
+type Channel struct {
+	cfg      config.EmailChannel
+	interval time.Duration
+	queue    chan *imap.Message
+}
+
+func New(cfg config.EmailChannel) *Channel {
+	c := &Channel{
+		cfg:      cfg,
+		interval: defaultInterval,
+		queue:    make(chan *imap.Message, webhookQueueSize),
+	}
+	// Channel validation already rejected malformed durations.
+	if cfg.IMAP != nil {
+		if d, err := time.ParseDuration(cfg.IMAP.Interval); err == nil && d > 0 {
+			c.interval = d
+		}
+	}
+	return c
+}
+
+// Receive serves the webhook and polls IMAP until ctx is cancelled.
+func (c *Channel) Receive(ctx context.Context, deliver func(context.Context, channel.Message) error) error {
+	var deliverMu sync.Mutex
+	handle := func(msg *imap.Message) error {
+		deliverMu.Lock()
+		defer deliverMu.Unlock()
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		out, ok := c.accept(msg)
+		if !ok {
+			return nil
+		}
+		return deliver(ctx, out)
+	}
+
+	var wg sync.WaitGroup
+	if c.cfg.Webhook != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.serveWebhook(ctx)
+		}()
+	}
+	if c.cfg.IMAP != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.pollIMAP(ctx, handle)
+		}()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return nil
+		case msg := <-c.queue:
+			handle(msg)
+		}
+	}
+}
+
+func (c *Channel) pollIMAP(ctx context.Context, handle func(*imap.Message) error) {
+	for {
+		if err := c.pollOnce(ctx, handle); err != nil && ctx.Err() == nil {
+			log.Printf("[Email] Channel %s: imap poll failed: %v", c.cfg.Name, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(c.interval):
+		}
+	}
+}
+
+// pollOnce handles the unseen messages in the mailbox, flagging each seen
+// once handled so a daemon stopping mid-exchange picks it up again.
+func (c *Channel) pollOnce(ctx context.Context, handle func(*imap.Message) error) error {
+	cfg := c.cfg.IMAP
+	client, err := imap.Dial(ctx, cfg.Address)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	if err := client.Login(cfg.Username, cfg.Password); err != nil {
+		return err
+	}
+	if err := client.Select(cfg.Mailbox); err != nil {
+		return err
+	}
+	uids, err := client.SearchUnseen()
+	if err != nil {
+		return err
+	}
+	for _, uid := range uids {
+		raw, err := client.Fetch(uid)
+		if err != nil {
+			return err
+		}
+		msg, err := imap.ParseMessage(raw)
+		if err != nil {
+			log.Printf("[Email] Channel %s: skipping unreadable message %d: %v", c.cfg.Name, uid, err)
+		} else if handle(msg) != nil {
+			return nil
+		}
+		if err := client.MarkSeen(uid); err != nil {
+			return err
+		}
+	}
+	return client.Logout()
+}
+
+// accept filters out mail the agent should not answer and turns the rest
+// into a channel message.
+func (c *Channel) accept(msg *imap.Message) (channel.Message, bool) {
+	sender := msg.Address()
+	if auto := strings.ToLower(msg.AutoSubmitted); auto != "" && auto != "no" {
+		log.Printf("[Email] Channel %s: ignoring automated message from %s", c.cfg.Name, sender)
+		return channel.Message{}, false
+	}
+	if !c.allowed(sender) {
+		log.Printf("[Email] Channel %s: ignoring message from unlisted sender %s", c.cfg.Name, sender)
+		return channel.Message{}, false
+	}
+	if c.cfg.SMTP != nil && strings.EqualFold(sender, fromAddress(c.cfg.SMTP.From)) {
+		return channel.Message{}, false
+	}
+	log.Printf("[Email] Channel %s: message from %s: %q", c.cfg.Name, sender, msg.Subject)
+	return channel.Message{
+		Thread: threadID(msg),
+		Sender: sender,
+		Text:   c.prompt(msg),
+		Reply:  msg,
+	}, true
+}
+
+// allowed matches sender against allowed_senders, case-insensitively.
+func (c *Channel) allowed(sender string) bool {
+	sender = strings.ToLower(sender)
+	if sender == "" {
+		return false
+	}
+	for _, pattern := range c.cfg.AllowedSenders {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if pattern == sender {
+			return true
+		}
+		if ok, _ := path.Match(pattern, sender); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Channel) prompt(msg *imap.Message) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Email received from %s\n", msg.From)
+	fmt.Fprintf(&b, "Subject: %s\n", msg.Subject)
+	if msg.Date != "" {
+		fmt.Fprintf(&b, "Date: %s\n", msg.Date)
+	}
+	b.WriteString("\n")
+	b.WriteString(msg.Text)
+	if c.cfg.SMTP != nil {
+		b.WriteString("\n\nYour final response will be sent to the sender as the email reply.")
+	}
+	return b.String()
+}
+
+// Send mails text back to the sender. Without SMTP the answer is only kept
+// in the conversation.
+func (c *Channel) Send(ctx context.Context, msg channel.Message, text string) error {
+	if c.cfg.SMTP == nil || strings.TrimSpace(text) == "" {
+		return nil
+	}
+	return sendReply(ctx, c.cfg.SMTP, msg.Reply.(*imap.Message), text)
+}
+
+// Progress reports nothing; mail has no status to update.
+func (c *Channel) Progress(context.Context, channel.Message) channel.Progress {
+	return noProgress{}
+}
+
+type noProgress struct{}
+
+func (noProgress) Update(string) {}
+func (noProgress) Done(error)    {}
+
+// threadID identifies the conversation a message belongs to: the first
+// message of its thread. Replies keep the root as their first reference.
+func threadID(msg *imap.Message) string {
+	if len(msg.References) > 0 {
+		return msg.References[0]
+	}
+	if msg.InReplyTo != "" {
+		return msg.InReplyTo
+	}
+	return msg.MessageID
+}
@@ -0,0 +1,147 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"opperator/config"
+	"opperator/internal/imap"
+)
+
+// sendReply answers orig with body, threading it through In-Reply-To and
+// References so the sender's client and the next inbound message keep the
+// conversation together.
+func sendReply(ctx context.Context, cfg *config.EmailSMTP, orig *imap.Message, body string) error {
+	to := orig.ReplyTo
+	if to == "" {
+		to = orig.From
+	}
+	rcpt, err := mail.ParseAddress(to)
+	if err != nil {
+		return fmt.Errorf("invalid recipient %q: %w", to, err)
+	}
+	from := fromAddress(cfg.From)
+
+	subject := orig.Subject
+	if !strings.HasPrefix(strings.ToLower(subject), "re:") {
+		subject = "Re: " + subject
+	}
+	references := orig.References
+	if orig.MessageID != "" {
+		references = append(append([]string(nil), references...), orig.MessageID)
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", rcpt.String())
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "Message-ID: %s\r\n", newMessageID(from))
+	if orig.MessageID != "" {
+		fmt.Fprintf(&msg, "In-Reply-To: %s\r\n", orig.MessageID)
+	}
+	if len(references) > 0 {
+		fmt.Fprintf(&msg, "References: %s\r\n", strings.Join(references, " "))
+	}
+	msg.WriteString("Auto-Submitted: auto-replied\r\n")
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+	qp := quotedprintable.NewWriter(&msg)
+	qp.Write([]byte(strings.ReplaceAll(body, "\n", "\r\n")))
+	qp.Close()
+	msg.WriteString("\r\n")
+
+	// net/smtp has no context support; run it so ctx still bounds the wait
+	errc := make(chan error, 1)
+	go func() {
+		errc <- send(cfg, from, rcpt.Address, msg.Bytes())
+	}()
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// send delivers one message. Port 465 speaks TLS from the start; other ports
+// are upgraded by net/smtp when the server advertises STARTTLS.
+func send(cfg *config.EmailSMTP, from, to string, msg []byte) error {
+	host, port, err := net.SplitHostPort(cfg.Address)
+	if err != nil {
+		host, port = cfg.Address, "587"
+	}
+	addr := net.JoinHostPort(host, port)
+	var auth smtp.Auth
+	if cfg.Username != "" {
+		auth = smtp.PlainAuth("", cfg.Username, cfg.Password, host)
+	}
+	if port != "465" {
+		return smtp.SendMail(addr, auth, from, []string{to}, msg)
+	}
+
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: 30 * time.Second}, "tcp", addr, &tls.Config{ServerName: host})
+	if err != nil {
+		return err
+	}
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+	if auth != nil {
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// fromAddress returns the bare address of a From value such as
+// "Assistant <me@example.com>".
+func fromAddress(from string) string {
+	addr, err := mail.ParseAddress(from)
+	if err != nil {
+		return strings.TrimSpace(from)
+	}
+	return addr.Address
+}
+
+func newMessageID(from string) string {
+	domain := "opperator.local"
+	if _, d, ok := strings.Cut(from, "@"); ok && d != "" {
+		domain = d
+	}
+	buf := make([]byte, 12)
+	rand.Read(buf)
+	return fmt.Sprintf("<%s@%s>", hex.EncodeToString(buf), domain)
+}
@@ -0,0 +1,108 @@
+package email
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"opperator/internal/imap"
+)
+
+// maxWebhookBody caps a posted message, attachments included.
+const maxWebhookBody = 25 << 20
+
+// serveWebhook listens for messages POSTed by a mail provider until ctx is
+// cancelled.
+func (c *Channel) serveWebhook(ctx context.Context) {
+	srv := &http.Server{
+		Addr:              c.cfg.Webhook.Listen,
+		Handler:           c,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+	log.Printf("[Email] Channel %s: webhook listening on %s", c.cfg.Name, c.cfg.Webhook.Listen)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Printf("[Email] Channel %s: webhook stopped: %v", c.cfg.Name, err)
+	}
+}
+
+// ServeHTTP accepts one inbound message and queues it for the agent. The body
+// may be the raw message (message/rfc822), a form with the raw message in an
+// "email" or "body-mime" field as sent by SendGrid and Mailgun, or JSON with
+// from, subject, text, message_id, in_reply_to and references.
+func (c *Channel) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !c.authorized(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxWebhookBody)
+	msg, err := decodeWebhook(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	select {
+	case c.queue <- msg:
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		http.Error(w, "too many pending messages", http.StatusServiceUnavailable)
+	}
+}
+
+func (c *Channel) authorized(r *http.Request) bool {
+	token := r.URL.Query().Get("token")
+	if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		token = bearer
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(c.cfg.Webhook.Secret)) == 1
+}
+
+func decodeWebhook(r *http.Request) (*imap.Message, error) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/json":
+		var msg imap.Message
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+		if strings.TrimSpace(msg.From) == "" {
+			return nil, fmt.Errorf("from is required")
+		}
+		return &msg, nil
+	case "multipart/form-data", "application/x-www-form-urlencoded":
+		if err := r.ParseMultipartForm(maxWebhookBody); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+			return nil, fmt.Errorf("invalid form: %w", err)
+		}
+		raw := r.FormValue("email")
+		if raw == "" {
+			raw = r.FormValue("body-mime")
+		}
+		if raw == "" {
+			return nil, fmt.Errorf("form has no email or body-mime field with the raw message")
+		}
+		return imap.ParseMessage([]byte(raw))
+	default:
+		raw, err := io.ReadAll(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		return imap.ParseMessage(raw)
+	}
+}
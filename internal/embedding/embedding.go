@@ -0,0 +1,49 @@
+// Package embedding holds what the stores searched by meaning share: the
+// embedder they are given and how vectors are stored and compared.
+package embedding
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+)
+
+// Embedder returns a vector for each text and the model that produced them.
+// Vectors of different models cannot be compared.
+type Embedder func(ctx context.Context, texts []string) ([][]float32, string, error)
+
+// Encode packs a vector into a BLOB.
+func Encode(v []float32) []byte {
+	b := make([]byte, 4*len(v))
+	for i, f := range v {
+		binary.LittleEndian.PutUint32(b[4*i:], math.Float32bits(f))
+	}
+	return b
+}
+
+// Decode unpacks a vector stored with Encode.
+func Decode(b []byte) []float32 {
+	v := make([]float32, len(b)/4)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(b[4*i:]))
+	}
+	return v
+}
+
+// Cosine returns the cosine similarity of a and b, or 0 when they differ in
+// length or either is zero.
+func Cosine(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
@@ -0,0 +1,187 @@
+package eval
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Runner sends messages to agent in one new conversation and returns the
+// reply to the last one.
+type Runner func(ctx context.Context, agent string, messages []string) (string, error)
+
+// Judge decides whether a reply meets a criterion and explains why.
+type Judge func(ctx context.Context, in JudgeInput) (pass bool, reason string, err error)
+
+// JudgeInput is what a judge grades.
+type JudgeInput struct {
+	Criteria  string `json:"criteria"`
+	Prompt    string `json:"prompt"`
+	Response  string `json:"response"`
+	Reference string `json:"reference,omitempty"`
+}
+
+// ConversationLoader returns the user messages of a recorded conversation
+// and its last assistant reply.
+type ConversationLoader func(ctx context.Context, id string) (messages []string, lastReply string, err error)
+
+// Options wires a run to the agent, judge and conversation store.
+type Options struct {
+	// Agent overrides the suite's agent.
+	Agent            string
+	Run              Runner
+	Judge            Judge
+	LoadConversation ConversationLoader
+	// Progress is called after each case.
+	Progress func(Result)
+}
+
+// Result is the outcome of one case.
+type Result struct {
+	Case       string   `json:"case"`
+	Passed     bool     `json:"passed"`
+	Reply      string   `json:"reply,omitempty"`
+	Failures   []string `json:"failures,omitempty"`
+	Error      string   `json:"error,omitempty"`
+	DurationMS int64    `json:"duration_ms"`
+}
+
+// Report is the outcome of a suite.
+type Report struct {
+	Suite   string   `json:"suite"`
+	Agent   string   `json:"agent"`
+	Passed  int      `json:"passed"`
+	Total   int      `json:"total"`
+	Results []Result `json:"results"`
+}
+
+// PassRate is the share of cases that passed, from 0 to 1.
+func (r Report) PassRate() float64 {
+	if r.Total == 0 {
+		return 0
+	}
+	return float64(r.Passed) / float64(r.Total)
+}
+
+// Run runs every case of suite in order. A case that errors counts as
+// failed; the run stops early only when ctx is cancelled.
+func Run(ctx context.Context, suite *Suite, opts Options) Report {
+	agent := suite.Agent
+	if opts.Agent != "" {
+		agent = opts.Agent
+	}
+	report := Report{Suite: suite.Name, Agent: agent}
+	for _, c := range suite.Cases {
+		if ctx.Err() != nil {
+			break
+		}
+		result := runCase(ctx, agent, c, opts)
+		report.Results = append(report.Results, result)
+		report.Total++
+		if result.Passed {
+			report.Passed++
+		}
+		if opts.Progress != nil {
+			opts.Progress(result)
+		}
+	}
+	return report
+}
+
+func runCase(ctx context.Context, agent string, c Case, opts Options) (result Result) {
+	start := time.Now()
+	result.Case = c.Name
+	defer func() { result.DurationMS = time.Since(start).Milliseconds() }()
+
+	messages := c.Messages()
+	expect := c.Expect
+	if c.Conversation != "" {
+		if opts.LoadConversation == nil {
+			result.Error = "recorded conversations are not available"
+			return result
+		}
+		recorded, lastReply, err := opts.LoadConversation(ctx, c.Conversation)
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		if len(recorded) == 0 {
+			result.Error = fmt.Sprintf("conversation %s has no user messages", c.Conversation)
+			return result
+		}
+		messages = recorded
+		if expect.Reference == "" {
+			expect.Reference = lastReply
+		}
+	}
+	if expect.Reference != "" && expect.Equals == "" && len(expect.Contains) == 0 && expect.Regex == "" && expect.Judge == "" {
+		expect.Judge = DefaultJudgeCriteria
+	}
+	if expect.empty() {
+		result.Error = "nothing to check the reply against"
+		return result
+	}
+
+	reply, err := opts.Run(ctx, agent, messages)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Reply = reply
+
+	failures, err := Grade(ctx, expect, messages[len(messages)-1], reply, opts.Judge)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Failures = failures
+	result.Passed = len(failures) == 0
+	return result
+}
+
+// Grade checks reply against expect and returns a description of every
+// check it failed. Equals ignores surrounding whitespace; contains and regex
+// are case-sensitive unless the pattern says otherwise.
+func Grade(ctx context.Context, expect Expect, prompt, reply string, judge Judge) ([]string, error) {
+	var failures []string
+	if expect.Equals != "" && strings.TrimSpace(reply) != strings.TrimSpace(expect.Equals) {
+		failures = append(failures, fmt.Sprintf("expected exactly %q", expect.Equals))
+	}
+	for _, s := range expect.Contains {
+		if !strings.Contains(reply, s) {
+			failures = append(failures, fmt.Sprintf("missing %q", s))
+		}
+	}
+	if expect.Regex != "" {
+		re, err := regexp.Compile(expect.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex: %w", err)
+		}
+		if !re.MatchString(reply) {
+			failures = append(failures, fmt.Sprintf("no match for /%s/", expect.Regex))
+		}
+	}
+	if expect.Judge != "" {
+		if judge == nil {
+			return nil, fmt.Errorf("no judge configured")
+		}
+		pass, reason, err := judge(ctx, JudgeInput{
+			Criteria:  expect.Judge,
+			Prompt:    prompt,
+			Response:  reply,
+			Reference: expect.Reference,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("judge: %w", err)
+		}
+		if !pass {
+			if reason == "" {
+				reason = "criterion not met"
+			}
+			failures = append(failures, "judge: "+reason)
+		}
+	}
+	return failures, nil
+}
@@ -0,0 +1,104 @@
+package eval
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultJudgeCriteria grades a reply against its reference when a case
+// gives a reference but no other expectation.
+const DefaultJudgeCriteria = "The response answers the prompt with the same substance as the reference response. Wording, formatting and extra detail may differ."
+
+// Suite is a set of cases run against one agent or core agent.
+type Suite struct {
+	Name string `yaml:"name"`
+	// Agent runs the cases; empty uses the default agent.
+	Agent string `yaml:"agent"`
+	Cases []Case `yaml:"cases"`
+}
+
+// Case is one prompt, or a sequence of them in one conversation, and what
+// the final reply is expected to satisfy.
+type Case struct {
+	Name   string   `yaml:"name"`
+	Prompt string   `yaml:"prompt"`
+	Turns  []string `yaml:"turns"`
+	// Conversation replays the user messages of a recorded conversation.
+	// Its last assistant reply is the reference unless one is given.
+	Conversation string `yaml:"conversation"`
+	Expect       Expect `yaml:"expect"`
+}
+
+// Expect lists the checks a reply must pass; every check given must pass.
+type Expect struct {
+	Equals   string   `yaml:"equals"`
+	Contains []string `yaml:"contains"`
+	Regex    string   `yaml:"regex"`
+	// Judge is a criterion an LLM judge grades the reply against.
+	Judge     string `yaml:"judge"`
+	Reference string `yaml:"reference"`
+}
+
+func (e Expect) empty() bool {
+	return e.Equals == "" && len(e.Contains) == 0 && e.Regex == "" && e.Judge == "" && e.Reference == ""
+}
+
+// Messages returns the user messages the case sends, in order. Conversation
+// cases have theirs filled in by the runner.
+func (c Case) Messages() []string {
+	if c.Prompt != "" {
+		return append([]string{c.Prompt}, c.Turns...)
+	}
+	return c.Turns
+}
+
+// LoadSuite reads and validates a suite file.
+func LoadSuite(path string) (*Suite, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read suite: %w", err)
+	}
+	var suite Suite
+	if err := yaml.Unmarshal(data, &suite); err != nil {
+		return nil, fmt.Errorf("failed to parse suite %s: %w", path, err)
+	}
+	if err := suite.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid suite %s: %w", path, err)
+	}
+	return &suite, nil
+}
+
+// Validate checks that every case has something to send and something to
+// check, and names unnamed cases after their position.
+func (s *Suite) Validate() error {
+	if len(s.Cases) == 0 {
+		return fmt.Errorf("no cases")
+	}
+	for i := range s.Cases {
+		c := &s.Cases[i]
+		c.Name = strings.TrimSpace(c.Name)
+		if c.Name == "" {
+			c.Name = fmt.Sprintf("case %d", i+1)
+		}
+		hasMessages := len(c.Messages()) > 0
+		if c.Conversation == "" && !hasMessages {
+			return fmt.Errorf("%s: set prompt, turns or conversation", c.Name)
+		}
+		if c.Conversation != "" && hasMessages {
+			return fmt.Errorf("%s: conversation can't be combined with prompt or turns", c.Name)
+		}
+		if c.Conversation == "" && c.Expect.empty() {
+			return fmt.Errorf("%s: expect needs at least one check", c.Name)
+		}
+		if c.Expect.Regex != "" {
+			if _, err := regexp.Compile(c.Expect.Regex); err != nil {
+				return fmt.Errorf("%s: invalid regex: %w", c.Name, err)
+			}
+		}
+	}
+	return nil
+}
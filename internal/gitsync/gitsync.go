@@ -0,0 +1,106 @@
+// Package gitsync fast-forwards a config directory kept in git, for
+// GitOps-style management of agents.yaml and agent code.
+package gitsync
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// ErrDirty is returned when tracked files in the checkout have local
+// changes, which a pull would otherwise have to merge or discard.
+var ErrDirty = errors.New("config directory has uncommitted changes")
+
+// Result describes a pull. From and To are the commits before and after;
+// they are equal when there was nothing new. Files lists the changed paths
+// relative to the config directory. Restarted is filled in by the daemon with
+// the running agents it restarted because their code changed.
+type Result struct {
+	Remote    string   `json:"remote"`
+	Branch    string   `json:"branch"`
+	From      string   `json:"from"`
+	To        string   `json:"to"`
+	Files     []string `json:"files,omitempty"`
+	Restarted []string `json:"restarted,omitempty"`
+}
+
+// Changed reports whether the pull moved the checkout.
+func (r *Result) Changed() bool {
+	return r.From != r.To
+}
+
+// Pull fetches branch from remote into the checkout at dir and fast-forwards
+// to it. An empty branch means the one checked out. It refuses to pull over
+// local changes to tracked files or when the histories have diverged.
+func Pull(ctx context.Context, dir, remote, branch string) (*Result, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, fmt.Errorf("git is not installed")
+	}
+	if _, err := git(ctx, dir, "rev-parse", "--is-inside-work-tree"); err != nil {
+		return nil, fmt.Errorf("%s is not a git checkout", dir)
+	}
+	if branch == "" {
+		head, err := git(ctx, dir, "rev-parse", "--abbrev-ref", "HEAD")
+		if err != nil {
+			return nil, err
+		}
+		if head == "HEAD" {
+			return nil, fmt.Errorf("config directory is on a detached HEAD; set git_sync.branch")
+		}
+		branch = head
+	}
+	status, err := git(ctx, dir, "status", "--porcelain", "--untracked-files=no")
+	if err != nil {
+		return nil, err
+	}
+	if status != "" {
+		return nil, ErrDirty
+	}
+
+	res := &Result{Remote: remote, Branch: branch}
+	if res.From, err = git(ctx, dir, "rev-parse", "HEAD"); err != nil {
+		return nil, err
+	}
+	if _, err := git(ctx, dir, "fetch", "--quiet", remote, branch); err != nil {
+		return nil, err
+	}
+	if _, err := git(ctx, dir, "merge", "--ff-only", "--quiet", "FETCH_HEAD"); err != nil {
+		return nil, fmt.Errorf("cannot fast-forward to %s/%s: %w", remote, branch, err)
+	}
+	if res.To, err = git(ctx, dir, "rev-parse", "HEAD"); err != nil {
+		return nil, err
+	}
+	if res.Changed() {
+		files, err := git(ctx, dir, "diff", "--name-only", "--relative", res.From, res.To)
+		if err != nil {
+			return nil, err
+		}
+		if files != "" {
+			res.Files = strings.Split(files, "\n")
+		}
+	}
+	return res, nil
+}
+
+// git runs a git command in dir and returns its trimmed output.
+func git(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	// Never wait on a credential prompt nobody can answer.
+	cmd.Env = append(cmd.Environ(), "GIT_TERMINAL_PROMPT=0")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return "", fmt.Errorf("git %s: %s", args[0], msg)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
@@ -0,0 +1,121 @@
+// Package hooks runs user scripts configured in daemon.yaml when events such
+// as agent crashes or failed tasks happen on the daemon, so site-specific
+// automation does not need changes to the daemon itself.
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"opperator/config"
+)
+
+// outputLogLimit caps how much of a failed hook's output is logged.
+const outputLogLimit = 2048
+
+// Event is what a hook receives as JSON on stdin.
+type Event struct {
+	Event  string         `json:"event"`
+	Agent  string         `json:"agent,omitempty"`
+	Daemon string         `json:"daemon"`
+	Time   time.Time      `json:"time"`
+	Data   map[string]any `json:"data,omitempty"`
+}
+
+// Runner runs the hooks matching each event. Hooks run in the background so
+// callers never wait on user scripts.
+type Runner struct {
+	hooks []config.HookConfig
+	dir   string
+	host  string
+	wg    sync.WaitGroup
+}
+
+// New builds a runner for the hooks section of daemon.yaml. Hooks run in the
+// config directory.
+func New(hooks []config.HookConfig) *Runner {
+	r := &Runner{hooks: hooks}
+	r.dir, _ = config.GetConfigDir()
+	r.host, _ = os.Hostname()
+	return r
+}
+
+// Run starts every hook matching ev. It returns immediately.
+func (r *Runner) Run(ev Event) {
+	if r == nil {
+		return
+	}
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+	if ev.Daemon == "" {
+		ev.Daemon = r.host
+	}
+	for _, h := range r.hooks {
+		if !h.Matches(ev.Event, ev.Agent) {
+			continue
+		}
+		r.wg.Add(1)
+		go r.run(h, ev)
+	}
+}
+
+func (r *Runner) run(h config.HookConfig, ev Event) {
+	defer r.wg.Done()
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		log.Printf("[Hooks] Failed to encode %s: %v", ev.Event, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.RunTimeout())
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "sh", "-c", h.Command)
+	cmd.Dir = r.dir
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Env = append(os.Environ(),
+		"OPPERATOR_HOOK_EVENT="+ev.Event,
+		"OPPERATOR_HOOK_AGENT="+ev.Agent,
+	)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	cmd.WaitDelay = time.Second
+
+	start := time.Now()
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			err = ctx.Err()
+		}
+		out := strings.TrimSpace(output.String())
+		if len(out) > outputLogLimit {
+			out = out[len(out)-outputLogLimit:]
+		}
+		log.Printf("[Hooks] %s hook %q failed: %v\n%s", ev.Event, h.Command, err, out)
+		return
+	}
+	log.Printf("[Hooks] Ran %s hook %q in %s", ev.Event, h.Command, time.Since(start).Round(time.Millisecond))
+}
+
+// Wait blocks until running hooks finish or ctx is done.
+func (r *Runner) Wait(ctx context.Context) {
+	if r == nil {
+		return
+	}
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+}
@@ -0,0 +1,206 @@
+// Package imap is a minimal IMAP4rev1 client covering what the daemon needs
+// to consume an inbox: log in, find unseen messages, fetch and flag them.
+package imap
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// commandTimeout bounds a single command round trip.
+const commandTimeout = 60 * time.Second
+
+// Client is a connection to an IMAP server. It is not safe for concurrent
+// use.
+type Client struct {
+	conn net.Conn
+	r    *bufio.Reader
+	tag  int
+}
+
+// response is one untagged server line with any literals it carried.
+type response struct {
+	text     string
+	literals [][]byte
+}
+
+// Dial connects over TLS to address (host:port, port 993 when omitted) and
+// reads the server greeting.
+func Dial(ctx context.Context, address string) (*Client, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		host, port = address, "993"
+	}
+	dialer := &tls.Dialer{Config: &tls.Config{ServerName: host}}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		return nil, fmt.Errorf("imap dial %s: %w", address, err)
+	}
+	c := &Client{conn: conn, r: bufio.NewReader(conn)}
+	conn.SetDeadline(time.Now().Add(commandTimeout))
+	greeting, err := c.r.ReadString('\n')
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("imap greeting: %w", err)
+	}
+	if !strings.HasPrefix(greeting, "* OK") && !strings.HasPrefix(greeting, "* PREAUTH") {
+		conn.Close()
+		return nil, fmt.Errorf("imap greeting: %s", strings.TrimSpace(greeting))
+	}
+	return c, nil
+}
+
+// Close drops the connection without logging out.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Login authenticates with a plain username and password.
+func (c *Client) Login(username, password string) error {
+	_, err := c.command("LOGIN %s %s", quote(username), quote(password))
+	return err
+}
+
+// Select opens mailbox for reading and writing.
+func (c *Client) Select(mailbox string) error {
+	if mailbox == "" {
+		mailbox = "INBOX"
+	}
+	_, err := c.command("SELECT %s", quote(mailbox))
+	return err
+}
+
+// SearchUnseen returns the UIDs of messages without the \Seen flag.
+func (c *Client) SearchUnseen() ([]uint32, error) {
+	responses, err := c.command("UID SEARCH UNSEEN")
+	if err != nil {
+		return nil, err
+	}
+	var uids []uint32
+	for _, resp := range responses {
+		fields := strings.Fields(resp.text)
+		if len(fields) < 2 || !strings.EqualFold(fields[0], "SEARCH") {
+			continue
+		}
+		for _, f := range fields[1:] {
+			uid, err := strconv.ParseUint(f, 10, 32)
+			if err == nil {
+				uids = append(uids, uint32(uid))
+			}
+		}
+	}
+	return uids, nil
+}
+
+// Fetch returns the raw RFC 822 message with the given UID without marking
+// it seen.
+func (c *Client) Fetch(uid uint32) ([]byte, error) {
+	responses, err := c.command("UID FETCH %d BODY.PEEK[]", uid)
+	if err != nil {
+		return nil, err
+	}
+	for _, resp := range responses {
+		if strings.Contains(strings.ToUpper(resp.text), "FETCH") && len(resp.literals) > 0 {
+			return resp.literals[0], nil
+		}
+	}
+	return nil, fmt.Errorf("imap: message %d not returned", uid)
+}
+
+// MarkSeen sets the \Seen flag on a message.
+func (c *Client) MarkSeen(uid uint32) error {
+	_, err := c.command(`UID STORE %d +FLAGS.SILENT (\Seen)`, uid)
+	return err
+}
+
+// Logout ends the session and closes the connection.
+func (c *Client) Logout() error {
+	_, err := c.command("LOGOUT")
+	c.conn.Close()
+	return err
+}
+
+// command sends a tagged command and collects untagged responses until the
+// tagged completion, which must be OK.
+func (c *Client) command(format string, args ...any) ([]response, error) {
+	c.tag++
+	tag := fmt.Sprintf("A%04d", c.tag)
+	c.conn.SetDeadline(time.Now().Add(commandTimeout))
+	if _, err := fmt.Fprintf(c.conn, "%s %s\r\n", tag, fmt.Sprintf(format, args...)); err != nil {
+		return nil, fmt.Errorf("imap write: %w", err)
+	}
+
+	var responses []response
+	for {
+		resp, err := c.readResponse()
+		if err != nil {
+			return nil, fmt.Errorf("imap read: %w", err)
+		}
+		if strings.HasPrefix(resp.text, tag+" ") {
+			status := strings.TrimPrefix(resp.text, tag+" ")
+			if !strings.HasPrefix(strings.ToUpper(status), "OK") {
+				verb, _, _ := strings.Cut(format, " ")
+				return nil, fmt.Errorf("imap %s: %s", verb, status)
+			}
+			return responses, nil
+		}
+		if strings.HasPrefix(resp.text, "* ") {
+			resp.text = strings.TrimPrefix(resp.text, "* ")
+			responses = append(responses, resp)
+		}
+	}
+}
+
+// readResponse reads one logical response line, reading any {n} literals it
+// announces.
+func (c *Client) readResponse() (response, error) {
+	var resp response
+	var text strings.Builder
+	for {
+		line, err := c.r.ReadString('\n')
+		if err != nil {
+			return resp, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		size, ok := literalSize(line)
+		if !ok {
+			text.WriteString(line)
+			resp.text = text.String()
+			return resp, nil
+		}
+		text.WriteString(line[:strings.LastIndex(line, "{")])
+		literal := make([]byte, size)
+		if _, err := io.ReadFull(c.r, literal); err != nil {
+			return resp, err
+		}
+		resp.literals = append(resp.literals, literal)
+	}
+}
+
+func literalSize(line string) (int, bool) {
+	if !strings.HasSuffix(line, "}") {
+		return 0, false
+	}
+	open := strings.LastIndex(line, "{")
+	if open < 0 {
+		return 0, false
+	}
+	size, err := strconv.Atoi(line[open+1 : len(line)-1])
+	if err != nil || size < 0 {
+		return 0, false
+	}
+	return size, true
+}
+
+func quote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
@@ -0,0 +1,136 @@
+package imap
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+)
+
+// maxTextSize caps the extracted text body.
+const maxTextSize = 256 * 1024
+
+// Message is the parts of an email the daemon acts on.
+type Message struct {
+	From       string   `json:"from"`
+	ReplyTo    string   `json:"reply_to,omitempty"`
+	To         []string `json:"to,omitempty"`
+	Subject    string   `json:"subject"`
+	Date       string   `json:"date,omitempty"`
+	MessageID  string   `json:"message_id,omitempty"`
+	InReplyTo  string   `json:"in_reply_to,omitempty"`
+	References []string `json:"references,omitempty"`
+	Text       string   `json:"text"`
+	// AutoSubmitted holds the Auto-Submitted header, set by auto-responders
+	// and mailing robots that must not be answered.
+	AutoSubmitted string `json:"auto_submitted,omitempty"`
+}
+
+var wordDecoder = mime.WordDecoder{}
+
+// ParseMessage extracts headers and the plain text body of a raw message.
+// For multipart messages the first text/plain part wins, falling back to
+// the first text/html part.
+func ParseMessage(raw []byte) (*Message, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("parse message: %w", err)
+	}
+	h := msg.Header
+	out := &Message{
+		From:       decodeHeader(h.Get("From")),
+		ReplyTo:    decodeHeader(h.Get("Reply-To")),
+		Subject:    decodeHeader(h.Get("Subject")),
+		Date:       h.Get("Date"),
+		MessageID:  strings.TrimSpace(h.Get("Message-Id")),
+		InReplyTo:  strings.TrimSpace(h.Get("In-Reply-To")),
+		References: strings.Fields(h.Get("References")),
+
+		AutoSubmitted: strings.TrimSpace(h.Get("Auto-Submitted")),
+	}
+	if addrs, err := h.AddressList("To"); err == nil {
+		for _, addr := range addrs {
+			out.To = append(out.To, addr.Address)
+		}
+	}
+
+	text, err := extractText(h.Get("Content-Type"), h.Get("Content-Transfer-Encoding"), msg.Body)
+	if err != nil {
+		return nil, err
+	}
+	if len(text) > maxTextSize {
+		text = text[:maxTextSize]
+	}
+	out.Text = strings.TrimSpace(text)
+	return out, nil
+}
+
+// Address returns the bare address of the From header.
+func (m *Message) Address() string {
+	addr, err := mail.ParseAddress(m.From)
+	if err != nil {
+		return strings.TrimSpace(m.From)
+	}
+	return addr.Address
+}
+
+func extractText(contentType, encoding string, body io.Reader) (string, error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = "text/plain"
+	}
+	if strings.HasPrefix(mediaType, "multipart/") {
+		reader := multipart.NewReader(body, params["boundary"])
+		var html string
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				return html, nil
+			}
+			if err != nil {
+				return "", fmt.Errorf("read multipart body: %w", err)
+			}
+			partType := part.Header.Get("Content-Type")
+			text, err := extractText(partType, part.Header.Get("Content-Transfer-Encoding"), part)
+			if err != nil {
+				return "", err
+			}
+			if strings.HasPrefix(partType, "text/html") {
+				if html == "" {
+					html = text
+				}
+				continue
+			}
+			if text != "" {
+				return text, nil
+			}
+		}
+	}
+	if !strings.HasPrefix(mediaType, "text/") {
+		return "", nil
+	}
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	}
+	data, err := io.ReadAll(io.LimitReader(body, maxTextSize))
+	if err != nil {
+		return "", fmt.Errorf("read body: %w", err)
+	}
+	return string(data), nil
+}
+
+func decodeHeader(value string) string {
+	decoded, err := wordDecoder.DecodeHeader(value)
+	if err != nil {
+		return value
+	}
+	return decoded
+}
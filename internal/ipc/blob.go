@@ -0,0 +1,89 @@
+package ipc
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// BlobChunkSize is how much of a file each blob upload request carries.
+const BlobChunkSize = 256 * 1024
+
+// blobRefKey marks a command argument that refers to an uploaded blob.
+const blobRefKey = "$blob"
+
+// BlobRef returns the command argument value referring to an uploaded blob.
+// The daemon replaces it with the path of the blob's file before the command
+// reaches the agent.
+func BlobRef(id string) map[string]interface{} {
+	return map[string]interface{}{blobRefKey: id}
+}
+
+// BlobRefID returns the blob an argument value refers to, if it is a blob
+// reference.
+func BlobRefID(value interface{}) (string, bool) {
+	ref, ok := value.(map[string]interface{})
+	if !ok || len(ref) != 1 {
+		return "", false
+	}
+	id, ok := ref[blobRefKey].(string)
+	return id, ok && id != ""
+}
+
+// UploadBlob sends the file at path to the daemon in chunks and returns the
+// ID of the blob holding it.
+func (c *Client) UploadBlob(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	req := Request{Type: RequestUploadBlob, BlobName: filepath.Base(path)}
+	buf := make([]byte, BlobChunkSize)
+	for {
+		n, err := io.ReadFull(f, buf)
+		if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+			return "", fmt.Errorf("read %s: %w", path, err)
+		}
+		req.BlobData = buf[:n]
+		req.BlobDone = n < len(buf)
+
+		resp, sendErr := c.sendRequestWithTimeout(req, time.Minute)
+		if sendErr != nil {
+			return "", sendErr
+		}
+		if !resp.Success {
+			return "", resp.errOr("failed to upload " + path)
+		}
+		if req.BlobDone {
+			return resp.BlobID, nil
+		}
+		req.BlobID = resp.BlobID
+		req.BlobOffset += int64(n)
+	}
+}
+
+// DownloadBlob writes the contents of a blob to w.
+func (c *Client) DownloadBlob(id string, w io.Writer) error {
+	req := Request{Type: RequestDownloadBlob, BlobID: id}
+	for {
+		resp, err := c.sendRequestWithTimeout(req, time.Minute)
+		if err != nil {
+			return err
+		}
+		if !resp.Success {
+			return resp.errOr("failed to download blob " + id)
+		}
+		if _, err := w.Write(resp.BlobData); err != nil {
+			return err
+		}
+		if resp.BlobDone {
+			return nil
+		}
+		req.BlobOffset += int64(len(resp.BlobData))
+	}
+}
@@ -2,6 +2,8 @@ package ipc
 
 import (
 	"bufio"
+	"context"
+	"encoding/json"
 	"fmt"
 	"net"
 	"os"
@@ -11,7 +13,11 @@ import (
 
 	"opperator/config"
 	"opperator/internal/agent"
+	"opperator/internal/backup"
+	"opperator/internal/gitsync"
 	"opperator/internal/protocol"
+	"opperator/pkg/tracing"
+	"tui/components/sidebar"
 )
 
 type Client struct {
@@ -58,7 +64,12 @@ func NewClientWithAuth(address, authToken string) (*Client, error) {
 		}
 	}
 
-	return &Client{conn: conn}, nil
+	client := &Client{conn: conn}
+	if err := client.checkDaemonVersion(address); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return client, nil
 }
 
 // performAuthHandshake sends the auth token and waits for confirmation
@@ -84,7 +95,7 @@ func performAuthHandshake(conn net.Conn, token string) error {
 
 	response := strings.TrimSpace(scanner.Text())
 	if response != "OK" {
-		return fmt.Errorf("auth rejected: %s", response)
+		return NewError(ErrCodeUnauthorized, fmt.Sprintf("auth rejected: %s", response))
 	}
 
 	return nil
@@ -101,7 +112,17 @@ func (c *Client) sendRequest(req Request) (Response, error) {
 	return c.sendRequestWithTimeout(req, 10*time.Second)
 }
 
+// timeoutMillis converts a request timeout for the wire. One that has already
+// run out is sent as 1ms, so the daemon fails the request instead of running
+// it without a timeout.
+func timeoutMillis(d time.Duration) int64 {
+	return max(d.Milliseconds(), 1)
+}
+
 func (c *Client) sendRequestWithTimeout(req Request, timeout time.Duration) (Response, error) {
+	if req.TimeoutMS == 0 {
+		req.TimeoutMS = timeoutMillis(timeout)
+	}
 	data, err := EncodeRequest(req)
 	if err != nil {
 		return Response{}, err
@@ -140,7 +161,7 @@ func (c *Client) ListAgents() ([]*ProcessInfo, error) {
 	}
 
 	if !resp.Success {
-		return nil, fmt.Errorf("%s", resp.Error)
+		return nil, resp.Err()
 	}
 
 	return resp.Processes, nil
@@ -148,30 +169,66 @@ func (c *Client) ListAgents() ([]*ProcessInfo, error) {
 
 func (c *Client) StartAgent(name string) error {
 	req := Request{Type: RequestStartAgent, AgentName: name}
-	resp, err := c.sendRequest(req)
+	// Starting waits for the agent's dependencies to come up first
+	resp, err := c.sendRequestWithTimeout(req, 2*time.Minute)
 	if err != nil {
 		return err
 	}
 
 	if !resp.Success {
-		return fmt.Errorf("%s", resp.Error)
+		return resp.Err()
 	}
 
 	return nil
 }
 
-func (c *Client) StopAgent(name string) error {
-	req := Request{Type: RequestStopAgent, AgentName: name}
-	resp, err := c.sendRequestWithTimeout(req, 15*time.Second)
+// StopAgent stops an agent and returns the running agents that depend on it.
+func (c *Client) StopAgent(name string) ([]string, error) {
+	dependents, _, err := c.StopAgentWithOptions(name, agent.StopOptions{})
+	return dependents, err
+}
+
+// StopAgentWithOptions stops an agent as opts asks and reports how its process
+// ended along with the running agents that depend on it.
+func (c *Client) StopAgentWithOptions(name string, opts agent.StopOptions) ([]string, agent.StopMethod, error) {
+	req := Request{Type: RequestStopAgent, AgentName: name, StopOptions: &opts}
+	// The agent's own stop_grace_period is unknown here, so leave room for a
+	// generous one when the request does not set it.
+	grace := opts.GracePeriod
+	if grace <= 0 {
+		grace = 2 * time.Minute
+	}
+	resp, err := c.sendRequestWithTimeout(req, grace+15*time.Second)
 	if err != nil {
-		return err
+		return nil, "", err
 	}
 
 	if !resp.Success {
-		return fmt.Errorf("%s", resp.Error)
+		return nil, "", resp.Err()
 	}
 
-	return nil
+	return resp.Dependents, resp.StopMethod, nil
+}
+
+// PauseAgent makes an agent reject commands and trigger events while its
+// process keeps running.
+func (c *Client) PauseAgent(name, reason string) error {
+	req := Request{Type: RequestPauseAgent, AgentName: name, PauseReason: reason}
+	resp, err := c.sendRequest(req)
+	if err != nil {
+		return err
+	}
+	return resp.Err()
+}
+
+// ResumeAgent lets a paused agent take commands again.
+func (c *Client) ResumeAgent(name string) error {
+	req := Request{Type: RequestResumeAgent, AgentName: name}
+	resp, err := c.sendRequest(req)
+	if err != nil {
+		return err
+	}
+	return resp.Err()
 }
 
 func (c *Client) RestartAgent(name string) error {
@@ -182,7 +239,7 @@ func (c *Client) RestartAgent(name string) error {
 	}
 
 	if !resp.Success {
-		return fmt.Errorf("%s", resp.Error)
+		return resp.Err()
 	}
 
 	return nil
@@ -193,17 +250,43 @@ func (c *Client) InvokeCommand(name, command string, args map[string]interface{}
 }
 
 func (c *Client) InvokeCommandWithProgress(name, command string, args map[string]interface{}, timeout time.Duration, progressFn func(protocol.CommandProgressMessage)) (*CommandResponse, error) {
+	return c.InvokeCommandContext(context.Background(), name, command, args, timeout, progressFn)
+}
+
+// InvokeCommandContext invokes an agent command until it completes or ctx is
+// done. timeout bounds the wait between progress updates. When ctx ends first
+// the connection is closed, which makes the daemon abort the command on the
+// agent; the client cannot be reused afterwards.
+func (c *Client) InvokeCommandContext(ctx context.Context, name, command string, args map[string]interface{}, timeout time.Duration, progressFn func(protocol.CommandProgressMessage)) (*CommandResponse, error) {
+	return c.InvokeCommandInDir(ctx, name, command, args, "", timeout, progressFn)
+}
+
+// InvokeCommandInDir is InvokeCommandContext with the command running in dir
+// instead of the directory it declares or the current one. The daemon rejects
+// directories outside the agent's allowed directories.
+func (c *Client) InvokeCommandInDir(ctx context.Context, name, command string, args map[string]interface{}, dir string, timeout time.Duration, progressFn func(protocol.CommandProgressMessage)) (*CommandResponse, error) {
 	if timeout <= 0 {
 		timeout = 10 * time.Second
 	}
 
 	req := Request{Type: RequestCommand, AgentName: name, Command: command, Args: args}
+	if deadline, ok := ctx.Deadline(); ok {
+		req.TimeoutMS = timeoutMillis(time.Until(deadline))
+	}
+	req.TraceParent = tracing.TraceParent(ctx)
 	if cwd, err := os.Getwd(); err == nil {
 		if abs, absErr := filepath.Abs(cwd); absErr == nil {
 			cwd = abs
 		}
 		req.WorkingDir = filepath.Clean(cwd)
 	}
+	if dir = strings.TrimSpace(dir); dir != "" {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return nil, fmt.Errorf("resolve working directory: %w", err)
+		}
+		req.CommandDir = abs
+	}
 
 	// Send the request
 	data, err := EncodeRequest(req)
@@ -223,6 +306,11 @@ func (c *Client) InvokeCommandWithProgress(name, command string, args map[string
 	buf := make([]byte, 0, 64*1024)
 	scanner.Buffer(buf, 1024*1024)
 
+	stop := context.AfterFunc(ctx, func() {
+		_ = c.conn.Close()
+	})
+	defer stop()
+
 	var finalResp Response
 	for scanner.Scan() {
 		resp, err := DecodeResponse(scanner.Bytes())
@@ -243,6 +331,9 @@ func (c *Client) InvokeCommandWithProgress(name, command string, args map[string
 		break
 	}
 
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
 	if err := scanner.Err(); err != nil {
 		return nil, fmt.Errorf("read error: %w", err)
 	}
@@ -250,7 +341,7 @@ func (c *Client) InvokeCommandWithProgress(name, command string, args map[string
 	c.conn.SetDeadline(time.Time{})
 
 	if !finalResp.Success {
-		return nil, fmt.Errorf("%s", finalResp.Error)
+		return nil, finalResp.Err()
 	}
 
 	if finalResp.Command == nil {
@@ -268,12 +359,36 @@ func (c *Client) ListCommands(name string) ([]protocol.CommandDescriptor, error)
 	}
 
 	if !resp.Success {
-		return nil, fmt.Errorf("%s", resp.Error)
+		return nil, resp.Err()
 	}
 
 	return resp.Commands, nil
 }
 
+// GetCustomSections returns the sidebar sections published by an agent.
+func (c *Client) GetCustomSections(name string) ([]sidebar.CustomSection, error) {
+	req := Request{Type: RequestGetCustomSections, AgentName: name}
+	resp, err := c.sendRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if !resp.Success {
+		return nil, resp.Err()
+	}
+
+	// Sections decode generically, so convert them back
+	data, err := json.Marshal(resp.Sections)
+	if err != nil {
+		return nil, err
+	}
+	var sections []sidebar.CustomSection
+	if err := json.Unmarshal(data, &sections); err != nil {
+		return nil, fmt.Errorf("decode custom sections: %w", err)
+	}
+	return sections, nil
+}
+
 func (c *Client) StopAll() error {
 	req := Request{Type: RequestStopAll}
 	resp, err := c.sendRequest(req)
@@ -282,12 +397,25 @@ func (c *Client) StopAll() error {
 	}
 
 	if !resp.Success {
-		return fmt.Errorf("%s", resp.Error)
+		return resp.Err()
 	}
 
 	return nil
 }
 
+// ProtocolFrames returns the recent protocol messages exchanged with the
+// agent name, numbered after seq.
+func (c *Client) ProtocolFrames(name string, after uint64) ([]agent.ProtocolFrame, error) {
+	resp, err := c.sendRequest(Request{Type: RequestProtocolFrames, AgentName: name, FramesAfter: after})
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Success {
+		return nil, resp.errOr("failed to get protocol frames")
+	}
+	return resp.Frames, nil
+}
+
 func (c *Client) GetLogs(name string) ([]string, error) {
 	req := Request{Type: RequestGetLogs, AgentName: name}
 	resp, err := c.sendRequest(req)
@@ -296,12 +424,77 @@ func (c *Client) GetLogs(name string) ([]string, error) {
 	}
 
 	if !resp.Success {
-		return nil, fmt.Errorf("%s", resp.Error)
+		return nil, resp.Err()
 	}
 
 	return resp.Logs, nil
 }
 
+// SearchLogs returns the agent log lines matching q.
+func (c *Client) SearchLogs(q agent.LogSearch) (*agent.LogSearchResult, error) {
+	resp, err := c.sendRequest(Request{Type: RequestSearchLogs, LogSearch: &q})
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Success {
+		return nil, resp.Err()
+	}
+	if resp.LogMatches == nil {
+		return &agent.LogSearchResult{}, nil
+	}
+	return resp.LogMatches, nil
+}
+
+// GetCrashReport returns the last crash report for an agent, or nil if it has
+// not crashed.
+func (c *Client) GetCrashReport(name string) (*agent.CrashReport, error) {
+	req := Request{Type: RequestGetCrashReport, AgentName: name}
+	resp, err := c.sendRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if !resp.Success {
+		return nil, resp.Err()
+	}
+
+	return resp.CrashReport, nil
+}
+
+// GetPromptHistory returns an agent's prompt versions, newest first.
+func (c *Client) GetPromptHistory(name string) ([]agent.PromptVersion, error) {
+	req := Request{Type: RequestPromptHistory, AgentName: name}
+	resp, err := c.sendRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if !resp.Success {
+		return nil, resp.Err()
+	}
+
+	return resp.Prompts, nil
+}
+
+// RevertPrompt restores an agent's prompt and description from a version in
+// its history.
+func (c *Client) RevertPrompt(name string, id int64) (*agent.PromptVersion, error) {
+	req := Request{Type: RequestRevertPrompt, AgentName: name, PromptVersionID: id}
+	resp, err := c.sendRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if !resp.Success {
+		return nil, resp.Err()
+	}
+	if len(resp.Prompts) == 0 {
+		return nil, fmt.Errorf("daemon did not return the restored prompt")
+	}
+
+	return &resp.Prompts[0], nil
+}
+
 func (c *Client) ToolTaskMetrics() (ToolTaskMetrics, error) {
 	req := Request{Type: RequestToolTaskMetrics}
 	resp, err := c.sendRequest(req)
@@ -309,11 +502,7 @@ func (c *Client) ToolTaskMetrics() (ToolTaskMetrics, error) {
 		return ToolTaskMetrics{}, err
 	}
 	if !resp.Success {
-		errMsg := strings.TrimSpace(resp.Error)
-		if errMsg == "" {
-			errMsg = "failed to fetch metrics"
-		}
-		return ToolTaskMetrics{}, fmt.Errorf("%s", errMsg)
+		return ToolTaskMetrics{}, resp.errOr("failed to fetch metrics")
 	}
 	if resp.Metrics == nil {
 		return ToolTaskMetrics{}, fmt.Errorf("daemon did not return metrics")
@@ -321,18 +510,16 @@ func (c *Client) ToolTaskMetrics() (ToolTaskMetrics, error) {
 	return *resp.Metrics, nil
 }
 
-func (c *Client) ListToolTasks() ([]*ToolTask, error) {
-	req := Request{Type: RequestListToolTasks}
+// ListToolTasks returns the tasks visible to the connection's user, or every
+// task when allUsers is set, which needs an admin token.
+func (c *Client) ListToolTasks(allUsers bool) ([]*ToolTask, error) {
+	req := Request{Type: RequestListToolTasks, AllUsers: allUsers}
 	resp, err := c.sendRequest(req)
 	if err != nil {
 		return nil, err
 	}
 	if !resp.Success {
-		errMsg := strings.TrimSpace(resp.Error)
-		if errMsg == "" {
-			errMsg = "failed to list tasks"
-		}
-		return nil, fmt.Errorf("%s", errMsg)
+		return nil, resp.errOr("failed to list tasks")
 	}
 	return resp.Tasks, nil
 }
@@ -344,11 +531,24 @@ func (c *Client) GetToolTask(id string) (*ToolTask, error) {
 		return nil, err
 	}
 	if !resp.Success {
-		errMsg := strings.TrimSpace(resp.Error)
-		if errMsg == "" {
-			errMsg = "task not found"
-		}
-		return nil, fmt.Errorf("%s", errMsg)
+		return nil, resp.errOr("task not found")
+	}
+	if resp.Task == nil {
+		return nil, fmt.Errorf("daemon returned no task payload")
+	}
+	return resp.Task, nil
+}
+
+// ReplayToolTask submits a new task running the same tool or agent command
+// with the same arguments as the task id, and returns it.
+func (c *Client) ReplayToolTask(id string) (*ToolTask, error) {
+	req := Request{Type: RequestReplayToolTask, TaskID: strings.TrimSpace(id), Origin: "cli"}
+	resp, err := c.sendRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Success {
+		return nil, resp.errOr("failed to replay task")
 	}
 	if resp.Task == nil {
 		return nil, fmt.Errorf("daemon returned no task payload")
@@ -363,11 +563,248 @@ func (c *Client) DeleteToolTask(id string) error {
 		return err
 	}
 	if !resp.Success {
-		errMsg := strings.TrimSpace(resp.Error)
-		if errMsg == "" {
-			errMsg = "failed to delete task"
-		}
-		return fmt.Errorf("%s", errMsg)
+		return resp.errOr("failed to delete task")
+	}
+	return nil
+}
+
+// ResourceUsage samples CPU, memory, descriptors and child processes of the
+// daemon and its agents.
+func (c *Client) ResourceUsage() (*ResourceReport, error) {
+	resp, err := c.sendRequest(Request{Type: RequestResourceUsage})
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Success {
+		return nil, resp.errOr("failed to sample resources")
+	}
+	if resp.Resources == nil {
+		return nil, fmt.Errorf("daemon returned no resource report")
+	}
+	return resp.Resources, nil
+}
+
+// DaemonStats returns the daemon's version, agent counts and task throughput.
+func (c *Client) DaemonStats() (*DaemonStats, error) {
+	resp, err := c.sendRequest(Request{Type: RequestDaemonStats})
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Success {
+		return nil, resp.errOr("failed to fetch daemon stats")
+	}
+	if resp.Stats == nil {
+		return nil, fmt.Errorf("daemon returned no stats")
+	}
+	return resp.Stats, nil
+}
+
+// BudgetStatus returns today's model usage of agentName, or of every agent
+// that made model calls today when empty, with their budgets.
+func (c *Client) BudgetStatus(agentName string) ([]BudgetStatus, error) {
+	resp, err := c.sendRequest(Request{Type: RequestBudgetStatus, AgentName: agentName})
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Success {
+		return nil, resp.errOr("failed to fetch budgets")
+	}
+	return resp.Budgets, nil
+}
+
+// SelfUpdate asks the daemon to install the latest release of channel, or
+// of its configured channel when empty, and restart.
+func (c *Client) SelfUpdate(channel string) (*SelfUpdateResult, error) {
+	resp, err := c.sendRequestWithTimeout(Request{Type: RequestSelfUpdate, UpdateChannel: channel}, 5*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Success {
+		return nil, resp.errOr("self update failed")
+	}
+	if resp.SelfUpdate == nil {
+		return nil, fmt.Errorf("daemon returned no update result")
+	}
+	return resp.SelfUpdate, nil
+}
+
+// BackupNow asks the daemon to snapshot its state to its backup bucket.
+func (c *Client) BackupNow() (*backup.Snapshot, error) {
+	resp, err := c.sendRequestWithTimeout(Request{Type: RequestBackupNow}, 10*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Success {
+		return nil, resp.errOr("backup failed")
+	}
+	if resp.Backup == nil {
+		return nil, fmt.Errorf("daemon returned no snapshot")
+	}
+	return resp.Backup, nil
+}
+
+// ReportUpdateFailure forwards a failed update of target to the daemon's
+// notification sinks.
+func (c *Client) ReportUpdateFailure(target, errMsg string) error {
+	resp, err := c.sendRequest(Request{Type: RequestReportUpdateFailure, UpdateTarget: target, UpdateError: errMsg})
+	if err != nil {
+		return err
+	}
+	if !resp.Success {
+		return resp.errOr("failed to report update failure")
+	}
+	return nil
+}
+
+// CreateAuthToken creates a managed auth token and returns its secret, which
+// the daemon does not keep.
+func (c *Client) CreateAuthToken(name, role, user string) (string, error) {
+	resp, err := c.sendRequest(Request{Type: RequestCreateAuthToken, TokenName: name, TokenRole: role, TokenUser: user})
+	if err != nil {
+		return "", err
+	}
+	if !resp.Success {
+		return "", resp.errOr("failed to create auth token")
+	}
+	return resp.AuthToken, nil
+}
+
+func (c *Client) RevokeAuthToken(name string) error {
+	resp, err := c.sendRequest(Request{Type: RequestRevokeAuthToken, TokenName: name})
+	if err != nil {
+		return err
+	}
+	if !resp.Success {
+		return resp.errOr("failed to revoke auth token")
+	}
+	return nil
+}
+
+func (c *Client) ListAuthTokens() ([]AuthTokenInfo, error) {
+	resp, err := c.sendRequest(Request{Type: RequestListAuthTokens})
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Success {
+		return nil, resp.errOr("failed to list auth tokens")
+	}
+	return resp.AuthTokens, nil
+}
+
+// ListWorkflows returns the workflow definitions found on the daemon.
+func (c *Client) ListWorkflows() ([]WorkflowInfo, error) {
+	resp, err := c.sendRequest(Request{Type: RequestListWorkflows})
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Success {
+		return nil, resp.errOr("failed to list workflows")
+	}
+	return resp.Workflows, nil
+}
+
+// RunWorkflow queues a workflow as a single task and returns it.
+func (c *Client) RunWorkflow(name string, inputs map[string]string, workingDir string) (*ToolTask, error) {
+	resp, err := c.sendRequest(Request{
+		Type:           RequestRunWorkflow,
+		Workflow:       strings.TrimSpace(name),
+		WorkflowInputs: inputs,
+		WorkingDir:     workingDir,
+		Origin:         "cli",
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Success {
+		return nil, resp.errOr("failed to run workflow")
+	}
+	if resp.Task == nil {
+		return nil, fmt.Errorf("daemon returned no task payload")
+	}
+	return resp.Task, nil
+}
+
+// ExecDetached queues message for agentName as a background conversation,
+// continuing conversationID when set, and returns its task.
+func (c *Client) ExecDetached(agentName, message, conversationID, workingDir string) (*ToolTask, error) {
+	resp, err := c.sendRequest(Request{
+		Type:       RequestExecDetached,
+		AgentName:  strings.TrimSpace(agentName),
+		Message:    message,
+		SessionID:  strings.TrimSpace(conversationID),
+		WorkingDir: workingDir,
+		Origin:     "cli",
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Success {
+		return nil, resp.errOr("failed to queue message")
+	}
+	if resp.Task == nil {
+		return nil, fmt.Errorf("daemon returned no task payload")
+	}
+	return resp.Task, nil
+}
+
+// ListTriggers returns the triggers running on the daemon.
+func (c *Client) ListTriggers() ([]TriggerInfo, error) {
+	resp, err := c.sendRequest(Request{Type: RequestListTriggers})
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Success {
+		return nil, resp.errOr("failed to list triggers")
+	}
+	return resp.Triggers, nil
+}
+
+// CheckGuardrails runs an agent's guardrails over a command call or, when
+// check.Command is empty, over the model's text.
+func (c *Client) CheckGuardrails(agentName string, check agent.GuardrailCheck) (agent.GuardrailVerdict, error) {
+	// Leave room for the agent's validator command.
+	resp, err := c.sendRequestWithTimeout(Request{Type: RequestCheckGuardrails, AgentName: agentName, Guardrail: &check}, time.Minute)
+	if err != nil {
+		return agent.GuardrailVerdict{}, err
+	}
+	if !resp.Success || resp.Guardrail == nil {
+		return agent.GuardrailVerdict{}, resp.errOr("failed to check guardrails")
+	}
+	return *resp.Guardrail, nil
+}
+
+// ListChannels returns the chat channels running on the daemon.
+func (c *Client) ListChannels() ([]ChannelInfo, error) {
+	resp, err := c.sendRequest(Request{Type: RequestListChannels})
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Success {
+		return nil, resp.errOr("failed to list channels")
+	}
+	return resp.Channels, nil
+}
+
+// AddChannel registers a chat channel on the daemon and starts it.
+func (c *Client) AddChannel(ch config.ChannelConfig) error {
+	resp, err := c.sendRequest(Request{Type: RequestAddChannel, Channel: &ch})
+	if err != nil {
+		return err
+	}
+	if !resp.Success {
+		return resp.errOr("failed to add channel")
+	}
+	return nil
+}
+
+// RemoveChannel stops a registered chat channel and forgets it.
+func (c *Client) RemoveChannel(name string) error {
+	resp, err := c.sendRequest(Request{Type: RequestRemoveChannel, ChannelName: strings.TrimSpace(name)})
+	if err != nil {
+		return err
+	}
+	if !resp.Success {
+		return resp.errOr("failed to remove channel")
 	}
 	return nil
 }
@@ -380,7 +817,7 @@ func (c *Client) Shutdown() error {
 	}
 
 	if !resp.Success {
-		return fmt.Errorf("%s", resp.Error)
+		return resp.Err()
 	}
 
 	return nil
@@ -394,34 +831,79 @@ func (c *Client) GetSecret(name string) (string, error) {
 	}
 
 	if !resp.Success {
-		errMsg := strings.TrimSpace(resp.Error)
-		if errMsg == "" {
-			errMsg = "failed to retrieve secret"
-		}
-		return "", fmt.Errorf("%s", errMsg)
+		return "", resp.errOr("failed to retrieve secret")
 	}
 
 	return resp.Secret, nil
 }
 
 func (c *Client) SetSecret(name, value string) error {
-	req := Request{Type: RequestSetSecret, SecretName: name, SecretValue: value}
+	return c.StoreSecret(name, value, "")
+}
+
+// StoreSecret stores a secret in the daemon's keyring. Mode "create" fails
+// if it exists, "update" if it does not; empty stores either way.
+func (c *Client) StoreSecret(name, value, mode string) error {
+	_, err := c.ReplaceSecret(name, value, mode)
+	return err
+}
+
+// ReplaceSecret stores a secret like StoreSecret and returns the agents the
+// daemon told that an existing value was replaced.
+func (c *Client) ReplaceSecret(name, value, mode string) ([]string, error) {
+	req := Request{Type: RequestSetSecret, SecretName: name, SecretValue: value, Mode: mode}
 	resp, err := c.sendRequest(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	if !resp.Success {
-		errMsg := strings.TrimSpace(resp.Error)
-		if errMsg == "" {
-			errMsg = "failed to set secret"
-		}
-		return fmt.Errorf("%s", errMsg)
+		return nil, resp.errOr("failed to set secret")
+	}
+
+	return resp.Notified, nil
+}
+
+// SecretRotated tells the daemon's agents using the secret name that its
+// value was replaced, and returns the agents told.
+func (c *Client) SecretRotated(name string) ([]string, error) {
+	resp, err := c.sendRequest(Request{Type: RequestSecretRotated, SecretName: name})
+	if err != nil {
+		return nil, err
 	}
+	if !resp.Success {
+		return nil, resp.errOr("failed to notify agents")
+	}
+	return resp.Notified, nil
+}
 
+// ListSecrets returns the names of the secrets the daemon has registered.
+// DeleteSecret removes a secret from the daemon's keyring.
+func (c *Client) DeleteSecret(name string) error {
+	resp, err := c.sendRequest(Request{Type: RequestDeleteSecret, SecretName: name})
+	if err != nil {
+		return err
+	}
+	if !resp.Success {
+		return resp.errOr("failed to delete secret")
+	}
 	return nil
 }
 
+func (c *Client) ListSecrets() ([]string, error) {
+	req := Request{Type: RequestListSecrets}
+	resp, err := c.sendRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if !resp.Success {
+		return nil, resp.errOr("failed to list secrets")
+	}
+
+	return resp.Secrets, nil
+}
+
 func (c *Client) ReloadConfig() error {
 	req := Request{Type: RequestReloadConfig}
 	resp, err := c.sendRequest(req)
@@ -430,12 +912,28 @@ func (c *Client) ReloadConfig() error {
 	}
 
 	if !resp.Success {
-		return fmt.Errorf("%s", resp.Error)
+		return resp.Err()
 	}
 
 	return nil
 }
 
+// ReloadConfigFromGit makes the daemon pull its config directory from git
+// and reload agents.yaml.
+func (c *Client) ReloadConfigFromGit() (*gitsync.Result, error) {
+	resp, err := c.sendRequestWithTimeout(Request{Type: RequestReloadConfig, FromGit: true}, 5*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Success {
+		return nil, resp.errOr("git sync failed")
+	}
+	if resp.GitSync == nil {
+		return nil, fmt.Errorf("daemon returned no git sync result")
+	}
+	return resp.GitSync, nil
+}
+
 func (c *Client) BootstrapAgent(name, description string, noStart bool) (string, error) {
 	req := Request{
 		Type:        RequestBootstrapAgent,
@@ -450,7 +948,7 @@ func (c *Client) BootstrapAgent(name, description string, noStart bool) (string,
 	}
 
 	if !resp.Success {
-		return "", fmt.Errorf("%s", resp.Error)
+		return "", resp.Err()
 	}
 
 	// The daemon returns the success message in the Error field for backwards compatibility
@@ -468,7 +966,7 @@ func (c *Client) DeleteAgent(name string) error {
 	}
 
 	if !resp.Success {
-		return fmt.Errorf("%s", resp.Error)
+		return resp.Err()
 	}
 
 	return nil
@@ -487,7 +985,7 @@ func (c *Client) ReceiveAgent(pkg *agent.AgentPackage, force, startAfter bool) e
 	}
 
 	if !resp.Success {
-		return fmt.Errorf("%s", resp.Error)
+		return resp.Err()
 	}
 
 	return nil
@@ -504,7 +1002,7 @@ func (c *Client) PackageAgent(name string) (*agent.AgentPackage, error) {
 	}
 
 	if !resp.Success {
-		return nil, fmt.Errorf("%s", resp.Error)
+		return nil, resp.Err()
 	}
 
 	if resp.AgentPackage == nil {
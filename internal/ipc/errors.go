@@ -0,0 +1,141 @@
+package ipc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"strings"
+
+	"opperator/internal/agent"
+	"opperator/internal/kv"
+	"opperator/pkg/keyringerr"
+)
+
+// ErrorCode categorises a failed response so clients can branch on the kind
+// of failure instead of matching error strings.
+type ErrorCode string
+
+const (
+	ErrCodeNotFound     ErrorCode = "not_found"
+	ErrCodeUnauthorized ErrorCode = "unauthorized"
+	ErrCodeForbidden    ErrorCode = "forbidden"
+	ErrCodeBusy         ErrorCode = "busy"
+	ErrCodeTimeout      ErrorCode = "timeout"
+	ErrCodeValidation   ErrorCode = "validation"
+	ErrCodeUnavailable  ErrorCode = "unavailable"
+	ErrCodeInternal     ErrorCode = "internal"
+	// ErrCodeIncompatible means client and daemon versions cannot work
+	// together.
+	ErrCodeIncompatible ErrorCode = "incompatible"
+	// ErrCodeBudgetExceeded means an agent has used up its daily model
+	// budget.
+	ErrCodeBudgetExceeded ErrorCode = "budget_exceeded"
+	// ErrCodeKeyringUnavailable means the daemon's OS keyring is locked or
+	// not running, so secrets cannot be read or stored until it is fixed.
+	ErrCodeKeyringUnavailable ErrorCode = "keyring_unavailable"
+)
+
+// Error is a daemon error carrying its category across the IPC boundary.
+type Error struct {
+	Code    ErrorCode
+	Message string
+	// InvalidArgs lists the offending command arguments of a validation
+	// error.
+	InvalidArgs []agent.ArgumentError
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// NewError builds an error with the given code.
+func NewError(code ErrorCode, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// NewErrorResponse builds a failed response with an explicit code.
+func NewErrorResponse(code ErrorCode, message string) Response {
+	return Response{Success: false, Error: message, ErrorCode: code}
+}
+
+// ErrorResponse builds a failed response from err, classifying it with CodeOf.
+func ErrorResponse(err error) Response {
+	resp := NewErrorResponse(CodeOf(err), err.Error())
+	var argsErr *agent.InvalidArgumentsError
+	if errors.As(err, &argsErr) {
+		resp.InvalidArgs = argsErr.Fields
+	}
+	return resp
+}
+
+// Err converts a failed response into an *Error, or returns nil on success.
+func (r Response) Err() error {
+	return r.errOr("unknown error")
+}
+
+// errOr is Err with a custom message for responses without error text.
+func (r Response) errOr(fallback string) error {
+	if r.Success {
+		return nil
+	}
+	msg := strings.TrimSpace(r.Error)
+	if msg == "" {
+		msg = fallback
+	}
+	code := r.ErrorCode
+	if code == "" {
+		code = ErrCodeInternal
+	}
+	return &Error{Code: code, Message: msg, InvalidArgs: r.InvalidArgs}
+}
+
+// CodeOf returns the category of err. Errors that carry no recognisable
+// category are reported as internal.
+func CodeOf(err error) ErrorCode {
+	if err == nil {
+		return ""
+	}
+	var ipcErr *Error
+	if errors.As(err, &ipcErr) && ipcErr.Code != "" {
+		return ipcErr.Code
+	}
+	// A failed dial means the daemon itself is not reachable
+	var argsErr *agent.InvalidArgumentsError
+	if errors.As(err, &argsErr) {
+		return ErrCodeValidation
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return ErrCodeUnavailable
+	}
+	if keyringerr.Is(err) {
+		return ErrCodeKeyringUnavailable
+	}
+	switch {
+	case errors.Is(err, agent.ErrNotFound), errors.Is(err, os.ErrNotExist), errors.Is(err, kv.ErrNotFound):
+		return ErrCodeNotFound
+	case errors.Is(err, agent.ErrAlreadyRunning):
+		return ErrCodeBusy
+	case errors.Is(err, agent.ErrNotRunning), errors.Is(err, agent.ErrPaused):
+		return ErrCodeUnavailable
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, os.ErrDeadlineExceeded):
+		return ErrCodeTimeout
+	case errors.Is(err, os.ErrPermission):
+		return ErrCodeUnauthorized
+	case errors.Is(err, agent.ErrWorkingDirNotAllowed):
+		return ErrCodeForbidden
+	case errors.Is(err, kv.ErrInvalid):
+		return ErrCodeValidation
+	}
+	// SQLite reports lock contention only through its message text
+	if strings.Contains(err.Error(), "database is locked") {
+		return ErrCodeBusy
+	}
+	return ErrCodeInternal
+}
+
+// IsCode reports whether err carries the given code.
+func IsCode(err error, code ErrorCode) bool {
+	return err != nil && CodeOf(err) == code
+}
@@ -0,0 +1,125 @@
+package ipc
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"opperator/config"
+	"opperator/version"
+)
+
+// VersionWarning reports a daemon running a different version than this
+// client. It prints to stderr by default; a full-screen UI can redirect it.
+var VersionWarning = func(msg string) {
+	fmt.Fprintf(os.Stderr, "Warning: %s\n", msg)
+}
+
+// handshakes remembers the outcome of the version handshake per daemon
+// address, so a process checks and warns once however often it connects.
+var handshakes sync.Map // address -> *handshake
+
+type handshake struct {
+	once sync.Once
+	err  error
+}
+
+// checkDaemonVersion runs the version handshake on a fresh connection. It
+// fails only when client and daemon cannot work together; errors talking to
+// the daemon are left for the request that follows to report.
+func (c *Client) checkDaemonVersion(address string) error {
+	entry, _ := handshakes.LoadOrStore(address, &handshake{})
+	h := entry.(*handshake)
+	h.once.Do(func() {
+		info, err := c.Hello()
+		if err != nil {
+			if !rejectsHandshake(err) {
+				// Retry on the next connection
+				handshakes.Delete(address)
+				return
+			}
+			info = &DaemonInfo{}
+		}
+		h.err = judgeDaemonVersion(address, info)
+	})
+	return h.err
+}
+
+// rejectsHandshake reports whether err is a daemon predating the handshake
+// turning the hello request away. Those daemons answer with a validation or
+// forbidden error, or, when they also predate error codes, with a bare
+// "unknown request type" message.
+func rejectsHandshake(err error) bool {
+	if IsCode(err, ErrCodeValidation) || IsCode(err, ErrCodeForbidden) {
+		return true
+	}
+	return strings.HasPrefix(err.Error(), "unknown request type")
+}
+
+// Hello exchanges versions with the daemon.
+func (c *Client) Hello() (*DaemonInfo, error) {
+	resp, err := c.sendRequestWithTimeout(Request{
+		Type:           RequestHello,
+		ClientVersion:  version.Get(),
+		ClientProtocol: version.Protocol,
+	}, 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Success {
+		return nil, resp.errOr("version handshake failed")
+	}
+	if resp.Daemon == nil {
+		return nil, fmt.Errorf("daemon returned no version")
+	}
+	return resp.Daemon, nil
+}
+
+func judgeDaemonVersion(address string, info *DaemonInfo) error {
+	daemonVersion := info.Version
+	if daemonVersion == "" {
+		daemonVersion = "an unknown older version"
+	}
+	name, local := daemonNameFor(address)
+	upgrade := fmt.Sprintf("run `op cloud update %s` to upgrade it", name)
+	if local {
+		upgrade = "restart it with `op daemon stop && op daemon start` to pick up the installed binary"
+	}
+
+	switch version.Compare(info.Version, info.Protocol, info.MinProtocol) {
+	case version.PeerTooOld:
+		return NewError(ErrCodeIncompatible, fmt.Sprintf(
+			"daemon '%s' runs %s, which this client (%s) no longer supports; %s",
+			name, daemonVersion, version.Get(), upgrade))
+	case version.PeerTooNew:
+		return NewError(ErrCodeIncompatible, fmt.Sprintf(
+			"daemon '%s' runs %s, which no longer supports this client (%s); update the client with `op version update`",
+			name, daemonVersion, version.Get()))
+	case version.Skewed:
+		VersionWarning(fmt.Sprintf("daemon '%s' runs %s but this client is %s; %s",
+			name, daemonVersion, version.Get(), upgrade))
+	}
+	return nil
+}
+
+// daemonNameFor finds the registry name of the daemon at address and whether
+// it runs on this machine.
+func daemonNameFor(address string) (name string, local bool) {
+	local = !strings.HasPrefix(address, "tcp://")
+	name = address
+	if local {
+		name = "local"
+	}
+	registry, err := config.LoadDaemonRegistry()
+	if err != nil {
+		return name, local
+	}
+	for _, d := range registry.Daemons {
+		if d.Address == address {
+			return d.Name, local
+		}
+	}
+	return name, local
+}
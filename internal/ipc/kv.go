@@ -0,0 +1,112 @@
+package ipc
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"opperator/internal/kv"
+)
+
+// KVGet returns agentName's value for key.
+func (c *Client) KVGet(agentName, key string) (*kv.Entry, error) {
+	resp, err := c.sendRequest(Request{Type: RequestKVGet, AgentName: agentName, KVKey: key})
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Success {
+		return nil, resp.errOr("failed to get key")
+	}
+	if len(resp.KV) == 0 {
+		return nil, fmt.Errorf("daemon returned no value")
+	}
+	return &resp.KV[0], nil
+}
+
+// KVSet stores value, which must be JSON, as agentName's value for key.
+func (c *Client) KVSet(agentName, key string, value json.RawMessage) error {
+	resp, err := c.sendRequest(Request{Type: RequestKVSet, AgentName: agentName, KVKey: key, KVValue: value})
+	if err != nil {
+		return err
+	}
+	if !resp.Success {
+		return resp.errOr("failed to set key")
+	}
+	return nil
+}
+
+// KVDelete removes agentName's value for key.
+func (c *Client) KVDelete(agentName, key string) error {
+	resp, err := c.sendRequest(Request{Type: RequestKVDelete, AgentName: agentName, KVKey: key})
+	if err != nil {
+		return err
+	}
+	if !resp.Success {
+		return resp.errOr("failed to delete key")
+	}
+	return nil
+}
+
+// KVList returns agentName's entries whose key starts with prefix.
+func (c *Client) KVList(agentName, prefix string) ([]kv.Entry, error) {
+	resp, err := c.sendRequest(Request{Type: RequestKVList, AgentName: agentName, KVPrefix: prefix})
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Success {
+		return nil, resp.errOr("failed to list keys")
+	}
+	return resp.KV, nil
+}
+
+// BlackboardGet returns the value of key on the namespace blackboard.
+func (c *Client) BlackboardGet(namespace, key string) (*kv.Entry, error) {
+	resp, err := c.sendRequest(Request{Type: RequestKVGet, KVNamespace: namespace, KVKey: key})
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Success {
+		return nil, resp.errOr("failed to get key")
+	}
+	if len(resp.KV) == 0 {
+		return nil, fmt.Errorf("daemon returned no value")
+	}
+	return &resp.KV[0], nil
+}
+
+// BlackboardSet stores value, which must be JSON, under key on the namespace
+// blackboard.
+func (c *Client) BlackboardSet(namespace, key string, value json.RawMessage) error {
+	resp, err := c.sendRequest(Request{Type: RequestKVSet, KVNamespace: namespace, KVKey: key, KVValue: value})
+	if err != nil {
+		return err
+	}
+	if !resp.Success {
+		return resp.errOr("failed to set key")
+	}
+	return nil
+}
+
+// BlackboardDelete removes key from the namespace blackboard.
+func (c *Client) BlackboardDelete(namespace, key string) error {
+	resp, err := c.sendRequest(Request{Type: RequestKVDelete, KVNamespace: namespace, KVKey: key})
+	if err != nil {
+		return err
+	}
+	if !resp.Success {
+		return resp.errOr("failed to delete key")
+	}
+	return nil
+}
+
+// BlackboardList returns the entries of the namespace blackboard whose key
+// starts with prefix.
+func (c *Client) BlackboardList(namespace, prefix string) ([]kv.Entry, error) {
+	resp, err := c.sendRequest(Request{Type: RequestKVList, KVNamespace: namespace, KVPrefix: prefix})
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Success {
+		return nil, resp.errOr("failed to list keys")
+	}
+	return resp.KV, nil
+}
@@ -2,7 +2,15 @@ package ipc
 
 import (
 	"encoding/json"
+	"time"
+
+	"opperator/config"
 	"opperator/internal/agent"
+	"opperator/internal/backup"
+	"opperator/internal/gitsync"
+	"opperator/internal/kb"
+	"opperator/internal/kv"
+	"opperator/internal/memory"
 	"opperator/internal/protocol"
 )
 
@@ -13,8 +21,14 @@ const (
 	RequestStartAgent        RequestType = "start"
 	RequestStopAgent         RequestType = "stop"
 	RequestRestartAgent      RequestType = "restart"
+	RequestPauseAgent        RequestType = "pause"
+	RequestResumeAgent       RequestType = "resume"
 	RequestStopAll           RequestType = "stop_all"
 	RequestGetLogs           RequestType = "get_logs"
+	RequestGetCrashReport    RequestType = "get_crash_report"
+	RequestSearchLogs        RequestType = "search_logs"
+	RequestPromptHistory     RequestType = "prompt_history"
+	RequestRevertPrompt      RequestType = "prompt_revert"
 	RequestGetCustomSections RequestType = "get_custom_sections"
 	RequestReloadConfig      RequestType = "reload_config"
 	RequestShutdown          RequestType = "shutdown"
@@ -26,10 +40,12 @@ const (
 	RequestDeleteToolTask    RequestType = "tool_delete"
 	RequestWatchToolTask     RequestType = "tool_watch"
 	RequestToolTaskMetrics   RequestType = "tool_metrics"
+	RequestReplayToolTask    RequestType = "tool_replay"
 	RequestGetSecret         RequestType = "secret_get"
 	RequestSetSecret         RequestType = "secret_set"
 	RequestDeleteSecret      RequestType = "secret_delete"
 	RequestListSecrets       RequestType = "secret_list"
+	RequestSecretRotated     RequestType = "secret_rotated"
 	RequestWatchAgentState   RequestType = "watch_agent_state"
 	RequestWatchAllTasks     RequestType = "watch_all_tasks"
 	RequestLifecycleEvent    RequestType = "lifecycle_event"
@@ -40,6 +56,52 @@ const (
 	RequestPackageAgent      RequestType = "package_agent"
 	RequestSetInvocationDir  RequestType = "set_invocation_dir"
 	RequestGetInvocationDir  RequestType = "get_invocation_dir"
+	RequestResourceUsage     RequestType = "resource_usage"
+	RequestDaemonStats       RequestType = "daemon_stats"
+	// RequestHello exchanges versions so either side can detect skew.
+	RequestHello RequestType = "hello"
+	// RequestSelfUpdate makes a daemon under systemd install the latest
+	// GitHub release itself and restart.
+	RequestSelfUpdate RequestType = "self_update"
+	// RequestBackupNow snapshots the daemon's config directory to its
+	// backup bucket.
+	RequestBackupNow RequestType = "backup_now"
+	// RequestReportUpdateFailure lets the CLI hand a failed update to the
+	// daemon's notification sinks.
+	RequestReportUpdateFailure RequestType = "report_update_failure"
+	RequestCreateAuthToken     RequestType = "token_create"
+	RequestRevokeAuthToken     RequestType = "token_revoke"
+	RequestListAuthTokens      RequestType = "token_list"
+	RequestListWorkflows       RequestType = "workflow_list"
+	RequestRunWorkflow         RequestType = "workflow_run"
+	RequestExecDetached        RequestType = "exec_detach"
+	RequestListTriggers        RequestType = "trigger_list"
+	RequestListChannels        RequestType = "channel_list"
+	RequestAddChannel          RequestType = "channel_add"
+	RequestRemoveChannel       RequestType = "channel_remove"
+	RequestCheckGuardrails     RequestType = "guardrail_check"
+	RequestRemember            RequestType = "memory_remember"
+	RequestRecall              RequestType = "memory_recall"
+	RequestSearchKnowledge     RequestType = "kb_search"
+	RequestSpawnSessionAgent   RequestType = "session_agent_spawn"
+	RequestEndSession          RequestType = "session_end"
+	// RequestBudgetCharge counts a model call, or the usage of one, against
+	// an agent's daily budget; RequestBudgetStatus reports today's usage.
+	RequestBudgetCharge RequestType = "budget_charge"
+	RequestBudgetStatus RequestType = "budget_status"
+	// RequestProtocolFrames returns the recent protocol messages exchanged
+	// with AgentName, numbered after FramesAfter.
+	RequestProtocolFrames RequestType = "protocol_frames"
+	// RequestUploadBlob appends a chunk of a file to a blob on the daemon,
+	// starting a new one when BlobID is empty.
+	RequestUploadBlob RequestType = "blob_upload"
+	// RequestDownloadBlob returns the chunk of a blob starting at BlobOffset.
+	RequestDownloadBlob RequestType = "blob_download"
+	// Key-value requests read and write AgentName's durable state.
+	RequestKVGet    RequestType = "kv_get"
+	RequestKVSet    RequestType = "kv_set"
+	RequestKVDelete RequestType = "kv_delete"
+	RequestKVList   RequestType = "kv_list"
 )
 
 type Request struct {
@@ -63,30 +125,217 @@ type Request struct {
 	LifecycleData map[string]interface{} `json:"lifecycle_data,omitempty"`
 	Description   string                 `json:"description,omitempty"`
 	NoStart       bool                   `json:"no_start,omitempty"`
+	DependsOn     []string               `json:"depends_on,omitempty"`
+	// Execution is the task's policy when the daemon restarts while it
+	// runs: "at_least_once" (default) runs it again, "at_most_once" fails it.
+	Execution string `json:"execution,omitempty"`
+
+	// AllUsers lifts the per-user task filter of a TCP connection. Only
+	// admin tokens may set it.
+	AllUsers bool `json:"all_users,omitempty"`
+
+	// TimeoutMS is how long, in milliseconds, the client waits for the
+	// response. The daemon cancels work for the request once that much time
+	// has passed since it arrived; a relative timeout keeps clock skew
+	// between client and daemon out of it.
+	TimeoutMS int64 `json:"timeout_ms,omitempty"`
+
+	// TraceParent is the W3C trace context of the client span that issued
+	// the request, so daemon and agent spans join the same trace.
+	TraceParent string `json:"traceparent,omitempty"`
+
+	// Version handshake fields, sent with RequestHello
+	ClientVersion  string `json:"client_version,omitempty"`
+	ClientProtocol int    `json:"client_protocol,omitempty"`
+
+	// Update failure report fields
+	UpdateTarget string `json:"update_target,omitempty"`
+	UpdateError  string `json:"update_error,omitempty"`
+
+	// UpdateChannel overrides the daemon's release channel for a self update
+	UpdateChannel string `json:"update_channel,omitempty"`
+
+	// Auth token management fields
+	TokenName string `json:"token_name,omitempty"`
+	TokenRole string `json:"token_role,omitempty"`
+	TokenUser string `json:"token_user,omitempty"`
+
+	// Workflow fields
+	Workflow       string            `json:"workflow,omitempty"`
+	WorkflowInputs map[string]string `json:"workflow_inputs,omitempty"`
+
+	// Message is the user message of a detached exec
+	Message string `json:"message,omitempty"`
+
+	// Chat channel fields
+	Channel     *config.ChannelConfig `json:"channel,omitempty"`
+	ChannelName string                `json:"channel_name,omitempty"`
+
+	// Guardrail is checked against AgentName's guardrails
+	Guardrail *agent.GuardrailCheck `json:"guardrail,omitempty"`
+
+	// Memory fields; memories belong to AgentName and, with the
+	// "conversation" scope, to the SessionID conversation
+	MemoryText  string `json:"memory_text,omitempty"`
+	MemoryScope string `json:"memory_scope,omitempty"`
+	MemoryLimit int    `json:"memory_limit,omitempty"`
+
+	// PromptVersionID is the prompt version to revert AgentName to
+	PromptVersionID int64 `json:"prompt_version_id,omitempty"`
+
+	// Knowledge base search fields
+	KnowledgeQuery string `json:"knowledge_query,omitempty"`
+	KnowledgeLimit int    `json:"knowledge_limit,omitempty"`
 
 	// Agent transfer fields
 	AgentPackage *agent.AgentPackage `json:"agent_package,omitempty"`
 	Force        bool                `json:"force,omitempty"`
 	StartAfter   bool                `json:"start_after,omitempty"`
+
+	// FromGit makes a config reload pull the config directory from git first
+	FromGit bool `json:"from_git,omitempty"`
+
+	// PauseReason is shown in the errors of commands sent to a paused agent
+	PauseReason string `json:"pause_reason,omitempty"`
+
+	// LogSearch selects the agent log lines a search returns
+	LogSearch *agent.LogSearch `json:"log_search,omitempty"`
+
+	// StopOptions tune how a stop request ends the agent's process
+	StopOptions *agent.StopOptions `json:"stop_options,omitempty"`
+
+	// BudgetCharge is the model usage to count against AgentName's budget
+	BudgetCharge *BudgetCharge `json:"budget_charge,omitempty"`
+
+	FramesAfter uint64 `json:"frames_after,omitempty"`
+
+	// CommandDir is the directory the user picked for a command to run in
+	CommandDir string `json:"command_dir,omitempty"`
+
+	// Blob upload fields
+	BlobID     string `json:"blob_id,omitempty"`
+	BlobName   string `json:"blob_name,omitempty"`
+	BlobOffset int64  `json:"blob_offset,omitempty"`
+	BlobData   []byte `json:"blob_data,omitempty"`
+	BlobDone   bool   `json:"blob_done,omitempty"`
+
+	// Key-value fields; KVPrefix narrows a list to the keys starting with it
+	// and KVNamespace targets a blackboard shared between agents instead of
+	// AgentName's own keys
+	KVKey       string          `json:"kv_key,omitempty"`
+	KVValue     json.RawMessage `json:"kv_value,omitempty"`
+	KVPrefix    string          `json:"kv_prefix,omitempty"`
+	KVNamespace string          `json:"kv_namespace,omitempty"`
 }
 
 type Response struct {
-	Success       bool                              `json:"success"`
-	Error         string                            `json:"error,omitempty"`
-	Processes     []*ProcessInfo                    `json:"processes,omitempty"`
-	Logs          []string                          `json:"logs,omitempty"`
-	Command       *CommandResponse                  `json:"command,omitempty"`
-	Commands      []protocol.CommandDescriptor      `json:"commands,omitempty"`
-	Progress      *protocol.CommandProgressMessage  `json:"progress,omitempty"`
-	Task          *ToolTask                         `json:"task,omitempty"`
-	Tasks         []*ToolTask                       `json:"tasks,omitempty"`
-	Secret        string                            `json:"secret,omitempty"`
-	Secrets       []string                          `json:"secrets,omitempty"`
-	Metrics       *ToolTaskMetrics                  `json:"metrics,omitempty"`
-	Sections      interface{}                       `json:"sections,omitempty"`
-	ProcessRoot   string                            `json:"process_root,omitempty"`
-	AgentPackage  *agent.AgentPackage               `json:"agent_package,omitempty"`
-	InvocationDir string                            `json:"invocation_dir,omitempty"`
+	Success       bool                             `json:"success"`
+	Error         string                           `json:"error,omitempty"`
+	ErrorCode     ErrorCode                        `json:"error_code,omitempty"`
+	InvalidArgs   []agent.ArgumentError            `json:"invalid_args,omitempty"`
+	Processes     []*ProcessInfo                   `json:"processes,omitempty"`
+	Logs          []string                         `json:"logs,omitempty"`
+	Command       *CommandResponse                 `json:"command,omitempty"`
+	Commands      []protocol.CommandDescriptor     `json:"commands,omitempty"`
+	Progress      *protocol.CommandProgressMessage `json:"progress,omitempty"`
+	Task          *ToolTask                        `json:"task,omitempty"`
+	Tasks         []*ToolTask                      `json:"tasks,omitempty"`
+	Secret        string                           `json:"secret,omitempty"`
+	Secrets       []string                         `json:"secrets,omitempty"`
+	Metrics       *ToolTaskMetrics                 `json:"metrics,omitempty"`
+	Sections      interface{}                      `json:"sections,omitempty"`
+	ProcessRoot   string                           `json:"process_root,omitempty"`
+	AgentPackage  *agent.AgentPackage              `json:"agent_package,omitempty"`
+	InvocationDir string                           `json:"invocation_dir,omitempty"`
+	CrashReport   *agent.CrashReport               `json:"crash_report,omitempty"`
+	Prompts       []agent.PromptVersion            `json:"prompts,omitempty"`
+	Resources     *ResourceReport                  `json:"resources,omitempty"`
+	Stats         *DaemonStats                     `json:"stats,omitempty"`
+	Daemon        *DaemonInfo                      `json:"daemon,omitempty"`
+	SelfUpdate    *SelfUpdateResult                `json:"self_update,omitempty"`
+	Backup        *backup.Snapshot                 `json:"backup,omitempty"`
+	AuthToken     string                           `json:"auth_token,omitempty"`
+	AuthTokens    []AuthTokenInfo                  `json:"auth_tokens,omitempty"`
+	Workflows     []WorkflowInfo                   `json:"workflows,omitempty"`
+	Triggers      []TriggerInfo                    `json:"triggers,omitempty"`
+	Channels      []ChannelInfo                    `json:"channels,omitempty"`
+	Guardrail     *agent.GuardrailVerdict          `json:"guardrail,omitempty"`
+	Memories      []memory.Memory                  `json:"memories,omitempty"`
+	Knowledge     []kb.Result                      `json:"knowledge,omitempty"`
+	SessionAgents []string                         `json:"session_agents,omitempty"`
+	Dependents    []string                         `json:"dependents,omitempty"`
+	GitSync       *gitsync.Result                  `json:"git_sync,omitempty"`
+	Budgets       []BudgetStatus                   `json:"budgets,omitempty"`
+	Notified      []string                         `json:"notified,omitempty"`
+	Frames        []agent.ProtocolFrame            `json:"frames,omitempty"`
+	LogMatches    *agent.LogSearchResult           `json:"log_matches,omitempty"`
+	StopMethod    agent.StopMethod                 `json:"stop_method,omitempty"`
+	BlobID        string                           `json:"blob_id,omitempty"`
+	BlobData      []byte                           `json:"blob_data,omitempty"`
+	BlobDone      bool                             `json:"blob_done,omitempty"`
+	KV            []kv.Entry                       `json:"kv,omitempty"`
+}
+
+// ChannelInfo describes a chat channel. Source is "config" for channels from
+// daemon.yaml and "registry" for those added with 'op channel add'.
+type ChannelInfo struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Agent       string `json:"agent"`
+	Source      string `json:"source"`
+	Connected   bool   `json:"connected"`
+	Messages    int64  `json:"messages"`
+	LastMessage string `json:"last_message,omitempty"`
+	LastError   string `json:"last_error,omitempty"`
+}
+
+// TriggerInfo describes a running trigger. Owner is "config" for triggers
+// from daemon.yaml and "agent:<name>" for those registered by an agent.
+type TriggerInfo struct {
+	Name      string `json:"name"`
+	Owner     string `json:"owner"`
+	Agent     string `json:"agent"`
+	Type      string `json:"type"`
+	Command   string `json:"command"`
+	Fired     int64  `json:"fired"`
+	LastFired string `json:"last_fired,omitempty"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+// WorkflowInfo describes a workflow definition available on a daemon.
+type WorkflowInfo struct {
+	Name        string              `json:"name"`
+	Description string              `json:"description,omitempty"`
+	Path        string              `json:"path,omitempty"`
+	Inputs      []WorkflowInputInfo `json:"inputs,omitempty"`
+	Steps       []WorkflowStepInfo  `json:"steps"`
+}
+
+type WorkflowInputInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+	Default     string `json:"default,omitempty"`
+}
+
+type WorkflowStepInfo struct {
+	ID      string   `json:"id"`
+	Agent   string   `json:"agent"`
+	Command string   `json:"command"`
+	Needs   []string `json:"needs,omitempty"`
+	If      string   `json:"if,omitempty"`
+}
+
+// AuthTokenInfo describes a token accepted by the TCP listener. Source is
+// "env" for OPPERATOR_AUTH_TOKEN, "config" for daemon.yaml entries and
+// "managed" for tokens created with 'op daemon token create'.
+type AuthTokenInfo struct {
+	Name       string     `json:"name"`
+	Role       string     `json:"role"`
+	User       string     `json:"user"`
+	Source     string     `json:"source"`
+	CreatedAt  *time.Time `json:"created_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
 }
 
 type ToolTaskMetrics struct {
@@ -96,6 +345,26 @@ type ToolTaskMetrics struct {
 	Failed      int64 `json:"failed"`
 	QueueDepth  int64 `json:"queue_depth"`
 	WorkerCount int64 `json:"worker_count"`
+
+	Origins map[string]ToolTaskOriginMetrics `json:"origins,omitempty"`
+	// Agents are the metrics agents reported through the protocol
+	Agents []AgentMetric `json:"agents,omitempty"`
+}
+
+// AgentMetric is a counter or gauge an agent reported: its total or latest
+// value and its per-minute values over the last half hour, oldest first.
+type AgentMetric struct {
+	Agent  string    `json:"agent"`
+	Name   string    `json:"name"`
+	Type   string    `json:"type"`
+	Value  float64   `json:"value"`
+	Points []float64 `json:"points,omitempty"`
+}
+
+// ToolTaskOriginMetrics is the queue state for one task origin.
+type ToolTaskOriginMetrics struct {
+	Queued  int64 `json:"queued"`
+	Running int64 `json:"running"`
 }
 
 type ToolTaskEvent struct {
@@ -116,13 +385,27 @@ type AgentStateEvent struct {
 	LogEntry            string                       `json:"log_entry,omitempty"` // For single log append events
 	CustomSections      interface{}                  `json:"custom_sections,omitempty"`
 	Status              string                       `json:"status,omitempty"`
+	LastExit            *agent.ExitInfo              `json:"last_exit,omitempty"`
 	Commands            []protocol.CommandDescriptor `json:"commands,omitempty"`
+	Capabilities        *protocol.Capabilities       `json:"capabilities,omitempty"`
 }
 
 type CommandResponse struct {
 	Success bool        `json:"success"`
 	Error   string      `json:"error,omitempty"`
 	Result  interface{} `json:"result,omitempty"`
+	// TaskID is the task the invocation was recorded as, for replaying it
+	TaskID string `json:"task_id,omitempty"`
+	// Files are the files the command output, kept by the daemon as blobs
+	Files []CommandFile `json:"files,omitempty"`
+}
+
+// CommandFile is a file a command output, downloadable with DownloadBlob.
+type CommandFile struct {
+	Name     string `json:"name"`
+	MimeType string `json:"mime_type,omitempty"`
+	Size     int64  `json:"size"`
+	BlobID   string `json:"blob_id"`
 }
 
 type ToolTask struct {
@@ -138,6 +421,7 @@ type ToolTask struct {
 	CommandArgs string             `json:"command_args,omitempty"`
 	Origin      string             `json:"origin,omitempty"`
 	ClientID    string             `json:"client_id,omitempty"`
+	Owner       string             `json:"owner,omitempty"`
 	Status      string             `json:"status"`
 	Result      string             `json:"result,omitempty"`
 	Metadata    string             `json:"metadata,omitempty"`
@@ -146,6 +430,9 @@ type ToolTask struct {
 	UpdatedAt   string             `json:"updated_at"`
 	CompletedAt string             `json:"completed_at,omitempty"`
 	Progress    []ToolTaskProgress `json:"progress,omitempty"`
+	DependsOn   []string           `json:"depends_on,omitempty"`
+	Attempts    int                `json:"attempts,omitempty"`
+	Execution   string             `json:"execution,omitempty"`
 }
 
 type ToolTaskProgress struct {
@@ -156,15 +443,94 @@ type ToolTaskProgress struct {
 }
 
 type ProcessInfo struct {
-	Name                string              `json:"name"`
-	Description         string              `json:"description,omitempty"`
-	Status              agent.ProcessStatus `json:"status"`
-	PID                 int                 `json:"pid"`
-	RestartCount        int                 `json:"restart_count"`
-	Uptime              int64               `json:"uptime"` // seconds
-	SystemPrompt        string              `json:"system_prompt,omitempty"`
-	SystemPromptReplace bool                `json:"system_prompt_replace,omitempty"`
-	Color               string              `json:"color,omitempty"`
+	Name                string                 `json:"name"`
+	Description         string                 `json:"description,omitempty"`
+	Status              agent.ProcessStatus    `json:"status"`
+	PID                 int                    `json:"pid"`
+	RestartCount        int                    `json:"restart_count"`
+	Uptime              int64                  `json:"uptime"` // seconds
+	SystemPrompt        string                 `json:"system_prompt,omitempty"`
+	SystemPromptReplace bool                   `json:"system_prompt_replace,omitempty"`
+	Color               string                 `json:"color,omitempty"`
+	LastExit            *agent.ExitInfo        `json:"last_exit,omitempty"`
+	Capabilities        *protocol.Capabilities `json:"capabilities,omitempty"`
+	Session             string                 `json:"session,omitempty"`
+	Paused              *agent.PauseInfo       `json:"paused,omitempty"`
+}
+
+// ResourceUsage is a point-in-time resource sample of one process and its
+// descendants. Fields the platform cannot report are -1.
+type ResourceUsage struct {
+	PID        int     `json:"pid"`
+	CPUPercent float64 `json:"cpu_percent"`
+	RSSBytes   uint64  `json:"rss_bytes"`
+	OpenFDs    int     `json:"open_fds"`
+	Threads    int     `json:"threads"`
+	Children   int     `json:"children"`
+}
+
+type AgentResourceUsage struct {
+	Name   string              `json:"name"`
+	Status agent.ProcessStatus `json:"status"`
+	Usage  *ResourceUsage      `json:"usage,omitempty"`
+	Error  string              `json:"error,omitempty"`
+}
+
+// DaemonInfo is the daemon side of the version handshake. Protocol is the
+// IPC protocol revision it speaks and MinProtocol the oldest client revision
+// it still serves; see the version package.
+type DaemonInfo struct {
+	Version     string `json:"version"`
+	Protocol    int    `json:"protocol"`
+	MinProtocol int    `json:"min_protocol"`
+}
+
+// SelfUpdateResult reports a self update. When Updated is set the daemon
+// restarts on LatestVersion shortly after responding.
+type SelfUpdateResult struct {
+	Channel        string `json:"channel"`
+	CurrentVersion string `json:"current_version"`
+	LatestVersion  string `json:"latest_version"`
+	Updated        bool   `json:"updated"`
+}
+
+// BudgetCharge describes model usage by an agent. Calls is 1 before a call,
+// which fails once the budget is spent, and 0 when reporting the output of a
+// call that was already counted. Token counts are estimates.
+type BudgetCharge struct {
+	Model        string `json:"model,omitempty"`
+	Calls        int    `json:"calls,omitempty"`
+	InputTokens  int    `json:"input_tokens,omitempty"`
+	OutputTokens int    `json:"output_tokens,omitempty"`
+}
+
+// BudgetStatus is an agent's model usage today and its daily limits; zero
+// limits are unlimited.
+type BudgetStatus struct {
+	Agent        string  `json:"agent"`
+	Calls        int     `json:"calls"`
+	InputTokens  int     `json:"input_tokens"`
+	OutputTokens int     `json:"output_tokens"`
+	Cost         float64 `json:"cost"`
+	MaxCalls     int     `json:"max_calls,omitempty"`
+	MaxCost      float64 `json:"max_cost,omitempty"`
+}
+
+// DaemonStats is a health summary of one daemon for 'op stats'. Completed24h
+// and Failed24h count async tasks that finished in the last 24 hours.
+type DaemonStats struct {
+	Version      string                      `json:"version"`
+	StartedAt    time.Time                   `json:"started_at"`
+	Agents       map[agent.ProcessStatus]int `json:"agents"`
+	Tasks        ToolTaskMetrics             `json:"tasks"`
+	Completed24h int                         `json:"completed_24h"`
+	Failed24h    int                         `json:"failed_24h"`
+}
+
+type ResourceReport struct {
+	SampledAt time.Time            `json:"sampled_at"`
+	Daemon    ResourceUsage        `json:"daemon"`
+	Agents    []AgentResourceUsage `json:"agents"`
 }
 
 func EncodeRequest(req Request) ([]byte, error) {
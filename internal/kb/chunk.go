@@ -0,0 +1,91 @@
+package kb
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// chunkSize is the length in bytes chunks are packed up to.
+	chunkSize = 1200
+	// chunkOverlap is how much of the end of a chunk is repeated at the
+	// start of the next, so text cut at a boundary is still found.
+	chunkOverlap = 150
+)
+
+// Chunk splits text into pieces of about chunkSize bytes, keeping
+// paragraphs together where they fit.
+func Chunk(text string) []string {
+	var (
+		chunks  []string
+		current strings.Builder
+	)
+	flush := func() {
+		if s := strings.TrimSpace(current.String()); s != "" {
+			chunks = append(chunks, s)
+		}
+		current.Reset()
+	}
+
+	for _, para := range paragraphs(text) {
+		for _, piece := range splitLong(para) {
+			if current.Len() > 0 && current.Len()+len(piece)+2 > chunkSize {
+				tail := overlap(current.String())
+				flush()
+				current.WriteString(tail)
+			}
+			if current.Len() > 0 {
+				current.WriteString("\n\n")
+			}
+			current.WriteString(piece)
+		}
+	}
+	flush()
+	return chunks
+}
+
+// paragraphs splits text on blank lines, dropping empty paragraphs.
+func paragraphs(text string) []string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	var out []string
+	for _, p := range strings.Split(text, "\n\n") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// splitLong cuts a paragraph at word boundaries into pieces that fit in a
+// chunk after the overlap.
+func splitLong(para string) []string {
+	const size = chunkSize - chunkOverlap
+	var out []string
+	for len(para) > size {
+		cut := strings.LastIndexAny(para[:size], " \n\t")
+		if cut <= 0 {
+			cut = size
+			for cut > 0 && !utf8.RuneStart(para[cut]) {
+				cut--
+			}
+		}
+		out = append(out, strings.TrimSpace(para[:cut]))
+		para = strings.TrimSpace(para[cut:])
+	}
+	if para != "" {
+		out = append(out, para)
+	}
+	return out
+}
+
+// overlap returns the last words of s, up to chunkOverlap bytes.
+func overlap(s string) string {
+	if len(s) <= chunkOverlap {
+		return ""
+	}
+	tail := s[len(s)-chunkOverlap:]
+	if i := strings.IndexAny(tail, " \n\t"); i >= 0 {
+		tail = tail[i+1:]
+	}
+	return strings.TrimSpace(tail)
+}
@@ -0,0 +1,257 @@
+package kb
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// maxDownload bounds the size of a fetched URL.
+const maxDownload = 20 << 20
+
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// Document is text loaded for the knowledge base. Source is the absolute
+// path or URL it came from.
+type Document struct {
+	Source string
+	Title  string
+	Text   string
+}
+
+// supported lists the file extensions Load reads.
+var supported = map[string]bool{
+	".md":       true,
+	".markdown": true,
+	".txt":      true,
+	".rst":      true,
+	".html":     true,
+	".htm":      true,
+	".pdf":      true,
+}
+
+// Load reads source, a URL, a file or a directory of supported files.
+func Load(ctx context.Context, source string) ([]Document, error) {
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		doc, err := loadURL(ctx, source)
+		if err != nil {
+			return nil, err
+		}
+		return []Document{doc}, nil
+	}
+
+	path, err := filepath.Abs(source)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		doc, err := loadFile(ctx, path)
+		if err != nil {
+			return nil, err
+		}
+		return []Document{doc}, nil
+	}
+
+	var docs []Document
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != path && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !supported[strings.ToLower(filepath.Ext(p))] {
+			return nil
+		}
+		doc, err := loadFile(ctx, p)
+		if err != nil {
+			return err
+		}
+		docs = append(docs, doc)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(docs) == 0 {
+		return nil, fmt.Errorf("%s: no supported documents found", source)
+	}
+	return docs, nil
+}
+
+func loadFile(ctx context.Context, path string) (Document, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	if !supported[ext] {
+		return Document{}, fmt.Errorf("%s: unsupported file type %q", path, ext)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Document{}, err
+	}
+	fallback := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	switch ext {
+	case ".pdf":
+		text, err := pdfText(ctx, data)
+		if err != nil {
+			return Document{}, fmt.Errorf("%s: %w", path, err)
+		}
+		return Document{Source: path, Title: fallback, Text: text}, nil
+	case ".html", ".htm":
+		title, text, err := htmlText(data)
+		if err != nil {
+			return Document{}, fmt.Errorf("%s: %w", path, err)
+		}
+		return Document{Source: path, Title: firstNonEmpty(title, fallback), Text: text}, nil
+	default:
+		text := string(data)
+		return Document{Source: path, Title: firstNonEmpty(markdownTitle(text), fallback), Text: text}, nil
+	}
+}
+
+func loadURL(ctx context.Context, url string) (Document, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return Document{}, err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return Document{}, fmt.Errorf("fetch %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Document{}, fmt.Errorf("fetch %s: %s", url, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxDownload))
+	if err != nil {
+		return Document{}, fmt.Errorf("fetch %s: %w", url, err)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	switch {
+	case mediaType == "application/pdf" || strings.HasSuffix(strings.ToLower(url), ".pdf"):
+		text, err := pdfText(ctx, data)
+		if err != nil {
+			return Document{}, fmt.Errorf("%s: %w", url, err)
+		}
+		return Document{Source: url, Title: url, Text: text}, nil
+	case mediaType == "text/html" || mediaType == "application/xhtml+xml":
+		title, text, err := htmlText(data)
+		if err != nil {
+			return Document{}, fmt.Errorf("%s: %w", url, err)
+		}
+		return Document{Source: url, Title: firstNonEmpty(title, url), Text: text}, nil
+	default:
+		text := string(data)
+		return Document{Source: url, Title: firstNonEmpty(markdownTitle(text), url), Text: text}, nil
+	}
+}
+
+// pdfText extracts a PDF's text with pdftotext from poppler.
+func pdfText(ctx context.Context, data []byte) (string, error) {
+	bin, err := exec.LookPath("pdftotext")
+	if err != nil {
+		return "", fmt.Errorf("reading PDFs requires pdftotext (poppler-utils) on PATH")
+	}
+	cmd := exec.CommandContext(ctx, bin, "-layout", "-", "-")
+	cmd.Stdin = bytes.NewReader(data)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("pdftotext: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return string(out), nil
+}
+
+// skipElements are HTML elements whose text is not content.
+var skipElements = map[string]bool{
+	"script":   true,
+	"style":    true,
+	"noscript": true,
+	"nav":      true,
+	"header":   true,
+	"footer":   true,
+	"svg":      true,
+}
+
+// blockElements end a paragraph in the extracted text.
+var blockElements = map[string]bool{
+	"p": true, "div": true, "section": true, "article": true, "br": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"li": true, "tr": true, "pre": true, "blockquote": true, "table": true,
+}
+
+// htmlText returns an HTML page's title and visible text.
+func htmlText(data []byte) (string, string, error) {
+	root, err := html.Parse(bytes.NewReader(data))
+	if err != nil {
+		return "", "", err
+	}
+	var (
+		title string
+		text  strings.Builder
+		walk  func(*html.Node)
+	)
+	walk = func(n *html.Node) {
+		switch n.Type {
+		case html.ElementNode:
+			if n.Data == "title" && n.FirstChild != nil && title == "" {
+				title = strings.TrimSpace(n.FirstChild.Data)
+				return
+			}
+			if skipElements[n.Data] {
+				return
+			}
+		case html.TextNode:
+			if s := strings.Join(strings.Fields(n.Data), " "); s != "" {
+				text.WriteString(s)
+				text.WriteString(" ")
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+		if n.Type == html.ElementNode && blockElements[n.Data] {
+			text.WriteString("\n\n")
+		}
+	}
+	walk(root)
+	return title, text.String(), nil
+}
+
+// markdownTitle returns the first top-level heading of a markdown text.
+func markdownTitle(text string) string {
+	for _, line := range strings.Split(text, "\n") {
+		if title, ok := strings.CutPrefix(strings.TrimSpace(line), "# "); ok {
+			return strings.TrimSpace(title)
+		}
+	}
+	return ""
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
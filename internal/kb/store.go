@@ -0,0 +1,214 @@
+// Package kb is the knowledge base: documents added with 'op kb add' are
+// split into chunks, embedded and searched by meaning through the
+// search_knowledge tool so agents can ground their answers in them.
+package kb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"opperator/internal/embedding"
+)
+
+const (
+	// DefaultLimit is the number of chunks a search returns when none is
+	// given.
+	DefaultLimit = 5
+	// MaxLimit caps the chunks a search returns.
+	MaxLimit = 20
+	// embedBatch is the number of chunks embedded per request.
+	embedBatch = 64
+)
+
+// DocumentInfo describes a document in the knowledge base.
+type DocumentInfo struct {
+	ID      int64     `json:"id"`
+	Source  string    `json:"source"`
+	Title   string    `json:"title"`
+	Chunks  int       `json:"chunks"`
+	AddedAt time.Time `json:"added_at"`
+}
+
+// Result is a chunk found by Search.
+type Result struct {
+	Source  string  `json:"source"`
+	Title   string  `json:"title"`
+	Content string  `json:"content"`
+	Score   float64 `json:"score"`
+}
+
+// Store keeps documents in kb_documents and their chunks in kb_chunks.
+type Store struct {
+	db    *sql.DB
+	embed embedding.Embedder
+}
+
+func NewStore(db *sql.DB, embed embedding.Embedder) *Store {
+	return &Store{db: db, embed: embed}
+}
+
+// Add chunks, embeds and stores doc, replacing an earlier version from the
+// same source.
+func (s *Store) Add(ctx context.Context, doc Document) (DocumentInfo, error) {
+	chunks := Chunk(doc.Text)
+	if len(chunks) == 0 {
+		return DocumentInfo{}, fmt.Errorf("%s: no text found", doc.Source)
+	}
+
+	vectors := make([][]float32, 0, len(chunks))
+	var model string
+	for start := 0; start < len(chunks); start += embedBatch {
+		batch := chunks[start:min(start+embedBatch, len(chunks))]
+		v, m, err := s.embed(ctx, batch)
+		if err != nil {
+			return DocumentInfo{}, fmt.Errorf("embed %s: %w", doc.Source, err)
+		}
+		vectors = append(vectors, v...)
+		model = m
+	}
+
+	info := DocumentInfo{Source: doc.Source, Title: doc.Title, Chunks: len(chunks), AddedAt: time.Now()}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return DocumentInfo{}, err
+	}
+	defer tx.Rollback()
+	if err := removeDocuments(ctx, tx, `source = ?`, doc.Source); err != nil {
+		return DocumentInfo{}, err
+	}
+	res, err := tx.ExecContext(ctx,
+		`INSERT INTO kb_documents (source, title, model, chunks, added_at) VALUES (?, ?, ?, ?, ?)`,
+		info.Source, info.Title, model, info.Chunks, info.AddedAt.Unix())
+	if err != nil {
+		return DocumentInfo{}, fmt.Errorf("store document: %w", err)
+	}
+	if info.ID, err = res.LastInsertId(); err != nil {
+		return DocumentInfo{}, err
+	}
+	for i, chunk := range chunks {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO kb_chunks (document_id, seq, content, embedding) VALUES (?, ?, ?, ?)`,
+			info.ID, i, chunk, embedding.Encode(vectors[i])); err != nil {
+			return DocumentInfo{}, fmt.Errorf("store chunk: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return DocumentInfo{}, err
+	}
+	return info, nil
+}
+
+// List returns every document, most recently added first.
+func (s *Store) List(ctx context.Context) ([]DocumentInfo, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, source, title, chunks, added_at FROM kb_documents ORDER BY added_at DESC, id DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var docs []DocumentInfo
+	for rows.Next() {
+		var (
+			doc   DocumentInfo
+			added int64
+		)
+		if err := rows.Scan(&doc.ID, &doc.Source, &doc.Title, &doc.Chunks, &added); err != nil {
+			return nil, err
+		}
+		doc.AddedAt = time.Unix(added, 0)
+		docs = append(docs, doc)
+	}
+	return docs, rows.Err()
+}
+
+// Remove deletes the document with ref as its ID or source, or every
+// document under ref when it is a directory or URL prefix ending in a
+// slash. It returns the number of documents removed.
+func (s *Store) Remove(ctx context.Context, ref string) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	where, args := `source = ?`, []any{ref}
+	if id, err := strconv.ParseInt(ref, 10, 64); err == nil {
+		where, args = `id = ?`, []any{id}
+	} else if strings.HasSuffix(ref, "/") {
+		where = `instr(source, ?) = 1`
+	}
+	var n int
+	if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM kb_documents WHERE `+where, args...).Scan(&n); err != nil {
+		return 0, err
+	}
+	if err := removeDocuments(ctx, tx, where, args...); err != nil {
+		return 0, err
+	}
+	return n, tx.Commit()
+}
+
+func removeDocuments(ctx context.Context, tx *sql.Tx, where string, args ...any) error {
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM kb_chunks WHERE document_id IN (SELECT id FROM kb_documents WHERE `+where+`)`, args...); err != nil {
+		return fmt.Errorf("remove chunks: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM kb_documents WHERE `+where, args...); err != nil {
+		return fmt.Errorf("remove documents: %w", err)
+	}
+	return nil
+}
+
+// Search returns the chunks most similar to query, best first. Documents
+// embedded with another model than the current one are not searched.
+func (s *Store) Search(ctx context.Context, query string, limit int) ([]Result, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, fmt.Errorf("query is required")
+	}
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	limit = min(limit, MaxLimit)
+
+	vectors, model, err := s.embed(ctx, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("embed query: %w", err)
+	}
+	target := vectors[0]
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT d.source, d.title, c.content, c.embedding
+		   FROM kb_chunks c JOIN kb_documents d ON d.id = c.document_id
+		  WHERE d.model = ?`, model)
+	if err != nil {
+		return nil, fmt.Errorf("query knowledge base: %w", err)
+	}
+	defer rows.Close()
+
+	var found []Result
+	for rows.Next() {
+		var (
+			r      Result
+			vector []byte
+		)
+		if err := rows.Scan(&r.Source, &r.Title, &r.Content, &vector); err != nil {
+			return nil, err
+		}
+		r.Score = embedding.Cosine(target, embedding.Decode(vector))
+		found = append(found, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(found, func(i, j int) bool { return found[i].Score > found[j].Score })
+	if len(found) > limit {
+		found = found[:limit]
+	}
+	return found, nil
+}
@@ -0,0 +1,132 @@
+package kv
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// GetShared returns the value of key on the namespace blackboard, or
+// ErrNotFound.
+func (s *Store) GetShared(ctx context.Context, namespace, key string) (Entry, error) {
+	if err := checkSharedKey(namespace, key); err != nil {
+		return Entry{}, err
+	}
+	e := Entry{Namespace: namespace, Key: key}
+	var (
+		value   string
+		updated int64
+	)
+	err := s.db.QueryRowContext(ctx,
+		`SELECT value, writer, updated_at FROM blackboard WHERE namespace = ? AND key = ?`,
+		namespace, key).Scan(&value, &e.Writer, &updated)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Entry{}, fmt.Errorf("%w: %s/%s", ErrNotFound, namespace, key)
+	}
+	if err != nil {
+		return Entry{}, fmt.Errorf("get %s/%s: %w", namespace, key, err)
+	}
+	e.Value = json.RawMessage(value)
+	e.UpdatedAt = time.Unix(updated, 0)
+	return e, nil
+}
+
+// SetShared stores value, which must be JSON, under key on the namespace
+// blackboard. writer is the agent writing it, or empty for an operator.
+func (s *Store) SetShared(ctx context.Context, namespace, writer, key string, value json.RawMessage) (Entry, error) {
+	if err := checkSharedKey(namespace, key); err != nil {
+		return Entry{}, err
+	}
+	if err := checkValue(key, value); err != nil {
+		return Entry{}, err
+	}
+	now := time.Now()
+	if _, err := s.db.ExecContext(ctx, `
+		INSERT INTO blackboard (namespace, key, value, writer, updated_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(namespace, key) DO UPDATE SET
+			value = excluded.value, writer = excluded.writer, updated_at = excluded.updated_at`,
+		namespace, key, string(value), writer, now.Unix()); err != nil {
+		return Entry{}, fmt.Errorf("set %s/%s: %w", namespace, key, err)
+	}
+	return Entry{Namespace: namespace, Key: key, Value: value, Writer: writer, UpdatedAt: time.Unix(now.Unix(), 0)}, nil
+}
+
+// DeleteShared removes key from the namespace blackboard, or returns
+// ErrNotFound.
+func (s *Store) DeleteShared(ctx context.Context, namespace, key string) error {
+	if err := checkSharedKey(namespace, key); err != nil {
+		return err
+	}
+	res, err := s.db.ExecContext(ctx, `DELETE FROM blackboard WHERE namespace = ? AND key = ?`, namespace, key)
+	if err != nil {
+		return fmt.Errorf("delete %s/%s: %w", namespace, key, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w: %s/%s", ErrNotFound, namespace, key)
+	}
+	return nil
+}
+
+// ListShared returns the entries of the namespace blackboard whose key
+// starts with prefix, sorted by key.
+func (s *Store) ListShared(ctx context.Context, namespace, prefix string) ([]Entry, error) {
+	if strings.TrimSpace(namespace) == "" {
+		return nil, fmt.Errorf("%w: namespace is required", ErrInvalid)
+	}
+	return s.queryShared(ctx, `
+		SELECT namespace, key, value, writer, updated_at FROM blackboard
+		WHERE namespace = ? AND substr(key, 1, ?) = ? ORDER BY key`,
+		namespace, len(prefix), prefix)
+}
+
+// Recent returns the limit most recently written entries across namespaces,
+// newest first.
+func (s *Store) Recent(ctx context.Context, namespaces []string, limit int) ([]Entry, error) {
+	if len(namespaces) == 0 || limit <= 0 {
+		return nil, nil
+	}
+	args := make([]any, 0, len(namespaces)+1)
+	for _, ns := range namespaces {
+		args = append(args, ns)
+	}
+	args = append(args, limit)
+	return s.queryShared(ctx, `
+		SELECT namespace, key, value, writer, updated_at FROM blackboard
+		WHERE namespace IN (?`+strings.Repeat(", ?", len(namespaces)-1)+`)
+		ORDER BY updated_at DESC, namespace, key LIMIT ?`, args...)
+}
+
+func (s *Store) queryShared(ctx context.Context, query string, args ...any) ([]Entry, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list keys: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []Entry
+	for rows.Next() {
+		var (
+			e       Entry
+			value   string
+			updated int64
+		)
+		if err := rows.Scan(&e.Namespace, &e.Key, &value, &e.Writer, &updated); err != nil {
+			return nil, fmt.Errorf("list keys: %w", err)
+		}
+		e.Value = json.RawMessage(value)
+		e.UpdatedAt = time.Unix(updated, 0)
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+func checkSharedKey(namespace, key string) error {
+	if strings.TrimSpace(namespace) == "" {
+		return fmt.Errorf("%w: namespace is required", ErrInvalid)
+	}
+	return checkKey(namespace, key)
+}
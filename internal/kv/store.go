@@ -0,0 +1,188 @@
+// Package kv is a key-value store the daemon keeps for agents, so they have
+// durable state across restarts and moves without keeping files of their
+// own. Keys are namespaced per agent and values are JSON. Blackboards are
+// namespaces shared between agents.
+package kv
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// MaxKeyLength bounds a key.
+	MaxKeyLength = 256
+	// MaxValueSize bounds the JSON encoding of a value.
+	MaxValueSize = 1 << 20
+)
+
+var (
+	// ErrNotFound is returned for a key the agent has no value for.
+	ErrNotFound = errors.New("key not found")
+	// ErrInvalid is returned for a key or value that cannot be stored.
+	ErrInvalid = errors.New("invalid key or value")
+)
+
+// Entry is a key and its value. Namespace and Writer are set for blackboard
+// entries: the blackboard and the agent that last wrote the key.
+type Entry struct {
+	Namespace string          `json:"namespace,omitempty"`
+	Key       string          `json:"key"`
+	Value     json.RawMessage `json:"value"`
+	Writer    string          `json:"writer,omitempty"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// Store keeps entries in the agent_kv table.
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// Get returns agent's value for key, or ErrNotFound.
+func (s *Store) Get(ctx context.Context, agent, key string) (Entry, error) {
+	if err := checkKey(agent, key); err != nil {
+		return Entry{}, err
+	}
+	var (
+		value   string
+		updated int64
+	)
+	err := s.db.QueryRowContext(ctx,
+		`SELECT value, updated_at FROM agent_kv WHERE agent = ? AND key = ?`, agent, key).Scan(&value, &updated)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Entry{}, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	if err != nil {
+		return Entry{}, fmt.Errorf("get %s: %w", key, err)
+	}
+	return Entry{Key: key, Value: json.RawMessage(value), UpdatedAt: time.Unix(updated, 0)}, nil
+}
+
+// Set stores value, which must be JSON, as agent's value for key.
+func (s *Store) Set(ctx context.Context, agent, key string, value json.RawMessage) (Entry, error) {
+	if err := checkKey(agent, key); err != nil {
+		return Entry{}, err
+	}
+	if err := checkValue(key, value); err != nil {
+		return Entry{}, err
+	}
+	now := time.Now()
+	if _, err := s.db.ExecContext(ctx, `
+		INSERT INTO agent_kv (agent, key, value, updated_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT(agent, key) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at`,
+		agent, key, string(value), now.Unix()); err != nil {
+		return Entry{}, fmt.Errorf("set %s: %w", key, err)
+	}
+	return Entry{Key: key, Value: value, UpdatedAt: time.Unix(now.Unix(), 0)}, nil
+}
+
+// Delete removes agent's value for key, or returns ErrNotFound.
+func (s *Store) Delete(ctx context.Context, agent, key string) error {
+	if err := checkKey(agent, key); err != nil {
+		return err
+	}
+	res, err := s.db.ExecContext(ctx, `DELETE FROM agent_kv WHERE agent = ? AND key = ?`, agent, key)
+	if err != nil {
+		return fmt.Errorf("delete %s: %w", key, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	return nil
+}
+
+// List returns agent's entries whose key starts with prefix, sorted by key.
+func (s *Store) List(ctx context.Context, agent, prefix string) ([]Entry, error) {
+	if strings.TrimSpace(agent) == "" {
+		return nil, fmt.Errorf("%w: agent is required", ErrInvalid)
+	}
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT key, value, updated_at FROM agent_kv WHERE agent = ? AND substr(key, 1, ?) = ? ORDER BY key`,
+		agent, len(prefix), prefix)
+	if err != nil {
+		return nil, fmt.Errorf("list keys: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []Entry
+	for rows.Next() {
+		var (
+			e       Entry
+			value   string
+			updated int64
+		)
+		if err := rows.Scan(&e.Key, &value, &updated); err != nil {
+			return nil, fmt.Errorf("list keys: %w", err)
+		}
+		e.Value = json.RawMessage(value)
+		e.UpdatedAt = time.Unix(updated, 0)
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// Import replaces agent's entries with entries, e.g. those of an agent moved
+// from another daemon.
+func (s *Store) Import(ctx context.Context, agent string, entries []Entry) error {
+	if strings.TrimSpace(agent) == "" {
+		return fmt.Errorf("%w: agent is required", ErrInvalid)
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM agent_kv WHERE agent = ?`, agent); err != nil {
+		return fmt.Errorf("import keys: %w", err)
+	}
+	for _, e := range entries {
+		if err := checkKey(agent, e.Key); err != nil {
+			return err
+		}
+		if !json.Valid(e.Value) {
+			return fmt.Errorf("%w: the value of %s is not JSON", ErrInvalid, e.Key)
+		}
+		updated := e.UpdatedAt
+		if updated.IsZero() {
+			updated = time.Now()
+		}
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO agent_kv (agent, key, value, updated_at) VALUES (?, ?, ?, ?)`,
+			agent, e.Key, string(e.Value), updated.Unix()); err != nil {
+			return fmt.Errorf("import %s: %w", e.Key, err)
+		}
+	}
+	return tx.Commit()
+}
+
+func checkKey(agent, key string) error {
+	switch {
+	case strings.TrimSpace(agent) == "":
+		return fmt.Errorf("%w: agent is required", ErrInvalid)
+	case key == "":
+		return fmt.Errorf("%w: key is required", ErrInvalid)
+	case len(key) > MaxKeyLength:
+		return fmt.Errorf("%w: key is longer than %d bytes", ErrInvalid, MaxKeyLength)
+	}
+	return nil
+}
+
+func checkValue(key string, value json.RawMessage) error {
+	if len(value) == 0 || !json.Valid(value) {
+		return fmt.Errorf("%w: the value of %s is not JSON", ErrInvalid, key)
+	}
+	if len(value) > MaxValueSize {
+		return fmt.Errorf("%w: the value of %s is larger than %d bytes", ErrInvalid, key, MaxValueSize)
+	}
+	return nil
+}
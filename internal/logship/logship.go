@@ -0,0 +1,280 @@
+// Package logship forwards daemon and agent log lines to external systems
+// (syslog, the Loki push API, generic HTTP endpoints) as configured in the
+// log_shipping section of daemon.yaml. Lines are batched per destination and
+// retried with backoff; when a destination falls behind, new lines are
+// dropped rather than blocking the daemon.
+package logship
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"opperator/config"
+)
+
+const (
+	defaultBatchSize = 100
+	// queueSize is how many lines wait per destination before new ones are
+	// dropped
+	queueSize = 10000
+	// maxAttempts is how often a batch is sent before it is dropped
+	maxAttempts = 5
+	maxBackoff  = 30 * time.Second
+	sendTimeout = 15 * time.Second
+	// logPrefix marks the shipper's own log lines, which are never shipped
+	// so a failing destination cannot feed itself
+	logPrefix = "[LogShip]"
+)
+
+// Record is one log line.
+type Record struct {
+	Time time.Time `json:"time"`
+	// Source is config.LogSourceDaemon or config.LogSourceAgents
+	Source  string `json:"source"`
+	Agent   string `json:"agent,omitempty"`
+	Level   string `json:"level"`
+	Message string `json:"message"`
+	Host    string `json:"host"`
+}
+
+// sender delivers batches to one destination.
+type sender interface {
+	send(ctx context.Context, batch []Record) error
+	close()
+}
+
+type destination struct {
+	config.LogDestination
+	sender  sender
+	queue   chan Record
+	dropped int
+	mu      sync.Mutex
+}
+
+// Shipper fans log lines out to the configured destinations.
+type Shipper struct {
+	host  string
+	dests []*destination
+	stop  chan struct{}
+	wg    sync.WaitGroup
+}
+
+// New starts a shipper for cfg. It returns nil when no destination is
+// configured; a nil shipper ignores every line.
+func New(cfg config.LogShippingConfig) (*Shipper, error) {
+	if len(cfg.Destinations) == 0 {
+		return nil, nil
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	s := &Shipper{host: host, stop: make(chan struct{})}
+	for _, dc := range cfg.Destinations {
+		snd, err := newSender(dc)
+		if err != nil {
+			for _, d := range s.dests {
+				d.sender.close()
+			}
+			return nil, fmt.Errorf("log destination %q: %w", dc.Name, err)
+		}
+		s.dests = append(s.dests, &destination{
+			LogDestination: dc,
+			sender:         snd,
+			queue:          make(chan Record, queueSize),
+		})
+	}
+	for _, d := range s.dests {
+		s.wg.Add(1)
+		go s.run(d)
+	}
+	return s, nil
+}
+
+func newSender(dc config.LogDestination) (sender, error) {
+	switch dc.Type {
+	case config.LogDestinationSyslog:
+		return newSyslogSender(dc.Address, dc.Tag)
+	case config.LogDestinationLoki:
+		return &lokiSender{url: dc.URL, headers: dc.Headers, labels: dc.Labels}, nil
+	case config.LogDestinationHTTP:
+		return &httpSender{url: dc.URL, headers: dc.Headers}, nil
+	}
+	return nil, fmt.Errorf("unknown type %q", dc.Type)
+}
+
+// Ship queues r for every destination that takes it. It never blocks.
+func (s *Shipper) Ship(r Record) {
+	if s == nil {
+		return
+	}
+	if r.Time.IsZero() {
+		r.Time = time.Now()
+	}
+	r.Host = s.host
+	for _, d := range s.dests {
+		if !d.Ships(r.Source) {
+			continue
+		}
+		if r.Agent != "" && len(d.Agents) > 0 && !slices.Contains(d.Agents, r.Agent) {
+			continue
+		}
+		select {
+		case d.queue <- r:
+		default:
+			d.mu.Lock()
+			d.dropped++
+			d.mu.Unlock()
+		}
+	}
+}
+
+// ShipAgentLine ships a line from an agent's log, whose "[level]" prefix,
+// when present, becomes the record's level.
+func (s *Shipper) ShipAgentLine(agentName, line string) {
+	if s == nil {
+		return
+	}
+	level := "info"
+	if tag, rest, ok := strings.Cut(line, "]"); ok && strings.HasPrefix(tag, "[") {
+		switch t := strings.ToLower(tag[1:]); t {
+		case "debug", "info", "warning", "error", "fatal":
+			level, line = t, strings.TrimSpace(rest)
+		}
+	}
+	s.Ship(Record{Source: config.LogSourceAgents, Agent: agentName, Level: level, Message: line})
+}
+
+// Write ships lines written to the daemon log, so the shipper can be teed
+// next to the log file. Lines in the json log format keep their level.
+func (s *Shipper) Write(p []byte) (int, error) {
+	if s == nil {
+		return len(p), nil
+	}
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\r\n"), "\n") {
+		if line == "" || strings.Contains(line, logPrefix) || strings.Contains(line, `"component":"LogShip"`) {
+			continue
+		}
+		r := Record{Source: config.LogSourceDaemon, Level: "info", Message: line}
+		var structured struct {
+			Level string `json:"level"`
+			Msg   string `json:"msg"`
+		}
+		if strings.HasPrefix(line, "{") && json.Unmarshal([]byte(line), &structured) == nil && structured.Msg != "" {
+			r.Level = strings.ToLower(structured.Level)
+		}
+		s.Ship(r)
+	}
+	return len(p), nil
+}
+
+// Close sends the lines still queued and stops the shipper, giving up when
+// ctx is done.
+func (s *Shipper) Close(ctx context.Context) {
+	if s == nil {
+		return
+	}
+	close(s.stop)
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+	for _, d := range s.dests {
+		d.sender.close()
+	}
+}
+
+func (s *Shipper) run(d *destination) {
+	defer s.wg.Done()
+	size := d.BatchSize
+	if size <= 0 {
+		size = defaultBatchSize
+	}
+	ticker := time.NewTicker(d.Flush())
+	defer ticker.Stop()
+
+	batch := make([]Record, 0, size)
+	failing := false
+	flush := func(final bool) {
+		if len(batch) == 0 {
+			return
+		}
+		err := s.send(d, batch, final)
+		d.mu.Lock()
+		dropped := d.dropped
+		d.dropped = 0
+		d.mu.Unlock()
+		switch {
+		case err != nil && !failing:
+			failing = true
+			log.Printf("%s Failed to ship logs to %s, dropping %d lines: %v", logPrefix, d.Name, len(batch)+dropped, err)
+		case err == nil && failing:
+			failing = false
+			log.Printf("%s Shipping logs to %s again (%d lines were dropped meanwhile)", logPrefix, d.Name, dropped)
+		case err == nil && dropped > 0:
+			log.Printf("%s Dropped %d lines for %s because it fell behind", logPrefix, dropped, d.Name)
+		}
+		batch = batch[:0]
+	}
+	for {
+		select {
+		case r := <-d.queue:
+			batch = append(batch, r)
+			if len(batch) >= size {
+				flush(false)
+			}
+		case <-ticker.C:
+			flush(false)
+		case <-s.stop:
+			for {
+				select {
+				case r := <-d.queue:
+					batch = append(batch, r)
+					if len(batch) >= size {
+						flush(true)
+					}
+				default:
+					flush(true)
+					return
+				}
+			}
+		}
+	}
+}
+
+// send delivers a batch, retrying with exponential backoff. The last batches
+// sent while stopping get a single attempt.
+func (s *Shipper) send(d *destination, batch []Record, final bool) error {
+	backoff := time.Second
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+		err = d.sender.send(ctx, batch)
+		cancel()
+		if err == nil || final {
+			return err
+		}
+		if attempt == maxAttempts {
+			break
+		}
+		select {
+		case <-time.After(backoff):
+		case <-s.stop:
+			final = true
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+	return err
+}
@@ -0,0 +1,185 @@
+package logship
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/syslog"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var httpClient = &http.Client{Timeout: sendTimeout}
+
+func postJSON(ctx context.Context, url string, headers map[string]string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// httpSender posts each batch as a JSON array of records.
+type httpSender struct {
+	url     string
+	headers map[string]string
+}
+
+func (s *httpSender) send(ctx context.Context, batch []Record) error {
+	return postJSON(ctx, s.url, s.headers, batch)
+}
+
+func (s *httpSender) close() {}
+
+// lokiSender pushes batches to the Loki push API, one stream per label set.
+type lokiSender struct {
+	url     string
+	headers map[string]string
+	labels  map[string]string
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+func (s *lokiSender) send(ctx context.Context, batch []Record) error {
+	streams := map[string]*lokiStream{}
+	var order []string
+	for _, r := range batch {
+		labels := make(map[string]string, len(s.labels)+4)
+		for k, v := range s.labels {
+			labels[k] = v
+		}
+		labels["source"] = r.Source
+		labels["level"] = r.Level
+		labels["host"] = r.Host
+		if r.Agent != "" {
+			labels["agent"] = r.Agent
+		}
+		key := labelKey(labels)
+		stream, ok := streams[key]
+		if !ok {
+			stream = &lokiStream{Stream: labels}
+			streams[key] = stream
+			order = append(order, key)
+		}
+		stream.Values = append(stream.Values, [2]string{strconv.FormatInt(r.Time.UnixNano(), 10), r.Message})
+	}
+	payload := struct {
+		Streams []*lokiStream `json:"streams"`
+	}{}
+	for _, key := range order {
+		payload.Streams = append(payload.Streams, streams[key])
+	}
+	return postJSON(ctx, s.url, s.headers, payload)
+}
+
+func (s *lokiSender) close() {}
+
+func labelKey(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s=%q,", k, labels[k])
+	}
+	return b.String()
+}
+
+// syslogSender writes to the local syslog daemon or a remote one over UDP
+// or TCP. The connection is redialled after a failed write.
+type syslogSender struct {
+	network string
+	addr    string
+	tag     string
+
+	mu     sync.Mutex
+	writer *syslog.Writer
+}
+
+func newSyslogSender(address, tag string) (*syslogSender, error) {
+	s := &syslogSender{tag: tag}
+	if s.tag == "" {
+		s.tag = "opperator"
+	}
+	if address != "" {
+		u, err := url.Parse(address)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address: %w", err)
+		}
+		s.network, s.addr = u.Scheme, u.Host
+	}
+	return s, nil
+}
+
+func (s *syslogSender) send(_ context.Context, batch []Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.writer == nil {
+		w, err := syslog.Dial(s.network, s.addr, syslog.LOG_INFO|syslog.LOG_DAEMON, s.tag)
+		if err != nil {
+			return err
+		}
+		s.writer = w
+	}
+	for i, r := range batch {
+		line := r.Message
+		if r.Agent != "" {
+			line = fmt.Sprintf("[%s] %s", r.Agent, line)
+		}
+		var err error
+		switch r.Level {
+		case "error", "fatal":
+			err = s.writer.Err(line)
+		case "warn", "warning":
+			err = s.writer.Warning(line)
+		case "debug":
+			err = s.writer.Debug(line)
+		default:
+			err = s.writer.Info(line)
+		}
+		if err != nil {
+			s.writer.Close()
+			s.writer = nil
+			return fmt.Errorf("after %d of %d lines: %w", i, len(batch), err)
+		}
+	}
+	return nil
+}
+
+func (s *syslogSender) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.writer != nil {
+		s.writer.Close()
+		s.writer = nil
+	}
+}
@@ -0,0 +1,149 @@
+// Package memory is a long-term store agents write facts to and recall them
+// from by meaning. Entries are embedded when written and ranked by cosine
+// similarity to the query when recalled.
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"opperator/internal/embedding"
+)
+
+const (
+	// DefaultLimit is the number of memories recalled when none is given.
+	DefaultLimit = 5
+	// MaxLimit caps the memories recalled at once.
+	MaxLimit = 50
+	// maxContentLength bounds one memory so it stays a fact, not a document.
+	maxContentLength = 4000
+)
+
+// Scopes a memory is kept or recalled in.
+const (
+	// ScopeAgent covers every conversation with the agent.
+	ScopeAgent = "agent"
+	// ScopeConversation covers one conversation.
+	ScopeConversation = "conversation"
+)
+
+// Memory is a remembered fact. ConversationID is empty for memories kept for
+// every conversation with the agent.
+type Memory struct {
+	ID             int64     `json:"id"`
+	Agent          string    `json:"agent"`
+	ConversationID string    `json:"conversation_id,omitempty"`
+	Content        string    `json:"content"`
+	CreatedAt      time.Time `json:"created_at"`
+	// Score is the similarity to the recall query, from -1 to 1.
+	Score float64 `json:"score,omitempty"`
+}
+
+// Store keeps memories in the memories table.
+type Store struct {
+	db    *sql.DB
+	embed embedding.Embedder
+}
+
+func NewStore(db *sql.DB, embed embedding.Embedder) *Store {
+	return &Store{db: db, embed: embed}
+}
+
+// Remember stores content for agent, for conversationID only when set.
+func (s *Store) Remember(ctx context.Context, agent, conversationID, content string) (Memory, error) {
+	agent = strings.TrimSpace(agent)
+	content = strings.TrimSpace(content)
+	if agent == "" {
+		return Memory{}, fmt.Errorf("agent is required")
+	}
+	if content == "" {
+		return Memory{}, fmt.Errorf("content is required")
+	}
+	if len(content) > maxContentLength {
+		return Memory{}, fmt.Errorf("content is longer than %d bytes; remember a shorter summary", maxContentLength)
+	}
+
+	vectors, model, err := s.embed(ctx, []string{content})
+	if err != nil {
+		return Memory{}, fmt.Errorf("embed memory: %w", err)
+	}
+	now := time.Now()
+	res, err := s.db.ExecContext(ctx,
+		`INSERT INTO memories (agent, conversation_id, content, model, embedding, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		agent, conversationID, content, model, embedding.Encode(vectors[0]), now.Unix())
+	if err != nil {
+		return Memory{}, fmt.Errorf("store memory: %w", err)
+	}
+	id, _ := res.LastInsertId()
+	return Memory{ID: id, Agent: agent, ConversationID: conversationID, Content: content, CreatedAt: now}, nil
+}
+
+// Recall returns agent's memories most similar to query, best first.
+// Memories kept for a conversation are only seen from that conversation;
+// with conversationOnly, memories kept for every conversation are left out.
+func (s *Store) Recall(ctx context.Context, agent, conversationID, query string, conversationOnly bool, limit int) ([]Memory, error) {
+	agent = strings.TrimSpace(agent)
+	query = strings.TrimSpace(query)
+	if agent == "" {
+		return nil, fmt.Errorf("agent is required")
+	}
+	if query == "" {
+		return nil, fmt.Errorf("query is required")
+	}
+	if conversationOnly && conversationID == "" {
+		return nil, fmt.Errorf("no conversation to recall from")
+	}
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	limit = min(limit, MaxLimit)
+
+	vectors, model, err := s.embed(ctx, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("embed query: %w", err)
+	}
+	target := vectors[0]
+
+	// Vectors of other models are not comparable, so only memories embedded
+	// with the current model are searched.
+	where := `agent = ? AND model = ? AND (conversation_id = '' OR conversation_id = ?)`
+	if conversationOnly {
+		where = `agent = ? AND model = ? AND conversation_id = ?`
+	}
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, conversation_id, content, embedding, created_at FROM memories WHERE `+where,
+		agent, model, conversationID)
+	if err != nil {
+		return nil, fmt.Errorf("query memories: %w", err)
+	}
+	defer rows.Close()
+
+	var found []Memory
+	for rows.Next() {
+		var (
+			m       Memory
+			vector  []byte
+			created int64
+		)
+		if err := rows.Scan(&m.ID, &m.ConversationID, &m.Content, &vector, &created); err != nil {
+			return nil, fmt.Errorf("scan memory: %w", err)
+		}
+		m.Agent = agent
+		m.CreatedAt = time.Unix(created, 0)
+		m.Score = embedding.Cosine(target, embedding.Decode(vector))
+		found = append(found, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query memories: %w", err)
+	}
+
+	sort.SliceStable(found, func(i, j int) bool { return found[i].Score > found[j].Score })
+	if len(found) > limit {
+		found = found[:limit]
+	}
+	return found, nil
+}
@@ -0,0 +1,169 @@
+// Package notify delivers daemon events such as agent crash loops and task
+// failures to external sinks (Slack, email, generic webhooks) according to the
+// routes configured in daemon.yaml.
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"opperator/config"
+)
+
+// EventType identifies what happened. Routes match on these values.
+type EventType string
+
+const (
+	EventAgentCrashLoop     EventType = "agent.crash_loop"
+	EventTaskFailed         EventType = "task.failed"
+	EventDaemonUpdated      EventType = "daemon.updated"
+	EventDaemonUpdateFailed EventType = "daemon.update_failed"
+	EventBackupFailed       EventType = "daemon.backup_failed"
+	EventBudgetExceeded     EventType = "agent.budget_exceeded"
+)
+
+// sendTimeout bounds a single delivery attempt to one sink.
+const sendTimeout = 15 * time.Second
+
+// Event is a notification about something that happened on a daemon.
+type Event struct {
+	Type    EventType         `json:"type"`
+	Title   string            `json:"title"`
+	Message string            `json:"message"`
+	Agent   string            `json:"agent,omitempty"`
+	Daemon  string            `json:"daemon,omitempty"`
+	Fields  map[string]string `json:"fields,omitempty"`
+	Time    time.Time         `json:"time"`
+}
+
+// Sink delivers events to one destination.
+type Sink interface {
+	Send(ctx context.Context, ev Event) error
+}
+
+type route struct {
+	events []EventType
+	agents []string
+	sinks  []string
+}
+
+func (r route) matches(ev Event) bool {
+	if !slices.Contains(r.events, ev.Type) && !slices.Contains(r.events, "*") {
+		return false
+	}
+	if len(r.agents) > 0 && ev.Agent != "" && !slices.Contains(r.agents, ev.Agent) {
+		return false
+	}
+	return true
+}
+
+// Dispatcher routes events to sinks. Delivery happens in the background so
+// callers on hot paths never wait on the network.
+type Dispatcher struct {
+	sinks  map[string]Sink
+	routes []route
+	host   string
+	wg     sync.WaitGroup
+}
+
+// New builds a dispatcher from the notifications section of daemon.yaml. A
+// config without routes yields a dispatcher that drops every event.
+func New(cfg config.NotificationsConfig) (*Dispatcher, error) {
+	d := &Dispatcher{sinks: make(map[string]Sink, len(cfg.Sinks))}
+	d.host = hostname()
+
+	for _, sc := range cfg.Sinks {
+		sink, err := newSink(sc)
+		if err != nil {
+			return nil, err
+		}
+		d.sinks[sc.Name] = sink
+	}
+	for _, rc := range cfg.Routes {
+		r := route{agents: rc.Agents, sinks: rc.Sinks}
+		for _, ev := range rc.Events {
+			r.events = append(r.events, EventType(ev))
+		}
+		d.routes = append(d.routes, r)
+	}
+	return d, nil
+}
+
+func newSink(sc config.NotificationSink) (Sink, error) {
+	switch sc.Type {
+	case config.SinkSlack:
+		return &slackSink{url: sc.URL}, nil
+	case config.SinkWebhook:
+		return &webhookSink{url: sc.URL, headers: sc.Headers}, nil
+	case config.SinkEmail:
+		return &emailSink{
+			host:     sc.SMTPHost,
+			port:     sc.SMTPPort,
+			username: sc.Username,
+			password: sc.Password,
+			from:     sc.From,
+			to:       sc.To,
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown notification sink type %q", sc.Type)
+}
+
+// Notify delivers ev to every sink routed for it. It returns immediately.
+func (d *Dispatcher) Notify(ev Event) {
+	if d == nil {
+		return
+	}
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+	if ev.Daemon == "" {
+		ev.Daemon = d.host
+	}
+
+	seen := make(map[string]bool)
+	for _, r := range d.routes {
+		if !r.matches(ev) {
+			continue
+		}
+		for _, name := range r.sinks {
+			sink, ok := d.sinks[name]
+			if !ok || seen[name] {
+				continue
+			}
+			seen[name] = true
+			d.wg.Add(1)
+			go d.deliver(name, sink, ev)
+		}
+	}
+}
+
+func (d *Dispatcher) deliver(name string, sink Sink, ev Event) {
+	defer d.wg.Done()
+	ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+	defer cancel()
+	if err := sink.Send(ctx, ev); err != nil {
+		log.Printf("[Notify] Failed to deliver %s to sink %s: %v", ev.Type, name, err)
+		return
+	}
+	log.Printf("[Notify] Delivered %s to sink %s", ev.Type, name)
+}
+
+// Wait blocks until in-flight deliveries finish or ctx is done.
+func (d *Dispatcher) Wait(ctx context.Context) {
+	if d == nil {
+		return
+	}
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+}
@@ -0,0 +1,139 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var httpClient = &http.Client{Timeout: sendTimeout}
+
+func hostname() string {
+	name, err := os.Hostname()
+	if err != nil {
+		return "unknown"
+	}
+	return name
+}
+
+// text renders an event as plain text for sinks without structured payloads.
+func (ev Event) text() string {
+	var b strings.Builder
+	b.WriteString(ev.Message)
+	keys := make([]string, 0, len(ev.Fields))
+	for k := range ev.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	if ev.Agent != "" || ev.Daemon != "" || len(keys) > 0 {
+		b.WriteString("\n")
+	}
+	if ev.Agent != "" {
+		fmt.Fprintf(&b, "\nAgent: %s", ev.Agent)
+	}
+	if ev.Daemon != "" {
+		fmt.Fprintf(&b, "\nDaemon: %s", ev.Daemon)
+	}
+	for _, k := range keys {
+		fmt.Fprintf(&b, "\n%s: %s", k, ev.Fields[k])
+	}
+	return b.String()
+}
+
+func postJSON(ctx context.Context, url string, headers map[string]string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// slackSink posts to a Slack incoming webhook.
+type slackSink struct {
+	url string
+}
+
+func (s *slackSink) Send(ctx context.Context, ev Event) error {
+	return postJSON(ctx, s.url, nil, map[string]string{
+		"text": fmt.Sprintf("*%s*\n%s", ev.Title, ev.text()),
+	})
+}
+
+// webhookSink posts the event as JSON to an arbitrary endpoint.
+type webhookSink struct {
+	url     string
+	headers map[string]string
+}
+
+func (s *webhookSink) Send(ctx context.Context, ev Event) error {
+	return postJSON(ctx, s.url, s.headers, ev)
+}
+
+// emailSink sends a plain-text email over SMTP. Servers advertising STARTTLS
+// are upgraded automatically by net/smtp.
+type emailSink struct {
+	host     string
+	port     int
+	username string
+	password string
+	from     string
+	to       []string
+}
+
+func (s *emailSink) Send(ctx context.Context, ev Event) error {
+	addr := net.JoinHostPort(s.host, strconv.Itoa(s.port))
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", s.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(s.to, ", "))
+	fmt.Fprintf(&msg, "Subject: [opperator] %s\r\n", ev.Title)
+	fmt.Fprintf(&msg, "Date: %s\r\n", ev.Time.Format(time.RFC1123Z))
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(ev.text(), "\n", "\r\n"))
+	msg.WriteString("\r\n")
+
+	var auth smtp.Auth
+	if s.username != "" {
+		auth = smtp.PlainAuth("", s.username, s.password, s.host)
+	}
+
+	// smtp.SendMail has no context support; run it so ctx still bounds the wait
+	errc := make(chan error, 1)
+	go func() {
+		errc <- smtp.SendMail(addr, auth, s.from, s.to, msg.Bytes())
+	}()
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,308 @@
+// Package policy authorizes agent commands, async tools and workflows before
+// the daemon runs them, using the rules in the policy section of daemon.yaml
+// and, optionally, an Open Policy Agent server.
+package policy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"opperator/config"
+)
+
+// opaTimeout bounds one OPA query when the config sets no timeout.
+const opaTimeout = 2 * time.Second
+
+// Input describes one execution to authorize. Command holds the command,
+// tool or workflow name depending on Kind. Trigger names the trigger that
+// fired when Origin is "trigger", and Workflow the workflow a step belongs to.
+type Input struct {
+	Kind     string         `json:"kind"`
+	Agent    string         `json:"agent,omitempty"`
+	Command  string         `json:"command"`
+	Args     map[string]any `json:"args,omitempty"`
+	Origin   string         `json:"origin,omitempty"`
+	User     string         `json:"user,omitempty"`
+	Trigger  string         `json:"trigger,omitempty"`
+	Workflow string         `json:"workflow,omitempty"`
+	Time     time.Time      `json:"time"`
+}
+
+// DeniedError is returned for executions the policy does not allow.
+type DeniedError struct {
+	Rule   string
+	Reason string
+}
+
+func (e *DeniedError) Error() string {
+	msg := "denied by policy"
+	if e.Rule != "" {
+		msg += " rule " + strconv.Quote(e.Rule)
+	}
+	if e.Reason != "" {
+		msg += ": " + e.Reason
+	}
+	return msg
+}
+
+// IsDenied reports whether err is a policy denial.
+func IsDenied(err error) bool {
+	var denied *DeniedError
+	return errors.As(err, &denied)
+}
+
+type rule struct {
+	config.PolicyRule
+	// start and end are minutes after midnight; hasHours is false when the
+	// rule applies at any time of day
+	start, end int
+	hasHours   bool
+}
+
+// Engine evaluates executions against the configured policy. A nil Engine
+// allows everything.
+type Engine struct {
+	rules    []rule
+	deny     bool
+	location *time.Location
+	opa      *config.OPAConfig
+	client   *http.Client
+}
+
+// New builds an engine from the policy section of daemon.yaml. It returns
+// nil when no policy is configured.
+func New(cfg config.PolicyConfig) (*Engine, error) {
+	if !cfg.Enabled() {
+		return nil, nil
+	}
+	e := &Engine{deny: cfg.Default == config.PolicyDeny, location: time.Local, opa: cfg.OPA}
+	if cfg.Timezone != "" {
+		loc, err := time.LoadLocation(cfg.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("policy.timezone: %w", err)
+		}
+		e.location = loc
+	}
+	for _, rc := range cfg.Rules {
+		r := rule{PolicyRule: rc}
+		for _, patterns := range [][]string{rc.Agents, rc.Commands, rc.Origins, rc.Users} {
+			for _, p := range patterns {
+				if _, err := path.Match(p, ""); err != nil {
+					return nil, fmt.Errorf("policy rule %q: bad pattern %q", rc.Name, p)
+				}
+			}
+		}
+		for name, p := range rc.Args {
+			if _, err := path.Match(p, ""); err != nil {
+				return nil, fmt.Errorf("policy rule %q: bad pattern %q for argument %s", rc.Name, p, name)
+			}
+		}
+		if rc.Hours != "" {
+			start, end, err := parseHours(rc.Hours)
+			if err != nil {
+				return nil, fmt.Errorf("policy rule %q: %w", rc.Name, err)
+			}
+			r.start, r.end, r.hasHours = start, end, true
+		}
+		e.rules = append(e.rules, r)
+	}
+	if e.opa != nil {
+		timeout := opaTimeout
+		if e.opa.Timeout != "" {
+			d, err := time.ParseDuration(e.opa.Timeout)
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("policy.opa.timeout: invalid duration %q", e.opa.Timeout)
+			}
+			timeout = d
+		}
+		e.client = &http.Client{Timeout: timeout}
+	}
+	return e, nil
+}
+
+// Check returns a *DeniedError when in is not allowed, or nil.
+func (e *Engine) Check(ctx context.Context, in Input) error {
+	if e == nil {
+		return nil
+	}
+	if in.Time.IsZero() {
+		in.Time = time.Now()
+	}
+	in.Time = in.Time.In(e.location)
+
+	for _, r := range e.rules {
+		if !r.matches(in) {
+			continue
+		}
+		if r.Effect == config.PolicyDeny {
+			return &DeniedError{Rule: r.Name, Reason: r.Reason}
+		}
+		return e.checkOPA(ctx, in)
+	}
+	if e.deny {
+		return &DeniedError{Reason: "no rule allows it and the default is deny"}
+	}
+	return e.checkOPA(ctx, in)
+}
+
+func (r rule) matches(in Input) bool {
+	if len(r.Kinds) > 0 && !slices.Contains(r.Kinds, in.Kind) {
+		return false
+	}
+	if !matchAny(r.Agents, in.Agent) || !matchAny(r.Commands, in.Command) ||
+		!matchAny(r.Origins, in.Origin) || !matchAny(r.Users, in.User) {
+		return false
+	}
+	for name, pattern := range r.Args {
+		value, ok := in.Args[name]
+		if !ok {
+			return false
+		}
+		if matched, _ := path.Match(pattern, argString(value)); !matched {
+			return false
+		}
+	}
+	if len(r.Days) > 0 && !slices.Contains(r.Days, strings.ToLower(in.Time.Weekday().String()[:3])) {
+		return false
+	}
+	if r.hasHours {
+		minute := in.Time.Hour()*60 + in.Time.Minute()
+		if r.start <= r.end {
+			return minute >= r.start && minute < r.end
+		}
+		return minute >= r.start || minute < r.end
+	}
+	return true
+}
+
+// matchAny reports whether value matches one of patterns; no patterns match
+// everything.
+func matchAny(patterns []string, value string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, value); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// argString renders an argument for pattern matching: strings as they are,
+// anything else as JSON.
+func argString(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+// parseHours parses "HH:MM-HH:MM" into minutes after midnight.
+func parseHours(s string) (int, int, error) {
+	from, to, ok := strings.Cut(s, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("hours %q: expected HH:MM-HH:MM", s)
+	}
+	start, err := parseClock(from)
+	if err != nil {
+		return 0, 0, fmt.Errorf("hours %q: %w", s, err)
+	}
+	end, err := parseClock(to)
+	if err != nil {
+		return 0, 0, fmt.Errorf("hours %q: %w", s, err)
+	}
+	if start == end {
+		return 0, 0, fmt.Errorf("hours %q: start and end are the same", s)
+	}
+	return start, end, nil
+}
+
+func parseClock(s string) (int, error) {
+	s = strings.TrimSpace(s)
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		if s != "24:00" {
+			return 0, fmt.Errorf("invalid time %q", s)
+		}
+		return 24 * 60, nil
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// checkOPA puts the execution to the OPA server, denying when it says no or
+// cannot be asked.
+func (e *Engine) checkOPA(ctx context.Context, in Input) error {
+	if e.opa == nil {
+		return nil
+	}
+	body, err := json.Marshal(map[string]any{"input": opaInput(in)})
+	if err != nil {
+		return &DeniedError{Rule: "opa", Reason: err.Error()}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.opa.URL, bytes.NewReader(body))
+	if err != nil {
+		return &DeniedError{Rule: "opa", Reason: err.Error()}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.opa.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return &DeniedError{Rule: "opa", Reason: fmt.Sprintf("policy server unavailable: %v", err)}
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode != http.StatusOK {
+		return &DeniedError{Rule: "opa", Reason: fmt.Sprintf("policy server returned %s", resp.Status)}
+	}
+
+	var decision struct {
+		Result json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal(data, &decision); err != nil || len(decision.Result) == 0 {
+		return &DeniedError{Rule: "opa", Reason: "policy server returned no decision"}
+	}
+	var allow bool
+	if json.Unmarshal(decision.Result, &allow) == nil {
+		if allow {
+			return nil
+		}
+		return &DeniedError{Rule: "opa"}
+	}
+	var verdict struct {
+		Allow  bool   `json:"allow"`
+		Reason string `json:"reason"`
+	}
+	if err := json.Unmarshal(decision.Result, &verdict); err != nil {
+		return &DeniedError{Rule: "opa", Reason: "policy server returned an invalid decision"}
+	}
+	if verdict.Allow {
+		return nil
+	}
+	return &DeniedError{Rule: "opa", Reason: verdict.Reason}
+}
+
+// opaInput adds the local hour and weekday, which rego cannot derive from a
+// timestamp without knowing the daemon's timezone.
+func opaInput(in Input) map[string]any {
+	data, _ := json.Marshal(in)
+	var out map[string]any
+	_ = json.Unmarshal(data, &out)
+	out["hour"] = in.Time.Hour()
+	out["minute"] = in.Time.Minute()
+	out["weekday"] = strings.ToLower(in.Time.Weekday().String()[:3])
+	return out
+}
@@ -0,0 +1,232 @@
+package policy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"opperator/config"
+)
+
+// monday10 is a Monday at 10:00 UTC.
+var monday10 = time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+
+func TestEngine_Check(t *testing.T) {
+	cfg := config.PolicyConfig{
+		Timezone: "UTC",
+		Rules: []config.PolicyRule{
+			{Name: "no-prod-deploys", Effect: config.PolicyDeny, Commands: []string{"deploy"}, Args: map[string]string{"env": "prod*"}, Reason: "use the release pipeline"},
+			{Name: "office-hours", Effect: config.PolicyDeny, Kinds: []string{config.PolicyKindWorkflow}, Hours: "18:00-08:00"},
+			{Name: "weekends", Effect: config.PolicyDeny, Agents: []string{"billing-*"}, Days: []string{"sat", "sun"}},
+			{Name: "remote-users", Effect: config.PolicyDeny, Origins: []string{"remote"}, Users: []string{"guest*"}},
+			{Name: "ops", Effect: config.PolicyAllow, Users: []string{"ops"}},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		in     Input
+		denied string
+	}{
+		{
+			name:   "denied argument",
+			in:     Input{Kind: config.PolicyKindCommand, Agent: "deployer", Command: "deploy", Args: map[string]any{"env": "production"}, Time: monday10},
+			denied: "no-prod-deploys",
+		},
+		{
+			name: "other argument value",
+			in:   Input{Kind: config.PolicyKindCommand, Agent: "deployer", Command: "deploy", Args: map[string]any{"env": "staging"}, Time: monday10},
+		},
+		{
+			name: "missing argument does not match",
+			in:   Input{Kind: config.PolicyKindCommand, Agent: "deployer", Command: "deploy", Time: monday10},
+		},
+		{
+			name:   "outside hours wrapping midnight",
+			in:     Input{Kind: config.PolicyKindWorkflow, Command: "nightly", Time: time.Date(2026, 3, 2, 23, 30, 0, 0, time.UTC)},
+			denied: "office-hours",
+		},
+		{
+			name:   "early morning",
+			in:     Input{Kind: config.PolicyKindWorkflow, Command: "nightly", Time: time.Date(2026, 3, 2, 7, 59, 0, 0, time.UTC)},
+			denied: "office-hours",
+		},
+		{
+			name: "within hours",
+			in:   Input{Kind: config.PolicyKindWorkflow, Command: "nightly", Time: time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC)},
+		},
+		{
+			name: "other kind outside hours",
+			in:   Input{Kind: config.PolicyKindTool, Command: "nightly", Time: time.Date(2026, 3, 2, 23, 30, 0, 0, time.UTC)},
+		},
+		{
+			name:   "denied day",
+			in:     Input{Kind: config.PolicyKindCommand, Agent: "billing-eu", Command: "charge", Time: time.Date(2026, 3, 7, 12, 0, 0, 0, time.UTC)},
+			denied: "weekends",
+		},
+		{
+			name:   "denied origin and user",
+			in:     Input{Kind: config.PolicyKindCommand, Agent: "crawler", Command: "run", Origin: "remote", User: "guest1", Time: monday10},
+			denied: "remote-users",
+		},
+		{
+			name: "same user locally",
+			in:   Input{Kind: config.PolicyKindCommand, Agent: "crawler", Command: "run", Origin: "cli", User: "guest1", Time: monday10},
+		},
+		{
+			name: "first match decides",
+			in:   Input{Kind: config.PolicyKindCommand, Agent: "deployer", Command: "deploy", Args: map[string]any{"env": "prod"}, User: "ops", Time: monday10},
+			// no-prod-deploys comes before the ops allow rule
+			denied: "no-prod-deploys",
+		},
+	}
+
+	engine, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := engine.Check(context.Background(), tt.in)
+			if tt.denied == "" {
+				if err != nil {
+					t.Errorf("Expected allowed, got %v", err)
+				}
+				return
+			}
+			denied, ok := err.(*DeniedError)
+			if !ok {
+				t.Fatalf("Expected *DeniedError, got %v", err)
+			}
+			if denied.Rule != tt.denied {
+				t.Errorf("Expected rule %q, got %q", tt.denied, denied.Rule)
+			}
+			if !IsDenied(err) {
+				t.Errorf("Expected IsDenied to report %v", err)
+			}
+		})
+	}
+}
+
+func TestEngine_DefaultDeny(t *testing.T) {
+	engine, err := New(config.PolicyConfig{
+		Default: config.PolicyDeny,
+		Rules:   []config.PolicyRule{{Name: "reports", Effect: config.PolicyAllow, Commands: []string{"report_*"}}},
+	})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	tests := []struct {
+		command string
+		allowed bool
+	}{
+		{command: "report_daily", allowed: true},
+		{command: "delete_all", allowed: false},
+		{command: "", allowed: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			err := engine.Check(context.Background(), Input{Kind: config.PolicyKindCommand, Command: tt.command})
+			if (err == nil) != tt.allowed {
+				t.Errorf("Expected allowed=%v, got %v", tt.allowed, err)
+			}
+		})
+	}
+}
+
+func TestEngine_NilAllows(t *testing.T) {
+	engine, err := New(config.PolicyConfig{})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if engine != nil {
+		t.Fatalf("Expected no engine without a policy")
+	}
+	if err := engine.Check(context.Background(), Input{Command: "anything"}); err != nil {
+		t.Errorf("Expected a nil engine to allow everything, got %v", err)
+	}
+}
+
+func TestNew_Rejects(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.PolicyConfig
+	}{
+		{name: "bad timezone", cfg: config.PolicyConfig{Default: config.PolicyDeny, Timezone: "Mars/Olympus"}},
+		{name: "bad pattern", cfg: config.PolicyConfig{Rules: []config.PolicyRule{{Name: "r", Effect: config.PolicyDeny, Agents: []string{"["}}}}},
+		{name: "bad argument pattern", cfg: config.PolicyConfig{Rules: []config.PolicyRule{{Name: "r", Effect: config.PolicyDeny, Args: map[string]string{"env": "["}}}}},
+		{name: "hours without end", cfg: config.PolicyConfig{Rules: []config.PolicyRule{{Name: "r", Effect: config.PolicyDeny, Hours: "09:00"}}}},
+		{name: "invalid hour", cfg: config.PolicyConfig{Rules: []config.PolicyRule{{Name: "r", Effect: config.PolicyDeny, Hours: "09:00-25:00"}}}},
+		{name: "empty hours", cfg: config.PolicyConfig{Rules: []config.PolicyRule{{Name: "r", Effect: config.PolicyDeny, Hours: "09:00-09:00"}}}},
+		{name: "bad OPA timeout", cfg: config.PolicyConfig{OPA: &config.OPAConfig{URL: "http://localhost", Timeout: "soon"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.cfg); err == nil {
+				t.Errorf("Expected an error for %s", tt.name)
+			}
+		})
+	}
+}
+
+func TestEngine_OPA(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		allowed bool
+		reason  string
+	}{
+		{name: "boolean allow", status: http.StatusOK, body: `{"result": true}`, allowed: true},
+		{name: "boolean deny", status: http.StatusOK, body: `{"result": false}`},
+		{name: "object allow", status: http.StatusOK, body: `{"result": {"allow": true}}`, allowed: true},
+		{name: "object deny with reason", status: http.StatusOK, body: `{"result": {"allow": false, "reason": "frozen"}}`, reason: "frozen"},
+		{name: "no decision", status: http.StatusOK, body: `{}`, reason: "policy server returned no decision"},
+		{name: "invalid decision", status: http.StatusOK, body: `{"result": "yes"}`, reason: "policy server returned an invalid decision"},
+		{name: "server error", status: http.StatusInternalServerError, body: `{"result": true}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Authorization") != "Bearer secret" {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			engine, err := New(config.PolicyConfig{OPA: &config.OPAConfig{URL: srv.URL, Headers: map[string]string{"Authorization": "Bearer secret"}}})
+			if err != nil {
+				t.Fatalf("New failed: %v", err)
+			}
+			err = engine.Check(context.Background(), Input{Kind: config.PolicyKindCommand, Command: "run"})
+			if (err == nil) != tt.allowed {
+				t.Fatalf("Expected allowed=%v, got %v", tt.allowed, err)
+			}
+			if denied, ok := err.(*DeniedError); ok && tt.reason != "" && denied.Reason != tt.reason {
+				t.Errorf("Expected reason %q, got %q", tt.reason, denied.Reason)
+			}
+		})
+	}
+}
+
+func TestEngine_OPAUnavailable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+
+	engine, err := New(config.PolicyConfig{OPA: &config.OPAConfig{URL: url, Timeout: "500ms"}})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if err := engine.Check(context.Background(), Input{Command: "run"}); !IsDenied(err) {
+		t.Errorf("Expected an unreachable policy server to deny, got %v", err)
+	}
+}
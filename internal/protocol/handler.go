@@ -13,6 +13,8 @@ import (
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"opperator/pkg/tracing"
 )
 
 // MessageHandler processes incoming messages
@@ -47,6 +49,15 @@ type ProcessProtocol struct {
 	wg        sync.WaitGroup
 
 	rawOutputHandler func(line string)
+	// lineFilter rewrites every line read from the process before it is
+	// parsed, for example to scrub secrets.
+	lineFilter func(line string) string
+	// frameTap sees every message exchanged with the process as its JSON
+	// line, after lineFilter
+	frameTap func(outbound bool, msgType MessageType, raw string)
+
+	// cancelSupported is set once the process negotiated command cancellation
+	cancelSupported atomic.Bool
 }
 
 type pendingResponse struct {
@@ -84,6 +95,22 @@ func (p *ProcessProtocol) SetRawOutputHandler(handler func(line string)) {
 	p.rawOutputHandler = handler
 }
 
+// SetLineFilter sets a function applied to every stdout and stderr line
+// before it is parsed or passed to the raw output handler.
+func (p *ProcessProtocol) SetLineFilter(filter func(line string) string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.lineFilter = filter
+}
+
+// SetFrameTap sets a function called with every message read from or
+// written to the process, for tracing the protocol traffic.
+func (p *ProcessProtocol) SetFrameTap(tap func(outbound bool, msgType MessageType, raw string)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.frameTap = tap
+}
+
 // Start begins processing messages from stdout
 func (p *ProcessProtocol) Start() {
 	p.startOnce.Do(func() {
@@ -164,18 +191,41 @@ func (p *ProcessProtocol) handleStdoutLine(line []byte) {
 	if len(trimmed) == 0 {
 		return
 	}
+	trimmed = []byte(p.filter(string(trimmed)))
 
 	msg, err := ParseMessage(trimmed)
 	if err != nil {
 		p.dispatchRaw(string(trimmed))
 		return
 	}
+	p.tap(false, msg.Type, string(trimmed))
 
 	p.handleResponse(msg)
 	p.handleProgress(msg)
 	p.dispatchMessage(msg)
 }
 
+func (p *ProcessProtocol) filter(line string) string {
+	p.mu.RLock()
+	filter := p.lineFilter
+	p.mu.RUnlock()
+
+	if filter == nil {
+		return line
+	}
+	return filter(line)
+}
+
+func (p *ProcessProtocol) tap(outbound bool, msgType MessageType, raw string) {
+	p.mu.RLock()
+	tap := p.frameTap
+	p.mu.RUnlock()
+
+	if tap != nil {
+		tap(outbound, msgType, raw)
+	}
+}
+
 func (p *ProcessProtocol) dispatchRaw(line string) {
 	p.mu.RLock()
 	handler := p.rawOutputHandler
@@ -200,6 +250,7 @@ func (p *ProcessProtocol) SendMessage(msg *Message) error {
 	if _, err := p.stdin.Write(append(data, '\n')); err != nil {
 		return fmt.Errorf("failed to write message: %w", err)
 	}
+	p.tap(true, msg.Type, p.filter(string(data)))
 
 	return nil
 }
@@ -216,6 +267,28 @@ func (p *ProcessProtocol) SendLifecycleEvent(eventType string, data map[string]i
 	return p.SendMessage(msg)
 }
 
+// SendHandshake acknowledges an agent's ready message with the negotiated
+// protocol version and capabilities.
+func (p *ProcessProtocol) SendHandshake(caps Capabilities) error {
+	msg, err := NewMessage(MsgHandshake, HandshakeMessage{
+		ProtocolVersion: caps.ProtocolVersion,
+		Capabilities:    caps.List(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create handshake message: %w", err)
+	}
+	return p.SendMessage(msg)
+}
+
+// SendKVResponse answers a key-value request of the process.
+func (p *ProcessProtocol) SendKVResponse(resp KVResponseMessage) error {
+	msg, err := NewMessage(MsgKVResponse, resp)
+	if err != nil {
+		return fmt.Errorf("failed to create kv response message: %w", err)
+	}
+	return p.SendMessage(msg)
+}
+
 // SendCommand sends a command message and waits for the response or context cancellation.
 func (p *ProcessProtocol) SendCommand(ctx context.Context, command string, args map[string]interface{}, workingDir string) (*ResponseMessage, error) {
 	return p.sendCommand(ctx, command, args, strings.TrimSpace(workingDir), nil)
@@ -232,10 +305,11 @@ func (p *ProcessProtocol) sendCommand(ctx context.Context, command string, args
 	}
 
 	cmd := CommandMessage{
-		Command:    command,
-		Args:       args,
-		ID:         generateID(),
-		WorkingDir: workingDir,
+		Command:     command,
+		Args:        args,
+		ID:          generateID(),
+		WorkingDir:  workingDir,
+		TraceParent: tracing.TraceParent(ctx),
 	}
 
 	listener := &pendingResponse{ch: make(chan *ResponseMessage, 1), progress: progress}
@@ -260,10 +334,30 @@ func (p *ProcessProtocol) sendCommand(ctx context.Context, command string, args
 		return resp, nil
 	case <-ctx.Done():
 		p.clearPendingResponse(cmd.ID)
+		p.sendCancel(cmd.ID)
 		return nil, ctx.Err()
 	}
 }
 
+// SetCancellationSupported enables cancel messages for commands whose caller
+// gives up before the process responds.
+func (p *ProcessProtocol) SetCancellationSupported(supported bool) {
+	p.cancelSupported.Store(supported)
+}
+
+func (p *ProcessProtocol) sendCancel(commandID string) {
+	if !p.cancelSupported.Load() {
+		return
+	}
+	msg, err := NewMessage(MsgCancel, CancelMessage{CommandID: commandID})
+	if err != nil {
+		return
+	}
+	if err := p.SendMessage(msg); err != nil {
+		log.Printf("protocol cancel send error: %v", err)
+	}
+}
+
 func (p *ProcessProtocol) handleResponse(msg *Message) {
 	if msg.Type != MsgResponse {
 		return
@@ -330,7 +424,7 @@ func (p *ProcessProtocol) drainStderr() {
 		if len(line) > 0 {
 			trimmed := bytes.TrimRight(line, "\r\n")
 			if len(trimmed) > 0 {
-				p.dispatchRaw(string(trimmed))
+				p.dispatchRaw(p.filter(string(trimmed)))
 			}
 		}
 		if err != nil {
@@ -350,6 +444,7 @@ func generateID() string {
 // DefaultHandlers provides a set of default message handlers
 type DefaultHandlers struct {
 	OnReady           func(pid int, version string)
+	OnHandshake       func(caps Capabilities)
 	OnLog             func(level LogLevel, message string, fields map[string]interface{})
 	OnEvent           func(name string, data map[string]interface{})
 	OnLifecycleEvent  func(eventType string, data map[string]interface{})
@@ -361,17 +456,25 @@ type DefaultHandlers struct {
 	OnCommandProgress       func(progress CommandProgressMessage)
 	OnSidebarSection        func(section SidebarSectionMessage)
 	OnSidebarSectionRemoval func(sectionID string)
+	OnTriggerRegistry       func(triggers []TriggerDescriptor)
+	OnMetric                func(metric MetricMessage)
+	OnKVRequest             func(req KVRequestMessage)
 }
 
 // RegisterDefaults registers the default handlers
 func (p *ProcessProtocol) RegisterDefaults(handlers *DefaultHandlers) {
-	if handlers.OnReady != nil {
+	if handlers.OnReady != nil || handlers.OnHandshake != nil {
 		p.RegisterHandlerFunc(MsgReady, func(msg *Message) error {
 			var data ReadyMessage
 			if err := msg.ExtractData(&data); err != nil {
 				return err
 			}
-			handlers.OnReady(data.PID, data.Version)
+			if handlers.OnReady != nil {
+				handlers.OnReady(data.PID, data.Version)
+			}
+			if handlers.OnHandshake != nil {
+				handlers.OnHandshake(data.Negotiate())
+			}
 			return nil
 		})
 	}
@@ -486,6 +589,39 @@ func (p *ProcessProtocol) RegisterDefaults(handlers *DefaultHandlers) {
 		})
 	}
 
+	if handlers.OnTriggerRegistry != nil {
+		p.RegisterHandlerFunc(MsgTriggerRegistry, func(msg *Message) error {
+			var data TriggerRegistryMessage
+			if err := msg.ExtractData(&data); err != nil {
+				return err
+			}
+			handlers.OnTriggerRegistry(data.Triggers)
+			return nil
+		})
+	}
+
+	if handlers.OnMetric != nil {
+		p.RegisterHandlerFunc(MsgMetric, func(msg *Message) error {
+			var data MetricMessage
+			if err := msg.ExtractData(&data); err != nil {
+				return err
+			}
+			handlers.OnMetric(data)
+			return nil
+		})
+	}
+
+	if handlers.OnKVRequest != nil {
+		p.RegisterHandlerFunc(MsgKVRequest, func(msg *Message) error {
+			var data KVRequestMessage
+			if err := msg.ExtractData(&data); err != nil {
+				return err
+			}
+			handlers.OnKVRequest(data)
+			return nil
+		})
+	}
+
 	p.RegisterHandlerFunc(MsgCommandRegistry, func(msg *Message) error {
 		var data CommandRegistryMessage
 		if err := msg.ExtractData(&data); err != nil {
@@ -2,6 +2,7 @@ package protocol
 
 import (
 	"encoding/json"
+	"fmt"
 	"strings"
 	"time"
 	"unicode"
@@ -11,7 +12,8 @@ type MessageType string
 
 const (
 	// Lifecycle messages
-	MsgReady MessageType = "ready"
+	MsgReady     MessageType = "ready"
+	MsgHandshake MessageType = "handshake" // manager → process, acknowledges ready
 
 	// Logging messages
 	MsgLog MessageType = "log"
@@ -29,11 +31,23 @@ const (
 	MsgSystemPrompt     MessageType = "system_prompt"
 	MsgAgentDescription MessageType = "agent_description"
 	MsgCommandProgress  MessageType = "command_progress"
+	MsgCancel           MessageType = "cancel" // manager → process, abort an in-flight command
 
 	// Sidebar messages
 	MsgSidebarSection        MessageType = "sidebar_section"
 	MsgSidebarSectionRemoval MessageType = "sidebar_section_removal"
 
+	// Trigger messages
+	MsgTriggerRegistry MessageType = "trigger_registry"
+
+	// Metric messages
+	MsgMetric MessageType = "metric"
+
+	// Key-value messages; the process asks and the manager answers with the
+	// same ID
+	MsgKVRequest  MessageType = "kv_request"
+	MsgKVResponse MessageType = "kv_response"
+
 	// Error messages
 	MsgError MessageType = "error"
 )
@@ -55,10 +69,108 @@ type Message struct {
 	Data      json.RawMessage `json:"data,omitempty"`
 }
 
+// ProtocolVersion is the newest agent protocol revision the manager speaks.
+const ProtocolVersion = 1
+
+// Capability names an optional protocol feature an agent declares in its
+// ready message.
+type Capability string
+
+const (
+	CapabilityAsyncCommands   Capability = "async_commands"
+	CapabilityLifecycleEvents Capability = "lifecycle_events"
+	CapabilityCustomSections  Capability = "custom_sections"
+	CapabilityStructuredLogs  Capability = "structured_logs"
+	CapabilityCancellation    Capability = "cancellation"
+)
+
+// legacyCapabilities are assumed for agents whose ready message predates the
+// handshake and carries no protocol version.
+var legacyCapabilities = []Capability{
+	CapabilityAsyncCommands,
+	CapabilityLifecycleEvents,
+	CapabilityCustomSections,
+	CapabilityStructuredLogs,
+}
+
 // ReadyMessage sent when process is ready
 type ReadyMessage struct {
-	PID     int    `json:"pid"`
-	Version string `json:"version,omitempty"`
+	PID             int          `json:"pid"`
+	Version         string       `json:"version,omitempty"`
+	ProtocolVersion int          `json:"protocol_version,omitempty"`
+	Capabilities    []Capability `json:"capabilities,omitempty"`
+}
+
+// HandshakeMessage answers a ready message with the negotiated protocol
+// version and the capabilities the manager will use.
+type HandshakeMessage struct {
+	ProtocolVersion int          `json:"protocol_version"`
+	Capabilities    []Capability `json:"capabilities"`
+}
+
+// Capabilities is the negotiated feature set of a running agent.
+type Capabilities struct {
+	ProtocolVersion int    `json:"protocol_version"`
+	SDKVersion      string `json:"sdk_version,omitempty"`
+	AsyncCommands   bool   `json:"async_commands"`
+	LifecycleEvents bool   `json:"lifecycle_events"`
+	CustomSections  bool   `json:"custom_sections"`
+	StructuredLogs  bool   `json:"structured_logs"`
+	Cancellation    bool   `json:"cancellation"`
+}
+
+// Negotiate resolves the protocol version and capabilities both sides
+// support. Unknown capabilities are ignored.
+func (r ReadyMessage) Negotiate() Capabilities {
+	caps := Capabilities{
+		ProtocolVersion: r.ProtocolVersion,
+		SDKVersion:      r.Version,
+	}
+	if caps.ProtocolVersion > ProtocolVersion {
+		caps.ProtocolVersion = ProtocolVersion
+	}
+
+	declared := r.Capabilities
+	if r.ProtocolVersion <= 0 {
+		caps.ProtocolVersion = 0
+		declared = legacyCapabilities
+	}
+	for _, c := range declared {
+		switch Capability(strings.TrimSpace(string(c))) {
+		case CapabilityAsyncCommands:
+			caps.AsyncCommands = true
+		case CapabilityLifecycleEvents:
+			caps.LifecycleEvents = true
+		case CapabilityCustomSections:
+			caps.CustomSections = true
+		case CapabilityStructuredLogs:
+			caps.StructuredLogs = true
+		case CapabilityCancellation:
+			caps.Cancellation = true
+		}
+	}
+	return caps
+}
+
+// List returns the enabled capabilities in declaration order.
+func (c Capabilities) List() []Capability {
+	var out []Capability
+	if c.AsyncCommands {
+		out = append(out, CapabilityAsyncCommands)
+	}
+	if c.LifecycleEvents {
+		out = append(out, CapabilityLifecycleEvents)
+	}
+	if c.CustomSections {
+		out = append(out, CapabilityCustomSections)
+	}
+	if c.StructuredLogs {
+		out = append(out, CapabilityStructuredLogs)
+	}
+	if c.Cancellation {
+		out = append(out, CapabilityCancellation)
+	}
+	return out
 }
 
 type LogMessage struct {
@@ -83,14 +195,32 @@ type CommandMessage struct {
 	Args       map[string]interface{} `json:"args,omitempty"`
 	ID         string                 `json:"id,omitempty"`
 	WorkingDir string                 `json:"working_dir,omitempty"`
+	// TraceParent is the W3C trace context of the span invoking the command.
+	TraceParent string `json:"traceparent,omitempty"`
+}
+
+// CancelMessage asks the process to abort the command with the given ID
+// because the caller gave up waiting for it.
+type CancelMessage struct {
+	CommandID string `json:"command_id"`
 }
 
 // ResponseMessage sent in response to commands
 type ResponseMessage struct {
-	CommandID string      `json:"command_id,omitempty"`
-	Success   bool        `json:"success"`
-	Result    interface{} `json:"result,omitempty"`
-	Error     string      `json:"error,omitempty"`
+	CommandID string       `json:"command_id,omitempty"`
+	Success   bool         `json:"success"`
+	Result    interface{}  `json:"result,omitempty"`
+	Error     string       `json:"error,omitempty"`
+	Files     []FileOutput `json:"files,omitempty"`
+}
+
+// FileOutput is a file a command wrote as part of its output, such as a
+// generated report or image. The daemon keeps a copy for clients to
+// download.
+type FileOutput struct {
+	Path     string `json:"path"`
+	Name     string `json:"name,omitempty"`
+	MimeType string `json:"mime_type,omitempty"`
 }
 
 // SystemPromptMessage allows an agent to publish its system prompt to the
@@ -106,11 +236,47 @@ type AgentDescriptionMessage struct {
 }
 
 // SidebarSectionMessage allows an agent to register/update custom sidebar sections.
+// A section of type "dashboard" is not shown in the sidebar; the daemon serves
+// its markdown or HTML content as a web page instead.
 type SidebarSectionMessage struct {
-	SectionID string `json:"section_id"`
-	Title     string `json:"title"`
-	Content   string `json:"content"`
-	Collapsed bool   `json:"collapsed"`
+	SectionID string          `json:"section_id"`
+	Title     string          `json:"title"`
+	Content   string          `json:"content"`
+	Collapsed bool            `json:"collapsed"`
+	Actions   []SidebarAction `json:"actions,omitempty"`
+	Type      string          `json:"type,omitempty"`
+	Format    string          `json:"format,omitempty"`
+}
+
+// Section types and dashboard formats.
+const (
+	SidebarSectionDashboard = "dashboard"
+
+	DashboardFormatMarkdown = "markdown"
+	DashboardFormatHTML     = "html"
+)
+
+// SidebarActionType is the kind of element a sidebar action renders as.
+type SidebarActionType string
+
+const (
+	// SidebarActionButton invokes its command with fixed arguments.
+	SidebarActionButton SidebarActionType = "button"
+	// SidebarActionInput asks for a line of text and passes it to its
+	// command as the argument named by Arg.
+	SidebarActionInput SidebarActionType = "input"
+)
+
+// SidebarAction is an interactive element of a custom sidebar section. Using
+// it invokes one of the agent's commands.
+type SidebarAction struct {
+	ID          string                 `json:"id"`
+	Label       string                 `json:"label"`
+	Type        SidebarActionType      `json:"type,omitempty"`
+	Command     string                 `json:"command"`
+	Args        map[string]interface{} `json:"args,omitempty"`
+	Arg         string                 `json:"arg,omitempty"`
+	Placeholder string                 `json:"placeholder,omitempty"`
 }
 
 // SidebarSectionRemovalMessage allows an agent to remove a custom sidebar section.
@@ -118,6 +284,61 @@ type SidebarSectionRemovalMessage struct {
 	SectionID string `json:"section_id"`
 }
 
+// Metric types.
+const (
+	MetricCounter = "counter"
+	MetricGauge   = "gauge"
+)
+
+// MetricMessage reports a named metric of the agent, such as items_processed
+// or api_errors. A counter adds Value to its total, 1 when Value is zero; a
+// gauge is set to Value. Type defaults to counter.
+type MetricMessage struct {
+	Name  string  `json:"name"`
+	Type  string  `json:"type,omitempty"`
+	Value float64 `json:"value,omitempty"`
+}
+
+// Key-value operations.
+const (
+	KVGet    = "get"
+	KVSet    = "set"
+	KVDelete = "delete"
+	KVList   = "list"
+)
+
+// KVRequestMessage reads or writes the agent's own key-value state, or the
+// blackboard Namespace when it is set. The manager knows which agent sent it
+// from the pipe it arrived on.
+type KVRequestMessage struct {
+	ID        string          `json:"id"`
+	Op        string          `json:"op"`
+	Namespace string          `json:"namespace,omitempty"`
+	Key       string          `json:"key,omitempty"`
+	Value     json.RawMessage `json:"value,omitempty"`
+	Prefix    string          `json:"prefix,omitempty"`
+}
+
+// KVResponseMessage answers the KVRequestMessage with the same ID. ErrorCode
+// is "not_found" for a missing key and "forbidden" for a blackboard the
+// agent may not use.
+type KVResponseMessage struct {
+	ID        string    `json:"id"`
+	Success   bool      `json:"success"`
+	Entries   []KVEntry `json:"entries,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	ErrorCode string    `json:"error_code,omitempty"`
+}
+
+// KVEntry is a key and its value. Writer is the agent that last wrote a
+// blackboard key.
+type KVEntry struct {
+	Key       string          `json:"key"`
+	Value     json.RawMessage `json:"value"`
+	Writer    string          `json:"writer,omitempty"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
 // CommandExposure indicates how a command should be exposed to users.
 type CommandExposure string
 
@@ -139,6 +360,9 @@ type CommandDescriptor struct {
 	Async            bool              `json:"async,omitempty"`
 	ProgressLabel    string            `json:"progress_label,omitempty"`
 	Hidden           bool              `json:"hidden,omitempty"`
+	// WorkingDir is the directory the command runs in unless the user picks
+	// one; relative paths are resolved against the agent's process root.
+	WorkingDir string `json:"working_dir,omitempty"`
 }
 
 // CommandProgressMessage emits incremental updates for a long-running command.
@@ -159,6 +383,13 @@ type CommandArgument struct {
 	Enum        []interface{}          `json:"enum,omitempty"`
 	Items       map[string]interface{} `json:"items,omitempty"`      // Schema for array items
 	Properties  map[string]interface{} `json:"properties,omitempty"` // Schema for object properties
+	Minimum     *float64               `json:"minimum,omitempty"`
+	Maximum     *float64               `json:"maximum,omitempty"`
+	MinLength   *int                   `json:"min_length,omitempty"`
+	MaxLength   *int                   `json:"max_length,omitempty"`
+	MinItems    *int                   `json:"min_items,omitempty"`
+	MaxItems    *int                   `json:"max_items,omitempty"`
+	Pattern     string                 `json:"pattern,omitempty"` // Regular expression string values must match
 }
 
 // CommandRegistryMessage announces available commands from the agent
@@ -166,6 +397,62 @@ type CommandRegistryMessage struct {
 	Commands []CommandDescriptor `json:"commands"`
 }
 
+// Trigger types understood by the daemon.
+const (
+	TriggerFile  = "file"
+	TriggerPoll  = "poll"
+	TriggerRSS   = "rss"
+	TriggerIMAP  = "imap"
+	TriggerSQS   = "sqs"
+	TriggerRedis = "redis"
+)
+
+// TriggerDescriptor declares an event source that invokes Command with the
+// event as its "event" argument. Which fields apply depends on Type.
+type TriggerDescriptor struct {
+	Name    string                 `json:"name" yaml:"name"`
+	Type    string                 `json:"type" yaml:"type"`
+	Command string                 `json:"command" yaml:"command"`
+	Args    map[string]interface{} `json:"args,omitempty" yaml:"args,omitempty"`
+	// Interval between polls for poll, rss, imap and sqs triggers, as a Go
+	// duration. Defaults to one minute.
+	Interval string `json:"interval,omitempty" yaml:"interval,omitempty"`
+
+	// file: Path is watched (a directory or a single file); Pattern filters
+	// base names with a glob; Events limits to create, write, remove and
+	// rename; Debounce coalesces bursts for the same path.
+	Path     string   `json:"path,omitempty" yaml:"path,omitempty"`
+	Pattern  string   `json:"pattern,omitempty" yaml:"pattern,omitempty"`
+	Events   []string `json:"events,omitempty" yaml:"events,omitempty"`
+	Debounce string   `json:"debounce,omitempty" yaml:"debounce,omitempty"`
+
+	// poll and rss: URL is fetched with Headers. Poll fires when the body
+	// changes; rss fires once per new feed item.
+	URL     string            `json:"url,omitempty" yaml:"url,omitempty"`
+	Headers map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+
+	// imap, redis: Address is host:port. IMAP uses TLS and fires per unseen
+	// message in Mailbox (default INBOX), marking it seen.
+	Address  string `json:"address,omitempty" yaml:"address,omitempty"`
+	Username string `json:"username,omitempty" yaml:"username,omitempty"`
+	Password string `json:"password,omitempty" yaml:"password,omitempty"`
+	Mailbox  string `json:"mailbox,omitempty" yaml:"mailbox,omitempty"`
+
+	// sqs: messages are long-polled from QueueURL in Region using the AWS_*
+	// credentials of the daemon and deleted once queued.
+	QueueURL string `json:"queue_url,omitempty" yaml:"queue_url,omitempty"`
+	Region   string `json:"region,omitempty" yaml:"region,omitempty"`
+
+	// redis: values are popped from the list Key.
+	Key string `json:"key,omitempty" yaml:"key,omitempty"`
+	DB  int    `json:"db,omitempty" yaml:"db,omitempty"`
+}
+
+// TriggerRegistryMessage replaces the triggers registered by an agent.
+type TriggerRegistryMessage struct {
+	Triggers []TriggerDescriptor `json:"triggers"`
+}
+
 type ErrorMessage struct {
 	Error   string `json:"error"`
 	Code    int    `json:"code,omitempty"`
@@ -326,6 +613,66 @@ func normalizeSlashScope(scope string) string {
 	return "local"
 }
 
+// NormalizeSidebarActions trims the actions of a sidebar section, dropping
+// those without a command and inputs without an argument to fill. IDs
+// default to the command name, numbered when several actions share it, and
+// actions repeating an ID are dropped.
+func NormalizeSidebarActions(actions []SidebarAction) []SidebarAction {
+	if len(actions) == 0 {
+		return nil
+	}
+
+	seen := make(map[string]struct{})
+	normalized := make([]SidebarAction, 0, len(actions))
+	for _, action := range actions {
+		action.Command = strings.TrimSpace(action.Command)
+		if action.Command == "" {
+			continue
+		}
+
+		action.Type = SidebarActionType(strings.ToLower(strings.TrimSpace(string(action.Type))))
+		switch action.Type {
+		case "":
+			action.Type = SidebarActionButton
+		case SidebarActionButton, SidebarActionInput:
+		default:
+			continue
+		}
+
+		action.Arg = strings.TrimSpace(action.Arg)
+		if action.Type == SidebarActionInput && action.Arg == "" {
+			continue
+		}
+
+		action.ID = strings.TrimSpace(action.ID)
+		if action.ID == "" {
+			action.ID = action.Command
+			for n := 2; ; n++ {
+				if _, taken := seen[action.ID]; !taken {
+					break
+				}
+				action.ID = fmt.Sprintf("%s_%d", action.Command, n)
+			}
+		}
+		if _, duplicate := seen[action.ID]; duplicate {
+			continue
+		}
+		seen[action.ID] = struct{}{}
+
+		action.Label = strings.TrimSpace(action.Label)
+		if action.Label == "" {
+			action.Label = action.Command
+		}
+		action.Placeholder = strings.TrimSpace(action.Placeholder)
+		normalized = append(normalized, action)
+	}
+
+	if len(normalized) == 0 {
+		return nil
+	}
+	return normalized
+}
+
 func normalizeCommandArguments(args []CommandArgument) []CommandArgument {
 	if len(args) == 0 {
 		return nil
@@ -381,6 +728,13 @@ func normalizeCommandArguments(args []CommandArgument) []CommandArgument {
 			Enum:        enumValues,
 			Items:       arg.Items,
 			Properties:  arg.Properties,
+			Minimum:     arg.Minimum,
+			Maximum:     arg.Maximum,
+			MinLength:   arg.MinLength,
+			MaxLength:   arg.MaxLength,
+			MinItems:    arg.MinItems,
+			MaxItems:    arg.MaxItems,
+			Pattern:     validPattern(arg.Pattern),
 		})
 		seen[name] = struct{}{}
 	}
@@ -0,0 +1,70 @@
+// Package share renders conversations for sharing outside opperator, with
+// secrets scrubbed, and uploads them to a paste service or S3 bucket.
+package share
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Redacted replaces scrubbed text.
+const Redacted = "[REDACTED]"
+
+// minSecretLength keeps short stored values such as "yes" from being
+// scrubbed everywhere they appear.
+const minSecretLength = 6
+
+// tokenPatterns match credentials with a recognisable shape.
+var tokenPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`),
+	regexp.MustCompile(`\bsk-[A-Za-z0-9_-]{20,}`),
+	regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`),
+	regexp.MustCompile(`\bxapp-[A-Za-z0-9-]{10,}`),
+	regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{30,}`),
+	regexp.MustCompile(`\bgithub_pat_[A-Za-z0-9_]{22,}`),
+	regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`),
+	regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}`),
+	regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`),
+}
+
+// Patterns whose first groups are kept and whose value is scrubbed.
+var (
+	bearerPattern     = regexp.MustCompile(`(?i)(\bbearer\s+)[A-Za-z0-9._~+/=-]{12,}`)
+	assignmentPattern = regexp.MustCompile(`(?i)(\b[a-z_-]*(?:password|passwd|secret|token|api[_-]?key)[a-z_-]*\b["']?\s*[:=]\s*["']?)[^\s"',;]{6,}`)
+	urlUserinfo       = regexp.MustCompile(`(://[^/\s:@]+:)[^/\s@]+@`)
+)
+
+// Redactor scrubs secrets from text: the given literal values, such as the
+// stored secrets, and anything shaped like a credential.
+type Redactor struct {
+	literals []string
+}
+
+// NewRedactor scrubs values in addition to credential-shaped text.
+func NewRedactor(values []string) *Redactor {
+	r := &Redactor{}
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if len(v) >= minSecretLength {
+			r.literals = append(r.literals, v)
+		}
+	}
+	// Longer values first so a secret containing another is fully removed.
+	sort.Slice(r.literals, func(i, j int) bool { return len(r.literals[i]) > len(r.literals[j]) })
+	return r
+}
+
+// Redact returns text with secrets replaced by Redacted.
+func (r *Redactor) Redact(text string) string {
+	for _, v := range r.literals {
+		text = strings.ReplaceAll(text, v, Redacted)
+	}
+	for _, p := range tokenPatterns {
+		text = p.ReplaceAllString(text, Redacted)
+	}
+	text = bearerPattern.ReplaceAllString(text, "${1}"+Redacted)
+	text = assignmentPattern.ReplaceAllString(text, "${1}"+Redacted)
+	text = urlUserinfo.ReplaceAllString(text, "${1}"+Redacted+"@")
+	return text
+}
@@ -0,0 +1,180 @@
+package share
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Formats a conversation can be rendered in.
+const (
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+)
+
+// maxToolOutput caps tool arguments and output in a rendering.
+const maxToolOutput = 4000
+
+// Conversation is a conversation prepared for sharing.
+type Conversation struct {
+	Title    string
+	Agent    string
+	Created  time.Time
+	Messages []Message
+}
+
+// Message is one entry of a shared conversation. Tool calls carry ToolName
+// and ToolInput; tool results carry ToolName and Text.
+type Message struct {
+	Role      string
+	Text      string
+	ToolName  string
+	ToolInput string
+}
+
+// Options controls what a rendering contains.
+type Options struct {
+	Format string
+	// StripTools leaves out tool arguments and results, keeping only which
+	// tools were used.
+	StripTools bool
+}
+
+// Render formats conv, redacting every piece of text with r.
+func Render(conv Conversation, r *Redactor, opts Options) (string, error) {
+	switch opts.Format {
+	case "", FormatMarkdown:
+		return renderMarkdown(conv, r, opts), nil
+	case FormatHTML:
+		return renderHTML(conv, r, opts), nil
+	}
+	return "", fmt.Errorf("unknown format %q (expected markdown or html)", opts.Format)
+}
+
+// ContentType returns the MIME type of a format.
+func ContentType(format string) string {
+	if format == FormatHTML {
+		return "text/html; charset=utf-8"
+	}
+	return "text/markdown; charset=utf-8"
+}
+
+// Extension returns the file extension of a format.
+func Extension(format string) string {
+	if format == FormatHTML {
+		return ".html"
+	}
+	return ".md"
+}
+
+func subtitle(conv Conversation) string {
+	parts := []string{"Shared from Opperator"}
+	if conv.Agent != "" {
+		parts = append(parts, "agent "+conv.Agent)
+	}
+	if !conv.Created.IsZero() {
+		parts = append(parts, conv.Created.Format("2006-01-02"))
+	}
+	return strings.Join(parts, " · ")
+}
+
+func speaker(role string) string {
+	if role == "user" {
+		return "User"
+	}
+	return "Assistant"
+}
+
+func renderMarkdown(conv Conversation, r *Redactor, opts Options) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n_%s_\n", r.Redact(conv.Title), subtitle(conv))
+	for _, m := range conv.Messages {
+		switch m.Role {
+		case "user", "assistant":
+			fmt.Fprintf(&b, "\n**%s**\n\n%s\n", speaker(m.Role), r.Redact(m.Text))
+		case "tool_call":
+			if opts.StripTools {
+				fmt.Fprintf(&b, "\n_Used tool `%s`_\n", m.ToolName)
+				continue
+			}
+			fmt.Fprintf(&b, "\n<details><summary>Tool call: %s</summary>\n\n%s</details>\n", m.ToolName, fence(r.Redact(m.ToolInput), "json"))
+		case "tool_call_response":
+			if opts.StripTools {
+				continue
+			}
+			fmt.Fprintf(&b, "\n<details><summary>Tool result: %s</summary>\n\n%s</details>\n", m.ToolName, fence(r.Redact(m.Text), ""))
+		}
+	}
+	return b.String()
+}
+
+// fence wraps text in a code block longer than any backtick run inside it.
+func fence(text, lang string) string {
+	text = truncate(text)
+	marker := "```"
+	for strings.Contains(text, marker) {
+		marker += "`"
+	}
+	return marker + lang + "\n" + text + "\n" + marker + "\n"
+}
+
+func renderHTML(conv Conversation, r *Redactor, opts Options) string {
+	var b strings.Builder
+	title := html.EscapeString(r.Redact(conv.Title))
+	fmt.Fprintf(&b, `<!doctype html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>%s</title>
+<style>
+body { max-width: 80ch; margin: 2em auto; padding: 0 1em; font: 15px/1.55 system-ui, sans-serif; color: #222; }
+.meta { color: #777; }
+.msg { margin: 1.2em 0; }
+.msg b { display: block; margin-bottom: .3em; }
+.text, pre { white-space: pre-wrap; word-wrap: break-word; }
+pre { background: #f4f4f4; padding: .6em; border-radius: 4px; font-size: 13px; }
+details { margin: .6em 0; color: #555; }
+</style>
+</head>
+<body>
+<h1>%s</h1>
+<p class="meta">%s</p>
+`, title, title, html.EscapeString(subtitle(conv)))
+	for _, m := range conv.Messages {
+		name := html.EscapeString(m.ToolName)
+		switch m.Role {
+		case "user", "assistant":
+			fmt.Fprintf(&b, "<div class=\"msg %s\"><b>%s</b><div class=\"text\">%s</div></div>\n",
+				m.Role, speaker(m.Role), html.EscapeString(r.Redact(m.Text)))
+		case "tool_call":
+			if opts.StripTools {
+				fmt.Fprintf(&b, "<p class=\"meta\">Used tool <code>%s</code></p>\n", name)
+				continue
+			}
+			fmt.Fprintf(&b, "<details><summary>Tool call: %s</summary><pre>%s</pre></details>\n",
+				name, html.EscapeString(truncate(r.Redact(m.ToolInput))))
+		case "tool_call_response":
+			if opts.StripTools {
+				continue
+			}
+			fmt.Fprintf(&b, "<details><summary>Tool result: %s</summary><pre>%s</pre></details>\n",
+				name, html.EscapeString(truncate(r.Redact(m.Text))))
+		}
+	}
+	b.WriteString("</body>\n</html>\n")
+	return b.String()
+}
+
+func truncate(text string) string {
+	if len(text) <= maxToolOutput {
+		return text
+	}
+	cut := maxToolOutput
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return text[:cut] + "\n…(truncated)"
+}
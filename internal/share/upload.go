@@ -0,0 +1,138 @@
+package share
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"opperator/config"
+	"opperator/internal/awssig"
+)
+
+var httpClient = &http.Client{Timeout: time.Minute}
+
+// Upload stores content at the configured target under a name derived from
+// name and returns the link to share.
+func Upload(ctx context.Context, cfg *config.ShareConfig, name, contentType string, content []byte) (string, error) {
+	switch cfg.Target {
+	case config.ShareTargetS3:
+		return uploadS3(ctx, cfg.S3, name, contentType, content)
+	case config.ShareTargetPaste:
+		return uploadPaste(ctx, cfg.Paste, name, contentType, content)
+	}
+	return "", fmt.Errorf("unknown share target %q", cfg.Target)
+}
+
+// uploadS3 PUTs the object under a random suffix so links cannot be guessed
+// from the conversation ID.
+func uploadS3(ctx context.Context, cfg *config.ShareS3, name, contentType string, content []byte) (string, error) {
+	if err := awssig.CheckEnv(); err != nil {
+		return "", fmt.Errorf("%w to upload to s3", err)
+	}
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+	dot := strings.LastIndex(name, ".")
+	if dot < 0 {
+		dot = len(name)
+	}
+	key := strings.TrimPrefix(cfg.Prefix, "/") + name[:dot] + "-" + hex.EncodeToString(suffix) + name[dot:]
+
+	var objectURL string
+	if cfg.Endpoint != "" {
+		objectURL = strings.TrimRight(cfg.Endpoint, "/") + "/" + cfg.Bucket + "/" + key
+	} else {
+		objectURL = fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", cfg.Bucket, cfg.Region, key)
+	}
+	u, err := url.Parse(objectURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid s3 object URL %q: %w", objectURL, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u.String(), bytes.NewReader(content))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", contentType)
+	awssig.Sign(req, content, "s3", cfg.Region, time.Now())
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return "", fmt.Errorf("s3 upload failed: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	if cfg.PublicURL != "" {
+		return strings.TrimRight(cfg.PublicURL, "/") + "/" + key, nil
+	}
+	return u.String(), nil
+}
+
+func uploadPaste(ctx context.Context, cfg *config.SharePaste, name, contentType string, content []byte) (string, error) {
+	body := bytes.NewReader(content)
+	var payload io.Reader = body
+	if cfg.Field != "" {
+		var buf bytes.Buffer
+		form := multipart.NewWriter(&buf)
+		part, err := form.CreateFormFile(cfg.Field, name)
+		if err != nil {
+			return "", err
+		}
+		part.Write(content)
+		if err := form.Close(); err != nil {
+			return "", err
+		}
+		payload = &buf
+		contentType = form.FormDataContentType()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.URL, payload)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", contentType)
+	for k, v := range cfg.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("paste upload failed: %s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+	if link := resp.Header.Get("Location"); link != "" {
+		return link, nil
+	}
+	var result struct {
+		URL  string `json:"url"`
+		Link string `json:"link"`
+	}
+	if json.Unmarshal(data, &result) == nil {
+		if result.URL != "" {
+			return result.URL, nil
+		}
+		if result.Link != "" {
+			return result.Link, nil
+		}
+	}
+	if text := strings.TrimSpace(string(data)); strings.HasPrefix(text, "http://") || strings.HasPrefix(text, "https://") {
+		return text, nil
+	}
+	return "", fmt.Errorf("paste service returned no link")
+}
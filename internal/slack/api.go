@@ -0,0 +1,127 @@
+package slack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const apiBase = "https://slack.com/api/"
+
+// maxTextLength keeps posted text under Slack's message limit.
+const maxTextLength = 39000
+
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// apiResponse carries the fields every Web API response shares.
+type apiResponse struct {
+	OK    bool   `json:"ok"`
+	Error string `json:"error"`
+}
+
+func (r apiResponse) err(method string) error {
+	if r.OK {
+		return nil
+	}
+	return fmt.Errorf("slack %s: %s", method, r.Error)
+}
+
+// call POSTs payload as JSON to a Web API method authenticated with token and
+// decodes the response into out.
+func call(ctx context.Context, token, method string, payload any, out any) error {
+	var body bytes.Buffer
+	if payload != nil {
+		if err := json.NewEncoder(&body).Encode(payload); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiBase+method, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return fmt.Errorf("slack %s: rate limited (retry after %ss)", method, resp.Header.Get("Retry-After"))
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("slack %s: %s", method, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// openConnection returns a Socket Mode WebSocket URL.
+func openConnection(ctx context.Context, appToken string) (string, error) {
+	var resp struct {
+		apiResponse
+		URL string `json:"url"`
+	}
+	if err := call(ctx, appToken, "apps.connections.open", nil, &resp); err != nil {
+		return "", err
+	}
+	if err := resp.err("apps.connections.open"); err != nil {
+		return "", err
+	}
+	return resp.URL, nil
+}
+
+// authTest returns the bot's own user ID.
+func authTest(ctx context.Context, botToken string) (string, error) {
+	var resp struct {
+		apiResponse
+		UserID string `json:"user_id"`
+	}
+	if err := call(ctx, botToken, "auth.test", nil, &resp); err != nil {
+		return "", err
+	}
+	if err := resp.err("auth.test"); err != nil {
+		return "", err
+	}
+	return resp.UserID, nil
+}
+
+// postMessage posts text to channel, in threadTS when set, and returns the
+// new message's timestamp.
+func postMessage(ctx context.Context, botToken, channel, threadTS, text string) (string, error) {
+	payload := map[string]any{"channel": channel, "text": truncate(text)}
+	if threadTS != "" {
+		payload["thread_ts"] = threadTS
+	}
+	var resp struct {
+		apiResponse
+		TS string `json:"ts"`
+	}
+	if err := call(ctx, botToken, "chat.postMessage", payload, &resp); err != nil {
+		return "", err
+	}
+	if err := resp.err("chat.postMessage"); err != nil {
+		return "", err
+	}
+	return resp.TS, nil
+}
+
+// updateMessage replaces the text of a message the bot posted.
+func updateMessage(ctx context.Context, botToken, channel, ts, text string) error {
+	var resp apiResponse
+	payload := map[string]any{"channel": channel, "ts": ts, "text": truncate(text)}
+	if err := call(ctx, botToken, "chat.update", payload, &resp); err != nil {
+		return err
+	}
+	return resp.err("chat.update")
+}
+
+func truncate(text string) string {
+	if len(text) <= maxTextLength {
+		return text
+	}
+	return strings.ToValidUTF8(text[:maxTextLength], "") + "\n…(truncated)"
+}
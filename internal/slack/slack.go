@@ -0,0 +1,299 @@
+// Package slack is a chat channel for a Slack app connected over Socket
+// Mode. Direct messages, mentions and replies in threads the bot is part of
+// are delivered to the agent; progress is shown by editing a status message
+// in the thread and the answer is posted as a reply.
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"opperator/config"
+	"opperator/internal/channel"
+)
+
+const (
+	// progressInterval throttles status message edits to stay within
+	// chat.update rate limits.
+	progressInterval = 2 * time.Second
+	// progressLines is how many recent progress lines the status shows.
+	progressLines = 6
+	// maxSeenEvents bounds the messages remembered to drop redeliveries.
+	maxSeenEvents = 500
+)
+
+// Channel is a Slack app as a chat channel.
+type Channel struct {
+	cfg config.SlackConfig
+
+	mu        sync.Mutex
+	botUserID string
+	seen      map[string]bool
+	order     []string
+	// active holds threads the bot has replied in since it started, so
+	// follow-ups there need no mention.
+	active map[string]bool
+}
+
+// reply locates where a message is answered.
+type reply struct {
+	channel  string
+	threadTS string
+}
+
+// envelope is a Socket Mode frame.
+type envelope struct {
+	Type       string          `json:"type"`
+	EnvelopeID string          `json:"envelope_id"`
+	Payload    json.RawMessage `json:"payload"`
+}
+
+type eventPayload struct {
+	Event message `json:"event"`
+}
+
+type message struct {
+	Type        string `json:"type"`
+	Subtype     string `json:"subtype"`
+	Channel     string `json:"channel"`
+	ChannelType string `json:"channel_type"`
+	User        string `json:"user"`
+	BotID       string `json:"bot_id"`
+	Text        string `json:"text"`
+	TS          string `json:"ts"`
+	ThreadTS    string `json:"thread_ts"`
+}
+
+var mentionPattern = regexp.MustCompile(`<@[A-Z0-9]+>`)
+
+func New(cfg config.SlackConfig) *Channel {
+	return &Channel{cfg: cfg, seen: make(map[string]bool), active: make(map[string]bool)}
+}
+
+// Receive serves one Socket Mode connection until Slack asks to reconnect,
+// it fails, or ctx is cancelled.
+func (c *Channel) Receive(ctx context.Context, deliver func(context.Context, channel.Message) error) error {
+	if c.botUserID == "" {
+		id, err := authTest(ctx, c.cfg.BotToken)
+		if err != nil {
+			return err
+		}
+		c.botUserID = id
+	}
+	url, err := openConnection(ctx, c.cfg.AppToken)
+	if err != nil {
+		return err
+	}
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, url, nil)
+	if err != nil {
+		return fmt.Errorf("dial socket mode: %w", err)
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	// Messages are answered in the background so the socket keeps being
+	// read; wait for them before returning.
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		var env envelope
+		if err := conn.ReadJSON(&env); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		switch env.Type {
+		case "hello":
+			log.Printf("[Slack] Connected to Socket Mode")
+		case "disconnect":
+			return nil
+		case "events_api":
+			// Acknowledge at once; Slack redelivers events not acked
+			// within three seconds.
+			if err := conn.WriteJSON(map[string]string{"envelope_id": env.EnvelopeID}); err != nil {
+				return err
+			}
+			var payload eventPayload
+			if err := json.Unmarshal(env.Payload, &payload); err != nil {
+				continue
+			}
+			msg, ok := c.accept(payload.Event)
+			if !ok {
+				continue
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				deliver(ctx, msg)
+			}()
+		default:
+			if env.EnvelopeID != "" {
+				if err := conn.WriteJSON(map[string]string{"envelope_id": env.EnvelopeID}); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// accept decides whether an event is meant for an agent.
+func (c *Channel) accept(msg message) (channel.Message, bool) {
+	if msg.Subtype != "" || msg.BotID != "" || msg.User == "" || msg.User == c.botUserID {
+		return channel.Message{}, false
+	}
+
+	direct := msg.ChannelType == "im"
+	mentioned := strings.Contains(msg.Text, "<@"+c.botUserID+">")
+	threadTS := msg.ThreadTS
+	if threadTS == "" && !direct {
+		threadTS = msg.TS
+	}
+	thread := msg.Channel
+	if threadTS != "" {
+		thread = msg.Channel + ":" + threadTS
+	}
+
+	c.mu.Lock()
+	following := c.active[thread]
+	c.mu.Unlock()
+	switch msg.Type {
+	case "app_mention":
+		if direct {
+			return channel.Message{}, false
+		}
+	case "message":
+		// Mentions in channels arrive as app_mention as well; answer those
+		// once.
+		if !direct && (mentioned || !following) {
+			return channel.Message{}, false
+		}
+	default:
+		return channel.Message{}, false
+	}
+	if !c.firstDelivery(msg) {
+		return channel.Message{}, false
+	}
+	if len(c.cfg.AllowedUsers) > 0 && !slices.Contains(c.cfg.AllowedUsers, msg.User) {
+		log.Printf("[Slack] Ignoring message from unlisted user %s", msg.User)
+		return channel.Message{}, false
+	}
+	text := strings.TrimSpace(mentionPattern.ReplaceAllStringFunc(msg.Text, c.stripSelf))
+	if text == "" {
+		return channel.Message{}, false
+	}
+
+	c.mu.Lock()
+	c.active[thread] = true
+	c.mu.Unlock()
+	return channel.Message{
+		Thread: thread,
+		Sender: msg.User,
+		Text:   text,
+		Agent:  c.cfg.Channels[msg.Channel],
+		Reply:  reply{channel: msg.Channel, threadTS: threadTS},
+	}, true
+}
+
+// stripSelf removes the bot's own mention and keeps mentions of others.
+func (c *Channel) stripSelf(mention string) string {
+	if mention == "<@"+c.botUserID+">" {
+		return ""
+	}
+	return mention
+}
+
+// firstDelivery reports whether the message has not been handled before,
+// whether redelivered by Slack or received as both message and app_mention.
+func (c *Channel) firstDelivery(msg message) bool {
+	key := msg.Channel + ":" + msg.TS
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.seen[key] {
+		return false
+	}
+	c.seen[key] = true
+	c.order = append(c.order, key)
+	for len(c.order) > maxSeenEvents {
+		delete(c.seen, c.order[0])
+		c.order = c.order[1:]
+	}
+	return true
+}
+
+// Send posts text in the message's thread.
+func (c *Channel) Send(ctx context.Context, msg channel.Message, text string) error {
+	r := msg.Reply.(reply)
+	_, err := postMessage(ctx, c.cfg.BotToken, r.channel, r.threadTS, text)
+	return err
+}
+
+// Progress posts a status message in the thread and keeps it updated.
+func (c *Channel) Progress(ctx context.Context, msg channel.Message) channel.Progress {
+	r := msg.Reply.(reply)
+	ts, err := postMessage(ctx, c.cfg.BotToken, r.channel, r.threadTS, ":hourglass_flowing_sand: _Working…_")
+	if err != nil {
+		log.Printf("[Slack] Failed to post status in %s: %v", r.channel, err)
+	}
+	return &status{ctx: ctx, token: c.cfg.BotToken, channel: r.channel, ts: ts}
+}
+
+// status edits the thread's status message with the latest progress lines,
+// at most once per progressInterval.
+type status struct {
+	ctx     context.Context
+	token   string
+	channel string
+	ts      string
+
+	mu      sync.Mutex
+	lines   []string
+	updated time.Time
+}
+
+func (s *status) Update(line string) {
+	if s.ts == "" {
+		return
+	}
+	s.mu.Lock()
+	s.lines = append(s.lines, line)
+	if len(s.lines) > progressLines {
+		s.lines = s.lines[len(s.lines)-progressLines:]
+	}
+	if time.Since(s.updated) < progressInterval {
+		s.mu.Unlock()
+		return
+	}
+	s.updated = time.Now()
+	text := ":hourglass_flowing_sand: _Working…_\n" + strings.Join(s.lines, "\n")
+	s.mu.Unlock()
+	s.set(text)
+}
+
+func (s *status) Done(err error) {
+	if err != nil {
+		s.set(":x: " + err.Error())
+		return
+	}
+	s.set(":white_check_mark: _Done_")
+}
+
+func (s *status) set(text string) {
+	if s.ts == "" {
+		return
+	}
+	if err := updateMessage(s.ctx, s.token, s.channel, s.ts, text); err != nil {
+		log.Printf("[Slack] Failed to update status: %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+package taskqueue
+
+import (
+	"log"
+	"time"
+)
+
+const (
+	defaultScaleUpAfter   = 10 * time.Second
+	defaultScaleDownAfter = time.Minute
+	// autoscaleInterval is how often queue depth and idle workers are sampled
+	autoscaleInterval = time.Second
+)
+
+// autoscale grows the worker pool up to MaxWorkers while the queue stays
+// deep and shrinks it back to WorkerCount while workers sit idle.
+func (m *Manager) autoscale() {
+	defer m.wg.Done()
+	ticker := time.NewTicker(autoscaleInterval)
+	defer ticker.Stop()
+	var busySince, idleSince time.Time
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case now := <-ticker.C:
+			depth := m.sched.len()
+			workers := m.WorkerCount()
+			running := int(m.metrics.inFlight.Load())
+
+			if depth > m.scaling.ScaleUpDepth {
+				idleSince = time.Time{}
+				switch {
+				case busySince.IsZero():
+					busySince = now
+				case now.Sub(busySince) >= m.scaling.ScaleUpAfter && workers < m.scaling.MaxWorkers:
+					m.addWorker(depth)
+					// Give the new worker a full period before adding another
+					busySince = now
+				}
+				continue
+			}
+			busySince = time.Time{}
+
+			if depth > 0 || running >= workers || workers <= m.scaling.WorkerCount {
+				idleSince = time.Time{}
+				continue
+			}
+			switch {
+			case idleSince.IsZero():
+				idleSince = now
+			case now.Sub(idleSince) >= m.scaling.ScaleDownAfter:
+				m.removeWorker()
+				idleSince = now
+			}
+		}
+	}
+}
+
+func (m *Manager) addWorker(depth int) {
+	m.mu.Lock()
+	m.workerCount++
+	workers := m.workerCount
+	m.mu.Unlock()
+	m.sched.grow()
+	m.wg.Add(1)
+	go m.worker()
+	log.Printf("taskqueue: scaled up to %d workers (%d tasks queued)", workers, depth)
+}
+
+func (m *Manager) removeWorker() {
+	m.mu.Lock()
+	m.workerCount--
+	workers := m.workerCount
+	m.mu.Unlock()
+	m.sched.shrink()
+	log.Printf("taskqueue: scaled down to %d workers (idle)", workers)
+}
@@ -0,0 +1,106 @@
+package taskqueue
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+)
+
+func normaliseDependencies(ids []string) []string {
+	var deps []string
+	for _, id := range ids {
+		id = strings.TrimSpace(id)
+		if id != "" && !slices.Contains(deps, id) {
+			deps = append(deps, id)
+		}
+	}
+	return deps
+}
+
+func taskOrigin(task *Task) string {
+	if origin := strings.TrimSpace(task.Origin); origin != "" {
+		return origin
+	}
+	return defaultTaskOrigin
+}
+
+// dependencyStateLocked reports whether every dependency of task completed.
+// A non-empty blocker explains why the task can never run.
+func (m *Manager) dependencyStateLocked(task *Task) (ready bool, blocker string) {
+	ready = true
+	for _, dep := range task.DependsOn {
+		depTask, ok := m.tasks[dep]
+		if !ok || depTask == nil {
+			return false, fmt.Sprintf("dependency %s was deleted", dep)
+		}
+		switch depTask.Status {
+		case StatusComplete:
+		case StatusFailed, StatusSkipped:
+			return false, fmt.Sprintf("dependency %s %s", dep, depTask.Status)
+		default:
+			ready = false
+		}
+	}
+	return ready, ""
+}
+
+func (m *Manager) markSkippedLocked(task *Task, reason string) {
+	now := time.Now().UTC()
+	task.Status = StatusSkipped
+	task.Error = "skipped: " + reason
+	task.Result = ""
+	task.CompletedAt = &now
+	task.UpdatedAt = now
+}
+
+// finishSkipped publishes a skipped task as a terminal failure so watchers
+// and event sinks release it, which in turn skips its own dependents.
+func (m *Manager) finishSkipped(task *Task) {
+	m.logTaskEvent("skipped", task, 0, nil)
+	m.finishWatchers(task.ID, TaskEvent{Type: TaskEventFailed, Task: task, Error: task.Error})
+}
+
+// resolveDependents re-evaluates tasks waiting on finishedID once it reaches
+// a terminal state or is deleted, queueing those now ready and skipping those
+// that can no longer run.
+func (m *Manager) resolveDependents(finishedID string) {
+	var (
+		ready   []*Task
+		skipped []*Task
+	)
+	m.mu.Lock()
+	delete(m.waiting, finishedID)
+	for id := range m.waiting {
+		task, ok := m.tasks[id]
+		if !ok || task == nil {
+			delete(m.waiting, id)
+			continue
+		}
+		if !slices.Contains(task.DependsOn, finishedID) {
+			continue
+		}
+		runnable, blocker := m.dependencyStateLocked(task)
+		switch {
+		case blocker != "":
+			delete(m.waiting, id)
+			m.markSkippedLocked(task, blocker)
+			if err := m.saveTaskLocked(task); err != nil {
+				log.Printf("taskqueue: save skipped state for task %s: %v", id, err)
+			}
+			skipped = append(skipped, task.Clone())
+		case runnable:
+			delete(m.waiting, id)
+			ready = append(ready, task.Clone())
+		}
+	}
+	m.mu.Unlock()
+
+	for _, task := range ready {
+		m.sched.pushNow(taskOrigin(task), task.ID)
+	}
+	for _, task := range skipped {
+		m.finishSkipped(task)
+	}
+}
@@ -8,11 +8,14 @@ import (
 	"fmt"
 	"log"
 	"runtime"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"opperator/pkg/tracing"
+
 	"github.com/google/uuid"
 )
 
@@ -23,6 +26,18 @@ const (
 	StatusPending  Status = "pending"
 	StatusComplete Status = "complete"
 	StatusFailed   Status = "failed"
+	// StatusSkipped marks a task that never ran because a dependency failed
+	// or was deleted.
+	StatusSkipped Status = "skipped"
+)
+
+// Execution policies decide what happens to a task that was running when
+// the daemon stopped. An at-least-once task runs again when the daemon
+// restarts; an at-most-once task fails instead, for commands whose side
+// effects must not be repeated.
+const (
+	ExecutionAtLeastOnce = "at_least_once"
+	ExecutionAtMostOnce  = "at_most_once"
 )
 
 // Task captures the persisted state for an asynchronous tool execution.
@@ -39,6 +54,7 @@ type Task struct {
 	CommandArgs string          `json:"command_args,omitempty"`
 	Origin      string          `json:"origin,omitempty"`
 	ClientID    string          `json:"client_id,omitempty"`
+	Owner       string          `json:"owner,omitempty"`
 	Status      Status          `json:"status"`
 	Result      string          `json:"result,omitempty"`
 	Metadata    string          `json:"metadata,omitempty"`
@@ -47,6 +63,17 @@ type Task struct {
 	UpdatedAt   time.Time       `json:"updated_at"`
 	CompletedAt *time.Time      `json:"completed_at,omitempty"`
 	Progress    []ProgressEntry `json:"progress,omitempty"`
+	DependsOn   []string        `json:"depends_on,omitempty"`
+	// Seq is the task's position in submission order, which queued tasks
+	// are resumed in after a restart.
+	Seq int64 `json:"seq,omitempty"`
+	// Attempts counts the times the task started running.
+	Attempts  int    `json:"attempts,omitempty"`
+	Execution string `json:"execution,omitempty"`
+
+	// TraceParent links the task's execution span to the request that
+	// submitted it. It is not persisted.
+	TraceParent string `json:"-"`
 }
 
 // ProgressEntry captures a single progress update emitted by a task.
@@ -62,6 +89,27 @@ type ManagerOptions struct {
 	WorkerCount          int
 	QueueSize            int
 	MaxPendingPerSession int
+	// MaxPendingPerClient caps in-flight tasks per client ID. Zero means
+	// unlimited.
+	MaxPendingPerClient int
+	// Origins overrides the scheduling policy of individual origins on top
+	// of the built-in defaults. A zero Weight or negative limit keeps the
+	// default for that field.
+	Origins map[string]OriginPolicy
+	// Workflow runs tasks submitted with mode "workflow". Such submissions
+	// are rejected when it is nil.
+	Workflow WorkflowRunner
+	// Conversation runs tasks submitted with mode "conversation". Such
+	// submissions are rejected when it is nil.
+	Conversation ConversationRunner
+	// MaxWorkers enables autoscaling when above WorkerCount: a worker is
+	// added while more than ScaleUpDepth tasks stay queued for ScaleUpAfter,
+	// and one is stopped after the pool has had an idle worker for
+	// ScaleDownAfter, never going below WorkerCount.
+	MaxWorkers     int
+	ScaleUpDepth   int
+	ScaleUpAfter   time.Duration
+	ScaleDownAfter time.Duration
 }
 
 type MetricsSnapshot struct {
@@ -71,6 +119,7 @@ type MetricsSnapshot struct {
 	Failed      int64
 	QueueDepth  int64
 	WorkerCount int64
+	Origins     map[string]OriginStats
 }
 
 type metrics struct {
@@ -146,6 +195,7 @@ func normalizeOptions(opts *ManagerOptions) ManagerOptions {
 		defaults.WorkerCount = 1
 	}
 	if opts == nil {
+		defaults.Origins = originPolicies(nil)
 		return defaults
 	}
 	if opts.WorkerCount > 0 {
@@ -163,9 +213,61 @@ func normalizeOptions(opts *ManagerOptions) ManagerOptions {
 	if opts.MaxPendingPerSession >= 0 {
 		defaults.MaxPendingPerSession = opts.MaxPendingPerSession
 	}
+	if opts.MaxPendingPerClient > 0 {
+		defaults.MaxPendingPerClient = opts.MaxPendingPerClient
+	}
+	if opts.MaxWorkers > defaults.WorkerCount {
+		defaults.MaxWorkers = opts.MaxWorkers
+		defaults.ScaleUpDepth = defaults.WorkerCount
+		if opts.ScaleUpDepth > 0 {
+			defaults.ScaleUpDepth = opts.ScaleUpDepth
+		}
+		defaults.ScaleUpAfter = defaultScaleUpAfter
+		if opts.ScaleUpAfter > 0 {
+			defaults.ScaleUpAfter = opts.ScaleUpAfter
+		}
+		defaults.ScaleDownAfter = defaultScaleDownAfter
+		if opts.ScaleDownAfter > 0 {
+			defaults.ScaleDownAfter = opts.ScaleDownAfter
+		}
+	}
+	defaults.Workflow = opts.Workflow
+	defaults.Conversation = opts.Conversation
+	defaults.Origins = originPolicies(opts.Origins)
 	return defaults
 }
 
+// originPolicies merges overrides into the default policies. Background
+// origins default to leaving one worker free for interactive work, which the
+// scheduler resolves against the current worker count.
+func originPolicies(overrides map[string]OriginPolicy) map[string]OriginPolicy {
+	policies := make(map[string]OriginPolicy, len(defaultOriginPolicies)+len(overrides))
+	for origin, p := range defaultOriginPolicies {
+		policies[origin] = p
+	}
+	for origin, p := range overrides {
+		origin = strings.TrimSpace(origin)
+		if origin == "" {
+			continue
+		}
+		base, ok := policies[origin]
+		if !ok {
+			base = OriginPolicy{Weight: 1}
+		}
+		if p.Weight > 0 {
+			base.Weight = p.Weight
+		}
+		if p.MaxPending >= 0 {
+			base.MaxPending = p.MaxPending
+		}
+		if p.MaxRunning >= 0 {
+			base.MaxRunning = p.MaxRunning
+		}
+		policies[origin] = base
+	}
+	return policies
+}
+
 func (m *metrics) snapshot() MetricsSnapshot {
 	if m == nil {
 		return MetricsSnapshot{}
@@ -191,6 +293,7 @@ func (m *Manager) logTaskEvent(action string, task *Task, duration time.Duration
 		"call_id":      strings.TrimSpace(task.CallID),
 		"origin":       strings.TrimSpace(task.Origin),
 		"client_id":    strings.TrimSpace(task.ClientID),
+		"owner":        strings.TrimSpace(task.Owner),
 		"status":       strings.TrimSpace(string(task.Status)),
 		"submitted_at": task.CreatedAt.UTC().Format(time.RFC3339Nano),
 	}
@@ -222,6 +325,9 @@ func (t *Task) Clone() *Task {
 		clone.Progress = make([]ProgressEntry, len(t.Progress))
 		copy(clone.Progress, t.Progress)
 	}
+	if len(t.DependsOn) > 0 {
+		clone.DependsOn = append([]string(nil), t.DependsOn...)
+	}
 	return &clone
 }
 
@@ -240,6 +346,18 @@ type AgentRunner interface {
 	Execute(ctx context.Context, agent, command, args, workingDir string, progress func(ProgressEvent)) (content string, metadata string, err error)
 }
 
+// WorkflowRunner executes a named workflow as a single task, reporting each
+// step as progress.
+type WorkflowRunner interface {
+	Execute(ctx context.Context, workflow, inputs, workingDir string, progress func(ProgressEvent)) (content string, metadata string, err error)
+}
+
+// ConversationRunner sends a message to an agent in the background, continuing
+// conversationID when set, and returns the final response.
+type ConversationRunner interface {
+	Execute(ctx context.Context, agent, message, conversationID, workingDir string, progress func(ProgressEvent)) (content string, metadata string, err error)
+}
+
 type SubmitRequest struct {
 	ToolName    string
 	Args        string
@@ -252,6 +370,16 @@ type SubmitRequest struct {
 	CommandArgs string
 	Origin      string
 	ClientID    string
+	TraceParent string
+	// DependsOn lists task IDs that must complete successfully before this
+	// task starts. If any of them fails or is deleted the task is skipped.
+	DependsOn []string
+	// Owner is the user whose auth token submitted the task, or "" for
+	// tasks submitted locally or by the daemon itself.
+	Owner string
+	// Execution is ExecutionAtLeastOnce (the default) or
+	// ExecutionAtMostOnce.
+	Execution string
 }
 
 // Manager coordinates asynchronous tool tasks, persisting their state and
@@ -259,9 +387,12 @@ type SubmitRequest struct {
 type Manager struct {
 	mu                   sync.RWMutex
 	tasks                map[string]*Task
-	queue                chan string
+	sched                *scheduler
+	waiting              map[string]struct{}
 	runner               ToolRunner
 	agent                AgentRunner
+	workflow             WorkflowRunner
+	conversation         ConversationRunner
 	db                   *sql.DB
 	discarded            map[string]struct{}
 	cancels              map[string]context.CancelFunc
@@ -269,7 +400,11 @@ type Manager struct {
 	cancel               context.CancelFunc
 	queueSize            int
 	workerCount          int
+	scaling              ManagerOptions
 	maxPendingPerSession int
+	maxPendingPerClient  int
+	originPolicies       map[string]OriginPolicy
+	lastSeq              int64 // Seq of the most recently submitted task
 	metrics              *metrics
 	watchMu              sync.RWMutex
 	watchers             map[string]map[*taskWatcher]struct{}
@@ -282,6 +417,18 @@ type Manager struct {
 // ErrClosed indicates the manager has been shut down and cannot accept work.
 var ErrClosed = errors.New("task queue closed")
 
+// ErrUnknownDependency indicates a submission depends on a task the manager
+// does not know.
+var ErrUnknownDependency = errors.New("unknown dependency")
+
+// ErrInvalidExecution indicates a submission with an unknown execution
+// policy.
+var ErrInvalidExecution = errors.New("invalid execution policy")
+
+// ErrPendingLimit indicates a session, origin or client already has the
+// maximum number of pending async tasks.
+var ErrPendingLimit = errors.New("pending async task limit reached")
+
 // NewManager constructs a manager backed by the provided storage path and
 // execution runner. In-flight tasks persisted with loading/pending states are
 // automatically resumed.
@@ -307,9 +454,12 @@ func NewManagerWithOptions(ctx context.Context, db *sql.DB, runner ToolRunner, a
 	queueCtx, cancel := context.WithCancel(baseCtx)
 	mgr := &Manager{
 		tasks:                make(map[string]*Task),
-		queue:                make(chan string, options.QueueSize),
+		sched:                newScheduler(options.QueueSize, options.WorkerCount, options.Origins),
+		waiting:              make(map[string]struct{}),
 		runner:               runner,
 		agent:                agent,
+		workflow:             options.Workflow,
+		conversation:         options.Conversation,
 		db:                   db,
 		discarded:            make(map[string]struct{}),
 		cancels:              make(map[string]context.CancelFunc),
@@ -317,7 +467,10 @@ func NewManagerWithOptions(ctx context.Context, db *sql.DB, runner ToolRunner, a
 		cancel:               cancel,
 		queueSize:            options.QueueSize,
 		workerCount:          options.WorkerCount,
+		scaling:              options,
 		maxPendingPerSession: options.MaxPendingPerSession,
+		maxPendingPerClient:  options.MaxPendingPerClient,
+		originPolicies:       options.Origins,
 		metrics:              newMetrics(),
 		watchers:             make(map[string]map[*taskWatcher]struct{}),
 		progressQueue:        make(chan progressRequest, 64),
@@ -326,10 +479,15 @@ func NewManagerWithOptions(ctx context.Context, db *sql.DB, runner ToolRunner, a
 		cancel()
 		return nil, err
 	}
+	context.AfterFunc(queueCtx, mgr.sched.close)
 	mgr.resumeIncomplete()
 	mgr.startWorkers(options.WorkerCount)
 	mgr.wg.Add(1)
 	go mgr.progressWriter()
+	if options.MaxWorkers > options.WorkerCount {
+		mgr.wg.Add(1)
+		go mgr.autoscale()
+	}
 	return mgr, nil
 }
 
@@ -385,21 +543,34 @@ func (m *Manager) Submit(ctx context.Context, req SubmitRequest) (*Task, error)
 	if mode == "agent" && m.agent == nil {
 		return nil, fmt.Errorf("agent runner is not configured")
 	}
+	if mode == "workflow" && m.workflow == nil {
+		return nil, fmt.Errorf("workflow runner is not configured")
+	}
+	if mode == "conversation" && m.conversation == nil {
+		return nil, fmt.Errorf("conversation runner is not configured")
+	}
 	sessionID := strings.TrimSpace(req.SessionID)
 	origin := strings.TrimSpace(req.Origin)
 	clientID := strings.TrimSpace(req.ClientID)
 	if origin == "" {
 		origin = defaultTaskOrigin
 	}
+	execution, err := normaliseExecution(req.Execution)
+	if err != nil {
+		return nil, err
+	}
 	if limit := m.pendingLimit(); limit > 0 {
 		normalised := normaliseSessionForLimit(sessionID)
 		m.mu.RLock()
 		pending := m.countPendingLocked(normalised)
 		m.mu.RUnlock()
 		if pending >= limit {
-			return nil, fmt.Errorf("pending async task limit reached for session %s (limit %d)", friendlySessionLabel(sessionID), limit)
+			return nil, fmt.Errorf("%w for session %s (limit %d)", ErrPendingLimit, friendlySessionLabel(sessionID), limit)
 		}
 	}
+	if err := m.checkQuotas(origin, clientID); err != nil {
+		return nil, err
+	}
 	if ctx == nil {
 		ctx = context.Background()
 	}
@@ -417,36 +588,71 @@ func (m *Manager) Submit(ctx context.Context, req SubmitRequest) (*Task, error)
 		CommandArgs: req.CommandArgs,
 		Origin:      origin,
 		ClientID:    clientID,
+		Owner:       strings.TrimSpace(req.Owner),
+		TraceParent: strings.TrimSpace(req.TraceParent),
+		DependsOn:   normaliseDependencies(req.DependsOn),
+		Execution:   execution,
 		Status:      StatusLoading,
 		CreatedAt:   now,
 		UpdatedAt:   now,
 	}
 	m.mu.Lock()
+	for _, dep := range task.DependsOn {
+		if _, ok := m.tasks[dep]; !ok {
+			m.mu.Unlock()
+			return nil, fmt.Errorf("%w: task %s", ErrUnknownDependency, dep)
+		}
+	}
+	m.lastSeq++
+	task.Seq = m.lastSeq
+	// Dependency state is checked in the same critical section that
+	// registers the task, so a dependency finishing concurrently either is
+	// seen here or sees this task in the waiting set.
+	ready, blocker := m.dependencyStateLocked(task)
+	if blocker != "" {
+		m.markSkippedLocked(task, blocker)
+	}
 	m.tasks[task.ID] = task
 	if err := m.saveTaskLocked(task); err != nil {
 		delete(m.tasks, task.ID)
 		m.mu.Unlock()
 		return nil, err
 	}
+	if !ready && blocker == "" {
+		m.waiting[task.ID] = struct{}{}
+	}
 	m.mu.Unlock()
 
 	m.emitTaskEvent(TaskEvent{Type: TaskEventSnapshot, Task: task.Clone()})
-
-	select {
-	case m.queue <- task.ID:
-	case <-ctx.Done():
-		return nil, ctx.Err()
-	case <-m.ctx.Done():
-		return nil, ErrClosed
-	}
-
 	if m.metrics != nil {
 		m.metrics.submitted.Add(1)
 	}
+	if blocker != "" {
+		m.finishSkipped(task.Clone())
+		return task.Clone(), nil
+	}
+	if !ready {
+		return task.Clone(), nil
+	}
+
+	if err := m.sched.push(ctx, origin, task.ID); err != nil {
+		return nil, err
+	}
 
 	return task.Clone(), nil
 }
 
+func normaliseExecution(policy string) (string, error) {
+	switch policy = strings.ToLower(strings.TrimSpace(policy)); policy {
+	case "", ExecutionAtLeastOnce:
+		return "", nil
+	case ExecutionAtMostOnce:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("%w %q (expected %s or %s)", ErrInvalidExecution, policy, ExecutionAtLeastOnce, ExecutionAtMostOnce)
+	}
+}
+
 func (m *Manager) pendingLimit() int {
 	if m == nil {
 		return 0
@@ -477,8 +683,9 @@ func (m *Manager) MetricsSnapshot() MetricsSnapshot {
 	if m.metrics != nil {
 		snapshot = m.metrics.snapshot()
 	}
-	snapshot.QueueDepth = int64(len(m.queue))
+	snapshot.QueueDepth = int64(m.sched.len())
 	snapshot.WorkerCount = int64(m.WorkerCount())
+	snapshot.Origins = m.sched.originStats()
 	return snapshot
 }
 
@@ -596,6 +803,7 @@ func (m *Manager) finishWatchers(taskID string, event TaskEvent) {
 		watcher.close()
 	}
 	m.emitTaskEvent(event)
+	m.resolveDependents(taskID)
 }
 
 func (m *Manager) Get(id string) (*Task, bool) {
@@ -686,15 +894,12 @@ func (m *Manager) progressWriter() {
 func (m *Manager) worker() {
 	defer m.wg.Done()
 	for {
-		select {
-		case <-m.ctx.Done():
+		id, origin, ok := m.sched.next()
+		if !ok {
 			return
-		case id, ok := <-m.queue:
-			if !ok {
-				return
-			}
-			m.runSafe(id)
 		}
+		m.runSafe(id)
+		m.sched.done(origin)
 	}
 }
 
@@ -770,6 +975,15 @@ func (m *Manager) failTaskAfterPanic(id string, panicErr error) {
 	m.finishWatchers(id, TaskEvent{Type: TaskEventFailed, Task: taskClone, Error: panicMsg})
 }
 
+type taskContextKey struct{}
+
+// TaskFromContext returns the task whose runner was given ctx, so runners can
+// tell where the work came from.
+func TaskFromContext(ctx context.Context) (*Task, bool) {
+	task, ok := ctx.Value(taskContextKey{}).(*Task)
+	return task, ok
+}
+
 func (m *Manager) run(id string) (bool, error) {
 	m.mu.Lock()
 	task, ok := m.tasks[id]
@@ -782,15 +996,18 @@ func (m *Manager) run(id string) (bool, error) {
 		return false, nil
 	}
 	start := time.Now()
-	ctx, cancel := context.WithCancel(m.ctx)
+	ctx, cancel := context.WithCancel(tracing.WithTraceParent(m.ctx, task.TraceParent))
 	m.cancels[id] = cancel
 	task.Status = StatusPending
+	task.Attempts++
 	task.Error = ""
 	task.Result = ""
 	task.Metadata = ""
 	task.CompletedAt = nil
 	task.UpdatedAt = time.Now().UTC()
 	taskSnapshot := task.Clone()
+	// The attempt is on disk before the task runs, so a restart can tell a
+	// task that was interrupted from one that never started.
 	if err := m.saveTaskLocked(task); err != nil {
 		log.Printf("taskqueue: save pending state for task %s: %v", id, err)
 	}
@@ -804,20 +1021,42 @@ func (m *Manager) run(id string) (bool, error) {
 		delete(m.cancels, id)
 		m.mu.Unlock()
 	}()
+	ctx = context.WithValue(ctx, taskContextKey{}, taskSnapshot)
+	ctx, span := tracing.Start(ctx, "task.execute",
+		"task.id", taskSnapshot.ID,
+		"task.mode", taskSnapshot.Mode,
+		"task.tool", taskSnapshot.ToolName,
+		"agent.name", taskSnapshot.AgentName,
+		"agent.command", taskSnapshot.CommandName,
+		"task.queue_wait_ms", start.Sub(taskSnapshot.CreatedAt).Milliseconds(),
+	)
+	defer span.End()
 	var (
 		content  string
 		metadata string
 		err      error
 	)
+	progress := func(ev ProgressEvent) {
+		m.appendProgress(task.ID, ev)
+	}
 	if strings.EqualFold(task.Mode, "agent") {
 		if m.agent == nil {
 			err = fmt.Errorf("agent runner not configured")
 		} else {
-			progress := func(ev ProgressEvent) {
-				m.appendProgress(task.ID, ev)
-			}
 			content, metadata, err = m.agent.Execute(ctx, task.AgentName, task.CommandName, task.CommandArgs, task.WorkingDir, progress)
 		}
+	} else if strings.EqualFold(task.Mode, "workflow") {
+		if m.workflow == nil {
+			err = fmt.Errorf("workflow runner not configured")
+		} else {
+			content, metadata, err = m.workflow.Execute(ctx, task.CommandName, task.CommandArgs, task.WorkingDir, progress)
+		}
+	} else if strings.EqualFold(task.Mode, "conversation") {
+		if m.conversation == nil {
+			err = fmt.Errorf("conversation runner not configured")
+		} else {
+			content, metadata, err = m.conversation.Execute(ctx, task.AgentName, task.CommandArgs, task.SessionID, task.WorkingDir, progress)
+		}
 	} else {
 		content, metadata, err = m.runner.Execute(ctx, task.ToolName, task.Args, task.WorkingDir)
 	}
@@ -843,6 +1082,7 @@ func (m *Manager) run(id string) (bool, error) {
 	m.mu.Unlock()
 
 	duration := time.Since(start)
+	span.RecordError(err)
 	if err != nil {
 		m.logTaskEvent("failed", taskClone, duration, err)
 	} else {
@@ -958,6 +1198,37 @@ func (m *Manager) countPendingLocked(sessionKey string) int {
 	return count
 }
 
+// checkQuotas enforces the per-origin and per-client pending limits.
+func (m *Manager) checkQuotas(origin, clientID string) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if limit := m.originPolicies[origin].MaxPending; limit > 0 {
+		if pending := m.countPendingMatchingLocked(func(t *Task) bool { return t.Origin == origin }); pending >= limit {
+			return fmt.Errorf("%w for origin %s (limit %d)", ErrPendingLimit, origin, limit)
+		}
+	}
+	if limit := m.maxPendingPerClient; limit > 0 && clientID != "" {
+		if pending := m.countPendingMatchingLocked(func(t *Task) bool { return t.ClientID == clientID }); pending >= limit {
+			return fmt.Errorf("%w for client %s (limit %d)", ErrPendingLimit, clientID, limit)
+		}
+	}
+	return nil
+}
+
+func (m *Manager) countPendingMatchingLocked(match func(*Task) bool) int {
+	count := 0
+	for _, task := range m.tasks {
+		if task == nil || !match(task) {
+			continue
+		}
+		switch task.Status {
+		case StatusLoading, StatusPending:
+			count++
+		}
+	}
+	return count
+}
+
 func mergeProgressMetadata(base string, progress []ProgressEntry) string {
 	trimmed := strings.TrimSpace(base)
 	if trimmed == "" && len(progress) == 0 {
@@ -1042,13 +1313,27 @@ func (m *Manager) saveTaskLocked(task *Task) error {
 	}
 	originValue := strings.TrimSpace(task.Origin)
 	clientValue := strings.TrimSpace(task.ClientID)
+	ownerArg := interface{}(nil)
+	if owner := strings.TrimSpace(task.Owner); owner != "" {
+		ownerArg = owner
+	}
+	dependsArg := interface{}(nil)
+	if len(task.DependsOn) > 0 {
+		if b, err := json.Marshal(task.DependsOn); err == nil {
+			dependsArg = string(b)
+		}
+	}
+	executionArg := interface{}(nil)
+	if task.Execution != "" {
+		executionArg = task.Execution
+	}
 	_, err := m.db.ExecContext(
 		context.Background(),
 		`INSERT INTO tool_tasks (
 			id, tool_name, args, working_dir, session_id, call_id, mode, agent_name,
 			command_name, command_args, origin, client_id, status, result, metadata, error,
-			created_at, updated_at, completed_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			created_at, updated_at, completed_at, depends_on, owner, seq, attempts, execution
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT(id) DO UPDATE SET
 			tool_name = excluded.tool_name,
 			args = excluded.args,
@@ -1067,7 +1352,12 @@ func (m *Manager) saveTaskLocked(task *Task) error {
 			error = excluded.error,
 			created_at = excluded.created_at,
 			updated_at = excluded.updated_at,
-			completed_at = excluded.completed_at`,
+			completed_at = excluded.completed_at,
+			depends_on = excluded.depends_on,
+			owner = excluded.owner,
+			seq = excluded.seq,
+			attempts = excluded.attempts,
+			execution = excluded.execution`,
 		id,
 		strings.TrimSpace(task.ToolName),
 		strings.TrimSpace(task.Args),
@@ -1087,6 +1377,11 @@ func (m *Manager) saveTaskLocked(task *Task) error {
 		created.UTC().UnixNano(),
 		updated.UTC().UnixNano(),
 		completed,
+		dependsArg,
+		ownerArg,
+		task.Seq,
+		task.Attempts,
+		executionArg,
 	)
 	return err
 }
@@ -1397,7 +1692,7 @@ func (m *Manager) loadFromDatabase() error {
 		SELECT
 			id, tool_name, args, working_dir, session_id, call_id, mode, agent_name,
 			command_name, command_args, origin, client_id, status, result, metadata, error,
-			created_at, updated_at, completed_at
+			created_at, updated_at, completed_at, depends_on, owner, seq, attempts, execution
 		FROM tool_tasks
 	`)
 	if err != nil {
@@ -1426,11 +1721,17 @@ func (m *Manager) loadFromDatabase() error {
 			createdAt   int64
 			updatedAt   int64
 			completedAt sql.NullInt64
+			dependsOn   sql.NullString
+			owner       sql.NullString
+			seq         int64
+			attempts    int
+			execution   sql.NullString
 		)
 		if err := rows.Scan(
 			&id, &toolName, &args, &workingDir, &sessionID, &callID, &mode,
 			&agentName, &commandName, &commandArgs, &origin, &clientID, &status, &result, &metadata,
-			&errorText, &createdAt, &updatedAt, &completedAt,
+			&errorText, &createdAt, &updatedAt, &completedAt, &dependsOn, &owner,
+			&seq, &attempts, &execution,
 		); err != nil {
 			return fmt.Errorf("scan tool tasks: %w", err)
 		}
@@ -1456,17 +1757,27 @@ func (m *Manager) loadFromDatabase() error {
 			CommandArgs: strings.TrimSpace(commandArgs.String),
 			Origin:      strings.TrimSpace(origin.String),
 			ClientID:    strings.TrimSpace(clientID.String),
+			Owner:       strings.TrimSpace(owner.String),
 			Result:      strings.TrimSpace(result.String),
 			Metadata:    strings.TrimSpace(metadata.String),
 			Error:       strings.TrimSpace(errorText.String),
 			CreatedAt:   time.Unix(0, createdAt).UTC(),
 			UpdatedAt:   time.Unix(0, updatedAt).UTC(),
+			Seq:         seq,
+			Attempts:    attempts,
+			Execution:   strings.TrimSpace(execution.String),
 		}
 		task.Status = Status(statusVal)
+		m.lastSeq = max(m.lastSeq, seq)
 		if completedAt.Valid {
 			ts := time.Unix(0, completedAt.Int64).UTC()
 			task.CompletedAt = &ts
 		}
+		if trimmed := strings.TrimSpace(dependsOn.String); trimmed != "" {
+			if err := json.Unmarshal([]byte(trimmed), &task.DependsOn); err != nil {
+				log.Printf("taskqueue: invalid dependencies for task %s: %v", task.ID, err)
+			}
+		}
 		tasks[task.ID] = task
 	}
 	if err := rows.Err(); err != nil {
@@ -1509,20 +1820,67 @@ func (m *Manager) loadFromDatabase() error {
 	return nil
 }
 
+// resumeIncomplete queues the tasks the previous run left queued or running,
+// in the order they were submitted. A task that was running starts again
+// unless it is at-most-once, in which case it fails as interrupted.
 func (m *Manager) resumeIncomplete() {
+	var finished []*Task
 	m.mu.Lock()
-	defer m.mu.Unlock()
-	for id, task := range m.tasks {
-		if task == nil {
-			continue
+	var tasks []*Task
+	for _, task := range m.tasks {
+		if task != nil && (task.Status == StatusLoading || task.Status == StatusPending) {
+			tasks = append(tasks, task)
 		}
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		if tasks[i].Seq != tasks[j].Seq {
+			return tasks[i].Seq < tasks[j].Seq
+		}
+		return tasks[i].CreatedAt.Before(tasks[j].CreatedAt)
+	})
+	for _, task := range tasks {
+		id := task.ID
 		switch task.Status {
-		case StatusLoading, StatusPending:
-			select {
-			case m.queue <- id:
-			default:
-				go func(taskID string) { m.queue <- taskID }(id)
+		case StatusPending:
+			if task.Execution == ExecutionAtMostOnce {
+				now := time.Now().UTC()
+				task.Status = StatusFailed
+				task.Error = "interrupted by a daemon restart; at_most_once tasks are not run again"
+				task.Result = ""
+				task.Metadata = mergeProgressMetadata("", task.Progress)
+				task.CompletedAt = &now
+				task.UpdatedAt = now
+				if err := m.saveTaskLocked(task); err != nil {
+					log.Printf("taskqueue: save interrupted state for task %s: %v", id, err)
+				}
+				finished = append(finished, task.Clone())
+				continue
 			}
+			log.Printf("taskqueue: task %s was interrupted after %d attempt(s); running it again", id, task.Attempts)
+		case StatusLoading:
+			ready, blocker := m.dependencyStateLocked(task)
+			if blocker != "" {
+				m.markSkippedLocked(task, blocker)
+				if err := m.saveTaskLocked(task); err != nil {
+					log.Printf("taskqueue: save skipped state for task %s: %v", id, err)
+				}
+				finished = append(finished, task.Clone())
+				continue
+			}
+			if !ready {
+				m.waiting[id] = struct{}{}
+				continue
+			}
+		}
+		m.sched.pushNow(taskOrigin(task), id)
+	}
+	m.mu.Unlock()
+	// Tasks waiting on one failed or skipped above may have been registered
+	// before it was visited, so release them now.
+	for _, task := range finished {
+		if task.Status == StatusFailed {
+			m.logTaskEvent("interrupted", task, 0, errors.New(task.Error))
 		}
+		m.resolveDependents(task.ID)
 	}
 }
@@ -0,0 +1,95 @@
+package taskqueue
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Record stores an invocation that already ran outside the queue, such as a
+// synchronous agent command, as a finished task so it can be inspected and
+// replayed like one submitted here. errMsg marks it failed.
+func (m *Manager) Record(req SubmitRequest, result, metadata, errMsg string, startedAt time.Time) (*Task, error) {
+	if m == nil {
+		return nil, fmt.Errorf("task manager not initialised")
+	}
+	name := strings.TrimSpace(req.ToolName)
+	if name == "" {
+		return nil, fmt.Errorf("tool name is required")
+	}
+	mode := strings.TrimSpace(req.Mode)
+	if mode == "" {
+		mode = "tool"
+	}
+	origin := strings.TrimSpace(req.Origin)
+	if origin == "" {
+		origin = defaultTaskOrigin
+	}
+	now := time.Now().UTC()
+	task := &Task{
+		ID:          uuid.NewString(),
+		ToolName:    name,
+		Args:        req.Args,
+		WorkingDir:  strings.TrimSpace(req.WorkingDir),
+		SessionID:   strings.TrimSpace(req.SessionID),
+		CallID:      strings.TrimSpace(req.CallID),
+		Mode:        mode,
+		AgentName:   strings.TrimSpace(req.AgentName),
+		CommandName: strings.TrimSpace(req.Command),
+		CommandArgs: req.CommandArgs,
+		Origin:      origin,
+		ClientID:    strings.TrimSpace(req.ClientID),
+		Owner:       strings.TrimSpace(req.Owner),
+		Status:      StatusComplete,
+		Result:      result,
+		Metadata:    metadata,
+		CreatedAt:   startedAt.UTC(),
+		UpdatedAt:   now,
+		CompletedAt: &now,
+	}
+	if errMsg = strings.TrimSpace(errMsg); errMsg != "" {
+		task.Status = StatusFailed
+		task.Error = errMsg
+		task.Result = ""
+	}
+	m.mu.Lock()
+	m.lastSeq++
+	task.Seq = m.lastSeq
+	m.tasks[task.ID] = task
+	if err := m.saveTaskLocked(task); err != nil {
+		delete(m.tasks, task.ID)
+		m.mu.Unlock()
+		return nil, err
+	}
+	taskClone := task.Clone()
+	m.mu.Unlock()
+
+	m.logTaskEvent("recorded", taskClone, now.Sub(task.CreatedAt), nil)
+	m.emitTaskEvent(TaskEvent{Type: TaskEventSnapshot, Task: taskClone.Clone()})
+	return taskClone, nil
+}
+
+// Replay submits a new task running the same tool or agent command with the
+// same arguments and execution policy as the task id. The new task belongs to
+// no session or tool call and waits on no dependencies.
+func (m *Manager) Replay(ctx context.Context, id, origin, owner string) (*Task, error) {
+	original, ok := m.Get(id)
+	if !ok {
+		return nil, fmt.Errorf("task %s not found", strings.TrimSpace(id))
+	}
+	return m.Submit(ctx, SubmitRequest{
+		ToolName:    original.ToolName,
+		Args:        original.Args,
+		WorkingDir:  original.WorkingDir,
+		Mode:        original.Mode,
+		AgentName:   original.AgentName,
+		Command:     original.CommandName,
+		CommandArgs: original.CommandArgs,
+		Origin:      origin,
+		Owner:       owner,
+		Execution:   original.Execution,
+	})
+}
@@ -0,0 +1,268 @@
+package taskqueue
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// OriginPolicy controls how tasks submitted from one origin (tui, cli,
+// webhook, schedule, trigger, ...) share the worker pool.
+type OriginPolicy struct {
+	// Weight is the origin's share of workers when several origins have
+	// queued tasks. An origin with weight 4 is dispatched four times as often
+	// as one with weight 1.
+	Weight int
+	// MaxPending caps queued plus running tasks from the origin; further
+	// submissions fail with ErrPendingLimit. Zero means unlimited.
+	MaxPending int
+	// MaxRunning caps how many workers the origin may occupy at once. Zero
+	// means unlimited.
+	MaxRunning int
+}
+
+// defaultOriginPolicies favours interactive origins and keeps background
+// origins from occupying every worker. A MaxRunning of -1 is one less than
+// the current worker count.
+var defaultOriginPolicies = map[string]OriginPolicy{
+	"tui":      {Weight: 4},
+	"cli":      {Weight: 2},
+	"webhook":  {Weight: 1, MaxRunning: -1},
+	"schedule": {Weight: 1, MaxRunning: -1},
+	"trigger":  {Weight: 1, MaxRunning: -1},
+}
+
+// originQueue is the FIFO of tasks waiting for one origin.
+type originQueue struct {
+	ids     []string
+	running int
+	// pass is the origin's virtual time in stride scheduling: it advances by
+	// 1/weight per dispatch and the origin with the lowest pass goes next.
+	pass float64
+}
+
+// scheduler hands queued task IDs to workers. It replaces a single FIFO so
+// one origin flooding the queue cannot starve the others.
+type scheduler struct {
+	mu       sync.Mutex
+	cond     *sync.Cond
+	origins  map[string]*originQueue
+	policies map[string]OriginPolicy
+	capacity int
+	depth    int
+	// workers is the size of the worker pool; retiring is how many of its
+	// workers should exit the next time they ask for a task.
+	workers  int
+	retiring int
+	closed   bool
+}
+
+func newScheduler(capacity, workers int, policies map[string]OriginPolicy) *scheduler {
+	s := &scheduler{
+		origins:  make(map[string]*originQueue),
+		policies: policies,
+		capacity: capacity,
+		workers:  workers,
+	}
+	s.cond = sync.NewCond(&s.mu)
+	return s
+}
+
+func (s *scheduler) policy(origin string) OriginPolicy {
+	p, ok := s.policies[origin]
+	if !ok {
+		p = OriginPolicy{Weight: 1}
+	}
+	if p.Weight < 1 {
+		p.Weight = 1
+	}
+	return p
+}
+
+func (s *scheduler) queue(origin string) *originQueue {
+	q, ok := s.origins[origin]
+	if !ok {
+		q = &originQueue{}
+		s.origins[origin] = q
+	}
+	return q
+}
+
+// push queues id, blocking while the scheduler is at capacity.
+func (s *scheduler) push(ctx context.Context, origin, id string) error {
+	stop := context.AfterFunc(ctx, func() {
+		s.mu.Lock()
+		s.cond.Broadcast()
+		s.mu.Unlock()
+	})
+	defer stop()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for s.depth >= s.capacity && !s.closed {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		s.cond.Wait()
+	}
+	if s.closed {
+		return ErrClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.enqueueLocked(origin, id)
+	return nil
+}
+
+// pushNow queues id regardless of capacity. It is used when resuming tasks
+// that were already accepted before a restart.
+func (s *scheduler) pushNow(origin, id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.enqueueLocked(origin, id)
+}
+
+func (s *scheduler) enqueueLocked(origin, id string) {
+	q := s.queue(origin)
+	if len(q.ids) == 0 {
+		// An origin that was idle must not bank credit from that time, or it
+		// would monopolise workers once it becomes busy.
+		if lowest, ok := s.minPassLocked(); ok && q.pass < lowest {
+			q.pass = lowest
+		}
+	}
+	q.ids = append(q.ids, id)
+	s.depth++
+	s.cond.Broadcast()
+}
+
+func (s *scheduler) minPassLocked() (float64, bool) {
+	found := false
+	var lowest float64
+	for _, q := range s.origins {
+		if len(q.ids) == 0 {
+			continue
+		}
+		if !found || q.pass < lowest {
+			lowest, found = q.pass, true
+		}
+	}
+	return lowest, found
+}
+
+// next blocks until a task may run and returns it with its origin. The
+// caller must call done(origin) once the task finishes. ok is false when the
+// scheduler is closed or the worker should exit to shrink the pool.
+func (s *scheduler) next() (id, origin string, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for {
+		if s.closed {
+			return "", "", false
+		}
+		if s.retiring > 0 {
+			s.retiring--
+			return "", "", false
+		}
+		if origin, ok := s.pickLocked(); ok {
+			q := s.origins[origin]
+			id = q.ids[0]
+			q.ids = q.ids[1:]
+			q.running++
+			q.pass += 1 / float64(s.policy(origin).Weight)
+			s.depth--
+			s.cond.Broadcast()
+			return id, origin, true
+		}
+		s.cond.Wait()
+	}
+}
+
+// pickLocked returns the eligible origin with the lowest pass. Ties are
+// broken by name so dispatch order is deterministic.
+func (s *scheduler) pickLocked() (string, bool) {
+	names := make([]string, 0, len(s.origins))
+	for name := range s.origins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	best := ""
+	for _, name := range names {
+		q := s.origins[name]
+		if len(q.ids) == 0 {
+			continue
+		}
+		limit := s.policy(name).MaxRunning
+		if limit < 0 {
+			limit = max(1, s.workers-1)
+		}
+		if limit > 0 && q.running >= limit {
+			continue
+		}
+		if best == "" || q.pass < s.origins[best].pass {
+			best = name
+		}
+	}
+	return best, best != ""
+}
+
+func (s *scheduler) done(origin string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if q, ok := s.origins[origin]; ok && q.running > 0 {
+		q.running--
+	}
+	s.cond.Broadcast()
+}
+
+// grow records a worker added to the pool.
+func (s *scheduler) grow() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.workers++
+	s.cond.Broadcast()
+}
+
+// shrink has one worker exit once it is idle.
+func (s *scheduler) shrink() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.workers--
+	s.retiring++
+	s.cond.Broadcast()
+}
+
+func (s *scheduler) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.depth
+}
+
+// originStats reports queued and running counts per origin.
+func (s *scheduler) originStats() map[string]OriginStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := make(map[string]OriginStats, len(s.origins))
+	for name, q := range s.origins {
+		if len(q.ids) == 0 && q.running == 0 {
+			continue
+		}
+		stats[name] = OriginStats{Queued: len(q.ids), Running: q.running}
+	}
+	return stats
+}
+
+func (s *scheduler) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	s.cond.Broadcast()
+}
+
+// OriginStats is the scheduler state for one origin.
+type OriginStats struct {
+	Queued  int
+	Running int
+}
@@ -0,0 +1,130 @@
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const apiBase = "https://api.telegram.org/bot"
+
+// maxMessageLength is Telegram's limit for one message.
+const maxMessageLength = 4096
+
+// pollTimeout is the long-poll duration of getUpdates in seconds.
+const pollTimeout = 50
+
+var httpClient = &http.Client{Timeout: (pollTimeout + 10) * time.Second}
+
+type update struct {
+	UpdateID int64    `json:"update_id"`
+	Message  *message `json:"message"`
+}
+
+type message struct {
+	MessageID int64  `json:"message_id"`
+	Text      string `json:"text"`
+	From      *struct {
+		ID int64 `json:"id"`
+	} `json:"from"`
+	Chat struct {
+		ID int64 `json:"id"`
+	} `json:"chat"`
+}
+
+// client calls the Bot API with one bot token.
+type client struct {
+	token string
+}
+
+// call POSTs params as JSON to method and decodes the result into out.
+func (c *client) call(ctx context.Context, method string, params any, out any) error {
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(params); err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiBase+c.token+"/"+method, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		// The URL embeds the token; keep it out of logs.
+		return fmt.Errorf("telegram %s: %s", method, strings.ReplaceAll(err.Error(), c.token, "***"))
+	}
+	defer resp.Body.Close()
+	var result struct {
+		OK          bool            `json:"ok"`
+		Description string          `json:"description"`
+		Result      json.RawMessage `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("telegram %s: %s", method, resp.Status)
+	}
+	if !result.OK {
+		return fmt.Errorf("telegram %s: %s", method, result.Description)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(result.Result, out)
+}
+
+func (c *client) getUpdates(ctx context.Context, offset int64) ([]update, error) {
+	var updates []update
+	err := c.call(ctx, "getUpdates", map[string]any{
+		"offset":          offset,
+		"timeout":         pollTimeout,
+		"allowed_updates": []string{"message"},
+	}, &updates)
+	return updates, err
+}
+
+// sendMessage sends text to chatID, split into several messages when it is
+// too long, and returns the ID of the last one.
+func (c *client) sendMessage(ctx context.Context, chatID int64, text string) (int64, error) {
+	var id int64
+	for _, chunk := range split(text) {
+		var sent message
+		if err := c.call(ctx, "sendMessage", map[string]any{"chat_id": chatID, "text": chunk}, &sent); err != nil {
+			return 0, err
+		}
+		id = sent.MessageID
+	}
+	return id, nil
+}
+
+func (c *client) editMessage(ctx context.Context, chatID, messageID int64, text string) error {
+	return c.call(ctx, "editMessageText", map[string]any{
+		"chat_id":    chatID,
+		"message_id": messageID,
+		"text":       split(text)[0],
+	}, nil)
+}
+
+func (c *client) deleteMessage(ctx context.Context, chatID, messageID int64) error {
+	return c.call(ctx, "deleteMessage", map[string]any{"chat_id": chatID, "message_id": messageID}, nil)
+}
+
+// split breaks text into chunks Telegram accepts, preferring line breaks.
+func split(text string) []string {
+	var chunks []string
+	for len(text) > maxMessageLength {
+		cut := strings.LastIndex(text[:maxMessageLength], "\n")
+		if cut <= 0 {
+			cut = maxMessageLength
+			for cut > 0 && !utf8.RuneStart(text[cut]) {
+				cut--
+			}
+		}
+		chunks = append(chunks, text[:cut])
+		text = strings.TrimLeft(text[cut:], "\n")
+	}
+	return append(chunks, text)
+}
@@ -0,0 +1,175 @@
+// Package telegram is a chat channel for a Telegram bot, so a daemon, for
+// example one on a VPS, can be chatted with from a phone. Messages from
+// allowed users are delivered to the agent; each chat continues its own
+// conversation until /new.
+package telegram
+
+import (
+	"context"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"opperator/config"
+	"opperator/internal/channel"
+)
+
+const (
+	// progressInterval throttles status message edits.
+	progressInterval = 2 * time.Second
+	// progressLines is how many recent progress lines the status shows.
+	progressLines = 6
+)
+
+const helpText = "Send a message to talk to the agent. /new starts a fresh conversation."
+
+// Channel is a Telegram bot as a chat channel.
+type Channel struct {
+	cfg    config.TelegramConfig
+	api    *client
+	offset int64
+}
+
+func New(cfg config.TelegramConfig, token string) *Channel {
+	return &Channel{cfg: cfg, api: &client{token: token}}
+}
+
+// Receive long-polls for updates until polling fails or ctx is cancelled.
+func (c *Channel) Receive(ctx context.Context, deliver func(context.Context, channel.Message) error) error {
+	// Chats are answered in the background so polling continues; wait for
+	// them before returning.
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		updates, err := c.api.getUpdates(ctx, c.offset)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		for _, u := range updates {
+			c.offset = u.UpdateID + 1
+			if u.Message == nil {
+				continue
+			}
+			msg, ok := c.accept(ctx, u.Message)
+			if !ok {
+				continue
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				deliver(ctx, msg)
+			}()
+		}
+	}
+}
+
+// accept turns a Telegram message into a channel message, answering
+// /start and /help itself.
+func (c *Channel) accept(ctx context.Context, msg *message) (channel.Message, bool) {
+	text := strings.TrimSpace(msg.Text)
+	if msg.From == nil || text == "" {
+		return channel.Message{}, false
+	}
+	if !slices.Contains(c.cfg.AllowedUsers, msg.From.ID) {
+		log.Printf("[Telegram] Ignoring message from unlisted user %d", msg.From.ID)
+		return channel.Message{}, false
+	}
+
+	out := channel.Message{
+		Thread: strconv.FormatInt(msg.Chat.ID, 10),
+		Sender: strconv.FormatInt(msg.From.ID, 10),
+		Text:   text,
+		Reply:  msg.Chat.ID,
+	}
+	command, _, _ := strings.Cut(text, " ")
+	command, _, _ = strings.Cut(command, "@")
+	switch command {
+	case "/start", "/help":
+		if _, err := c.api.sendMessage(ctx, msg.Chat.ID, helpText); err != nil {
+			log.Printf("[Telegram] Failed to send message to chat %d: %v", msg.Chat.ID, err)
+		}
+		return channel.Message{}, false
+	case "/new":
+		out.Reset = true
+	}
+	return out, true
+}
+
+// Send sends text to the message's chat.
+func (c *Channel) Send(ctx context.Context, msg channel.Message, text string) error {
+	if strings.TrimSpace(text) == "" {
+		text = "(no response)"
+	}
+	_, err := c.api.sendMessage(ctx, msg.Reply.(int64), text)
+	return err
+}
+
+// Progress sends a status message to the chat and keeps it updated until
+// the answer arrives.
+func (c *Channel) Progress(ctx context.Context, msg channel.Message) channel.Progress {
+	chatID := msg.Reply.(int64)
+	id, err := c.api.sendMessage(ctx, chatID, "Working…")
+	if err != nil {
+		log.Printf("[Telegram] Failed to send status to chat %d: %v", chatID, err)
+	}
+	return &status{ctx: ctx, api: c.api, chatID: chatID, messageID: id}
+}
+
+// status edits a chat's status message with the latest progress lines, at
+// most once per progressInterval.
+type status struct {
+	ctx       context.Context
+	api       *client
+	chatID    int64
+	messageID int64
+
+	mu      sync.Mutex
+	lines   []string
+	updated time.Time
+}
+
+func (s *status) Update(line string) {
+	s.mu.Lock()
+	s.lines = append(s.lines, line)
+	if len(s.lines) > progressLines {
+		s.lines = s.lines[len(s.lines)-progressLines:]
+	}
+	if time.Since(s.updated) < progressInterval {
+		s.mu.Unlock()
+		return
+	}
+	s.updated = time.Now()
+	text := "Working…\n" + strings.Join(s.lines, "\n")
+	s.mu.Unlock()
+	s.set(text)
+}
+
+// Done removes the status once answered and leaves the failure otherwise.
+func (s *status) Done(err error) {
+	if err != nil {
+		s.set("Failed: " + err.Error())
+		return
+	}
+	if s.messageID == 0 {
+		return
+	}
+	if err := s.api.deleteMessage(s.ctx, s.chatID, s.messageID); err != nil {
+		log.Printf("[Telegram] Failed to remove status: %v", err)
+	}
+}
+
+func (s *status) set(text string) {
+	if s.messageID == 0 {
+		return
+	}
+	if err := s.api.editMessage(s.ctx, s.chatID, s.messageID, text); err != nil {
+		log.Printf("[Telegram] Failed to update status: %v", err)
+	}
+}
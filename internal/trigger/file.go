@@ -0,0 +1,135 @@
+package trigger
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"opperator/internal/protocol"
+)
+
+// fileSource fires when files under a path are created, written, removed or
+// renamed. Events for the same path within the debounce window are
+// coalesced into one.
+type fileSource struct {
+	path     string
+	pattern  string
+	events   []string
+	debounce time.Duration
+}
+
+var fileOps = map[string]fsnotify.Op{
+	"create": fsnotify.Create,
+	"write":  fsnotify.Write,
+	"remove": fsnotify.Remove,
+	"rename": fsnotify.Rename,
+}
+
+func newFileSource(d protocol.TriggerDescriptor, debounce time.Duration) (*fileSource, error) {
+	path := d.Path
+	if strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		path = filepath.Join(home, path[2:])
+	}
+	events := make([]string, 0, len(d.Events))
+	for _, ev := range d.Events {
+		ev = strings.ToLower(strings.TrimSpace(ev))
+		if _, ok := fileOps[ev]; !ok {
+			return nil, fmt.Errorf("trigger %q: unknown file event %q (expected create, write, remove or rename)", d.Name, ev)
+		}
+		events = append(events, ev)
+	}
+	if d.Pattern != "" {
+		if _, err := filepath.Match(d.Pattern, ""); err != nil {
+			return nil, fmt.Errorf("trigger %q: invalid pattern: %w", d.Name, err)
+		}
+	}
+	return &fileSource{path: path, pattern: d.Pattern, events: events, debounce: debounce}, nil
+}
+
+func (s *fileSource) run(ctx context.Context, emit func(map[string]any) error) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+	if err := watcher.Add(s.path); err != nil {
+		return fmt.Errorf("watch %s: %w", s.path, err)
+	}
+
+	pending := make(map[string]fsnotify.Op)
+	var flush <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return fmt.Errorf("watcher closed")
+			}
+			return err
+		case ev, ok := <-watcher.Events:
+			if !ok {
+				return fmt.Errorf("watcher closed")
+			}
+			if !s.matches(ev) {
+				continue
+			}
+			pending[ev.Name] |= ev.Op
+			flush = time.After(s.debounce)
+		case <-flush:
+			flush = nil
+			for path, op := range pending {
+				delete(pending, path)
+				event := map[string]any{
+					"path":   path,
+					"events": opNames(op),
+				}
+				if info, err := os.Stat(path); err == nil {
+					event["size"] = info.Size()
+					event["modified"] = info.ModTime().UTC().Format(time.RFC3339)
+				}
+				if err := emit(event); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+func (s *fileSource) matches(ev fsnotify.Event) bool {
+	if s.pattern != "" {
+		if ok, _ := filepath.Match(s.pattern, filepath.Base(ev.Name)); !ok {
+			return false
+		}
+	}
+	if len(s.events) == 0 {
+		return ev.Op&(fsnotify.Create|fsnotify.Write|fsnotify.Remove|fsnotify.Rename) != 0
+	}
+	for _, name := range s.events {
+		if ev.Op.Has(fileOps[name]) {
+			return true
+		}
+	}
+	return false
+}
+
+func opNames(op fsnotify.Op) []string {
+	var names []string
+	for name, bit := range fileOps {
+		if op.Has(bit) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
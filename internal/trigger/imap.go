@@ -0,0 +1,77 @@
+package trigger
+
+import (
+	"context"
+	"time"
+
+	"opperator/internal/imap"
+)
+
+// imapSource fires once per unseen message in a mailbox and flags it seen
+// after the command was queued.
+type imapSource struct {
+	address  string
+	username string
+	password string
+	mailbox  string
+	interval time.Duration
+}
+
+func (s *imapSource) run(ctx context.Context, emit func(map[string]any) error) error {
+	for {
+		if err := s.poll(ctx, emit); err != nil {
+			return err
+		}
+		if !sleep(ctx, s.interval) {
+			return nil
+		}
+	}
+}
+
+func (s *imapSource) poll(ctx context.Context, emit func(map[string]any) error) error {
+	client, err := imap.Dial(ctx, s.address)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	if err := client.Login(s.username, s.password); err != nil {
+		return err
+	}
+	if err := client.Select(s.mailbox); err != nil {
+		return err
+	}
+	uids, err := client.SearchUnseen()
+	if err != nil {
+		return err
+	}
+	for _, uid := range uids {
+		if ctx.Err() != nil {
+			return nil
+		}
+		raw, err := client.Fetch(uid)
+		if err != nil {
+			return err
+		}
+		msg, err := imap.ParseMessage(raw)
+		if err != nil {
+			return err
+		}
+		event := map[string]any{
+			"uid":         uid,
+			"from":        msg.From,
+			"to":          msg.To,
+			"subject":     msg.Subject,
+			"date":        msg.Date,
+			"message_id":  msg.MessageID,
+			"in_reply_to": msg.InReplyTo,
+			"text":        msg.Text,
+		}
+		if err := emit(event); err != nil {
+			return err
+		}
+		if err := client.MarkSeen(uid); err != nil {
+			return err
+		}
+	}
+	return client.Logout()
+}
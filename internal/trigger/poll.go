@@ -0,0 +1,194 @@
+package trigger
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxBodySize caps how much of a polled response is read and passed on.
+const maxBodySize = 1 << 20
+
+// maxSeenItems bounds the feed item IDs remembered by an rss trigger.
+const maxSeenItems = 1000
+
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// pollSource fetches a URL on an interval and fires when the body changes.
+// The first fetch only records the baseline.
+type pollSource struct {
+	url      string
+	headers  map[string]string
+	interval time.Duration
+}
+
+func (s *pollSource) run(ctx context.Context, emit func(map[string]any) error) error {
+	var last string
+	for {
+		body, resp, err := fetch(ctx, s.url, s.headers)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(body)
+		hash := hex.EncodeToString(sum[:])
+		if last != "" && hash != last {
+			event := map[string]any{
+				"url":          s.url,
+				"status":       resp.StatusCode,
+				"content_type": resp.Header.Get("Content-Type"),
+				"body":         string(body),
+				"hash":         hash,
+			}
+			if err := emit(event); err != nil {
+				return err
+			}
+		}
+		last = hash
+		if !sleep(ctx, s.interval) {
+			return nil
+		}
+	}
+}
+
+// rssSource fires once for each new item of an RSS or Atom feed. Items
+// present on the first fetch are treated as already seen.
+type rssSource struct {
+	url      string
+	headers  map[string]string
+	interval time.Duration
+}
+
+type feedItem struct {
+	ID        string
+	Title     string
+	Link      string
+	Summary   string
+	Published string
+}
+
+func (s *rssSource) run(ctx context.Context, emit func(map[string]any) error) error {
+	seen := make(map[string]bool)
+	var order []string
+	primed := false
+	for {
+		body, _, err := fetch(ctx, s.url, s.headers)
+		if err != nil {
+			return err
+		}
+		items, err := parseFeed(body)
+		if err != nil {
+			return err
+		}
+		// Feeds list newest first; fire oldest first.
+		for i := len(items) - 1; i >= 0; i-- {
+			item := items[i]
+			if seen[item.ID] {
+				continue
+			}
+			if primed {
+				event := map[string]any{
+					"feed":      s.url,
+					"id":        item.ID,
+					"title":     item.Title,
+					"link":      item.Link,
+					"summary":   item.Summary,
+					"published": item.Published,
+				}
+				if err := emit(event); err != nil {
+					return err
+				}
+			}
+			seen[item.ID] = true
+			order = append(order, item.ID)
+		}
+		for len(order) > maxSeenItems {
+			delete(seen, order[0])
+			order = order[1:]
+		}
+		primed = true
+		if !sleep(ctx, s.interval) {
+			return nil
+		}
+	}
+}
+
+func fetch(ctx context.Context, url string, headers map[string]string) ([]byte, *http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return nil, nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	if err != nil {
+		return nil, nil, err
+	}
+	return body, resp, nil
+}
+
+// parseFeed reads RSS 2.0 channel items or Atom entries.
+func parseFeed(data []byte) ([]feedItem, error) {
+	var doc struct {
+		XMLName xml.Name
+		Channel struct {
+			Items []struct {
+				GUID        string `xml:"guid"`
+				Title       string `xml:"title"`
+				Link        string `xml:"link"`
+				Description string `xml:"description"`
+				PubDate     string `xml:"pubDate"`
+			} `xml:"item"`
+		} `xml:"channel"`
+		Entries []struct {
+			ID      string `xml:"id"`
+			Title   string `xml:"title"`
+			Summary string `xml:"summary"`
+			Updated string `xml:"updated"`
+			Links   []struct {
+				Href string `xml:"href,attr"`
+			} `xml:"link"`
+		} `xml:"entry"`
+	}
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse feed: %w", err)
+	}
+
+	var items []feedItem
+	for _, it := range doc.Channel.Items {
+		id := strings.TrimSpace(it.GUID)
+		if id == "" {
+			id = strings.TrimSpace(it.Link)
+		}
+		if id == "" {
+			id = strings.TrimSpace(it.Title)
+		}
+		items = append(items, feedItem{ID: id, Title: it.Title, Link: it.Link, Summary: it.Description, Published: it.PubDate})
+	}
+	for _, e := range doc.Entries {
+		link := ""
+		if len(e.Links) > 0 {
+			link = e.Links[0].Href
+		}
+		id := strings.TrimSpace(e.ID)
+		if id == "" {
+			id = link
+		}
+		items = append(items, feedItem{ID: id, Title: e.Title, Link: link, Summary: e.Summary, Published: e.Updated})
+	}
+	return items, nil
+}
@@ -0,0 +1,134 @@
+package trigger
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// redisPopTimeout is how long one BLPOP blocks before it is reissued, which
+// bounds how long cancelling the trigger takes.
+const redisPopTimeout = 5 * time.Second
+
+// redisSource pops values from a Redis list with BLPOP, speaking RESP
+// directly to avoid a client dependency. A value popped while the command
+// cannot be queued is pushed back to the head of the list.
+type redisSource struct {
+	address  string
+	password string
+	db       int
+	key      string
+}
+
+type redisConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func (s *redisSource) run(ctx context.Context, emit func(map[string]any) error) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", s.address)
+	if err != nil {
+		return fmt.Errorf("redis dial %s: %w", s.address, err)
+	}
+	defer conn.Close()
+	rc := &redisConn{conn: conn, r: bufio.NewReader(conn)}
+
+	if s.password != "" {
+		if _, err := rc.do("AUTH", s.password); err != nil {
+			return err
+		}
+	}
+	if s.db != 0 {
+		if _, err := rc.do("SELECT", strconv.Itoa(s.db)); err != nil {
+			return err
+		}
+	}
+
+	for ctx.Err() == nil {
+		reply, err := rc.do("BLPOP", s.key, strconv.Itoa(int(redisPopTimeout.Seconds())))
+		if err != nil {
+			return err
+		}
+		pair, ok := reply.([]any)
+		if !ok || len(pair) != 2 {
+			// Timed out with a nil reply.
+			continue
+		}
+		value, _ := pair[1].(string)
+		if err := emit(map[string]any{"key": s.key, "value": value}); err != nil {
+			if _, pushErr := rc.do("LPUSH", s.key, value); pushErr != nil {
+				return fmt.Errorf("%w (and failed to requeue value: %v)", err, pushErr)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// do sends a command and reads its reply.
+func (c *redisConn) do(args ...string) (any, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	c.conn.SetDeadline(time.Now().Add(redisPopTimeout + 10*time.Second))
+	if _, err := io.WriteString(c.conn, b.String()); err != nil {
+		return nil, fmt.Errorf("redis write: %w", err)
+	}
+	return c.read()
+}
+
+func (c *redisConn) read() (any, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("redis read: %w", err)
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		return nil, fmt.Errorf("redis: empty reply")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, fmt.Errorf("redis: %s", line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis: bad bulk length %q", line)
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(c.r, buf); err != nil {
+			return nil, fmt.Errorf("redis read: %w", err)
+		}
+		return string(buf[:size]), nil
+	case '*':
+		count, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("redis: bad array length %q", line)
+		}
+		if count < 0 {
+			return nil, nil
+		}
+		items := make([]any, count)
+		for i := range items {
+			if items[i], err = c.read(); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("redis: unexpected reply %q", line)
+}
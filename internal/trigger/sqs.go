@@ -0,0 +1,125 @@
+package trigger
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"opperator/internal/awssig"
+)
+
+// sqsWaitSeconds is the long-poll duration of each ReceiveMessage call.
+const sqsWaitSeconds = 20
+
+// sqsSource long-polls an SQS queue through the query API, signing requests
+// with AWS Signature Version 4 from the AWS_ACCESS_KEY_ID,
+// AWS_SECRET_ACCESS_KEY and optional AWS_SESSION_TOKEN environment variables.
+// Messages are deleted once their command is queued; otherwise they become
+// visible again after the queue's visibility timeout.
+type sqsSource struct {
+	queueURL *url.URL
+	region   string
+}
+
+type sqsMessage struct {
+	MessageID     string `xml:"MessageId"`
+	ReceiptHandle string `xml:"ReceiptHandle"`
+	Body          string `xml:"Body"`
+	Attributes    []struct {
+		Name  string `xml:"Name"`
+		Value string `xml:"Value"`
+	} `xml:"Attribute"`
+}
+
+func newSQSSource(queueURL, region string) (*sqsSource, error) {
+	u, err := url.Parse(queueURL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid queue_url %q", queueURL)
+	}
+	return &sqsSource{queueURL: u, region: region}, nil
+}
+
+func (s *sqsSource) run(ctx context.Context, emit func(map[string]any) error) error {
+	if err := awssig.CheckEnv(); err != nil {
+		return fmt.Errorf("%w for sqs triggers", err)
+	}
+	for ctx.Err() == nil {
+		var resp struct {
+			Messages []sqsMessage `xml:"ReceiveMessageResult>Message"`
+		}
+		err := s.call(ctx, url.Values{
+			"Action":              {"ReceiveMessage"},
+			"MaxNumberOfMessages": {"10"},
+			"WaitTimeSeconds":     {fmt.Sprint(sqsWaitSeconds)},
+			"AttributeName.1":     {"All"},
+		}, &resp)
+		if err != nil {
+			return err
+		}
+		for _, msg := range resp.Messages {
+			attrs := make(map[string]string, len(msg.Attributes))
+			for _, a := range msg.Attributes {
+				attrs[a.Name] = a.Value
+			}
+			event := map[string]any{
+				"queue":      s.queueURL.String(),
+				"message_id": msg.MessageID,
+				"body":       msg.Body,
+				"attributes": attrs,
+			}
+			if err := emit(event); err != nil {
+				return err
+			}
+			if err := s.call(ctx, url.Values{
+				"Action":        {"DeleteMessage"},
+				"ReceiptHandle": {msg.ReceiptHandle},
+			}, nil); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// call POSTs a signed query API action to the queue URL and decodes the XML
+// response into out when it is non-nil.
+func (s *sqsSource) call(ctx context.Context, params url.Values, out any) error {
+	params.Set("Version", "2012-11-05")
+	body := params.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.queueURL.String(), strings.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	awssig.Sign(req, []byte(body), "sqs", s.region, time.Now())
+
+	client := &http.Client{Timeout: (sqsWaitSeconds + 10) * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		var errResp struct {
+			Code    string `xml:"Error>Code"`
+			Message string `xml:"Error>Message"`
+		}
+		if xml.Unmarshal(data, &errResp) == nil && errResp.Code != "" {
+			return fmt.Errorf("sqs %s: %s: %s", params.Get("Action"), errResp.Code, errResp.Message)
+		}
+		return fmt.Errorf("sqs %s: %s", params.Get("Action"), resp.Status)
+	}
+	if out == nil {
+		return nil
+	}
+	return xml.Unmarshal(data, out)
+}
@@ -0,0 +1,300 @@
+// Package trigger watches external event sources (files, URLs, feeds,
+// inboxes and queues) and invokes agent commands with each event. Triggers
+// come from daemon.yaml or are registered by running agents.
+package trigger
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"opperator/internal/protocol"
+)
+
+// OwnerConfig owns the triggers declared in daemon.yaml. Agent-registered
+// triggers are owned by the agent name.
+const OwnerConfig = "config"
+
+const (
+	defaultInterval = time.Minute
+	minBackoff      = 5 * time.Second
+	maxBackoff      = 5 * time.Minute
+)
+
+// Trigger is a descriptor bound to the agent whose command it invokes.
+type Trigger struct {
+	protocol.TriggerDescriptor
+	Owner string
+	Agent string
+}
+
+// Invoker queues Command of t with event as its payload. Sources only
+// acknowledge an event (delete a queue message, flag a mail) once it returns
+// nil.
+type Invoker func(ctx context.Context, t Trigger, event map[string]any) error
+
+// Status reports the state of a trigger for 'op trigger list'.
+type Status struct {
+	Name      string
+	Owner     string
+	Agent     string
+	Type      string
+	Command   string
+	Fired     int64
+	LastFired time.Time
+	LastError string
+}
+
+// source produces events until ctx is cancelled or it fails.
+type source interface {
+	run(ctx context.Context, emit func(event map[string]any) error) error
+}
+
+type active struct {
+	trigger Trigger
+	cancel  context.CancelFunc
+	done    chan struct{}
+
+	mu        sync.Mutex
+	fired     int64
+	lastFired time.Time
+	lastError string
+}
+
+// Manager runs the registered triggers.
+type Manager struct {
+	ctx    context.Context
+	invoke Invoker
+
+	mu     sync.Mutex
+	active map[string]*active
+}
+
+func NewManager(ctx context.Context, invoke Invoker) *Manager {
+	return &Manager{ctx: ctx, invoke: invoke, active: make(map[string]*active)}
+}
+
+// Replace stops every trigger of owner and starts triggers in its place.
+// Passing none removes the owner's triggers.
+func (m *Manager) Replace(owner string, triggers []Trigger) {
+	m.mu.Lock()
+	var stopping []*active
+	for key, a := range m.active {
+		if a.trigger.Owner == owner {
+			a.cancel()
+			stopping = append(stopping, a)
+			delete(m.active, key)
+		}
+	}
+	for _, t := range triggers {
+		t.Owner = owner
+		key := owner + "/" + t.Name
+		if _, dup := m.active[key]; dup {
+			log.Printf("[Trigger] Ignoring duplicate trigger %q from %s", t.Name, owner)
+			continue
+		}
+		ctx, cancel := context.WithCancel(m.ctx)
+		a := &active{trigger: t, cancel: cancel, done: make(chan struct{})}
+		m.active[key] = a
+		go m.run(ctx, a)
+	}
+	m.mu.Unlock()
+
+	for _, a := range stopping {
+		<-a.done
+	}
+	if len(triggers) > 0 || len(stopping) > 0 {
+		log.Printf("[Trigger] %s now has %d trigger(s)", owner, len(triggers))
+	}
+}
+
+// List returns the status of every trigger sorted by owner and name.
+func (m *Manager) List() []Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]Status, 0, len(m.active))
+	for _, a := range m.active {
+		a.mu.Lock()
+		out = append(out, Status{
+			Name:      a.trigger.Name,
+			Owner:     a.trigger.Owner,
+			Agent:     a.trigger.Agent,
+			Type:      a.trigger.Type,
+			Command:   a.trigger.Command,
+			Fired:     a.fired,
+			LastFired: a.lastFired,
+			LastError: a.lastError,
+		})
+		a.mu.Unlock()
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Owner != out[j].Owner {
+			return out[i].Owner < out[j].Owner
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+// Stop cancels every trigger and waits for them to exit.
+func (m *Manager) Stop() {
+	m.mu.Lock()
+	all := make([]*active, 0, len(m.active))
+	for key, a := range m.active {
+		a.cancel()
+		all = append(all, a)
+		delete(m.active, key)
+	}
+	m.mu.Unlock()
+	for _, a := range all {
+		<-a.done
+	}
+}
+
+// run keeps a trigger's source running, restarting it with backoff after
+// failures.
+func (m *Manager) run(ctx context.Context, a *active) {
+	defer close(a.done)
+	src, err := newSource(a.trigger.TriggerDescriptor)
+	if err != nil {
+		a.setError(err)
+		log.Printf("[Trigger] %s/%s disabled: %v", a.trigger.Owner, a.trigger.Name, err)
+		return
+	}
+
+	emit := func(event map[string]any) error {
+		if err := m.invoke(ctx, a.trigger, event); err != nil {
+			a.setError(err)
+			return err
+		}
+		a.mu.Lock()
+		a.fired++
+		a.lastFired = time.Now()
+		a.lastError = ""
+		a.mu.Unlock()
+		return nil
+	}
+
+	backoff := minBackoff
+	for {
+		started := time.Now()
+		err := src.run(ctx, emit)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			a.setError(err)
+			log.Printf("[Trigger] %s/%s failed: %v (retrying in %s)", a.trigger.Owner, a.trigger.Name, err, backoff)
+		}
+		if time.Since(started) > maxBackoff {
+			backoff = minBackoff
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+func (a *active) setError(err error) {
+	a.mu.Lock()
+	a.lastError = err.Error()
+	a.mu.Unlock()
+}
+
+// Validate checks that a descriptor has the fields its type needs.
+func Validate(d protocol.TriggerDescriptor) error {
+	_, err := newSource(d)
+	return err
+}
+
+func newSource(d protocol.TriggerDescriptor) (source, error) {
+	if strings.TrimSpace(d.Name) == "" {
+		return nil, fmt.Errorf("trigger name is required")
+	}
+	if strings.TrimSpace(d.Command) == "" {
+		return nil, fmt.Errorf("trigger %q: command is required", d.Name)
+	}
+	interval, err := parseDuration(d.Interval, defaultInterval)
+	if err != nil {
+		return nil, fmt.Errorf("trigger %q: invalid interval: %w", d.Name, err)
+	}
+
+	require := func(fields ...string) error {
+		for i := 0; i < len(fields); i += 2 {
+			if strings.TrimSpace(fields[i+1]) == "" {
+				return fmt.Errorf("trigger %q: %s is required for %s triggers", d.Name, fields[i], d.Type)
+			}
+		}
+		return nil
+	}
+
+	switch strings.ToLower(strings.TrimSpace(d.Type)) {
+	case protocol.TriggerFile:
+		if err := require("path", d.Path); err != nil {
+			return nil, err
+		}
+		debounce, err := parseDuration(d.Debounce, 500*time.Millisecond)
+		if err != nil {
+			return nil, fmt.Errorf("trigger %q: invalid debounce: %w", d.Name, err)
+		}
+		return newFileSource(d, debounce)
+	case protocol.TriggerPoll:
+		if err := require("url", d.URL); err != nil {
+			return nil, err
+		}
+		return &pollSource{url: d.URL, headers: d.Headers, interval: interval}, nil
+	case protocol.TriggerRSS:
+		if err := require("url", d.URL); err != nil {
+			return nil, err
+		}
+		return &rssSource{url: d.URL, headers: d.Headers, interval: interval}, nil
+	case protocol.TriggerIMAP:
+		if err := require("address", d.Address, "username", d.Username, "password", d.Password); err != nil {
+			return nil, err
+		}
+		return &imapSource{address: d.Address, username: d.Username, password: d.Password, mailbox: d.Mailbox, interval: interval}, nil
+	case protocol.TriggerSQS:
+		if err := require("queue_url", d.QueueURL, "region", d.Region); err != nil {
+			return nil, err
+		}
+		return newSQSSource(d.QueueURL, d.Region)
+	case protocol.TriggerRedis:
+		if err := require("address", d.Address, "key", d.Key); err != nil {
+			return nil, err
+		}
+		return &redisSource{address: d.Address, password: d.Password, db: d.DB, key: d.Key}, nil
+	default:
+		return nil, fmt.Errorf("trigger %q: unknown type %q (expected file, poll, rss, imap, sqs or redis)", d.Name, d.Type)
+	}
+}
+
+func parseDuration(value string, fallback time.Duration) (time.Duration, error) {
+	if strings.TrimSpace(value) == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(value))
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("must be positive")
+	}
+	return d, nil
+}
+
+// sleep waits for d or until ctx is done, reporting whether to continue.
+func sleep(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
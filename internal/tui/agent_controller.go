@@ -13,6 +13,7 @@ import (
 	tea "github.com/charmbracelet/bubbletea/v2"
 	"github.com/google/uuid"
 
+	"opperator/config"
 	"tui/commands"
 	"tui/coreagent"
 	"tui/internal/conversation"
@@ -43,6 +44,13 @@ type agentController struct {
 	activeCommands      []protocol.CommandDescriptor
 	activeColor         string
 
+	// Slash commands of every running agent, by agent name, for global
+	// scopes, namespaced names and aliases
+	agentSlashCommands     map[string][]protocol.CommandDescriptor
+	slashAliases           []config.SlashAlias
+	slashAliasErr          error
+	reportedSlashConflicts map[string]struct{}
+
 	// Focused agent tracking for Builder command inheritance
 	focusedAgentName     string
 	focusedAgentCommands []protocol.CommandDescriptor
@@ -72,6 +80,7 @@ func newAgentController(
 		refreshSidebar:       noOpCmd(refreshSidebar),
 		setInputAgentID:      noOpStr(setInputAgentID),
 	}
+	ctrl.slashAliases, ctrl.slashAliasErr = config.LoadSlashAliases()
 	ctrl.resetActiveAgentState()
 	return ctrl
 }
@@ -144,8 +153,7 @@ func (c *agentController) resetActiveAgentState() {
 	c.activePromptReplace = false
 	c.activeCommands = nil
 	c.activeColor = ""
-	commands.SetLocal(nil)
-	commands.SetGlobal(nil)
+	c.rebuildSlashCommands()
 }
 
 func (c *agentController) setFocusedAgent(agentName string) {
@@ -269,9 +277,7 @@ func (c *agentController) applyActiveAgent(meta llm.AgentMetadata, persist bool)
 	c.activeCommands = append([]protocol.CommandDescriptor(nil), meta.Commands...)
 	c.activeColor = meta.Color
 	tooling.BuildAgentCommandTools(meta.Name, c.activeCommands)
-	localCmds, globalCmds := c.buildSlashCommandsForAgent(meta.Name, c.activeCommands)
-	commands.SetGlobal(globalCmds)
-	commands.SetLocal(localCmds)
+	c.rebuildSlashCommands()
 	if persist && c.convStore != nil && strings.TrimSpace(c.sessionID) != "" {
 		_ = c.convStore.UpdateActiveAgent(context.Background(), c.sessionID, meta.Name)
 		c.refreshConversations()
@@ -303,9 +309,7 @@ func (c *agentController) updateActiveAgentCommands(agentName string, cmds []pro
 
 	c.activeCommands = append([]protocol.CommandDescriptor(nil), normalized...)
 	tooling.BuildAgentCommandTools(c.activeName, c.activeCommands)
-	localCmds, globalCmds := c.buildSlashCommandsForAgent(c.activeName, c.activeCommands)
-	commands.SetGlobal(globalCmds)
-	commands.SetLocal(localCmds)
+	c.rebuildSlashCommands()
 	c.refreshHelp()
 	c.refreshSidebar()
 	return true
@@ -428,73 +432,40 @@ func (c *agentController) invokeAgentCommand(agentName, commandName string, args
 	}
 }
 
-func (c *agentController) buildSlashCommandsForAgent(agentName string, cmds []protocol.CommandDescriptor) ([]commands.Command, []commands.Command) {
-	trimmedAgent := strings.TrimSpace(agentName)
-	if trimmedAgent == "" || len(cmds) == 0 {
-		return nil, nil
+// slashCommandFor builds the palette entry invoking cmd of an agent under
+// the given slash name.
+func slashCommandFor(agentName string, cmd protocol.CommandDescriptor, name string) commands.Command {
+	commandName := strings.TrimSpace(cmd.Name)
+	title := strings.TrimSpace(cmd.Title)
+	if title == "" {
+		title = commandName
 	}
 
-	local := make([]commands.Command, 0, len(cmds))
-	global := make([]commands.Command, 0, len(cmds))
-
-	for _, cmd := range cmds {
-		if !hasSlashExposure(cmd) {
-			continue
-		}
-
-		slash := strings.TrimSpace(cmd.SlashCommand)
-		if slash == "" {
-			continue
-		}
-
-		commandName := strings.TrimSpace(cmd.Name)
-		if commandName == "" {
-			continue
-		}
-
-		title := strings.TrimSpace(cmd.Title)
-		if title == "" {
-			title = commandName
-		}
+	description := strings.TrimSpace(cmd.Description)
+	if description == "" {
+		description = fmt.Sprintf("Invoke %s on sub-agent %s", title, agentName)
+	}
 
-		description := strings.TrimSpace(cmd.Description)
-		if description == "" {
-			description = fmt.Sprintf("Invoke %s on sub-agent %s", title, trimmedAgent)
-		}
+	argumentSchema := cmd.Arguments // Capture the argument schema
+	isAsync := cmd.Async            // Capture async flag
 
-		requiresArg := cmd.ArgumentRequired
-		hint := strings.TrimSpace(cmd.ArgumentHint)
-		agentCopy := trimmedAgent
-		commandCopy := commandName
-		argumentSchema := cmd.Arguments // Capture the argument schema
-		isAsync := cmd.Async            // Capture async flag
-
-		command := commands.Command{
-			Name:             slash,
-			Description:      description,
-			RequiresArgument: requiresArg,
-			ArgumentHint:     hint,
-			Action: func(ctx commands.Context, argument string) tea.Cmd {
-				trimmed := strings.TrimSpace(argument)
-
-				// Commands with argument schema use LLM-powered parser
-				if len(argumentSchema) > 0 {
-					return parseSlashCommandArgumentsCmd(agentCopy, commandCopy, trimmed, argumentSchema, isAsync)
-				}
+	return commands.Command{
+		Name:             name,
+		Description:      description,
+		RequiresArgument: cmd.ArgumentRequired,
+		ArgumentHint:     strings.TrimSpace(cmd.ArgumentHint),
+		Action: func(ctx commands.Context, argument string) tea.Cmd {
+			trimmed := strings.TrimSpace(argument)
 
-				// Commands with no arguments - invoke directly
-				return ctx.InvokeAgentCommand(agentCopy, commandCopy, nil)
-			},
-		}
+			// Commands with argument schema use LLM-powered parser
+			if len(argumentSchema) > 0 {
+				return parseSlashCommandArgumentsCmd(agentName, commandName, trimmed, argumentSchema, isAsync)
+			}
 
-		if cmd.SlashScope == protocol.SlashCommandScopeGlobal {
-			global = append(global, command)
-		} else {
-			local = append(local, command)
-		}
+			// Commands with no arguments - invoke directly
+			return ctx.InvokeAgentCommand(agentName, commandName, nil)
+		},
 	}
-
-	return local, global
 }
 
 func (c *agentController) handleAgentCommand(target string) tea.Cmd {
@@ -710,6 +681,12 @@ func (m *Model) InvokeAgentCommand(agentName, commandName string, args map[strin
 	if m.agents == nil {
 		return util.ReportWarn("Agent support unavailable")
 	}
+	label := fmt.Sprintf("command %s", strings.TrimSpace(commandName))
+	if queued := m.queueIfOffline(agentName, label, func() tea.Cmd {
+		return m.InvokeAgentCommand(agentName, commandName, args)
+	}); queued != nil {
+		return queued
+	}
 	if desc, ok := m.agents.commandDescriptor(agentName, commandName); ok && desc.Async {
 		return m.scheduleAsyncAgentCommand(agentName, desc, args)
 	}
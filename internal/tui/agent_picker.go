@@ -324,7 +324,7 @@ func (p *agentPicker) View() string {
 				} else {
 					descStyle = lipgloss.NewStyle().Foreground(theme.Success)
 				}
-			case "crashed":
+			case "crashed", "crashlooping":
 				if i == p.index {
 					descStyle = theme.S().SelectedBase.Foreground(theme.Error)
 				} else {
@@ -817,7 +817,7 @@ func (m *Model) handleCycleAgentResult(msg cycleAgentResultMsg) tea.Cmd {
 
 	// Clear custom sections before switching agents to prevent stale sections from persisting
 	if m.sidebar != nil {
-		m.sidebar.SetCustomSections(nil)
+		m.sidebar.SetCustomSections("", nil)
 	}
 
 	_ = m.refreshSidebar()
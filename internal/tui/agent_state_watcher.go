@@ -33,6 +33,7 @@ type agentStateEventMsg struct {
 	CustomSections      []cmpsidebar.CustomSection
 	Status              string
 	Commands            []protocol.CommandDescriptor
+	Capabilities        *protocol.Capabilities
 	Daemon              string // NEW: Which daemon this event came from
 }
 
@@ -126,15 +127,47 @@ func (m *Model) autoDisableDaemon(daemonName string) error {
 	return config.SaveDaemonRegistry(registry)
 }
 
-// watchSingleDaemon watches agent state events from a single daemon
+// watchSingleDaemon watches agent state events from a single daemon. When
+// the stream drops, e.g. while the daemon restarts, it reports the daemon
+// disconnected and reconnects with exponential backoff.
 func (m *Model) watchSingleDaemon(ctx context.Context, daemonName string, eventCh chan<- agentStateEventMsg) {
+	delay := reconnectMinDelay
+	online := true
+	for {
+		m.streamAgentState(ctx, daemonName, eventCh, func() {
+			delay = reconnectMinDelay
+			if !online {
+				online = true
+				sendConnectionEvent(ctx, eventCh, daemonName, connectionUp)
+			}
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		if online {
+			online = false
+			sendConnectionEvent(ctx, eventCh, daemonName, connectionDown)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = nextReconnectDelay(delay)
+	}
+}
+
+// streamAgentState forwards the agent state events of one connection to
+// daemonName until it ends. onConnect runs once the daemon accepted the
+// subscription.
+func (m *Model) streamAgentState(ctx context.Context, daemonName string, eventCh chan<- agentStateEventMsg, onConnect func()) {
 	payload := struct {
 		Type string `json:"type"`
 	}{Type: "watch_agent_state"}
 
 	conn, cleanup, err := tools.OpenStreamToDaemon(ctx, daemonName, payload)
 	if err != nil {
-		// Silently fail - daemon may be offline
 		return
 	}
 	defer cleanup()
@@ -155,6 +188,7 @@ func (m *Model) watchSingleDaemon(ctx context.Context, daemonName string, eventC
 	if err := json.Unmarshal(scanner.Bytes(), &resp); err != nil || !resp.Success {
 		return
 	}
+	onConnect()
 
 	// Read events and send to shared channel
 	for scanner.Scan() {
@@ -170,6 +204,7 @@ func (m *Model) watchSingleDaemon(ctx context.Context, daemonName string, eventC
 			CustomSections      interface{}                  `json:"custom_sections,omitempty"`
 			Status              string                       `json:"status,omitempty"`
 			Commands            []protocol.CommandDescriptor `json:"commands,omitempty"`
+			Capabilities        *protocol.Capabilities       `json:"capabilities,omitempty"`
 		}
 
 		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
@@ -196,6 +231,11 @@ func (m *Model) watchSingleDaemon(ctx context.Context, daemonName string, eventC
 						if collapsed, ok := sMap["collapsed"].(bool); ok {
 							section.Collapsed = collapsed
 						}
+						if actions, ok := sMap["actions"]; ok && actions != nil {
+							if data, err := json.Marshal(actions); err == nil {
+								_ = json.Unmarshal(data, &section.Actions)
+							}
+						}
 						sections = append(sections, section)
 					}
 				}
@@ -215,6 +255,7 @@ func (m *Model) watchSingleDaemon(ctx context.Context, daemonName string, eventC
 			CustomSections:      sections,
 			Status:              event.Status,
 			Commands:            protocol.NormalizeCommandDescriptors(event.Commands),
+			Capabilities:        event.Capabilities,
 			Daemon:              daemonName, // Tag event with daemon name
 		}:
 		case <-ctx.Done():
@@ -259,7 +300,7 @@ func (m *Model) handleFocusAgentEvent(msg focusAgentEventMsg) tea.Cmd {
 		m.sidebar.SetFocusedAgentDescription("")
 		// Clear custom sections from the previous focused agent
 		// New agent's sections will be fetched via fetchFocusedAgentMetadataCmd
-		m.sidebar.SetCustomSections(nil)
+		m.sidebar.SetCustomSections("", nil)
 	}
 
 	// Persist the focused agent to the conversation
@@ -568,9 +609,25 @@ func (m *Model) handleInitialCustomSections(msg initialCustomSectionsMsg) tea.Cm
 	}
 
 	if shouldSetSections && msg.err == nil && msg.sections != nil {
-		m.sidebar.SetCustomSections(msg.sections)
+		m.sidebar.SetCustomSections(msg.agentName, msg.sections)
 		return m.refreshSidebar()
 	}
 
 	return nil
 }
+
+// supportedSections drops agent-provided sidebar sections when the agent did
+// not negotiate custom sections. Daemon-managed sections ("__" prefix) stay.
+func (m *Model) supportedSections(agentName, daemonName string, sections []cmpsidebar.CustomSection) []cmpsidebar.CustomSection {
+	caps := m.agentCaps[agentStatusKey(agentName, daemonName)]
+	if caps == nil || caps.CustomSections {
+		return sections
+	}
+	filtered := make([]cmpsidebar.CustomSection, 0, len(sections))
+	for _, section := range sections {
+		if strings.HasPrefix(section.ID, "__") {
+			filtered = append(filtered, section)
+		}
+	}
+	return filtered
+}
@@ -122,22 +122,11 @@ func (w *AsyncTaskWatcher) loadFromDB() {
 			continue
 		}
 
-		// Extract label from metadata
-		label := toolName.String
-		if metadata.Valid && metadata.String != "" {
-			var meta map[string]interface{}
-			if err := json.Unmarshal([]byte(metadata.String), &meta); err == nil {
-				if labelStr, ok := meta["label"].(string); ok && labelStr != "" {
-					label = labelStr
-				}
-			}
-		}
-
 		info := AsyncTaskInfo{
 			ID:       id.String,
 			ToolName: toolName.String,
 			Status:   status.String,
-			Label:    strings.TrimSpace(label),
+			Label:    asyncTaskLabel(toolName.String, metadata.String),
 		}
 
 		w.tasks[id.String] = info
@@ -146,80 +135,83 @@ func (w *AsyncTaskWatcher) loadFromDB() {
 	w.sendUpdate()
 }
 
-// streamLoop subscribes to task events from the daemon
+// streamLoop subscribes to task events from the daemon. Whenever the stream
+// drops it reconnects with exponential backoff, and after reconnecting it
+// reloads the tasks from the database, since the events sent in between
+// are lost.
 func (w *AsyncTaskWatcher) streamLoop(ctx context.Context) {
 	if w == nil {
 		return
 	}
 
-	// Keep retrying connection to daemon
+	delay := reconnectMinDelay
+	resync := false
 	for {
+		w.streamTasks(ctx, func() {
+			delay = reconnectMinDelay
+			if resync {
+				w.loadFromDB()
+			}
+		})
+		resync = true
+
 		select {
 		case <-ctx.Done():
 			return
-		default:
-		}
-
-		// Connect to daemon task stream
-		payload := struct {
-			Type string `json:"type"`
-		}{Type: "watch_all_tasks"}
-
-		conn, cleanup, err := tooling.OpenStream(ctx, payload)
-		if err != nil {
-			// Wait before retrying
-			time.Sleep(2 * time.Second)
-			continue
+		case <-time.After(delay):
 		}
+		delay = nextReconnectDelay(delay)
+	}
+}
 
-		// Read events from stream
-		scanner := bufio.NewScanner(conn)
-		buf := make([]byte, 0, 64*1024)
-		scanner.Buffer(buf, 64*1024*1024)
+// streamTasks handles the task events of one connection until it ends.
+// onConnect runs once the daemon accepted the subscription.
+func (w *AsyncTaskWatcher) streamTasks(ctx context.Context, onConnect func()) {
+	payload := struct {
+		Type string `json:"type"`
+	}{Type: "watch_all_tasks"}
 
-		// Read initial success response
-		if !scanner.Scan() {
-			cleanup()
-			time.Sleep(2 * time.Second)
-			continue
-		}
+	conn, cleanup, err := tooling.OpenStream(ctx, payload)
+	if err != nil {
+		return
+	}
+	defer cleanup()
 
-		var resp struct {
-			Success bool   `json:"success"`
-			Error   string `json:"error"`
-		}
-		if err := json.Unmarshal(scanner.Bytes(), &resp); err != nil || !resp.Success {
-			cleanup()
-			time.Sleep(2 * time.Second)
-			continue
-		}
+	// Read events from stream
+	scanner := bufio.NewScanner(conn)
+	buf := make([]byte, 0, 64*1024)
+	scanner.Buffer(buf, 64*1024*1024)
 
-		for scanner.Scan() {
-			var event struct {
-				Type string `json:"type"`
-				Task *struct {
-					ID       string `json:"id"`
-					ToolName string `json:"tool_name"`
-					Status   string `json:"status"`
-					Metadata string `json:"metadata"`
-				} `json:"task"`
-			}
+	// Read initial success response
+	if !scanner.Scan() {
+		return
+	}
 
-			if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
-				continue
-			}
+	var resp struct {
+		Success bool   `json:"success"`
+		Error   string `json:"error"`
+	}
+	if err := json.Unmarshal(scanner.Bytes(), &resp); err != nil || !resp.Success {
+		return
+	}
+	onConnect()
 
-			w.handleTaskEvent(event.Type, event.Task)
+	for scanner.Scan() {
+		var event struct {
+			Type string `json:"type"`
+			Task *struct {
+				ID       string `json:"id"`
+				ToolName string `json:"tool_name"`
+				Status   string `json:"status"`
+				Metadata string `json:"metadata"`
+			} `json:"task"`
 		}
 
-		cleanup()
-
-		// Connection lost, retry after delay
-		select {
-		case <-ctx.Done():
-			return
-		case <-time.After(2 * time.Second):
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			continue
 		}
+
+		w.handleTaskEvent(event.Type, event.Task)
 	}
 }
 
@@ -256,22 +248,11 @@ func (w *AsyncTaskWatcher) handleTaskEvent(eventType string, task *struct {
 		return
 	}
 
-	// Extract label from metadata
-	label := task.ToolName
-	if task.Metadata != "" {
-		var meta map[string]interface{}
-		if err := json.Unmarshal([]byte(task.Metadata), &meta); err == nil {
-			if labelStr, ok := meta["label"].(string); ok && labelStr != "" {
-				label = labelStr
-			}
-		}
-	}
-
 	info := AsyncTaskInfo{
 		ID:       taskID,
 		ToolName: task.ToolName,
 		Status:   task.Status,
-		Label:    strings.TrimSpace(label),
+		Label:    asyncTaskLabel(task.ToolName, task.Metadata),
 	}
 
 	oldInfo, exists := w.tasks[taskID]
@@ -281,6 +262,38 @@ func (w *AsyncTaskWatcher) handleTaskEvent(eventType string, task *struct {
 	}
 }
 
+// asyncTaskLabel picks the display label of a task from its metadata. A
+// workflow shows its current step and overall progress so the whole pipeline
+// reads as one entry.
+func asyncTaskLabel(toolName, metadata string) string {
+	label := toolName
+	if metadata == "" {
+		return strings.TrimSpace(label)
+	}
+	var meta map[string]interface{}
+	if err := json.Unmarshal([]byte(metadata), &meta); err != nil {
+		return strings.TrimSpace(label)
+	}
+	if labelStr, ok := meta["label"].(string); ok && labelStr != "" {
+		return strings.TrimSpace(labelStr)
+	}
+	if raw, ok := meta["last_progress_metadata"].(string); ok {
+		var step struct {
+			Workflow  string `json:"workflow"`
+			Step      string `json:"workflow_step"`
+			Completed int    `json:"completed"`
+			Total     int    `json:"total"`
+		}
+		if err := json.Unmarshal([]byte(raw), &step); err == nil && step.Workflow != "" {
+			label = fmt.Sprintf("workflow %s · %s", step.Workflow, step.Step)
+			if step.Total > 0 {
+				label = fmt.Sprintf("%s (%d/%d)", label, step.Completed, step.Total)
+			}
+		}
+	}
+	return strings.TrimSpace(label)
+}
+
 // sendUpdate sends an update notification
 func (w *AsyncTaskWatcher) sendUpdate() {
 	tasks := make([]AsyncTaskInfo, 0, len(w.tasks))
@@ -654,6 +667,9 @@ func (m *Model) handleAsyncToolUpdate(msg llm.AsyncToolUpdateMsg) tea.Cmd {
 		if cmd := m.recordToolResultsForSession(sessionID, []tooltypes.Result{result}); cmd != nil {
 			cmds = append(cmds, cmd)
 		}
+		if cmd := m.notifyAsyncResult(result); cmd != nil {
+			cmds = append(cmds, cmd)
+		}
 		if callID == "" || !m.sessionManager().ToolResultHandled(context.Background(), sessionID, callID) {
 			if callID != "" {
 				resumeCallIDs = append(resumeCallIDs, callID)
@@ -817,7 +833,7 @@ func isTaskComplete(task *tooling.AsyncTask) bool {
 		return false
 	}
 	status := strings.ToLower(strings.TrimSpace(task.Status))
-	return status == "complete" || status == "failed"
+	return status == "complete" || status == "failed" || status == "skipped"
 }
 
 // isTaskFailed checks if an async task ended in failure
@@ -826,7 +842,7 @@ func isTaskFailed(task *tooling.AsyncTask) bool {
 		return false
 	}
 	status := strings.ToLower(strings.TrimSpace(task.Status))
-	return status == "failed"
+	return status == "failed" || status == "skipped"
 }
 
 // handleSlashCommandAsyncCompletion handles completion of async tasks from slash commands
@@ -919,6 +935,9 @@ func (m *Model) handleSlashCommandAsyncCompletion(msg TaskUpdateMsg) tea.Cmd {
 		if cmd := m.recordToolResultsForSession(m.sessionID, []tooltypes.Result{result}); cmd != nil {
 			cmds = append(cmds, cmd)
 		}
+		if cmd := m.notifyAsyncResult(result); cmd != nil {
+			cmds = append(cmds, cmd)
+		}
 
 		// Mark as handled and remove from pending
 		delete(m.pendingAsyncTasks, ct.taskID)
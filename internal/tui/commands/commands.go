@@ -32,6 +32,11 @@ type Context interface {
 	InvokeAgentCommand(agentName, commandName string, args map[string]any) tea.Cmd
 	GetCurrentCoreAgentID() string
 	ClearFocus()
+	ShowContext()
+	SetMockTools(path string)
+	ShowProtocolTrace(agentName string) tea.Cmd
+	RenameConversation(title string) tea.Cmd
+	ShowTaskStats(arg string) tea.Cmd
 }
 
 var (
@@ -65,6 +70,52 @@ var (
 				return nil
 			},
 		},
+		{
+			Name:        "/context",
+			Description: "show the context files added to the instructions",
+			Scope:       ScopeBase,
+			Action: func(ctx Context, _ string) tea.Cmd {
+				ctx.ShowContext()
+				return nil
+			},
+		},
+		{
+			Name:         "/mock-tools",
+			Description:  "resolve tool calls from a fixtures file instead of running them",
+			Scope:        ScopeBase,
+			ArgumentHint: "[fixtures.yaml|off]",
+			Action: func(ctx Context, arg string) tea.Cmd {
+				ctx.SetMockTools(strings.TrimSpace(arg))
+				return nil
+			},
+		},
+		{
+			Name:         "/trace",
+			Description:  "show the protocol messages recently exchanged with an agent",
+			Scope:        ScopeBase,
+			ArgumentHint: "[agent]",
+			Action: func(ctx Context, arg string) tea.Cmd {
+				return ctx.ShowProtocolTrace(strings.TrimSpace(arg))
+			},
+		},
+		{
+			Name:         "/rename",
+			Description:  "rename the conversation, or have the model title it",
+			Scope:        ScopeBase,
+			ArgumentHint: "[title]",
+			Action: func(ctx Context, arg string) tea.Cmd {
+				return ctx.RenameConversation(strings.TrimSpace(arg))
+			},
+		},
+		{
+			Name:         "/stats",
+			Description:  "chart finished async tasks, failures and durations over time",
+			Scope:        ScopeBase,
+			ArgumentHint: "[hours|days]",
+			Action: func(ctx Context, arg string) tea.Cmd {
+				return ctx.ShowTaskStats(arg)
+			},
+		},
 	}
 
 	dynamicMu      sync.RWMutex
@@ -17,24 +17,42 @@ type (
 	NewMsg      struct{}
 	DeleteMsg   struct{ ID string }
 	CloseMsg    struct{}
+	PinMsg      struct {
+		ID     string
+		Pinned bool
+	}
+	ArchiveMsg struct {
+		ID       string
+		Archived bool
+	}
 )
 
 type Model struct {
+	all      []conversation.Conversation
 	convs    []conversation.Conversation
 	selected int
-	width    int
-	height   int
-	keyMap   KeyMap
-	help     help.Model
+	// workspace limits the list to its conversations unless showAll is set
+	workspace string
+	showAll   bool
+	// showArchived lists the archived conversations instead of the others
+	showArchived bool
+	archived     int
+	width        int
+	height       int
+	keyMap       KeyMap
+	help         help.Model
 }
 
-func New(convs []conversation.Conversation, currentID string) *Model {
+// New lists convs, limited to workspace when one is active
+func New(convs []conversation.Conversation, currentID, workspace string) *Model {
 	km := DefaultKeyMap()
+	km.Workspace.SetEnabled(workspace != "")
 	h := help.New()
 	t := styles.CurrentTheme()
 	h.Styles = t.S().Help
-	m := &Model{convs: convs, width: 250, height: 60, keyMap: km, help: h}
-	for i, c := range convs {
+	m := &Model{all: convs, workspace: workspace, width: 250, height: 60, keyMap: km, help: h}
+	m.applyFilter()
+	for i, c := range m.convs {
 		if c.ID == currentID {
 			m.selected = i
 			break
@@ -45,9 +63,48 @@ func New(convs []conversation.Conversation, currentID string) *Model {
 
 func (m *Model) Init() tea.Cmd { return nil }
 
+// SetConversations replaces the listed conversations, keeping the selected
+// one selected when it is still listed
 func (m *Model) SetConversations(convs []conversation.Conversation) {
-	m.convs = convs
-	if m.selected >= len(convs) {
+	var selectedID string
+	if m.selected < len(m.convs) {
+		selectedID = m.convs[m.selected].ID
+	}
+	m.all = convs
+	m.applyFilter()
+	for i, c := range m.convs {
+		if c.ID == selectedID {
+			m.selected = i
+			break
+		}
+	}
+}
+
+// applyFilter lists the pinned conversations first, then the rest, or only
+// the archived ones when showArchived is set
+func (m *Model) applyFilter() {
+	visible := m.all
+	if !m.showAll {
+		visible = conversation.InWorkspace(m.all, m.workspace)
+	}
+	var pinned, recent, archived []conversation.Conversation
+	for _, c := range visible {
+		switch {
+		case c.Archived:
+			archived = append(archived, c)
+		case c.Pinned:
+			pinned = append(pinned, c)
+		default:
+			recent = append(recent, c)
+		}
+	}
+	m.archived = len(archived)
+	if m.showArchived {
+		m.convs = archived
+	} else {
+		m.convs = append(pinned, recent...)
+	}
+	if m.selected >= len(m.convs) {
 		m.selected = 0
 	}
 }
@@ -87,6 +144,24 @@ func (m *Model) handleKey(k fmt.Stringer) (tea.Model, tea.Cmd) {
 			id := m.convs[m.selected].ID
 			return m, func() tea.Msg { return DeleteMsg{ID: id} }
 		}
+	case key.Matches(k, m.keyMap.Pin):
+		if len(m.convs) > 0 && !m.showArchived {
+			c := m.convs[m.selected]
+			return m, func() tea.Msg { return PinMsg{ID: c.ID, Pinned: !c.Pinned} }
+		}
+	case key.Matches(k, m.keyMap.Archive):
+		if len(m.convs) > 0 {
+			c := m.convs[m.selected]
+			return m, func() tea.Msg { return ArchiveMsg{ID: c.ID, Archived: !c.Archived} }
+		}
+	case key.Matches(k, m.keyMap.Archived):
+		m.showArchived = !m.showArchived
+		m.selected = 0
+		m.applyFilter()
+	case key.Matches(k, m.keyMap.Workspace):
+		m.showAll = !m.showAll
+		m.selected = 0
+		m.applyFilter()
 	case key.Matches(k, m.keyMap.Close):
 		return m, func() tea.Msg { return CloseMsg{} }
 	}
@@ -100,10 +175,22 @@ func (m *Model) View() string {
 
 	var list string
 	if len(m.convs) == 0 {
-		list = s.Base.PaddingLeft(1).Render("No conversations")
+		empty := "No conversations"
+		if m.showArchived {
+			empty = "No archived conversations"
+		}
+		list = s.Base.PaddingLeft(1).Render(empty)
 	} else {
-		items := make([]string, len(m.convs))
+		section := s.Subtle.PaddingLeft(1)
+		hasPinned := !m.showArchived && m.convs[0].Pinned
+		var items []string
 		for i, c := range m.convs {
+			if hasPinned && i == 0 {
+				items = append(items, section.Render("Pinned"))
+			}
+			if hasPinned && !c.Pinned && m.convs[i-1].Pinned {
+				items = append(items, "", section.Render("Recent"))
+			}
 			// Always reserve space with a left border to avoid layout shift
 			itemStyle := s.Base.
 				PaddingLeft(1).
@@ -119,12 +206,27 @@ func (m *Model) View() string {
 			if agent := strings.TrimSpace(c.ActiveAgent); agent != "" {
 				label = fmt.Sprintf("%s · %s", label, agent)
 			}
-			items[i] = itemStyle.Render(label)
+			if m.showAll && c.Workspace != "" {
+				label = fmt.Sprintf("%s [%s]", label, c.Workspace)
+			}
+			items = append(items, itemStyle.Render(label))
 		}
 		list = lipgloss.JoinVertical(lipgloss.Left, items...)
 	}
 
-	title := s.Title.PaddingLeft(1).Render("Session history")
+	heading := "Session history"
+	if m.showArchived {
+		heading = "Archived sessions"
+	}
+	if m.workspace != "" && !m.showAll {
+		heading = fmt.Sprintf("%s · %s", heading, m.workspace)
+	} else if m.workspace != "" {
+		heading += " · all workspaces"
+	}
+	if !m.showArchived && m.archived > 0 {
+		heading += s.Subtle.Render(fmt.Sprintf(" (%d archived)", m.archived))
+	}
+	title := s.Title.PaddingLeft(1).Render(heading)
 	content := lipgloss.JoinVertical(
 		lipgloss.Left,
 		title,
@@ -5,12 +5,16 @@ import (
 )
 
 type KeyMap struct {
-	Select   key.Binding
-	Next     key.Binding
-	Previous key.Binding
-	New      key.Binding
-	Delete   key.Binding
-	Close    key.Binding
+	Select    key.Binding
+	Next      key.Binding
+	Previous  key.Binding
+	New       key.Binding
+	Delete    key.Binding
+	Pin       key.Binding
+	Archive   key.Binding
+	Archived  key.Binding
+	Workspace key.Binding
+	Close     key.Binding
 }
 
 func DefaultKeyMap() KeyMap {
@@ -35,6 +39,22 @@ func DefaultKeyMap() KeyMap {
 			key.WithKeys("d"),
 			key.WithHelp("d", "delete"),
 		),
+		Pin: key.NewBinding(
+			key.WithKeys("p"),
+			key.WithHelp("p", "pin"),
+		),
+		Archive: key.NewBinding(
+			key.WithKeys("a"),
+			key.WithHelp("a", "archive"),
+		),
+		Archived: key.NewBinding(
+			key.WithKeys("A"),
+			key.WithHelp("A", "archived"),
+		),
+		Workspace: key.NewBinding(
+			key.WithKeys("w"),
+			key.WithHelp("w", "all workspaces"),
+		),
 		Close: key.NewBinding(
 			key.WithKeys("esc", "ctrl+c"),
 			key.WithHelp("esc", "cancel"),
@@ -49,6 +69,10 @@ func (k KeyMap) KeyBindings() []key.Binding {
 		k.Previous,
 		k.New,
 		k.Delete,
+		k.Pin,
+		k.Archive,
+		k.Archived,
+		k.Workspace,
 		k.Close,
 	}
 }
@@ -74,6 +98,10 @@ func (k KeyMap) ShortHelp() []key.Binding {
 		k.Select,
 		k.New,
 		k.Delete,
+		k.Pin,
+		k.Archive,
+		k.Archived,
+		k.Workspace,
 		k.Close,
 	}
 }
@@ -1,6 +1,7 @@
 package header
 
 import (
+	"fmt"
 	"strings"
 
 	tea "github.com/charmbracelet/bubbletea/v2"
@@ -20,6 +21,7 @@ type Header interface {
 
 	SetMeta(title, model, status, hint string)
 	SetUpdateAvailable(available bool)
+	SetOffline(daemons []string, queued int)
 }
 
 type header struct {
@@ -31,6 +33,9 @@ type header struct {
 	status          string
 	hint            string
 	updateAvailable bool
+
+	offline []string
+	queued  int
 }
 
 func New() Header { return &header{} }
@@ -67,6 +72,17 @@ func (h *header) View() string {
 		updateNoticeWidth = lipgloss.Width(styledNotice) + 2 // +2 for spacing
 	}
 
+	// The connection notice takes the place of the update notice while a
+	// daemon is offline
+	if len(h.offline) > 0 {
+		notice := "○ offline: " + strings.Join(h.offline, ", ") + " · reconnecting…"
+		if h.queued > 0 {
+			notice += fmt.Sprintf(" · %d queued", h.queued)
+		}
+		styledNotice = lipgloss.NewStyle().Foreground(t.Warning).Bold(true).Render(notice)
+		updateNoticeWidth = lipgloss.Width(styledNotice) + 2
+	}
+
 	// Calculate available width for the pattern
 	labelWidth := lipgloss.Width(label)
 	versionWidth := lipgloss.Width(versionStr)
@@ -90,7 +106,7 @@ func (h *header) View() string {
 
 	// Build the full header
 	var result string
-	if h.updateAvailable {
+	if styledNotice != "" {
 		// Use lipgloss to place left content on left and update notice on right
 		rightSide := lipgloss.NewStyle().
 			Align(lipgloss.Right).
@@ -115,3 +131,6 @@ func (h *header) SetMeta(title, model, status, hint string) {
 func (h *header) SetUpdateAvailable(available bool) {
 	h.updateAvailable = available
 }
+func (h *header) SetOffline(daemons []string, queued int) {
+	h.offline, h.queued = daemons, queued
+}
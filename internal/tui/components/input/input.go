@@ -12,6 +12,7 @@ import (
 	"tui/components/textarea"
 	"tui/coreagent"
 	"tui/styles"
+	"tui/voice"
 )
 
 type Input struct {
@@ -27,6 +28,9 @@ type Input struct {
 	argHintStyleSet bool
 
 	currentAgentID string // current core agent ID for filtering commands
+
+	recording    *voice.Recording
+	transcribing bool
 }
 
 func (c *Input) clearArgHint() {
@@ -86,7 +90,7 @@ func (c *Input) initIfNeeded() {
 		return
 	}
 	ta := textarea.New()
-	ta.Placeholder = "Send a message..."
+	ta.Placeholder = defaultPlaceholder
 	ta.Focus()
 	ta.Prompt = "┃ "
 	ta.CharLimit = 100000
@@ -111,6 +115,8 @@ func (c *Input) Init() tea.Cmd {
 func (c *Input) Update(msg tea.Msg) tea.Cmd {
 	c.initIfNeeded()
 	switch m := msg.(type) {
+	case transcriptMsg:
+		return c.insertTranscript(m)
 	case tea.KeyMsg:
 		if !c.f {
 			return nil
@@ -177,6 +183,16 @@ func (c *Input) SetValue(v string) {
 	c.refreshPickerState()
 }
 
+// SetPlaceholder replaces the text shown while the input is empty; an empty
+// placeholder restores the default.
+func (c *Input) SetPlaceholder(text string) {
+	c.initIfNeeded()
+	if text == "" {
+		text = defaultPlaceholder
+	}
+	c.ta.Placeholder = text
+}
+
 func (c *Input) Focus() tea.Cmd {
 	c.initIfNeeded()
 	c.f = true
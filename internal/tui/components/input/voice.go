@@ -0,0 +1,96 @@
+package input
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"tui/util"
+	"tui/voice"
+)
+
+const (
+	defaultPlaceholder      = "Send a message..."
+	recordingPlaceholder    = "Listening… press ctrl+r to stop"
+	transcribingPlaceholder = "Transcribing…"
+)
+
+// transcriptMsg carries the text of a finished voice recording.
+type transcriptMsg struct {
+	text string
+	err  error
+}
+
+// ToggleVoice starts recording from the microphone, or stops the running
+// recording and transcribes it into the input box.
+func (c *Input) ToggleVoice() tea.Cmd {
+	c.initIfNeeded()
+	if c.transcribing {
+		return nil
+	}
+	if c.recording == nil {
+		rec, err := voice.Start()
+		if err != nil {
+			return util.ReportError(err)
+		}
+		c.recording = rec
+		c.ta.Placeholder = recordingPlaceholder
+		c.ta.Prompt = "● "
+		return nil
+	}
+
+	rec := c.recording
+	c.recording = nil
+	c.transcribing = true
+	c.ta.Placeholder = transcribingPlaceholder
+	c.ta.Prompt = "┃ "
+	cfg := voice.ConfigFromEnv()
+	return func() tea.Msg {
+		path, err := rec.Stop()
+		if err != nil {
+			return transcriptMsg{err: err}
+		}
+		defer os.Remove(path)
+		text, err := voice.Transcribe(context.Background(), cfg, path)
+		return transcriptMsg{text: text, err: err}
+	}
+}
+
+// IsRecording reports whether voice input is being captured.
+func (c *Input) IsRecording() bool { return c.recording != nil }
+
+// CancelVoice discards a running recording.
+func (c *Input) CancelVoice() {
+	if c.recording == nil {
+		return
+	}
+	c.recording.Cancel()
+	c.recording = nil
+	c.resetVoicePrompt()
+}
+
+func (c *Input) resetVoicePrompt() {
+	c.ta.Placeholder = defaultPlaceholder
+	c.ta.Prompt = "┃ "
+}
+
+// insertTranscript adds the transcript at the cursor, separated from
+// existing text by a space.
+func (c *Input) insertTranscript(msg transcriptMsg) tea.Cmd {
+	c.transcribing = false
+	c.resetVoicePrompt()
+	if msg.err != nil {
+		return util.ReportError(msg.err)
+	}
+	if msg.text == "" {
+		return util.ReportWarn("No speech recognised")
+	}
+	text := msg.text
+	if before := c.ta.Value(); before != "" && !strings.HasSuffix(before, " ") && !strings.HasSuffix(before, "\n") {
+		text = " " + text
+	}
+	c.ta.InsertString(text)
+	c.refreshPickerState()
+	return nil
+}
@@ -13,3 +13,16 @@ var ClearSelectionKey = key.NewBinding(
 	key.WithKeys("esc"),
 	key.WithHelp("esc", "clear selection"),
 )
+
+// ExpandKey is the key binding for expanding or folding tool output.
+var ExpandKey = key.NewBinding(
+	key.WithKeys("e"),
+	key.WithHelp("e", "expand output"),
+)
+
+// SearchKey is the key binding for searching the result in the tool detail
+// view.
+var SearchKey = key.NewBinding(
+	key.WithKeys("/"),
+	key.WithHelp("/", "search"),
+)
@@ -521,6 +521,22 @@ func (c *Messages) FocusedToolEntry() (toolstate.Execution, bool) {
 	return toolstate.Execution{}, false
 }
 
+// ToggleFocusedToolExpanded expands or folds the output of the focused tool
+// call. It reports whether a tool call is focused.
+func (c *Messages) ToggleFocusedToolExpanded() bool {
+	if c.focus < 0 || c.focus >= len(c.items) {
+		return false
+	}
+	tc, ok := c.items[c.focus].(ToolCallCmp)
+	if !ok {
+		return false
+	}
+	tc.ToggleExpanded()
+	c.markDirty(c.focus)
+	c.ensureVisibleIdx = c.focus
+	return true
+}
+
 func (c *Messages) ToolEntryByID(id string) (toolstate.Execution, bool) {
 	id = strings.TrimSpace(id)
 	if id == "" {
@@ -12,6 +12,8 @@ import (
 	"tui/toolstate"
 	"tui/util"
 
+	"opperator/config"
+
 	"github.com/atotto/clipboard"
 	"github.com/charmbracelet/bubbles/v2/key"
 	tea "github.com/charmbracelet/bubbletea/v2"
@@ -28,6 +30,7 @@ type ToolCallCmp interface {
 	SetEntry(toolstate.Execution)
 	Entry() toolstate.Execution
 	Animating() bool
+	ToggleExpanded()
 }
 
 type toolCallCmp struct {
@@ -39,6 +42,17 @@ type toolCallCmp struct {
 	spinnerSettings anim.Settings
 	spinning        bool
 	savedPending    string
+	// expanded shows the whole output regardless of the tool's display
+	// settings.
+	expanded bool
+}
+
+// toolOutput is how finished tool calls are folded, from tui.yaml.
+var toolOutput config.ToolOutputConfig
+
+// SetToolOutputConfig sets how finished tool calls are folded.
+func SetToolOutputConfig(cfg config.ToolOutputConfig) {
+	toolOutput = cfg
 }
 
 func NewToolCallCmp(entry toolstate.Execution) ToolCallCmp {
@@ -176,9 +190,40 @@ func (m *toolCallCmp) View() string {
 	} else {
 		content = defaultFinishedRender(m.entry, width)
 	}
-	return style.Width(max(m.width, 1)).Render(strings.TrimSpace(content))
+	content = strings.TrimSpace(content)
+	if !m.expanded {
+		content = m.fold(content)
+	}
+	return style.Width(max(m.width, 1)).Render(content)
+}
+
+// fold shortens finished output per the tool's display settings, noting how
+// many lines are hidden.
+func (m *toolCallCmp) fold(content string) string {
+	collapsed, maxLines := toolOutput.For(m.entry.Call.Name)
+	if collapsed {
+		maxLines = 1
+	}
+	lines := strings.Split(content, "\n")
+	if maxLines <= 0 || len(lines) <= maxLines {
+		return content
+	}
+	hidden := len(lines) - maxLines
+	t := styles.CurrentTheme()
+	hint := t.S().Subtle.Render(fmt.Sprintf("… %d more %s (%s to expand)", hidden, pluralLines(hidden), ExpandKey.Help().Key))
+	return strings.Join(lines[:maxLines], "\n") + "\n" + hint
 }
 
+func pluralLines(n int) string {
+	if n == 1 {
+		return "line"
+	}
+	return "lines"
+}
+
+// ToggleExpanded switches between the folded and the whole output.
+func (m *toolCallCmp) ToggleExpanded() { m.expanded = !m.expanded }
+
 func (m *toolCallCmp) Focus() tea.Cmd             { m.focused = true; return nil }
 func (m *toolCallCmp) Blur() tea.Cmd              { m.focused = false; return nil }
 func (m *toolCallCmp) IsFocused() bool            { return m.focused }
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/charmbracelet/bubbles/v2/key"
 	tea "github.com/charmbracelet/bubbletea/v2"
 	"github.com/charmbracelet/lipgloss/v2"
 
@@ -22,6 +23,11 @@ type ToolDetailView struct {
 	width, height  int
 	agentName      string
 	taskDefinition string
+
+	// query narrows the result to the lines containing it; searching is set
+	// while it is being typed.
+	query     string
+	searching bool
 }
 
 func NewToolDetailView() *ToolDetailView {
@@ -64,7 +70,8 @@ func (v *ToolDetailView) SetData(call tooltypes.Call, result tooltypes.Result) t
 		appender.add(message.Assistant, label, body)
 	}
 
-	appender.add(message.Assistant, "Result", result.Content)
+	label, body := v.searchResult(result.Content)
+	appender.add(message.Assistant, label, body)
 
 	if !appender.hasContent() {
 		appender.add(message.Assistant, "", "(no additional details)")
@@ -72,6 +79,75 @@ func (v *ToolDetailView) SetData(call tooltypes.Call, result tooltypes.Result) t
 	return nil
 }
 
+// Searching reports whether a search query is being typed, in which case
+// every key goes to the query.
+func (v *ToolDetailView) Searching() bool { return v.searching }
+
+// HandlesEscape reports whether esc ends a search rather than closing the
+// view.
+func (v *ToolDetailView) HandlesEscape() bool { return v.searching || v.query != "" }
+
+// searchResult returns the label and body of the result section, narrowed to
+// the lines matching the search query.
+func (v *ToolDetailView) searchResult(content string) (string, string) {
+	if v.query == "" {
+		if v.searching {
+			return "Result — search: _", content
+		}
+		return "Result", content
+	}
+	needle := strings.ToLower(v.query)
+	var matches []string
+	for _, line := range strings.Split(content, "\n") {
+		if strings.Contains(strings.ToLower(line), needle) {
+			matches = append(matches, line)
+		}
+	}
+	label := fmt.Sprintf("Result — %d matching %s for %q", len(matches), pluralLines(len(matches)), v.query)
+	if v.searching {
+		label = fmt.Sprintf("Result — search: %s_ (%d matching)", v.query, len(matches))
+	}
+	if len(matches) == 0 {
+		return label, "(no matches)"
+	}
+	return label, strings.Join(matches, "\n")
+}
+
+// updateSearch handles the keys of the result search: / starts it, enter
+// keeps the query and esc clears it.
+func (v *ToolDetailView) updateSearch(msg tea.KeyPressMsg) bool {
+	if !v.searching {
+		switch {
+		case key.Matches(msg, SearchKey):
+			v.searching = true
+		case msg.String() == "esc" && v.query != "":
+			v.query = ""
+		default:
+			return false
+		}
+		v.SetData(v.call, v.result)
+		return true
+	}
+
+	switch msg.String() {
+	case "enter":
+		v.searching = false
+	case "esc":
+		v.searching, v.query = false, ""
+	case "backspace":
+		if r := []rune(v.query); len(r) > 0 {
+			v.query = string(r[:len(r)-1])
+		}
+	default:
+		if msg.Text == "" {
+			return true
+		}
+		v.query += msg.Text
+	}
+	v.SetData(v.call, v.result)
+	return true
+}
+
 func (v *ToolDetailView) Update(msg tea.Msg) tea.Cmd {
 	if v.log == nil {
 		return nil
@@ -80,6 +156,11 @@ func (v *ToolDetailView) Update(msg tea.Msg) tea.Cmd {
 	case tea.WindowSizeMsg:
 		v.SetFrameSize(m.Width, m.Height)
 		return nil
+	case tea.KeyPressMsg:
+		if v.updateSearch(m) {
+			return nil
+		}
+		return v.log.Update(msg)
 	default:
 		return v.log.Update(msg)
 	}
@@ -8,6 +8,7 @@ type CustomViewportState struct {
 	Inited   bool
 	Y        int  // Y position of section in sidebar
 	Height   int  // Height of section
+	ActionsY int  // Y position of the first action line
 }
 
 // NewCustomViewportState creates a new CustomViewportState
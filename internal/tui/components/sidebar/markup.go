@@ -118,6 +118,25 @@ func ParseMarkupWithStyle(input string, defaultStyle lipgloss.Style) string {
 	return result.String()
 }
 
+// StripMarkup removes the tags understood by ParseMarkup, keeping their text.
+func StripMarkup(input string) string {
+	var result strings.Builder
+	for {
+		tagStart := strings.IndexByte(input, '<')
+		if tagStart == -1 {
+			break
+		}
+		tagEnd := strings.IndexByte(input[tagStart:], '>')
+		if tagEnd == -1 {
+			break
+		}
+		result.WriteString(input[:tagStart])
+		input = input[tagStart+tagEnd+1:]
+	}
+	result.WriteString(input)
+	return result.String()
+}
+
 // parseAttributes parses tag attributes in the form: attr="value" or attr='value'
 func parseAttributes(parts []string) map[string]string {
 	attrs := make(map[string]string)
@@ -170,3 +170,30 @@ func (m *MouseHandler) HandleCustomSectionViewportsMouse(msg tea.Msg, customView
 
 	return nil
 }
+
+// CustomSectionActionAt returns the custom section and action under a mouse
+// click, if any.
+func (m *MouseHandler) CustomSectionActionAt(msg tea.Msg, customViewports map[string]*CustomViewportState) (int, int, bool) {
+	click, ok := msg.(tea.MouseClickMsg)
+	if !ok {
+		return -1, -1, false
+	}
+	mouse := click.Mouse()
+	if mouse.Button != tea.MouseLeft || !m.IsMouseInSidebar(msg) {
+		return -1, -1, false
+	}
+
+	for i, section := range m.sections.CustomSections {
+		if len(section.Actions) == 0 || !m.sections.CustomSectionsExpanded[section.ID] {
+			continue
+		}
+		vp, exists := customViewports[section.ID]
+		if !exists || !vp.Inited {
+			continue
+		}
+		if idx := mouse.Y - vp.ActionsY; idx >= 0 && idx < len(section.Actions) {
+			return i, idx, true
+		}
+	}
+	return -1, -1, false
+}
@@ -4,6 +4,7 @@ package sidebar
 type NavigationState struct {
 	selectedSection     Section
 	selectedCustomIndex int // -1 if no custom section selected
+	selectedAction      int // -1 if no action of the selected custom section is selected
 }
 
 // NewNavigationState creates a new navigation state
@@ -11,6 +12,7 @@ func NewNavigationState() *NavigationState {
 	return &NavigationState{
 		selectedSection:     SectionNone,
 		selectedCustomIndex: -1,
+		selectedAction:      -1,
 	}
 }
 
@@ -24,16 +26,29 @@ func (n *NavigationState) GetSelectedCustomIndex() int {
 	return n.selectedCustomIndex
 }
 
+// GetSelectedActionIndex returns the selected action of the selected custom section
+func (n *NavigationState) GetSelectedActionIndex() int {
+	return n.selectedAction
+}
+
 // SetSelectedSection sets the selected section and clears custom index
 func (n *NavigationState) SetSelectedSection(section Section) {
 	n.selectedSection = section
 	n.selectedCustomIndex = -1
+	n.selectedAction = -1
 }
 
 // SetSelectedCustomIndex sets the selected custom section index and clears built-in selection
 func (n *NavigationState) SetSelectedCustomIndex(index int) {
 	n.selectedCustomIndex = index
 	n.selectedSection = SectionNone
+	n.selectedAction = -1
+}
+
+// SetSelectedAction selects an action of a custom section
+func (n *NavigationState) SetSelectedAction(customIndex, action int) {
+	n.SetSelectedCustomIndex(customIndex)
+	n.selectedAction = action
 }
 
 // IsCustomSectionSelected returns true if a custom section is currently selected
@@ -104,6 +119,19 @@ func (h *NavigationHelper) FindSectionIndex(sections []Section, target Section)
 	return -1
 }
 
+// selectableActions returns the actions of a custom section that can be
+// selected, which are none while it is collapsed.
+func (h *NavigationHelper) selectableActions(customIndex int) []SectionAction {
+	if customIndex < 0 || customIndex >= len(h.sections.CustomSections) {
+		return nil
+	}
+	section := h.sections.CustomSections[customIndex]
+	if !h.sections.CustomSectionsExpanded[section.ID] {
+		return nil
+	}
+	return section.Actions
+}
+
 // ValidateAndFixSelection ensures the current selection is valid for available sections
 func (h *NavigationHelper) ValidateAndFixSelection() {
 	sections := h.GetAvailableSections()
@@ -111,11 +139,13 @@ func (h *NavigationHelper) ValidateAndFixSelection() {
 	if len(sections) == 0 && len(h.sections.CustomSections) == 0 {
 		h.nav.selectedSection = SectionNone
 		h.nav.selectedCustomIndex = -1
+		h.nav.selectedAction = -1
 		return
 	}
 
 	if h.nav.selectedCustomIndex >= 0 {
 		if h.nav.selectedCustomIndex >= len(h.sections.CustomSections) {
+			h.nav.selectedAction = -1
 			if len(sections) > 0 {
 				h.nav.selectedSection = sections[0]
 				h.nav.selectedCustomIndex = -1
@@ -124,8 +154,12 @@ func (h *NavigationHelper) ValidateAndFixSelection() {
 				h.nav.selectedSection = SectionNone
 			}
 		}
+		if h.nav.selectedAction >= len(h.selectableActions(h.nav.selectedCustomIndex)) {
+			h.nav.selectedAction = -1
+		}
 		return
 	}
+	h.nav.selectedAction = -1
 
 	if h.nav.selectedSection != SectionNone {
 		if h.FindSectionIndex(sections, h.nav.selectedSection) == -1 {
@@ -159,8 +193,14 @@ func (h *NavigationHelper) FocusNext() bool {
 	h.ValidateAndFixSelection()
 
 	if h.nav.selectedCustomIndex >= 0 {
+		// Step through the actions of an expanded section before leaving it
+		if h.nav.selectedAction < len(h.selectableActions(h.nav.selectedCustomIndex))-1 {
+			h.nav.selectedAction++
+			return true
+		}
 		if h.nav.selectedCustomIndex < len(h.sections.CustomSections)-1 {
 			h.nav.selectedCustomIndex++
+			h.nav.selectedAction = -1
 			return true
 		}
 		return false
@@ -200,14 +240,17 @@ func (h *NavigationHelper) FocusPrev() bool {
 	h.ValidateAndFixSelection()
 
 	if h.nav.selectedCustomIndex >= 0 {
+		if h.nav.selectedAction >= 0 {
+			h.nav.selectedAction--
+			return true
+		}
 		if h.nav.selectedCustomIndex > 0 {
 			h.nav.selectedCustomIndex--
 			return true
 		}
 
 		if len(sections) > 0 {
-			h.nav.selectedCustomIndex = -1
-			h.nav.selectedSection = sections[len(sections)-1]
+			h.nav.SetSelectedSection(sections[len(sections)-1])
 			return true
 		}
 
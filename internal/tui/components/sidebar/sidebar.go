@@ -23,6 +23,9 @@ type Sidebar struct {
 	// Custom section viewports (keyed by section ID)
 	customViewports map[string]*CustomViewportState
 
+	// Agent owning the custom sections, whose commands their actions invoke
+	sectionsAgent string
+
 	// Navigation helper
 	navHelper *NavigationHelper
 
@@ -169,7 +172,17 @@ func (s *Sidebar) SetTodos(todos []TodoItem) {
 	s.builder.SetTodos(todos)
 }
 
-func (s *Sidebar) SetCustomSections(sections []CustomSection) {
+// SetCustomSections replaces the custom sections with those of an agent.
+func (s *Sidebar) SetCustomSections(agentName string, sections []CustomSection) {
+	s.sectionsAgent = agentName
+	// Dashboards are served by the daemon over HTTP, not shown here
+	shown := sections[:0:0]
+	for _, section := range sections {
+		if section.Type != SectionTypeDashboard {
+			shown = append(shown, section)
+		}
+	}
+	sections = shown
 	changed := s.sections.SetCustomSections(sections, s.prefsStore)
 	if changed {
 		// Clean up viewports for removed sections
@@ -221,6 +234,12 @@ func (s *Sidebar) Update(msg tea.Msg) tea.Cmd {
 	// Handle mouse events for logs viewport
 	logsCmd := s.mouseHandler.HandleLogsViewportMouse(msg)
 
+	// Clicking an action of a custom section uses it
+	if sectionIdx, actionIdx, ok := s.mouseHandler.CustomSectionActionAt(msg, s.customViewports); ok {
+		s.nav.SetSelectedAction(sectionIdx, actionIdx)
+		return s.sectionActionCmd(sectionIdx, actionIdx)
+	}
+
 	// Handle mouse events for custom section viewports
 	customCmd := s.mouseHandler.HandleCustomSectionViewportsMouse(msg, s.customViewports)
 
@@ -239,6 +258,33 @@ func (s *Sidebar) FocusPrev() bool {
 	return s.navHelper.FocusPrev()
 }
 
+// Activate uses the selected action of a custom section, or toggles the
+// selected section when no action is selected.
+func (s *Sidebar) Activate() tea.Cmd {
+	if actionIdx := s.nav.GetSelectedActionIndex(); actionIdx >= 0 {
+		return s.sectionActionCmd(s.nav.GetSelectedCustomIndex(), actionIdx)
+	}
+	s.ToggleSection()
+	return nil
+}
+
+// sectionActionCmd reports the use of an action of a custom section.
+func (s *Sidebar) sectionActionCmd(sectionIdx, actionIdx int) tea.Cmd {
+	if sectionIdx < 0 || sectionIdx >= len(s.sections.CustomSections) {
+		return nil
+	}
+	section := s.sections.CustomSections[sectionIdx]
+	if actionIdx < 0 || actionIdx >= len(section.Actions) {
+		return nil
+	}
+	msg := SectionActionMsg{
+		Agent:     s.sectionsAgent,
+		SectionID: section.ID,
+		Action:    section.Actions[actionIdx],
+	}
+	return func() tea.Msg { return msg }
+}
+
 func (s *Sidebar) ToggleSection() {
 	selectedCustomIdx := s.nav.GetSelectedCustomIndex()
 	if selectedCustomIdx >= 0 && selectedCustomIdx < len(s.sections.CustomSections) {
@@ -401,7 +447,7 @@ func (s *Sidebar) renderAgentsSection(state *SidebarRenderState) {
 				statusStyle = lipgloss.NewStyle().Foreground(t.Success)
 			case "inactive", "idle":
 				statusStyle = lipgloss.NewStyle().Foreground(t.FgMuted)
-			case "crashed":
+			case "crashed", "crashlooping":
 				statusStyle = lipgloss.NewStyle().Foreground(t.Error)
 			case "error", "failed":
 				statusStyle = lipgloss.NewStyle().Foreground(t.Error)
@@ -534,7 +580,7 @@ func (s *Sidebar) renderFocusedAgentSection(state *SidebarRenderState) {
 		switch strings.ToLower(s.builder.FocusedAgentStatus) {
 		case "running":
 			statusView = lipgloss.NewStyle().Foreground(t.Success).Render(s.builder.FocusedAgentStatus)
-		case "crashed":
+		case "crashed", "crashlooping":
 			statusView = lipgloss.NewStyle().Foreground(t.Error).Render(s.builder.FocusedAgentStatus)
 		case "stopped":
 			statusView = lipgloss.NewStyle().Foreground(t.FgMuted).Render(s.builder.FocusedAgentStatus)
@@ -827,36 +873,54 @@ func (s *Sidebar) renderCustomSections(state *SidebarRenderState) {
 				vpWidth = 1
 			}
 
-			// Parse the content with markup styling and manually wrap lines
-			rawContent := ParseMarkupWithStyle(section.Content, defaultStyle)
-
-			// Manually wrap each line to fit within viewport width
-			contentLines := strings.Split(rawContent, "\n")
-			var wrappedLines []string
-			for _, line := range contentLines {
-				if line == "" {
-					wrappedLines = append(wrappedLines, line)
-					continue
+			// Sections made of actions alone have no content to scroll
+			vpHeight, totalLines := 0, 0
+			if section.Content != "" || len(section.Actions) == 0 {
+				// Parse the content with markup styling and manually wrap lines
+				rawContent := ParseMarkupWithStyle(section.Content, defaultStyle)
+
+				// Manually wrap each line to fit within viewport width
+				contentLines := strings.Split(rawContent, "\n")
+				var wrappedLines []string
+				for _, line := range contentLines {
+					if line == "" {
+						wrappedLines = append(wrappedLines, line)
+						continue
+					}
+					// Use lipgloss to wrap the line
+					wrapped := lipgloss.NewStyle().Width(vpWidth).Render(line)
+					wrappedParts := strings.Split(wrapped, "\n")
+					wrappedLines = append(wrappedLines, wrappedParts...)
 				}
-				// Use lipgloss to wrap the line
-				wrapped := lipgloss.NewStyle().Width(vpWidth).Render(line)
-				wrappedParts := strings.Split(wrapped, "\n")
-				wrappedLines = append(wrappedLines, wrappedParts...)
-			}
 
-			styledContent := strings.Join(wrappedLines, "\n")
-			totalLines := len(wrappedLines)
+				styledContent := strings.Join(wrappedLines, "\n")
+				totalLines = len(wrappedLines)
 
-			// Set viewport height: minimum of total lines or 20 (same as logs)
-			vpHeight := min(totalLines, 20)
-			vp.SetSize(vpWidth, vpHeight)
-			vp.SetContent(styledContent)
+				// Set viewport height: minimum of total lines or 20 (same as logs)
+				vpHeight = min(totalLines, 20)
+				vp.SetSize(vpWidth, vpHeight)
+				vp.SetContent(styledContent)
 
-			// Get rendered viewport content
-			content = vp.View()
+				// Get rendered viewport content
+				content = vp.View()
+			}
+
+			// Actions follow the content, one per line
+			if len(section.Actions) > 0 {
+				actionLines := make([]string, len(section.Actions))
+				for j, action := range section.Actions {
+					actionSelected := isSelected && s.nav.GetSelectedActionIndex() == j
+					actionLines[j] = s.renderSectionAction(t, action, actionSelected, vpWidth)
+				}
+				if content != "" {
+					content += "\n"
+				}
+				content += strings.Join(actionLines, "\n")
+			}
 
 			// Store position for mouse handling
 			vp.Y = state.CumulativeHeight
+			vp.ActionsY = vp.Y + 1 + vpHeight
 
 			// Render with scrollbar if content exceeds viewport
 			if totalLines > vpHeight {
@@ -882,6 +946,20 @@ func (s *Sidebar) renderCustomSections(state *SidebarRenderState) {
 	}
 }
 
+// renderSectionAction renders an action of a custom section as a single line.
+func (s *Sidebar) renderSectionAction(t styles.Theme, action SectionAction, selected bool, width int) string {
+	label := action.Label
+	if action.Type == SectionActionInput {
+		label += "…"
+	}
+	text := truncateToOneLine("[ "+label+" ]", width-2)
+
+	if selected {
+		return t.S().Base.Foreground(t.Accent).Bold(true).Render("› " + text)
+	}
+	return t.S().Base.Foreground(t.FgMuted).Render("  " + text)
+}
+
 func (s *Sidebar) appendToggleHint(state *SidebarRenderState) {
 	if s.agent.Name != "" {
 		return
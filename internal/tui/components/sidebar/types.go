@@ -25,10 +25,44 @@ type AgentListItem struct {
 
 // CustomSection represents a custom sidebar section
 type CustomSection struct {
-	ID        string `json:"id"`
-	Title     string `json:"title"`
-	Content   string `json:"content"`
-	Collapsed bool   `json:"collapsed"`
+	ID        string          `json:"id"`
+	Title     string          `json:"title"`
+	Content   string          `json:"content"`
+	Collapsed bool            `json:"collapsed"`
+	Actions   []SectionAction `json:"actions,omitempty"`
+	// Type is SectionTypeDashboard for a dashboard served by the daemon
+	// instead of shown in the sidebar; Format is then "markdown" or "html".
+	Type   string `json:"type,omitempty"`
+	Format string `json:"format,omitempty"`
+}
+
+// SectionTypeDashboard marks a section published as a web dashboard.
+const SectionTypeDashboard = "dashboard"
+
+// SectionActionButton and SectionActionInput are the types of section actions.
+const (
+	SectionActionButton = "button"
+	SectionActionInput  = "input"
+)
+
+// SectionAction is a button or input of a custom section that invokes a
+// command of the agent owning the section. An input passes the text entered
+// as the argument named by Arg, along with Args.
+type SectionAction struct {
+	ID          string         `json:"id"`
+	Label       string         `json:"label"`
+	Type        string         `json:"type"`
+	Command     string         `json:"command"`
+	Args        map[string]any `json:"args,omitempty"`
+	Arg         string         `json:"arg,omitempty"`
+	Placeholder string         `json:"placeholder,omitempty"`
+}
+
+// SectionActionMsg is sent when an action of a custom section is used.
+type SectionActionMsg struct {
+	Agent     string
+	SectionID string
+	Action    SectionAction
 }
 
 // TodoItem represents a todo item
@@ -1,6 +1,9 @@
 package sidebar
 
-import "strings"
+import (
+	"reflect"
+	"strings"
+)
 
 // max returns the maximum of two integers
 func max(a, b int) int {
@@ -98,7 +101,8 @@ func customSectionsEqual(a, b []CustomSection) bool {
 		if a[i].ID != b[i].ID ||
 			a[i].Title != b[i].Title ||
 			a[i].Content != b[i].Content ||
-			a[i].Collapsed != b[i].Collapsed {
+			a[i].Collapsed != b[i].Collapsed ||
+			!reflect.DeepEqual(a[i].Actions, b[i].Actions) {
 			return false
 		}
 	}
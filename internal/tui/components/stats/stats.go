@@ -6,13 +6,13 @@ import (
 	"errors"
 	"fmt"
 	"net"
-	"os"
-	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/charmbracelet/lipgloss/v2"
 	"tui/styles"
+
+	"opperator/config"
 )
 
 // Stats is the right-side status panel (simple) with basic process tracking.
@@ -136,7 +136,7 @@ func FetchCounts() (running, stopped, crashed, total int, _ error) {
 		switch strings.ToLower(string(p.Status)) {
 		case "running":
 			running++
-		case "crashed":
+		case "crashed", "crashlooping":
 			crashed++
 		default:
 			stopped++
@@ -153,7 +153,8 @@ type processInfo struct {
 type processStat string
 
 func getSocketPath() string {
-	return filepath.Join(os.TempDir(), "opperator.sock")
+	socketPath, _ := config.GetSocketPath()
+	return socketPath
 }
 
 func max(a, b int) int {
@@ -0,0 +1,157 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+
+	llm "tui/llm"
+	"tui/util"
+)
+
+// Delays between attempts to reconnect to a daemon. The delay doubles after
+// every failed attempt and starts over once a connection holds.
+const (
+	reconnectMinDelay = 500 * time.Millisecond
+	reconnectMaxDelay = 30 * time.Second
+)
+
+// Connection states reported by the watchers in agentStateEventMsg.Status for
+// events of type "connection".
+const (
+	connectionUp   = "connected"
+	connectionDown = "disconnected"
+)
+
+// nextReconnectDelay returns the delay to wait after delay failed.
+func nextReconnectDelay(delay time.Duration) time.Duration {
+	if delay < reconnectMinDelay {
+		return reconnectMinDelay
+	}
+	return min(delay*2, reconnectMaxDelay)
+}
+
+// queuedAction is a user action that needs a daemon that was offline when
+// it was taken. It runs once the daemon is back.
+type queuedAction struct {
+	daemon string
+	label  string
+	run    func() tea.Cmd
+}
+
+// sendConnectionEvent reports daemonName going up or down.
+func sendConnectionEvent(ctx context.Context, eventCh chan<- agentStateEventMsg, daemonName, status string) {
+	select {
+	case eventCh <- agentStateEventMsg{Type: "connection", Daemon: daemonName, Status: status}:
+	case <-ctx.Done():
+	}
+}
+
+// handleDaemonConnection tracks which daemons are offline. When one comes
+// back it refreshes everything the TUI may have missed and runs the actions
+// queued for it.
+func (m *Model) handleDaemonConnection(msg agentStateEventMsg) tea.Cmd {
+	daemon := msg.Daemon
+	if daemon == "" {
+		daemon = "local"
+	}
+
+	if msg.Status == connectionDown {
+		if m.offlineDaemons[daemon] {
+			return nil
+		}
+		m.offlineDaemons[daemon] = true
+		m.updateConnectionStatus()
+		return util.ReportWarn(fmt.Sprintf("Lost connection to daemon '%s'; reconnecting…", daemon))
+	}
+
+	if !m.offlineDaemons[daemon] {
+		return nil
+	}
+	delete(m.offlineDaemons, daemon)
+
+	var (
+		cmds    []tea.Cmd
+		pending []queuedAction
+		ran     []string
+	)
+	for _, action := range m.queuedActions {
+		if action.daemon != daemon {
+			pending = append(pending, action)
+			continue
+		}
+		cmds = append(cmds, action.run())
+		ran = append(ran, action.label)
+	}
+	m.queuedActions = pending
+	m.updateConnectionStatus()
+
+	// State and task events sent while disconnected are gone; fetch afresh
+	llm.InvalidateAgentListCache()
+	cmds = append(cmds, m.initialStatsCmd(), m.refreshAgentListCmd())
+	if agent := m.currentActiveAgentName(); agent != "" {
+		llm.InvalidateAgentMetadataCache(agent)
+		cmds = append(cmds, m.fetchInitialAgentLogsCmd(agent), m.fetchInitialCustomSectionsCmd(agent))
+	}
+	if m.sidebar != nil {
+		if focused := m.sidebar.FocusedAgentName(); focused != "" {
+			llm.InvalidateAgentMetadataCache(focused)
+			cmds = append(cmds, m.fetchFocusedAgentMetadataCmd(focused))
+		}
+	}
+
+	notice := fmt.Sprintf("Reconnected to daemon '%s'", daemon)
+	if len(ran) > 0 {
+		notice += "; running queued " + strings.Join(ran, ", ")
+	}
+	cmds = append(cmds, util.ReportInfo(notice))
+	return tea.Batch(cmds...)
+}
+
+// queueIfOffline holds back an action on agentName while the daemon running
+// it is offline. It returns nil when the daemon is reachable and the action
+// should run now.
+func (m *Model) queueIfOffline(agentName, label string, run func() tea.Cmd) tea.Cmd {
+	if len(m.offlineDaemons) == 0 {
+		return nil
+	}
+	agent := strings.TrimSpace(agentName)
+	if agent == "" {
+		agent = m.currentActiveAgentName()
+	}
+	daemon := m.agentDaemon(agent)
+	if !m.offlineDaemons[daemon] {
+		return nil
+	}
+	m.queuedActions = append(m.queuedActions, queuedAction{daemon: daemon, label: label, run: run})
+	m.updateConnectionStatus()
+	return util.ReportWarn(fmt.Sprintf("Daemon '%s' is offline; %s will run when it reconnects", daemon, label))
+}
+
+// agentDaemon returns the daemon agentName was last seen on, or "local".
+func (m *Model) agentDaemon(agentName string) string {
+	for key := range m.agentStatuses {
+		if idx := strings.LastIndex(key, "@"); idx > 0 && key[:idx] == agentName {
+			return key[idx+1:]
+		}
+	}
+	return "local"
+}
+
+// updateConnectionStatus shows the offline daemons and queued actions in
+// the header.
+func (m *Model) updateConnectionStatus() {
+	if m.header == nil {
+		return
+	}
+	offline := make([]string, 0, len(m.offlineDaemons))
+	for daemon := range m.offlineDaemons {
+		offline = append(offline, daemon)
+	}
+	sort.Strings(offline)
+	m.header.SetOffline(offline, len(m.queuedActions))
+}
@@ -0,0 +1,166 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+
+	tooltypes "tui/tools/types"
+)
+
+// longStreamThreshold is how long a response has to stream before its
+// completion is worth a desktop notification.
+const longStreamThreshold = 10 * time.Second
+
+// Desktop notification methods, selected with OPPERATOR_NOTIFY.
+const (
+	notifyAuto   = "auto"
+	notifyOSC    = "osc"
+	notifyBell   = "bell"
+	notifyNative = "native"
+	notifyOff    = "off"
+)
+
+// desktopNotifier tells the user that work finished while the terminal was in
+// the background. It relies on terminal focus reporting, so terminals that
+// never report focus changes never receive notifications.
+type desktopNotifier struct {
+	method  string
+	blurred bool
+}
+
+func newDesktopNotifier() *desktopNotifier {
+	method := strings.ToLower(strings.TrimSpace(os.Getenv("OPPERATOR_NOTIFY")))
+	switch method {
+	case notifyOSC, notifyBell, notifyNative, notifyOff:
+	default:
+		method = detectNotifyMethod()
+	}
+	return &desktopNotifier{method: method}
+}
+
+// detectNotifyMethod prefers OSC 777 on terminals known to render it, then a
+// native notifier, and falls back to the terminal bell.
+func detectNotifyMethod() string {
+	switch os.Getenv("TERM_PROGRAM") {
+	case "WezTerm", "ghostty":
+		return notifyOSC
+	}
+	term := os.Getenv("TERM")
+	if strings.HasPrefix(term, "foot") || strings.HasPrefix(term, "rxvt") {
+		return notifyOSC
+	}
+	if nativeNotifier() != "" {
+		return notifyNative
+	}
+	return notifyBell
+}
+
+func nativeNotifier() string {
+	name := "notify-send"
+	if runtime.GOOS == "darwin" {
+		name = "osascript"
+	}
+	if _, err := exec.LookPath(name); err != nil {
+		return ""
+	}
+	return name
+}
+
+func (n *desktopNotifier) setFocused(focused bool) {
+	if n != nil {
+		n.blurred = !focused
+	}
+}
+
+// notify returns a command emitting the notification, or nil when the
+// terminal has focus or notifications are disabled.
+func (n *desktopNotifier) notify(title, body string) tea.Cmd {
+	if n == nil || !n.blurred || n.method == notifyOff {
+		return nil
+	}
+	method := n.method
+	return func() tea.Msg {
+		switch method {
+		case notifyOSC:
+			writeTerminal(fmt.Sprintf("\x1b]777;notify;%s;%s\x07", oscSafe(title), oscSafe(body)))
+		case notifyNative:
+			if err := runNativeNotifier(title, body); err != nil {
+				writeTerminal("\a")
+			}
+		default:
+			writeTerminal("\a")
+		}
+		return nil
+	}
+}
+
+func runNativeNotifier(title, body string) error {
+	switch nativeNotifier() {
+	case "notify-send":
+		return exec.Command("notify-send", "--app-name=Opperator", title, body).Run()
+	case "osascript":
+		script := fmt.Sprintf("display notification %q with title %q", body, title)
+		return exec.Command("osascript", "-e", script).Run()
+	}
+	return fmt.Errorf("no native notifier available")
+}
+
+// writeTerminal writes an escape sequence straight to the terminal, wrapping
+// it for tmux passthrough when running inside tmux.
+func writeTerminal(seq string) {
+	if os.Getenv("TMUX") != "" && seq != "\a" {
+		seq = "\x1bPtmux;" + strings.ReplaceAll(seq, "\x1b", "\x1b\x1b") + "\x1b\\"
+	}
+	_, _ = os.Stdout.WriteString(seq)
+}
+
+// oscSafe strips characters that would terminate or split an OSC 777 payload.
+func oscSafe(s string) string {
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case r == ';':
+			return ','
+		case r < 0x20 || r == 0x7f:
+			return ' '
+		}
+		return r
+	}, s)
+	return strings.TrimSpace(s)
+}
+
+func truncateNotification(s string, max int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if len([]rune(s)) <= max {
+		return s
+	}
+	return string([]rune(s)[:max-1]) + "…"
+}
+
+// notifyAsyncResult announces a finished async task.
+func (m *Model) notifyAsyncResult(result tooltypes.Result) tea.Cmd {
+	name := firstNonEmpty(strings.TrimSpace(result.Name), "Async task")
+	title := name + " finished"
+	if result.IsError {
+		title = name + " failed"
+	}
+	return m.desktop.notify(title, truncateNotification(result.Content, 120))
+}
+
+// notifyStreamDone announces a response that streamed for longer than
+// longStreamThreshold.
+func (m *Model) notifyStreamDone(startedAt time.Time) tea.Cmd {
+	if startedAt.IsZero() {
+		return nil
+	}
+	elapsed := time.Since(startedAt)
+	if elapsed < longStreamThreshold {
+		return nil
+	}
+	return m.desktop.notify("Opperator response ready", fmt.Sprintf("Finished after %s", elapsed.Round(time.Second)))
+}
@@ -76,6 +76,10 @@ func agentStatusMessage(status string) (string, string) {
 		return "stopped", "start before selecting"
 	case "crashed":
 		return "crashed", "inform user and ask to debug"
+	case "crashlooping":
+		return "crashlooping", "keeps crashing on restart; inform user and ask to debug"
+	case "provisioning":
+		return "provisioning", "installing dependencies; wait for it to start"
 	default:
 		return value, ""
 	}
@@ -4,11 +4,10 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"os"
-	"path/filepath"
 	"strings"
 	"time"
 
+	"opperator/config"
 	"opperator/pkg/db"
 	"opperator/pkg/migration"
 )
@@ -19,22 +18,28 @@ type Conversation struct {
 	CreatedAt        int64
 	ActiveAgent      string
 	FocusedAgentName string
+	Workspace        string
+	// Renamed is set once a person named the conversation, which stops
+	// generated titles from replacing its title
+	Renamed bool
+	// Pinned conversations are listed first and never archived automatically
+	Pinned   bool
+	Archived bool
 }
 
 // Store manages conversation metadata persisted to sqlite.
 type Store struct {
 	db *sql.DB
+	// workspace tags new conversations; empty when no workspace is active
+	workspace string
 }
 
 func Open() (*Store, error) {
-	home, err := os.UserHomeDir()
+	dbPath, err := config.GetDatabasePath()
 	if err != nil {
 		return nil, err
 	}
 
-	dir := filepath.Join(home, ".config", "opperator")
-	dbPath := filepath.Join(dir, "opperator.db")
-
 	// Initialize centralized database connection pools
 	if err := db.Initialize(dbPath); err != nil {
 		return nil, err
@@ -46,6 +51,9 @@ func Open() (*Store, error) {
 	}
 
 	s := &Store{db: writeDB}
+	if ws, err := config.ActiveWorkspace(); err == nil && ws != nil {
+		s.workspace = ws.Name
+	}
 
 	// Run migrations automatically
 	migrationRunner := migration.NewRunner(writeDB)
@@ -64,11 +72,15 @@ func (s *Store) Create(ctx context.Context, title string) (Conversation, error)
 	id := fmt.Sprintf("%d", time.Now().UnixNano())
 	ts := time.Now().Unix()
 
+	var workspace interface{}
+	if s.workspace != "" {
+		workspace = s.workspace
+	}
 	_, err := s.db.ExecContext(ctx,
-		`INSERT INTO conversations(id, title, created_at) VALUES(?, ?, ?)`,
-		id, title, ts)
+		`INSERT INTO conversations(id, title, created_at, workspace) VALUES(?, ?, ?, ?)`,
+		id, title, ts, workspace)
 
-	return Conversation{ID: id, Title: title, CreatedAt: ts}, err
+	return Conversation{ID: id, Title: title, CreatedAt: ts, Workspace: s.workspace}, err
 }
 
 func (s *Store) List(ctx context.Context) ([]Conversation, error) {
@@ -79,7 +91,7 @@ func (s *Store) List(ctx context.Context) ([]Conversation, error) {
 	}
 
 	rows, err := readDB.QueryContext(ctx,
-		`SELECT id, title, created_at, active_agent, focused_agent_name FROM conversations ORDER BY created_at DESC`)
+		`SELECT id, title, created_at, active_agent, focused_agent_name, workspace, title_renamed, pinned, archived_at IS NOT NULL FROM conversations ORDER BY created_at DESC`)
 	if err != nil {
 		return nil, err
 	}
@@ -90,13 +102,15 @@ func (s *Store) List(ctx context.Context) ([]Conversation, error) {
 		var c Conversation
 		var agent sql.NullString
 		var focusedAgent sql.NullString
-		rows.Scan(&c.ID, &c.Title, &c.CreatedAt, &agent, &focusedAgent)
+		var workspace sql.NullString
+		rows.Scan(&c.ID, &c.Title, &c.CreatedAt, &agent, &focusedAgent, &workspace, &c.Renamed, &c.Pinned, &c.Archived)
 		if agent.Valid {
 			c.ActiveAgent = agent.String
 		}
 		if focusedAgent.Valid {
 			c.FocusedAgentName = focusedAgent.String
 		}
+		c.Workspace = workspace.String
 		convs = append(convs, c)
 	}
 
@@ -115,6 +129,86 @@ func (s *Store) UpdateTitle(ctx context.Context, id, title string) error {
 	return err
 }
 
+// SetGeneratedTitle replaces the title of a conversation nobody renamed.
+func (s *Store) SetGeneratedTitle(ctx context.Context, id, title string) error {
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE conversations SET title = ? WHERE id = ? AND title_renamed = 0`,
+		title, id)
+	return err
+}
+
+// Rename gives a conversation a title of a person's choosing, which
+// generated titles no longer replace. An empty title hands the title back
+// to generation.
+func (s *Store) Rename(ctx context.Context, id, title string) error {
+	if strings.TrimSpace(title) == "" {
+		_, err := s.db.ExecContext(ctx,
+			`UPDATE conversations SET title_renamed = 0 WHERE id = ?`, id)
+		return err
+	}
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE conversations SET title = ?, title_renamed = 1 WHERE id = ?`,
+		strings.TrimSpace(title), id)
+	return err
+}
+
+// SetPinned pins or unpins a conversation.
+func (s *Store) SetPinned(ctx context.Context, id string, pinned bool) error {
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE conversations SET pinned = ? WHERE id = ?`, pinned, id)
+	return err
+}
+
+// SetArchived archives or restores a conversation. Archiving unpins it.
+func (s *Store) SetArchived(ctx context.Context, id string, archived bool) error {
+	if !archived {
+		_, err := s.db.ExecContext(ctx,
+			`UPDATE conversations SET archived_at = NULL WHERE id = ?`, id)
+		return err
+	}
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE conversations SET archived_at = ?, pinned = 0 WHERE id = ? AND archived_at IS NULL`,
+		time.Now().Unix(), id)
+	return err
+}
+
+// ArchiveIdle archives the unpinned conversations without a message for
+// idle, counting from their creation when they have none, and returns
+// their IDs.
+func (s *Store) ArchiveIdle(ctx context.Context, idle time.Duration) ([]string, error) {
+	if idle <= 0 {
+		return nil, nil
+	}
+	cutoff := time.Now().Add(-idle).Unix()
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id FROM conversations
+		 WHERE archived_at IS NULL AND pinned = 0
+		   AND COALESCE((SELECT MAX(created_at) FROM messages WHERE session_id = conversations.id), created_at) < ?`,
+		cutoff)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	now := time.Now().Unix()
+	for _, id := range ids {
+		if _, err := s.db.ExecContext(ctx,
+			`UPDATE conversations SET archived_at = ? WHERE id = ?`, now, id); err != nil {
+			return nil, err
+		}
+	}
+	return ids, nil
+}
+
 func (s *Store) UpdateActiveAgent(ctx context.Context, id, agent string) error {
 	var value interface{}
 	if strings.TrimSpace(agent) == "" {
@@ -151,9 +245,10 @@ func (s *Store) Get(ctx context.Context, id string) (Conversation, error) {
 	var c Conversation
 	var agent sql.NullString
 	var focusedAgent sql.NullString
+	var workspace sql.NullString
 	row := readDB.QueryRowContext(ctx,
-		`SELECT id, title, created_at, active_agent, focused_agent_name FROM conversations WHERE id = ?`, id)
-	if err := row.Scan(&c.ID, &c.Title, &c.CreatedAt, &agent, &focusedAgent); err != nil {
+		`SELECT id, title, created_at, active_agent, focused_agent_name, workspace, title_renamed, pinned, archived_at IS NOT NULL FROM conversations WHERE id = ?`, id)
+	if err := row.Scan(&c.ID, &c.Title, &c.CreatedAt, &agent, &focusedAgent, &workspace, &c.Renamed, &c.Pinned, &c.Archived); err != nil {
 		return Conversation{}, err
 	}
 	if agent.Valid {
@@ -162,9 +257,39 @@ func (s *Store) Get(ctx context.Context, id string) (Conversation, error) {
 	if focusedAgent.Valid {
 		c.FocusedAgentName = focusedAgent.String
 	}
+	c.Workspace = workspace.String
 	return c, nil
 }
 
+// Workspace is the active workspace new conversations are tagged with, or ""
+func (s *Store) Workspace() string { return s.workspace }
+
+// InWorkspace keeps the conversations tagged with workspace, or all of them
+// when workspace is empty
+func InWorkspace(convs []Conversation, workspace string) []Conversation {
+	if workspace == "" {
+		return convs
+	}
+	var filtered []Conversation
+	for _, c := range convs {
+		if c.Workspace == workspace {
+			filtered = append(filtered, c)
+		}
+	}
+	return filtered
+}
+
+// Unarchived leaves out archived conversations
+func Unarchived(convs []Conversation) []Conversation {
+	var filtered []Conversation
+	for _, c := range convs {
+		if !c.Archived {
+			filtered = append(filtered, c)
+		}
+	}
+	return filtered
+}
+
 func (s *Store) Close() error {
 	return nil
 }
@@ -6,6 +6,8 @@ import (
 	"strings"
 
 	"github.com/zalando/go-keyring"
+
+	"opperator/pkg/keyringerr"
 )
 
 const (
@@ -17,7 +19,11 @@ const (
 var ErrNotFound = errors.New("secret not found")
 
 func GetSecret(name string) (string, error) {
-	secret, err := keyring.Get(serviceName, name)
+	var secret string
+	err := keyringerr.Do(func() (err error) {
+		secret, err = keyring.Get(serviceName, name)
+		return err
+	})
 	if err != nil {
 		if errors.Is(err, keyring.ErrNotFound) {
 			return "", ErrNotFound
@@ -32,14 +38,14 @@ func SetSecret(name, value string) error {
 	if trimmed == "" {
 		return fmt.Errorf("secret %q cannot be empty", name)
 	}
-	if err := keyring.Set(serviceName, name, trimmed); err != nil {
+	if err := keyringerr.Do(func() error { return keyring.Set(serviceName, name, trimmed) }); err != nil {
 		return fmt.Errorf("store secret %q: %w", name, err)
 	}
 	return nil
 }
 
 func DeleteSecret(name string) error {
-	if err := keyring.Delete(serviceName, name); err != nil {
+	if err := keyringerr.Do(func() error { return keyring.Delete(serviceName, name) }); err != nil {
 		if errors.Is(err, keyring.ErrNotFound) {
 			return ErrNotFound
 		}
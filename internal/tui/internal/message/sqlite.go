@@ -5,8 +5,9 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
-	"strings"
 	"time"
+
+	"opperator/pkg/msgstore"
 )
 
 type SQLiteService struct {
@@ -18,17 +19,17 @@ func NewSQLiteService(db *sql.DB) *SQLiteService {
 }
 
 func (s *SQLiteService) Create(ctx context.Context, sessionID string, params CreateMessageParams) (Message, error) {
-	metadata, _ := json.Marshal(params.Parts)
-	now := time.Now().Unix()
+	parts := make([]msgstore.Part, 0, len(params.Parts))
+	for _, part := range params.Parts {
+		parts = append(parts, storedPart(part))
+	}
 
-	res, err := s.db.ExecContext(ctx,
-		`INSERT INTO messages(session_id, role, metadata, created_at, updated_at) VALUES(?, ?, ?, ?, ?)`,
-		sessionID, params.Role, string(metadata), now, now)
+	id, err := msgstore.Insert(ctx, s.db, sessionID, string(params.Role), parts)
 	if err != nil {
 		return Message{}, err
 	}
 
-	id, _ := res.LastInsertId()
+	now := time.Now().Unix()
 	return Message{
 		ID:        fmt.Sprintf("%d", id),
 		SessionID: sessionID,
@@ -40,36 +41,29 @@ func (s *SQLiteService) Create(ctx context.Context, sessionID string, params Cre
 }
 
 func (s *SQLiteService) List(ctx context.Context, sessionID string) ([]Message, error) {
-	rows, err := s.db.QueryContext(ctx,
-		`SELECT id, role, metadata, created_at, updated_at 
-		 FROM messages WHERE session_id = ? ORDER BY id`,
-		sessionID)
+	stored, err := msgstore.List(ctx, s.db, sessionID, msgstore.ListOptions{})
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
-
-	var msgs []Message
-	for rows.Next() {
-		var id int64
-		var role, metadata string
-		var created, updated int64
-
-		rows.Scan(&id, &role, &metadata, &created, &updated)
-
-		parts := s.deserializeParts(metadata)
 
+	msgs := make([]Message, 0, len(stored))
+	for _, m := range stored {
+		var parts []ContentPart
+		for _, p := range m.Parts {
+			if part := contentPart(p); part != nil {
+				parts = append(parts, part)
+			}
+		}
 		msgs = append(msgs, Message{
-			ID:        fmt.Sprintf("%d", id),
+			ID:        fmt.Sprintf("%d", m.ID),
 			SessionID: sessionID,
-			Role:      Role(role),
+			Role:      Role(m.Role),
 			Parts:     parts,
-			CreatedAt: created,
-			UpdatedAt: updated,
+			CreatedAt: m.CreatedAt,
+			UpdatedAt: m.UpdatedAt,
 		})
 	}
-
-	return msgs, rows.Err()
+	return msgs, nil
 }
 
 func (s *SQLiteService) DeleteBySession(ctx context.Context, sessionID string) error {
@@ -77,47 +71,47 @@ func (s *SQLiteService) DeleteBySession(ctx context.Context, sessionID string) e
 	return err
 }
 
-func (s *SQLiteService) deserializeParts(metadata string) []ContentPart {
-	if metadata == "" {
-		return []ContentPart{}
-	}
-
-	var rawParts []json.RawMessage
-	if err := json.Unmarshal([]byte(metadata), &rawParts); err != nil {
-		return []ContentPart{}
+// storedPart converts a content part to its stored form.
+func storedPart(part ContentPart) msgstore.Part {
+	data, _ := json.Marshal(part)
+	p := msgstore.Part{Data: data}
+	switch v := part.(type) {
+	case TextContent:
+		p.Kind, p.Text = msgstore.KindText, v.Text
+	case ToolCall:
+		p.Kind, p.ToolCallID, p.ToolName, p.ToolInput = msgstore.KindToolCall, v.ID, v.Name, v.Input
+	case ToolResult:
+		p.Kind, p.ToolCallID, p.ToolName, p.Text, p.IsError = msgstore.KindToolResult, v.ToolCallID, v.Name, v.Content, v.IsError
+	case Finish:
+		p.Kind = msgstore.KindFinish
+	case TurnSummary:
+		p.Kind = msgstore.KindTurnSummary
 	}
-
-	var parts []ContentPart
-	for _, raw := range rawParts {
-		if part := s.deserializeSinglePart(raw); part != nil {
-			parts = append(parts, part)
-		}
-	}
-	return parts
+	return p
 }
 
-func (s *SQLiteService) deserializeSinglePart(raw json.RawMessage) ContentPart {
-	var textContent TextContent
-	if err := json.Unmarshal(raw, &textContent); err == nil && textContent.Text != "" {
-		return textContent
-	}
-
-	var toolCall ToolCall
-	if err := json.Unmarshal(raw, &toolCall); err == nil && toolCall.ID != "" {
-		return toolCall
-	}
-
-	var toolResult ToolResult
-	if err := json.Unmarshal(raw, &toolResult); err == nil && toolResult.ToolCallID != "" {
-		return toolResult
-	}
-
-	var turnSummary TurnSummary
-	if err := json.Unmarshal(raw, &turnSummary); err == nil {
-		if strings.TrimSpace(turnSummary.AgentID) != "" || turnSummary.DurationMilli > 0 || strings.TrimSpace(turnSummary.AgentName) != "" || strings.TrimSpace(turnSummary.AgentColor) != "" {
-			return turnSummary
+// contentPart converts a stored part back to its content part, or returns
+// nil for kinds the TUI does not show.
+func contentPart(p msgstore.Part) ContentPart {
+	switch p.Kind {
+	case msgstore.KindText:
+		return TextContent{Text: p.Text}
+	case msgstore.KindToolCall:
+		var tc ToolCall
+		json.Unmarshal(p.Data, &tc)
+		tc.ID, tc.Name, tc.Input = p.ToolCallID, p.ToolName, p.ToolInput
+		return tc
+	case msgstore.KindToolResult:
+		var tr ToolResult
+		json.Unmarshal(p.Data, &tr)
+		tr.ToolCallID, tr.Name, tr.Content, tr.IsError = p.ToolCallID, p.ToolName, p.Text, p.IsError
+		return tr
+	case msgstore.KindTurnSummary:
+		var ts TurnSummary
+		if err := json.Unmarshal(p.Data, &ts); err != nil {
+			return nil
 		}
+		return ts
 	}
-
 	return nil
 }
@@ -36,7 +36,7 @@ func ParseCommandArguments(ctx context.Context, rawInput string, schema []protoc
 
 	// Get API key
 	apiKey, err := keyring.GetAPIKey()
-	if err != nil {
+	if err != nil && opperclient.Local() == nil {
 		if err == keyring.ErrNotFound {
 			return nil, fmt.Errorf("Opper API key not configured. Run: opperator secret create --name=%s", keyring.OpperAPIKeyName)
 		}
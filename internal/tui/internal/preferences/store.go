@@ -4,11 +4,10 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"os"
-	"path/filepath"
 	"strconv"
 	"time"
 
+	"opperator/config"
 	"opperator/pkg/db"
 	"opperator/pkg/migration"
 )
@@ -19,14 +18,11 @@ type Store struct {
 }
 
 func Open() (*Store, error) {
-	home, err := os.UserHomeDir()
+	dbPath, err := config.GetDatabasePath()
 	if err != nil {
 		return nil, err
 	}
 
-	dir := filepath.Join(home, ".config", "opperator")
-	dbPath := filepath.Join(dir, "opperator.db")
-
 	// Initialize centralized database connection pools
 	if err := db.Initialize(dbPath); err != nil {
 		return nil, err
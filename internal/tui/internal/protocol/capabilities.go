@@ -0,0 +1,12 @@
+package protocol
+
+// Capabilities mirrors the feature set the daemon negotiated with an agent.
+type Capabilities struct {
+	ProtocolVersion int    `json:"protocol_version"`
+	SDKVersion      string `json:"sdk_version,omitempty"`
+	AsyncCommands   bool   `json:"async_commands"`
+	LifecycleEvents bool   `json:"lifecycle_events"`
+	CustomSections  bool   `json:"custom_sections"`
+	StructuredLogs  bool   `json:"structured_logs"`
+	Cancellation    bool   `json:"cancellation"`
+}
@@ -45,6 +45,13 @@ type CommandArgument struct {
 	Enum        []interface{}          `json:"enum,omitempty"`
 	Items       map[string]interface{} `json:"items,omitempty"`      // Schema for array items
 	Properties  map[string]interface{} `json:"properties,omitempty"` // Schema for object properties
+	Minimum     *float64               `json:"minimum,omitempty"`
+	Maximum     *float64               `json:"maximum,omitempty"`
+	MinLength   *int                   `json:"min_length,omitempty"`
+	MaxLength   *int                   `json:"max_length,omitempty"`
+	MinItems    *int                   `json:"min_items,omitempty"`
+	MaxItems    *int                   `json:"max_items,omitempty"`
+	Pattern     string                 `json:"pattern,omitempty"` // Regular expression string values must match
 }
 
 func NormalizeCommandDescriptors(defs []CommandDescriptor) []CommandDescriptor {
@@ -217,6 +224,13 @@ func normalizeCommandArguments(args []CommandArgument) []CommandArgument {
 			Enum:        enumValues,
 			Items:       arg.Items,
 			Properties:  arg.Properties,
+			Minimum:     arg.Minimum,
+			Maximum:     arg.Maximum,
+			MinLength:   arg.MinLength,
+			MaxLength:   arg.MaxLength,
+			MinItems:    arg.MinItems,
+			MaxItems:    arg.MaxItems,
+			Pattern:     validPattern(arg.Pattern),
 		})
 		seen[name] = struct{}{}
 	}
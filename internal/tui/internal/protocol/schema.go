@@ -0,0 +1,187 @@
+package protocol
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ParametersSchema returns the JSON Schema of the command's arguments as an
+// object, as given to models for tool calls.
+func (d CommandDescriptor) ParametersSchema() map[string]interface{} {
+	properties := make(map[string]interface{}, len(d.Arguments))
+	required := make([]string, 0, len(d.Arguments))
+	for _, arg := range d.Arguments {
+		name := strings.TrimSpace(arg.Name)
+		if name == "" {
+			continue
+		}
+		properties[name] = arg.JSONSchema()
+		if arg.Required {
+			required = append(required, name)
+		}
+	}
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// JSONSchema returns the JSON Schema of the argument. Item and property
+// schemas are JSON Schemas themselves; a property may also be marked
+// required with "required": true, which is moved to its object's required
+// list, and snake_case keywords such as min_length are accepted.
+func (a CommandArgument) JSONSchema() map[string]interface{} {
+	typeName := strings.ToLower(strings.TrimSpace(a.Type))
+	if typeName == "" {
+		typeName = "string"
+	}
+	schema := map[string]interface{}{"type": typeName}
+	if desc := strings.TrimSpace(a.Description); desc != "" {
+		schema["description"] = desc
+	}
+	if a.Default != nil {
+		schema["default"] = a.Default
+	}
+	if len(a.Enum) > 0 {
+		schema["enum"] = a.Enum
+	}
+	if a.Minimum != nil {
+		schema["minimum"] = *a.Minimum
+	}
+	if a.Maximum != nil {
+		schema["maximum"] = *a.Maximum
+	}
+	if a.MinLength != nil {
+		schema["minLength"] = *a.MinLength
+	}
+	if a.MaxLength != nil {
+		schema["maxLength"] = *a.MaxLength
+	}
+	if a.MinItems != nil {
+		schema["minItems"] = *a.MinItems
+	}
+	if a.MaxItems != nil {
+		schema["maxItems"] = *a.MaxItems
+	}
+	if pattern := validPattern(a.Pattern); pattern != "" {
+		schema["pattern"] = pattern
+	}
+	if len(a.Items) > 0 {
+		schema["items"] = a.Items
+	}
+	if len(a.Properties) > 0 {
+		schema["properties"] = a.Properties
+	}
+	return normalizeSchema(schema)
+}
+
+// schemaKeywords maps snake_case spellings agents may use to JSON Schema.
+var schemaKeywords = map[string]string{
+	"min_length":            "minLength",
+	"max_length":            "maxLength",
+	"min_items":             "minItems",
+	"max_items":             "maxItems",
+	"additional_properties": "additionalProperties",
+}
+
+// normalizeSchema returns a copy of schema with keywords in their JSON
+// Schema spelling, lowercase types, valid patterns and required properties
+// listed on their object, recursing into items and properties.
+func normalizeSchema(schema map[string]interface{}) map[string]interface{} {
+	if schema == nil {
+		return nil
+	}
+	out := make(map[string]interface{}, len(schema))
+	for key, value := range schema {
+		if canonical, ok := schemaKeywords[key]; ok {
+			key = canonical
+		}
+		out[key] = value
+	}
+	if t, ok := out["type"].(string); ok {
+		out["type"] = strings.ToLower(strings.TrimSpace(t))
+	}
+	if p, ok := out["pattern"].(string); ok {
+		if p = validPattern(p); p == "" {
+			delete(out, "pattern")
+		} else {
+			out["pattern"] = p
+		}
+	}
+	if items, ok := out["items"].(map[string]interface{}); ok {
+		out["items"] = normalizeSchema(items)
+	}
+
+	properties, ok := out["properties"].(map[string]interface{})
+	if !ok {
+		return out
+	}
+	var required []string
+	switch r := out["required"].(type) {
+	case []interface{}:
+		for _, name := range r {
+			if s, ok := name.(string); ok {
+				required = append(required, s)
+			}
+		}
+	case []string:
+		required = append(required, r...)
+	}
+	normalized := make(map[string]interface{}, len(properties))
+	for name, value := range properties {
+		sub, ok := value.(map[string]interface{})
+		if !ok {
+			normalized[name] = value
+			continue
+		}
+		if flag, ok := sub["required"].(bool); ok {
+			if flag {
+				required = append(required, name)
+			}
+			trimmed := make(map[string]interface{}, len(sub))
+			for k, v := range sub {
+				if k != "required" {
+					trimmed[k] = v
+				}
+			}
+			sub = trimmed
+		}
+		normalized[name] = normalizeSchema(sub)
+	}
+	out["properties"] = normalized
+	if len(required) > 0 {
+		sort.Strings(required)
+		out["required"] = dedupe(required)
+	} else {
+		delete(out, "required")
+	}
+	return out
+}
+
+// validPattern returns pattern when it compiles, and "" otherwise, so an
+// invalid pattern doesn't make the whole schema unusable.
+func validPattern(pattern string) string {
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "" {
+		return ""
+	}
+	if _, err := regexp.Compile(pattern); err != nil {
+		return ""
+	}
+	return pattern
+}
+
+func dedupe(sorted []string) []string {
+	out := sorted[:0]
+	for i, s := range sorted {
+		if i == 0 || s != sorted[i-1] {
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,149 @@
+// Package taskstats summarises the finished async tasks in the tool_tasks
+// table over time: how many finished per hour or day, how many failed, and
+// how long each agent command or tool took.
+package taskstats
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Bucket is one hour or day of finished tasks.
+type Bucket struct {
+	Start    time.Time
+	Complete int
+	Failed   int
+}
+
+// Total is how many tasks finished in the bucket.
+func (b Bucket) Total() int { return b.Complete + b.Failed }
+
+// Command is the finished tasks of one agent command or tool.
+type Command struct {
+	// Name is agent/command for agent commands and the tool name otherwise
+	Name   string
+	Total  int
+	Failed int
+	// Average is the mean time from submission to completion
+	Average time.Duration
+	// Averages is the mean duration per bucket, zero where none finished
+	Averages []time.Duration
+}
+
+// Report is the finished tasks of the last len(Buckets) hours or days.
+type Report struct {
+	Interval time.Duration
+	Buckets  []Bucket
+	Commands []Command
+}
+
+// Total is how many tasks finished in the report's window.
+func (r Report) Total() (complete, failed int) {
+	for _, b := range r.Buckets {
+		complete += b.Complete
+		failed += b.Failed
+	}
+	return complete, failed
+}
+
+// Load summarises the tasks finished in the last count intervals, the last
+// one being the interval now falls in. Commands are sorted by how many
+// tasks they ran.
+func Load(ctx context.Context, db *sql.DB, interval time.Duration, count int, now time.Time) (Report, error) {
+	if interval <= 0 || count <= 0 {
+		return Report{}, fmt.Errorf("invalid interval")
+	}
+	first := truncate(now, interval).Add(-time.Duration(count-1) * interval)
+	report := Report{Interval: interval, Buckets: make([]Bucket, count)}
+	for i := range report.Buckets {
+		report.Buckets[i].Start = first.Add(time.Duration(i) * interval)
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT tool_name, COALESCE(agent_name, ''), COALESCE(command_name, ''), status, created_at, completed_at
+		FROM tool_tasks
+		WHERE completed_at IS NOT NULL AND completed_at >= ? AND status IN ('complete', 'failed')`,
+		first.UnixNano())
+	if err != nil {
+		return Report{}, fmt.Errorf("failed to query tasks: %w", err)
+	}
+	defer rows.Close()
+
+	type totals struct {
+		Command
+		sum    time.Duration
+		sums   []time.Duration
+		counts []int
+	}
+	byName := make(map[string]*totals)
+	for rows.Next() {
+		var tool, agent, command, status string
+		var created, completed int64
+		if err := rows.Scan(&tool, &agent, &command, &status, &created, &completed); err != nil {
+			return Report{}, err
+		}
+		at := time.Unix(0, completed)
+		i := int(at.Sub(first) / interval)
+		if i < 0 || i >= count {
+			continue
+		}
+		failed := status == "failed"
+		if failed {
+			report.Buckets[i].Failed++
+		} else {
+			report.Buckets[i].Complete++
+		}
+
+		name := tool
+		if agent != "" && command != "" {
+			name = agent + "/" + command
+		}
+		t, ok := byName[name]
+		if !ok {
+			t = &totals{Command: Command{Name: name}, sums: make([]time.Duration, count), counts: make([]int, count)}
+			byName[name] = t
+		}
+		duration := max(at.Sub(time.Unix(0, created)), 0)
+		t.Total++
+		if failed {
+			t.Failed++
+		}
+		t.sum += duration
+		t.sums[i] += duration
+		t.counts[i]++
+	}
+	if err := rows.Err(); err != nil {
+		return Report{}, err
+	}
+
+	for _, t := range byName {
+		t.Average = t.sum / time.Duration(t.Total)
+		t.Averages = make([]time.Duration, count)
+		for i, n := range t.counts {
+			if n > 0 {
+				t.Averages[i] = t.sums[i] / time.Duration(n)
+			}
+		}
+		report.Commands = append(report.Commands, t.Command)
+	}
+	sort.Slice(report.Commands, func(i, j int) bool {
+		if report.Commands[i].Total != report.Commands[j].Total {
+			return report.Commands[i].Total > report.Commands[j].Total
+		}
+		return report.Commands[i].Name < report.Commands[j].Name
+	})
+	return report, nil
+}
+
+// truncate rounds t down to interval in local time, so days start at local
+// midnight.
+func truncate(t time.Time, interval time.Duration) time.Time {
+	if interval >= 24*time.Hour {
+		y, m, d := t.Date()
+		return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+	}
+	return t.Truncate(interval)
+}
@@ -33,12 +33,15 @@ func defaultKeyHandlers() map[string]keyHandler {
 		"ctrl+j":    handleNewlineKey,
 		"ctrl+s":    handleSessionsKey,
 		"ctrl+b":    handleToggleSidebarKey,
+		"ctrl+r":    handleVoiceKey,
 		"enter":     handleEnterKey,
 		" ":         handleSpaceKey,
+		"e":         handleExpandToolKey,
 	}
 }
 
-func handleQuitKey(_ *Model, _ keyEventContext) (tea.Cmd, bool) {
+func handleQuitKey(m *Model, _ keyEventContext) (tea.Cmd, bool) {
+	m.input.CancelVoice()
 	return tea.Quit, true
 }
 
@@ -192,6 +195,14 @@ func handleEscapeKey(m *Model, ctx keyEventContext) (tea.Cmd, bool) {
 	if m.toolDetail != nil {
 		return m.closeToolDetail(), true
 	}
+	if m.input.IsRecording() {
+		m.input.CancelVoice()
+		return nil, true
+	}
+	if m.pendingSectionInput != nil {
+		m.cancelSectionInput()
+		return nil, true
+	}
 	if ctx.busy {
 		return m.cancel(), true
 	}
@@ -219,7 +230,7 @@ func handleSessionsKey(m *Model, _ keyEventContext) (tea.Cmd, bool) {
 		return nil, false
 	}
 	convs, _ := m.convStore.List(context.Background())
-	m.convModal = cmpconversations.New(convs, m.sessionID)
+	m.convModal = cmpconversations.New(convs, m.sessionID, m.convStore.Workspace())
 	initCmd := m.convModal.Init()
 	var sizeCmd tea.Cmd
 	if m.w > 0 && m.h > 0 {
@@ -230,8 +241,7 @@ func handleSessionsKey(m *Model, _ keyEventContext) (tea.Cmd, bool) {
 
 func handleEnterKey(m *Model, _ keyEventContext) (tea.Cmd, bool) {
 	if m.sidebar.HasFocus() {
-		m.sidebar.ToggleSection()
-		return nil, true
+		return m.sidebar.Activate(), true
 	}
 
 	if m.toolDetail != nil {
@@ -249,14 +259,30 @@ func handleEnterKey(m *Model, _ keyEventContext) (tea.Cmd, bool) {
 	return m.submitInput(val), true
 }
 
+// handleVoiceKey toggles push-to-talk: the first press starts recording, the
+// second transcribes the recording into the input box.
+func handleVoiceKey(m *Model, _ keyEventContext) (tea.Cmd, bool) {
+	if !m.input.IsFocused() {
+		return nil, false
+	}
+	return m.input.ToggleVoice(), true
+}
+
 func handleSpaceKey(m *Model, _ keyEventContext) (tea.Cmd, bool) {
 	if m.sidebar.HasFocus() {
-		m.sidebar.ToggleSection()
-		return nil, true
+		return m.sidebar.Activate(), true
 	}
 	return nil, false
 }
 
+// handleExpandToolKey expands or folds the output of the focused tool call.
+func handleExpandToolKey(m *Model, _ keyEventContext) (tea.Cmd, bool) {
+	if m.input.IsFocused() || m.sidebar.HasFocus() || m.messages == nil {
+		return nil, false
+	}
+	return nil, m.messages.ToggleFocusedToolExpanded()
+}
+
 func handleToggleSidebarKey(m *Model, _ keyEventContext) (tea.Cmd, bool) {
 	m.sidebarVisible = !m.sidebarVisible
 
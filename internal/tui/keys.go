@@ -1,6 +1,10 @@
 package tui
 
-import "github.com/charmbracelet/bubbles/v2/key"
+import (
+	"github.com/charmbracelet/bubbles/v2/key"
+
+	cmpmessages "tui/components/messages"
+)
 
 type keyMap struct {
 	Help          key.Binding
@@ -14,6 +18,8 @@ type keyMap struct {
 	Sessions      key.Binding
 	SwitchAgent   key.Binding
 	ToggleSidebar key.Binding
+	Voice         key.Binding
+	ExpandOutput  key.Binding
 }
 
 // dynamicKeyMap adapts the help bindings based on focus state.
@@ -21,36 +27,43 @@ type dynamicKeyMap struct {
 	km            keyMap
 	inputFocused  bool
 	cancelVisible bool
+	toolDetail    bool
 }
 
 func (d dynamicKeyMap) ShortHelp() []key.Binding {
+	if d.toolDetail {
+		return []key.Binding{d.km.Cancel, cmpmessages.SearchKey, toolDetailCopyKey, toolDetailSaveKey, toolDetailOpenKey, d.km.Quit}
+	}
 	var keys []key.Binding
 	if d.cancelVisible {
 		keys = append(keys, d.km.Cancel)
 	}
 	keys = append(keys, d.km.Sessions, d.km.SwitchAgent)
 	if d.inputFocused {
-		keys = append(keys, d.km.Newline, d.km.ToggleFocus, d.km.Quit)
+		keys = append(keys, d.km.Newline, d.km.Voice, d.km.ToggleFocus, d.km.Quit)
 		return keys
 	}
-	keys = append(keys, d.km.FocusPrev, d.km.FocusNext, d.km.ToggleFocus, d.km.ClearFocus, d.km.Quit)
+	keys = append(keys, d.km.FocusPrev, d.km.FocusNext, d.km.ToggleFocus, d.km.ClearFocus, d.km.ExpandOutput, d.km.Quit)
 	return keys
 }
 
 func (d dynamicKeyMap) FullHelp() [][]key.Binding {
+	if d.toolDetail {
+		return [][]key.Binding{d.ShortHelp()}
+	}
 	if d.inputFocused {
 		keys := []key.Binding{}
 		if d.cancelVisible {
 			keys = append(keys, d.km.Cancel)
 		}
-		keys = append(keys, d.km.Sessions, d.km.SwitchAgent, d.km.Newline, d.km.ToggleFocus, d.km.Quit)
+		keys = append(keys, d.km.Sessions, d.km.SwitchAgent, d.km.Newline, d.km.Voice, d.km.ToggleFocus, d.km.Quit)
 		return [][]key.Binding{keys}
 	}
 	keys := []key.Binding{}
 	if d.cancelVisible {
 		keys = append(keys, d.km.Cancel)
 	}
-	keys = append(keys, d.km.Sessions, d.km.SwitchAgent, d.km.FocusPrev, d.km.FocusNext, d.km.ToggleFocus, d.km.ClearFocus, d.km.Quit)
+	keys = append(keys, d.km.Sessions, d.km.SwitchAgent, d.km.FocusPrev, d.km.FocusNext, d.km.ToggleFocus, d.km.ClearFocus, d.km.ExpandOutput, d.km.Quit)
 	return [][]key.Binding{keys}
 }
 
@@ -107,4 +120,12 @@ var defaultKeys = keyMap{
 		key.WithKeys("ctrl+b"),
 		key.WithHelp("ctrl+b", "toggle sidebar"),
 	),
+	Voice: key.NewBinding(
+		key.WithKeys("ctrl+r"),
+		key.WithHelp("ctrl+r", "voice input"),
+	),
+	ExpandOutput: key.NewBinding(
+		key.WithKeys("e"),
+		key.WithHelp("e", "expand output"),
+	),
 }
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"opperator/config"
+	"opperator/pkg/prototrace"
 	"tui/cache"
 	"tui/components/sidebar"
 	"tui/internal/protocol"
@@ -251,16 +252,35 @@ func FetchAgentMetadata(ctx context.Context, name string) (AgentMetadata, error)
 
 	// Try to fetch commands, but don't fail if agent is stopped/crashed
 	// The description, system prompt, and color are already set from the config
+	cmds, err := FetchAgentCommands(ctx, result.Name, agentDaemon)
+	if err != nil {
+		// Agent might be stopped - return metadata without commands (but don't cache)
+		return result, nil
+	}
+	result.Commands = cmds
+
+	// Cache the successful result (only for remote agents)
+	if isRemoteAgent {
+		agentMetadataCache.Set(trimmed, result)
+	}
+
+	return result, nil
+}
+
+// FetchAgentCommands retrieves the normalized commands of an agent from the
+// daemon running it.
+func FetchAgentCommands(ctx context.Context, name, daemonName string) ([]protocol.CommandDescriptor, error) {
+	if daemonName == "" {
+		daemonName = "local"
+	}
 	cmdPayload := struct {
 		Type      string `json:"type"`
 		AgentName string `json:"agent_name"`
-	}{Type: "list_commands", AgentName: result.Name}
+	}{Type: "list_commands", AgentName: name}
 
-	// Query the correct daemon for commands
-	cmdData, err := tooling.IPCRequestToDaemon(ctx, agentDaemon, cmdPayload)
+	cmdData, err := tooling.IPCRequestToDaemon(ctx, daemonName, cmdPayload)
 	if err != nil {
-		// Agent might be stopped - return metadata without commands (but don't cache)
-		return result, nil
+		return nil, err
 	}
 
 	var cmdResp struct {
@@ -269,22 +289,15 @@ func FetchAgentMetadata(ctx context.Context, name string) (AgentMetadata, error)
 		Commands []protocol.CommandDescriptor `json:"commands"`
 	}
 	if err := json.Unmarshal(cmdData, &cmdResp); err != nil {
-		// Failed to decode - return metadata without commands (but don't cache)
-		return result, nil
+		return nil, fmt.Errorf("decode commands response: %w", err)
 	}
 	if !cmdResp.Success {
-		// Command list failed (agent might be stopped) - return metadata without commands (but don't cache)
-		return result, nil
-	}
-
-	result.Commands = protocol.NormalizeCommandDescriptors(cmdResp.Commands)
-
-	// Cache the successful result (only for remote agents)
-	if isRemoteAgent {
-		agentMetadataCache.Set(trimmed, result)
+		if cmdResp.Error == "" {
+			cmdResp.Error = "unknown error"
+		}
+		return nil, errors.New(cmdResp.Error)
 	}
-
-	return result, nil
+	return protocol.NormalizeCommandDescriptors(cmdResp.Commands), nil
 }
 
 // FetchAgentLogs retrieves recent logs for the given agent name.
@@ -346,6 +359,54 @@ func FetchAgentLogs(ctx context.Context, name string, maxLines int) ([]string, e
 	return logs, nil
 }
 
+// FetchProtocolFrames retrieves the protocol messages the daemon kept for
+// the given agent name, oldest first.
+func FetchProtocolFrames(ctx context.Context, name string) ([]prototrace.Frame, error) {
+	trimmed := strings.TrimSpace(name)
+	if trimmed == "" {
+		return nil, fmt.Errorf("agent name required")
+	}
+
+	agentDaemon := "local"
+	agents, err := ListAgents(ctx)
+	if err == nil {
+		for _, agent := range agents {
+			if strings.EqualFold(agent.Name, trimmed) {
+				if agent.Daemon != "" {
+					agentDaemon = agent.Daemon
+				}
+				break
+			}
+		}
+	}
+
+	payload := struct {
+		Type      string `json:"type"`
+		AgentName string `json:"agent_name"`
+	}{Type: "protocol_frames", AgentName: trimmed}
+
+	data, err := tooling.IPCRequestToDaemon(ctx, agentDaemon, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp struct {
+		Success bool               `json:"success"`
+		Error   string             `json:"error"`
+		Frames  []prototrace.Frame `json:"frames"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("decode protocol frames response: %w", err)
+	}
+	if !resp.Success {
+		if resp.Error == "" {
+			resp.Error = "unknown error"
+		}
+		return nil, errors.New(resp.Error)
+	}
+	return resp.Frames, nil
+}
+
 // FetchAgentCustomSections retrieves custom sidebar sections for the given agent name.
 func FetchAgentCustomSections(ctx context.Context, name string) ([]sidebar.CustomSection, error) {
 	trimmed := strings.TrimSpace(name)
@@ -224,12 +224,11 @@ func (a *asyncTracker) poll(state *watchState) {
 		}
 		task, err := tooling.FetchAsyncTaskFromDaemon(context.Background(), reg.TaskID, daemonName)
 		if err != nil {
-			errMsg := strings.TrimSpace(err.Error())
-			if errMsg != "" && strings.Contains(strings.ToLower(errMsg), "not found") {
+			if tooling.IsDaemonError(err, tooling.DaemonErrNotFound) {
 				a.emit(AsyncToolUpdateMsg{
 					SessionID: reg.SessionID,
 					CallID:    reg.CallID,
-					Error:     errMsg,
+					Error:     strings.TrimSpace(err.Error()),
 				})
 				return
 			}
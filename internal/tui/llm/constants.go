@@ -1,5 +0,0 @@
-package llm
-
-const (
-	maxFollowPasses = 60
-)
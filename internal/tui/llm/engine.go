@@ -8,10 +8,13 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea/v2"
 
+	"opperator/pkg/loopdetect"
+	"opperator/pkg/tracing"
 	"tui/internal/keyring"
 	"tui/opper"
 	"tui/lsp"
@@ -29,6 +32,7 @@ type Engine struct {
 	invocationDir string
 	async         *asyncTracker
 	secretPrompts secretprompt.Service
+	mockTools     atomic.Pointer[tooling.MockFixtures]
 }
 
 func NewEngine(permissions permission.Service, secrets secretprompt.Service, workingDir string, invocationDir string, manager *lsp.Manager) *Engine {
@@ -46,6 +50,15 @@ func NewEngine(permissions permission.Service, secrets secretprompt.Service, wor
 	}
 }
 
+// SetMockTools resolves tool calls from fixtures instead of running them;
+// nil runs them for real again.
+func (e *Engine) SetMockTools(f *tooling.MockFixtures) {
+	if e == nil {
+		return
+	}
+	e.mockTools.Store(f)
+}
+
 func (e *Engine) AsyncUpdates() <-chan AsyncToolUpdateMsg {
 	if e == nil || e.async == nil {
 		return nil
@@ -84,6 +97,8 @@ type streamPhaseResult struct {
 	toolResults []tooltypes.Result
 	hadActivity bool
 	spanID      string
+	// loop is set when the phase's tool calls complete a loop
+	loop *loopdetect.LoopError
 }
 
 type streamToolCollector struct {
@@ -204,7 +219,7 @@ func (e *Engine) Request(adapter Adapter) (tea.Cmd, context.CancelFunc, chan tea
 
 	cmd := func() tea.Msg {
 		apiKey, err := keyring.GetAPIKey()
-		if err != nil {
+		if err != nil && opper.Local() == nil {
 			close(ch)
 			cancel()
 			if errors.Is(err, keyring.ErrNotFound) {
@@ -239,12 +254,19 @@ func (e *Engine) runSession(
 	defer close(ch)
 	defer cancel()
 
+	ctx, span := tracing.Start(ctx, "tui.session")
+	defer span.End()
+
 	turnStart := time.Now()
+	guard := newTurnGuard(agentKey(adapter))
+	ctx, cancelTurn := guard.turnContext(ctx)
+	defer cancelTurn()
 
-	req := e.buildStreamRequest(adapter, specs)
+	req := e.buildStreamRequest(adapter, specs, guard)
 
-	res, err := e.streamPhase(ctx, adapter, ch, client, req, "INITIAL", "TOOL RESULTS")
+	res, err := e.streamPhase(ctx, adapter, ch, client, req, guard, "INITIAL", "TOOL RESULTS")
 	if err != nil {
+		recordFailedPhase(adapter, res)
 		adapter.RecordTurnCompletion(time.Since(turnStart))
 		ch <- StreamDoneMsg{Err: err}
 		return
@@ -257,7 +279,12 @@ func (e *Engine) runSession(
 	if len(res.toolCalls) > 0 {
 		adapter.RecordAssistantToolCalls(res.toolCalls, res.content)
 		adapter.RecordToolResults(res.toolResults)
-		e.followUpLoop(ctx, adapter, ch, client, specs, 1, turnStart)
+		if res.loop != nil {
+			adapter.RecordTurnCompletion(time.Since(turnStart))
+			ch <- StreamDoneMsg{Err: res.loop}
+			return
+		}
+		e.followUpLoop(ctx, adapter, ch, client, specs, guard, 1, turnStart)
 		return
 	}
 
@@ -283,14 +310,15 @@ func (e *Engine) followUpLoop(
 	ch chan tea.Msg,
 	client *opper.Opper,
 	specs []tooling.Spec,
+	guard *turnGuard,
 	pass int,
 	turnStart time.Time,
 ) {
 	currentPass := pass
 	for {
-		if currentPass > maxFollowPasses {
+		if currentPass >= guard.limits.MaxRounds {
 			adapter.RecordTurnCompletion(time.Since(turnStart))
-			ch <- StreamDoneMsg{Err: fmt.Errorf("max follow-up passes reached")}
+			ch <- StreamDoneMsg{Err: fmt.Errorf("max follow-up passes reached (%d); raise max_rounds in conversation.yaml", guard.limits.MaxRounds)}
 			return
 		}
 
@@ -298,10 +326,11 @@ func (e *Engine) followUpLoop(
 
 		label := fmt.Sprintf("FOLLOW-UP %d", currentPass)
 		resultsLabel := fmt.Sprintf("TOOL RESULTS %d", currentPass)
-		req := e.buildStreamRequest(adapter, specs)
+		req := e.buildStreamRequest(adapter, specs, guard)
 
-		res, err := e.streamPhase(ctx, adapter, ch, client, req, label, resultsLabel)
+		res, err := e.streamPhase(ctx, adapter, ch, client, req, guard, label, resultsLabel)
 		if err != nil {
+			recordFailedPhase(adapter, res)
 			adapter.RecordTurnCompletion(time.Since(turnStart))
 			ch <- StreamDoneMsg{Err: err}
 			return
@@ -314,6 +343,11 @@ func (e *Engine) followUpLoop(
 		if len(res.toolCalls) > 0 {
 			adapter.RecordAssistantToolCalls(res.toolCalls, res.content)
 			adapter.RecordToolResults(res.toolResults)
+			if res.loop != nil {
+				adapter.RecordTurnCompletion(time.Since(turnStart))
+				ch <- StreamDoneMsg{Err: res.loop}
+				return
+			}
 			currentPass++
 			continue
 		}
@@ -333,7 +367,68 @@ func (e *Engine) followUpLoop(
 	}
 }
 
+// recordFailedPhase keeps what a phase that failed partway produced: the
+// tool calls that ran before the round timed out, or the text of an answer
+// whose stream was cut off.
+func recordFailedPhase(adapter Adapter, res streamPhaseResult) {
+	switch {
+	case len(res.toolCalls) > 0:
+		adapter.RecordAssistantToolCalls(res.toolCalls, res.content)
+		adapter.RecordToolResults(res.toolResults)
+	case res.content != "":
+		adapter.RecordAssistantContent(res.content + "\n\n[response interrupted]")
+	}
+}
+
+// streamPhase runs one model call and its tool calls inside a trace span,
+// bounded by the guard's turn timeout and checked for loops.
 func (e *Engine) streamPhase(
+	ctx context.Context,
+	adapter Adapter,
+	ch chan tea.Msg,
+	client *opper.Opper,
+	req opper.StreamRequest,
+	guard *turnGuard,
+	label string,
+	resultsLabel string,
+) (streamPhaseResult, error) {
+	ctx, span := tracing.Start(ctx, "tui.stream_phase", "llm.phase", label)
+	defer span.End()
+	roundCtx, cancel := guard.roundContext(ctx)
+	defer cancel()
+
+	res, err := e.runStreamPhase(roundCtx, adapter, ch, client, req, label, resultsLabel)
+	if err == nil && errors.Is(roundCtx.Err(), context.DeadlineExceeded) {
+		// The tools ran out of time
+		err = roundCtx.Err()
+	}
+	err = guard.explain(err, ctx, roundCtx)
+	span.SetAttributes("llm.tool_calls", len(res.toolCalls), "opper.span_id", res.spanID)
+	span.RecordError(err)
+	if err == nil && len(res.toolCalls) > 0 {
+		if res.loop = checkLoop(guard.loops, res.content, res.toolCalls); res.loop != nil {
+			span.SetAttributes(res.loop.Attributes()...)
+			span.RecordError(res.loop)
+		}
+	}
+	return res, err
+}
+
+// checkLoop feeds the text and tool calls of a phase to loops, returning the
+// loop they complete, if any.
+func checkLoop(loops *loopdetect.Detector, content string, calls []tooltypes.Call) *loopdetect.LoopError {
+	err := loops.Text(content)
+	for _, call := range calls {
+		if err != nil {
+			break
+		}
+		err = loops.ToolCall(call.Name, call.Input)
+	}
+	loop, _ := err.(*loopdetect.LoopError)
+	return loop
+}
+
+func (e *Engine) runStreamPhase(
 	ctx context.Context,
 	adapter Adapter,
 	ch chan tea.Msg,
@@ -344,6 +439,12 @@ func (e *Engine) streamPhase(
 ) (streamPhaseResult, error) {
 	var res streamPhaseResult
 
+	budgetCtx := tooling.WithAgentContext(ctx, adapter.ActiveAgentName(), adapter.CoreAgentID())
+	model := fmt.Sprint(req.Model)
+	if err := tooling.ChargeModelCall(budgetCtx, model, req.Input); err != nil {
+		return res, err
+	}
+
 	events, err := client.Stream(ctx, req)
 	if err != nil {
 		return res, err
@@ -353,8 +454,13 @@ func (e *Engine) streamPhase(
 	collector := newToolStreamCollector(label, ch)
 	var textBuilder strings.Builder
 	var spanID string
+	var streamErr error
 
 	for event := range events {
+		if event.Err != nil {
+			streamErr = event.Err
+			continue
+		}
 		chunk := event.Data
 		if spanID == "" && chunk.SpanID != "" {
 			spanID = chunk.SpanID
@@ -391,11 +497,22 @@ func (e *Engine) streamPhase(
 	}
 
 	res.spanID = spanID
+	if streamErr != nil {
+		// Keep the text of the cut-off answer; its tool calls may be incomplete
+		res.content = strings.TrimSpace(textBuilder.String())
+		tooling.RecordModelOutput(budgetCtx, model, res.content)
+		return res, streamErr
+	}
 
 	assembled, err := aggregator.Assemble()
 	if err != nil {
 		return res, fmt.Errorf("assemble json: %w", err)
 	}
+	if assembled != "" {
+		tooling.RecordModelOutput(budgetCtx, model, assembled)
+	} else {
+		tooling.RecordModelOutput(budgetCtx, model, textBuilder.String())
+	}
 
 	var output sessionOutput
 	if assembled != "" {
@@ -500,6 +617,7 @@ func (e *Engine) executeToolCalls(ctx context.Context, adapter Adapter, calls []
 		sessionID := strings.TrimSpace(adapter.SessionID())
 		toolCtx := tooling.WithSessionContext(ctx, sessionID, call.ID)
 		toolCtx = tooling.WithAgentContext(toolCtx, adapter.ActiveAgentName(), adapter.CoreAgentID())
+		toolCtx = tooling.WithMockFixtures(toolCtx, e.mockTools.Load())
 
 		content, metadata := e.runner.Execute(toolCtx, call.Name, argsJSON, func(ev SubAgentEvent) {
 			if ev.ToolCallID == "" {
@@ -582,16 +700,15 @@ func (e *Engine) executeToolCalls(ctx context.Context, adapter Adapter, calls []
 }
 
 func (e *Engine) allowToolExecution(adapter Adapter, call sessionToolCall, argsJSON string) (bool, string) {
-	if e.permissions == nil {
-		return true, ""
-	}
-
 	sessionID := ""
 	if adapter != nil {
 		sessionID = adapter.SessionID()
 	}
 
-	return requestToolPermission(e.permissions, e.workingDir, sessionID, call.ID, call.Name, argsJSON, call.Reason)
+	if ok, msg := requestToolPermission(e.permissions, e.workingDir, sessionID, call.ID, call.Name, argsJSON, call.Reason); !ok {
+		return ok, msg
+	}
+	return checkToolGuardrails(e.permissions, sessionID, call.ID, call.Name, argsJSON)
 }
 
 func (e *Engine) resolvePath(path string) (string, error) {
@@ -610,9 +727,10 @@ func truncateForPermission(s string) string {
 	return string(runes[:60]) + "…"
 }
 
-func (e *Engine) buildStreamRequest(adapter Adapter, specs []tooling.Spec) opper.StreamRequest {
+func (e *Engine) buildStreamRequest(adapter Adapter, specs []tooling.Spec, guard *turnGuard) opper.StreamRequest {
 	instructions := strings.TrimSpace(adapter.BuildInstructions())
-	conv := adapter.BuildConversation()
+	conv := tooling.CompactToolOutputs(adapter.BuildConversation(), guard.limits.RecentToolOutputs, guard.limits.ToolOutputTokens)
+	conv = tooling.TruncateToolOutputs(conv, guard.limits.ToolOutputTokens)
 
 	input := map[string]any{
 		"conversation": conv,
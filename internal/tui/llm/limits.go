@@ -0,0 +1,69 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"opperator/config"
+	"opperator/pkg/loopdetect"
+)
+
+// turnGuard bounds one turn: how many rounds it may take, how long they may
+// run, and whether it is going in circles.
+type turnGuard struct {
+	loops  *loopdetect.Detector
+	limits config.Limits
+}
+
+// newTurnGuard applies the conversation.yaml limits of agentName. Like op
+// exec, a conversation.yaml that cannot be read leaves the defaults in
+// place rather than failing the turn.
+func newTurnGuard(agentName string) *turnGuard {
+	cfg, err := config.LoadConversationConfig()
+	if err != nil {
+		log.Printf("[Conversation] Ignoring conversation settings: %v", err)
+	}
+	limits := cfg.LimitsFor(agentName)
+	return &turnGuard{loops: loopdetect.New(limits.LoopRepeats), limits: limits}
+}
+
+// turnContext bounds the whole turn by the configured timeout, if any.
+func (g *turnGuard) turnContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if g.limits.Timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, g.limits.Timeout)
+}
+
+// roundContext bounds one model call and its tool calls by the configured
+// turn timeout, if any.
+func (g *turnGuard) roundContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if g.limits.TurnTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, g.limits.TurnTimeout)
+}
+
+// explain replaces err with what ran out when a timeout caused it.
+func (g *turnGuard) explain(err error, ctx, roundCtx context.Context) error {
+	switch {
+	case err == nil:
+		return nil
+	case g.limits.Timeout > 0 && errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return fmt.Errorf("response timed out after %s (timeout in conversation.yaml)", g.limits.Timeout)
+	case g.limits.TurnTimeout > 0 && errors.Is(roundCtx.Err(), context.DeadlineExceeded):
+		return fmt.Errorf("model round timed out after %s (turn_timeout in conversation.yaml)", g.limits.TurnTimeout)
+	}
+	return err
+}
+
+// agentKey names the agent a conversation runs with, as conversation.yaml
+// refers to it.
+func agentKey(adapter Adapter) string {
+	if name := adapter.ActiveAgentName(); name != "" {
+		return name
+	}
+	return adapter.CoreAgentID()
+}
@@ -1,9 +1,11 @@
 package llm
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 
 	"tui/permission"
 	tooling "tui/tools"
@@ -164,3 +166,35 @@ func requestToolPermission(
 		return true, ""
 	}
 }
+
+// checkToolGuardrails runs the guardrails of the agent behind an agent
+// command tool call. Violations the agent marks as confirmable are put to the
+// user through the permission service; the rest block the call.
+func checkToolGuardrails(perms permission.Service, sessionID, toolCallID, toolName, argsJSON string) (bool, string) {
+	if !tooling.IsAgentCommandToolName(toolName) {
+		return true, ""
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	verdict := tooling.CheckAgentCommandGuardrails(ctx, toolName, argsJSON)
+	if verdict.Allowed {
+		return true, ""
+	}
+	blocked := "blocked by guardrail: " + verdict.Reason
+	if !verdict.Confirm || perms == nil {
+		return false, blocked
+	}
+	granted := perms.Request(permission.CreatePermissionRequest{
+		SessionID:   sessionID,
+		ToolCallID:  toolCallID,
+		ToolName:    toolName,
+		Description: fmt.Sprintf("Guardrail flagged %s: %s", toolName, verdict.Reason),
+		Action:      "Run despite guardrail",
+		Params:      json.RawMessage(argsJSON),
+		Reason:      verdict.Reason,
+	})
+	if !granted {
+		return false, blocked
+	}
+	return true, ""
+}
@@ -0,0 +1,20 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+
+	"tui/internal/keyring"
+	"tui/opper"
+	"tui/sessionstate"
+)
+
+// GenerateConversationTitle titles a conversation from its recent messages
+// with the stored Opper API key; see sessionstate.GenerateTitle.
+func GenerateConversationTitle(ctx context.Context, current string, messages []sessionstate.TitleMessage) (string, bool, error) {
+	apiKey, err := keyring.GetAPIKey()
+	if err != nil && opper.Local() == nil {
+		return "", false, fmt.Errorf("failed to read Opper API key: %w", err)
+	}
+	return sessionstate.GenerateTitle(ctx, opper.New(apiKey), current, messages)
+}
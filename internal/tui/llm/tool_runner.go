@@ -6,6 +6,10 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"sync"
+
+	"opperator/config"
+	"opperator/pkg/loopdetect"
 
 	"tui/internal/keyring"
 	"tui/opper"
@@ -33,6 +37,15 @@ type localToolRunner struct {
 
 func (r *localToolRunner) Execute(ctx context.Context, name string, args string, progress func(SubAgentEvent)) (string, string) {
 	lower := strings.ToLower(name)
+	// In mock mode every tool resolves from fixtures, except that the agent
+	// tool without a fixture still runs its sub-agent, with mocked tools
+	if mocks := tooling.MockFixturesFromContext(ctx); mocks != nil && (lower != "agent" || mocks.Has(lower)) {
+		result := mocks.Resolve(name, args)
+		if result.Error {
+			return "error: " + result.Output, ""
+		}
+		return result.Output, ""
+	}
 	switch lower {
 	case tooling.ViewToolName:
 		return tooling.RunView(ctx, args, r.workingDir)
@@ -68,6 +81,8 @@ func (r *localToolRunner) Execute(ctx context.Context, name string, args string,
 		return tooling.RunRestartAgent(ctx, args)
 	case tooling.GetLogsToolName:
 		return tooling.RunGetLogs(ctx, args)
+	case tooling.SpawnSessionAgentToolName:
+		return tooling.RunSpawnSessionAgent(ctx, args)
 	case tooling.MoveAgentToolName:
 		return tooling.RunMoveAgent(ctx, args)
 	case tooling.ManageSecretToolName:
@@ -82,6 +97,14 @@ func (r *localToolRunner) Execute(ctx context.Context, name string, args string,
 		return tooling.RunBootstrapNewAgent(ctx, args)
 	case tooling.ReadDocumentationToolName:
 		return tooling.RunReadDocumentation(ctx, args)
+	case tooling.RememberToolName:
+		return tooling.RunRemember(ctx, args)
+	case tooling.RecallToolName:
+		return tooling.RunRecall(ctx, args)
+	case tooling.SearchKnowledgeToolName:
+		return tooling.RunSearchKnowledge(ctx, args)
+	case tooling.ReadMoreToolName:
+		return tooling.RunReadMore(args)
 	case "agent":
 		// Block Builder from executing the agent tool
 		activeAgent := tooling.ActiveAgentFromContext(ctx)
@@ -196,6 +219,13 @@ func (r *localToolRunner) runManageSecret(ctx context.Context, args string) (str
 			reason = strings.TrimSpace(trimmed[colon+1:])
 		}
 		errorMsg = reason
+		if tooling.KeyringUnavailable(metadata) {
+			// Keep the value queued in the prompt until the keyring is
+			// unlocked; waiting on it does not use up an attempt
+			defaultValue = params.Value
+			errorMsg = reason + " Press enter to retry."
+			attempt--
+		}
 		params.Value = ""
 	}
 
@@ -229,7 +259,7 @@ func runLocalAgentToolProgressive(ctx context.Context, arguments string, progres
 	}
 
 	apiKey, err := keyring.GetAPIKey()
-	if err != nil {
+	if err != nil && opper.Local() == nil {
 		if errors.Is(err, keyring.ErrNotFound) {
 			return fmt.Sprintf("error: Opper API key is not configured. Run `op secret create %s` to store one", keyring.OpperAPIKeyName), ""
 		}
@@ -298,7 +328,19 @@ func runLocalAgentToolProgressive(ctx context.Context, arguments string, progres
 		instructions = builderAgentInstructions()
 	}
 
-	for pass := 0; pass < maxFollowPasses; pass++ {
+	maxRounds := config.DefaultMaxRounds
+	toolOutputTokens := 0
+	recentToolOutputs := 0
+	loopRepeats := 0
+	if cfg, err := config.LoadConversationConfig(); err == nil {
+		limits := cfg.LimitsFor(resolvedAgentID)
+		maxRounds = limits.MaxRounds
+		toolOutputTokens = limits.ToolOutputTokens
+		recentToolOutputs = limits.RecentToolOutputs
+		loopRepeats = limits.LoopRepeats
+	}
+	loops := loopdetect.New(loopRepeats)
+	for pass := 0; pass < maxRounds; pass++ {
 		if ctx != nil {
 			select {
 			case <-ctx.Done():
@@ -311,7 +353,7 @@ func runLocalAgentToolProgressive(ctx context.Context, arguments string, progres
 			Name:         "opperator.agent_tool",
 			Instructions: &instructions,
 			Input: map[string]any{
-				"conversation": conversation,
+				"conversation": tooling.CompactToolOutputs(conversation, recentToolOutputs, toolOutputTokens),
 				"tools":        tooling.SpecsToAPIDefinitions(specs),
 			},
 			OutputSchema: sessionOutputSchema(),
@@ -328,8 +370,13 @@ func runLocalAgentToolProgressive(ctx context.Context, arguments string, progres
 
 		aggregator := newJSONChunkAggregator()
 		var textBuilder strings.Builder
+		var streamErr error
 
 		for event := range events {
+			if event.Err != nil {
+				streamErr = event.Err
+				continue
+			}
 			chunk := event.Data
 			if chunk.JSONPath != "" || chunk.ChunkType == "json" {
 				path := chunk.JSONPath
@@ -349,6 +396,10 @@ func runLocalAgentToolProgressive(ctx context.Context, arguments string, progres
 			}
 		}
 
+		if streamErr != nil {
+			return fmt.Sprintf("error: %v", streamErr), ""
+		}
+
 		assembled, err := aggregator.Assemble()
 		if err != nil {
 			return fmt.Sprintf("error assembling response: %v", err), ""
@@ -388,6 +439,15 @@ func runLocalAgentToolProgressive(ctx context.Context, arguments string, progres
 			return text, metadata
 		}
 
+		if err := loops.Text(text); err != nil {
+			return fmt.Sprintf("error: %v", err), ""
+		}
+		for _, tool := range output.Tools {
+			if err := loops.ToolCall(strings.TrimSpace(tool.Name), tool.Arguments); err != nil {
+				return fmt.Sprintf("error: %v", err), ""
+			}
+		}
+
 		for _, tool := range output.Tools {
 			name := strings.TrimSpace(tool.Name)
 			if name == "" {
@@ -477,7 +537,7 @@ func runLocalAgentToolProgressive(ctx context.Context, arguments string, progres
 			toolMsg := map[string]any{
 				"role":    "tool_call_output",
 				"tool_id": callID,
-				"content": result,
+				"content": tooling.TruncateToolOutput(callID, result, toolOutputTokens),
 			}
 			if trimmedMeta := strings.TrimSpace(meta); trimmedMeta != "" {
 				toolMsg["metadata"] = trimmedMeta
@@ -507,4 +567,21 @@ func remoteAgentInstructions(systemPrompt string, replace bool, agentName string
 	return b.String()
 }
 
-func ModelName() string { return "gcp/gemini-flash-latest" }
+const defaultModelName = "gcp/gemini-flash-latest"
+
+var (
+	modelNameOnce sync.Once
+	modelName     string
+)
+
+// ModelName is the active workspace's model, or the default model when no
+// workspace sets one. It is read once per process.
+func ModelName() string {
+	modelNameOnce.Do(func() {
+		modelName = defaultModelName
+		if ws, err := config.ActiveWorkspace(); err == nil && ws != nil && strings.TrimSpace(ws.Model) != "" {
+			modelName = strings.TrimSpace(ws.Model)
+		}
+	})
+	return modelName
+}
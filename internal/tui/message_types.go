@@ -6,10 +6,12 @@ import (
 
 	tea "github.com/charmbracelet/bubbletea/v2"
 
+	"opperator/pkg/prototrace"
 	"opperator/updater"
 	cmpsidebar "tui/components/sidebar"
 	"tui/internal/plan"
 	"tui/internal/pubsub"
+	"tui/internal/taskstats"
 	llm "tui/llm"
 	"tui/permission"
 	"tui/secretprompt"
@@ -109,6 +111,25 @@ type initialAgentLogsMsg struct {
 	err       error
 }
 
+type conversationTitleMsg struct {
+	sessionID string
+	title     string
+	// announce reports the title in the conversation, for /rename
+	announce bool
+	err      error
+}
+
+type taskStatsMsg struct {
+	report taskstats.Report
+	err    error
+}
+
+type protocolTraceMsg struct {
+	agentName string
+	frames    []prototrace.Frame
+	err       error
+}
+
 type initialPlanItemsMsg struct {
 	agentName string
 	items     []plan.PlanItem
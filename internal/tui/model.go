@@ -3,12 +3,14 @@ package tui
 import (
 	"context"
 	"fmt"
+	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/charmbracelet/bubbles/v2/help"
 	tea "github.com/charmbracelet/bubbletea/v2"
 
+	"opperator/pkg/prototrace"
 	"tui/commands"
 	cmpconversations "tui/components/conversations"
 	cmpheader "tui/components/header"
@@ -59,6 +61,8 @@ type UIComponents struct {
 	helpH              int
 
 	toolDetail *toolDetailOverlay
+
+	desktop *desktopNotifier
 }
 
 type SessionState struct {
@@ -102,6 +106,7 @@ type Model struct {
 	SecretPromptController
 
 	llmEngine *llm.Engine
+	mockTools *tooling.MockFixtures
 
 	workingDir     string
 	userWorkingDir string
@@ -115,7 +120,13 @@ type Model struct {
 	asyncTaskWatcher  *AsyncTaskWatcher
 	pendingAsyncTasks map[string]string // map[taskID]callID - tracks async tasks waiting for completion
 
-	agentStatuses map[string]string // map[agentKey]status where agentKey = agentName@daemonName (running, stopped, crashed)
+	agentStatuses map[string]string                 // map[agentKey]status where agentKey = agentName@daemonName (running, stopped, crashed)
+	agentCaps     map[string]*protocol.Capabilities // map[agentKey]negotiated capabilities, keyed like agentStatuses
+
+	offlineDaemons map[string]bool // daemons whose state stream is down, while the watcher reconnects
+	queuedActions  []queuedAction  // actions waiting for an offline daemon, in the order they were taken
+
+	pendingSectionInput *cmpsidebar.SectionActionMsg // sidebar input waiting for text typed in the chat input
 
 	focusAgentCh     <-chan pubsub.Event[tooling.FocusAgentEvent]
 	focusAgentCancel context.CancelFunc
@@ -208,6 +219,112 @@ func (m *Model) ClearFocus() {
 	tooling.PublishFocusAgentEvent("")
 }
 
+// ShowContext lists the context files added to the core agents'
+// instructions. It is shown in the conversation but not sent to the model.
+func (m *Model) ShowContext() {
+	var b strings.Builder
+	files := sessionstate.ContextFiles()
+	if len(files) == 0 {
+		b.WriteString("No context files are loaded. Add `~/.config/opperator/context.md` for every project, or `OPPERATOR.md` in a project directory.")
+	} else {
+		b.WriteString("These files are added to the instructions:")
+		for _, f := range files {
+			fmt.Fprintf(&b, "\n\n**%s**\n\n%s", f.Path, f.Content)
+		}
+	}
+	if name := strings.TrimSpace(m.currentActiveAgentName()); name != "" {
+		fmt.Fprintf(&b, "\n\nContext files are not added while talking to %s; they apply to Opperator and Builder.", name)
+	}
+	m.messages.AddAssistantStart("")
+	m.messages.AppendAssistant(b.String())
+	m.messages.EndAssistant()
+}
+
+// SetMockTools turns mock mode on with the fixtures file at path, or off
+// with "off" or no path while it is on. In mock mode tool calls resolve to
+// canned results instead of reaching agents.
+func (m *Model) SetMockTools(path string) {
+	var msg string
+	switch {
+	case strings.EqualFold(path, "off") || (path == "" && m.mockTools != nil):
+		if m.mockTools == nil {
+			msg = "Mock tools are already off."
+			break
+		}
+		m.mockTools = nil
+		msg = "Mock tools are off; tools run for real again."
+	case path == "":
+		msg = "Usage: `/mock-tools <fixtures.yaml>` resolves tool calls from canned results; `/mock-tools off` turns it off."
+	default:
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(m.userWorkingDir, path)
+		}
+		fixtures, err := tooling.LoadMockFixtures(path)
+		if err != nil {
+			msg = fmt.Sprintf("Mock tools not enabled: %v", err)
+			break
+		}
+		m.mockTools = fixtures
+		msg = fmt.Sprintf("Mock tools are on. Tool calls resolve from `%s` (%s); tools without a fixture return an error.",
+			path, strings.Join(fixtures.Names(), ", "))
+	}
+	m.llmEngine.SetMockTools(m.mockTools)
+	m.messages.AddAssistantStart("")
+	m.messages.AppendAssistant(msg)
+	m.messages.EndAssistant()
+}
+
+// traceFrames is how many protocol messages /trace shows.
+const traceFrames = 20
+
+// ShowProtocolTrace fetches the protocol messages recently exchanged with
+// agentName, or with the active or focused agent, for /trace.
+func (m *Model) ShowProtocolTrace(agentName string) tea.Cmd {
+	if agentName == "" {
+		agentName = strings.TrimSpace(m.currentActiveAgentName())
+	}
+	if agentName == "" && m.sidebar != nil {
+		agentName = m.sidebar.FocusedAgentName()
+	}
+	if agentName == "" {
+		m.messages.AddAssistantStart("")
+		m.messages.AppendAssistant("Usage: `/trace <agent>` shows the protocol messages recently exchanged between the daemon and the agent.")
+		m.messages.EndAssistant()
+		return nil
+	}
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		frames, err := llm.FetchProtocolFrames(ctx, agentName)
+		return protocolTraceMsg{agentName: agentName, frames: frames, err: err}
+	}
+}
+
+func (m *Model) showProtocolTrace(msg protocolTraceMsg) {
+	var b strings.Builder
+	switch {
+	case msg.err != nil:
+		fmt.Fprintf(&b, "Could not get the protocol messages of %s: %v", msg.agentName, msg.err)
+	case len(msg.frames) == 0:
+		fmt.Fprintf(&b, "No protocol messages of %s were recorded since its daemon started.", msg.agentName)
+	default:
+		frames := msg.frames
+		if len(frames) > traceFrames {
+			frames = frames[len(frames)-traceFrames:]
+		}
+		fmt.Fprintf(&b, "Last %d protocol messages of **%s** (← from the agent, → to it). Run `op agent trace %s -f` to follow them.\n\n```\n",
+			len(frames), msg.agentName, msg.agentName)
+		for _, frame := range frames {
+			b.WriteString(prototrace.Pretty(frame))
+			b.WriteString("\n")
+		}
+		b.WriteString("```")
+	}
+	m.messages.AddAssistantStart("")
+	m.messages.AppendAssistant(b.String())
+	m.messages.EndAssistant()
+}
+
 func (m *Model) currentCoreAgentTools() []tooling.Spec {
 	if m.agents == nil {
 		return nil
@@ -306,6 +423,8 @@ func New() (*Model, error) {
 		return nil, err
 	}
 
+	cmpmessages.SetToolOutputConfig(deps.ToolOutput)
+
 	sidebarVisible := true
 	if deps.PreferencesStore != nil {
 		if visible, err := deps.PreferencesStore.GetBool(context.Background(), "sidebar.visible"); err == nil {
@@ -327,6 +446,7 @@ func New() (*Model, error) {
 			status:         cmpstatus.NewStatusCmp(),
 			keys:           defaultKeys,
 			help:           help.New(),
+			desktop:        newDesktopNotifier(),
 		},
 		SessionState: SessionState{
 			convStore:  deps.ConversationStore,
@@ -353,6 +473,8 @@ func New() (*Model, error) {
 	m.asyncProgressSeen = make(map[string]int)
 	m.pendingAsyncTasks = make(map[string]string)
 	m.agentStatuses = make(map[string]string)
+	m.agentCaps = make(map[string]*protocol.Capabilities)
+	m.offlineDaemons = make(map[string]bool)
 
 	if deps.ConversationStore != nil {
 		m.asyncTaskWatcher = NewAsyncTaskWatcher(deps.ConversationStore.DB())
@@ -452,7 +574,7 @@ func (m *Model) Init() tea.Cmd {
 		// Polling disabled for logs/metadata - now using event-driven updates
 	}
 
-	cmds = append(cmds, m.initialStatsCmd())
+	cmds = append(cmds, m.initialStatsCmd(), m.daemonVersionCheckCmd())
 
 	if cmd := m.waitPermissionRequestEvent(); cmd != nil {
 		cmds = append(cmds, cmd)
@@ -569,7 +691,7 @@ func (m *Model) handleToolDetailMsg(msg tea.Msg) (tea.Cmd, bool) {
 		return nil, false
 	case tea.KeyMsg, tea.KeyPressMsg:
 		keyStr, ok := keyString(msg)
-		if ok && keyStr == "esc" {
+		if ok && keyStr == "esc" && !m.toolDetail.HandlesEscape() {
 			cmd := m.closeToolDetail()
 			return cmd, true
 		}
@@ -586,6 +708,12 @@ func (m *Model) handleMessage(msg tea.Msg) tea.Cmd {
 	switch v := msg.(type) {
 	case sessionStreamMsg:
 		return m.handleStreamMsg(v.SessionID, v.Msg)
+	case tea.FocusMsg:
+		m.desktop.setFocused(true)
+		return nil
+	case tea.BlurMsg:
+		m.desktop.setFocused(false)
+		return nil
 	case initialStatsMsg:
 		m.agentStatuses = v.statuses
 		if m.stats != nil {
@@ -625,6 +753,9 @@ func (m *Model) handleMessage(msg tea.Msg) tea.Cmd {
 			warnMsg := fmt.Sprintf("Daemon '%s' is unreachable and has been temporarily disabled. Re-enable with: op daemon enable %s", v.Daemon, v.Daemon)
 			return tea.Batch(util.ReportWarn(warnMsg), m.waitAgentStateEvent())
 		}
+		if v.Type == "connection" {
+			return tea.Batch(m.handleDaemonConnection(v), m.waitAgentStateEvent())
+		}
 
 		if v.Type == "status" && v.Status != "" {
 			m.updateAgentStatusAndRefreshStats(v.AgentName, v.Daemon, v.Status)
@@ -699,15 +830,21 @@ func (m *Model) handleMessage(msg tea.Msg) tea.Cmd {
 			case "sections":
 				// Set custom sections if this is the current active agent OR focused agent in Builder
 				if v.CustomSections != nil && (isCurrentAgent || isFocusedAgent) {
-					m.sidebar.SetCustomSections(v.CustomSections)
+					m.sidebar.SetCustomSections(v.AgentName, m.supportedSections(v.AgentName, v.Daemon, v.CustomSections))
+				}
+			case "capabilities":
+				if v.Capabilities != nil && strings.TrimSpace(v.AgentName) != "" {
+					m.agentCaps[agentStatusKey(v.AgentName, v.Daemon)] = v.Capabilities
 				}
 			case "commands":
 				agentName := strings.TrimSpace(v.AgentName)
 				if agentName != "" && v.Commands != nil {
 					normalized := protocol.NormalizeCommandDescriptors(v.Commands)
 					tooling.BuildAgentCommandTools(agentName, normalized)
+					var slashWarnings tea.Cmd
 					if m.agents != nil {
 						m.agents.updateActiveAgentCommands(agentName, normalized)
+						slashWarnings = m.agents.updateAgentSlashCommands(agentName, normalized)
 					}
 					if coreID == coreagent.IDBuilder {
 						focusedName := strings.TrimSpace(m.sidebar.FocusedAgentName())
@@ -715,6 +852,9 @@ func (m *Model) handleMessage(msg tea.Msg) tea.Cmd {
 							m.sidebar.SetFocusedAgentCommands(normalized)
 						}
 					}
+					if slashWarnings != nil {
+						return tea.Batch(slashWarnings, m.waitAgentStateEvent())
+					}
 				}
 			case "logs":
 				// Determine if we should display these logs based on current mode
@@ -811,7 +951,16 @@ func (m *Model) handleMessage(msg tea.Msg) tea.Cmd {
 	case agentMetadataFetchedMsg:
 		return m.handleAgentMetadataFetched(v)
 	case agentListRefreshedMsg:
-		return m.handleAgentListRefreshed(v)
+		cmd := m.handleAgentListRefreshed(v)
+		if v.err == nil {
+			cmd = tea.Batch(cmd, fetchAgentSlashCommandsCmd(v.agents))
+		}
+		return cmd
+	case agentSlashCommandsFetchedMsg:
+		if m.agents == nil {
+			return nil
+		}
+		return m.agents.setAgentSlashCommands(v.commands)
 	case agentListRefreshNeededMsg:
 		// Invalidate cache and trigger immediate refresh
 		llm.InvalidateAgentListCache()
@@ -823,16 +972,30 @@ func (m *Model) handleMessage(msg tea.Msg) tea.Cmd {
 		return m.handleFocusedAgentMetadata(v)
 	case initialAgentLogsMsg:
 		return m.handleInitialAgentLogs(v)
+	case protocolTraceMsg:
+		m.showProtocolTrace(v)
+		return nil
+	case taskStatsMsg:
+		m.showTaskStats(v)
+		return nil
+	case conversationTitleMsg:
+		m.handleConversationTitle(v)
+		return nil
 	case initialPlanItemsMsg:
 		return m.handleInitialPlanItems(v)
 	case initialCustomSectionsMsg:
 		return m.handleInitialCustomSections(v)
+	case cmpsidebar.SectionActionMsg:
+		return m.handleSectionAction(v)
 	default:
 		return nil
 	}
 }
 
 func (m *Model) submitInput(val string) tea.Cmd {
+	if val != "" && m.pendingSectionInput != nil {
+		return m.submitSectionInput(val)
+	}
 	if val == "" || m.isSessionBusy(m.sessionID) {
 		return nil
 	}
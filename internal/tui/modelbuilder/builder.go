@@ -6,7 +6,9 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
+	"opperator/config"
 	"tui/internal/conversation"
 	"tui/internal/inputhistory"
 	"tui/internal/message"
@@ -55,6 +57,9 @@ type Dependencies struct {
 
 	LSPManager *lsp.Manager
 	LLMEngine  *llm.Engine
+
+	// ToolOutput is how tool calls are folded, from tui.yaml.
+	ToolOutput config.ToolOutputConfig
 }
 
 func New() *Builder {
@@ -101,6 +106,8 @@ func (b *Builder) Build() (*Dependencies, error) {
 		return nil, fmt.Errorf("failed to open preferences store: %w", err)
 	}
 
+	archiveIdleConversations(convStore)
+
 	sessionID, err := getOrCreateInitialSession(convStore)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize session: %w", err)
@@ -159,15 +166,18 @@ func (b *Builder) Build() (*Dependencies, error) {
 		LLMEngine:             llm.NewEngine(permSvc, secretSvc, workingDir, invocationDir, lspManager),
 	}
 
+	if cfg, err := config.LoadTUIConfig(); err == nil {
+		deps.ToolOutput = cfg.ToolOutput
+	}
+
 	return deps, nil
 }
 
 func defaultWorkingDir() string {
-	home, err := os.UserHomeDir()
+	dir, err := config.GetConfigDir()
 	if err != nil {
 		return "."
 	}
-	dir := filepath.Join(home, ".config", "opperator")
 	if mkErr := os.MkdirAll(dir, 0o755); mkErr != nil {
 		return "."
 	}
@@ -190,11 +200,32 @@ func detectInvocationDir() string {
 	return abs
 }
 
+// archiveIdleConversations archives the conversations idle for longer than
+// conversation.yaml's archive_after_days and ends their session agents.
+func archiveIdleConversations(convStore *conversation.Store) {
+	cfg, err := config.LoadConversationConfig()
+	if err != nil || cfg.ArchiveAfter() <= 0 {
+		return
+	}
+	ids, err := convStore.ArchiveIdle(context.Background(), cfg.ArchiveAfter())
+	if err != nil || len(ids) == 0 {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		for _, id := range ids {
+			_ = tooling.EndSessionAgents(ctx, id)
+		}
+	}()
+}
+
 func getOrCreateInitialSession(convStore *conversation.Store) (string, error) {
 	convs, err := convStore.List(context.Background())
 	if err != nil {
 		return "", err
 	}
+	convs = conversation.Unarchived(conversation.InWorkspace(convs, convStore.Workspace()))
 
 	if len(convs) == 0 {
 		c, err := convStore.Create(context.Background(), "")
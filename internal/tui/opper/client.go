@@ -8,9 +8,13 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
+
+	"opperator/pkg/scrub"
+	"opperator/pkg/tracing"
 )
 
 const defaultBaseURL = "https://api.opper.ai/v2"
@@ -24,6 +28,11 @@ type Opper struct {
 	// BaseURL defaults to https://api.opper.ai/v2
 	BaseURL    string
 	HTTPClient *http.Client
+	// Scrubber, when set, replaces personal data in requests with
+	// placeholders that Stream restores in the response.
+	Scrubber *scrub.Scrubber
+	// OpenAI, when set, receives calls in place of the Opper API.
+	OpenAI *OpenAICompat
 }
 
 // WithHTTPClient allows supplying a custom HTTP client when constructing Opper via New.
@@ -40,6 +49,14 @@ func WithBaseURL(baseURL string) Option {
 	}
 }
 
+// WithScrubber overrides the scrubber from scrubbing.yaml; nil disables
+// scrubbing.
+func WithScrubber(s *scrub.Scrubber) Option {
+	return func(o *Opper) {
+		o.Scrubber = s
+	}
+}
+
 // WithTimeout applies an HTTP client timeout when constructing via New.
 func WithTimeout(d time.Duration) Option {
 	return func(o *Opper) {
@@ -55,6 +72,8 @@ func New(apiKey string, opts ...Option) *Opper {
 		APIKey:     apiKey,
 		BaseURL:    defaultBaseURL,
 		HTTPClient: &http.Client{Timeout: 0}, // no timeout for streams
+		Scrubber:   scrub.Configured(),
+		OpenAI:     Local(),
 	}
 
 	for _, opt := range opts {
@@ -112,12 +131,22 @@ type SSEEvent struct {
 	Event *string        `json:"event,omitempty"`
 	Data  StreamingChunk `json:"data"`
 	Retry *int           `json:"retry,omitempty"`
+
+	// Err, set only on the last event of a stream, reports why the stream
+	// ended early.
+	Err error `json:"-"`
 }
 
 // Stream calls POST /call/stream and returns a channel of SSEEvent.
-// Caller should range over the returned channel until it closes.
+// Caller should range over the returned channel until it closes. A dropped
+// connection is resumed transparently where possible; otherwise the last
+// event carries an Err wrapping ErrStreamInterrupted.
 func (c *Opper) Stream(ctx context.Context, reqBody StreamRequest) (<-chan SSEEvent, error) {
-	resp, err := c.doStream(ctx, reqBody)
+	mapping, reqBody, err := c.scrub(reqBody)
+	if err != nil {
+		return nil, err
+	}
+	body, parse, err := c.openStream(ctx, reqBody)
 	if err != nil {
 		return nil, err
 	}
@@ -126,16 +155,53 @@ func (c *Opper) Stream(ctx context.Context, reqBody StreamRequest) (<-chan SSEEv
 
 	go func() {
 		defer close(out)
-		defer resp.Body.Close()
 
-		_ = streamSSE(resp.Body, func(evt SSEEvent) bool {
+		send := func(evt SSEEvent) bool {
 			select {
 			case out <- evt:
 				return true
 			case <-ctx.Done():
 				return false
 			}
-		})
+		}
+		// A stream that could not be resumed ends with its error
+		fail := func(err error) {
+			if err != nil && ctx.Err() == nil {
+				send(SSEEvent{Err: err})
+			}
+		}
+		if mapping == nil {
+			fail(c.readStream(ctx, reqBody, body, parse, send))
+			return
+		}
+
+		// Restore placeholders per path; text held back waiting for the end
+		// of a placeholder is sent as a final chunk of its path.
+		last := make(map[string]StreamingChunk)
+		if err := c.readStream(ctx, reqBody, body, parse, func(evt SSEEvent) bool {
+			if delta, ok := evt.Data.Delta.(string); ok {
+				path := evt.Data.JSONPath
+				last[path] = evt.Data
+				evt.Data.Delta = mapping.RestoreDelta(path, delta)
+			}
+			return send(evt)
+		}); err != nil || ctx.Err() != nil {
+			fail(err)
+			return
+		}
+		rest := mapping.Flush()
+		paths := make([]string, 0, len(rest))
+		for path := range rest {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		for _, path := range paths {
+			chunk := last[path]
+			chunk.Delta = rest[path]
+			if !send(SSEEvent{Data: chunk}) {
+				return
+			}
+		}
 	}()
 
 	return out, nil
@@ -145,12 +211,70 @@ func (c *Opper) Stream(ctx context.Context, reqBody StreamRequest) (<-chan SSEEv
 // This allows direct access to the SSE stream without parsing.
 // The caller is responsible for reading and closing the response body.
 func (c *Opper) StreamRaw(ctx context.Context, reqBody StreamRequest) (io.ReadCloser, error) {
-	resp, err := c.doStream(ctx, reqBody)
+	// The raw stream is not parsed, so placeholders are left in it.
+	_, reqBody, err := c.scrub(reqBody)
 	if err != nil {
 		return nil, err
 	}
+	body, parse, err := c.openStream(ctx, reqBody)
+	if err != nil {
+		return nil, err
+	}
+	if c.OpenAI == nil {
+		return body, nil
+	}
+
+	// Re-encode the chat completion stream as Opper events.
+	pr, pw := io.Pipe()
+	go func() {
+		defer body.Close()
+		pw.CloseWithError(parse(body, func(evt SSEEvent) bool {
+			data, err := json.Marshal(evt.Data)
+			if err == nil {
+				_, err = fmt.Fprintf(pw, "data: %s\n\n", data)
+			}
+			return err == nil
+		}))
+	}()
+	return pr, nil
+}
+
+// openStream starts the call on the Opper API or the OpenAI-compatible
+// server and returns the response body with the parser for its events.
+func (c *Opper) openStream(ctx context.Context, reqBody StreamRequest) (io.ReadCloser, func(io.Reader, func(SSEEvent) bool) error, error) {
+	if c.OpenAI != nil {
+		resp, err := c.doChatStream(ctx, reqBody)
+		if err != nil {
+			return nil, nil, err
+		}
+		return resp.Body, streamChat, nil
+	}
+	resp, err := c.doStream(ctx, reqBody)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp.Body, streamSSE, nil
+}
 
-	return resp.Body, nil
+// scrub replaces personal data in the request's input and instructions when
+// a scrubber is configured, returning the mapping to restore the response.
+func (c *Opper) scrub(reqBody StreamRequest) (*scrub.Mapping, StreamRequest, error) {
+	if c.Scrubber == nil {
+		return nil, reqBody, nil
+	}
+	mapping := c.Scrubber.NewMapping()
+	if reqBody.Input != nil {
+		input, err := mapping.ScrubValue(reqBody.Input)
+		if err != nil {
+			return nil, reqBody, fmt.Errorf("scrub input: %w", err)
+		}
+		reqBody.Input = input
+	}
+	if reqBody.Instructions != nil {
+		instructions := mapping.Scrub(*reqBody.Instructions)
+		reqBody.Instructions = &instructions
+	}
+	return mapping, reqBody, nil
 }
 
 func (c *Opper) doStream(ctx context.Context, reqBody StreamRequest) (*http.Response, error) {
@@ -170,6 +294,10 @@ func (c *Opper) doStream(ctx context.Context, reqBody StreamRequest) (*http.Resp
 	if c.APIKey != "" {
 		req.Header.Set("Authorization", "Bearer "+c.APIKey)
 	}
+	// Let Opper join the caller's trace
+	if traceParent := tracing.TraceParent(ctx); traceParent != "" {
+		req.Header.Set("traceparent", traceParent)
+	}
 
 	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
@@ -192,8 +320,16 @@ func parseAPIError(resp *http.Response) error {
 		Type    string      `json:"type"`
 		Message string      `json:"message"`
 		Detail  interface{} `json:"detail"`
+		// OpenAI-compatible servers nest it: { error: { type, message } }
+		Error *struct {
+			Type    string `json:"type"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &apiErr); err == nil && apiErr.Error != nil {
+		apiErr.Type, apiErr.Message = apiErr.Error.Type, apiErr.Error.Message
 	}
-	if err := json.Unmarshal(body, &apiErr); err == nil && (apiErr.Message != "" || apiErr.Type != "") {
+	if apiErr.Message != "" || apiErr.Type != "" {
 		if apiErr.Detail != nil {
 			return fmt.Errorf("api error %s: %s (%v)", apiErr.Type, apiErr.Message, apiErr.Detail)
 		}
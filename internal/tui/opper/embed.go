@@ -0,0 +1,83 @@
+package opper
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// DefaultEmbeddingModel embeds text on the Opper API.
+const DefaultEmbeddingModel = "azure/text-embedding-3-large"
+
+// Embed returns an embedding for each input together with the model that
+// produced them; vectors of different models cannot be compared. Inputs are
+// scrubbed like any other request, with one mapping for the whole batch so
+// the same value gets the same placeholder in every input.
+func (c *Opper) Embed(ctx context.Context, inputs []string) ([][]float32, string, error) {
+	c.ensureDefaults()
+
+	if c.Scrubber != nil {
+		mapping := c.Scrubber.NewMapping()
+		scrubbed := make([]string, len(inputs))
+		for i, input := range inputs {
+			scrubbed[i] = mapping.Scrub(input)
+		}
+		inputs = scrubbed
+	}
+
+	url, model, apiKey := c.BaseURL+"/embeddings", DefaultEmbeddingModel, c.APIKey
+	if c.OpenAI != nil {
+		url = strings.TrimRight(c.OpenAI.BaseURL, "/") + "/embeddings"
+		model, apiKey = c.OpenAI.EmbeddingModel, c.OpenAI.APIKey
+		if model == "" {
+			model = c.OpenAI.Model
+		}
+	}
+
+	payload, err := json.Marshal(map[string]any{"model": model, "input": inputs})
+	if err != nil {
+		return nil, "", fmt.Errorf("marshal request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, "", fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("do request: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", parseAPIError(resp)
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, "", fmt.Errorf("decode embeddings: %w", err)
+	}
+	vectors := make([][]float32, len(inputs))
+	for _, d := range result.Data {
+		if d.Index >= 0 && d.Index < len(vectors) {
+			vectors[d.Index] = d.Embedding
+		}
+	}
+	for i, v := range vectors {
+		if len(v) == 0 {
+			return nil, "", fmt.Errorf("no embedding returned for input %d", i)
+		}
+	}
+	return vectors, model, nil
+}
@@ -0,0 +1,204 @@
+package opper
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+
+	"opperator/config"
+	"opperator/pkg/tracing"
+)
+
+// OpenAICompat is an OpenAI-compatible chat completions server, such as
+// Ollama or vLLM, that takes the place of the Opper API.
+type OpenAICompat struct {
+	// BaseURL is the API root the /chat/completions path is added to.
+	BaseURL string
+	APIKey  string
+	// Model replaces the model named in each request.
+	Model string
+	// EmbeddingModel is used by Embed.
+	EmbeddingModel string
+}
+
+var (
+	localOnce sync.Once
+	local     *OpenAICompat
+)
+
+// Local returns the server configured in llm.yaml, loaded once, or nil when
+// calls go to the Opper API.
+func Local() *OpenAICompat {
+	localOnce.Do(func() {
+		cfg, err := config.LoadLLMConfig()
+		if err != nil {
+			log.Printf("[Opper] Ignoring model settings: %v", err)
+			return
+		}
+		if cfg != nil {
+			local = &OpenAICompat{BaseURL: cfg.BaseURL, APIKey: cfg.APIKey, Model: cfg.Model, EmbeddingModel: cfg.EmbeddingModel}
+		}
+	})
+	return local
+}
+
+// WithOpenAICompat overrides the server from llm.yaml; nil sends calls to
+// the Opper API.
+func WithOpenAICompat(compat *OpenAICompat) Option {
+	return func(o *Opper) {
+		o.OpenAI = compat
+	}
+}
+
+var schemaNameUnsafe = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// doChatStream sends reqBody as a streaming chat completion. The
+// instructions become the system message, the input the user message and
+// the output schema the response format.
+func (c *Opper) doChatStream(ctx context.Context, reqBody StreamRequest) (*http.Response, error) {
+	c.ensureDefaults()
+
+	system := ""
+	if reqBody.Instructions != nil {
+		system = *reqBody.Instructions
+	}
+	payload := map[string]any{
+		"model":  c.OpenAI.Model,
+		"stream": true,
+	}
+	if reqBody.OutputSchema != nil {
+		schema, err := json.Marshal(reqBody.OutputSchema)
+		if err != nil {
+			return nil, fmt.Errorf("marshal output schema: %w", err)
+		}
+		// Not every server enforces the response format, so the schema is
+		// spelled out in the prompt as well.
+		system = strings.TrimSpace(system + "\n\nRespond with only a JSON object that matches this JSON schema:\n" + string(schema))
+		payload["response_format"] = map[string]any{
+			"type": "json_schema",
+			"json_schema": map[string]any{
+				"name":   schemaNameUnsafe.ReplaceAllString(reqBody.Name, "_"),
+				"schema": reqBody.OutputSchema,
+			},
+		}
+	}
+
+	var messages []map[string]string
+	if system != "" {
+		messages = append(messages, map[string]string{"role": "system", "content": system})
+	}
+	for _, example := range reqBody.Examples {
+		input, err := chatContent(example.Input)
+		if err != nil {
+			return nil, fmt.Errorf("marshal example: %w", err)
+		}
+		output, err := chatContent(example.Output)
+		if err != nil {
+			return nil, fmt.Errorf("marshal example: %w", err)
+		}
+		messages = append(messages,
+			map[string]string{"role": "user", "content": input},
+			map[string]string{"role": "assistant", "content": output})
+	}
+	input, err := chatContent(reqBody.Input)
+	if err != nil {
+		return nil, fmt.Errorf("marshal input: %w", err)
+	}
+	messages = append(messages, map[string]string{"role": "user", "content": input})
+	payload["messages"] = messages
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+	url := strings.TrimRight(c.OpenAI.BaseURL, "/") + "/chat/completions"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+	if c.OpenAI.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.OpenAI.APIKey)
+	}
+	if traceParent := tracing.TraceParent(ctx); traceParent != "" {
+		req.Header.Set("traceparent", traceParent)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("do request: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, parseAPIError(resp)
+	}
+	return resp, nil
+}
+
+// chatContent renders a request value as message text: strings as they
+// are, anything else as JSON.
+func chatContent(v any) (string, error) {
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+	if v == nil {
+		return "", nil
+	}
+	data, err := json.Marshal(v)
+	return string(data), err
+}
+
+// streamChat reads a chat completion stream and emits the JSON object the
+// model writes as Opper's JSONPath chunks.
+func streamChat(r io.Reader, emit func(SSEEvent) bool) error {
+	decoder := newJSONPathDecoder(func(path string, delta any) bool {
+		return emit(SSEEvent{Data: StreamingChunk{Delta: delta, JSONPath: path, ChunkType: "json"}})
+	})
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadString('\n')
+		data, ok := strings.CutPrefix(strings.TrimRight(line, "\r\n"), "data:")
+		data = strings.TrimSpace(data)
+		if ok && data == "[DONE]" {
+			break
+		}
+		if ok && data != "" {
+			var chunk struct {
+				Choices []struct {
+					Delta struct {
+						Content string `json:"content"`
+					} `json:"delta"`
+				} `json:"choices"`
+				Error *struct {
+					Message string `json:"message"`
+				} `json:"error"`
+			}
+			if jsonErr := json.Unmarshal([]byte(data), &chunk); jsonErr == nil {
+				if chunk.Error != nil {
+					return fmt.Errorf("%w: %s", errStreamAPI, chunk.Error.Message)
+				}
+				for _, choice := range chunk.Choices {
+					if !decoder.Write(choice.Delta.Content) {
+						return nil
+					}
+				}
+			}
+		}
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return err
+		}
+	}
+	decoder.Close()
+	return nil
+}
@@ -0,0 +1,183 @@
+package opper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// maxStreamResumes bounds how often a dropped stream is resumed before the
+// call fails.
+const maxStreamResumes = 2
+
+// resumeHintChars is how much of the interrupted answer the resumed call is
+// shown.
+const resumeHintChars = 2000
+
+// ErrStreamInterrupted is delivered in the last event of a stream whose
+// connection dropped and could not be resumed. The text streamed before it
+// is all there is of the answer.
+var ErrStreamInterrupted = errors.New("model response was interrupted")
+
+// errStreamAPI marks errors the server reported inside a stream, which a
+// retry would not fix.
+var errStreamAPI = errors.New("api error")
+
+// readStream parses body into emit. When the connection drops partway, it
+// calls the model again with a hint to continue from where the answer
+// stopped and stitches the rest of the answer onto what was sent, up to
+// maxStreamResumes times.
+func (c *Opper) readStream(ctx context.Context, reqBody StreamRequest, body io.ReadCloser, parse func(io.Reader, func(SSEEvent) bool) error, emit func(SSEEvent) bool) error {
+	st := &stitcher{emit: emit, sent: make(map[string]string)}
+	var err error
+	for attempt := 0; ; attempt++ {
+		if body != nil {
+			err = parse(body, st.forward)
+			body.Close()
+			if st.failed {
+				return fmt.Errorf("%w: the resumed answer did not match what was already streamed", ErrStreamInterrupted)
+			}
+			if err == nil || st.stopped || ctx.Err() != nil || errors.Is(err, errStreamAPI) {
+				return err
+			}
+		}
+		if attempt >= maxStreamResumes {
+			return fmt.Errorf("%w after %d retries: %v", ErrStreamInterrupted, maxStreamResumes, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(attempt+1) * time.Second):
+		}
+		st.resume()
+		body, parse, err = c.openStream(ctx, withResumeHint(reqBody, st.text()))
+		if err != nil {
+			body = nil
+		}
+	}
+}
+
+// withResumeHint asks the model to continue an answer that was cut off
+// after partial.
+func withResumeHint(reqBody StreamRequest, partial string) StreamRequest {
+	partial = strings.TrimSpace(partial)
+	if partial == "" {
+		return reqBody
+	}
+	if len(partial) > resumeHintChars {
+		partial = "..." + partial[len(partial)-resumeHintChars:]
+	}
+	var instructions string
+	if reqBody.Instructions != nil {
+		instructions = *reqBody.Instructions + "\n\n"
+	}
+	instructions += "Your previous answer to this request was cut off by a dropped connection. " +
+		"Continue from: the end of the text below. Write only what comes after it in the text field, " +
+		"without repeating any of it, and give the rest of the output (such as tool calls) in full.\n\n" +
+		"<interrupted_answer>\n" + partial + "\n</interrupted_answer>"
+	reqBody.Instructions = &instructions
+	return reqBody
+}
+
+// stitcher forwards stream events and, after a resume, drops what the new
+// call repeats of the events already forwarded. Text may either be
+// continued or regenerated from the start; other fields must be
+// regenerated to match.
+type stitcher struct {
+	emit    func(SSEEvent) bool
+	stopped bool
+	failed  bool
+
+	// sent holds the string deltas forwarded per JSON path, and other
+	// paths with a value forwarded
+	sent    map[string]string
+	resumed bool
+	// pending buffers the resumed deltas of a path until they show whether
+	// they repeat sent; decided paths are forwarded as they come
+	pending map[string]string
+	decided map[string]bool
+}
+
+func (s *stitcher) resume() {
+	s.resumed = true
+	s.pending = make(map[string]string)
+	s.decided = make(map[string]bool)
+}
+
+// text returns the answer text forwarded so far.
+func (s *stitcher) text() string {
+	var b strings.Builder
+	for path, value := range s.sent {
+		if isTextPath(path) {
+			b.WriteString(value)
+		}
+	}
+	return b.String()
+}
+
+func (s *stitcher) forward(evt SSEEvent) bool {
+	path := evt.Data.JSONPath
+	delta, isString := evt.Data.Delta.(string)
+	if !s.resumed || evt.Data.Delta == nil {
+		return s.send(path, evt)
+	}
+	if !isString {
+		if _, seen := s.sent[path]; seen {
+			return true
+		}
+		return s.send(path, evt)
+	}
+	if s.decided[path] {
+		return s.send(path, evt)
+	}
+
+	prev := s.sent[path]
+	buffered := s.pending[path] + delta
+	switch {
+	case prev == "":
+		s.decided[path] = true
+	case strings.HasPrefix(prev, buffered):
+		// Still repeating what was sent; wait for more
+		s.pending[path] = buffered
+		return true
+	case strings.HasPrefix(buffered, prev):
+		// Regenerated from the start; forward what is new
+		s.decided[path] = true
+		buffered = buffered[len(prev):]
+	case isTextPath(path):
+		// Continued rather than repeated
+		s.decided[path] = true
+	default:
+		s.failed = true
+		return false
+	}
+	delete(s.pending, path)
+	if buffered == "" {
+		return true
+	}
+	evt.Data.Delta = buffered
+	return s.send(path, evt)
+}
+
+func (s *stitcher) send(path string, evt SSEEvent) bool {
+	if delta, ok := evt.Data.Delta.(string); ok {
+		s.sent[path] += delta
+	} else if evt.Data.Delta != nil {
+		s.sent[path] += ""
+	}
+	if !s.emit(evt) {
+		s.stopped = true
+		return false
+	}
+	return true
+}
+
+// isTextPath reports whether path carries the answer text: plain text
+// chunks or the text field of structured output.
+func isTextPath(path string) bool {
+	return path == "" || path == "text" || strings.HasSuffix(path, ".text")
+}
@@ -6,6 +6,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 )
 
 // JSONChunkAggregator combines streaming JSON chunks indexed by JSONPath into a
@@ -179,3 +180,337 @@ func assignJSONPath(root map[string]any, path string, value any) error {
 	}
 	return nil
 }
+
+// jsonPathDecoder is the inverse of JSONChunkAggregator: it reads a JSON
+// object that arrives in pieces and reports it as the JSONPath deltas the
+// Opper API streams. String values are reported as they arrive; numbers,
+// booleans, empty objects and arrays that do not hold objects once complete.
+// Output that does not start with an object is reported as text.
+type jsonPathDecoder struct {
+	emit func(path string, delta any) bool
+
+	state   decoderState
+	stack   []decoderFrame
+	path    string // path of the value being read
+	raw     strings.Builder
+	key     strings.Builder
+	escaped bool
+	sent    int // bytes of the current string value already reported
+	depth   int // nesting of a captured array
+	inStr   bool
+	stopped bool
+}
+
+type decoderState int
+
+const (
+	decodeStart decoderState = iota
+	decodeFence
+	decodeText
+	decodeKey
+	decodeKeyString
+	decodeColon
+	decodeValue
+	decodeArrayStart
+	decodeString
+	decodeLiteral
+	decodeCapture
+	decodeAfterValue
+	decodeDone
+)
+
+type decoderFrame struct {
+	array bool
+	path  string
+	key   string
+	index int
+	count int
+}
+
+func newJSONPathDecoder(emit func(path string, delta any) bool) *jsonPathDecoder {
+	return &jsonPathDecoder{emit: emit}
+}
+
+// Write feeds the next piece of the document. It reports false once emit
+// asked to stop.
+func (d *jsonPathDecoder) Write(s string) bool {
+	for i := 0; i < len(s) && !d.stopped; i++ {
+		if d.state == decodeText {
+			d.send("text", s[i:])
+			break
+		}
+		d.feed(s[i])
+	}
+	if d.state == decodeString && !d.stopped {
+		d.flushString(false)
+	}
+	return !d.stopped
+}
+
+// Close reports what is left of a document that ended early.
+func (d *jsonPathDecoder) Close() {
+	switch d.state {
+	case decodeString:
+		d.flushString(true)
+	case decodeLiteral:
+		d.literal()
+	}
+}
+
+func (d *jsonPathDecoder) feed(c byte) {
+	switch d.state {
+	case decodeStart:
+		switch {
+		case isJSONSpace(c):
+		case c == '`':
+			// Skip a ```json fence around the object.
+			d.state = decodeFence
+		case c == '{':
+			d.push(false, "")
+		default:
+			d.state = decodeText
+			d.send("text", string(c))
+		}
+	case decodeFence:
+		if c == '\n' {
+			d.state = decodeStart
+		}
+	case decodeKey:
+		switch c {
+		case '"':
+			d.key.Reset()
+			d.escaped = false
+			d.state = decodeKeyString
+		case '}':
+			d.closeFrame()
+		}
+	case decodeKeyString:
+		if d.escaped {
+			d.escaped = false
+			d.key.WriteByte(c)
+		} else if c == '\\' {
+			d.escaped = true
+			d.key.WriteByte(c)
+		} else if c == '"' {
+			key, _ := unquoteJSON(d.key.String())
+			d.stack[len(d.stack)-1].key = key
+			d.state = decodeColon
+		} else {
+			d.key.WriteByte(c)
+		}
+	case decodeColon:
+		if c == ':' {
+			d.path = d.childPath()
+			d.state = decodeValue
+		}
+	case decodeValue:
+		switch {
+		case isJSONSpace(c):
+		case c == '"':
+			d.raw.Reset()
+			d.escaped = false
+			d.sent = 0
+			d.state = decodeString
+		case c == '{':
+			d.push(false, d.path)
+		case c == '[':
+			d.state = decodeArrayStart
+		case c == ']' || c == '}':
+			d.closeFrame()
+		default:
+			d.raw.Reset()
+			d.raw.WriteByte(c)
+			d.state = decodeLiteral
+		}
+	case decodeArrayStart:
+		switch {
+		case isJSONSpace(c):
+		case c == '{':
+			// Arrays of objects are reported element by element.
+			d.push(true, d.path)
+			d.path = d.childPath()
+			d.push(false, d.path)
+		default:
+			// Other arrays are reported whole, which also keeps nested
+			// arrays out of the paths.
+			d.raw.Reset()
+			d.raw.WriteByte('[')
+			d.depth = 1
+			d.inStr = false
+			d.escaped = false
+			d.state = decodeCapture
+			d.feed(c)
+		}
+	case decodeString:
+		if d.escaped {
+			d.escaped = false
+			d.raw.WriteByte(c)
+		} else if c == '\\' {
+			d.escaped = true
+			d.raw.WriteByte(c)
+		} else if c == '"' {
+			d.flushString(true)
+			d.valueDone()
+		} else {
+			d.raw.WriteByte(c)
+		}
+	case decodeLiteral:
+		if isJSONSpace(c) || c == ',' || c == '}' || c == ']' {
+			d.literal()
+			d.valueDone()
+			d.feed(c)
+			return
+		}
+		d.raw.WriteByte(c)
+	case decodeCapture:
+		d.raw.WriteByte(c)
+		switch {
+		case d.inStr:
+			if d.escaped {
+				d.escaped = false
+			} else if c == '\\' {
+				d.escaped = true
+			} else if c == '"' {
+				d.inStr = false
+			}
+		case c == '"':
+			d.inStr = true
+		case c == '[' || c == '{':
+			d.depth++
+		case c == ']' || c == '}':
+			d.depth--
+			if d.depth == 0 {
+				var value any
+				if err := json.Unmarshal([]byte(d.raw.String()), &value); err == nil {
+					d.send(d.path, value)
+				}
+				d.valueDone()
+			}
+		}
+	case decodeAfterValue:
+		switch c {
+		case ',':
+			top := &d.stack[len(d.stack)-1]
+			if top.array {
+				top.index++
+				d.path = d.childPath()
+				d.state = decodeValue
+			} else {
+				d.state = decodeKey
+			}
+		case '}', ']':
+			d.closeFrame()
+		}
+	}
+}
+
+func (d *jsonPathDecoder) push(array bool, path string) {
+	d.stack = append(d.stack, decoderFrame{array: array, path: path})
+	if array {
+		d.state = decodeValue
+	} else {
+		d.state = decodeKey
+	}
+}
+
+func (d *jsonPathDecoder) childPath() string {
+	top := d.stack[len(d.stack)-1]
+	if top.array {
+		return top.path + "[" + strconv.Itoa(top.index) + "]"
+	}
+	if top.path == "" {
+		return top.key
+	}
+	return top.path + "." + top.key
+}
+
+func (d *jsonPathDecoder) closeFrame() {
+	top := d.stack[len(d.stack)-1]
+	d.stack = d.stack[:len(d.stack)-1]
+	if !top.array && top.count == 0 && top.path != "" {
+		d.send(top.path, map[string]any{})
+	}
+	if len(d.stack) == 0 {
+		d.state = decodeDone
+		return
+	}
+	d.valueDone()
+}
+
+func (d *jsonPathDecoder) valueDone() {
+	d.stack[len(d.stack)-1].count++
+	d.state = decodeAfterValue
+}
+
+func (d *jsonPathDecoder) literal() {
+	var value any
+	if err := json.Unmarshal([]byte(d.raw.String()), &value); err == nil {
+		d.send(d.path, value)
+	}
+}
+
+// flushString reports the decoded part of the current string value not yet
+// sent. Unless final, an escape sequence or UTF-8 character cut off at the
+// end waits for the next piece.
+func (d *jsonPathDecoder) flushString(final bool) {
+	raw := d.raw.String()
+	if !final {
+		raw = raw[:completeJSONPrefix(raw)]
+	}
+	decoded, err := unquoteJSON(raw)
+	if err != nil || len(decoded) <= d.sent {
+		return
+	}
+	delta := decoded[d.sent:]
+	d.sent = len(decoded)
+	d.send(d.path, delta)
+}
+
+func (d *jsonPathDecoder) send(path string, delta any) {
+	if !d.emit(path, delta) {
+		d.stopped = true
+	}
+}
+
+// completeJSONPrefix returns the length of the longest prefix of a raw JSON
+// string body that ends neither inside an escape sequence, nor between the
+// halves of a surrogate pair, nor inside a UTF-8 character.
+func completeJSONPrefix(raw string) int {
+	end := len(raw)
+	for i := 0; i < len(raw); i++ {
+		if raw[i] != '\\' {
+			continue
+		}
+		n := 2
+		if i+1 < len(raw) && raw[i+1] == 'u' {
+			n = 6
+			if i+n <= len(raw) {
+				if v, err := strconv.ParseUint(raw[i+2:i+6], 16, 16); err == nil && v >= 0xD800 && v < 0xDC00 {
+					n = 12
+				}
+			}
+		}
+		if i+n > len(raw) {
+			end = i
+			break
+		}
+		i += n - 1
+	}
+	for back := 0; back < utf8.UTFMax && end > 0; back++ {
+		if r, size := utf8.DecodeLastRuneInString(raw[:end]); r != utf8.RuneError || size > 1 {
+			break
+		}
+		end--
+	}
+	return end
+}
+
+func unquoteJSON(raw string) (string, error) {
+	var s string
+	err := json.Unmarshal([]byte(`"`+raw+`"`), &s)
+	return s, err
+}
+
+func isJSONSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
@@ -0,0 +1,54 @@
+package tui
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+
+	cmpsidebar "tui/components/sidebar"
+	"tui/util"
+)
+
+// handleSectionAction runs the command of a custom section button. For an
+// input it first asks for the text in the chat input; the next submit sends
+// it instead of a chat message.
+func (m *Model) handleSectionAction(msg cmpsidebar.SectionActionMsg) tea.Cmd {
+	if msg.Action.Type != cmpsidebar.SectionActionInput {
+		return m.InvokeAgentCommand(msg.Agent, msg.Action.Command, msg.Action.Args)
+	}
+
+	m.pendingSectionInput = &msg
+	placeholder := msg.Action.Placeholder
+	if placeholder == "" {
+		placeholder = msg.Action.Label + "..."
+	}
+	m.input.SetValue("")
+	m.input.SetPlaceholder(placeholder)
+	m.sidebar.Blur()
+	m.messages.ClearFocus()
+	cmd := m.input.Focus()
+	m.refreshHelp()
+	return tea.Batch(cmd, util.ReportInfo(fmt.Sprintf("%s: type a value and press enter, esc to cancel", msg.Action.Label)))
+}
+
+// submitSectionInput invokes the command of the pending section input with
+// the text entered.
+func (m *Model) submitSectionInput(val string) tea.Cmd {
+	pending := m.pendingSectionInput
+	m.cancelSectionInput()
+
+	args := make(map[string]any, len(pending.Action.Args)+1)
+	for k, v := range pending.Action.Args {
+		args[k] = v
+	}
+	args[pending.Action.Arg] = val
+	return m.InvokeAgentCommand(pending.Agent, pending.Action.Command, args)
+}
+
+// cancelSectionInput drops the pending section input and restores the chat
+// input.
+func (m *Model) cancelSectionInput() {
+	m.pendingSectionInput = nil
+	m.input.SetPlaceholder("")
+	m.input.SetValue("")
+}
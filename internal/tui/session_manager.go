@@ -2,6 +2,7 @@ package tui
 
 import (
 	"context"
+	"fmt"
 	"strings"
 	"time"
 
@@ -423,3 +424,92 @@ func (m *Model) loadConversation(sessionID string) error {
 func (m *Model) maybeUpdateConversationTitle(text string) {
 	m.sessionManager().MaybeUpdateTitle(context.Background(), text)
 }
+
+// titleTimeout bounds the model call generating a conversation title.
+const titleTimeout = 30 * time.Second
+
+// maybeGenerateTitle titles the conversation sessionID with the model after
+// its first exchange, and every few exchanges after that checks whether it
+// moved on to another topic. Conversations someone renamed keep their title.
+func (m *Model) maybeGenerateTitle(sessionID string) tea.Cmd {
+	if m.convStore == nil || strings.TrimSpace(sessionID) == "" {
+		return nil
+	}
+	history := m.sessionManager().ConversationHistory(context.Background(), sessionID)
+	userMessages := sessionstate.CountUserMessages(history)
+	if !sessionstate.NeedsTitle(userMessages) {
+		return nil
+	}
+	conv, err := m.convStore.Get(context.Background(), sessionID)
+	if err != nil || conv.Renamed {
+		return nil
+	}
+	current := conv.Title
+	if userMessages == 1 {
+		// Still the start of the first message
+		current = ""
+	}
+	return generateTitleCmd(sessionID, current, sessionstate.TitleMessages(history), false)
+}
+
+func generateTitleCmd(sessionID, current string, messages []sessionstate.TitleMessage, announce bool) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), titleTimeout)
+		defer cancel()
+		title, changed, err := llm.GenerateConversationTitle(ctx, current, messages)
+		if !changed {
+			title = ""
+		}
+		return conversationTitleMsg{sessionID: sessionID, title: title, announce: announce, err: err}
+	}
+}
+
+func (m *Model) handleConversationTitle(msg conversationTitleMsg) {
+	if msg.title != "" && m.convStore != nil {
+		if err := m.convStore.SetGeneratedTitle(context.Background(), msg.sessionID, msg.title); err != nil && msg.err == nil {
+			msg.err = err
+		}
+		m.refreshConversationModalList()
+	}
+	if !msg.announce || msg.sessionID != m.sessionID {
+		return
+	}
+	text := fmt.Sprintf("Conversation titled **%s**.", msg.title)
+	if msg.err != nil {
+		text = fmt.Sprintf("Could not generate a title: %v", msg.err)
+	}
+	m.messages.AddAssistantStart("")
+	m.messages.AppendAssistant(text)
+	m.messages.EndAssistant()
+}
+
+// RenameConversation gives the current conversation title, for /rename.
+// Without a title it has the model title it again, and generated titles
+// follow its topic from then on.
+func (m *Model) RenameConversation(title string) tea.Cmd {
+	if m.convStore == nil || strings.TrimSpace(m.sessionID) == "" {
+		return nil
+	}
+	ctx := context.Background()
+	if err := m.convStore.Rename(ctx, m.sessionID, title); err != nil {
+		m.messages.AddAssistantStart("")
+		m.messages.AppendAssistant(fmt.Sprintf("Could not rename the conversation: %v", err))
+		m.messages.EndAssistant()
+		return nil
+	}
+	if title != "" {
+		m.refreshConversationModalList()
+		m.messages.AddAssistantStart("")
+		m.messages.AppendAssistant(fmt.Sprintf("Conversation renamed to **%s**.", title))
+		m.messages.EndAssistant()
+		return nil
+	}
+	messages := sessionstate.TitleMessages(m.sessionManager().ConversationHistory(ctx, m.sessionID))
+	if len(messages) == 0 {
+		m.messages.AddAssistantStart("")
+		m.messages.AppendAssistant("Usage: `/rename <title>` names this conversation; `/rename` alone has the model title it once it has messages.")
+		m.messages.EndAssistant()
+		return nil
+	}
+	return generateTitleCmd(m.sessionID, "", messages, true)
+}
@@ -3,8 +3,10 @@ package sessionstate
 import (
 	"encoding/json"
 	"fmt"
+	"os"
 	"strings"
 
+	"opperator/config"
 	"tui/coreagent"
 	tooling "tui/tools"
 	tooltypes "tui/tools/types"
@@ -27,6 +29,16 @@ type TodoInfo struct {
 	Completed bool
 }
 
+// ContextFiles returns the context files core agents are given: the global
+// context.md and the OPPERATOR.md nearest the working directory.
+func ContextFiles() []config.ContextFile {
+	wd, err := os.Getwd()
+	if err != nil {
+		wd = ""
+	}
+	return config.LoadContextFiles(wd)
+}
+
 // BuildInstructions constructs the instruction payload for a session request.
 func BuildInstructions(basePrompt, agentName, agentPrompt string, agentPromptReplace bool, agentOptions []AgentOption, agentListErr error, focusedAgentTools []tooling.Spec, focusedAgentInfo FocusedAgentInfo, coreAgentID string) string {
 	base := strings.TrimSpace(basePrompt)
@@ -61,6 +73,11 @@ func BuildInstructions(basePrompt, agentName, agentPrompt string, agentPromptRep
 		return b.String()
 	}
 
+	// Core agents get the user's context files
+	if section := config.FormatContextFiles(ContextFiles()); section != "" {
+		base += "\n\n" + section
+	}
+
 	// Add focused agent tools section for Builder when tools are available
 	toolsSection := ""
 	docsSection := ""
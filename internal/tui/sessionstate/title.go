@@ -0,0 +1,150 @@
+package sessionstate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"tui/opper"
+)
+
+// TitleModel is the model titles are generated with: a small one, since a
+// title is a few words.
+const TitleModel = "openai/gpt-5-nano"
+
+// RetitleEvery is how many user messages pass between checks for a change
+// of topic, after the title generated from the first exchange.
+const RetitleEvery = 5
+
+const (
+	maxTitleLength       = 60
+	maxTitleMessages     = 6
+	maxTitleMessageChars = 1000
+)
+
+// TitleMessage is one message of the conversation shown to the title model.
+type TitleMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// NeedsTitle reports whether a conversation with userMessages user messages
+// just finished the exchange after which its title is generated or checked
+// against its topic: the first, then every RetitleEvery.
+func NeedsTitle(userMessages int) bool {
+	return userMessages > 0 && (userMessages-1)%RetitleEvery == 0
+}
+
+// TitleMessages picks the user and assistant text of history shown to the
+// title model: its last few messages, each shortened.
+func TitleMessages(history []Message) []TitleMessage {
+	var messages []TitleMessage
+	for _, h := range history {
+		role := strings.ToLower(strings.TrimSpace(h.Role))
+		content := strings.TrimSpace(h.Content)
+		if (role != "user" && role != "assistant") || content == "" {
+			continue
+		}
+		if runes := []rune(content); len(runes) > maxTitleMessageChars {
+			content = string(runes[:maxTitleMessageChars]) + "…"
+		}
+		messages = append(messages, TitleMessage{Role: role, Content: content})
+	}
+	if len(messages) > maxTitleMessages {
+		messages = messages[len(messages)-maxTitleMessages:]
+	}
+	return messages
+}
+
+// CountUserMessages counts the user messages of history with text.
+func CountUserMessages(history []Message) int {
+	count := 0
+	for _, h := range history {
+		if strings.EqualFold(strings.TrimSpace(h.Role), "user") && strings.TrimSpace(h.Content) != "" {
+			count++
+		}
+	}
+	return count
+}
+
+// GenerateTitle asks the title model for a concise title of a conversation
+// from its recent messages. Given the current title, it only returns a new
+// one when the conversation has moved on to a different topic; changed is
+// false when the current title still fits.
+func GenerateTitle(ctx context.Context, client *opper.Opper, current string, messages []TitleMessage) (title string, changed bool, err error) {
+	if len(messages) == 0 {
+		return "", false, fmt.Errorf("no messages to title")
+	}
+	instructions := "Write a title for this conversation of at most six words, in the language of the conversation. " +
+		"Name its subject plainly, without quotes, punctuation at the end, or words like 'conversation' or 'chat'."
+	if current != "" {
+		instructions += " The conversation is currently titled current_title. Set topic_changed only when the recent " +
+			"messages are about a clearly different subject than that title describes; a follow-up, refinement " +
+			"or detail of the same subject is not a change."
+	}
+	req := opper.StreamRequest{
+		Name:         "opperator.conversation_title",
+		Instructions: &instructions,
+		Input: map[string]any{
+			"current_title": current,
+			"messages":      messages,
+		},
+		OutputSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"title":         map[string]any{"type": "string"},
+				"topic_changed": map[string]any{"type": "boolean"},
+			},
+			"required": []string{"title", "topic_changed"},
+		},
+		Model: TitleModel,
+	}
+
+	events, err := client.Stream(ctx, req)
+	if err != nil {
+		return "", false, err
+	}
+	agg := opper.NewJSONChunkAggregator()
+	var streamErr error
+	for event := range events {
+		if event.Err != nil {
+			streamErr = event.Err
+		}
+		if event.Data.JSONPath != "" && event.Data.Delta != nil {
+			agg.Add(event.Data.JSONPath, event.Data.Delta)
+		}
+	}
+	if streamErr != nil {
+		return "", false, streamErr
+	}
+	assembled, err := agg.Assemble()
+	if err != nil {
+		return "", false, fmt.Errorf("failed to assemble title: %w", err)
+	}
+	var out struct {
+		Title        string `json:"title"`
+		TopicChanged bool   `json:"topic_changed"`
+	}
+	if err := json.Unmarshal([]byte(assembled), &out); err != nil {
+		return "", false, fmt.Errorf("failed to parse title: %w", err)
+	}
+	title = cleanTitle(out.Title)
+	if title == "" {
+		return "", false, fmt.Errorf("model returned an empty title")
+	}
+	if current != "" && (!out.TopicChanged || strings.EqualFold(title, current)) {
+		return current, false, nil
+	}
+	return title, true, nil
+}
+
+func cleanTitle(title string) string {
+	title = strings.Join(strings.Fields(title), " ")
+	title = strings.Trim(title, `"'“”‘’`)
+	title = strings.TrimRight(title, ".!")
+	if runes := []rune(title); len(runes) > maxTitleLength {
+		title = strings.TrimSpace(string(runes[:maxTitleLength-1])) + "…"
+	}
+	return title
+}
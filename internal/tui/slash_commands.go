@@ -0,0 +1,224 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+
+	"tui/commands"
+	"tui/internal/protocol"
+	llm "tui/llm"
+	"tui/util"
+)
+
+// agentSlashCommandsFetchedMsg carries the commands of every running agent.
+type agentSlashCommandsFetchedMsg struct {
+	commands map[string][]protocol.CommandDescriptor
+}
+
+// fetchAgentSlashCommandsCmd collects the commands of the running agents so
+// their global slash commands are available whichever agent is active.
+func fetchAgentSlashCommandsCmd(agents []llm.AgentInfo) tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		all := make(map[string][]protocol.CommandDescriptor, len(agents))
+		for _, agent := range agents {
+			if !strings.EqualFold(strings.TrimSpace(agent.Status), "running") {
+				continue
+			}
+			wg.Add(1)
+			go func(agent llm.AgentInfo) {
+				defer wg.Done()
+				cmds, err := llm.FetchAgentCommands(ctx, agent.Name, agent.Daemon)
+				if err != nil {
+					return
+				}
+				mu.Lock()
+				all[agent.Name] = cmds
+				mu.Unlock()
+			}(agent)
+		}
+		wg.Wait()
+		return agentSlashCommandsFetchedMsg{commands: all}
+	}
+}
+
+// setAgentSlashCommands replaces the known commands of every agent.
+func (c *agentController) setAgentSlashCommands(all map[string][]protocol.CommandDescriptor) tea.Cmd {
+	c.agentSlashCommands = all
+	return c.reportSlashWarnings(c.rebuildSlashCommands())
+}
+
+// updateAgentSlashCommands records new commands announced by one agent.
+func (c *agentController) updateAgentSlashCommands(agentName string, cmds []protocol.CommandDescriptor) tea.Cmd {
+	if c.agentSlashCommands == nil {
+		c.agentSlashCommands = make(map[string][]protocol.CommandDescriptor)
+	}
+	c.agentSlashCommands[agentName] = cmds
+	return c.reportSlashWarnings(c.rebuildSlashCommands())
+}
+
+// reportSlashWarnings shows each warning once per session.
+func (c *agentController) reportSlashWarnings(warnings []string) tea.Cmd {
+	if c.reportedSlashConflicts == nil {
+		c.reportedSlashConflicts = make(map[string]struct{})
+	}
+	var cmds []tea.Cmd
+	for _, warning := range warnings {
+		if _, seen := c.reportedSlashConflicts[warning]; seen {
+			continue
+		}
+		c.reportedSlashConflicts[warning] = struct{}{}
+		cmds = append(cmds, util.ReportWarn(warning))
+	}
+	return tea.Batch(cmds...)
+}
+
+// rebuildSlashCommands registers the slash commands of the active agent and
+// the global ones of every running agent, each also as /agent:command, plus
+// the configured aliases. A global command exposed by several agents is
+// only registered under its namespaced names. It returns warnings about
+// names that clash.
+func (c *agentController) rebuildSlashCommands() []string {
+	all := make(map[string][]protocol.CommandDescriptor, len(c.agentSlashCommands)+1)
+	for name, cmds := range c.agentSlashCommands {
+		all[name] = cmds
+	}
+	active := strings.TrimSpace(c.activeName)
+	if active != "" {
+		for name := range all {
+			if strings.EqualFold(name, active) {
+				delete(all, name)
+			}
+		}
+		all[active] = c.activeCommands
+	}
+
+	var warnings []string
+	if c.slashAliasErr != nil {
+		warnings = append(warnings, fmt.Sprintf("Slash command aliases not loaded: %v", c.slashAliasErr))
+	}
+
+	taken := make(map[string]struct{})
+	for _, cmd := range commands.List() {
+		if cmd.Scope == commands.ScopeBase {
+			taken[cmd.Name] = struct{}{}
+		}
+	}
+
+	agents := make([]string, 0, len(all))
+	for name := range all {
+		agents = append(agents, name)
+	}
+	sort.Strings(agents)
+
+	// Plain names of global commands, by the agents exposing them
+	globalOwners := make(map[string][]string)
+	for _, agent := range agents {
+		for _, cmd := range slashExposed(all[agent]) {
+			if cmd.SlashScope == protocol.SlashCommandScopeGlobal {
+				globalOwners[cmd.SlashCommand] = append(globalOwners[cmd.SlashCommand], agent)
+			}
+		}
+	}
+
+	var local, global []commands.Command
+	register := func(list *[]commands.Command, agent string, cmd protocol.CommandDescriptor, name string) bool {
+		if _, exists := taken[name]; exists {
+			return false
+		}
+		taken[name] = struct{}{}
+		*list = append(*list, slashCommandFor(agent, cmd, name))
+		return true
+	}
+
+	for _, alias := range c.slashAliases {
+		agent, cmd, ok := findSlashTarget(all, alias.Agent, alias.Command)
+		if !ok {
+			continue
+		}
+		if !register(&global, agent, cmd, alias.Name) {
+			warnings = append(warnings, fmt.Sprintf("Slash alias %s clashes with a built-in command and was ignored", alias.Name))
+		}
+	}
+
+	// The active agent's plain names win over other agents' global ones
+	if active != "" {
+		for _, cmd := range slashExposed(all[active]) {
+			if cmd.SlashScope != protocol.SlashCommandScopeGlobal {
+				register(&local, active, cmd, cmd.SlashCommand)
+			}
+		}
+	}
+
+	for _, agent := range agents {
+		for _, cmd := range slashExposed(all[agent]) {
+			isGlobal := cmd.SlashScope == protocol.SlashCommandScopeGlobal
+			if !isGlobal && agent != active {
+				continue
+			}
+			if isGlobal {
+				owners := globalOwners[cmd.SlashCommand]
+				if len(owners) == 1 {
+					register(&global, agent, cmd, cmd.SlashCommand)
+				} else if owners[0] == agent {
+					names := make([]string, len(owners))
+					for i, owner := range owners {
+						names[i] = namespacedSlash(owner, cmd.SlashCommand)
+					}
+					warnings = append(warnings, fmt.Sprintf("%s is a global command of %s; use %s",
+						cmd.SlashCommand, strings.Join(owners, ", "), strings.Join(names, " or ")))
+				}
+				register(&global, agent, cmd, namespacedSlash(agent, cmd.SlashCommand))
+			} else {
+				register(&local, agent, cmd, namespacedSlash(agent, cmd.SlashCommand))
+			}
+		}
+	}
+
+	commands.SetGlobal(global)
+	commands.SetLocal(local)
+	return warnings
+}
+
+// slashExposed returns the commands exposed as slash commands.
+func slashExposed(cmds []protocol.CommandDescriptor) []protocol.CommandDescriptor {
+	out := make([]protocol.CommandDescriptor, 0, len(cmds))
+	for _, cmd := range cmds {
+		if hasSlashExposure(cmd) && strings.TrimSpace(cmd.SlashCommand) != "" && strings.TrimSpace(cmd.Name) != "" {
+			out = append(out, cmd)
+		}
+	}
+	return out
+}
+
+// namespacedSlash returns the /agent:command form of an agent's slash
+// command.
+func namespacedSlash(agent, slash string) string {
+	agent = strings.ToLower(strings.Join(strings.Fields(agent), "_"))
+	return "/" + agent + ":" + strings.TrimPrefix(slash, "/")
+}
+
+// findSlashTarget finds an agent command by its slash or command name.
+func findSlashTarget(all map[string][]protocol.CommandDescriptor, agent, command string) (string, protocol.CommandDescriptor, bool) {
+	for name, cmds := range all {
+		if !strings.EqualFold(name, agent) {
+			continue
+		}
+		for _, cmd := range cmds {
+			if strings.EqualFold(strings.TrimPrefix(cmd.SlashCommand, "/"), command) || strings.EqualFold(cmd.Name, command) {
+				return name, cmd, true
+			}
+		}
+	}
+	return "", protocol.CommandDescriptor{}, false
+}
@@ -35,6 +35,7 @@ func Start() error {
 		model,
 		tea.WithAltScreen(),
 		tea.WithMouseAllMotion(),              // Enable mouse motion events for text selection
+		tea.WithReportFocus(),                 // Focus events gate desktop notifications
 		tea.WithFilter(scrollEventFilter), // Filter events before they enter the queue
 	)
 	_, err = p.Run()
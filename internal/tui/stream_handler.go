@@ -282,6 +282,16 @@ func (m *Model) handleStreamMsg(sessionID string, msg tea.Msg) tea.Cmd {
 				cmds = append(cmds, cmd)
 			}
 		}
+		if state := m.streamState(sessionID); state != nil && v.Err == nil {
+			if cmd := m.notifyStreamDone(state.StartedAt); cmd != nil {
+				cmds = append(cmds, cmd)
+			}
+		}
+		if v.Err == nil {
+			if cmd := m.maybeGenerateTitle(sessionID); cmd != nil {
+				cmds = append(cmds, cmd)
+			}
+		}
 		if cmd := m.completeResponse(sessionID); cmd != nil {
 			cmds = append(cmds, cmd)
 		}
@@ -386,11 +396,12 @@ func (m *Model) handleStreamMsg(sessionID string, msg tea.Msg) tea.Cmd {
 func (m *Model) requestLLM(sessionID string) tea.Cmd {
 	if m.llmEngine == nil {
 		m.llmEngine = llm.NewEngine(m.permissions, m.secretPromptService(), m.workingDir, m.userWorkingDir, m.lspManager)
+		m.llmEngine.SetMockTools(m.mockTools)
 	}
 	adapter := newSessionAdapter(m, sessionID)
 	m.beginSpanTurnForSession(sessionID)
 	cmd, cancel, ch := m.llmEngine.Request(adapter)
-	state := &streaming.State{Cancel: cancel, Channel: ch}
+	state := &streaming.State{Cancel: cancel, Channel: ch, StartedAt: time.Now()}
 	m.setStreamState(sessionID, state)
 	return func() tea.Msg {
 		msg := cmd()
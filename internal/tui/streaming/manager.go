@@ -3,6 +3,7 @@ package streaming
 import (
 	"context"
 	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea/v2"
 
@@ -15,6 +16,7 @@ type State struct {
 	Channel   chan tea.Msg
 	Canceling bool
 	Waiting   bool
+	StartedAt time.Time
 }
 
 type PendingAssistant struct {
@@ -0,0 +1,148 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea/v2"
+
+	"opperator/pkg/sparkline"
+	"tui/internal/taskstats"
+)
+
+// statsCommands is how many agent commands and tools /stats lists.
+const statsCommands = 12
+
+// ShowTaskStats charts the async tasks finished on the local daemon: per
+// hour over the last day, or per day over the last two weeks with "days".
+func (m *Model) ShowTaskStats(arg string) tea.Cmd {
+	interval, count := time.Hour, 24
+	switch strings.ToLower(strings.TrimSpace(arg)) {
+	case "", "hour", "hours", "hourly":
+	case "day", "days", "daily":
+		interval, count = 24*time.Hour, 14
+	default:
+		m.messages.AddAssistantStart("")
+		m.messages.AppendAssistant("Usage: `/stats [hours|days]` charts the finished async tasks per hour over the last day, or per day over the last two weeks.")
+		m.messages.EndAssistant()
+		return nil
+	}
+	if m.convStore == nil {
+		return nil
+	}
+	db := m.convStore.DB()
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		report, err := taskstats.Load(ctx, db, interval, count, time.Now())
+		return taskStatsMsg{report: report, err: err}
+	}
+}
+
+func (m *Model) showTaskStats(msg taskStatsMsg) {
+	m.messages.AddAssistantStart("")
+	m.messages.AppendAssistant(renderTaskStats(msg.report, msg.err))
+	m.messages.EndAssistant()
+}
+
+func renderTaskStats(report taskstats.Report, err error) string {
+	if err != nil {
+		return fmt.Sprintf("Could not read the task history: %v", err)
+	}
+	unit, span := "hour", "day"
+	if report.Interval >= 24*time.Hour {
+		unit, span = "day", fmt.Sprintf("%d days", len(report.Buckets))
+	}
+	complete, failed := report.Total()
+	if complete+failed == 0 {
+		return fmt.Sprintf("No async tasks finished on the local daemon in the last %s.", span)
+	}
+
+	done := make([]float64, len(report.Buckets))
+	failures := make([]float64, len(report.Buckets))
+	rates := make([]float64, len(report.Buckets))
+	for i, b := range report.Buckets {
+		done[i] = float64(b.Total())
+		failures[i] = float64(b.Failed)
+		rates[i] = 100
+		if b.Total() > 0 {
+			rates[i] = 100 * float64(b.Complete) / float64(b.Total())
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Async tasks per %s over the last %s: **%d** finished, %d failed (%.0f%% success).\n\n```\n",
+		unit, span, complete+failed, failed, 100*float64(complete)/float64(complete+failed))
+	fmt.Fprintf(&b, "%-9s %s  max %d\n", "finished", sparkline.Render(done), int(maxOf(done)))
+	fmt.Fprintf(&b, "%-9s %s  max %d\n", "failed", sparkline.Render(failures), int(maxOf(failures)))
+	fmt.Fprintf(&b, "%-9s %s  min %.0f%%\n", "success", sparkline.Render(rates), minOf(rates))
+	fmt.Fprintf(&b, "%-9s %s → %s\n", "", report.Buckets[0].Start.Format(bucketFormat(report.Interval)),
+		report.Buckets[len(report.Buckets)-1].Start.Format(bucketFormat(report.Interval)))
+	b.WriteString("```\n\n")
+
+	width := 0
+	commands := report.Commands
+	if len(commands) > statsCommands {
+		commands = commands[:statsCommands]
+	}
+	for _, c := range commands {
+		width = max(width, len([]rune(c.Name)))
+	}
+	width = min(width, 32)
+	fmt.Fprintf(&b, "By agent command, with the trend of the average duration per %s:\n\n```\n", unit)
+	fmt.Fprintf(&b, "%-*s %6s %7s %8s  %s\n", width, "COMMAND", "TASKS", "FAILED", "AVG", "TREND")
+	for _, c := range commands {
+		averages := make([]float64, len(c.Averages))
+		for i, d := range c.Averages {
+			averages[i] = d.Seconds()
+		}
+		name := c.Name
+		if runes := []rune(name); len(runes) > width {
+			name = string(runes[:width-1]) + "…"
+		}
+		fmt.Fprintf(&b, "%-*s %6d %6.0f%% %8s  %s\n", width, name, c.Total,
+			100*float64(c.Failed)/float64(c.Total), formatTaskDuration(c.Average), sparkline.Render(averages))
+	}
+	if len(report.Commands) > len(commands) {
+		fmt.Fprintf(&b, "… and %d more\n", len(report.Commands)-len(commands))
+	}
+	b.WriteString("```")
+	return b.String()
+}
+
+func bucketFormat(interval time.Duration) string {
+	if interval >= 24*time.Hour {
+		return "Jan 2"
+	}
+	return "Jan 2 15:04"
+}
+
+func formatTaskDuration(d time.Duration) string {
+	switch {
+	case d < time.Second:
+		return fmt.Sprintf("%dms", d.Milliseconds())
+	case d < time.Minute:
+		return fmt.Sprintf("%.1fs", d.Seconds())
+	default:
+		return d.Round(time.Second).String()
+	}
+}
+
+func maxOf(values []float64) float64 {
+	out := 0.0
+	for _, v := range values {
+		out = math.Max(out, v)
+	}
+	return out
+}
+
+func minOf(values []float64) float64 {
+	out := 100.0
+	for _, v := range values {
+		out = math.Min(out, v)
+	}
+	return out
+}
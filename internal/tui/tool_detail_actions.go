@@ -0,0 +1,228 @@
+package tui
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/atotto/clipboard"
+	"github.com/charmbracelet/bubbles/v2/key"
+	tea "github.com/charmbracelet/bubbletea/v2"
+
+	tooling "tui/tools"
+	tooltypes "tui/tools/types"
+	"tui/util"
+)
+
+// toolDownloadTimeout bounds downloading the files of a tool result.
+const toolDownloadTimeout = 5 * time.Minute
+
+var (
+	toolDetailCopyKey = key.NewBinding(
+		key.WithKeys("c", "y"),
+		key.WithHelp("c", "copy result"),
+	)
+	toolDetailSaveKey = key.NewBinding(
+		key.WithKeys("s"),
+		key.WithHelp("s", "save"),
+	)
+	toolDetailOpenKey = key.NewBinding(
+		key.WithKeys("o"),
+		key.WithHelp("o", "open"),
+	)
+)
+
+// toolArtifacts are the files an agent command output, as recorded in its
+// tool result metadata.
+type toolArtifacts struct {
+	Daemon string                `json:"daemon"`
+	Files  []tooling.CommandFile `json:"files"`
+}
+
+func parseToolArtifacts(metadata string) toolArtifacts {
+	var a toolArtifacts
+	if strings.TrimSpace(metadata) != "" {
+		_ = json.Unmarshal([]byte(metadata), &a)
+	}
+	if a.Daemon == "" {
+		a.Daemon = "local"
+	}
+	return a
+}
+
+// toolDetailAction runs the action bound to msg on the tool result shown in
+// the detail view, if any.
+func toolDetailAction(msg tea.KeyPressMsg, call tooltypes.Call, result tooltypes.Result) (tea.Cmd, bool) {
+	switch {
+	case key.Matches(msg, toolDetailCopyKey):
+		content := toolResultText(result.Content)
+		return tea.Sequence(
+			tea.SetClipboard(content),
+			func() tea.Msg {
+				_ = clipboard.WriteAll(content)
+				return nil
+			},
+			util.ReportInfo("Tool result copied to clipboard"),
+		), true
+	case key.Matches(msg, toolDetailSaveKey):
+		return func() tea.Msg {
+			paths, err := saveToolResult(call, result, downloadsDir())
+			if err != nil {
+				return util.InfoMsg{Type: util.InfoTypeError, Msg: err.Error()}
+			}
+			return util.InfoMsg{Type: util.InfoTypeInfo, Msg: "Saved " + strings.Join(paths, ", ")}
+		}, true
+	case key.Matches(msg, toolDetailOpenKey):
+		return func() tea.Msg {
+			dir, err := os.MkdirTemp("", "opperator-")
+			if err != nil {
+				return util.InfoMsg{Type: util.InfoTypeError, Msg: err.Error()}
+			}
+			paths, err := saveToolResult(call, result, dir)
+			if err == nil {
+				for _, path := range paths {
+					if err = openWithSystem(path); err != nil {
+						break
+					}
+				}
+			}
+			if err != nil {
+				return util.InfoMsg{Type: util.InfoTypeError, Msg: err.Error()}
+			}
+			return util.InfoMsg{Type: util.InfoTypeInfo, Msg: "Opened " + strings.Join(paths, ", ")}
+		}, true
+	}
+	return nil, false
+}
+
+// saveToolResult writes the files of a tool result into dir, or the result
+// itself when it has none, and returns the paths written.
+func saveToolResult(call tooltypes.Call, result tooltypes.Result, dir string) ([]string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	artifacts := parseToolArtifacts(result.Metadata)
+	if len(artifacts.Files) == 0 {
+		content := toolResultText(result.Content)
+		if content == "" {
+			return nil, errors.New("tool result is empty")
+		}
+		name := toolResultFileName(call, content)
+		f, path, err := createUniqueFile(dir, name)
+		if err != nil {
+			return nil, err
+		}
+		_, err = f.WriteString(content + "\n")
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return nil, err
+		}
+		return []string{path}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), toolDownloadTimeout)
+	defer cancel()
+	var paths []string
+	for _, file := range artifacts.Files {
+		f, path, err := createUniqueFile(dir, file.Name)
+		if err != nil {
+			return paths, err
+		}
+		err = tooling.DownloadBlob(ctx, artifacts.Daemon, file.BlobID, f)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			os.Remove(path)
+			return paths, fmt.Errorf("download %s: %w", file.Name, err)
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// toolResultText returns a tool result with JSON indented.
+func toolResultText(content string) string {
+	trimmed := strings.TrimSpace(content)
+	var buf bytes.Buffer
+	if json.Valid([]byte(trimmed)) && json.Indent(&buf, []byte(trimmed), "", "  ") == nil {
+		return buf.String()
+	}
+	return trimmed
+}
+
+func toolResultFileName(call tooltypes.Call, content string) string {
+	name := strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ' ' {
+			return '-'
+		}
+		return r
+	}, strings.TrimSpace(call.Name))
+	if name == "" {
+		name = "tool-result"
+	}
+	if json.Valid([]byte(content)) {
+		return name + ".json"
+	}
+	return name + ".txt"
+}
+
+// downloadsDir is where saved tool results go: ~/Downloads when it exists,
+// else the current directory.
+func downloadsDir() string {
+	if home, err := os.UserHomeDir(); err == nil {
+		dir := filepath.Join(home, "Downloads")
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return dir
+		}
+	}
+	if wd, err := os.Getwd(); err == nil {
+		return wd
+	}
+	return "."
+}
+
+// createUniqueFile creates name in dir, or "name-2.ext" and so on when it
+// exists.
+func createUniqueFile(dir, name string) (*os.File, string, error) {
+	name = filepath.Base(strings.TrimSpace(name))
+	if name == "." || name == ".." || name == string(filepath.Separator) {
+		return nil, "", fmt.Errorf("invalid file name %q", name)
+	}
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for i := 1; ; i++ {
+		path := filepath.Join(dir, name)
+		if i > 1 {
+			path = filepath.Join(dir, fmt.Sprintf("%s-%d%s", base, i, ext))
+		}
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0o644)
+		if err == nil {
+			return f, path, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, "", err
+		}
+	}
+}
+
+func openWithSystem(path string) error {
+	switch runtime.GOOS {
+	case "darwin":
+		return exec.Command("open", path).Start()
+	case "windows":
+		return exec.Command("rundll32", "url.dll,FileProtocolHandler", path).Start()
+	default:
+		return exec.Command("xdg-open", path).Start()
+	}
+}
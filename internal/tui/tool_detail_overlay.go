@@ -10,8 +10,10 @@ import (
 )
 
 type toolDetailOverlay struct {
-	id   string
-	view *cmpmessages.ToolDetailView
+	id     string
+	view   *cmpmessages.ToolDetailView
+	call   tooltypes.Call
+	result tooltypes.Result
 }
 
 func newToolDetailOverlay(call tooltypes.Call, result tooltypes.Result, width, height int) *toolDetailOverlay {
@@ -19,6 +21,7 @@ func newToolDetailOverlay(call tooltypes.Call, result tooltypes.Result, width, h
 	overlay.view.SetFrameSize(width, height)
 	overlay.view.SetData(call, result)
 	overlay.id = strings.TrimSpace(call.ID)
+	overlay.call, overlay.result = call, result
 	return overlay
 }
 
@@ -37,6 +40,7 @@ func (o *toolDetailOverlay) SetData(call tooltypes.Call, result tooltypes.Result
 		o.view = cmpmessages.NewToolDetailView()
 	}
 	o.id = strings.TrimSpace(call.ID)
+	o.call, o.result = call, result
 	return o.view.SetData(call, result)
 }
 
@@ -44,6 +48,11 @@ func (o *toolDetailOverlay) Update(msg tea.Msg) tea.Cmd {
 	if o == nil || o.view == nil {
 		return nil
 	}
+	if press, ok := msg.(tea.KeyPressMsg); ok && !o.view.Searching() {
+		if cmd, handled := toolDetailAction(press, o.call, o.result); handled {
+			return cmd
+		}
+	}
 	return o.view.Update(msg)
 }
 
@@ -60,3 +69,9 @@ func (o *toolDetailOverlay) CallID() string {
 	}
 	return o.id
 }
+
+// HandlesEscape reports whether esc belongs to the view's search rather than
+// closing the overlay.
+func (o *toolDetailOverlay) HandlesEscape() bool {
+	return o != nil && o.view != nil && o.view.HandlesEscape()
+}
@@ -29,6 +29,7 @@ func init() {
 	registerMoveAgentRenderer()
 	registerFocusAgentRenderer()
 	registerAgentCommandRenderer()
+	registerSpawnSessionAgentRenderer()
 }
 
 func registerListAgentsRenderer() {
@@ -141,7 +142,7 @@ func registerListAgentsRenderer() {
 				switch status {
 				case "running":
 					statusView = lipgloss.NewStyle().Foreground(t.Success).Render(status)
-				case "crashed":
+				case "crashed", "crashlooping":
 					statusView = lipgloss.NewStyle().Foreground(t.Error).Render(status)
 				case "stopped":
 					statusView = lipgloss.NewStyle().Foreground(t.FgMuted).Render(status)
@@ -531,6 +532,17 @@ func renderAgentAction(label string, call tooltypes.Call, result tooltypes.Resul
 				name = meta.Name
 			}
 		}
+	case "Spawn":
+		var params SpawnSessionAgentParams
+		_ = json.Unmarshal([]byte(call.Input), &params)
+		name = params.Template
+		var meta SpawnSessionAgentMetadata
+		if result.Metadata != "" {
+			_ = json.Unmarshal([]byte(result.Metadata), &meta)
+			if strings.TrimSpace(meta.Name) != "" {
+				name = meta.Name
+			}
+		}
 	}
 
 	trimmed := filepath.Base(strings.TrimSpace(name))
@@ -555,6 +567,34 @@ func renderAgentAction(label string, call tooltypes.Call, result tooltypes.Resul
 	return header + "\n\n" + gutter + body
 }
 
+func registerSpawnSessionAgentRenderer() {
+	toolregistry.Register(SpawnSessionAgentToolName, toolregistry.Definition{
+		Label: "Spawn",
+		Pending: func(call tooltypes.Call, width int, spinner string) string {
+			t := styles.CurrentTheme()
+			var params SpawnSessionAgentParams
+			_ = json.Unmarshal([]byte(call.Input), &params)
+			title := "Spawn session agent"
+			if target := strings.TrimSpace(params.Template); target != "" {
+				title = fmt.Sprintf("Spawn session agent from %s", target)
+			}
+			header := lipgloss.NewStyle().Foreground(t.FgMuted).Render("└ " + title + " ")
+			return strings.TrimSpace(header + spinner)
+		},
+		Render: func(call tooltypes.Call, result tooltypes.Result, width int) string {
+			return renderAgentAction("Spawn", call, result)
+		},
+		SummaryRender: func(call tooltypes.Call, result tooltypes.Result, width int) string {
+			var meta SpawnSessionAgentMetadata
+			_ = json.Unmarshal([]byte(result.Metadata), &meta)
+			if name := strings.TrimSpace(meta.Name); name != "" {
+				return "Spawn " + name
+			}
+			return "Spawn session agent"
+		},
+	})
+}
+
 func registerFocusAgentRenderer() {
 	toolregistry.Register(FocusAgentToolName, toolregistry.Definition{
 		Label: "Focus",
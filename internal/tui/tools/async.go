@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"opperator/config"
+	"opperator/pkg/tracing"
 	toolregistry "tui/tools/registry"
 )
 
@@ -143,6 +144,9 @@ func RunAsyncTool(ctx context.Context, arguments string, workingDir string, sess
 	if clientID != "" {
 		payload["client_id"] = clientID
 	}
+	if traceParent := tracing.TraceParent(ctx); traceParent != "" {
+		payload["traceparent"] = traceParent
+	}
 
 	// Find which daemon has the agent (if agent-based async task)
 	daemonName := "local" // Default for non-agent tasks
@@ -276,22 +280,19 @@ func FetchAsyncTaskFromDaemon(ctx context.Context, taskID string, daemonName str
 		return nil, err
 	}
 	var resp struct {
-		Success bool          `json:"success"`
-		Error   string        `json:"error"`
-		Task    *rawAsyncTask `json:"task"`
+		Success   bool          `json:"success"`
+		Error     string        `json:"error"`
+		ErrorCode string        `json:"error_code"`
+		Task      *rawAsyncTask `json:"task"`
 	}
 	if err := json.Unmarshal(respBytes, &resp); err != nil {
 		return nil, err
 	}
 	if !resp.Success {
-		errMsg := strings.TrimSpace(resp.Error)
-		if errMsg == "" {
-			errMsg = "unknown error"
-		}
-		return nil, errors.New(errMsg)
+		return nil, newDaemonError(resp.ErrorCode, resp.Error, "unknown error")
 	}
 	if resp.Task == nil {
-		return nil, fmt.Errorf("task not found")
+		return nil, newDaemonError(DaemonErrNotFound, "task not found", "")
 	}
 	task, err := decodeAsyncTask(*resp.Task)
 	if err != nil {
@@ -7,12 +7,13 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
 	"strings"
 	"time"
+
+	"opperator/config"
 )
 
 //go:embed bash.md
@@ -246,9 +247,9 @@ func isWorkingDirUnderConfig(workingDir string) (bool, string) {
 }
 
 func configRoot() string {
-	home, err := os.UserHomeDir()
+	dir, err := config.GetConfigDir()
 	if err != nil {
 		return "~/.config/opperator"
 	}
-	return filepath.Join(home, ".config", "opperator")
+	return dir
 }
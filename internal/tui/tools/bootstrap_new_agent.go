@@ -11,6 +11,7 @@ import (
 	"strings"
 	"time"
 
+	"opperator/config"
 	"opperator/templates"
 
 	"gopkg.in/yaml.v3"
@@ -45,10 +46,6 @@ type AgentConfig struct {
 	Description string   `yaml:"description"`
 }
 
-type AgentsYAML struct {
-	Agents []AgentConfig `yaml:"agents"`
-}
-
 func BootstrapNewAgentSpec() Spec {
 	return Spec{
 		Name:        BootstrapNewAgentToolName,
@@ -107,14 +104,12 @@ func RunBootstrapNewAgent(ctx context.Context, arguments string) (string, string
 	}
 
 	// Get opperator config directory
-	homeDir, err := os.UserHomeDir()
+	configDir, err := config.GetConfigDir()
 	if err != nil {
-		meta.Error = fmt.Sprintf("failed to get home directory: %v", err)
+		meta.Error = fmt.Sprintf("failed to get config directory: %v", err)
 		mb, _ := json.Marshal(meta)
 		return fmt.Sprintf("Error: %s", meta.Error), string(mb)
 	}
-
-	configDir := filepath.Join(homeDir, ".config", "opperator")
 	agentDir := filepath.Join(configDir, "agents", agentName)
 
 	// Check if agent directory already exists
@@ -339,25 +334,47 @@ func initializeVenv(agentDir string) error {
 	return nil
 }
 
-// addAgentToYAML adds a new agent entry to agents.yaml
+// addAgentToYAML adds a new agent entry to agents.yaml. It edits the YAML
+// tree rather than a decoded struct so other agents' fields, comments and
+// !encrypted values survive.
 func addAgentToYAML(yamlPath, agentName, description string) error {
-	var config AgentsYAML
-
 	// Read existing YAML if it exists
 	data, err := os.ReadFile(yamlPath)
 	if err != nil && !os.IsNotExist(err) {
 		return err
 	}
 
-	if len(data) > 0 {
-		if err := yaml.Unmarshal(data, &config); err != nil {
-			return err
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("agents.yaml: top level is not a mapping")
+	}
+
+	var agents *yaml.Node
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == "agents" {
+			agents = root.Content[i+1]
+			break
 		}
 	}
+	if agents == nil {
+		agents = &yaml.Node{}
+		root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "agents"}, agents)
+	}
+	if agents.Kind != yaml.SequenceNode {
+		*agents = yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+	}
 
 	// Check if agent already exists
-	for _, agent := range config.Agents {
-		if agent.Name == agentName {
+	for _, item := range agents.Content {
+		var existing AgentConfig
+		if err := item.Decode(&existing); err == nil && existing.Name == agentName {
 			return fmt.Errorf("agent %q already exists in agents.yaml", agentName)
 		}
 	}
@@ -370,10 +387,14 @@ func addAgentToYAML(yamlPath, agentName, description string) error {
 		ProcessRoot: fmt.Sprintf("agents/%s", agentName),
 		Description: description,
 	}
-	config.Agents = append(config.Agents, newAgent)
+	var item yaml.Node
+	if err := item.Encode(newAgent); err != nil {
+		return err
+	}
+	agents.Content = append(agents.Content, &item)
 
 	// Write back to file
-	data, err = yaml.Marshal(&config)
+	data, err = yaml.Marshal(&doc)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,123 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DaemonErrBudgetExceeded is returned for model calls of an agent that has
+// used up its daily budget.
+const DaemonErrBudgetExceeded = "budget_exceeded"
+
+// budgetTimeout bounds a budget check so an unresponsive daemon does not
+// stall the conversation.
+const budgetTimeout = 3 * time.Second
+
+// ChargeModelCall counts a model call by the agent in ctx against its daily
+// budget on the agent's daemon, before the call is made. It fails only when
+// the budget is spent. Budgets are advisory: the daemon only counts, and a
+// call goes ahead when the daemon cannot be asked.
+func ChargeModelCall(ctx context.Context, model string, input any) error {
+	err := chargeBudget(ctx, map[string]any{
+		"model":        model,
+		"calls":        1,
+		"input_tokens": EstimateTokens(input),
+	})
+	if IsDaemonError(err, DaemonErrBudgetExceeded) {
+		return err
+	}
+	return nil
+}
+
+// RecordModelOutput adds the output of a call counted by ChargeModelCall to
+// the agent's estimated cost.
+func RecordModelOutput(ctx context.Context, model, output string) {
+	if output == "" {
+		return
+	}
+	_ = chargeBudget(ctx, map[string]any{
+		"model":         model,
+		"output_tokens": EstimateTokens(output),
+	})
+}
+
+func chargeBudget(ctx context.Context, charge map[string]any) error {
+	ctx, cancel := context.WithTimeout(ctx, budgetTimeout)
+	defer cancel()
+	// Budgets are kept per agent the way memories are
+	owner, daemonName := memoryOwner(ctx)
+	if daemonName == "" {
+		var err error
+		if daemonName, err = budgetDaemon(ctx, owner); err != nil {
+			return err
+		}
+	}
+	respBytes, err := ipcRequestToDaemon(ctx, daemonName, map[string]any{
+		"type":          "budget_charge",
+		"agent_name":    owner,
+		"budget_charge": charge,
+	})
+	if err != nil {
+		return err
+	}
+	var resp struct {
+		Success   bool   `json:"success"`
+		Error     string `json:"error"`
+		ErrorCode string `json:"error_code"`
+	}
+	if err := json.Unmarshal(respBytes, &resp); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	if !resp.Success {
+		return newDaemonError(resp.ErrorCode, resp.Error, "budget charge failed")
+	}
+	return nil
+}
+
+// budgetDaemons caches which daemon hosts an agent, as looking it up asks
+// every daemon and budgets are charged on every model call.
+var budgetDaemons struct {
+	sync.Mutex
+	byAgent map[string]budgetDaemonEntry
+}
+
+type budgetDaemonEntry struct {
+	daemon string
+	at     time.Time
+}
+
+func budgetDaemon(ctx context.Context, agentName string) (string, error) {
+	budgetDaemons.Lock()
+	entry, ok := budgetDaemons.byAgent[agentName]
+	budgetDaemons.Unlock()
+	if ok && time.Since(entry.at) < time.Minute {
+		return entry.daemon, nil
+	}
+	daemonName, err := FindAgentDaemon(ctx, agentName)
+	if err != nil {
+		return "", err
+	}
+	budgetDaemons.Lock()
+	if budgetDaemons.byAgent == nil {
+		budgetDaemons.byAgent = make(map[string]budgetDaemonEntry)
+	}
+	budgetDaemons.byAgent[agentName] = budgetDaemonEntry{daemon: daemonName, at: time.Now()}
+	budgetDaemons.Unlock()
+	return daemonName, nil
+}
+
+// EstimateTokens approximates the token count of v, a string or a value
+// sent as JSON, at four bytes per token.
+func EstimateTokens(v any) int {
+	if s, ok := v.(string); ok {
+		return (len(s) + 3) / 4
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return 0
+	}
+	return (len(data) + 3) / 4
+}
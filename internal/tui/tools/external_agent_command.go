@@ -9,6 +9,7 @@ import (
 	"time"
 	"unicode"
 
+	"opperator/pkg/tracing"
 	"tui/internal/protocol"
 )
 
@@ -29,6 +30,15 @@ type ExternalAgentCommandTarget struct {
 	Hidden        bool
 }
 
+// CommandFile is a file an agent command output, kept by its daemon as a
+// blob.
+type CommandFile struct {
+	Name     string `json:"name"`
+	MimeType string `json:"mime_type,omitempty"`
+	Size     int64  `json:"size"`
+	BlobID   string `json:"blob_id"`
+}
+
 type externalAgentCommandDef struct {
 	ToolName      string
 	AgentName     string
@@ -204,54 +214,7 @@ func extractCommandArgs(arguments string, schema []protocol.CommandArgument) (ma
 }
 
 func buildAgentCommandParameters(cmd protocol.CommandDescriptor) map[string]any {
-	base := map[string]any{
-		"type": "object",
-	}
-
-	properties := make(map[string]any)
-	required := make([]string, 0, len(cmd.Arguments))
-
-	for _, arg := range cmd.Arguments {
-		name := strings.TrimSpace(arg.Name)
-		if name == "" {
-			continue
-		}
-
-		typeName := strings.TrimSpace(arg.Type)
-		if typeName == "" {
-			typeName = "string"
-		}
-
-		argSchema := map[string]any{
-			"type": typeName,
-		}
-		if desc := strings.TrimSpace(arg.Description); desc != "" {
-			argSchema["description"] = desc
-		}
-		if arg.Default != nil {
-			argSchema["default"] = arg.Default
-		}
-		if len(arg.Enum) > 0 {
-			argSchema["enum"] = arg.Enum
-		}
-		if arg.Items != nil {
-			argSchema["items"] = arg.Items
-		}
-		if arg.Properties != nil {
-			argSchema["properties"] = arg.Properties
-		}
-
-		properties[name] = argSchema
-		if arg.Required {
-			required = append(required, name)
-		}
-	}
-
-	base["properties"] = properties
-	if len(required) > 0 {
-		base["required"] = required
-	}
-	return base
+	return cmd.ParametersSchema()
 }
 
 func sanitizeToolSegment(s string) string {
@@ -341,17 +304,24 @@ func RunAgentCommand(ctx context.Context, toolName, arguments, workingDir string
 	}
 
 	payload := struct {
-		Type       string         `json:"type"`
-		AgentName  string         `json:"agent_name"`
-		Command    string         `json:"command"`
-		Args       map[string]any `json:"args,omitempty"`
-		WorkingDir string         `json:"working_dir,omitempty"`
+		Type        string         `json:"type"`
+		AgentName   string         `json:"agent_name"`
+		Command     string         `json:"command"`
+		Args        map[string]any `json:"args,omitempty"`
+		WorkingDir  string         `json:"working_dir,omitempty"`
+		TimeoutMS   int64          `json:"timeout_ms,omitempty"`
+		TraceParent string         `json:"traceparent,omitempty"`
 	}{
-		Type:       "command",
-		AgentName:  agentName,
-		Command:    commandName,
-		Args:       argsData,
-		WorkingDir: workingDir,
+		Type:        "command",
+		AgentName:   agentName,
+		Command:     commandName,
+		Args:        argsData,
+		WorkingDir:  workingDir,
+		TraceParent: tracing.TraceParent(ctx),
+	}
+	// Let the daemon abort the agent command once this call gives up
+	if deadline, ok := ctx.Deadline(); ok {
+		payload.TimeoutMS = max(time.Until(deadline).Milliseconds(), 1)
 	}
 	// Send command to the correct daemon
 	respb, err := IPCRequestToDaemon(ctx, daemonName, payload)
@@ -362,9 +332,10 @@ func RunAgentCommand(ctx context.Context, toolName, arguments, workingDir string
 		Success bool   `json:"success"`
 		Error   string `json:"error"`
 		Command struct {
-			Success bool   `json:"success"`
-			Error   string `json:"error"`
-			Result  any    `json:"result"`
+			Success bool          `json:"success"`
+			Error   string        `json:"error"`
+			Result  any           `json:"result"`
+			Files   []CommandFile `json:"files"`
 		} `json:"command"`
 	}
 	if err := json.Unmarshal(respb, &resp); err != nil {
@@ -404,6 +375,10 @@ func RunAgentCommand(ctx context.Context, toolName, arguments, workingDir string
 		"success": true,
 		"result":  resp.Command.Result,
 	}
+	if len(resp.Command.Files) > 0 {
+		meta["files"] = resp.Command.Files
+		meta["daemon"] = daemonName
+	}
 	if mb, err := json.Marshal(meta); err == nil {
 		metadata = string(mb)
 	}
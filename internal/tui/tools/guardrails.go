@@ -0,0 +1,64 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// GuardrailVerdict is the daemon's judgement of an agent command call.
+type GuardrailVerdict struct {
+	Allowed bool   `json:"allowed"`
+	Confirm bool   `json:"confirm"`
+	Reason  string `json:"reason"`
+}
+
+// CheckAgentCommandGuardrails runs the target agent's guardrails over a
+// generated agent command tool call before it is executed. Calls that are
+// not agent commands, and daemons without guardrail support, are allowed.
+func CheckAgentCommandGuardrails(ctx context.Context, toolName, arguments string) GuardrailVerdict {
+	target, ok := LookupAgentCommandTool(toolName)
+	if !ok {
+		return GuardrailVerdict{Allowed: true}
+	}
+	args, err := extractCommandArgs(arguments, target.Arguments)
+	if err != nil {
+		// The command itself reports malformed arguments.
+		return GuardrailVerdict{Allowed: true}
+	}
+	agentName := strings.TrimSpace(target.Agent)
+	daemonName, err := FindAgentDaemon(ctx, agentName)
+	if err != nil {
+		return GuardrailVerdict{Allowed: true}
+	}
+
+	payload := map[string]any{
+		"type":       "guardrail_check",
+		"agent_name": agentName,
+		"guardrail": map[string]any{
+			"command":   strings.TrimSpace(target.Command),
+			"arguments": args,
+		},
+	}
+	respb, err := IPCRequestToDaemon(ctx, daemonName, payload)
+	if err != nil {
+		return GuardrailVerdict{Reason: fmt.Sprintf("could not check guardrails: %v", err)}
+	}
+	var resp struct {
+		Success   bool              `json:"success"`
+		Error     string            `json:"error"`
+		ErrorCode string            `json:"error_code"`
+		Guardrail *GuardrailVerdict `json:"guardrail"`
+	}
+	if err := json.Unmarshal(respb, &resp); err != nil {
+		return GuardrailVerdict{Reason: fmt.Sprintf("could not check guardrails: %v", err)}
+	}
+	if !resp.Success || resp.Guardrail == nil {
+		if resp.ErrorCode == "validation" || resp.ErrorCode == "not_found" {
+			return GuardrailVerdict{Allowed: true}
+		}
+		return GuardrailVerdict{Reason: "could not check guardrails: " + resp.Error}
+	}
+	return *resp.Guardrail
+}
@@ -4,7 +4,9 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net"
 	"strings"
 	"time"
@@ -12,6 +14,44 @@ import (
 	"opperator/config"
 )
 
+// Error categories reported by the daemon in the error_code response field.
+const (
+	DaemonErrNotFound     = "not_found"
+	DaemonErrUnauthorized = "unauthorized"
+	DaemonErrForbidden    = "forbidden"
+	DaemonErrBusy         = "busy"
+	DaemonErrTimeout      = "timeout"
+	DaemonErrValidation   = "validation"
+	DaemonErrUnavailable  = "unavailable"
+	// DaemonErrKeyringUnavailable means the daemon's keyring is locked or
+	// not running.
+	DaemonErrKeyringUnavailable = "keyring_unavailable"
+)
+
+// DaemonError is a failed daemon response together with its error category.
+type DaemonError struct {
+	Code    string
+	Message string
+}
+
+func (e *DaemonError) Error() string {
+	return e.Message
+}
+
+func newDaemonError(code, message, fallback string) error {
+	msg := strings.TrimSpace(message)
+	if msg == "" {
+		msg = fallback
+	}
+	return &DaemonError{Code: strings.TrimSpace(code), Message: msg}
+}
+
+// IsDaemonError reports whether err is a daemon error with the given code.
+func IsDaemonError(err error, code string) bool {
+	var daemonErr *DaemonError
+	return errors.As(err, &daemonErr) && daemonErr.Code == code
+}
+
 // dialIPC connects to the local daemon (backward compatibility)
 func dialIPC(ctx context.Context) (net.Conn, func(), error) {
 	return dialIPCDaemon(ctx, "local")
@@ -188,6 +228,42 @@ func IPCRequestToDaemon(ctx context.Context, daemonName string, payload any) ([]
 	return ipcRequestToDaemon(ctx, daemonName, payload)
 }
 
+// DownloadBlob writes a blob kept by a daemon, such as a file an agent
+// command output, to w.
+func DownloadBlob(ctx context.Context, daemonName, blobID string, w io.Writer) error {
+	var offset int64
+	for {
+		respb, err := ipcRequestToDaemon(ctx, daemonName, map[string]any{
+			"type":        "blob_download",
+			"blob_id":     blobID,
+			"blob_offset": offset,
+		})
+		if err != nil {
+			return err
+		}
+		var resp struct {
+			Success   bool   `json:"success"`
+			Error     string `json:"error"`
+			ErrorCode string `json:"error_code"`
+			BlobData  []byte `json:"blob_data"`
+			BlobDone  bool   `json:"blob_done"`
+		}
+		if err := json.Unmarshal(respb, &resp); err != nil {
+			return fmt.Errorf("decode response: %w", err)
+		}
+		if !resp.Success {
+			return newDaemonError(resp.ErrorCode, resp.Error, "failed to download "+blobID)
+		}
+		if _, err := w.Write(resp.BlobData); err != nil {
+			return err
+		}
+		if resp.BlobDone {
+			return nil
+		}
+		offset += int64(len(resp.BlobData))
+	}
+}
+
 // SendLifecycleEvent sends a lifecycle event to an agent via the daemon.
 func SendLifecycleEvent(agentName, eventType string, data map[string]interface{}) {
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
@@ -132,8 +132,9 @@ func RunManageSecret(ctx context.Context, arguments string) (string, string) {
 	}
 
 	var resp struct {
-		Success bool   `json:"success"`
-		Error   string `json:"error"`
+		Success   bool   `json:"success"`
+		Error     string `json:"error"`
+		ErrorCode string `json:"error_code"`
 	}
 	if err := json.Unmarshal(respBytes, &resp); err != nil {
 		return fmt.Sprintf("error decoding secret response: %v", err), ""
@@ -143,7 +144,17 @@ func RunManageSecret(ctx context.Context, arguments string) (string, string) {
 		if errMsg == "" {
 			errMsg = "unknown error"
 		}
-		return fmt.Sprintf("error storing secret: %s", errMsg), ""
+		var metadata string
+		if resp.ErrorCode == DaemonErrKeyringUnavailable {
+			metaBytes, _ := json.Marshal(map[string]any{
+				"secret": map[string]any{
+					"name":       name,
+					"error_code": resp.ErrorCode,
+				},
+			})
+			metadata = string(metaBytes)
+		}
+		return fmt.Sprintf("error storing secret: %s", errMsg), metadata
 	}
 
 	action := "updated"
@@ -163,6 +174,21 @@ func RunManageSecret(ctx context.Context, arguments string) (string, string) {
 	return fmt.Sprintf("secret %q %s", name, action), string(metaBytes)
 }
 
+// KeyringUnavailable reports whether the metadata of a manage_secret result
+// says the daemon's keyring was locked or not running, so the same value can
+// be stored once it is unlocked.
+func KeyringUnavailable(metadata string) bool {
+	var meta struct {
+		Secret struct {
+			ErrorCode string `json:"error_code"`
+		} `json:"secret"`
+	}
+	if metadata == "" || json.Unmarshal([]byte(metadata), &meta) != nil {
+		return false
+	}
+	return meta.Secret.ErrorCode == DaemonErrKeyringUnavailable
+}
+
 func runManageSecretDelete(ctx context.Context, name string) (string, string) {
 	respBytes, err := IPCRequestCtx(ctx, map[string]any{
 		"type":        ipcRequestDeleteSecret,
@@ -0,0 +1,177 @@
+package tools
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+//go:embed remember.md
+var rememberDescription []byte
+
+//go:embed recall.md
+var recallDescription []byte
+
+const (
+	RememberToolName = "remember"
+	RecallToolName   = "recall"
+)
+
+type RememberParams struct {
+	Content string `json:"content"`
+	Scope   string `json:"scope"`
+}
+
+type RecallParams struct {
+	Query string `json:"query"`
+	Scope string `json:"scope"`
+	Limit int    `json:"limit"`
+}
+
+type memoryEntry struct {
+	ID             int64     `json:"id"`
+	ConversationID string    `json:"conversation_id,omitempty"`
+	Content        string    `json:"content"`
+	CreatedAt      time.Time `json:"created_at"`
+	Score          float64   `json:"score,omitempty"`
+}
+
+var memoryScopeProperty = map[string]any{
+	"type":        "string",
+	"enum":        []string{"agent", "conversation"},
+	"description": "agent (default) for every conversation with this agent, conversation for this conversation only",
+}
+
+func RememberSpec() Spec {
+	return Spec{
+		Name:        RememberToolName,
+		Description: strings.TrimSpace(string(rememberDescription)),
+		Parameters: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"content": map[string]any{"type": "string", "description": "The fact to remember"},
+				"scope":   memoryScopeProperty,
+			},
+			"required": []string{"content"},
+		},
+	}
+}
+
+func RecallSpec() Spec {
+	return Spec{
+		Name:        RecallToolName,
+		Description: strings.TrimSpace(string(recallDescription)),
+		Parameters: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"query": map[string]any{"type": "string", "description": "What to look for"},
+				"scope": memoryScopeProperty,
+				"limit": map[string]any{
+					"type":        "integer",
+					"description": "Optional number of memories (default 5)",
+					"default":     5,
+				},
+			},
+			"required": []string{"query"},
+		},
+	}
+}
+
+// RunRemember stores a memory for the agent in ctx.
+func RunRemember(ctx context.Context, arguments string) (string, string) {
+	var params RememberParams
+	_ = json.Unmarshal([]byte(arguments), &params)
+	if strings.TrimSpace(params.Content) == "" {
+		return "error: missing content", ""
+	}
+
+	memories, err := memoryRequest(ctx, "memory_remember", params.Content, params.Scope, 0)
+	if err != nil {
+		return fmt.Sprintf("error: %v", err), ""
+	}
+	scope := "this agent"
+	if params.Scope == "conversation" {
+		scope = "this conversation"
+	}
+	return fmt.Sprintf("Remembered for %s.", scope), marshalMemories(memories)
+}
+
+// RunRecall searches the memories of the agent in ctx.
+func RunRecall(ctx context.Context, arguments string) (string, string) {
+	var params RecallParams
+	_ = json.Unmarshal([]byte(arguments), &params)
+	if strings.TrimSpace(params.Query) == "" {
+		return "error: missing query", ""
+	}
+
+	memories, err := memoryRequest(ctx, "memory_recall", params.Query, params.Scope, params.Limit)
+	if err != nil {
+		return fmt.Sprintf("error: %v", err), ""
+	}
+	if len(memories) == 0 {
+		return "No memories found.", marshalMemories(nil)
+	}
+	lines := make([]string, 0, len(memories))
+	for _, m := range memories {
+		lines = append(lines, fmt.Sprintf("- %s (%s, relevance %.2f)", m.Content, m.CreatedAt.Format("2006-01-02"), m.Score))
+	}
+	return strings.Join(lines, "\n"), marshalMemories(memories)
+}
+
+// memoryRequest sends a memory request for the agent in ctx: a managed agent
+// keeps its memories on its own daemon, core agents on the local one.
+func memoryRequest(ctx context.Context, requestType, text, scope string, limit int) ([]memoryEntry, error) {
+	owner, daemonName := memoryOwner(ctx)
+	if daemonName == "" {
+		var err error
+		if daemonName, err = FindAgentDaemon(ctx, owner); err != nil {
+			return nil, err
+		}
+	}
+
+	respBytes, err := ipcRequestToDaemon(ctx, daemonName, map[string]any{
+		"type":         requestType,
+		"agent_name":   owner,
+		"session_id":   SessionIDFromContext(ctx),
+		"memory_text":  text,
+		"memory_scope": strings.ToLower(strings.TrimSpace(scope)),
+		"memory_limit": limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Success   bool          `json:"success"`
+		Error     string        `json:"error"`
+		ErrorCode string        `json:"error_code"`
+		Memories  []memoryEntry `json:"memories"`
+	}
+	if err := json.Unmarshal(respBytes, &resp); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	if !resp.Success {
+		return nil, newDaemonError(resp.ErrorCode, resp.Error, "memory request failed")
+	}
+	return resp.Memories, nil
+}
+
+// memoryOwner names whose memories ctx refers to. Core agents are prefixed
+// so they cannot share memories with a managed agent of the same name.
+func memoryOwner(ctx context.Context) (owner, daemonName string) {
+	if agent := strings.TrimSpace(ActiveAgentFromContext(ctx)); agent != "" {
+		return agent, ""
+	}
+	core := strings.ToLower(strings.TrimSpace(CoreAgentFromContext(ctx)))
+	if core == "" {
+		core = "opperator"
+	}
+	return "core:" + core, "local"
+}
+
+func marshalMemories(memories []memoryEntry) string {
+	b, _ := json.Marshal(map[string]any{"memories": memories})
+	return string(b)
+}
@@ -0,0 +1,201 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MockFixtures resolves tool calls from canned results instead of running
+// them, for demos and tests of the conversation loop without live agents.
+//
+// A fixtures file maps tool names to a result, or to a list of rules tried
+// in order, each optionally matching on arguments:
+//
+//	tools:
+//	  get_forecast: "Sunny, 21°C"
+//	  bash:
+//	    - match: {command: ls}
+//	      result: "README.md"
+//	    - error: "command not allowed"
+//
+// Managed agent commands can be named with or without their agent prefix
+// (weather__get_forecast or get_forecast).
+type MockFixtures struct {
+	Path  string
+	tools map[string][]mockRule
+}
+
+// MockResult is the canned outcome of a tool call.
+type MockResult struct {
+	Output string
+	Error  bool
+}
+
+type mockRule struct {
+	Match  map[string]any `yaml:"match"`
+	Result string         `yaml:"result"`
+	Error  string         `yaml:"error"`
+}
+
+type mockTool []mockRule
+
+func (t *mockTool) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		*t = mockTool{{Result: node.Value}}
+		return nil
+	case yaml.MappingNode:
+		var rule mockRule
+		if err := node.Decode(&rule); err != nil {
+			return err
+		}
+		*t = mockTool{rule}
+		return nil
+	}
+	var rules []mockRule
+	if err := node.Decode(&rules); err != nil {
+		return err
+	}
+	*t = rules
+	return nil
+}
+
+// LoadMockFixtures reads a fixtures file.
+func LoadMockFixtures(path string) (*MockFixtures, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tool fixtures: %w", err)
+	}
+	var file struct {
+		Tools map[string]mockTool `yaml:"tools"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse tool fixtures %s: %w", path, err)
+	}
+	if len(file.Tools) == 0 {
+		return nil, fmt.Errorf("tool fixtures %s define no tools", path)
+	}
+	f := &MockFixtures{Path: path, tools: make(map[string][]mockRule, len(file.Tools))}
+	for name, rules := range file.Tools {
+		for i := range rules {
+			match, err := normalizeMockValue(rules[i].Match)
+			if err != nil {
+				return nil, fmt.Errorf("tool fixtures %s: %s: %w", path, name, err)
+			}
+			rules[i].Match, _ = match.(map[string]any)
+		}
+		f.tools[strings.ToLower(strings.TrimSpace(name))] = rules
+	}
+	return f, nil
+}
+
+// Names returns the tools with fixtures, sorted.
+func (f *MockFixtures) Names() []string {
+	names := make([]string, 0, len(f.tools))
+	for name := range f.tools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Has reports whether name has fixtures.
+func (f *MockFixtures) Has(name string) bool {
+	_, ok := f.lookup(name)
+	return ok
+}
+
+// Resolve returns the canned result of a call to name with the JSON
+// arguments args. Calls without a matching fixture resolve to an error, so
+// nothing runs for real.
+func (f *MockFixtures) Resolve(name, args string) MockResult {
+	rules, ok := f.lookup(name)
+	if !ok {
+		return MockResult{Output: fmt.Sprintf("no mock fixture for tool %s in %s", name, f.Path), Error: true}
+	}
+	var parsed map[string]any
+	if strings.TrimSpace(args) != "" {
+		_ = json.Unmarshal([]byte(args), &parsed)
+	}
+	for _, rule := range rules {
+		if !mockArgsMatch(rule.Match, parsed) {
+			continue
+		}
+		if rule.Error != "" {
+			return MockResult{Output: rule.Error, Error: true}
+		}
+		return MockResult{Output: rule.Result}
+	}
+	return MockResult{Output: fmt.Sprintf("no mock fixture of tool %s matches its arguments", name), Error: true}
+}
+
+// lookup finds the rules of a tool by its full name, its name without the
+// agent command prefix, or the command name alone.
+func (f *MockFixtures) lookup(name string) ([]mockRule, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	candidates := []string{name, strings.TrimPrefix(name, agentCommandPrefix)}
+	if i := strings.LastIndex(name, "__"); i >= 0 {
+		candidates = append(candidates, name[i+2:])
+	}
+	for _, candidate := range candidates {
+		if rules, ok := f.tools[candidate]; ok {
+			return rules, true
+		}
+	}
+	return nil, false
+}
+
+func mockArgsMatch(match, args map[string]any) bool {
+	for key, want := range match {
+		got, ok := args[key]
+		if !ok || !reflect.DeepEqual(got, want) {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeMockValue converts YAML values to what decoding JSON arguments
+// yields, so numbers compare as float64.
+func normalizeMockValue(v any) (any, error) {
+	if v == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("invalid match: %w", err)
+	}
+	var out any
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, fmt.Errorf("invalid match: %w", err)
+	}
+	return out, nil
+}
+
+type mockContextKey struct{}
+
+// WithMockFixtures resolves the tool calls made with ctx, including those of
+// sub-agents, from f.
+func WithMockFixtures(ctx context.Context, f *MockFixtures) context.Context {
+	if f == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, mockContextKey{}, f)
+}
+
+// MockFixturesFromContext returns the fixtures tool calls resolve from, or
+// nil when tools run for real.
+func MockFixturesFromContext(ctx context.Context) *MockFixtures {
+	if ctx == nil {
+		return nil
+	}
+	f, _ := ctx.Value(mockContextKey{}).(*MockFixtures)
+	return f
+}
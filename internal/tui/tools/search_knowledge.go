@@ -0,0 +1,85 @@
+package tools
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+//go:embed search_knowledge.md
+var searchKnowledgeDescription []byte
+
+const SearchKnowledgeToolName = "search_knowledge"
+
+type SearchKnowledgeParams struct {
+	Query string `json:"query"`
+	Limit int    `json:"limit"`
+}
+
+type knowledgeResult struct {
+	Source  string  `json:"source"`
+	Title   string  `json:"title"`
+	Content string  `json:"content"`
+	Score   float64 `json:"score"`
+}
+
+func SearchKnowledgeSpec() Spec {
+	return Spec{
+		Name:        SearchKnowledgeToolName,
+		Description: strings.TrimSpace(string(searchKnowledgeDescription)),
+		Parameters: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"query": map[string]any{"type": "string", "description": "What to look for"},
+				"limit": map[string]any{
+					"type":        "integer",
+					"description": "Optional number of passages (default 5)",
+					"default":     5,
+				},
+			},
+			"required": []string{"query"},
+		},
+	}
+}
+
+// RunSearchKnowledge searches the knowledge base kept by the local daemon.
+func RunSearchKnowledge(ctx context.Context, arguments string) (string, string) {
+	var params SearchKnowledgeParams
+	_ = json.Unmarshal([]byte(arguments), &params)
+	if strings.TrimSpace(params.Query) == "" {
+		return "error: missing query", ""
+	}
+
+	respBytes, err := ipcRequestToDaemon(ctx, "local", map[string]any{
+		"type":            "kb_search",
+		"knowledge_query": params.Query,
+		"knowledge_limit": params.Limit,
+	})
+	if err != nil {
+		return fmt.Sprintf("error: %v", err), ""
+	}
+	var resp struct {
+		Success   bool              `json:"success"`
+		Error     string            `json:"error"`
+		ErrorCode string            `json:"error_code"`
+		Knowledge []knowledgeResult `json:"knowledge"`
+	}
+	if err := json.Unmarshal(respBytes, &resp); err != nil {
+		return fmt.Sprintf("error: decode response: %v", err), ""
+	}
+	if !resp.Success {
+		return fmt.Sprintf("error: %v", newDaemonError(resp.ErrorCode, resp.Error, "knowledge base search failed")), ""
+	}
+
+	metadata, _ := json.Marshal(map[string]any{"results": resp.Knowledge})
+	if len(resp.Knowledge) == 0 {
+		return "No matching passages found. Documents are added with 'op kb add'.", string(metadata)
+	}
+	blocks := make([]string, 0, len(resp.Knowledge))
+	for i, r := range resp.Knowledge {
+		blocks = append(blocks, fmt.Sprintf("[%d] %s (%s, relevance %.2f)\n%s", i+1, r.Title, r.Source, r.Score, r.Content))
+	}
+	return strings.Join(blocks, "\n\n"), string(metadata)
+}
@@ -0,0 +1,131 @@
+package tools
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"opperator/config"
+)
+
+//go:embed spawn_session_agent.md
+var spawnSessionAgentDescription []byte
+
+const (
+	SpawnSessionAgentToolName = "spawn_session_agent"
+	spawnSessionAgentDelay    = 1 * time.Millisecond
+)
+
+type SpawnSessionAgentParams struct {
+	Template string `json:"template"`
+	Purpose  string `json:"purpose,omitempty"`
+}
+
+type SpawnSessionAgentMetadata struct {
+	Name     string `json:"name"`
+	Template string `json:"template"`
+	Daemon   string `json:"daemon,omitempty"`
+	Session  string `json:"session"`
+	At       string `json:"at"`
+}
+
+func SpawnSessionAgentSpec() Spec {
+	return Spec{
+		Name:        SpawnSessionAgentToolName,
+		Description: strings.TrimSpace(string(spawnSessionAgentDescription)),
+		Parameters: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"template": map[string]any{"type": "string", "description": "Name of the installed agent to copy"},
+				"purpose":  map[string]any{"type": "string", "description": "What the temporary agent is for; shown as its description"},
+			},
+			"required": []string{"template"},
+		},
+	}
+}
+
+func RunSpawnSessionAgent(ctx context.Context, arguments string) (string, string) {
+	if err := sleepWithCancel(ctx, spawnSessionAgentDelay); err != nil {
+		return "canceled", ""
+	}
+
+	var params SpawnSessionAgentParams
+	_ = json.Unmarshal([]byte(arguments), &params)
+	template := strings.TrimSpace(params.Template)
+	if template == "" {
+		return "error: missing template", ""
+	}
+	sessionID := SessionIDFromContext(ctx)
+	if sessionID == "" {
+		return "error: session agents need a conversation", ""
+	}
+
+	daemonName, err := FindAgentDaemon(ctx, template)
+	if err != nil {
+		return fmt.Sprintf("error: %v", err), ""
+	}
+	respb, err := ipcRequestToDaemon(ctx, daemonName, struct {
+		Type        string `json:"type"`
+		AgentName   string `json:"agent_name"`
+		SessionID   string `json:"session_id"`
+		Description string `json:"description,omitempty"`
+	}{Type: "session_agent_spawn", AgentName: template, SessionID: sessionID, Description: strings.TrimSpace(params.Purpose)})
+	if err != nil {
+		return fmt.Sprintf("error: %v", err), ""
+	}
+	var resp struct {
+		Success       bool     `json:"success"`
+		Error         string   `json:"error"`
+		SessionAgents []string `json:"session_agents"`
+	}
+	if err := json.Unmarshal(respb, &resp); err != nil {
+		return fmt.Sprintf("error decoding response: %v", err), ""
+	}
+	if !resp.Success || len(resp.SessionAgents) == 0 {
+		if strings.TrimSpace(resp.Error) == "" {
+			resp.Error = "unknown error"
+		}
+		return "error: " + resp.Error, ""
+	}
+	name := resp.SessionAgents[0]
+
+	PublishFocusAgentEvent(name)
+
+	meta := SpawnSessionAgentMetadata{
+		Name:     name,
+		Template: template,
+		Daemon:   daemonName,
+		Session:  sessionID,
+		At:       time.Now().Format(time.RFC3339),
+	}
+	mb, _ := json.Marshal(meta)
+	return fmt.Sprintf("Started session agent %q from %q and focused on it. It is removed when this conversation is deleted.", name, template), string(mb)
+}
+
+// EndSessionAgents removes the agents spawned for a conversation from every
+// enabled daemon.
+func EndSessionAgents(ctx context.Context, sessionID string) error {
+	sess := strings.TrimSpace(sessionID)
+	if sess == "" {
+		return nil
+	}
+	payload := map[string]any{"type": "session_end", "session_id": sess}
+	registry, err := config.LoadDaemonRegistry()
+	if err != nil {
+		_, err := ipcRequestCtx(ctx, payload)
+		return err
+	}
+	var lastErr error
+	for _, daemon := range registry.Daemons {
+		if !daemon.Enabled {
+			continue
+		}
+		if _, err := ipcRequestToDaemon(ctx, daemon.Name, payload); err != nil {
+			lastErr = err
+		}
+	}
+	return lastErr
+}
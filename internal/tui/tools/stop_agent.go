@@ -67,8 +67,9 @@ func RunStopAgent(ctx context.Context, arguments string) (string, string) {
 		return fmt.Sprintf("error: %v", err), ""
 	}
 	var resp struct {
-		Success bool   `json:"success"`
-		Error   string `json:"error"`
+		Success    bool     `json:"success"`
+		Error      string   `json:"error"`
+		Dependents []string `json:"dependents"`
 	}
 	if err := json.Unmarshal(respb, &resp); err != nil {
 		return fmt.Sprintf("error decoding response: %v", err), ""
@@ -88,5 +89,9 @@ func RunStopAgent(ctx context.Context, arguments string) (string, string) {
 	if daemonName != "local" {
 		daemonSuffix = fmt.Sprintf(" on daemon %q", daemonName)
 	}
-	return fmt.Sprintf("Stopped agent %q%s", params.Name, daemonSuffix), string(mb)
+	warning := ""
+	if len(resp.Dependents) > 0 {
+		warning = fmt.Sprintf(". Warning: still running agents depend on it: %s", strings.Join(resp.Dependents, ", "))
+	}
+	return fmt.Sprintf("Stopped agent %q%s%s", params.Name, daemonSuffix, warning), string(mb)
 }
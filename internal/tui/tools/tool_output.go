@@ -0,0 +1,230 @@
+package tools
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+//go:embed read_more.md
+var readMoreDescription []byte
+
+const ReadMoreToolName = "read_more"
+
+// DefaultToolOutputTokens is how many tokens of a tool output the model sees
+// when conversation.yaml sets no tool_output_tokens.
+const DefaultToolOutputTokens = 8000
+
+// DefaultRecentToolOutputs is how many of the latest tool outputs reach the
+// model whole when conversation.yaml sets no recent_tool_outputs; older ones
+// are compacted to a summary.
+const DefaultRecentToolOutputs = 6
+
+// compactedPreviewChars is how much of a compacted output's first line its
+// summary shows.
+const compactedPreviewChars = 120
+
+// truncationSlack lets outputs slightly over the budget through whole, so a
+// page from read_more with its note is not cut again.
+const truncationSlack = 256
+
+// maxKeptOutputs bounds how many truncated outputs read_more can page
+// through. Conversations are rebuilt from their stored tool results every
+// round, which keeps the ones still in use here.
+const maxKeptOutputs = 256
+
+type ReadMoreParams struct {
+	ToolCallID string `json:"tool_call_id"`
+	Offset     int    `json:"offset"`
+}
+
+type keptOutput struct {
+	content []rune
+	limit   int
+}
+
+var keptOutputs = struct {
+	sync.Mutex
+	byCall map[string]keptOutput
+	order  []string
+}{byCall: make(map[string]keptOutput)}
+
+func ReadMoreSpec() Spec {
+	return Spec{
+		Name:        ReadMoreToolName,
+		Description: strings.TrimSpace(string(readMoreDescription)),
+		Parameters: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"tool_call_id": map[string]any{"type": "string", "description": "ID of the tool call whose output was truncated"},
+				"offset":       map[string]any{"type": "integer", "description": "Character offset to continue from, as given in the truncation note"},
+			},
+			"required": []string{"tool_call_id", "offset"},
+		},
+	}
+}
+
+// TruncateToolOutputs caps the content of every tool_call_output entry of
+// conversation at budgetTokens, or DefaultToolOutputTokens when it is not
+// positive, keeping the full content for read_more. Metadata over the budget
+// is left out, since it only serves rendering.
+func TruncateToolOutputs(conversation []map[string]any, budgetTokens int) []map[string]any {
+	for _, entry := range conversation {
+		if entry["role"] != "tool_call_output" {
+			continue
+		}
+		callID, _ := entry["tool_id"].(string)
+		if content, ok := entry["content"].(string); ok {
+			entry["content"] = TruncateToolOutput(callID, content, budgetTokens)
+		}
+		if metadata, ok := entry["metadata"].(string); ok && EstimateTokens(metadata) > outputBudget(budgetTokens) {
+			delete(entry, "metadata")
+		}
+	}
+	return conversation
+}
+
+// CompactToolOutputs replaces the content of all but the keepRecent latest
+// tool_call_output entries of conversation, DefaultRecentToolOutputs when it
+// is not positive, with a one-line summary. An older output identical to a
+// later one points to it instead. Compacted outputs stay available through
+// read_more. Entries are copied before they are changed, so conversation
+// itself is left as it was.
+func CompactToolOutputs(conversation []map[string]any, keepRecent, budgetTokens int) []map[string]any {
+	if keepRecent <= 0 {
+		keepRecent = DefaultRecentToolOutputs
+	}
+	toolNames := make(map[string]string)
+	for _, entry := range conversation {
+		calls, _ := entry["tool_calls"].([]map[string]any)
+		for _, call := range calls {
+			id, _ := call["id"].(string)
+			fn, _ := call["function"].(map[string]any)
+			name, _ := fn["name"].(string)
+			toolNames[id] = name
+		}
+	}
+
+	out := make([]map[string]any, len(conversation))
+	copy(out, conversation)
+	seen := 0
+	laterByContent := make(map[string]string)
+	for i := len(out) - 1; i >= 0; i-- {
+		entry := out[i]
+		if entry["role"] != "tool_call_output" {
+			continue
+		}
+		callID, _ := entry["tool_id"].(string)
+		content, _ := entry["content"].(string)
+		seen++
+		if seen <= keepRecent || strings.TrimSpace(callID) == "" || len(content) <= compactedPreviewChars {
+			if content != "" {
+				laterByContent[content] = callID
+			}
+			continue
+		}
+
+		name := toolNames[callID]
+		if name == "" {
+			name, _ = entry["name"].(string)
+		}
+		compacted := make(map[string]any, len(entry))
+		for k, v := range entry {
+			compacted[k] = v
+		}
+		delete(compacted, "metadata")
+		if later, ok := laterByContent[content]; ok {
+			compacted["content"] = fmt.Sprintf("[Output identical to the output of tool call %q below.]", later)
+		} else {
+			keepOutput(callID, []rune(content), outputBudget(budgetTokens)*4)
+			compacted["content"] = compactionSummary(callID, name, content)
+			laterByContent[content] = callID
+		}
+		out[i] = compacted
+	}
+	return out
+}
+
+func compactionSummary(callID, name, content string) string {
+	preview := strings.TrimSpace(content)
+	if line, _, ok := strings.Cut(preview, "\n"); ok {
+		preview = strings.TrimSpace(line) + " …"
+	}
+	if runes := []rune(preview); len(runes) > compactedPreviewChars {
+		preview = string(runes[:compactedPreviewChars]) + "…"
+	}
+	tool := "tool"
+	if name != "" {
+		tool = name
+	}
+	return fmt.Sprintf("[Earlier %s output compacted (%d characters), starting: %s Call read_more with tool_call_id %q and offset 0 to read it again.]", tool, len([]rune(content)), preview, callID)
+}
+
+// TruncateToolOutput returns the first budgetTokens of content followed by a
+// note telling the model how to read the rest with read_more, or content
+// itself when it fits.
+func TruncateToolOutput(callID, content string, budgetTokens int) string {
+	limit := outputBudget(budgetTokens) * 4
+	runes := []rune(content)
+	callID = strings.TrimSpace(callID)
+	if len(runes) <= limit+truncationSlack || callID == "" {
+		return content
+	}
+	keepOutput(callID, runes, limit)
+	return string(runes[:limit]) + truncationNote(callID, 0, limit, len(runes))
+}
+
+// RunReadMore returns the part of a truncated tool output starting at the
+// requested offset.
+func RunReadMore(arguments string) (string, string) {
+	var params ReadMoreParams
+	if err := json.Unmarshal([]byte(arguments), &params); err != nil {
+		return fmt.Sprintf("error: invalid arguments: %v", err), ""
+	}
+	callID := strings.TrimSpace(params.ToolCallID)
+	if callID == "" {
+		return "error: tool_call_id is required", ""
+	}
+	keptOutputs.Lock()
+	kept, ok := keptOutputs.byCall[callID]
+	keptOutputs.Unlock()
+	if !ok {
+		return fmt.Sprintf("error: no truncated output for tool call %s", callID), ""
+	}
+	total := len(kept.content)
+	if params.Offset < 0 || params.Offset >= total {
+		return fmt.Sprintf("error: offset %d is outside the output (%d characters)", params.Offset, total), ""
+	}
+	end := min(params.Offset+kept.limit, total)
+	page := string(kept.content[params.Offset:end])
+	if end < total {
+		page += truncationNote(callID, params.Offset, end, total)
+	}
+	return page, ""
+}
+
+func outputBudget(budgetTokens int) int {
+	if budgetTokens <= 0 {
+		return DefaultToolOutputTokens
+	}
+	return budgetTokens
+}
+
+func truncationNote(callID string, start, end, total int) string {
+	return fmt.Sprintf("\n\n[Output truncated: showing characters %d-%d of %d. Call read_more with tool_call_id %q and offset %d to read more.]", start, end, total, callID, end)
+}
+
+func keepOutput(callID string, content []rune, limit int) {
+	keptOutputs.Lock()
+	defer keptOutputs.Unlock()
+	if _, ok := keptOutputs.byCall[callID]; !ok {
+		keptOutputs.order = append(keptOutputs.order, callID)
+	}
+	keptOutputs.byCall[callID] = keptOutput{content: content, limit: limit}
+	for len(keptOutputs.order) > maxKeptOutputs {
+		delete(keptOutputs.byCall, keptOutputs.order[0])
+		keptOutputs.order = keptOutputs.order[1:]
+	}
+}
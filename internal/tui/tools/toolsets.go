@@ -6,17 +6,18 @@ import (
 )
 
 func OpperatorSpecs() []Spec {
-	return []Spec{
+	return append([]Spec{
 		ListAgentsSpec(),
 		StartAgentSpec(),
 		StopAgentSpec(),
 		RestartAgentSpec(),
 		GetLogsSpec(),
-	}
+		SpawnSessionAgentSpec(),
+	}, SharedSpecs()...)
 }
 
 func BuilderSpecs() []Spec {
-	return []Spec{
+	return append([]Spec{
 		FocusAgentSpec(),
 		PlanSpec(),
 		BootstrapNewAgentSpec(),
@@ -39,7 +40,28 @@ func BuilderSpecs() []Spec {
 		DiagnosticsSpec(),
 		BashSpec(),
 		ReadDocumentationSpec(),
+	}, SharedSpecs()...)
+}
+
+// SharedSpecs are the tools every agent gets, core, managed or sub-agent:
+// long-term memory, the knowledge base and paging through long tool outputs.
+func SharedSpecs() []Spec {
+	return []Spec{
+		RememberSpec(),
+		RecallSpec(),
+		SearchKnowledgeSpec(),
+		ReadMoreSpec(),
+	}
+}
+
+// IsSharedToolName reports whether name is one of SharedSpecs, which run in
+// the caller rather than in a managed agent.
+func IsSharedToolName(name string) bool {
+	switch name {
+	case RememberToolName, RecallToolName, SearchKnowledgeToolName, ReadMoreToolName:
+		return true
 	}
+	return false
 }
 
 type AgentOption struct {
@@ -0,0 +1,100 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"opperator/config"
+	"opperator/version"
+)
+
+// DaemonVersionProblem describes a daemon whose version differs from the
+// TUI's. Incompatible ones cannot be used until one side is updated.
+type DaemonVersionProblem struct {
+	Daemon       string
+	Message      string
+	Incompatible bool
+}
+
+// CheckDaemonVersions runs the version handshake against every enabled
+// daemon and reports those whose version differs. Unreachable daemons are
+// skipped; other code reports them.
+func CheckDaemonVersions(ctx context.Context) []DaemonVersionProblem {
+	registry, err := config.LoadDaemonRegistry()
+	if err != nil {
+		return nil
+	}
+	var problems []DaemonVersionProblem
+	for _, daemon := range registry.Daemons {
+		if !daemon.Enabled {
+			continue
+		}
+		if problem, ok := checkDaemonVersion(ctx, daemon); ok {
+			problems = append(problems, problem)
+		}
+	}
+	return problems
+}
+
+func checkDaemonVersion(ctx context.Context, daemon config.DaemonConfig) (DaemonVersionProblem, bool) {
+	payload := map[string]any{
+		"type":            "hello",
+		"client_version":  version.Get(),
+		"client_protocol": version.Protocol,
+	}
+	data, err := ipcRequestToDaemon(ctx, daemon.Name, payload)
+	if err != nil {
+		return DaemonVersionProblem{}, false
+	}
+	var resp struct {
+		Success   bool   `json:"success"`
+		Error     string `json:"error"`
+		ErrorCode string `json:"error_code"`
+		Daemon    *struct {
+			Version     string `json:"version"`
+			Protocol    int    `json:"protocol"`
+			MinProtocol int    `json:"min_protocol"`
+		} `json:"daemon"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return DaemonVersionProblem{}, false
+	}
+	var daemonVersion string
+	var protocol, minProtocol int
+	switch {
+	case resp.Success && resp.Daemon != nil:
+		daemonVersion, protocol, minProtocol = resp.Daemon.Version, resp.Daemon.Protocol, resp.Daemon.MinProtocol
+	case resp.ErrorCode == DaemonErrValidation || resp.ErrorCode == DaemonErrForbidden,
+		resp.ErrorCode == "" && strings.HasPrefix(resp.Error, "unknown request type"):
+		// Daemons predating the handshake reject the request type, without
+		// an error code when they also predate those
+	default:
+		return DaemonVersionProblem{}, false
+	}
+
+	shown := daemonVersion
+	if shown == "" {
+		shown = "an older version"
+	}
+	upgrade := fmt.Sprintf("run `op cloud update %s`", daemon.Name)
+	if !strings.HasPrefix(daemon.Address, "tcp://") {
+		upgrade = "restart it with `op daemon stop && op daemon start`"
+	}
+
+	problem := DaemonVersionProblem{Daemon: daemon.Name}
+	switch version.Compare(daemonVersion, protocol, minProtocol) {
+	case version.Compatible:
+		return DaemonVersionProblem{}, false
+	case version.Skewed:
+		problem.Message = fmt.Sprintf("Daemon '%s' runs %s but Opperator is %s; %s", daemon.Name, shown, version.Get(), upgrade)
+	case version.PeerTooOld:
+		problem.Incompatible = true
+		problem.Message = fmt.Sprintf("Daemon '%s' runs %s, which Opperator %s no longer supports; %s", daemon.Name, shown, version.Get(), upgrade)
+	case version.PeerTooNew:
+		problem.Incompatible = true
+		problem.Message = fmt.Sprintf("Daemon '%s' runs %s, which no longer supports Opperator %s; run `op version update`", daemon.Name, shown, version.Get())
+	}
+	return problem, true
+}
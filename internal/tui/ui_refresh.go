@@ -13,10 +13,12 @@ import (
 	cmpconversations "tui/components/conversations"
 	cmpsidebar "tui/components/sidebar"
 	"tui/coreagent"
+	"tui/internal/conversation"
 	llm "tui/llm"
 	"tui/styles"
 	tooling "tui/tools"
 	tooltypes "tui/tools/types"
+	"tui/util"
 )
 
 // currentKeys builds the dynamic key map based on current UI state
@@ -40,7 +42,7 @@ func (m *Model) currentKeys() dynamicKeyMap {
 			key.WithKeys("esc"),
 			key.WithHelp("esc", "close tool detail"),
 		)
-		return dynamicKeyMap{km: km, inputFocused: false, cancelVisible: true}
+		return dynamicKeyMap{km: km, inputFocused: false, cancelVisible: true, toolDetail: true}
 	}
 	busy := m.isSessionBusy(m.sessionID)
 	if busy {
@@ -153,7 +155,7 @@ func (m *Model) refreshSidebar() tea.Cmd {
 			// so we pass nil for commands
 			_, _ = m.sidebar.SetAgentInfo(coreName, description, coreColor, nil)
 
-			m.sidebar.SetCustomSections(nil)
+			m.sidebar.SetCustomSections("", nil)
 
 			// Don't fetch here - it's handled by Init() and status change events
 			// This prevents redundant fetches on every sidebar refresh
@@ -176,11 +178,11 @@ func (m *Model) refreshSidebar() tea.Cmd {
 			// Similarly, don't clear custom sections if there's a focused agent
 			// Custom sections for focused agents are fetched via handleFocusedAgentMetadata
 			if focusedAgent := m.sidebar.FocusedAgentName(); focusedAgent == "" {
-				m.sidebar.SetCustomSections(nil)
+				m.sidebar.SetCustomSections("", nil)
 			}
 		} else {
 			_, _ = m.sidebar.SetAgentInfo("", "", "", nil)
-			m.sidebar.SetCustomSections(nil)
+			m.sidebar.SetCustomSections("", nil)
 			m.sidebar.SetAgentList(nil)
 			m.sidebar.SetFocusedAgent("")
 		}
@@ -267,6 +269,8 @@ func (m *Model) handleConvModalMsg(msg tea.Msg) (tea.Cmd, bool) {
 	switch v := msg.(type) {
 	case cmpconversations.SelectedMsg:
 		m.convModal = nil
+		// Picking an archived conversation brings it back
+		_ = m.convStore.SetArchived(context.Background(), v.ID, false)
 		cmd := m.setSession(v.ID)
 		m.refreshHeaderMeta()
 		return tea.Batch(m.input.Focus(), cmd), true
@@ -299,9 +303,11 @@ func (m *Model) handleConvModalMsg(msg tea.Msg) (tea.Cmd, bool) {
 			_ = m.msgStore.DeleteBySession(context.Background(), v.ID)
 		}
 		_ = tooling.DeleteAsyncTasksBySession(context.Background(), v.ID)
+		_ = tooling.EndSessionAgents(context.Background(), v.ID)
 		_ = m.convStore.Delete(context.Background(), v.ID)
 		m.convModal = nil
 		convs, _ := m.convStore.List(context.Background())
+		convs = conversation.Unarchived(conversation.InWorkspace(convs, m.convStore.Workspace()))
 		if len(convs) > 0 {
 			cmd := m.setSession(convs[0].ID)
 			m.refreshHeaderMeta()
@@ -312,6 +318,33 @@ func (m *Model) handleConvModalMsg(msg tea.Msg) (tea.Cmd, bool) {
 			m.refreshHeaderMeta()
 			return tea.Batch(m.input.Focus(), cmd), true
 		}
+	case cmpconversations.PinMsg:
+		_ = m.convStore.SetPinned(context.Background(), v.ID, v.Pinned)
+		m.refreshConversationModalList()
+		return nil, true
+	case cmpconversations.ArchiveMsg:
+		ctx := context.Background()
+		_ = m.convStore.SetArchived(ctx, v.ID, v.Archived)
+		if v.Archived {
+			_ = tooling.EndSessionAgents(ctx, v.ID)
+		}
+		if !v.Archived || v.ID != m.sessionID {
+			m.refreshConversationModalList()
+			return nil, true
+		}
+		// The open conversation was archived: move to the newest other one
+		m.convModal = nil
+		convs, _ := m.convStore.List(ctx)
+		convs = conversation.Unarchived(conversation.InWorkspace(convs, m.convStore.Workspace()))
+		nextID := ""
+		if len(convs) > 0 {
+			nextID = convs[0].ID
+		} else if c, err := m.convStore.Create(ctx, ""); err == nil {
+			nextID = c.ID
+		}
+		cmd := m.setSession(nextID)
+		m.refreshHeaderMeta()
+		return tea.Batch(m.input.Focus(), cmd), true
 	case cmpconversations.CloseMsg:
 		m.convModal = nil
 		return m.input.Focus(), true
@@ -486,6 +519,30 @@ func (m *Model) shouldSkipMessagesUpdate(msg tea.Msg) bool {
 }
 
 // initialStatsCmd fetches initial agent statistics from all enabled daemons
+// daemonVersionCheckCmd warns when a daemon runs a different version than
+// the TUI, and reports an error when the two cannot work together.
+func (m *Model) daemonVersionCheckCmd() tea.Cmd {
+	return func() tea.Msg {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		problems := tooling.CheckDaemonVersions(ctx)
+		if len(problems) == 0 {
+			return nil
+		}
+		msg := util.InfoMsg{Type: util.InfoTypeWarn, TTL: 30 * time.Second}
+		parts := make([]string, 0, len(problems))
+		for _, p := range problems {
+			if p.Incompatible {
+				msg.Type = util.InfoTypeError
+			}
+			parts = append(parts, p.Message)
+		}
+		msg.Msg = strings.Join(parts, " · ")
+		return msg
+	}
+}
+
 func (m *Model) initialStatsCmd() tea.Cmd {
 	return func() tea.Msg {
 		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
@@ -508,7 +565,7 @@ func (m *Model) initialStatsCmd() tea.Cmd {
 			switch strings.ToLower(agent.Status) {
 			case "running":
 				running++
-			case "crashed":
+			case "crashed", "crashlooping":
 				crashed++
 			default:
 				stopped++
@@ -535,7 +592,7 @@ func (m *Model) updateAgentStatusAndRefreshStats(agentName, daemonName, status s
 		switch strings.ToLower(st) {
 		case "running":
 			running++
-		case "crashed":
+		case "crashed", "crashlooping":
 			crashed++
 		default: // stopped or any other status
 			stopped++
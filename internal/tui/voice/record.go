@@ -0,0 +1,137 @@
+// Package voice records speech from the microphone and turns it into text,
+// either through Opper or a local whisper.cpp server, so agents can be
+// driven hands-free from the input box.
+package voice
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// ErrNoRecorder is returned when no supported audio recorder is installed.
+var ErrNoRecorder = errors.New("no audio recorder found; install sox (rec), alsa-utils (arecord) or ffmpeg")
+
+// sampleRate is what whisper models are trained on; recording at it avoids
+// resampling on the server.
+const sampleRate = "16000"
+
+// Recording is a microphone capture in progress.
+type Recording struct {
+	cmd  *exec.Cmd
+	path string
+	done chan error
+}
+
+// Start begins recording 16 kHz mono WAV to a temporary file.
+func Start() (*Recording, error) {
+	f, err := os.CreateTemp("", "opperator-voice-*.wav")
+	if err != nil {
+		return nil, err
+	}
+	path := f.Name()
+	f.Close()
+
+	args, err := recorderCommand(path)
+	if err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		os.Remove(path)
+		return nil, fmt.Errorf("start %s: %w", filepath.Base(args[0]), err)
+	}
+
+	r := &Recording{cmd: cmd, path: path, done: make(chan error, 1)}
+	go func() {
+		err := cmd.Wait()
+		if err != nil && stderr.Len() > 0 {
+			err = fmt.Errorf("%s: %s", filepath.Base(args[0]), strings.TrimSpace(stderr.String()))
+		}
+		r.done <- err
+	}()
+
+	// A recorder that cannot open the microphone exits right away.
+	select {
+	case err := <-r.done:
+		os.Remove(path)
+		if err == nil {
+			err = fmt.Errorf("%s exited before recording", filepath.Base(args[0]))
+		}
+		return nil, err
+	case <-time.After(200 * time.Millisecond):
+	}
+	return r, nil
+}
+
+// Stop ends the recording and returns the path of the WAV file, which the
+// caller removes once done with it.
+func (r *Recording) Stop() (string, error) {
+	// Recorders finalise the WAV header on interrupt; a killed one leaves
+	// a file with a bogus length.
+	if err := r.cmd.Process.Signal(os.Interrupt); err != nil {
+		r.cmd.Process.Kill()
+	}
+	select {
+	case <-r.done:
+	case <-time.After(3 * time.Second):
+		r.cmd.Process.Kill()
+		<-r.done
+	}
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return "", err
+	}
+	// Anything up to the 44-byte header is silence at best.
+	if info.Size() <= 44 {
+		os.Remove(r.path)
+		return "", errors.New("no audio was recorded")
+	}
+	return r.path, nil
+}
+
+// Cancel ends the recording and discards it.
+func (r *Recording) Cancel() {
+	r.cmd.Process.Kill()
+	<-r.done
+	os.Remove(r.path)
+}
+
+// recorderCommand picks a recorder: OPPERATOR_VOICE_RECORDER when set, with
+// {file} replaced by the output path, otherwise sox, arecord or ffmpeg.
+func recorderCommand(path string) ([]string, error) {
+	if custom := strings.Fields(os.Getenv("OPPERATOR_VOICE_RECORDER")); len(custom) > 0 {
+		for i, arg := range custom {
+			custom[i] = strings.ReplaceAll(arg, "{file}", path)
+		}
+		return custom, nil
+	}
+	if bin, err := exec.LookPath("rec"); err == nil {
+		return []string{bin, "-q", "-c", "1", "-r", sampleRate, "-b", "16", path}, nil
+	}
+	if bin, err := exec.LookPath("arecord"); err == nil && runtime.GOOS == "linux" {
+		return []string{bin, "-q", "-f", "S16_LE", "-c", "1", "-r", sampleRate, path}, nil
+	}
+	if bin, err := exec.LookPath("ffmpeg"); err == nil {
+		var input []string
+		switch runtime.GOOS {
+		case "darwin":
+			input = []string{"-f", "avfoundation", "-i", ":0"}
+		case "windows":
+			input = []string{"-f", "dshow", "-i", "audio=default"}
+		default:
+			input = []string{"-f", "pulse", "-i", "default"}
+		}
+		args := append([]string{bin, "-loglevel", "error", "-y"}, input...)
+		return append(args, "-ac", "1", "-ar", sampleRate, path), nil
+	}
+	return nil, ErrNoRecorder
+}
@@ -0,0 +1,137 @@
+package voice
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"tui/internal/keyring"
+)
+
+// Transcription providers, selected with OPPERATOR_VOICE_PROVIDER.
+const (
+	ProviderOpper   = "opper"
+	ProviderWhisper = "whisper"
+)
+
+const (
+	defaultOpperURL   = "https://api.opper.ai/compat/openai/audio/transcriptions"
+	defaultOpperModel = "whisper-1"
+	// defaultWhisperURL is where whisper.cpp's example server listens.
+	defaultWhisperURL = "http://127.0.0.1:8080/inference"
+)
+
+var httpClient = &http.Client{Timeout: 2 * time.Minute}
+
+// Config selects the transcription endpoint.
+type Config struct {
+	Provider string
+	URL      string
+	Model    string
+	// Language is an ISO-639-1 hint; empty lets the model detect it.
+	Language string
+}
+
+// ConfigFromEnv reads OPPERATOR_VOICE_PROVIDER (opper or whisper),
+// OPPERATOR_VOICE_URL, OPPERATOR_VOICE_MODEL and OPPERATOR_VOICE_LANGUAGE.
+func ConfigFromEnv() Config {
+	cfg := Config{
+		Provider: strings.ToLower(strings.TrimSpace(os.Getenv("OPPERATOR_VOICE_PROVIDER"))),
+		URL:      strings.TrimSpace(os.Getenv("OPPERATOR_VOICE_URL")),
+		Model:    strings.TrimSpace(os.Getenv("OPPERATOR_VOICE_MODEL")),
+		Language: strings.TrimSpace(os.Getenv("OPPERATOR_VOICE_LANGUAGE")),
+	}
+	if cfg.Provider != ProviderWhisper {
+		cfg.Provider = ProviderOpper
+	}
+	if cfg.URL == "" {
+		cfg.URL = defaultOpperURL
+		if cfg.Provider == ProviderWhisper {
+			cfg.URL = defaultWhisperURL
+		}
+	}
+	if cfg.Model == "" && cfg.Provider == ProviderOpper {
+		cfg.Model = defaultOpperModel
+	}
+	return cfg
+}
+
+// Transcribe uploads the WAV file at path and returns the recognised text.
+// Opper takes an OpenAI-style transcription request authenticated with the
+// stored API key; whisper.cpp's server takes the same multipart upload
+// without authentication.
+func Transcribe(ctx context.Context, cfg Config, path string) (string, error) {
+	var apiKey string
+	if cfg.Provider == ProviderOpper {
+		key, err := keyring.GetAPIKey()
+		if err != nil {
+			return "", fmt.Errorf("voice input needs the Opper API key (op secret create %s) or OPPERATOR_VOICE_PROVIDER=whisper: %w", keyring.OpperAPIKeyName, err)
+		}
+		apiKey = key
+	}
+
+	audio, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer audio.Close()
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", filepath.Base(path))
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(part, audio); err != nil {
+		return "", err
+	}
+	fields := map[string]string{"response_format": "json", "model": cfg.Model, "language": cfg.Language}
+	for name, value := range fields {
+		if value != "" {
+			form.WriteField(name, value)
+		}
+	}
+	if err := form.Close(); err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, cfg.URL, &body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("transcription request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("transcription failed: %s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+	var result struct {
+		Text  string `json:"text"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return "", fmt.Errorf("invalid transcription response: %w", err)
+	}
+	if result.Error != "" {
+		return "", fmt.Errorf("transcription failed: %s", result.Error)
+	}
+	return strings.TrimSpace(result.Text), nil
+}
@@ -0,0 +1,238 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"opperator/internal/taskqueue"
+)
+
+// StepStatus is the state of one step during a run.
+type StepStatus string
+
+const (
+	StepPending  StepStatus = "pending"
+	StepRunning  StepStatus = "running"
+	StepComplete StepStatus = "complete"
+	StepFailed   StepStatus = "failed"
+	StepSkipped  StepStatus = "skipped"
+)
+
+// StepResult records how a step ended and what it produced.
+type StepResult struct {
+	Status StepStatus `json:"status"`
+	Output any        `json:"output,omitempty"`
+	Error  string     `json:"error,omitempty"`
+}
+
+// Result is the outcome of a workflow run.
+type Result struct {
+	Workflow string                 `json:"workflow"`
+	Steps    map[string]*StepResult `json:"steps"`
+}
+
+// ProgressMetadata is attached to every progress event of a run so clients
+// can render the pipeline as a single unit.
+type ProgressMetadata struct {
+	Workflow  string     `json:"workflow"`
+	Step      string     `json:"workflow_step"`
+	State     StepStatus `json:"state"`
+	Completed int        `json:"completed,omitempty"`
+	Total     int        `json:"total,omitempty"`
+	// StepMetadata carries metadata from the agent's own progress event.
+	StepMetadata string `json:"step_metadata,omitempty"`
+}
+
+type stepDone struct {
+	id     string
+	output any
+	err    error
+}
+
+// Run executes wf with the given inputs, invoking each step's agent command
+// through runner. Steps whose needs are met run concurrently. A failing step
+// stops the workflow unless it sets continue_on_error.
+func Run(ctx context.Context, wf *Workflow, inputs map[string]string, workingDir string, runner taskqueue.AgentRunner, progress func(taskqueue.ProgressEvent)) (*Result, error) {
+	if runner == nil {
+		return nil, fmt.Errorf("agent runner not configured")
+	}
+	resolved, err := wf.ResolveInputs(inputs)
+	if err != nil {
+		return nil, err
+	}
+	if progress == nil {
+		progress = func(taskqueue.ProgressEvent) {}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	result := &Result{Workflow: wf.Name, Steps: make(map[string]*StepResult, len(wf.Steps))}
+	for _, step := range wf.Steps {
+		result.Steps[step.ID] = &StepResult{Status: StepPending}
+	}
+	vars := &scope{inputs: resolved, steps: result.Steps}
+
+	finished := 0
+	emit := func(id string, state StepStatus, text string) {
+		meta, _ := json.Marshal(ProgressMetadata{
+			Workflow:  wf.Name,
+			Step:      id,
+			State:     state,
+			Completed: finished,
+			Total:     len(wf.Steps),
+		})
+		progress(taskqueue.ProgressEvent{Text: text, Metadata: string(meta), Status: string(state)})
+	}
+	skip := func(id, reason string) {
+		result.Steps[id].Status = StepSkipped
+		result.Steps[id].Error = reason
+		finished++
+		emit(id, StepSkipped, fmt.Sprintf("%s skipped: %s", id, reason))
+	}
+
+	done := make(chan stepDone)
+	running := 0
+	var failure error
+	for {
+		// Start or skip every pending step whose needs have settled. Skips
+		// can unblock further skips, so repeat until nothing changes.
+		for changed := true; changed; {
+			changed = false
+			for i := range wf.Steps {
+				step := &wf.Steps[i]
+				if result.Steps[step.ID].Status != StepPending {
+					continue
+				}
+				if failure != nil {
+					skip(step.ID, "workflow failed")
+					changed = true
+					continue
+				}
+				ready, blocker := needsState(step, result.Steps)
+				if blocker != "" {
+					skip(step.ID, blocker)
+					changed = true
+					continue
+				}
+				if !ready {
+					continue
+				}
+				changed = true
+				ok, err := vars.evaluate(step.If)
+				if err != nil {
+					failure = fmt.Errorf("step %s: condition: %w", step.ID, err)
+					skip(step.ID, err.Error())
+					continue
+				}
+				if !ok {
+					skip(step.ID, "condition is false")
+					continue
+				}
+				args, err := vars.expand(map[string]any(step.Args))
+				if err != nil {
+					failure = fmt.Errorf("step %s: %w", step.ID, err)
+					skip(step.ID, err.Error())
+					continue
+				}
+				encoded, err := json.Marshal(args)
+				if err != nil {
+					failure = fmt.Errorf("step %s: encode args: %w", step.ID, err)
+					skip(step.ID, err.Error())
+					continue
+				}
+				result.Steps[step.ID].Status = StepRunning
+				running++
+				emit(step.ID, StepRunning, fmt.Sprintf("%s started: %s.%s", step.ID, step.Agent, step.Command))
+				go runStep(ctx, wf.Name, *step, string(encoded), workingDir, runner, progress, done)
+			}
+		}
+		if running == 0 {
+			break
+		}
+
+		d := <-done
+		running--
+		finished++
+		step := result.Steps[d.id]
+		if d.err != nil {
+			step.Status = StepFailed
+			step.Error = strings.TrimSpace(d.err.Error())
+			emit(d.id, StepFailed, fmt.Sprintf("%s failed: %s", d.id, step.Error))
+			if failure == nil && !continueOnError(wf, d.id) {
+				failure = fmt.Errorf("step %s failed: %s", d.id, step.Error)
+				cancel()
+			}
+			continue
+		}
+		step.Status = StepComplete
+		step.Output = d.output
+		emit(d.id, StepComplete, fmt.Sprintf("%s complete", d.id))
+	}
+
+	if failure != nil {
+		return result, failure
+	}
+	return result, nil
+}
+
+func runStep(ctx context.Context, workflow string, step Step, args, workingDir string, runner taskqueue.AgentRunner, progress func(taskqueue.ProgressEvent), done chan<- stepDone) {
+	forward := func(ev taskqueue.ProgressEvent) {
+		meta, _ := json.Marshal(ProgressMetadata{
+			Workflow:     workflow,
+			Step:         step.ID,
+			State:        StepRunning,
+			StepMetadata: ev.Metadata,
+		})
+		text := ev.Text
+		if text != "" {
+			text = fmt.Sprintf("[%s] %s", step.ID, text)
+		}
+		progress(taskqueue.ProgressEvent{Text: text, Metadata: string(meta), Status: ev.Status})
+	}
+	content, metadata, err := runner.Execute(ctx, step.Agent, step.Command, args, workingDir, forward)
+	done <- stepDone{id: step.ID, output: decodeOutput(content, metadata), err: err}
+}
+
+// needsState reports whether every need of step completed. A non-empty
+// blocker means one of them failed or was skipped.
+func needsState(step *Step, results map[string]*StepResult) (ready bool, blocker string) {
+	ready = true
+	for _, need := range step.Needs {
+		switch results[need].Status {
+		case StepComplete:
+		case StepFailed, StepSkipped:
+			return false, fmt.Sprintf("needs %s which %s", need, results[need].Status)
+		default:
+			ready = false
+		}
+	}
+	return ready, ""
+}
+
+func continueOnError(wf *Workflow, id string) bool {
+	for _, step := range wf.Steps {
+		if step.ID == id {
+			return step.ContinueOnError
+		}
+	}
+	return false
+}
+
+// decodeOutput prefers the structured command result from the runner's
+// metadata and falls back to the content, parsed as JSON when possible.
+func decodeOutput(content, metadata string) any {
+	var meta map[string]any
+	if err := json.Unmarshal([]byte(metadata), &meta); err == nil {
+		if result, ok := meta["result"]; ok {
+			return result
+		}
+	}
+	var parsed any
+	if err := json.Unmarshal([]byte(content), &parsed); err == nil {
+		return parsed
+	}
+	return content
+}
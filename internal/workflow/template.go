@@ -0,0 +1,241 @@
+package workflow
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// expressionPattern matches {{ ... }} placeholders in step arguments and
+// conditions.
+var expressionPattern = regexp.MustCompile(`\{\{\s*([^{}]+?)\s*\}\}`)
+
+type refKind int
+
+const (
+	refInput refKind = iota
+	refStep
+)
+
+type reference struct {
+	kind refKind
+	name string
+	// field is "output" or "status" for step references.
+	field string
+	path  []string
+}
+
+// scope holds the values templates can reference while a workflow runs.
+type scope struct {
+	inputs map[string]string
+	steps  map[string]*StepResult
+}
+
+// parseReference understands inputs.NAME, steps.ID.status and
+// steps.ID.output[.key|.index...].
+func parseReference(expr string) (reference, error) {
+	parts := strings.Split(strings.TrimSpace(expr), ".")
+	switch parts[0] {
+	case "inputs":
+		if len(parts) != 2 || parts[1] == "" {
+			return reference{}, fmt.Errorf("invalid expression %q (expected inputs.NAME)", expr)
+		}
+		return reference{kind: refInput, name: parts[1]}, nil
+	case "steps":
+		if len(parts) < 3 || parts[1] == "" {
+			return reference{}, fmt.Errorf("invalid expression %q (expected steps.ID.output or steps.ID.status)", expr)
+		}
+		switch parts[2] {
+		case "output":
+			return reference{kind: refStep, name: parts[1], field: "output", path: parts[3:]}, nil
+		case "status":
+			if len(parts) != 3 {
+				return reference{}, fmt.Errorf("invalid expression %q", expr)
+			}
+			return reference{kind: refStep, name: parts[1], field: "status"}, nil
+		}
+	}
+	return reference{}, fmt.Errorf("invalid expression %q (expected inputs.* or steps.*)", expr)
+}
+
+// references lists every template reference used by a step.
+func references(step *Step) ([]reference, error) {
+	var refs []reference
+	var walk func(v any) error
+	walk = func(v any) error {
+		switch val := v.(type) {
+		case string:
+			for _, m := range expressionPattern.FindAllStringSubmatch(val, -1) {
+				ref, err := parseReference(m[1])
+				if err != nil {
+					return err
+				}
+				refs = append(refs, ref)
+			}
+		case map[string]any:
+			for _, item := range val {
+				if err := walk(item); err != nil {
+					return err
+				}
+			}
+		case []any:
+			for _, item := range val {
+				if err := walk(item); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if err := walk(step.If); err != nil {
+		return nil, err
+	}
+	if err := walk(map[string]any(step.Args)); err != nil {
+		return nil, err
+	}
+	return refs, nil
+}
+
+func (s *scope) lookup(expr string) (any, error) {
+	ref, err := parseReference(expr)
+	if err != nil {
+		return nil, err
+	}
+	if ref.kind == refInput {
+		return s.inputs[ref.name], nil
+	}
+	result, ok := s.steps[ref.name]
+	if !ok {
+		return nil, fmt.Errorf("step %q has not run", ref.name)
+	}
+	if ref.field == "status" {
+		return string(result.Status), nil
+	}
+	value := result.Output
+	for _, key := range ref.path {
+		switch container := value.(type) {
+		case map[string]any:
+			value = container[key]
+		case []any:
+			idx, err := strconv.Atoi(key)
+			if err != nil || idx < 0 || idx >= len(container) {
+				return nil, fmt.Errorf("%s: index %q out of range", expr, key)
+			}
+			value = container[idx]
+		default:
+			return nil, nil
+		}
+	}
+	return value, nil
+}
+
+// expand substitutes references in v. A string consisting of a single
+// expression takes the referenced value as is, so objects and numbers keep
+// their type; otherwise values are interpolated as text.
+func (s *scope) expand(v any) (any, error) {
+	switch val := v.(type) {
+	case string:
+		if m := expressionPattern.FindStringSubmatch(val); m != nil && m[0] == strings.TrimSpace(val) {
+			return s.lookup(m[1])
+		}
+		return s.interpolate(val)
+	case map[string]any:
+		out := make(map[string]any, len(val))
+		for key, item := range val {
+			expanded, err := s.expand(item)
+			if err != nil {
+				return nil, err
+			}
+			out[key] = expanded
+		}
+		return out, nil
+	case []any:
+		out := make([]any, len(val))
+		for i, item := range val {
+			expanded, err := s.expand(item)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = expanded
+		}
+		return out, nil
+	default:
+		return v, nil
+	}
+}
+
+func (s *scope) interpolate(text string) (string, error) {
+	var firstErr error
+	out := expressionPattern.ReplaceAllStringFunc(text, func(match string) string {
+		expr := expressionPattern.FindStringSubmatch(match)[1]
+		value, err := s.lookup(expr)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			return ""
+		}
+		return stringify(value)
+	})
+	return out, firstErr
+}
+
+// evaluate reports whether a step condition holds. Conditions are either a
+// single value, true unless empty, "false", "0" or "null", or a comparison
+// "a == b" / "a != b" of interpolated text.
+func (s *scope) evaluate(condition string) (bool, error) {
+	condition = strings.TrimSpace(condition)
+	if condition == "" {
+		return true, nil
+	}
+	for _, op := range []string{"==", "!="} {
+		left, right, found := strings.Cut(condition, op)
+		if !found {
+			continue
+		}
+		l, err := s.interpolate(strings.TrimSpace(left))
+		if err != nil {
+			return false, err
+		}
+		r, err := s.interpolate(strings.TrimSpace(right))
+		if err != nil {
+			return false, err
+		}
+		equal := unquote(l) == unquote(r)
+		return equal == (op == "=="), nil
+	}
+	negate := strings.HasPrefix(condition, "!")
+	value, err := s.interpolate(strings.TrimPrefix(condition, "!"))
+	if err != nil {
+		return false, err
+	}
+	switch strings.ToLower(unquote(strings.TrimSpace(value))) {
+	case "", "false", "0", "null":
+		return negate, nil
+	}
+	return !negate, nil
+}
+
+func stringify(value any) string {
+	switch val := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	default:
+		b, err := json.Marshal(val)
+		if err != nil {
+			return fmt.Sprint(val)
+		}
+		return string(b)
+	}
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
@@ -0,0 +1,276 @@
+// Package workflow runs declarative multi-step agent pipelines. A workflow is
+// a YAML file in the workflows config directory whose steps invoke agent
+// commands, feed earlier outputs into later arguments, and run in parallel
+// wherever their dependencies allow.
+package workflow
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"opperator/config"
+)
+
+// ErrNotFound indicates no workflow definition has the requested name.
+var ErrNotFound = errors.New("workflow not found")
+
+// Workflow is a parsed workflow definition.
+type Workflow struct {
+	Name        string  `yaml:"name"`
+	Description string  `yaml:"description,omitempty"`
+	Inputs      []Input `yaml:"inputs,omitempty"`
+	Steps       []Step  `yaml:"steps"`
+
+	// Path is the file the definition was loaded from.
+	Path string `yaml:"-"`
+}
+
+// Input declares a value supplied when the workflow is run.
+type Input struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description,omitempty"`
+	Required    bool   `yaml:"required,omitempty"`
+	Default     string `yaml:"default,omitempty"`
+}
+
+// Step invokes one agent command.
+type Step struct {
+	ID      string         `yaml:"id"`
+	Agent   string         `yaml:"agent"`
+	Command string         `yaml:"command"`
+	Args    map[string]any `yaml:"args,omitempty"`
+	// Needs lists steps that must finish first. Steps without a path between
+	// them run in parallel.
+	Needs []string `yaml:"needs,omitempty"`
+	// If is a condition evaluated once Needs have finished. The step and
+	// everything depending on it are skipped when it is false.
+	If string `yaml:"if,omitempty"`
+	// ContinueOnError lets the workflow carry on when the step fails; its
+	// dependents are skipped.
+	ContinueOnError bool `yaml:"continue_on_error,omitempty"`
+}
+
+// Load reads the workflow called name from the workflows directory.
+func Load(name string) (*Workflow, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("workflow name is required")
+	}
+	dir, err := config.GetWorkflowsDir()
+	if err != nil {
+		return nil, err
+	}
+	for _, ext := range []string{".yaml", ".yml"} {
+		path := filepath.Join(dir, name+ext)
+		if _, err := os.Stat(path); err == nil {
+			return LoadFile(path)
+		}
+	}
+	// Fall back to the name declared inside the file.
+	all, err := LoadAll()
+	if err != nil {
+		return nil, err
+	}
+	for _, wf := range all {
+		if wf.Name == name {
+			return wf, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrNotFound, name)
+}
+
+// LoadAll reads every workflow in the workflows directory, sorted by name.
+// Invalid files are logged and skipped so one broken definition does not hide
+// the rest.
+func LoadAll() ([]*Workflow, error) {
+	dir, err := config.GetWorkflowsDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read workflows directory: %w", err)
+	}
+	var workflows []*Workflow
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+		wf, err := LoadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			log.Printf("[Workflow] Skipping %s: %v", entry.Name(), err)
+			continue
+		}
+		workflows = append(workflows, wf)
+	}
+	sort.Slice(workflows, func(i, j int) bool { return workflows[i].Name < workflows[j].Name })
+	return workflows, nil
+}
+
+// LoadFile parses and validates a single workflow file. The name defaults to
+// the file name without its extension.
+func LoadFile(path string) (*Workflow, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read workflow: %w", err)
+	}
+	var wf Workflow
+	if err := yaml.Unmarshal(data, &wf); err != nil {
+		return nil, fmt.Errorf("failed to parse workflow %s: %w", filepath.Base(path), err)
+	}
+	if strings.TrimSpace(wf.Name) == "" {
+		wf.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	wf.Path = path
+	if err := wf.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid workflow %s: %w", wf.Name, err)
+	}
+	return &wf, nil
+}
+
+// Validate checks step IDs, dependencies and template references.
+func (wf *Workflow) Validate() error {
+	if len(wf.Steps) == 0 {
+		return fmt.Errorf("at least one step is required")
+	}
+	inputs := make(map[string]bool, len(wf.Inputs))
+	for _, in := range wf.Inputs {
+		if strings.TrimSpace(in.Name) == "" {
+			return fmt.Errorf("input name is required")
+		}
+		if inputs[in.Name] {
+			return fmt.Errorf("duplicate input %q", in.Name)
+		}
+		inputs[in.Name] = true
+	}
+
+	steps := make(map[string]*Step, len(wf.Steps))
+	for i := range wf.Steps {
+		step := &wf.Steps[i]
+		step.ID = strings.TrimSpace(step.ID)
+		if step.ID == "" {
+			return fmt.Errorf("step %d: id is required", i+1)
+		}
+		if strings.ContainsAny(step.ID, ". ") {
+			return fmt.Errorf("step %q: id must not contain dots or spaces", step.ID)
+		}
+		if _, dup := steps[step.ID]; dup {
+			return fmt.Errorf("duplicate step id %q", step.ID)
+		}
+		if strings.TrimSpace(step.Agent) == "" || strings.TrimSpace(step.Command) == "" {
+			return fmt.Errorf("step %q: agent and command are required", step.ID)
+		}
+		steps[step.ID] = step
+	}
+	for _, step := range steps {
+		for _, need := range step.Needs {
+			if _, ok := steps[need]; !ok {
+				return fmt.Errorf("step %q needs unknown step %q", step.ID, need)
+			}
+		}
+	}
+	if cycle := findCycle(wf.Steps, steps); cycle != "" {
+		return fmt.Errorf("dependency cycle through step %q", cycle)
+	}
+
+	for _, step := range steps {
+		ancestors := ancestorsOf(step.ID, steps)
+		refs, err := references(step)
+		if err != nil {
+			return fmt.Errorf("step %q: %w", step.ID, err)
+		}
+		for _, ref := range refs {
+			switch ref.kind {
+			case refInput:
+				if !inputs[ref.name] {
+					return fmt.Errorf("step %q references undeclared input %q", step.ID, ref.name)
+				}
+			case refStep:
+				if !ancestors[ref.name] {
+					return fmt.Errorf("step %q references step %q without needing it", step.ID, ref.name)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// ResolveInputs applies defaults and rejects missing or unknown inputs.
+func (wf *Workflow) ResolveInputs(given map[string]string) (map[string]string, error) {
+	resolved := make(map[string]string, len(wf.Inputs))
+	for key := range given {
+		if !slices.ContainsFunc(wf.Inputs, func(in Input) bool { return in.Name == key }) {
+			return nil, fmt.Errorf("unknown input %q", key)
+		}
+	}
+	for _, in := range wf.Inputs {
+		value, ok := given[in.Name]
+		if !ok {
+			value = in.Default
+		}
+		if in.Required && value == "" {
+			return nil, fmt.Errorf("input %q is required", in.Name)
+		}
+		resolved[in.Name] = value
+	}
+	return resolved, nil
+}
+
+func findCycle(order []Step, steps map[string]*Step) string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(steps))
+	var visit func(id string) string
+	visit = func(id string) string {
+		switch state[id] {
+		case visiting:
+			return id
+		case visited:
+			return ""
+		}
+		state[id] = visiting
+		for _, need := range steps[id].Needs {
+			if cycle := visit(need); cycle != "" {
+				return cycle
+			}
+		}
+		state[id] = visited
+		return ""
+	}
+	for _, step := range order {
+		if cycle := visit(step.ID); cycle != "" {
+			return cycle
+		}
+	}
+	return ""
+}
+
+func ancestorsOf(id string, steps map[string]*Step) map[string]bool {
+	seen := make(map[string]bool)
+	stack := append([]string(nil), steps[id].Needs...)
+	for len(stack) > 0 {
+		next := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if seen[next] {
+			continue
+		}
+		seen[next] = true
+		stack = append(stack, steps[next].Needs...)
+	}
+	return seen
+}
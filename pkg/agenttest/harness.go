@@ -0,0 +1,461 @@
+// Package agenttest runs one agent under an isolated daemon, with its own
+// temporary config directory, socket and database, so agent repositories
+// can test their commands against the real protocol in CI:
+//
+//	func TestForecast(t *testing.T) {
+//		h := agenttest.New(t, agenttest.Agent{Name: "weather", Command: "python3", Args: []string{"main.py"}}, agenttest.Options{})
+//		res := h.MustInvoke(t, "get_forecast", map[string]any{"city": "Paris"})
+//		if !strings.Contains(res.Text(), "Paris") {
+//			t.Fatalf("unexpected forecast: %s", res.Text())
+//		}
+//	}
+//
+// The daemon is the op binary on PATH unless Options.Binary says otherwise.
+package agenttest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"opperator/config"
+	"opperator/internal/agent"
+	"opperator/internal/ipc"
+	"opperator/internal/protocol"
+)
+
+const (
+	defaultStartTimeout  = 60 * time.Second
+	defaultInvokeTimeout = 5 * time.Minute
+	pollInterval         = 100 * time.Millisecond
+	// commandSettle is how long a ready agent is given to register its
+	// commands before it is taken to have none.
+	commandSettle = 2 * time.Second
+)
+
+// Agent is the agent under test, as it would appear in agents.yaml.
+type Agent struct {
+	Name    string
+	Command string
+	Args    []string
+	// ProcessRoot is the directory the agent runs in; the current
+	// directory when empty.
+	ProcessRoot string
+	Env         map[string]string
+	// Runtime is "auto" (default) to provision dependencies from
+	// pyproject.toml, requirements.txt or package.json, or "none".
+	Runtime string
+}
+
+// Options tunes a harness.
+type Options struct {
+	// Binary is the op executable that runs the daemon; "op" on PATH when
+	// empty.
+	Binary string
+	// StartTimeout bounds starting the daemon and the agent; one minute
+	// when zero.
+	StartTimeout time.Duration
+	// InvokeTimeout bounds each command; five minutes when zero.
+	InvokeTimeout time.Duration
+}
+
+// Harness is a running isolated daemon with one agent loaded.
+type Harness struct {
+	agent  string
+	dir    string
+	socket string
+	opts   Options
+
+	cmd    *exec.Cmd
+	exited chan struct{}
+	output bytes.Buffer
+
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// Result is the outcome of a command invocation.
+type Result struct {
+	Success bool
+	Error   string
+	// Output is the command's result as decoded from JSON.
+	Output   any
+	Progress []string
+	Duration time.Duration
+}
+
+// Text returns the output as a string: strings as they are, anything else
+// as JSON.
+func (r Result) Text() string {
+	switch v := r.Output.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	}
+	data, err := json.Marshal(r.Output)
+	if err != nil {
+		return fmt.Sprint(r.Output)
+	}
+	return string(data)
+}
+
+// Decode unmarshals the output into v.
+func (r Result) Decode(v any) error {
+	data, err := json.Marshal(r.Output)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// New starts a harness for a test, failing it when the agent doesn't come
+// up, and closes the harness when the test ends.
+func New(t testing.TB, a Agent, opts Options) *Harness {
+	t.Helper()
+	timeout := opts.StartTimeout
+	if timeout <= 0 {
+		timeout = defaultStartTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	h, err := Start(ctx, a, opts)
+	if err != nil {
+		t.Fatalf("agenttest: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := h.Close(); err != nil {
+			t.Logf("agenttest: %v", err)
+		}
+	})
+	return h
+}
+
+// Start runs an isolated daemon, loads a and waits until the agent is
+// running and has registered its commands. Close the harness when done.
+func Start(ctx context.Context, a Agent, opts Options) (*Harness, error) {
+	if strings.TrimSpace(a.Name) == "" || strings.TrimSpace(a.Command) == "" {
+		return nil, errors.New("agent name and command are required")
+	}
+	if opts.StartTimeout <= 0 {
+		opts.StartTimeout = defaultStartTimeout
+	}
+	if opts.InvokeTimeout <= 0 {
+		opts.InvokeTimeout = defaultInvokeTimeout
+	}
+	binary := opts.Binary
+	if binary == "" {
+		path, err := exec.LookPath("op")
+		if err != nil {
+			return nil, fmt.Errorf("op binary not found on PATH; set Options.Binary: %w", err)
+		}
+		binary = path
+	}
+
+	root := a.ProcessRoot
+	if root == "" {
+		root = "."
+	}
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+
+	// Kept short: the socket path must fit in sun_path on macOS
+	dir, err := os.MkdirTemp("", "op-agenttest-")
+	if err != nil {
+		return nil, err
+	}
+	h := &Harness{
+		agent:  a.Name,
+		dir:    dir,
+		socket: filepath.Join(dir, "tmp", "opperator.sock"),
+		opts:   opts,
+		exited: make(chan struct{}),
+	}
+	if err := h.writeConfig(a, root); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, opts.StartTimeout)
+	defer cancel()
+	if err := h.startDaemon(ctx, binary); err != nil {
+		h.Close()
+		return nil, err
+	}
+	if err := h.startAgent(ctx); err != nil {
+		logs, _ := h.Logs()
+		h.Close()
+		if len(logs) > 0 {
+			return nil, fmt.Errorf("%w\nagent logs:\n%s", err, strings.Join(tail(logs, 20), "\n"))
+		}
+		return nil, err
+	}
+	return h, nil
+}
+
+// writeConfig writes an agents.yaml with only the agent under test into
+// the harness's home directory.
+func (h *Harness) writeConfig(a Agent, root string) error {
+	configDir := filepath.Join(h.dir, "home", ".config", "opperator")
+	for _, d := range []string{configDir, filepath.Join(h.dir, "tmp")} {
+		if err := os.MkdirAll(d, 0o755); err != nil {
+			return err
+		}
+	}
+	noAutostart := false
+	cfg := agent.Config{Agents: []agent.AgentConfig{{
+		Name:            a.Name,
+		Command:         a.Command,
+		Args:            a.Args,
+		ProcessRoot:     root,
+		Env:             a.Env,
+		Runtime:         a.Runtime,
+		StartWithDaemon: &noAutostart,
+	}}}
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(configDir, "agents.yaml"), data, 0o644)
+}
+
+// startDaemon runs 'op daemon start --foreground' with HOME and TMPDIR in
+// the harness directory, so its config, database, socket and lock are its
+// own, and waits for the socket to accept connections.
+func (h *Harness) startDaemon(ctx context.Context, binary string) error {
+	cmd := exec.Command(binary, "daemon", "start", "--foreground")
+	env := make([]string, 0, len(os.Environ())+2)
+	for _, kv := range os.Environ() {
+		key, _, _ := strings.Cut(kv, "=")
+		switch key {
+		case "HOME", "TMPDIR", "OPPERATOR_TCP_PORT", config.InstanceEnv, config.HomeEnv:
+			continue
+		}
+		env = append(env, kv)
+	}
+	cmd.Env = append(env, "HOME="+filepath.Join(h.dir, "home"), "TMPDIR="+filepath.Join(h.dir, "tmp"))
+	cmd.Stdout = &h.output
+	cmd.Stderr = &h.output
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start daemon: %w", err)
+	}
+	h.cmd = cmd
+	go func() {
+		cmd.Wait()
+		close(h.exited)
+	}()
+
+	for {
+		if client, err := ipc.NewClient(h.socket); err == nil {
+			client.Close()
+			return nil
+		}
+		select {
+		case <-h.exited:
+			return fmt.Errorf("daemon exited during startup: %s", strings.TrimSpace(h.output.String()))
+		case <-ctx.Done():
+			return fmt.Errorf("daemon did not start: %w", ctx.Err())
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// startAgent starts the agent and waits for its ready handshake and command
+// registry.
+func (h *Harness) startAgent(ctx context.Context) error {
+	if err := h.withClient(func(c *ipc.Client) error { return c.StartAgent(h.agent) }); err != nil {
+		return fmt.Errorf("failed to start agent %s: %w", h.agent, err)
+	}
+
+	var readyAt time.Time
+	running := false
+	for {
+		info, err := h.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case info.Status == agent.StatusCrashed, info.Status == agent.StatusCrashLooping,
+			info.Status == agent.StatusStopped && running:
+			return fmt.Errorf("agent %s %s during startup", h.agent, info.Status)
+		case info.Status == agent.StatusRunning:
+			running = true
+			if readyAt.IsZero() && info.Capabilities != nil {
+				readyAt = time.Now()
+			}
+			commands, err := h.Commands()
+			if err != nil {
+				return err
+			}
+			if len(commands) > 0 || (!readyAt.IsZero() && time.Since(readyAt) >= commandSettle) {
+				return nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("agent %s did not become ready: %w", h.agent, ctx.Err())
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+func (h *Harness) withClient(fn func(*ipc.Client) error) error {
+	client, err := ipc.NewClient(h.socket)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	return fn(client)
+}
+
+// Dir is the harness's temporary directory, holding the daemon's home and
+// logs; it is removed on Close.
+func (h *Harness) Dir() string {
+	return h.dir
+}
+
+// Info returns the agent's process information.
+func (h *Harness) Info() (*ipc.ProcessInfo, error) {
+	var found *ipc.ProcessInfo
+	err := h.withClient(func(c *ipc.Client) error {
+		agents, err := c.ListAgents()
+		if err != nil {
+			return err
+		}
+		for _, info := range agents {
+			if info.Name == h.agent {
+				found = info
+				return nil
+			}
+		}
+		return fmt.Errorf("agent %s is not loaded", h.agent)
+	})
+	return found, err
+}
+
+// Commands returns the commands the agent registered.
+func (h *Harness) Commands() ([]protocol.CommandDescriptor, error) {
+	var commands []protocol.CommandDescriptor
+	err := h.withClient(func(c *ipc.Client) error {
+		var err error
+		commands, err = c.ListCommands(h.agent)
+		return err
+	})
+	return commands, err
+}
+
+// Invoke runs a command of the agent with args. A command that fails is
+// reported in the result; the error is for failing to reach the agent.
+func (h *Harness) Invoke(ctx context.Context, command string, args map[string]any) (Result, error) {
+	var result Result
+	start := time.Now()
+	err := h.withClient(func(c *ipc.Client) error {
+		resp, err := c.InvokeCommandContext(ctx, h.agent, command, args, h.opts.InvokeTimeout, func(p protocol.CommandProgressMessage) {
+			if p.Text != "" {
+				result.Progress = append(result.Progress, p.Text)
+			}
+		})
+		if err != nil {
+			return err
+		}
+		result.Success = resp.Success
+		result.Error = resp.Error
+		result.Output = resp.Result
+		return nil
+	})
+	result.Duration = time.Since(start)
+	return result, err
+}
+
+// MustInvoke runs a command and fails the test unless it succeeds.
+func (h *Harness) MustInvoke(t testing.TB, command string, args map[string]any) Result {
+	t.Helper()
+	result, err := h.Invoke(context.Background(), command, args)
+	if err != nil {
+		t.Fatalf("agenttest: invoke %s: %v", command, err)
+	}
+	if !result.Success {
+		t.Fatalf("agenttest: %s failed: %s", command, result.Error)
+	}
+	return result
+}
+
+// Logs returns the agent's log lines, oldest first.
+func (h *Harness) Logs() ([]string, error) {
+	var logs []string
+	err := h.withClient(func(c *ipc.Client) error {
+		var err error
+		logs, err = c.GetLogs(h.agent)
+		return err
+	})
+	return logs, err
+}
+
+// WaitForLog waits until a log line of the agent contains substr.
+func (h *Harness) WaitForLog(ctx context.Context, substr string) error {
+	for {
+		logs, err := h.Logs()
+		if err != nil {
+			return err
+		}
+		for _, line := range logs {
+			if strings.Contains(line, substr) {
+				return nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("no log line of %s contains %q: %w", h.agent, substr, ctx.Err())
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// DaemonLog returns the isolated daemon's log, for diagnosing failures.
+func (h *Harness) DaemonLog() string {
+	data, err := os.ReadFile(filepath.Join(h.dir, "home", ".config", "opperator", "logs", "daemon.log"))
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// Close stops the agent and the daemon and removes the harness directory.
+func (h *Harness) Close() error {
+	h.closeOnce.Do(func() {
+		if h.cmd != nil {
+			_ = h.withClient(func(c *ipc.Client) error { return c.Shutdown() })
+			select {
+			case <-h.exited:
+			case <-time.After(10 * time.Second):
+				_ = h.cmd.Process.Kill()
+				<-h.exited
+				h.closeErr = errors.New("daemon did not shut down; killed it")
+			}
+		}
+		if err := os.RemoveAll(h.dir); err != nil && h.closeErr == nil {
+			h.closeErr = err
+		}
+	})
+	return h.closeErr
+}
+
+func tail(lines []string, n int) []string {
+	if len(lines) > n {
+		return lines[len(lines)-n:]
+	}
+	return lines
+}
@@ -0,0 +1,124 @@
+package agenttest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Suite is a file of command invocations and what to expect of them, run
+// by 'op agent test <name> <file>':
+//
+//	tests:
+//	  - name: forecast for Paris
+//	    command: get_forecast
+//	    args: {city: Paris}
+//	    expect:
+//	      contains: ["Paris"]
+//	      log: fetched forecast
+//	  - command: get_forecast
+//	    args: {}
+//	    expect:
+//	      success: false
+//	      error: city is required
+type Suite struct {
+	Tests []Case `yaml:"tests"`
+}
+
+// Case is one command invocation.
+type Case struct {
+	Name    string         `yaml:"name"`
+	Command string         `yaml:"command"`
+	Args    map[string]any `yaml:"args"`
+	Expect  Expect         `yaml:"expect"`
+}
+
+// Expect is what a case checks; every check given must pass.
+type Expect struct {
+	// Success is whether the command should succeed; true when unset.
+	Success *bool `yaml:"success"`
+	// Contains lists text the output must contain.
+	Contains []string `yaml:"contains"`
+	// Error is text the error must contain.
+	Error string `yaml:"error"`
+	// Log is text a log line of the agent must contain afterwards.
+	Log string `yaml:"log"`
+}
+
+// CaseResult is the outcome of a case.
+type CaseResult struct {
+	Case     Case
+	Result   Result
+	Failures []string
+}
+
+// Passed reports whether every check of the case passed.
+func (r CaseResult) Passed() bool {
+	return len(r.Failures) == 0
+}
+
+// LoadSuite reads a suite file.
+func LoadSuite(path string) (*Suite, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tests: %w", err)
+	}
+	var suite Suite
+	if err := yaml.Unmarshal(data, &suite); err != nil {
+		return nil, fmt.Errorf("failed to parse tests %s: %w", path, err)
+	}
+	if len(suite.Tests) == 0 {
+		return nil, fmt.Errorf("%s has no tests", path)
+	}
+	for i := range suite.Tests {
+		c := &suite.Tests[i]
+		if strings.TrimSpace(c.Command) == "" {
+			return nil, fmt.Errorf("%s: test %d has no command", path, i+1)
+		}
+		if c.Name == "" {
+			c.Name = c.Command
+		}
+	}
+	return &suite, nil
+}
+
+// Run invokes the case's command and checks the result.
+func (h *Harness) Run(ctx context.Context, c Case) CaseResult {
+	out := CaseResult{Case: c}
+	result, err := h.Invoke(ctx, c.Command, c.Args)
+	out.Result = result
+	if err != nil {
+		out.Failures = append(out.Failures, err.Error())
+		return out
+	}
+
+	wantSuccess := c.Expect.Success == nil || *c.Expect.Success
+	switch {
+	case wantSuccess && !result.Success:
+		out.Failures = append(out.Failures, "failed: "+result.Error)
+	case !wantSuccess && result.Success:
+		out.Failures = append(out.Failures, "succeeded, expected it to fail")
+	}
+	text := result.Text()
+	for _, s := range c.Expect.Contains {
+		if !strings.Contains(text, s) {
+			out.Failures = append(out.Failures, fmt.Sprintf("output is missing %q", s))
+		}
+	}
+	if c.Expect.Error != "" && !strings.Contains(result.Error, c.Expect.Error) {
+		out.Failures = append(out.Failures, fmt.Sprintf("error %q doesn't contain %q", result.Error, c.Expect.Error))
+	}
+	if c.Expect.Log != "" {
+		// Logs are forwarded asynchronously, so give them a moment
+		logCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+		defer cancel()
+		if err := h.WaitForLog(logCtx, c.Expect.Log); err != nil {
+			out.Failures = append(out.Failures, fmt.Sprintf("no log line contains %q", c.Expect.Log))
+		}
+	}
+	return out
+}
@@ -17,6 +17,9 @@ type CommandArgument struct {
 	Enum        []interface{}          `json:"enum,omitempty"`
 	Items       map[string]interface{} `json:"items,omitempty"`
 	Properties  map[string]interface{} `json:"properties,omitempty"`
+	// Schema, when set, is the argument's full JSON Schema and is used
+	// instead of the fields above.
+	Schema map[string]interface{} `json:"schema,omitempty"`
 }
 
 // OpperClient interface for Opper API calls
@@ -158,6 +161,13 @@ func buildArgumentSchema(args []CommandArgument) map[string]any {
 	required := []string{}
 
 	for _, arg := range args {
+		if arg.Schema != nil {
+			properties[arg.Name] = arg.Schema
+			if arg.Required {
+				required = append(required, arg.Name)
+			}
+			continue
+		}
 		argSchema := map[string]any{
 			"type":        arg.Type,
 			"description": arg.Description,
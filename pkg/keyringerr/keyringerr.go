@@ -0,0 +1,113 @@
+// Package keyringerr recognises an OS keyring that cannot be used right now,
+// because it is locked or because none is running for the session (common
+// over SSH or right after login), and keeps keyring calls from hanging on
+// an unlock prompt nobody can answer.
+package keyringerr
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Timeout bounds one keyring call.
+const Timeout = 10 * time.Second
+
+// Error is a keyring call that failed because of the keyring itself rather
+// than the secret asked for. Locked is false when no keyring could be
+// reached at all.
+type Error struct {
+	Locked bool
+	Err    error
+}
+
+func (e *Error) Error() string {
+	state := "unavailable"
+	if e.Locked {
+		state = "locked"
+	}
+	return fmt.Sprintf("the system keyring is %s (%v). %s", state, e.Err, hint(e.Locked))
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Is reports whether err comes from a locked or unavailable keyring.
+func Is(err error) bool {
+	var keyringErr *Error
+	return errors.As(err, &keyringErr)
+}
+
+// Patterns of the errors the keyring backends report, matched lowercase.
+var (
+	lockedPatterns = []string{
+		"is locked",
+		"prompt dismissed",
+		"user interaction is not allowed",
+		"user canceled",
+		"passphrase you entered is not correct",
+	}
+	unavailablePatterns = []string{
+		"org.freedesktop.secrets",
+		"dbus",
+		"d-bus",
+		"$display",
+		"cannot autolaunch",
+		"no such interface",
+		"executable file not found",
+		"keychain could not be found",
+		"no keychain",
+	}
+)
+
+// Classify returns err as an *Error when it comes from a locked or
+// unavailable keyring, and unchanged otherwise.
+func Classify(err error) error {
+	if err == nil || Is(err) {
+		return err
+	}
+	msg := strings.ToLower(err.Error())
+	for _, p := range lockedPatterns {
+		if strings.Contains(msg, p) {
+			return &Error{Locked: true, Err: err}
+		}
+	}
+	for _, p := range unavailablePatterns {
+		if strings.Contains(msg, p) {
+			return &Error{Err: err}
+		}
+	}
+	return err
+}
+
+// Do runs fn, one keyring call, and classifies its error. A call that does
+// not return within Timeout is taken for a keyring waiting to be unlocked;
+// it keeps running in the background and its result is dropped.
+func Do(fn func() error) error {
+	done := make(chan error, 1)
+	go func() { done <- fn() }()
+	select {
+	case err := <-done:
+		return Classify(err)
+	case <-time.After(Timeout):
+		return &Error{Locked: true, Err: fmt.Errorf("no answer after %s", Timeout)}
+	}
+}
+
+// hint says how to get the keyring working on this platform.
+func hint(locked bool) string {
+	switch runtime.GOOS {
+	case "darwin":
+		if locked {
+			return "Unlock it with `security unlock-keychain` and try again."
+		}
+		return "Check that your login keychain exists in Keychain Access and try again."
+	case "windows":
+		return "Check that Credential Manager is available to this user and try again."
+	}
+	if locked {
+		return "Unlock it from your desktop session, or over SSH run `gnome-keyring-daemon --unlock` and type your password followed by Ctrl+D, then try again."
+	}
+	return "Opperator stores secrets in a Secret Service such as GNOME Keyring or KWallet. Over SSH, start one with `eval $(dbus-launch --sh-syntax)` followed by `gnome-keyring-daemon --unlock`, then try again."
+}
@@ -0,0 +1,108 @@
+// Package loopdetect notices a model conversation going in circles: the
+// same tool called with the same arguments, or the same text written, over
+// and over within one turn. It lets the conversation loops stop long before
+// their round cap.
+package loopdetect
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DefaultRepeats is how many identical tool calls or texts make a loop.
+const DefaultRepeats = 4
+
+// Loop kinds.
+const (
+	KindToolCall = "tool_call"
+	KindText     = "text"
+)
+
+// LoopError is returned once something repeats often enough to be a loop.
+// Tool names the repeated tool for KindToolCall.
+type LoopError struct {
+	Kind  string
+	Tool  string
+	Count int
+}
+
+func (e *LoopError) Error() string {
+	if e.Kind == KindToolCall {
+		return fmt.Sprintf("stopped a loop: %s was called %d times with the same arguments", e.Tool, e.Count)
+	}
+	return fmt.Sprintf("stopped a loop: the same response was written %d times", e.Count)
+}
+
+// Attributes describes the loop as key/value pairs for a trace span.
+func (e *LoopError) Attributes() []any {
+	return []any{"loop.kind", e.Kind, "loop.tool", e.Tool, "loop.repeats", e.Count}
+}
+
+// IsLoop reports whether err is a detected loop.
+func IsLoop(err error) bool {
+	var loop *LoopError
+	return errors.As(err, &loop)
+}
+
+// Detector counts what a conversation loop has seen during one turn. The
+// zero value is not usable; create one with New for every turn.
+type Detector struct {
+	repeats int
+	calls   map[string]int
+	texts   map[string]int
+}
+
+// New returns a detector that reports a loop at the repeats-th identical
+// tool call or text, or at DefaultRepeats when repeats is not positive.
+func New(repeats int) *Detector {
+	if repeats <= 0 {
+		repeats = DefaultRepeats
+	}
+	return &Detector{repeats: repeats, calls: make(map[string]int), texts: make(map[string]int)}
+}
+
+// ToolCall records a call of tool with args, which may be a JSON string or
+// any value encoding to JSON, and returns a *LoopError once the same call
+// has been seen often enough.
+func (d *Detector) ToolCall(tool string, args any) error {
+	key := tool + "\x00" + argsKey(args)
+	d.calls[key]++
+	if n := d.calls[key]; n >= d.repeats {
+		return &LoopError{Kind: KindToolCall, Tool: tool, Count: n}
+	}
+	return nil
+}
+
+// Text records a text the model wrote alongside tool calls and returns a
+// *LoopError once the same text has been seen often enough. Whitespace and
+// case are ignored, and empty texts are not counted.
+func (d *Detector) Text(text string) error {
+	key := strings.ToLower(strings.Join(strings.Fields(text), " "))
+	if key == "" {
+		return nil
+	}
+	d.texts[key]++
+	if n := d.texts[key]; n >= d.repeats {
+		return &LoopError{Kind: KindText, Count: n}
+	}
+	return nil
+}
+
+// argsKey renders args canonically, so the same arguments in a different
+// key order or spacing compare equal.
+func argsKey(args any) string {
+	if s, ok := args.(string); ok {
+		var v any
+		if json.Unmarshal([]byte(s), &v) != nil {
+			return strings.TrimSpace(s)
+		}
+		args = v
+	}
+	data, err := json.Marshal(args)
+	if err != nil {
+		return fmt.Sprint(args)
+	}
+	return string(data)
+}
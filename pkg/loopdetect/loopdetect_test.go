@@ -0,0 +1,143 @@
+package loopdetect
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestArgsKey(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b any
+		same bool
+	}{
+		{
+			name: "key order",
+			a:    `{"path": "a.txt", "limit": 10}`,
+			b:    `{"limit":10,"path":"a.txt"}`,
+			same: true,
+		},
+		{
+			name: "string and value",
+			a:    `{"path":"a.txt"}`,
+			b:    map[string]any{"path": "a.txt"},
+			same: true,
+		},
+		{
+			name: "surrounding whitespace of non-JSON",
+			a:    "  ls -la \n",
+			b:    "ls -la",
+			same: true,
+		},
+		{
+			name: "different values",
+			a:    `{"path":"a.txt"}`,
+			b:    `{"path":"b.txt"}`,
+			same: false,
+		},
+		{
+			name: "different types",
+			a:    `{"limit":10}`,
+			b:    `{"limit":"10"}`,
+			same: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := argsKey(tt.a), argsKey(tt.b)
+			if (a == b) != tt.same {
+				t.Errorf("argsKey(%v) = %q, argsKey(%v) = %q, expected same=%v", tt.a, a, tt.b, b, tt.same)
+			}
+		})
+	}
+}
+
+func TestDetector_Text(t *testing.T) {
+	tests := []struct {
+		name  string
+		texts []string
+		loop  bool
+	}{
+		{
+			name:  "whitespace and case",
+			texts: []string{"Let me check.", "let me  check.", "LET ME\nCHECK.", " Let me check. "},
+			loop:  true,
+		},
+		{
+			name:  "different texts",
+			texts: []string{"one", "two", "three", "four"},
+			loop:  false,
+		},
+		{
+			name:  "empty texts are not counted",
+			texts: []string{"", " ", "\n", "\t", ""},
+			loop:  false,
+		},
+		{
+			name:  "below the threshold",
+			texts: []string{"again", "again", "again"},
+			loop:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := New(0)
+			var err error
+			for _, text := range tt.texts {
+				if err = d.Text(text); err != nil {
+					break
+				}
+			}
+			if (err != nil) != tt.loop {
+				t.Fatalf("Expected loop=%v, got %v", tt.loop, err)
+			}
+			if tt.loop {
+				var loop *LoopError
+				if !errors.As(err, &loop) || loop.Kind != KindText || loop.Count != DefaultRepeats {
+					t.Errorf("Expected a text loop at %d repeats, got %#v", DefaultRepeats, err)
+				}
+			}
+		})
+	}
+}
+
+func TestDetector_Threshold(t *testing.T) {
+	tests := []struct {
+		name    string
+		repeats int
+		want    int
+	}{
+		{name: "default", repeats: 0, want: DefaultRepeats},
+		{name: "negative uses default", repeats: -1, want: DefaultRepeats},
+		{name: "configured", repeats: 2, want: 2},
+		{name: "configured higher", repeats: 7, want: 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := New(tt.repeats)
+			for i := 1; i < tt.want; i++ {
+				if err := d.ToolCall("read_file", `{"path":"a.txt"}`); err != nil {
+					t.Fatalf("Expected no loop at call %d, got %v", i, err)
+				}
+				// Calls with other arguments do not count towards the loop
+				if err := d.ToolCall("read_file", `{"path":"b.txt"}`); err != nil {
+					t.Fatalf("Expected no loop for other arguments at call %d, got %v", i, err)
+				}
+			}
+			err := d.ToolCall("read_file", `{"path": "a.txt"}`)
+			var loop *LoopError
+			if !errors.As(err, &loop) {
+				t.Fatalf("Expected a loop at call %d, got %v", tt.want, err)
+			}
+			if loop.Kind != KindToolCall || loop.Tool != "read_file" || loop.Count != tt.want {
+				t.Errorf("Expected read_file loop at %d calls, got %#v", tt.want, loop)
+			}
+			if !IsLoop(err) {
+				t.Errorf("Expected IsLoop to report %v", err)
+			}
+		})
+	}
+}
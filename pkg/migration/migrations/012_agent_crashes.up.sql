@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS agent_crashes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    agent_name TEXT NOT NULL,
+    exit_code INTEGER NOT NULL,
+    signal TEXT,
+    stderr_tail TEXT,
+    created_at INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_agent_crashes_agent_created_at ON agent_crashes(agent_name, created_at DESC);
@@ -0,0 +1 @@
+ALTER TABLE tool_tasks DROP COLUMN depends_on;
@@ -0,0 +1 @@
+ALTER TABLE tool_tasks ADD COLUMN depends_on TEXT;
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS email_threads (
+    channel TEXT NOT NULL,
+    thread_id TEXT NOT NULL,
+    conversation_id TEXT NOT NULL,
+    created_at INTEGER NOT NULL,
+    PRIMARY KEY (channel, thread_id)
+);
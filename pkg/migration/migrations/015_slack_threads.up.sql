@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS slack_threads (
+    thread_key TEXT PRIMARY KEY,
+    conversation_id TEXT NOT NULL,
+    created_at INTEGER NOT NULL
+);
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS telegram_chats (
+    chat_id INTEGER PRIMARY KEY,
+    conversation_id TEXT NOT NULL,
+    created_at INTEGER NOT NULL
+);
@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS email_threads (
+    channel TEXT NOT NULL,
+    thread_id TEXT NOT NULL,
+    conversation_id TEXT NOT NULL,
+    created_at INTEGER NOT NULL,
+    PRIMARY KEY (channel, thread_id)
+);
+CREATE TABLE IF NOT EXISTS slack_threads (
+    thread_key TEXT PRIMARY KEY,
+    conversation_id TEXT NOT NULL,
+    created_at INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS telegram_chats (
+    chat_id INTEGER PRIMARY KEY,
+    conversation_id TEXT NOT NULL,
+    created_at INTEGER NOT NULL
+);
+
+INSERT OR IGNORE INTO slack_threads (thread_key, conversation_id, created_at)
+SELECT thread_id, conversation_id, created_at FROM channel_threads WHERE channel = 'slack';
+
+INSERT OR IGNORE INTO telegram_chats (chat_id, conversation_id, created_at)
+SELECT CAST(thread_id AS INTEGER), conversation_id, created_at FROM channel_threads WHERE channel = 'telegram';
+
+INSERT OR IGNORE INTO email_threads (channel, thread_id, conversation_id, created_at)
+SELECT channel, thread_id, conversation_id, created_at FROM channel_threads WHERE channel NOT IN ('slack', 'telegram');
+
+DROP TABLE IF EXISTS channel_threads;
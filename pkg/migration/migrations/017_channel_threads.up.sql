@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS channel_threads (
+    channel TEXT NOT NULL,
+    thread_id TEXT NOT NULL,
+    conversation_id TEXT NOT NULL,
+    created_at INTEGER NOT NULL,
+    PRIMARY KEY (channel, thread_id)
+);
+
+INSERT OR IGNORE INTO channel_threads (channel, thread_id, conversation_id, created_at)
+SELECT channel, thread_id, conversation_id, created_at FROM email_threads;
+
+INSERT OR IGNORE INTO channel_threads (channel, thread_id, conversation_id, created_at)
+SELECT 'slack', thread_key, conversation_id, created_at FROM slack_threads;
+
+INSERT OR IGNORE INTO channel_threads (channel, thread_id, conversation_id, created_at)
+SELECT 'telegram', CAST(chat_id AS TEXT), conversation_id, created_at FROM telegram_chats;
+
+DROP TABLE IF EXISTS email_threads;
+DROP TABLE IF EXISTS slack_threads;
+DROP TABLE IF EXISTS telegram_chats;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_memories_agent;
+DROP TABLE IF EXISTS memories;
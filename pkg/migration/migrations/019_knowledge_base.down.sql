@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_kb_chunks_document;
+DROP TABLE IF EXISTS kb_chunks;
+DROP TABLE IF EXISTS kb_documents;
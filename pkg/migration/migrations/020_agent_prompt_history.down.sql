@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_agent_prompt_history_agent;
+DROP TABLE IF EXISTS agent_prompt_history;
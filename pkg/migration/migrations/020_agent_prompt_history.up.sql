@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS agent_prompt_history (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    agent_name TEXT NOT NULL,
+    system_prompt TEXT NOT NULL,
+    system_prompt_replace INTEGER NOT NULL DEFAULT 0,
+    description TEXT NOT NULL,
+    source TEXT NOT NULL,
+    created_at INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_agent_prompt_history_agent ON agent_prompt_history(agent_name, id);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_model_recordings_session;
+DROP TABLE IF EXISTS model_recordings;
@@ -0,0 +1 @@
+ALTER TABLE custom_sections DROP COLUMN actions;
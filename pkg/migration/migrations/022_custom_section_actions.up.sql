@@ -0,0 +1 @@
+ALTER TABLE custom_sections ADD COLUMN actions TEXT;
@@ -0,0 +1,2 @@
+ALTER TABLE custom_sections DROP COLUMN format;
+ALTER TABLE custom_sections DROP COLUMN section_type;
@@ -0,0 +1,2 @@
+ALTER TABLE custom_sections ADD COLUMN section_type TEXT;
+ALTER TABLE custom_sections ADD COLUMN format TEXT;
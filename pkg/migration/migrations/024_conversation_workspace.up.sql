@@ -0,0 +1,3 @@
+ALTER TABLE conversations ADD COLUMN workspace TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_conversations_workspace ON conversations(workspace);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_tool_tasks_owner;
+
+ALTER TABLE tool_tasks DROP COLUMN owner;
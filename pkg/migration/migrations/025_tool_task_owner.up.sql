@@ -0,0 +1,3 @@
+ALTER TABLE tool_tasks ADD COLUMN owner TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_tool_tasks_owner ON tool_tasks(owner);
@@ -0,0 +1 @@
+ALTER TABLE conversations ADD COLUMN title_renamed INTEGER NOT NULL DEFAULT 0;
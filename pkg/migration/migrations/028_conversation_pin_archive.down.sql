@@ -0,0 +1,2 @@
+ALTER TABLE conversations DROP COLUMN archived_at;
+ALTER TABLE conversations DROP COLUMN pinned;
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS agent_metrics (
+    agent_name TEXT NOT NULL,
+    name TEXT NOT NULL,
+    kind TEXT NOT NULL,
+    bucket INTEGER NOT NULL,
+    value REAL NOT NULL DEFAULT 0,
+    PRIMARY KEY (agent_name, name, bucket)
+);
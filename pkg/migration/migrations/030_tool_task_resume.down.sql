@@ -0,0 +1,3 @@
+ALTER TABLE tool_tasks DROP COLUMN execution;
+ALTER TABLE tool_tasks DROP COLUMN attempts;
+ALTER TABLE tool_tasks DROP COLUMN seq;
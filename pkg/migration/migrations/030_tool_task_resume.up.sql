@@ -0,0 +1,6 @@
+ALTER TABLE tool_tasks ADD COLUMN seq INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE tool_tasks ADD COLUMN attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE tool_tasks ADD COLUMN execution TEXT;
+
+-- Rows are inserted on submission, so rowid order is submission order
+UPDATE tool_tasks SET seq = rowid;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_message_parts_tool_call;
+DROP INDEX IF EXISTS idx_message_parts_message;
+DROP TABLE IF EXISTS message_parts;
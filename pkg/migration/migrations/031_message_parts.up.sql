@@ -0,0 +1,46 @@
+-- Typed parts of conversation messages. messages.metadata keeps the JSON the
+-- parts were written as for older binaries; readers use these rows.
+CREATE TABLE IF NOT EXISTS message_parts (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    message_id INTEGER NOT NULL,
+    position INTEGER NOT NULL,
+    kind TEXT NOT NULL, -- text, tool_call, tool_result, attachment, finish, turn_summary
+    text TEXT NOT NULL DEFAULT '',
+    tool_call_id TEXT NOT NULL DEFAULT '',
+    tool_name TEXT NOT NULL DEFAULT '',
+    tool_input TEXT NOT NULL DEFAULT '',
+    is_error INTEGER NOT NULL DEFAULT 0,
+    mime_type TEXT NOT NULL DEFAULT '',
+    path TEXT NOT NULL DEFAULT '',
+    data TEXT NOT NULL DEFAULT '{}', -- the full part as JSON, for fields without a column
+    FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_message_parts_message ON message_parts(message_id, position);
+CREATE INDEX IF NOT EXISTS idx_message_parts_tool_call ON message_parts(tool_call_id) WHERE tool_call_id != '';
+
+-- Backfill from the JSON arrays, classifying parts the way the old readers
+-- probed them: text first, then tool calls (id), then tool results
+-- (tool_call_id or the older tool_id).
+INSERT INTO message_parts (message_id, position, kind, text, tool_call_id, tool_name, tool_input, is_error, data)
+SELECT
+    m.id,
+    CAST(p.key AS INTEGER),
+    CASE
+        WHEN COALESCE(json_extract(p.value, '$.text'), json_extract(p.value, '$.Text'), '') != '' THEN 'text'
+        WHEN COALESCE(json_extract(p.value, '$.id'), '') != '' THEN 'tool_call'
+        WHEN COALESCE(json_extract(p.value, '$.tool_call_id'), json_extract(p.value, '$.tool_id'), '') != '' THEN 'tool_result'
+        WHEN json_extract(p.value, '$.agent_id') IS NOT NULL THEN 'turn_summary'
+        WHEN json_extract(p.value, '$.reason') IS NOT NULL AND json_extract(p.value, '$.time') IS NOT NULL THEN 'finish'
+        ELSE 'unknown'
+    END,
+    COALESCE(json_extract(p.value, '$.text'), json_extract(p.value, '$.Text'), json_extract(p.value, '$.content'), ''),
+    COALESCE(json_extract(p.value, '$.tool_call_id'), json_extract(p.value, '$.tool_id'), json_extract(p.value, '$.id'), ''),
+    COALESCE(json_extract(p.value, '$.name'), ''),
+    COALESCE(json_extract(p.value, '$.input'), ''),
+    COALESCE(json_extract(p.value, '$.is_error'), 0),
+    p.value
+FROM messages m, json_each(m.metadata) p
+WHERE json_valid(m.metadata) AND json_type(m.metadata) = 'array' AND json_type(p.value) = 'object';
+
+DELETE FROM message_parts WHERE kind = 'unknown';
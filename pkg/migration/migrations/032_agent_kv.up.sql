@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS agent_kv (
+    agent TEXT NOT NULL,
+    key TEXT NOT NULL,
+    value TEXT NOT NULL,
+    updated_at INTEGER NOT NULL,
+    PRIMARY KEY (agent, key)
+);
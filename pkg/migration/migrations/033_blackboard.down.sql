@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_blackboard_updated;
+DROP TABLE IF EXISTS blackboard;
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS blackboard (
+    namespace TEXT NOT NULL,
+    key TEXT NOT NULL,
+    value TEXT NOT NULL,
+    writer TEXT NOT NULL DEFAULT '',
+    updated_at INTEGER NOT NULL,
+    PRIMARY KEY (namespace, key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_blackboard_updated ON blackboard(updated_at);
@@ -0,0 +1,265 @@
+// Package msgstore stores conversation messages as typed parts.
+//
+// Every message row keeps its parts as a JSON array in messages.metadata, so
+// older binaries can still read it, and as rows of message_parts, which
+// readers use. Messages written by older binaries have no part rows; their
+// metadata is classified when they are read.
+package msgstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Kind is the type of a message part.
+type Kind string
+
+const (
+	KindText        Kind = "text"
+	KindToolCall    Kind = "tool_call"
+	KindToolResult  Kind = "tool_result"
+	KindAttachment  Kind = "attachment"
+	KindFinish      Kind = "finish"
+	KindTurnSummary Kind = "turn_summary"
+)
+
+// Part is one typed piece of a message.
+type Part struct {
+	Kind Kind
+	// Text is the text of a text part or the content of a tool result.
+	Text string
+	// ToolCallID identifies the call of a tool call or tool result part.
+	ToolCallID string
+	ToolName   string
+	// ToolInput is the JSON arguments of a tool call.
+	ToolInput string
+	IsError   bool
+	// MimeType and Path describe an attachment.
+	MimeType string
+	Path     string
+	// Data is the whole part as JSON, including fields without a column.
+	Data json.RawMessage
+}
+
+// Message is a stored message with its parts.
+type Message struct {
+	ID        int64
+	SessionID string
+	Role      string
+	Parts     []Part
+	CreatedAt int64
+	UpdatedAt int64
+}
+
+// Text returns the text of the message's last text or tool result part.
+func (m Message) Text() string {
+	var text string
+	for _, p := range m.Parts {
+		if (p.Kind == KindText || p.Kind == KindToolResult) && p.Text != "" {
+			text = p.Text
+		}
+	}
+	return text
+}
+
+// TextPart returns a text part.
+func TextPart(text string) Part {
+	data, _ := json.Marshal(map[string]any{"text": text})
+	return Part{Kind: KindText, Text: text, Data: data}
+}
+
+// ToolCallPart returns a finished tool call part; input is the JSON arguments.
+func ToolCallPart(id, name, input string) Part {
+	data, _ := json.Marshal(map[string]any{
+		"id":       id,
+		"name":     name,
+		"input":    input,
+		"type":     "function",
+		"finished": true,
+	})
+	return Part{Kind: KindToolCall, ToolCallID: id, ToolName: name, ToolInput: input, Data: data}
+}
+
+// ToolResultPart returns a tool result part.
+func ToolResultPart(toolCallID, name, content string, isError bool) Part {
+	data, _ := json.Marshal(map[string]any{
+		"tool_call_id": toolCallID,
+		"name":         name,
+		"content":      content,
+		"is_error":     isError,
+	})
+	return Part{Kind: KindToolResult, Text: content, ToolCallID: toolCallID, ToolName: name, IsError: isError, Data: data}
+}
+
+// Insert stores a message and its parts in one transaction and returns its ID.
+func Insert(ctx context.Context, db *sql.DB, sessionID, role string, parts []Part) (int64, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	id, err := InsertTx(ctx, tx, sessionID, role, parts)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+// InsertTx stores a message and its parts within tx and returns its ID.
+func InsertTx(ctx context.Context, tx *sql.Tx, sessionID, role string, parts []Part) (int64, error) {
+	raw := make([]json.RawMessage, 0, len(parts))
+	for _, p := range parts {
+		if len(p.Data) == 0 {
+			return 0, fmt.Errorf("%s part has no data", p.Kind)
+		}
+		raw = append(raw, p.Data)
+	}
+	metadata, err := json.Marshal(raw)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now().Unix()
+	res, err := tx.ExecContext(ctx,
+		`INSERT INTO messages(session_id, role, metadata, created_at, updated_at) VALUES(?, ?, ?, ?, ?)`,
+		sessionID, role, string(metadata), now, now)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	for i, p := range parts {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO message_parts(message_id, position, kind, text, tool_call_id, tool_name, tool_input, is_error, mime_type, path, data)
+			 VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			id, i, string(p.Kind), p.Text, p.ToolCallID, p.ToolName, p.ToolInput, p.IsError, p.MimeType, p.Path, string(p.Data))
+		if err != nil {
+			return 0, fmt.Errorf("store %s part: %w", p.Kind, err)
+		}
+	}
+	return id, nil
+}
+
+// ListOptions narrow down the messages List returns.
+type ListOptions struct {
+	// ThroughID, when set, stops at the message with this ID.
+	ThroughID int64
+	// Roles, when set, keeps only messages with one of these roles.
+	Roles []string
+}
+
+// List returns a conversation's messages in order.
+func List(ctx context.Context, db *sql.DB, sessionID string, opts ListOptions) ([]Message, error) {
+	query := `SELECT m.id, m.role, m.metadata, m.created_at, m.updated_at,
+	                 p.kind, p.text, p.tool_call_id, p.tool_name, p.tool_input, p.is_error, p.mime_type, p.path, p.data
+	          FROM messages m LEFT JOIN message_parts p ON p.message_id = m.id
+	          WHERE m.session_id = ?`
+	args := []any{sessionID}
+	if opts.ThroughID > 0 {
+		query += ` AND m.id <= ?`
+		args = append(args, opts.ThroughID)
+	}
+	if len(opts.Roles) > 0 {
+		query += ` AND m.role IN (?` + strings.Repeat(`, ?`, len(opts.Roles)-1) + `)`
+		for _, r := range opts.Roles {
+			args = append(args, r)
+		}
+	}
+	query += ` ORDER BY m.id, p.position`
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var msgs []Message
+	for rows.Next() {
+		var (
+			m        Message
+			metadata string
+			kind     sql.NullString
+			data     sql.NullString
+		)
+		var text, callID, name, input, mime, path sql.NullString
+		var isError sql.NullBool
+		if err := rows.Scan(&m.ID, &m.Role, &metadata, &m.CreatedAt, &m.UpdatedAt,
+			&kind, &text, &callID, &name, &input, &isError, &mime, &path, &data); err != nil {
+			return nil, err
+		}
+		if n := len(msgs); n == 0 || msgs[n-1].ID != m.ID {
+			m.SessionID = sessionID
+			if !kind.Valid {
+				// Written by a binary that predates message_parts
+				m.Parts = PartsFromMetadata(metadata)
+			}
+			msgs = append(msgs, m)
+		}
+		if !kind.Valid {
+			continue
+		}
+		p := Part{
+			Kind:       Kind(kind.String),
+			Text:       text.String,
+			ToolCallID: callID.String,
+			ToolName:   name.String,
+			ToolInput:  input.String,
+			IsError:    isError.Bool,
+			MimeType:   mime.String,
+			Path:       path.String,
+			Data:       json.RawMessage(data.String),
+		}
+		last := &msgs[len(msgs)-1]
+		last.Parts = append(last.Parts, p)
+	}
+	return msgs, rows.Err()
+}
+
+// PartsFromMetadata classifies the JSON array of a message written without
+// part rows, the same way the migration that introduced them did.
+func PartsFromMetadata(metadata string) []Part {
+	var raws []json.RawMessage
+	if err := json.Unmarshal([]byte(metadata), &raws); err != nil {
+		return nil
+	}
+	var parts []Part
+	for _, raw := range raws {
+		var f map[string]any
+		if err := json.Unmarshal(raw, &f); err != nil {
+			continue
+		}
+		str := func(keys ...string) string {
+			for _, k := range keys {
+				if s, ok := f[k].(string); ok && s != "" {
+					return s
+				}
+			}
+			return ""
+		}
+		p := Part{Data: raw}
+		switch {
+		case str("text", "Text") != "":
+			p.Kind, p.Text = KindText, str("text", "Text")
+		case str("id") != "":
+			p.Kind, p.ToolCallID, p.ToolName, p.ToolInput = KindToolCall, str("id"), str("name"), str("input")
+		case str("tool_call_id", "tool_id") != "":
+			p.Kind, p.ToolCallID, p.ToolName = KindToolResult, str("tool_call_id", "tool_id"), str("name")
+			p.Text = str("content")
+			p.IsError, _ = f["is_error"].(bool)
+		case f["agent_id"] != nil:
+			p.Kind = KindTurnSummary
+		case f["reason"] != nil && f["time"] != nil:
+			p.Kind = KindFinish
+		default:
+			continue
+		}
+		parts = append(parts, p)
+	}
+	return parts
+}
@@ -0,0 +1,119 @@
+// Package prototrace describes the JSON protocol messages exchanged between
+// the daemon and an agent process, as kept for tracing, and renders them for
+// people reading a trace.
+package prototrace
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Directions of a Frame.
+const (
+	FromAgent = "in"
+	ToAgent   = "out"
+)
+
+// Frame is one JSON message exchanged between the daemon and an agent
+// process, with stored secrets redacted.
+type Frame struct {
+	Seq       uint64          `json:"seq"`
+	Time      time.Time       `json:"time"`
+	Direction string          `json:"direction"`
+	Type      string          `json:"type"`
+	Message   json.RawMessage `json:"message"`
+}
+
+// Arrow shows the direction of f as seen from the agent: ← for messages it
+// sent, → for messages sent to it.
+func (f Frame) Arrow() string {
+	if f.Direction == FromAgent {
+		return "←"
+	}
+	return "→"
+}
+
+func (f Frame) header() string {
+	return fmt.Sprintf("%s %s %s", f.Time.Local().Format("15:04:05.000"), f.Arrow(), f.Type)
+}
+
+// Line renders f on one line, its message shortened to width characters
+// when width is positive.
+func Line(f Frame, width int) string {
+	message := string(f.Message)
+	if runes := []rune(message); width > 0 && len(runes) > width {
+		message = string(runes[:width]) + "…"
+	}
+	return f.header() + " " + message
+}
+
+// Pretty renders f as a header line followed by the message's data as
+// indented JSON.
+func Pretty(f Frame) string {
+	var msg struct {
+		Data json.RawMessage `json:"data"`
+	}
+	body := []byte(f.Message)
+	if err := json.Unmarshal(f.Message, &msg); err == nil {
+		body = msg.Data
+	}
+	header := fmt.Sprintf("#%d %s", f.Seq, f.header())
+	if len(bytes.TrimSpace(body)) == 0 || string(body) == "null" {
+		return header
+	}
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, body, "  ", "  "); err != nil {
+		return header + "\n  " + string(body)
+	}
+	return header + "\n  " + indented.String()
+}
+
+// Matches reports whether f has one of types, or whether types is empty.
+func Matches(f Frame, types []string) bool {
+	if len(types) == 0 {
+		return true
+	}
+	for _, t := range types {
+		if strings.EqualFold(strings.TrimSpace(t), f.Type) {
+			return true
+		}
+	}
+	return false
+}
+
+// Write appends frames to w as JSON lines.
+func Write(w io.Writer, frames []Frame) error {
+	enc := json.NewEncoder(w)
+	for _, f := range frames {
+		if err := enc.Encode(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Read parses frames written by Write.
+func Read(r io.Reader) ([]Frame, error) {
+	var frames []Frame
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		var f Frame
+		if err := json.Unmarshal(text, &f); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		frames = append(frames, f)
+	}
+	return frames, scanner.Err()
+}
@@ -0,0 +1,257 @@
+// Package scrub replaces personal data in content sent to a model with
+// placeholders such as [EMAIL_1] and puts the original values back into the
+// model's output.
+package scrub
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+
+	"opperator/config"
+)
+
+// detector finds one kind of value. valid, when set, rejects matches that
+// only look right, such as digit runs failing the Luhn check.
+type detector struct {
+	label   string
+	pattern *regexp.Regexp
+	valid   func(string) bool
+}
+
+var builtin = map[string]detector{
+	config.ScrubEmail: {
+		label:   "EMAIL",
+		pattern: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`),
+	},
+	config.ScrubCard: {
+		label:   "CARD",
+		pattern: regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`),
+		valid:   luhn,
+	},
+	config.ScrubPhone: {
+		label:   "PHONE",
+		pattern: regexp.MustCompile(`(?:\+\d{1,3}[ .-]?(?:\(\d{1,4}\)[ .-]?)?\d[\d .-]{5,13}\d|\(\d{2,4}\)[ .-]?\d{3,4}[ .-]?\d{3,4})`),
+	},
+	config.ScrubIP: {
+		label:   "IP",
+		pattern: regexp.MustCompile(`\b(?:(?:25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)\.){3}(?:25[0-5]|2[0-4]\d|1\d\d|[1-9]?\d)\b`),
+	},
+}
+
+// builtinOrder runs detectors whose matches contain others' first: an email
+// address can contain digits that look like a phone number.
+var builtinOrder = []string{config.ScrubEmail, config.ScrubCard, config.ScrubIP, config.ScrubPhone}
+
+var (
+	placeholderPattern = regexp.MustCompile(`\[[A-Z0-9_]+_\d+\]`)
+	partialPlaceholder = regexp.MustCompile(`\[[A-Z0-9_]*$`)
+)
+
+// maxPlaceholderLength bounds how much streamed text is held back waiting
+// for the end of a placeholder.
+const maxPlaceholderLength = 48
+
+// Scrubber holds the detectors to run.
+type Scrubber struct {
+	detectors []detector
+}
+
+// New builds a scrubber from cfg. It returns nil when cfg is nil or
+// scrubbing is disabled.
+func New(cfg *config.ScrubConfig) (*Scrubber, error) {
+	if cfg == nil || !cfg.Enabled {
+		return nil, nil
+	}
+	s := &Scrubber{}
+	enabled := cfg.Detectors
+	if len(enabled) == 0 {
+		enabled = builtinOrder
+	}
+	for _, name := range builtinOrder {
+		for _, want := range enabled {
+			if want == name {
+				s.detectors = append(s.detectors, builtin[name])
+			}
+		}
+	}
+	for _, p := range cfg.Patterns {
+		re, err := regexp.Compile(p.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("pattern %s: %w", p.Name, err)
+		}
+		s.detectors = append(s.detectors, detector{label: label(p.Name), pattern: re})
+	}
+	return s, nil
+}
+
+var (
+	configuredOnce sync.Once
+	configured     *Scrubber
+)
+
+// Configured returns the scrubber described by scrubbing.yaml, loaded once
+// per process, or nil when scrubbing is off.
+func Configured() *Scrubber {
+	configuredOnce.Do(func() {
+		cfg, err := config.LoadScrubConfig()
+		if err == nil {
+			configured, err = New(cfg)
+		}
+		if err != nil {
+			log.Printf("[Scrub] Scrubbing disabled: %v", err)
+		}
+	})
+	return configured
+}
+
+func label(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(strings.TrimSpace(name)) {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+// luhn reports whether the digits of s pass the card number checksum.
+func luhn(s string) bool {
+	sum, double, digits := 0, false, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+		digits++
+	}
+	return digits >= 13 && sum%10 == 0
+}
+
+// Mapping scrubs the content of one model call and restores its output. The
+// same value always gets the same placeholder within a mapping.
+type Mapping struct {
+	scrubber     *Scrubber
+	values       map[string]string
+	placeholders map[string]string
+	counts       map[string]int
+	pending      map[string]string
+}
+
+// NewMapping starts the placeholders of one model call.
+func (s *Scrubber) NewMapping() *Mapping {
+	return &Mapping{
+		scrubber:     s,
+		values:       make(map[string]string),
+		placeholders: make(map[string]string),
+		counts:       make(map[string]int),
+		pending:      make(map[string]string),
+	}
+}
+
+// Scrub replaces detected values in text with placeholders.
+func (m *Mapping) Scrub(text string) string {
+	for _, d := range m.scrubber.detectors {
+		text = d.pattern.ReplaceAllStringFunc(text, func(match string) string {
+			if placeholderPattern.MatchString(match) || (d.valid != nil && !d.valid(match)) {
+				return match
+			}
+			return m.placeholder(d.label, match)
+		})
+	}
+	return text
+}
+
+func (m *Mapping) placeholder(label, value string) string {
+	if p, ok := m.placeholders[value]; ok {
+		return p
+	}
+	m.counts[label]++
+	p := fmt.Sprintf("[%s_%d]", label, m.counts[label])
+	m.placeholders[value] = p
+	m.values[p] = value
+	return p
+}
+
+// ScrubValue scrubs every string in a JSON-encodable value, such as a
+// request's input, and returns the scrubbed copy.
+func (m *Mapping) ScrubValue(v any) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var generic any
+	if err := dec.Decode(&generic); err != nil {
+		return nil, err
+	}
+	return m.scrubGeneric(generic), nil
+}
+
+func (m *Mapping) scrubGeneric(v any) any {
+	switch t := v.(type) {
+	case string:
+		return m.Scrub(t)
+	case map[string]any:
+		for k, x := range t {
+			t[k] = m.scrubGeneric(x)
+		}
+	case []any:
+		for i, x := range t {
+			t[i] = m.scrubGeneric(x)
+		}
+	}
+	return v
+}
+
+// Restore puts the original values back in place of placeholders.
+func (m *Mapping) Restore(text string) string {
+	if len(m.values) == 0 {
+		return text
+	}
+	return placeholderPattern.ReplaceAllStringFunc(text, func(p string) string {
+		if value, ok := m.values[p]; ok {
+			return value
+		}
+		return p
+	})
+}
+
+// RestoreDelta restores a streamed piece of the output at path. A
+// placeholder split across pieces is held back until it is complete.
+func (m *Mapping) RestoreDelta(path, delta string) string {
+	text := m.pending[path] + delta
+	delete(m.pending, path)
+	if loc := partialPlaceholder.FindStringIndex(text); loc != nil && loc[1]-loc[0] < maxPlaceholderLength && len(m.values) > 0 {
+		m.pending[path] = text[loc[0]:]
+		text = text[:loc[0]]
+	}
+	return m.Restore(text)
+}
+
+// Flush returns the text still held back by RestoreDelta, by path, once the
+// stream has ended.
+func (m *Mapping) Flush() map[string]string {
+	out := make(map[string]string, len(m.pending))
+	for path, text := range m.pending {
+		out[path] = m.Restore(text)
+	}
+	clear(m.pending)
+	return out
+}
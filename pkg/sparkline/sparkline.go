@@ -0,0 +1,33 @@
+// Package sparkline draws a series of numbers as a one-line chart of block
+// characters, for terminals and sidebars too small for a real chart.
+package sparkline
+
+import "strings"
+
+var blocks = []rune("▁▂▃▄▅▆▇█")
+
+// Render draws values scaled between their minimum and maximum, or from zero
+// when none is negative. A flat series draws as its lowest block, or its
+// highest when every value is positive.
+func Render(values []float64) string {
+	if len(values) == 0 {
+		return ""
+	}
+	lo, hi := values[0], values[0]
+	for _, v := range values {
+		lo = min(lo, v)
+		hi = max(hi, v)
+	}
+	if lo > 0 {
+		lo = 0
+	}
+	var b strings.Builder
+	for _, v := range values {
+		level := 0
+		if hi > lo {
+			level = int((v - lo) / (hi - lo) * float64(len(blocks)-1))
+		}
+		b.WriteRune(blocks[min(max(level, 0), len(blocks)-1)])
+	}
+	return b.String()
+}
@@ -0,0 +1,273 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	exportInterval  = 5 * time.Second
+	exportBatchSize = 256
+	maxQueuedSpans  = 4096
+)
+
+// otlpExporter batches finished spans and posts them to an OTLP/HTTP endpoint
+// using the JSON encoding.
+type otlpExporter struct {
+	endpoint    string
+	serviceName string
+	headers     map[string]string
+	client      *http.Client
+
+	mu      sync.Mutex
+	pending []*Span
+	dropped int
+
+	flush chan struct{}
+	stop  chan struct{}
+	done  chan struct{}
+	once  sync.Once
+}
+
+func newOTLPExporter(endpoint, serviceName string, headers map[string]string) *otlpExporter {
+	e := &otlpExporter{
+		endpoint:    endpoint,
+		serviceName: serviceName,
+		headers:     headers,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		flush:       make(chan struct{}, 1),
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	go e.loop()
+	return e
+}
+
+func (e *otlpExporter) enqueue(s *Span) {
+	e.mu.Lock()
+	if len(e.pending) >= maxQueuedSpans {
+		e.dropped++
+		e.mu.Unlock()
+		return
+	}
+	e.pending = append(e.pending, s)
+	full := len(e.pending) >= exportBatchSize
+	e.mu.Unlock()
+
+	if full {
+		select {
+		case e.flush <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (e *otlpExporter) loop() {
+	defer close(e.done)
+	ticker := time.NewTicker(exportInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-e.stop:
+			e.export(context.Background())
+			return
+		case <-ticker.C:
+		case <-e.flush:
+		}
+		e.export(context.Background())
+	}
+}
+
+// shutdown exports any remaining spans and stops the background loop.
+func (e *otlpExporter) shutdown(ctx context.Context) error {
+	e.once.Do(func() { close(e.stop) })
+	select {
+	case <-e.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (e *otlpExporter) export(ctx context.Context) {
+	for {
+		e.mu.Lock()
+		if len(e.pending) == 0 {
+			dropped := e.dropped
+			e.dropped = 0
+			e.mu.Unlock()
+			if dropped > 0 {
+				log.Printf("[Tracing] Dropped %d spans: export queue full", dropped)
+			}
+			return
+		}
+		n := min(len(e.pending), exportBatchSize)
+		batch := e.pending[:n:n]
+		e.pending = e.pending[n:]
+		e.mu.Unlock()
+
+		if err := e.post(ctx, batch); err != nil {
+			log.Printf("[Tracing] Failed to export %d spans: %v", len(batch), err)
+			return
+		}
+	}
+}
+
+func (e *otlpExporter) post(ctx context.Context, batch []*Span) error {
+	body, err := json.Marshal(e.encode(batch))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range e.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("collector returned %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// OTLP JSON payload types. Only the fields this package produces are modelled.
+
+type otlpPayload struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+}
+
+const (
+	spanKindInternal = 1
+	statusUnset      = 0
+	statusError      = 2
+)
+
+func (e *otlpExporter) encode(batch []*Span) otlpPayload {
+	spans := make([]otlpSpan, 0, len(batch))
+	for _, s := range batch {
+		s.mu.Lock()
+		span := otlpSpan{
+			TraceID:           hex.EncodeToString(s.ctx.TraceID[:]),
+			SpanID:            hex.EncodeToString(s.ctx.SpanID[:]),
+			Name:              s.name,
+			Kind:              spanKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+			Attributes:        encodeAttributes(s.attrs),
+			Status:            otlpStatus{Code: statusUnset},
+		}
+		if s.parent != [8]byte{} {
+			span.ParentSpanID = hex.EncodeToString(s.parent[:])
+		}
+		if s.err != "" {
+			span.Status = otlpStatus{Code: statusError, Message: s.err}
+		}
+		s.mu.Unlock()
+		spans = append(spans, span)
+	}
+
+	return otlpPayload{ResourceSpans: []otlpResourceSpans{{
+		Resource: otlpResource{Attributes: encodeAttributes(map[string]any{
+			"service.name": e.serviceName,
+		})},
+		ScopeSpans: []otlpScopeSpans{{
+			Scope: otlpScope{Name: "opperator"},
+			Spans: spans,
+		}},
+	}}}
+}
+
+func encodeAttributes(attrs map[string]any) []otlpKeyValue {
+	out := make([]otlpKeyValue, 0, len(attrs))
+	for key, raw := range attrs {
+		var v otlpValue
+		switch val := raw.(type) {
+		case string:
+			v.StringValue = &val
+		case bool:
+			v.BoolValue = &val
+		case int:
+			s := strconv.FormatInt(int64(val), 10)
+			v.IntValue = &s
+		case int64:
+			s := strconv.FormatInt(val, 10)
+			v.IntValue = &s
+		case float64:
+			v.DoubleValue = &val
+		case time.Duration:
+			s := strconv.FormatInt(val.Milliseconds(), 10)
+			v.IntValue = &s
+		default:
+			s := fmt.Sprint(val)
+			v.StringValue = &s
+		}
+		out = append(out, otlpKeyValue{Key: key, Value: v})
+	}
+	return out
+}
@@ -0,0 +1,231 @@
+// Package tracing records OpenTelemetry-compatible spans and exports them over
+// OTLP/HTTP when an endpoint is configured. Trace context crosses process
+// boundaries as a W3C traceparent string, so a single request can be followed
+// from the CLI through the daemon into an agent and out to Opper.
+//
+// Tracing is disabled unless OTEL_EXPORTER_OTLP_ENDPOINT or
+// OTEL_EXPORTER_OTLP_TRACES_ENDPOINT is set; spans are then cheap no-ops that
+// still carry an incoming trace context along.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	mu       sync.RWMutex
+	exporter *otlpExporter
+)
+
+// Init enables tracing for serviceName when an OTLP endpoint is configured in
+// the environment. The returned function flushes pending spans and must be
+// called before the process exits.
+func Init(serviceName string) func(context.Context) error {
+	endpoint := strings.TrimSpace(os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"))
+	if endpoint == "" {
+		base := strings.TrimSpace(os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"))
+		if base == "" {
+			return func(context.Context) error { return nil }
+		}
+		endpoint = strings.TrimRight(base, "/") + "/v1/traces"
+	}
+	if name := strings.TrimSpace(os.Getenv("OTEL_SERVICE_NAME")); name != "" {
+		serviceName = name
+	}
+
+	exp := newOTLPExporter(endpoint, serviceName, parseHeaders(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS")))
+	mu.Lock()
+	exporter = exp
+	mu.Unlock()
+
+	return func(ctx context.Context) error {
+		mu.Lock()
+		if exporter == exp {
+			exporter = nil
+		}
+		mu.Unlock()
+		return exp.shutdown(ctx)
+	}
+}
+
+// Enabled reports whether spans are being exported.
+func Enabled() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return exporter != nil
+}
+
+// SpanContext identifies a span within a trace.
+type SpanContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+}
+
+// IsValid reports whether both IDs are set.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != [16]byte{} && sc.SpanID != [8]byte{}
+}
+
+// Span is a timed operation within a trace. A nil *Span is valid and ignores
+// all calls, so callers never need to check whether tracing is enabled.
+type Span struct {
+	name     string
+	ctx      SpanContext
+	parent   [8]byte
+	start    time.Time
+	end      time.Time
+	attrs    map[string]any
+	err      string
+	mu       sync.Mutex
+	ended    bool
+	exporter *otlpExporter
+}
+
+type spanKey struct{}
+type remoteKey struct{}
+
+// Start begins a span named name as a child of the span or remote trace
+// context in ctx. Attributes are given as alternating key/value pairs.
+func Start(ctx context.Context, name string, attrs ...any) (context.Context, *Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	mu.RLock()
+	exp := exporter
+	mu.RUnlock()
+	if exp == nil {
+		return ctx, nil
+	}
+
+	span := &Span{
+		name:     name,
+		start:    time.Now(),
+		attrs:    make(map[string]any),
+		exporter: exp,
+	}
+	if parent := spanContextFrom(ctx); parent.IsValid() {
+		span.ctx.TraceID = parent.TraceID
+		span.parent = parent.SpanID
+	} else {
+		_, _ = rand.Read(span.ctx.TraceID[:])
+	}
+	_, _ = rand.Read(span.ctx.SpanID[:])
+	span.SetAttributes(attrs...)
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// SetAttributes records alternating key/value pairs on the span.
+func (s *Span) SetAttributes(kv ...any) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := 0; i+1 < len(kv); i += 2 {
+		key, ok := kv[i].(string)
+		if !ok || key == "" {
+			continue
+		}
+		s.attrs[key] = kv[i+1]
+	}
+}
+
+// RecordError marks the span as failed. A nil err is ignored.
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	s.err = err.Error()
+	s.mu.Unlock()
+}
+
+// End finishes the span and queues it for export. Only the first call counts.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	s.mu.Unlock()
+	s.exporter.enqueue(s)
+}
+
+// TraceParent returns the W3C traceparent for the current span in ctx, or
+// for the remote context it carries, or "" when there is none.
+func TraceParent(ctx context.Context) string {
+	sc := spanContextFrom(ctx)
+	if !sc.IsValid() {
+		return ""
+	}
+	return fmt.Sprintf("00-%s-%s-01", hex.EncodeToString(sc.TraceID[:]), hex.EncodeToString(sc.SpanID[:]))
+}
+
+// WithTraceParent returns ctx carrying the remote trace context in header so
+// spans started from it join that trace. Malformed headers are ignored.
+func WithTraceParent(ctx context.Context, header string) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	sc, ok := parseTraceParent(header)
+	if !ok {
+		return ctx
+	}
+	return context.WithValue(ctx, remoteKey{}, sc)
+}
+
+func spanContextFrom(ctx context.Context) SpanContext {
+	if ctx == nil {
+		return SpanContext{}
+	}
+	if span, ok := ctx.Value(spanKey{}).(*Span); ok && span != nil {
+		return span.ctx
+	}
+	if sc, ok := ctx.Value(remoteKey{}).(SpanContext); ok {
+		return sc
+	}
+	return SpanContext{}
+}
+
+func parseTraceParent(header string) (SpanContext, bool) {
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) != 4 || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return SpanContext{}, false
+	}
+	var sc SpanContext
+	if _, err := hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil {
+		return SpanContext{}, false
+	}
+	if _, err := hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil {
+		return SpanContext{}, false
+	}
+	return sc, sc.IsValid()
+}
+
+// parseHeaders reads the OTEL_EXPORTER_OTLP_HEADERS key=value,key=value form.
+func parseHeaders(raw string) map[string]string {
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(raw, ",") {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		if key != "" {
+			headers[key] = strings.TrimSpace(value)
+		}
+	}
+	return headers
+}
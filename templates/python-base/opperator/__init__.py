@@ -6,12 +6,13 @@ A framework for building managed processes that integrate with Opperator process
 from .agent import OpperatorAgent
 from .protocol import (
     Message, MessageType, LogLevel,
-    ReadyMessage, LogMessage,
-    CommandMessage, ResponseMessage, ErrorMessage,
+    ReadyMessage, LogMessage, HandshakeMessage, Capability, PROTOCOL_VERSION,
+    CommandMessage, ResponseMessage, ErrorMessage, FileOutput,
     CommandDefinition, CommandArgument, CommandExposure, SlashCommandScope
 )
 from .lifecycle import LifecycleManager
 from .secrets import get_secret, SecretError
+from .kv import KVStore, KVError
 from .cli import (
     ExecClient,
     ExecResult,
@@ -36,9 +37,13 @@ __all__ = [
     'LogLevel',
     'ReadyMessage',
     'LogMessage',
+    'HandshakeMessage',
+    'Capability',
+    'PROTOCOL_VERSION',
     'CommandMessage',
     'ResponseMessage',
     'ErrorMessage',
+    'FileOutput',
     'CommandDefinition',
     'CommandArgument',
     'CommandExposure',
@@ -46,6 +51,8 @@ __all__ = [
     'LifecycleManager',
     'get_secret',
     'SecretError',
+    'KVStore',
+    'KVError',
     'ExecClient',
     'ExecResult',
     'ExecEvent',
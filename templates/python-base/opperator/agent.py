@@ -3,11 +3,14 @@
 import copy
 import json
 import os
+import select
 import socket
 import sys
 import tempfile
 import threading
+import time
 import traceback
+import uuid
 from abc import ABC, abstractmethod
 from concurrent.futures import ThreadPoolExecutor
 from typing import Any, Callable, Dict, Iterable, List, Optional, Sequence, Set, Union
@@ -19,12 +22,15 @@ from .protocol import (
     Message,
     CommandMessage,
     LifecycleEventMessage,
+    HandshakeMessage,
     CommandDefinition,
     CommandArgument,
     CommandExposure,
     SlashCommandScope,
+    FileOutput,
 )
 from . import secrets as secret_client
+from .kv import KVError, KVStore
 from .lifecycle import LifecycleManager
 from . import cli
 
@@ -89,7 +95,10 @@ class OpperatorAgent(ABC):
         self._message_thread: Optional[threading.Thread] = None
         self._stop_reading = threading.Event()
         self._command_state = threading.local()
+        self._cancel_events: Dict[str, threading.Event] = {}
+        self._cancel_lock = threading.Lock()
         self._invocation_dir: Optional[str] = None
+        self._negotiated_capabilities: Optional[Set[str]] = None
         self._max_async_workers = (
             max_async_workers if (max_async_workers or 0) > 0 else None
         )
@@ -99,9 +108,21 @@ class OpperatorAgent(ABC):
         # Sidebar sections
         self._sidebar_sections: Dict[str, Dict[str, Any]] = {}
 
+        # Event triggers
+        self._triggers: Dict[str, Dict[str, Any]] = {}
+
         # Agent invocation
         self._exec_client: Optional[cli.ExecClient] = None
 
+        # Durable state kept by the daemon
+        self._kv_pending: Dict[str, Dict[str, Any]] = {}
+        self._kv_lock = threading.Lock()
+        self._deferred_messages: List[Message] = []
+        # stdin is read through the fd so a pending read can time out;
+        # bytes past the last complete line wait here
+        self._stdin_buffer = b""
+        self.kv = KVStore(self._kv_request)
+
         # Setup lifecycle handlers
         self.lifecycle.on_shutdown(self._handle_shutdown)
         self.lifecycle.on_reload(self._handle_reload)
@@ -111,6 +132,24 @@ class OpperatorAgent(ABC):
         """Send a log message"""
         Protocol.send_log(level, message, fields if fields else None)
 
+    def increment(self, name: str, value: float = 1) -> None:
+        """Add value to the counter name, e.g. items_processed.
+
+        The daemon keeps a per-minute history of the agent's metrics and
+        shows them as sparklines in the sidebar.
+        """
+        name = str(name or "").strip()
+        if not name:
+            raise ValueError("metric name cannot be empty")
+        Protocol.send_metric(name, value, "counter")
+
+    def gauge(self, name: str, value: float) -> None:
+        """Set the gauge name to value, e.g. queue_size."""
+        name = str(name or "").strip()
+        if not name:
+            raise ValueError("metric name cannot be empty")
+        Protocol.send_metric(name, value, "gauge")
+
     def register_command(
         self,
         name: str,
@@ -127,8 +166,14 @@ class OpperatorAgent(ABC):
         async_enabled: bool = False,
         progress_label: Optional[str] = None,
         hidden: bool = False,
+        working_dir: Optional[str] = None,
     ):
-        """Register a command handler."""
+        """Register a command handler.
+
+        ``working_dir`` is the directory the command runs in unless the user
+        picks one with ``--cwd``; relative paths are resolved against the
+        agent's process root.
+        """
 
         if not callable(handler):
             raise TypeError("handler must be callable")
@@ -149,6 +194,7 @@ class OpperatorAgent(ABC):
             async_enabled=async_enabled,
             progress_label=progress_label,
             hidden=hidden,
+            working_dir=working_dir,
         )
 
         normalized_definition = definition.normalized()
@@ -209,7 +255,14 @@ class OpperatorAgent(ABC):
         self._description = (description or "").strip()
         Protocol.send_agent_description(self._description)
 
-    def register_section(self, section_id: str, title: str, content: str, collapsed: bool = False) -> None:
+    def register_section(
+        self,
+        section_id: str,
+        title: str,
+        content: str,
+        collapsed: bool = False,
+        actions: Optional[List[Dict[str, Any]]] = None,
+    ) -> None:
         """Register or update a custom sidebar section.
 
         Args:
@@ -217,12 +270,21 @@ class OpperatorAgent(ABC):
             title: Display title for the section header
             content: Section content with optional XML-like markup for colors/styling
             collapsed: Whether the section should start collapsed (default: False)
+            actions: Buttons and inputs shown under the content. Each is a dict
+                with ``label`` and ``command``; a button passes ``args`` to the
+                command, while ``type: "input"`` asks the user for text and
+                passes it as the argument named by ``arg``.
 
         Example:
             self.register_section(
                 "metrics",
                 "System Metrics",
-                '<b>CPU:</b> <c fg="green">23%</c>\\n<b>Memory:</b> <c fg="yellow">67%</c>'
+                '<b>CPU:</b> <c fg="green">23%</c>\\n<b>Memory:</b> <c fg="yellow">67%</c>',
+                actions=[
+                    {"label": "Refresh", "command": "refresh_metrics"},
+                    {"label": "Filter", "type": "input", "command": "filter_metrics",
+                     "arg": "pattern", "placeholder": "process name"},
+                ],
             )
         """
         section_id = str(section_id or "").strip()
@@ -236,10 +298,69 @@ class OpperatorAgent(ABC):
         self._sidebar_sections[section_id] = {
             'title': title,
             'content': str(content),
-            'collapsed': bool(collapsed)
+            'collapsed': bool(collapsed),
+            'actions': list(actions or []),
+            'type': None,
+            'format': None
         }
 
-        Protocol.send_sidebar_section(section_id, title, content, collapsed)
+        Protocol.send_sidebar_section(section_id, title, content, collapsed, actions)
+
+    def register_dashboard(
+        self,
+        dashboard_id: str,
+        title: str,
+        content: str,
+        format: str = "markdown",
+    ) -> None:
+        """Publish a dashboard the daemon serves as a web page.
+
+        Dashboards are not shown in the sidebar. When ``dashboards.listen`` is
+        set in daemon.yaml they are served at
+        ``/dashboards/<agent>/<dashboard_id>`` to clients presenting a daemon
+        auth token. Call it again, or ``update_section``, to refresh the page.
+
+        Args:
+            dashboard_id: Unique identifier, shared with sidebar sections
+            title: Page title
+            content: Markdown (tables supported) or, with ``format="html"``,
+                an HTML fragment or complete document that may load scripts
+                to draw charts
+            format: ``"markdown"`` (default) or ``"html"``
+
+        Example:
+            self.register_dashboard(
+                "latency",
+                "Latency",
+                "| Endpoint | p50 | p99 |\n|---|---|---|\n| /api | 12ms | 80ms |",
+            )
+        """
+        format = str(format or "markdown").strip().lower()
+        if format not in ("markdown", "html"):
+            raise ValueError("format must be 'markdown' or 'html'")
+
+        section_id = str(dashboard_id or "").strip()
+        if not section_id:
+            raise ValueError("dashboard_id cannot be empty")
+
+        title = str(title or "").strip() or section_id
+
+        self._sidebar_sections[section_id] = {
+            'title': title,
+            'content': str(content),
+            'collapsed': False,
+            'actions': [],
+            'type': 'dashboard',
+            'format': format
+        }
+
+        Protocol.send_sidebar_section(
+            section_id,
+            title,
+            content,
+            section_type='dashboard',
+            format=format
+        )
 
     def update_section(self, section_id: str, content: str) -> None:
         """Update the content of an existing sidebar section.
@@ -269,7 +390,10 @@ class OpperatorAgent(ABC):
             section_id,
             section['title'],
             content,
-            section['collapsed']
+            section['collapsed'],
+            section['actions'],
+            section_type=section.get('type'),
+            format=section.get('format')
         )
 
     def unregister_section(self, section_id: str) -> bool:
@@ -296,6 +420,65 @@ class OpperatorAgent(ABC):
         Protocol.send_sidebar_section_removal(section_id)
         return True
 
+    def register_trigger(self, name: str, type: str, command: str, **options: Any) -> None:
+        """Register or replace an event trigger that invokes one of this agent's commands.
+
+        The daemon watches the source and queues ``command`` as an async task
+        for every event, passing ``trigger`` (the trigger name) and ``event``
+        (the event payload) alongside any ``args``.
+
+        Args:
+            name: Unique trigger name within this agent
+            type: One of ``file``, ``poll``, ``rss``, ``imap``, ``sqs`` or ``redis``
+            command: Name of the command to invoke
+            **options: Source settings such as ``path``, ``url``, ``interval``,
+                ``pattern``, ``events``, ``queue_url`` or ``args``
+
+        Example:
+            self.register_trigger(
+                "inbox",
+                "file",
+                "process_file",
+                path="~/Inbox",
+                pattern="*.pdf",
+                events=["create"],
+            )
+        """
+        name = str(name or "").strip()
+        if not name:
+            raise ValueError("trigger name cannot be empty")
+
+        command_name = self._normalize_command_name(command)
+        descriptor: Dict[str, Any] = {
+            'name': name,
+            'type': str(type or "").strip().lower(),
+            'command': command_name,
+        }
+        for key, value in options.items():
+            if value is not None:
+                descriptor[key] = value
+
+        self._triggers[name] = descriptor
+        Protocol.send_trigger_registry(self._triggers.values())
+
+    def unregister_trigger(self, name: str) -> bool:
+        """Remove a previously registered trigger.
+
+        Returns:
+            True if the trigger was removed, False if it didn't exist
+        """
+        name = str(name or "").strip()
+        if name not in self._triggers:
+            self.log(
+                LogLevel.WARNING,
+                f"Attempted to unregister non-existent trigger: {name}"
+            )
+            return False
+
+        del self._triggers[name]
+        Protocol.send_trigger_registry(self._triggers.values())
+        return True
+
     def _parse_exposures(
         self, expose_as: Optional[Sequence[CommandExposure]]
     ) -> Optional[Iterable[CommandExposure]]:
@@ -348,11 +531,150 @@ class OpperatorAgent(ABC):
             command_id, text=text, metadata=metadata, status=status, progress=progress
         )
 
+    def is_cancelled(self) -> bool:
+        """Return whether the manager cancelled the currently executing command.
+
+        Long-running async commands should poll this and stop early, since the
+        caller has already given up waiting for the result.
+        """
+
+        command_id = getattr(self._command_state, "command_id", None)
+        if not command_id:
+            return False
+        with self._cancel_lock:
+            event = self._cancel_events.get(command_id)
+        return event is not None and event.is_set()
+
+    def output_file(
+        self,
+        path: str,
+        *,
+        mime_type: Optional[str] = None,
+        name: Optional[str] = None,
+    ) -> None:
+        """Report a file the currently executing command wrote as output.
+
+        The daemon keeps a copy once the command succeeds, and
+        ``op agent command --output-dir`` saves it locally. The MIME type is
+        guessed from the name when not given.
+        """
+
+        files = getattr(self._command_state, "files", None)
+        if files is None:
+            raise RuntimeError("output_file can only be called while a command runs")
+        absolute = os.path.abspath(os.path.expanduser(str(path)))
+        if not os.path.isfile(absolute):
+            raise FileNotFoundError(absolute)
+        files.append(FileOutput(path=absolute, mime_type=mime_type, name=name))
+
+    def get_trace_parent(self) -> Optional[str]:
+        """Return the W3C traceparent of the currently executing command.
+
+        Pass it as the ``traceparent`` header on outgoing requests (such as
+        Opper calls) so they join the trace of the request that invoked the
+        command. Returns ``None`` when the caller is not tracing.
+        """
+
+        return getattr(self._command_state, "traceparent", None)
+
+    def _cancel_command(self, command_id: str) -> None:
+        if not command_id:
+            return
+        with self._cancel_lock:
+            event = self._cancel_events.get(command_id)
+        if event is not None:
+            event.set()
+
     def get_secret(self, name: str, *, timeout: float = 5.0) -> str:
         """Fetch a named secret from the Opperator daemon."""
 
         return secret_client.get_secret(name, timeout=timeout)
 
+    def blackboard(self, namespace: str) -> KVStore:
+        """Key-value store shared with other agents under *namespace*.
+
+        The namespace must be declared under blackboards in daemon.yaml with
+        this agent among its readers or writers.
+        """
+
+        return KVStore(self._kv_request, namespace=namespace)
+
+    def _kv_request(self, data: Dict[str, Any], timeout: float) -> Dict[str, Any]:
+        """Send a kv_request message and wait for the daemon's kv_response."""
+
+        request_id = uuid.uuid4().hex
+        pending: Dict[str, Any] = {"event": threading.Event(), "response": None}
+        with self._kv_lock:
+            self._kv_pending[request_id] = pending
+        try:
+            Protocol.send_message(MessageType.KV_REQUEST, {**data, "id": request_id})
+            if threading.current_thread() is self._message_thread:
+                # Synchronous commands run on the reader thread, which would
+                # otherwise never see the response; read stdin here and
+                # leave other messages for the reader loop
+                self._pump_until(pending["event"], timeout)
+            elif not pending["event"].wait(timeout):
+                raise KVError(f"daemon did not answer {data.get('op')} within {timeout:g}s")
+        finally:
+            with self._kv_lock:
+                self._kv_pending.pop(request_id, None)
+        return pending["response"] or {}
+
+    def _pump_until(self, event: threading.Event, timeout: float) -> None:
+        deadline = time.monotonic() + timeout
+        while not event.is_set():
+            remaining = deadline - time.monotonic()
+            if remaining <= 0:
+                raise KVError(f"daemon did not answer within {timeout:g}s")
+            line = self._read_stdin_line(remaining)
+            if line is None:
+                continue
+            if not line:
+                raise KVError("manager closed the protocol pipe")
+            line = line.strip()
+            if not line:
+                continue
+            try:
+                msg = Message.from_json(line)
+            except json.JSONDecodeError:
+                continue
+            if msg.type == MessageType.KV_RESPONSE:
+                self._resolve_kv(msg.data or {})
+            else:
+                self._deferred_messages.append(msg)
+
+    def _read_stdin_line(self, timeout: Optional[float] = None) -> Optional[str]:
+        """Read one line from the manager.
+
+        Returns an empty string once stdin is closed and ``None`` when no
+        complete line arrived within *timeout* seconds.
+        """
+
+        fd = sys.stdin.fileno()
+        deadline = None if timeout is None else time.monotonic() + timeout
+        while b"\n" not in self._stdin_buffer:
+            if deadline is not None:
+                remaining = deadline - time.monotonic()
+                if remaining <= 0:
+                    return None
+                ready, _, _ = select.select([fd], [], [], remaining)
+                if not ready:
+                    return None
+            chunk = os.read(fd, 65536)
+            if not chunk:
+                line, self._stdin_buffer = self._stdin_buffer, b""
+                return line.decode("utf-8", errors="replace")
+            self._stdin_buffer += chunk
+        line, _, self._stdin_buffer = self._stdin_buffer.partition(b"\n")
+        return line.decode("utf-8", errors="replace") + "\n"
+
+    def _resolve_kv(self, data: Dict[str, Any]) -> None:
+        with self._kv_lock:
+            pending = self._kv_pending.get(str(data.get("id") or ""))
+        if pending is not None:
+            pending["response"] = data
+            pending["event"].set()
+
     def _get_exec_client(self) -> cli.ExecClient:
         """Get or create exec client (lazy initialization)."""
         if self._exec_client is None:
@@ -470,7 +792,7 @@ class OpperatorAgent(ABC):
         def read_loop():
             while not self._stop_reading.is_set():
                 try:
-                    line = sys.stdin.readline()
+                    line = self._read_stdin_line()
                     if not line:
                         break
 
@@ -481,6 +803,8 @@ class OpperatorAgent(ABC):
                     try:
                         msg = Message.from_json(line)
                         self._handle_message(msg)
+                        while self._deferred_messages:
+                            self._handle_message(self._deferred_messages.pop(0))
                     except json.JSONDecodeError:
                         # Ignore non-JSON output
                         continue
@@ -500,6 +824,24 @@ class OpperatorAgent(ABC):
         elif msg.type == MessageType.LIFECYCLE_EVENT and msg.data:
             event = LifecycleEventMessage.from_dict(msg.data)
             self._handle_lifecycle_event(event)
+        elif msg.type == MessageType.CANCEL and msg.data:
+            self._cancel_command(str(msg.data.get("command_id") or ""))
+        elif msg.type == MessageType.KV_RESPONSE and msg.data:
+            self._resolve_kv(msg.data)
+        elif msg.type == MessageType.HANDSHAKE and msg.data:
+            handshake = HandshakeMessage.from_dict(msg.data)
+            self._negotiated_capabilities = set(handshake.capabilities)
+
+    def supports(self, capability: str) -> bool:
+        """Return whether the manager negotiated ``capability``.
+
+        Before the handshake arrives every capability is assumed available.
+        """
+        capabilities = self._negotiated_capabilities
+        if capabilities is None:
+            return True
+        value = capability.value if hasattr(capability, "value") else capability
+        return value in capabilities
 
     def _handle_command(self, cmd: CommandMessage):
         # Safely retrieve invocation_dir (where user ran 'op' from)
@@ -508,7 +850,7 @@ class OpperatorAgent(ABC):
             self._invocation_dir = invocation_dir
 
         if cmd.command == "__list_commands":
-            self._set_command_context(cmd.id, invocation_dir)
+            self._set_command_context(cmd.id, invocation_dir, cmd.traceparent)
             try:
                 commands = [
                     definition.to_dict() for definition in self._command_definitions()
@@ -551,6 +893,9 @@ class OpperatorAgent(ABC):
         prepared_args: Dict[str, Any],
         invocation_dir: Optional[str],
     ) -> None:
+        if cmd.id:
+            with self._cancel_lock:
+                self._cancel_events[cmd.id] = threading.Event()
         try:
             executor = self._ensure_async_executor()
             executor.submit(
@@ -571,26 +916,42 @@ class OpperatorAgent(ABC):
         prepared_args: Dict[str, Any],
         invocation_dir: Optional[str],
     ) -> None:
-        self._set_command_context(cmd.id, invocation_dir)
+        self._set_command_context(cmd.id, invocation_dir, cmd.traceparent)
         try:
+            if self.is_cancelled():
+                # Cancelled while queued; the caller is no longer waiting
+                return
             result = handler(prepared_args)
         except Exception as exc:  # pragma: no cover - handler-specific failures
             self.log(LogLevel.ERROR, f"Command '{cmd.command}' failed", error=str(exc))
             Protocol.send_response(success=False, command_id=cmd.id, error=str(exc))
         else:
-            Protocol.send_response(success=True, command_id=cmd.id, result=result)
+            files = getattr(self._command_state, "files", None) or None
+            Protocol.send_response(
+                success=True, command_id=cmd.id, result=result, files=files
+            )
         finally:
             self._clear_command_context()
+            if cmd.id:
+                with self._cancel_lock:
+                    self._cancel_events.pop(cmd.id, None)
 
     def _set_command_context(
-        self, command_id: Optional[str], invocation_dir: Optional[str]
+        self,
+        command_id: Optional[str],
+        invocation_dir: Optional[str],
+        traceparent: Optional[str] = None,
     ) -> None:
         setattr(self._command_state, "command_id", command_id)
         setattr(self._command_state, "invocation_directory", invocation_dir)
+        setattr(self._command_state, "traceparent", traceparent)
+        setattr(self._command_state, "files", [])
 
     def _clear_command_context(self) -> None:
         setattr(self._command_state, "command_id", None)
         setattr(self._command_state, "invocation_directory", None)
+        setattr(self._command_state, "traceparent", None)
+        setattr(self._command_state, "files", None)
 
     def _ensure_async_executor(self) -> ThreadPoolExecutor:
         if self._async_executor is not None:
@@ -897,6 +1258,8 @@ class OpperatorAgent(ABC):
                     data.get("old_path", ""),
                     new_path
                 )
+            elif event_type == "secret_rotated":
+                self.on_secret_rotated(data.get("name", ""))
         except Exception as exc:
             self.log(LogLevel.ERROR, f"Lifecycle event handler failed: {exc}")
 
@@ -1056,3 +1419,16 @@ class OpperatorAgent(ABC):
             new_path: New invocation directory
         """
         pass
+
+    def on_secret_rotated(self, name: str):
+        """Called when a secret this agent fetched with get_secret() was given a new value.
+
+        Fetch it again and reconnect whatever uses it; the old value may stop
+        working at any time. For OPPER_API_KEY, the OPPER_API_KEY environment
+        variable still holds the old key until the agent restarts, but
+        get_secret("OPPER_API_KEY") returns the new one.
+
+        Args:
+            name: Name of the rotated secret
+        """
+        pass
@@ -0,0 +1,99 @@
+"""Durable key-value state kept for the agent by the Opperator daemon."""
+
+from __future__ import annotations
+
+import json
+from typing import Any, Callable, Dict, Optional
+
+
+class KVError(RuntimeError):
+    """Raised when the daemon cannot read or write a key."""
+
+
+# Sends a kv_request message and returns the matching kv_response data
+KVTransport = Callable[[Dict[str, Any], float], Dict[str, Any]]
+
+
+class KVStore:
+    """Key-value state of one agent, persisted by the daemon.
+
+    Values are anything JSON can encode. State survives agent restarts and
+    moves to another daemon, so agents do not need files of their own for it.
+    Requests travel as kv_request messages on the agent's own protocol pipe,
+    so the daemon always knows which agent is asking.
+
+    With *namespace* set the store is a blackboard shared with other agents
+    instead. Blackboards are declared in daemon.yaml, which lists the agents
+    that may read and write each one.
+    """
+
+    def __init__(
+        self,
+        transport: KVTransport,
+        *,
+        namespace: Optional[str] = None,
+        timeout: float = 5.0,
+    ):
+        self._transport = transport
+        self._namespace = namespace
+        self._timeout = timeout
+
+    def get(self, key: str, default: Any = None) -> Any:
+        """Return the value of *key*, or *default* when it is not set."""
+
+        response = self._request("get", key=key, missing_ok=True)
+        if response is None:
+            return default
+        entries = response.get("entries") or []
+        return entries[0].get("value") if entries else default
+
+    def set(self, key: str, value: Any) -> None:
+        """Store *value* under *key*."""
+
+        try:
+            encoded = json.loads(json.dumps(value))
+        except (TypeError, ValueError) as exc:
+            raise KVError(f"value of {key!r} is not JSON serialisable: {exc}") from exc
+        self._request("set", key=key, value=encoded)
+
+    def delete(self, key: str) -> bool:
+        """Remove *key*. Returns whether it was set."""
+
+        return self._request("delete", key=key, missing_ok=True) is not None
+
+    def list(self, prefix: str = "") -> Dict[str, Any]:
+        """Return the keys starting with *prefix* and their values."""
+
+        response = self._request("list", prefix=prefix) or {}
+        return {entry["key"]: entry.get("value") for entry in response.get("entries") or []}
+
+    def _request(
+        self,
+        op: str,
+        *,
+        key: Optional[str] = None,
+        value: Any = None,
+        prefix: str = "",
+        missing_ok: bool = False,
+    ) -> Optional[Dict[str, Any]]:
+        payload: Dict[str, Any] = {"op": op}
+        if self._namespace:
+            payload["namespace"] = self._namespace
+        if key is not None:
+            if not key:
+                raise ValueError("key cannot be empty")
+            payload["key"] = key
+        if op == "set":
+            payload["value"] = value
+        if prefix:
+            payload["prefix"] = prefix
+
+        response = self._transport(payload, self._timeout)
+        if not response.get("success", False):
+            if missing_ok and response.get("error_code") == "not_found":
+                return None
+            raise KVError(response.get("error") or f"{op} failed")
+        return response
+
+
+__all__ = ["KVStore", "KVError"]
@@ -13,6 +13,7 @@ class MessageType(str, Enum):
     """Message types for process communication"""
     # Lifecycle messages
     READY = "ready"
+    HANDSHAKE = "handshake"
 
     # Logging messages
     LOG = "log"
@@ -27,15 +28,42 @@ class MessageType(str, Enum):
     SYSTEM_PROMPT = "system_prompt"
     AGENT_DESCRIPTION = "agent_description"
     COMMAND_PROGRESS = "command_progress"
+    CANCEL = "cancel"
 
     # Sidebar messages
     SIDEBAR_SECTION = "sidebar_section"
     SIDEBAR_SECTION_REMOVAL = "sidebar_section_removal"
 
+    # Trigger messages
+    TRIGGER_REGISTRY = "trigger_registry"
+
+    # Metric messages
+    METRIC = "metric"
+
+    # Key-value messages
+    KV_REQUEST = "kv_request"
+    KV_RESPONSE = "kv_response"
+
     # Error messages
     ERROR = "error"
 
 
+# Newest agent protocol revision this SDK speaks
+PROTOCOL_VERSION = 1
+
+
+class Capability(str, Enum):
+    """Optional protocol features declared in the ready message"""
+    ASYNC_COMMANDS = "async_commands"
+    LIFECYCLE_EVENTS = "lifecycle_events"
+    CUSTOM_SECTIONS = "custom_sections"
+    STRUCTURED_LOGS = "structured_logs"
+    CANCELLATION = "cancellation"
+
+
+SUPPORTED_CAPABILITIES = [capability.value for capability in Capability]
+
+
 class LogLevel(str, Enum):
     """Log severity levels"""
     DEBUG = "debug"
@@ -76,11 +104,27 @@ class ReadyMessage:
     """Sent when process is ready"""
     pid: int
     version: Optional[str] = None
+    protocol_version: Optional[int] = None
+    capabilities: Optional[List[str]] = None
     
     def to_dict(self) -> Dict[str, Any]:
         return {k: v for k, v in asdict(self).items() if v is not None}
 
 
+@dataclass
+class HandshakeMessage:
+    """Negotiated protocol version and capabilities from the manager"""
+    protocol_version: int
+    capabilities: List[str]
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> 'HandshakeMessage':
+        return cls(
+            protocol_version=int(data.get('protocol_version', 0) or 0),
+            capabilities=list(data.get('capabilities') or []),
+        )
+
+
 @dataclass
 class LogMessage:
     """Structured log message"""
@@ -119,6 +163,7 @@ class CommandMessage:
     args: Optional[Dict[str, Any]] = None
     id: Optional[str] = None
     working_dir: Optional[str] = None
+    traceparent: Optional[str] = None
 
     @classmethod
     def from_dict(cls, data: Dict[str, Any]) -> 'CommandMessage':
@@ -126,10 +171,27 @@ class CommandMessage:
             command=data.get('command', ''),
             args=data.get('args'),
             id=data.get('id'),
-            working_dir=data.get('working_dir')
+            working_dir=data.get('working_dir'),
+            traceparent=data.get('traceparent')
         )
 
 
+@dataclass
+class FileOutput:
+    """A file a command wrote as output"""
+    path: str
+    mime_type: Optional[str] = None
+    name: Optional[str] = None
+
+    def to_dict(self) -> Dict[str, Any]:
+        data = {'path': self.path}
+        if self.mime_type:
+            data['mime_type'] = self.mime_type
+        if self.name:
+            data['name'] = self.name
+        return data
+
+
 @dataclass
 class ResponseMessage:
     """Response to a command"""
@@ -137,6 +199,7 @@ class ResponseMessage:
     success: bool = True
     result: Optional[Any] = None
     error: Optional[str] = None
+    files: Optional[List[FileOutput]] = None
 
     def to_dict(self) -> Dict[str, Any]:
         data = {
@@ -148,6 +211,8 @@ class ResponseMessage:
             data['result'] = self.result
         if self.error:
             data['error'] = self.error
+        if self.files:
+            data['files'] = [f.to_dict() for f in self.files]
         return data
 
 
@@ -277,6 +342,7 @@ class CommandDefinition:
     async_enabled: bool = False
     progress_label: Optional[str] = None
     hidden: bool = False
+    working_dir: Optional[str] = None
 
     def normalized(self) -> 'CommandDefinition':
         name = str(self.name).strip()
@@ -322,6 +388,7 @@ class CommandDefinition:
         async_enabled = bool(self.async_enabled)
         progress_label = (self.progress_label or '').strip() or None
         hidden = bool(self.hidden)
+        working_dir = (self.working_dir or '').strip() or None
 
         return replace(
             self,
@@ -336,6 +403,7 @@ class CommandDefinition:
             async_enabled=async_enabled,
             progress_label=progress_label,
             hidden=hidden,
+            working_dir=working_dir,
         )
 
     @staticmethod
@@ -424,6 +492,8 @@ class CommandDefinition:
             data["progress_label"] = normalized.progress_label
         if normalized.hidden:
             data["hidden"] = True
+        if normalized.working_dir:
+            data["working_dir"] = normalized.working_dir
         return data
 
     @staticmethod
@@ -478,14 +548,24 @@ class SidebarSectionMessage:
     title: str
     content: str
     collapsed: bool = False
+    actions: Optional[List[Dict[str, Any]]] = None
+    type: Optional[str] = None
+    format: Optional[str] = None
 
     def to_dict(self) -> Dict[str, Any]:
-        return {
+        payload = {
             'section_id': str(self.section_id).strip(),
             'title': str(self.title).strip(),
             'content': str(self.content),
             'collapsed': bool(self.collapsed)
         }
+        if self.actions:
+            payload['actions'] = [dict(action) for action in self.actions]
+        if self.type:
+            payload['type'] = str(self.type)
+        if self.format:
+            payload['format'] = str(self.format)
+        return payload
 
 
 @dataclass
@@ -532,8 +612,13 @@ class Protocol:
     
     @staticmethod
     def send_ready(pid: int, version: Optional[str] = None):
-        """Send ready message"""
-        msg = ReadyMessage(pid=pid, version=version)
+        """Send ready message declaring the protocol version and capabilities"""
+        msg = ReadyMessage(
+            pid=pid,
+            version=version,
+            protocol_version=PROTOCOL_VERSION,
+            capabilities=list(SUPPORTED_CAPABILITIES),
+        )
         Protocol.send_message(MessageType.READY, msg.to_dict())
     
     
@@ -551,13 +636,15 @@ class Protocol:
 
     @staticmethod
     def send_response(success: bool, command_id: Optional[str] = None,
-                      result: Optional[Any] = None, error: Optional[str] = None):
+                      result: Optional[Any] = None, error: Optional[str] = None,
+                      files: Optional[List[FileOutput]] = None):
         """Send response to a command"""
         msg = ResponseMessage(
             command_id=command_id,
             success=success,
             result=result,
-            error=error
+            error=error,
+            files=files
         )
         Protocol.send_message(MessageType.RESPONSE, msg.to_dict())
 
@@ -619,14 +706,25 @@ class Protocol:
         Protocol.send_message(MessageType.AGENT_DESCRIPTION, payload)
 
     @staticmethod
-    def send_sidebar_section(section_id: str, title: str, content: str, collapsed: bool = False) -> None:
+    def send_sidebar_section(
+        section_id: str,
+        title: str,
+        content: str,
+        collapsed: bool = False,
+        actions: Optional[List[Dict[str, Any]]] = None,
+        section_type: Optional[str] = None,
+        format: Optional[str] = None,
+    ) -> None:
         """Send or update a custom sidebar section."""
 
         msg = SidebarSectionMessage(
             section_id=section_id,
             title=title,
             content=content,
-            collapsed=collapsed
+            collapsed=collapsed,
+            actions=actions,
+            type=section_type,
+            format=format
         )
         Protocol.send_message(MessageType.SIDEBAR_SECTION, msg.to_dict())
 
@@ -637,6 +735,18 @@ class Protocol:
         msg = SidebarSectionRemovalMessage(section_id=section_id)
         Protocol.send_message(MessageType.SIDEBAR_SECTION_REMOVAL, msg.to_dict())
 
+    @staticmethod
+    def send_trigger_registry(triggers: Iterable[Dict[str, Any]]) -> None:
+        """Publish the complete set of triggers registered by the agent."""
+
+        Protocol.send_message(MessageType.TRIGGER_REGISTRY, {'triggers': list(triggers)})
+
+    @staticmethod
+    def send_metric(name: str, value: float, metric_type: str = "counter") -> None:
+        """Report a counter increment or a gauge value to the manager."""
+
+        Protocol.send_message(MessageType.METRIC, {'name': name, 'type': metric_type, 'value': value})
+
     @staticmethod
     def read_message() -> Optional[Message]:
         """Read a message from stdin"""
@@ -11,6 +11,12 @@ from typing import Final, Optional
 DEFAULT_SOCKET_NAME: Final[str] = "opperator.sock"
 ENV_SOCKET_PATH: Final[str] = "OPPERATOR_SOCKET_PATH"
 REQUEST_TYPE: Final[str] = "secret_get"
+OPPER_API_KEY_NAME: Final[str] = "OPPER_API_KEY"
+# Set by the daemon when the agent has its own opper_api_key
+ENV_OPPER_API_KEY_SECRET: Final[str] = "OPPERATOR_OPPER_API_KEY_SECRET"
+# Set by the daemon to the agent's name, so it can tell the agents using a
+# secret when it is rotated
+ENV_AGENT_NAME: Final[str] = "OPPERATOR_AGENT_NAME"
 
 
 class SecretError(RuntimeError):
@@ -42,11 +48,16 @@ def get_secret(name: str, *, timeout: float = 5.0) -> str:
     trimmed = (name or "").strip()
     if not trimmed:
         raise ValueError("secret name cannot be empty")
+    if trimmed == OPPER_API_KEY_NAME:
+        trimmed = os.environ.get(ENV_OPPER_API_KEY_SECRET) or trimmed
 
     payload = {
         "type": REQUEST_TYPE,
         "secret_name": trimmed,
     }
+    agent_name = os.environ.get(ENV_AGENT_NAME)
+    if agent_name:
+        payload["agent_name"] = agent_name
 
     path = _resolve_socket_path()
     try:
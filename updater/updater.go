@@ -6,6 +6,7 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -24,6 +25,9 @@ const (
 	githubRepo      = "opper-ai/opperator"
 )
 
+// ErrNoReleases is returned when the repository has no published releases.
+var ErrNoReleases = errors.New("no releases found on GitHub")
+
 // Release represents a GitHub release
 type Release struct {
 	TagName    string  `json:"tag_name"`
@@ -144,7 +148,7 @@ func fetchLatestFromAllReleases(client *http.Client, includePrerelease bool) (*R
 	defer resp.Body.Close()
 
 	if resp.StatusCode == http.StatusNotFound {
-		return nil, fmt.Errorf("no releases found on GitHub")
+		return nil, ErrNoReleases
 	}
 
 	if resp.StatusCode != http.StatusOK {
@@ -158,7 +162,7 @@ func fetchLatestFromAllReleases(client *http.Client, includePrerelease bool) (*R
 	}
 
 	if len(releases) == 0 {
-		return nil, fmt.Errorf("no releases found on GitHub")
+		return nil, ErrNoReleases
 	}
 
 	// If not including pre-releases, find the first stable release
@@ -255,6 +259,15 @@ func DownloadAndInstall(info *UpdateInfo) error {
 	return nil
 }
 
+// DownloadAndInstallVerified is DownloadAndInstall for unattended updates: it
+// refuses releases that publish no checksums to verify the download against.
+func DownloadAndInstallVerified(info *UpdateInfo) error {
+	if info.ChecksumURL == "" {
+		return fmt.Errorf("release %s publishes no SHA256SUMS; refusing to install an unverified binary", info.LatestVersion)
+	}
+	return DownloadAndInstall(info)
+}
+
 // downloadFile downloads a file from url to filepath
 func downloadFile(filepath string, url string) error {
 	resp, err := http.Get(url)
@@ -0,0 +1,41 @@
+package version
+
+// Protocol is the revision of the daemon IPC protocol spoken by this build.
+// Bump it when requests or responses change in a way an older peer would
+// misread, and raise MinProtocol once the old shape is no longer supported.
+const Protocol = 1
+
+// MinProtocol is the oldest protocol revision of a peer this build still
+// works with. Daemons that predate the version handshake count as revision 0.
+const MinProtocol = 0
+
+// Compatibility describes how this build relates to a peer.
+type Compatibility int
+
+const (
+	// Compatible peers run the same version, or one of them is a dev build.
+	Compatible Compatibility = iota
+	// Skewed peers run different versions that still understand each other,
+	// including daemons too old to report their version.
+	Skewed
+	// PeerTooOld peers speak a protocol revision below MinProtocol.
+	PeerTooOld
+	// PeerTooNew peers no longer support the protocol revision of this build.
+	PeerTooNew
+)
+
+// Compare checks a peer reporting its version, protocol revision and
+// minimum supported revision against this build.
+func Compare(peerVersion string, peerProtocol, peerMinProtocol int) Compatibility {
+	switch {
+	case peerProtocol < MinProtocol:
+		return PeerTooOld
+	case peerMinProtocol > Protocol:
+		return PeerTooNew
+	}
+	current := Get()
+	if current == "dev" || peerVersion == "dev" || (peerVersion == current && peerProtocol == Protocol) {
+		return Compatible
+	}
+	return Skewed
+}